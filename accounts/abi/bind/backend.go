@@ -36,6 +36,10 @@ var (
 	// on a backend that doesn't implement PendingContractCaller.
 	ErrNoPendingState = errors.New("backend does not support pending state")
 
+	// This error is raised when attempting to perform a call pinned to a block
+	// hash on a backend that doesn't implement BlockHashContractCaller.
+	ErrNoBlockHashState = errors.New("backend does not support block hash state")
+
 	// This error is returned by WaitDeployed if contract creation leaves an
 	// empty contract behind.
 	ErrNoCodeAfterDeploy = errors.New("no contract code after deployment")
@@ -62,6 +66,18 @@ type PendingContractCaller interface {
 	PendingCallContract(ctx context.Context, call indigo.CallMsg) ([]byte, error)
 }
 
+// BlockHashContractCaller defines methods to perform contract calls on the state
+// of a block selected by hash. Call will try to discover this interface when a
+// block hash is requested. If the backend does not support it, Call returns
+// ErrNoBlockHashState.
+type BlockHashContractCaller interface {
+	// CodeAtHash returns the code of the given account in the state of the block.
+	CodeAtHash(ctx context.Context, contract common.Address, blockHash common.Hash) ([]byte, error)
+	// CallContractAtHash executes an Indigo contract call against the state of
+	// the block.
+	CallContractAtHash(ctx context.Context, call indigo.CallMsg, blockHash common.Hash) ([]byte, error)
+}
+
 // ContractTransactor defines the methods needed to allow operating with contract
 // on a write only basis. Beside the transacting method, the remainder are helpers
 // used when the user does not provide some needed values, but rather leaves it up
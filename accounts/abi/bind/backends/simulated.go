@@ -43,8 +43,10 @@ import (
 
 // This nil assignment ensures compile time that SimulatedBackend implements bind.ContractBackend.
 var _ bind.ContractBackend = (*SimulatedBackend)(nil)
+var _ bind.BlockHashContractCaller = (*SimulatedBackend)(nil)
 
 var errBlockNumberUnsupported = errors.New("SimulatedBackend cannot access blocks other than the latest block")
+var errBlockNotCanonical = errors.New("block hash is not currently canonical")
 var errGasEstimationFailed = errors.New("gas required exceeds allowance or always failing transaction")
 
 // SimulatedBackend implements bind.ContractBackend, simulating a blockchain in
@@ -125,6 +127,35 @@ func (b *SimulatedBackend) CodeAt(ctx context.Context, contract common.Address,
 	return statedb.GetCode(contract), nil
 }
 
+// CodeAtHash returns the code associated with a certain account in the state of
+// the canonical block with the given hash.
+func (b *SimulatedBackend) CodeAtHash(ctx context.Context, contract common.Address, blockHash common.Hash) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, statedb, err := b.stateByHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return statedb.GetCode(contract), nil
+}
+
+// stateByHash retrieves the canonical block with the given hash and its state.
+func (b *SimulatedBackend) stateByHash(hash common.Hash) (*types.Block, *state.StateDB, error) {
+	block := b.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, nil, indigo.NotFound
+	}
+	if canonical := b.blockchain.GetBlockByNumber(block.NumberU64()); canonical == nil || canonical.Hash() != hash {
+		return nil, nil, errBlockNotCanonical
+	}
+	statedb, err := b.blockchain.StateAt(block.Root())
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, statedb, nil
+}
+
 // BalanceAt returns the wei balance of a certain account in the blockchain.
 func (b *SimulatedBackend) BalanceAt(ctx context.Context, contract common.Address, blockNumber *big.Int) (*big.Int, error) {
 	b.mu.Lock()
@@ -192,6 +223,20 @@ func (b *SimulatedBackend) CallContract(ctx context.Context, call indigo.CallMsg
 	return rval, err
 }
 
+// CallContractAtHash executes a contract call on the state of the canonical
+// block with the given hash.
+func (b *SimulatedBackend) CallContractAtHash(ctx context.Context, call indigo.CallMsg, blockHash common.Hash) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block, statedb, err := b.stateByHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	rval, _, _, err := b.callContract(ctx, call, block, statedb)
+	return rval, err
+}
+
 // PendingCallContract executes a contract call on the pending state.
 func (b *SimulatedBackend) PendingCallContract(ctx context.Context, call indigo.CallMsg) ([]byte, error) {
 	b.mu.Lock()
@@ -434,8 +479,14 @@ func (fb *filterBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEve
 func (fb *filterBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return fb.bc.SubscribeLogsEvent(ch)
 }
+func (fb *filterBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return fb.bc.SubscribeChainReorgEvent(ch)
+}
 
 func (fb *filterBackend) BloomStatus() (uint64, uint64) { return 4096, 0 }
+func (fb *filterBackend) BloomParams() (uint, uint) {
+	return params.HeaderBloomBits, params.HeaderBloomHashes
+}
 func (fb *filterBackend) ServiceFilter(ctx context.Context, ms *bloombits.MatcherSession) {
 	panic("not supported")
 }
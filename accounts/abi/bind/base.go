@@ -36,8 +36,9 @@ type SignerFn func(types.Signer, common.Address, *types.Transaction) (*types.Tra
 
 // CallOpts is the collection of options to fine tune a contract call request.
 type CallOpts struct {
-	Pending bool           // Whether to operate on the pending state or the last known one
-	From    common.Address // Optional the sender address, otherwise the first account is used
+	Pending   bool           // Whether to operate on the pending state or the last known one
+	BlockHash common.Hash    // Optional block to operate on the state of, pinned by hash
+	From      common.Address // Optional the sender address, otherwise the first account is used
 
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 }
@@ -133,7 +134,21 @@ func (c *BoundContract) Call(opts *CallOpts, result interface{}, method string,
 		code   []byte
 		output []byte
 	)
-	if opts.Pending {
+	if opts.BlockHash != (common.Hash{}) {
+		hb, ok := c.caller.(BlockHashContractCaller)
+		if !ok {
+			return ErrNoBlockHashState
+		}
+		output, err = hb.CallContractAtHash(ctx, msg, opts.BlockHash)
+		if err == nil && len(output) == 0 {
+			// Make sure we have a contract to operate on, and bail out otherwise.
+			if code, err = hb.CodeAtHash(ctx, c.address, opts.BlockHash); err != nil {
+				return err
+			} else if len(code) == 0 {
+				return ErrNoCode
+			}
+		}
+	} else if opts.Pending {
 		pb, ok := c.caller.(PendingContractCaller)
 		if !ok {
 			return ErrNoPendingState
@@ -0,0 +1,299 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend delegating signing to an
+// external signer daemon, so that keys never live in the node's keystore.
+//
+// The signer is reached over JSON-RPC (IPC, HTTP or WebSocket) and must
+// implement the following methods:
+//
+//	account_version                      returns the signer version string
+//	account_list                         returns the addresses it can sign for
+//	account_signHash(address, hash)      returns the 65 byte [R || S || V] signature
+//	account_signTransaction(args)        returns the RLP encoded signed transaction
+//
+// Signing requests may be held by the signer until its operator approves them.
+// Requests denied by the operator are answered with error code 4001.
+package external
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo"
+	"github.com/fulcrumchain/indigo/accounts"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+// ExternalScheme is the protocol scheme prefixing external signer URLs.
+const ExternalScheme = "extapi"
+
+const (
+	rejectedCode    = 4001             // JSON-RPC error code of requests denied by the operator
+	approvalTimeout = 5 * time.Minute  // Maximum time to wait for a signing request to be approved
+	queryTimeout    = 10 * time.Second // Maximum time to wait for non-signing requests
+)
+
+// ErrRejected is returned if the operator of the external signer denied a
+// signing request.
+var ErrRejected = errors.New("request rejected by external signer")
+
+// ExternalBackend is an accounts.Backend exposing the single wallet of an
+// external signer.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer at endpoint, which is
+// either a URL or the path of an IPC socket.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{signers: []accounts.Wallet{signer}}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. The external signer never arrives or
+// departs, so no events are ever sent.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner is an accounts.Wallet backed by an external signer daemon.
+type ExternalSigner struct {
+	client *rpc.Client
+	url    accounts.URL
+
+	cache []accounts.Account // Accounts last reported by the signer
+	lock  sync.Mutex
+}
+
+// NewExternalSigner connects to the external signer at endpoint, which is
+// either a URL or the path of an IPC socket.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	signer := newExternalSigner(client, endpoint)
+
+	// Query the signer once, so that an unusable endpoint fails early
+	if _, err := signer.Status(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return signer, nil
+}
+
+func newExternalSigner(client *rpc.Client, endpoint string) *ExternalSigner {
+	return &ExternalSigner{
+		client: client,
+		url:    accounts.URL{Scheme: ExternalScheme, Path: endpoint},
+	}
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (s *ExternalSigner) URL() accounts.URL {
+	return s.url
+}
+
+// Status implements accounts.Wallet, returning the version of the signer.
+func (s *ExternalSigner) Status() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	var version string
+	if err := s.call(ctx, &version, "account_version"); err != nil {
+		return "Failed", err
+	}
+	return fmt.Sprintf("Connected (version %s)", version), nil
+}
+
+// Open implements accounts.Wallet, but is a noop since the connection to the
+// signer is established on creation.
+func (s *ExternalSigner) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, tearing down the connection to the signer.
+func (s *ExternalSigner) Close() error {
+	s.client.Close()
+	return nil
+}
+
+// Accounts implements accounts.Wallet, returning the accounts the signer can
+// sign for. If the signer is unreachable, the last known list is returned.
+func (s *ExternalSigner) Accounts() []accounts.Account {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var addrs []common.Address
+	if err := s.call(ctx, &addrs, "account_list"); err != nil {
+		log.Warn("Failed to list external signer accounts", "url", s.url, "err", err)
+		return s.cache
+	}
+	s.cache = make([]accounts.Account, len(addrs))
+	for i, addr := range addrs {
+		s.cache[i] = accounts.Account{Address: addr, URL: s.url}
+	}
+	return s.cache
+}
+
+// Contains implements accounts.Wallet, returning whether the signer can sign
+// for the account.
+func (s *ExternalSigner) Contains(account accounts.Account) bool {
+	if account.URL != (accounts.URL{}) && account.URL != s.url {
+		return false
+	}
+	for _, known := range s.Accounts() {
+		if known.Address == account.Address {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported by external signers.
+func (s *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop since external signers
+// manage their own accounts.
+func (s *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain indigo.ChainStateReader) {}
+
+// SignHash implements accounts.Wallet, requesting the signer to sign the hash.
+// The call blocks until the operator approves or denies the request.
+func (s *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), approvalTimeout)
+	defer cancel()
+
+	var sig hexutil.Bytes
+	if err := s.call(ctx, &sig, "account_signHash", account.Address, hexutil.Bytes(hash)); err != nil {
+		return nil, err
+	}
+	// Make sure the signer didn't sign with some other key
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return nil, err
+	}
+	if crypto.PubkeyToAddress(*pub) != account.Address {
+		return nil, fmt.Errorf("external signer signed with wrong key")
+	}
+	return sig, nil
+}
+
+// signTxArgs is the transaction sent to the signer for signing.
+type signTxArgs struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Data     hexutil.Bytes   `json:"data"`
+	ChainID  *hexutil.Big    `json:"chainId,omitempty"`
+}
+
+// SignTx implements accounts.Wallet, requesting the signer to sign the
+// transaction. The call blocks until the operator approves or denies the
+// request.
+func (s *ExternalSigner) SignTx(ctx context.Context, account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, approvalTimeout)
+	defer cancel()
+
+	args := &signTxArgs{
+		From:     account.Address,
+		To:       tx.To(),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Value:    (*hexutil.Big)(tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     tx.Data(),
+		ChainID:  (*hexutil.Big)(chainID),
+	}
+	var raw hexutil.Bytes
+	if err := s.call(ctx, &raw, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, signed); err != nil {
+		return nil, err
+	}
+	// Make sure the signer signed what was requested, with the right key
+	txSigner := signerFor(chainID)
+	if txSigner.Hash(signed) != txSigner.Hash(tx) {
+		return nil, fmt.Errorf("external signer returned different transaction")
+	}
+	from, err := types.Sender(ctx, txSigner, signed)
+	if err != nil {
+		return nil, err
+	}
+	if from != account.Address {
+		return nil, fmt.Errorf("external signer signed with wrong key")
+	}
+	return signed, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but is not supported since
+// the signer authenticates requests itself.
+func (s *ExternalSigner) SignHashWithPassphrase(ctx context.Context, account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but is not supported since
+// the signer authenticates requests itself.
+func (s *ExternalSigner) SignTxWithPassphrase(ctx context.Context, account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, accounts.ErrNotSupported
+}
+
+// call performs a JSON-RPC call against the signer, translating denied
+// requests into ErrRejected.
+func (s *ExternalSigner) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	err := s.client.CallContext(ctx, result, method, args...)
+	if rpcErr, ok := err.(rpc.Error); ok && rpcErr.ErrorCode() == rejectedCode {
+		return ErrRejected
+	}
+	return err
+}
+
+func signerFor(chainID *big.Int) types.Signer {
+	if chainID == nil {
+		return types.HomesteadSigner{}
+	}
+	return types.NewEIP155Signer(chainID)
+}
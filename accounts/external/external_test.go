@@ -0,0 +1,136 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/accounts"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+// rejectedError is the error a signer answers denied requests with.
+type rejectedError struct{}
+
+func (rejectedError) Error() string  { return "request denied" }
+func (rejectedError) ErrorCode() int { return rejectedCode }
+
+// TxArgs mirrors signTxArgs, as the RPC server requires exported types.
+type TxArgs signTxArgs
+
+// SignerService is a signer daemon approving requests from a single key, as
+// long as approve is set.
+type SignerService struct {
+	key     *ecdsa.PrivateKey
+	approve bool
+}
+
+func (s *SignerService) Version() string {
+	return "1.0.0"
+}
+
+func (s *SignerService) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *SignerService) SignHash(addr common.Address, hash hexutil.Bytes) (hexutil.Bytes, error) {
+	if !s.approve {
+		return nil, rejectedError{}
+	}
+	return crypto.Sign(hash, s.key)
+}
+
+func (s *SignerService) SignTransaction(ctx context.Context, args TxArgs) (hexutil.Bytes, error) {
+	if !s.approve {
+		return nil, rejectedError{}
+	}
+	tx := types.NewTransaction(uint64(args.Nonce), *args.To, args.Value.ToInt(), uint64(args.Gas), args.GasPrice.ToInt(), args.Data)
+	signed, err := types.SignTx(tx, types.NewEIP155Signer(args.ChainID.ToInt()), s.key)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(signed)
+}
+
+func newTestSigner(t *testing.T) (*ExternalSigner, *SignerService) {
+	key, _ := crypto.GenerateKey()
+	service := &SignerService{key: key, approve: true}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", service); err != nil {
+		t.Fatal(err)
+	}
+	return newExternalSigner(rpc.DialInProc(server), "test"), service
+}
+
+func TestExternalSigner(t *testing.T) {
+	signer, service := newTestSigner(t)
+	defer signer.Close()
+
+	if status, err := signer.Status(); err != nil || status != "Connected (version 1.0.0)" {
+		t.Errorf("status mismatch: have %q, %v", status, err)
+	}
+	addr := crypto.PubkeyToAddress(service.key.PublicKey)
+	account := accounts.Account{Address: addr}
+	if accs := signer.Accounts(); len(accs) != 1 || accs[0].Address != addr || accs[0].URL != signer.URL() {
+		t.Fatalf("accounts mismatch: have %v", accs)
+	}
+	if !signer.Contains(account) {
+		t.Errorf("signer account not contained")
+	}
+	if signer.Contains(accounts.Account{Address: common.Address{1}}) {
+		t.Errorf("foreign account contained")
+	}
+	// Approved requests are signed with the signer's key.
+	hash := crypto.Keccak256([]byte("header"))
+	sig, err := signer.SignHash(account, hash)
+	if err != nil {
+		t.Fatalf("failed to sign hash: %v", err)
+	}
+	if pub, err := crypto.SigToPub(hash, sig); err != nil || crypto.PubkeyToAddress(*pub) != addr {
+		t.Errorf("hash signed with wrong key")
+	}
+	chainID := big.NewInt(1)
+	tx := types.NewTransaction(1, common.Address{2}, big.NewInt(3), 21000, big.NewInt(4), nil)
+	signed, err := signer.SignTx(context.Background(), account, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, err := types.Sender(context.Background(), types.NewEIP155Signer(chainID), signed); err != nil || from != addr {
+		t.Errorf("transaction sender mismatch: have %x, want %x", from, addr)
+	}
+	// Signatures by another key are refused.
+	if _, err := signer.SignHash(accounts.Account{Address: common.Address{1}}, hash); err == nil {
+		t.Errorf("signature by wrong key accepted")
+	}
+	// Denied requests surface as rejections.
+	service.approve = false
+	if _, err := signer.SignHash(account, hash); err != ErrRejected {
+		t.Errorf("denied hash error mismatch: have %v, want %v", err, ErrRejected)
+	}
+	if _, err := signer.SignTx(context.Background(), account, tx, chainID); err != ErrRejected {
+		t.Errorf("denied transaction error mismatch: have %v, want %v", err, ErrRejected)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/crypto/bls"
+	"github.com/pborman/uuid"
+)
+
+// blsKeyDir is the subdirectory of the keystore holding BLS keys. They have no
+// address and don't back any wallet, so they are kept apart from the account
+// keys and identified by their public key instead.
+const blsKeyDir = "bls"
+
+var (
+	ErrNoBLSKey     = errors.New("no BLS key for given public key")
+	errBLSPlaintext = errors.New("BLS keys require an encrypted keystore")
+)
+
+// encryptedBLSKeyJSON is the format of BLS key files, following that of
+// account keys with the public key in place of the address.
+type encryptedBLSKeyJSON struct {
+	PublicKey string     `json:"pubkey"`
+	Crypto    cryptoJSON `json:"crypto"`
+	Id        string     `json:"id"`
+	Version   int        `json:"version"`
+}
+
+// blsStorage returns the encrypted storage BLS keys are kept in.
+func (ks *KeyStore) blsStorage() (*keyStorePassphrase, error) {
+	storage, ok := ks.storage.(*keyStorePassphrase)
+	if !ok {
+		return nil, errBLSPlaintext
+	}
+	return storage, nil
+}
+
+// NewBLSKey generates a new BLS key, stores it encrypted with the passphrase
+// and returns its public key.
+func (ks *KeyStore) NewBLSKey(passphrase string) (*bls.PublicKey, error) {
+	key, err := bls.GenerateKey(crand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ks.ImportBLSKey(key, passphrase)
+}
+
+// ImportBLSKey stores the given BLS key encrypted with the passphrase and
+// returns its public key.
+func (ks *KeyStore) ImportBLSKey(key *bls.SecretKey, passphrase string) (*bls.PublicKey, error) {
+	storage, err := ks.blsStorage()
+	if err != nil {
+		return nil, err
+	}
+	pub := key.PublicKey()
+	if _, err := ks.findBLSKey(pub); err == nil {
+		return nil, fmt.Errorf("BLS key %x already exists", blsKeyID(pub))
+	}
+	cryptoStruct, err := encryptData(key.Bytes(), passphrase, storage.kdf)
+	if err != nil {
+		return nil, err
+	}
+	keyjson, err := json.Marshal(encryptedBLSKeyJSON{
+		PublicKey: hex.EncodeToString(pub.Bytes()),
+		Crypto:    cryptoStruct,
+		Id:        uuid.NewRandom().String(),
+		Version:   version,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Name the file after a hash, as the public key is too long for file names
+	name := fmt.Sprintf("UTC--%s--%x", toISO8601(time.Now().UTC()), blsKeyID(pub))
+	if err := writeKeyFile(storage.JoinPath(filepath.Join(blsKeyDir, name)), keyjson); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// BLSKeys returns the public keys of all stored BLS keys.
+func (ks *KeyStore) BLSKeys() ([]*bls.PublicKey, error) {
+	var pubs []*bls.PublicKey
+	err := ks.eachBLSKey(func(path string, key *encryptedBLSKeyJSON, pub *bls.PublicKey) bool {
+		pubs = append(pubs, pub)
+		return true
+	})
+	return pubs, err
+}
+
+// SignBLS signs a message with the BLS key of the given public key, decrypting
+// it with the passphrase.
+func (ks *KeyStore) SignBLS(pub *bls.PublicKey, passphrase string, msg []byte) (*bls.Signature, error) {
+	key, err := ks.getBLSKey(pub, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.Sign(msg), nil
+}
+
+// ProveBLSPossession creates the proof that the keystore holds the secret key
+// of the given public key, required to aggregate its signatures over common
+// messages with those of other keys.
+func (ks *KeyStore) ProveBLSPossession(pub *bls.PublicKey, passphrase string) (*bls.Signature, error) {
+	key, err := ks.getBLSKey(pub, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.ProvePossession(), nil
+}
+
+// getBLSKey loads and decrypts the BLS key of the given public key.
+func (ks *KeyStore) getBLSKey(pub *bls.PublicKey, passphrase string) (*bls.SecretKey, error) {
+	key, err := ks.findBLSKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := decryptData(key.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := bls.SecretKeyFromBytes(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure we're really operating on the requested key (no swap attacks)
+	if !sk.PublicKey().Equal(pub) {
+		return nil, fmt.Errorf("key content mismatch: have BLS key %x, want %x", blsKeyID(sk.PublicKey()), blsKeyID(pub))
+	}
+	return sk, nil
+}
+
+// findBLSKey returns the stored BLS key of the given public key.
+func (ks *KeyStore) findBLSKey(pub *bls.PublicKey) (*encryptedBLSKeyJSON, error) {
+	var found *encryptedBLSKeyJSON
+
+	want := pub.Bytes()
+	err := ks.eachBLSKey(func(path string, key *encryptedBLSKeyJSON, stored *bls.PublicKey) bool {
+		if bytes.Equal(stored.Bytes(), want) {
+			found = key
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrNoBLSKey
+	}
+	return found, nil
+}
+
+// eachBLSKey calls fn with the stored BLS keys until it returns false, skipping
+// files that aren't BLS keys.
+func (ks *KeyStore) eachBLSKey(fn func(path string, key *encryptedBLSKeyJSON, pub *bls.PublicKey) bool) error {
+	storage, err := ks.blsStorage()
+	if err != nil {
+		return err
+	}
+	dir := storage.JoinPath(blsKeyDir)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, fi := range files {
+		if skipKeyFile(fi) {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		blob, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		key := new(encryptedBLSKeyJSON)
+		if err := json.Unmarshal(blob, key); err != nil {
+			continue
+		}
+		raw, err := hex.DecodeString(key.PublicKey)
+		if err != nil {
+			continue
+		}
+		pub, err := bls.PublicKeyFromBytes(raw)
+		if err != nil {
+			continue
+		}
+		if !fn(path, key, pub) {
+			break
+		}
+	}
+	return nil
+}
+
+// blsKeyID returns a short identifier of a BLS public key.
+func blsKeyID(pub *bls.PublicKey) []byte {
+	return crypto.Keccak256(pub.Bytes())[:20]
+}
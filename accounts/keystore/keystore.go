@@ -80,8 +80,14 @@ type unlocked struct {
 
 // NewKeyStore creates a keystore for the given directory.
 func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
+	return NewKeyStoreWithKDF(keydir, ScryptKDF(scryptN, scryptP))
+}
+
+// NewKeyStoreWithKDF creates a keystore for the given directory, encrypting new
+// key files with the given key derivation function.
+func NewKeyStoreWithKDF(keydir string, kdf KDFConfig) *KeyStore {
 	keydir, _ = filepath.Abs(keydir)
-	ks := &KeyStore{storage: &keyStorePassphrase{keydir, scryptN, scryptP}}
+	ks := &KeyStore{storage: &keyStorePassphrase{keydir, kdf}}
 	ks.init(keydir)
 	return ks
 }
@@ -429,13 +435,11 @@ func (ks *KeyStore) Export(a accounts.Account, passphrase, newPassphrase string)
 	if err != nil {
 		return nil, err
 	}
-	var N, P int
+	kdf := ScryptKDF(StandardScryptN, StandardScryptP)
 	if store, ok := ks.storage.(*keyStorePassphrase); ok {
-		N, P = store.scryptN, store.scryptP
-	} else {
-		N, P = StandardScryptN, StandardScryptP
+		kdf = store.kdf
 	}
-	return EncryptKey(key, newPassphrase, N, P)
+	return EncryptKeyWithKDF(key, newPassphrase, kdf)
 }
 
 // Import stores the given encrypted JSON key into the key directory.
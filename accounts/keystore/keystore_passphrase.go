@@ -41,12 +41,19 @@ import (
 	"github.com/fulcrumchain/indigo/crypto"
 	"github.com/fulcrumchain/indigo/crypto/randentropy"
 	"github.com/pborman/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
 
 const (
-	keyHeaderKDF = "scrypt"
+	keyHeaderKDF = KDFScrypt
+
+	// KDFScrypt is the name of the scrypt key derivation function, the default.
+	KDFScrypt = "scrypt"
+
+	// KDFArgon2id is the name of the Argon2id key derivation function.
+	KDFArgon2id = "argon2id"
 
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
@@ -64,14 +71,80 @@ const (
 	// memory and taking approximately 100ms CPU time on a modern processor.
 	LightScryptP = 6
 
+	// StandardArgon2Time is the number of passes of the Argon2id KDF, which
+	// together with the memory and thread settings takes approximately 100ms CPU
+	// time on a modern processor.
+	StandardArgon2Time = 3
+
+	// StandardArgon2Memory is the memory in KiB used by the Argon2id KDF, 64MB.
+	StandardArgon2Memory = 64 * 1024
+
+	// StandardArgon2Threads is the parallelism of the Argon2id KDF.
+	StandardArgon2Threads = 4
+
+	// LightArgon2Time is the number of passes of the Argon2id KDF in light mode.
+	LightArgon2Time = 1
+
+	// LightArgon2Memory is the memory in KiB used by the Argon2id KDF in light
+	// mode, 4MB.
+	LightArgon2Memory = 4 * 1024
+
 	scryptR     = 8
 	scryptDKLen = 32
 )
 
+// KDFConfig selects the key derivation function and its parameters used to
+// encrypt new key files. Key files are always decrypted with the function and
+// parameters they were written with. Zero parameters take the standard values.
+type KDFConfig struct {
+	KDF           string `toml:",omitempty"` // KDFScrypt or KDFArgon2id, scrypt if empty
+	ScryptN       int    `toml:",omitempty"`
+	ScryptP       int    `toml:",omitempty"`
+	Argon2Time    uint32 `toml:",omitempty"`
+	Argon2Memory  uint32 `toml:",omitempty"` // Memory in KiB
+	Argon2Threads uint8  `toml:",omitempty"`
+}
+
+// ScryptKDF returns the configuration of the scrypt KDF with the given N and P.
+func ScryptKDF(n, p int) KDFConfig {
+	return KDFConfig{KDF: KDFScrypt, ScryptN: n, ScryptP: p}
+}
+
+// withDefaults fills in the standard values of unset parameters.
+func (c KDFConfig) withDefaults() KDFConfig {
+	if c.KDF == "" {
+		c.KDF = KDFScrypt
+	}
+	if c.ScryptN == 0 {
+		c.ScryptN = StandardScryptN
+	}
+	if c.ScryptP == 0 {
+		c.ScryptP = StandardScryptP
+	}
+	if c.Argon2Time == 0 {
+		c.Argon2Time = StandardArgon2Time
+	}
+	if c.Argon2Memory == 0 {
+		c.Argon2Memory = StandardArgon2Memory
+	}
+	if c.Argon2Threads == 0 {
+		c.Argon2Threads = StandardArgon2Threads
+	}
+	return c
+}
+
+// Validate checks that the configured KDF is supported.
+func (c KDFConfig) Validate() error {
+	switch c.KDF {
+	case "", KDFScrypt, KDFArgon2id:
+		return nil
+	}
+	return fmt.Errorf("unsupported KDF: %s", c.KDF)
+}
+
 type keyStorePassphrase struct {
 	keysDirPath string
-	scryptN     int
-	scryptP     int
+	kdf         KDFConfig
 }
 
 func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string) (*Key, error) {
@@ -93,12 +166,18 @@ func (ks keyStorePassphrase) GetKey(addr common.Address, filename, auth string)
 
 // StoreKey generates a key, encrypts with 'auth' and stores in the given directory
 func StoreKey(dir, auth string, scryptN, scryptP int) (common.Address, error) {
-	_, a, err := storeNewKey(&keyStorePassphrase{dir, scryptN, scryptP}, crand.Reader, auth)
+	return StoreKeyWithKDF(dir, auth, ScryptKDF(scryptN, scryptP))
+}
+
+// StoreKeyWithKDF generates a key, encrypts it with 'auth' using the given KDF
+// and stores it in the given directory.
+func StoreKeyWithKDF(dir, auth string, kdf KDFConfig) (common.Address, error) {
+	_, a, err := storeNewKey(&keyStorePassphrase{dir, kdf}, crand.Reader, auth)
 	return a.Address, err
 }
 
 func (ks keyStorePassphrase) StoreKey(filename string, key *Key, auth string) error {
-	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	keyjson, err := EncryptKeyWithKDF(key, auth, ks.kdf)
 	if err != nil {
 		return err
 	}
@@ -116,48 +195,79 @@ func (ks keyStorePassphrase) JoinPath(filename string) string {
 // EncryptKey encrypts a key using the specified scrypt parameters into a json
 // blob that can be decrypted later on.
 func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
-	authArray := []byte(auth)
-	salt := randentropy.GetEntropyCSPRNG(32)
-	derivedKey, err := scrypt.Key(authArray, salt, scryptN, scryptR, scryptP, scryptDKLen)
+	return EncryptKeyWithKDF(key, auth, ScryptKDF(scryptN, scryptP))
+}
+
+// EncryptKeyWithKDF encrypts a key using the specified key derivation function
+// into a json blob that can be decrypted later on.
+func EncryptKeyWithKDF(key *Key, auth string, kdf KDFConfig) ([]byte, error) {
+	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
+	cryptoStruct, err := encryptData(keyBytes, auth, kdf)
 	if err != nil {
 		return nil, err
 	}
+	encryptedKeyJSONV3 := encryptedKeyJSONV3{
+		hex.EncodeToString(key.Address[:]),
+		cryptoStruct,
+		key.Id.String(),
+		version,
+	}
+	return json.Marshal(encryptedKeyJSONV3)
+}
+
+// encryptData encrypts data with a key derived from 'auth' using the given KDF.
+func encryptData(data []byte, auth string, kdf KDFConfig) (cryptoJSON, error) {
+	if err := kdf.Validate(); err != nil {
+		return cryptoJSON{}, err
+	}
+	kdf = kdf.withDefaults()
+
+	authArray := []byte(auth)
+	salt := randentropy.GetEntropyCSPRNG(32)
+
+	var (
+		derivedKey []byte
+		kdfParams  = make(map[string]interface{}, 5)
+	)
+	switch kdf.KDF {
+	case KDFArgon2id:
+		derivedKey = argon2.IDKey(authArray, salt, kdf.Argon2Time, kdf.Argon2Memory, kdf.Argon2Threads, scryptDKLen)
+		kdfParams["t"] = kdf.Argon2Time
+		kdfParams["m"] = kdf.Argon2Memory
+		kdfParams["p"] = kdf.Argon2Threads
+	default:
+		var err error
+		derivedKey, err = scrypt.Key(authArray, salt, kdf.ScryptN, scryptR, kdf.ScryptP, scryptDKLen)
+		if err != nil {
+			return cryptoJSON{}, err
+		}
+		kdfParams["n"] = kdf.ScryptN
+		kdfParams["r"] = scryptR
+		kdfParams["p"] = kdf.ScryptP
+	}
+	kdfParams["dklen"] = scryptDKLen
+	kdfParams["salt"] = hex.EncodeToString(salt)
 	encryptKey := derivedKey[:16]
-	keyBytes := math.PaddedBigBytes(key.PrivateKey.D, 32)
 
 	iv := randentropy.GetEntropyCSPRNG(aes.BlockSize) // 16
-	cipherText, err := aesCTRXOR(encryptKey, keyBytes, iv)
+	cipherText, err := aesCTRXOR(encryptKey, data, iv)
 	if err != nil {
-		return nil, err
+		return cryptoJSON{}, err
 	}
 	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
 
-	scryptParamsJSON := make(map[string]interface{}, 5)
-	scryptParamsJSON["n"] = scryptN
-	scryptParamsJSON["r"] = scryptR
-	scryptParamsJSON["p"] = scryptP
-	scryptParamsJSON["dklen"] = scryptDKLen
-	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
-
 	cipherParamsJSON := cipherparamsJSON{
 		IV: hex.EncodeToString(iv),
 	}
 
-	cryptoStruct := cryptoJSON{
+	return cryptoJSON{
 		Cipher:       "aes-128-ctr",
 		CipherText:   hex.EncodeToString(cipherText),
 		CipherParams: cipherParamsJSON,
-		KDF:          keyHeaderKDF,
-		KDFParams:    scryptParamsJSON,
+		KDF:          kdf.KDF,
+		KDFParams:    kdfParams,
 		MAC:          hex.EncodeToString(mac),
-	}
-	encryptedKeyJSONV3 := encryptedKeyJSONV3{
-		hex.EncodeToString(key.Address[:]),
-		cryptoStruct,
-		key.Id.String(),
-		version,
-	}
-	return json.Marshal(encryptedKeyJSONV3)
+	}, nil
 }
 
 // DecryptKey decrypts a key from a json blob, returning the private key itself.
@@ -202,42 +312,44 @@ func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byt
 	if keyProtected.Version != version {
 		return nil, nil, fmt.Errorf("Version not supported: %v", keyProtected.Version)
 	}
-
-	if keyProtected.Crypto.Cipher != "aes-128-ctr" {
-		return nil, nil, fmt.Errorf("Cipher not supported: %v", keyProtected.Crypto.Cipher)
-	}
-
 	keyId = uuid.Parse(keyProtected.Id)
-	mac, err := hex.DecodeString(keyProtected.Crypto.MAC)
+	plainText, err := decryptData(keyProtected.Crypto, auth)
 	if err != nil {
 		return nil, nil, err
 	}
+	return plainText, keyId, err
+}
 
-	iv, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
+// decryptData decrypts data encrypted by encryptData.
+func decryptData(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
+	if cryptoJSON.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("Cipher not supported: %v", cryptoJSON.Cipher)
+	}
+	mac, err := hex.DecodeString(cryptoJSON.MAC)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
+	iv, err := hex.DecodeString(cryptoJSON.CipherParams.IV)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
+	cipherText, err := hex.DecodeString(cryptoJSON.CipherText)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
-	if !bytes.Equal(calculatedMAC, mac) {
-		return nil, nil, ErrDecrypt
+	derivedKey, err := getKDFKey(cryptoJSON, auth)
+	if err != nil {
+		return nil, err
 	}
 
-	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
-	if err != nil {
-		return nil, nil, err
+	calculatedMAC := crypto.Keccak256(derivedKey[16:32], cipherText)
+	if !bytes.Equal(calculatedMAC, mac) {
+		return nil, ErrDecrypt
 	}
-	return plainText, keyId, err
+	return aesCTRXOR(derivedKey[:16], cipherText, iv)
 }
 
 func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byte, keyId []byte, err error) {
@@ -288,6 +400,15 @@ func getKDFKey(cryptoJSON cryptoJSON, auth string) ([]byte, error) {
 		p := ensureInt(cryptoJSON.KDFParams["p"])
 		return scrypt.Key(authArray, salt, n, r, p, dkLen)
 
+	} else if cryptoJSON.KDF == KDFArgon2id {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t <= 0 || m <= 0 || p <= 0 || p > 255 {
+			return nil, fmt.Errorf("Invalid Argon2id parameters: t=%d m=%d p=%d", t, m, p)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
+
 	} else if cryptoJSON.KDF == "pbkdf2" {
 		c := ensureInt(cryptoJSON.KDFParams["c"])
 		prf := cryptoJSON.KDFParams["prf"].(string)
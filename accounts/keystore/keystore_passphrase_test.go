@@ -17,6 +17,7 @@
 package keystore
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"testing"
 
@@ -58,3 +59,40 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that keys can be encrypted with Argon2id and read back transparently,
+// and that unsupported KDFs are rejected.
+func TestKeyEncryptDecryptArgon2id(t *testing.T) {
+	keyjson, err := ioutil.ReadFile("testdata/very-light-scrypt.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DecryptKey(keyjson, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kdf := KDFConfig{KDF: KDFArgon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1}
+	if keyjson, err = EncryptKeyWithKDF(key, "foo", kdf); err != nil {
+		t.Fatalf("failed to encrypt key with argon2id: %v", err)
+	}
+	var stored encryptedKeyJSONV3
+	if err := json.Unmarshal(keyjson, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Crypto.KDF != KDFArgon2id {
+		t.Errorf("KDF mismatch: have %q, want %q", stored.Crypto.KDF, KDFArgon2id)
+	}
+	if _, err := DecryptKey(keyjson, "bar"); err != ErrDecrypt {
+		t.Errorf("decrypting with bad password: have %v, want %v", err, ErrDecrypt)
+	}
+	decrypted, err := DecryptKey(keyjson, "foo")
+	if err != nil {
+		t.Fatalf("failed to decrypt argon2id key: %v", err)
+	}
+	if decrypted.Address != key.Address {
+		t.Errorf("key address mismatch: have %x, want %x", decrypted.Address, key.Address)
+	}
+	if _, err := EncryptKeyWithKDF(key, "foo", KDFConfig{KDF: "bcrypt"}); err == nil {
+		t.Errorf("unsupported KDF accepted")
+	}
+}
@@ -17,6 +17,7 @@
 package keystore
 
 import (
+	crand "crypto/rand"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/fulcrumchain/indigo/accounts"
 	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/crypto/bls"
 	"github.com/fulcrumchain/indigo/event"
 )
 
@@ -358,6 +360,61 @@ func checkAccounts(t *testing.T, live map[common.Address]accounts.Account, walle
 	}
 }
 
+func TestBLSKeys(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	pub, err := ks.NewBLSKey("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubs, err := ks.BLSKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pubs) != 1 || !pubs[0].Equal(pub) {
+		t.Fatalf("BLS key list mismatch: have %d keys, want 1", len(pubs))
+	}
+	// BLS keys must not show up as accounts
+	if accs := ks.Accounts(); len(accs) != 0 {
+		t.Errorf("BLS key listed as account: %v", accs)
+	}
+	msg := []byte("some message")
+	if _, err := ks.SignBLS(pub, "bar", msg); err != ErrDecrypt {
+		t.Errorf("signing with wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	sig, err := ks.SignBLS(pub, "foo", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bls.Verify(pub, msg, sig) {
+		t.Error("BLS signature invalid")
+	}
+	proof, err := ks.ProveBLSPossession(pub, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.VerifyPossession(proof) {
+		t.Error("BLS possession proof invalid")
+	}
+	// Unknown keys and plaintext keystores must be rejected
+	other, _ := bls.GenerateKey(crand.Reader)
+	if _, err := ks.SignBLS(other.PublicKey(), "foo", msg); err != ErrNoBLSKey {
+		t.Errorf("signing with unknown key: have %v, want %v", err, ErrNoBLSKey)
+	}
+	if _, err := ks.ImportBLSKey(other, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.ImportBLSKey(other, "foo"); err == nil {
+		t.Error("duplicate BLS key imported")
+	}
+	pdir, pks := tmpKeyStore(t, false)
+	defer os.RemoveAll(pdir)
+	if _, err := pks.NewBLSKey("foo"); err != errBLSPlaintext {
+		t.Errorf("plaintext keystore: have %v, want %v", err, errBLSPlaintext)
+	}
+}
+
 // checkEvents checks that all events in 'want' are present in 'have'. Events may be present multiple times.
 func checkEvents(t *testing.T, want []walletEvent, have []walletEvent) {
 	for _, wantEv := range want {
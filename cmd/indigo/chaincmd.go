@@ -71,6 +71,7 @@ It expects the genesis file as argument.`,
 			utils.GCModeFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheTrieFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -40,13 +40,16 @@ import (
 
 var (
 	dumpConfigCommand = cli.Command{
-		Action:      utils.MigrateFlags(dumpConfig),
-		Name:        "dumpconfig",
-		Usage:       "Show configuration values",
-		ArgsUsage:   "",
-		Flags:       append(append(nodeFlags, rpcFlags...), whisperFlags...),
-		Category:    "MISCELLANEOUS COMMANDS",
-		Description: `The dumpconfig command shows configuration values.`,
+		Action:    utils.MigrateFlags(dumpConfig),
+		Name:      "dumpconfig",
+		Usage:     "Show configuration values",
+		ArgsUsage: "[<file>]",
+		Flags:     append(append(nodeFlags, rpcFlags...), whisperFlags...),
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The dumpconfig command shows configuration values, resulting from the defaults,
+the configuration file and the flags given. If a file is specified, the values
+are written to it, to be loaded back with --config.`,
 	}
 
 	configFileFlag = cli.StringFlag{
@@ -114,6 +117,9 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, indigoConfig) {
 		Dashboard: dashboard.DefaultConfig,
 	}
 
+	// Apply the role preset, before the config file and flags overriding it.
+	utils.SetRoleConfig(ctx, &cfg.Node, &cfg.Eth)
+
 	// Load config file.
 	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
 		if err := loadConfig(file, &cfg); err != nil {
@@ -193,7 +199,19 @@ func dumpConfig(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	io.WriteString(os.Stdout, comment)
-	os.Stdout.Write(out)
+	dump := os.Stdout
+	if len(ctx.Args()) > 0 {
+		// The config may hold credentials, keep it readable by the owner only
+		dump, err = os.OpenFile(ctx.Args().First(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer dump.Close()
+		if err := dump.Chmod(0600); err != nil {
+			return err
+		}
+	}
+	io.WriteString(dump, comment)
+	dump.Write(out)
 	return nil
 }
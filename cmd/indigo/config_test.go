@@ -0,0 +1,92 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/eth"
+	"github.com/fulcrumchain/indigo/ethdb/archive"
+)
+
+// Tests that the eth configuration, including the nested transaction pool,
+// gas price oracle and archive settings, survives a dump and reload.
+func TestConfigRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "indigo-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	want := eth.DefaultConfig
+	want.DatabaseCache = 2048
+	want.TrieTimeout = 30 * time.Minute
+	want.Etherbase = common.Address{0x01}
+	want.ExtraData = []byte("indigo")
+	want.GasPrice = big.NewInt(42)
+	want.TxPool.GlobalSlots = 4096
+	want.TxPool.Tenants = []core.TenantQuota{{Name: "treasury", Slots: 16, Weight: 2}}
+	want.GPO.Percentile = 80
+	want.Archive = archive.Config{
+		Endpoint:  "s3.example.org",
+		Bucket:    "indigo",
+		Period:    time.Minute,
+		Retention: []archive.Retention{{Type: "receipts", Never: true}},
+	}
+
+	out, err := tomlSettings.Marshal(&indigoConfig{Eth: want})
+	if err != nil {
+		t.Fatalf("failed to dump config: %v", err)
+	}
+	file := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(file, out, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var have indigoConfig
+	if err := loadConfig(file, &have); err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if !reflect.DeepEqual(have.Eth, want) {
+		t.Errorf("config mismatch:\nhave %+v\nwant %+v", have.Eth, want)
+	}
+}
+
+// Tests that unknown fields are refused, naming the file they are found in.
+func TestConfigUnknownField(t *testing.T) {
+	dir, err := ioutil.TempDir("", "indigo-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.toml")
+	if err := ioutil.WriteFile(file, []byte("[Eth.TxPool]\nGlobalSlot = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var cfg indigoConfig
+	if err := loadConfig(file, &cfg); err == nil {
+		t.Fatal("loaded config with an unknown field")
+	}
+}
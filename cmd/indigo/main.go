@@ -64,6 +64,7 @@ var (
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
+		utils.ExternalSignerFlag,
 		utils.DashboardEnabledFlag,
 		utils.DashboardAddrFlag,
 		utils.DashboardPortFlag,
@@ -73,40 +74,80 @@ var (
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceBumpFlag,
+		utils.TxPoolLocalSamePriceFlag,
 		utils.TxPoolAccountSlotsFlag,
 		utils.TxPoolGlobalSlotsFlag,
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
+		utils.TxPoolAccountQuotaFlag,
+		utils.TxPoolAccountQuotaBumpFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolLifetimeTiersFlag,
+		utils.TxPoolTenantsFlag,
 		utils.FastSyncFlag,
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
+		utils.RoleFlag,
 		utils.GCModeFlag,
+		utils.SyncIngressFlag,
+		utils.SyncIngressBurstFlag,
+		utils.SyncEgressFlag,
+		utils.SyncEgressBurstFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
 		utils.LightKDFFlag,
+		utils.KeyStoreKDFFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptPFlag,
+		utils.KeyStoreArgon2TimeFlag,
+		utils.KeyStoreArgon2MemoryFlag,
+		utils.KeyStoreArgon2ThreadsFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
+		utils.CacheTrieFlag,
 		utils.TrieCacheGenFlag,
+		utils.SnapshotFlag,
+		utils.ReceiptRetentionFlag,
+		utils.TxWorkersFlag,
+		utils.HistoryCacheFlag,
+		utils.HistoryIntervalFlag,
+		utils.HistoryLifetimeFlag,
+		utils.AddressIndexFlag,
+		utils.InternalIndexFlag,
+		utils.BloomSectionRateFlag,
+		utils.BloomIOBudgetFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
+		utils.PeersAdaptiveFlag,
+		utils.PeersMinFlag,
+		utils.PeersRoleFlag,
 		utils.MaxPendingPeersFlag,
+		utils.RekeyIntervalFlag,
+		utils.BannedClientsFlag,
 		utils.EtherbaseFlag,
 		utils.GasPriceFlag,
 		utils.MinerThreadsFlag,
 		utils.MiningEnabledFlag,
 		utils.TargetGasLimitFlag,
+		utils.GasLimitTargetFlag,
+		utils.TxOrderCommitFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
+		utils.HeadBeaconFlag,
 		utils.NetrestrictFlag,
+		utils.FirewallInboundAllowFlag,
+		utils.FirewallInboundDenyFlag,
+		utils.FirewallOutboundAllowFlag,
+		utils.FirewallOutboundDenyFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
 		utils.TestnetFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMStatsFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
@@ -116,8 +157,12 @@ var (
 		utils.TracingSampleRateFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
+		utils.StatusBarFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoDepthWeightFlag,
+		utils.GpoLatencyWeightFlag,
+		utils.GpoLatencyTargetFlag,
 		utils.ExtraDataFlag,
 		utils.ArchiveEndpointFlag,
 		utils.ArchiveBucketFlag,
@@ -125,6 +170,30 @@ var (
 		utils.ArchiveSecretFlag,
 		utils.ArchiveAgeFlag,
 		utils.ArchivePeriodFlag,
+		utils.ArchiveRetentionFlag,
+		utils.ImportPolicyEndpointFlag,
+		utils.ImportPolicyTimeoutFlag,
+		utils.ImportPolicyFailOpenFlag,
+		utils.BlocklistAddressesFlag,
+		utils.BlocklistCodeHashesFlag,
+		utils.BlocklistAllowCallsFlag,
+		utils.WatchAddressesFlag,
+		utils.WatchConfirmationsFlag,
+		utils.ScheduleFlag,
+		utils.WatchdogThresholdFlag,
+		utils.WatchdogHaltFlag,
+		utils.ServeStateDiagFlag,
+		utils.DBSupervisorIntervalFlag,
+		utils.DBSupervisorRestartFlag,
+		utils.DBSupervisorResyncFlag,
+		utils.DBSupervisorSourceFlag,
+		utils.SQLMirrorDriverFlag,
+		utils.SQLMirrorDSNFlag,
+		utils.SQLMirrorConfirmationsFlag,
+		utils.HealthMinPeersFlag,
+		utils.HealthMaxSyncLagFlag,
+		utils.HealthMaxBlockAgeFlag,
+		utils.HealthMaxPoolFillFlag,
 		configFileFlag,
 	}
 
@@ -138,8 +207,13 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSCompressionFlag,
+		utils.WSMaxFrameSizeFlag,
+		utils.WSMaxMessageSizeFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
+		utils.FinalitySafeDepthFlag,
+		utils.FinalityFinalizedDepthFlag,
 	}
 
 	whisperFlags = []cli.Flag{
@@ -323,4 +397,7 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			utils.Fatalf("Failed to start mining: %v", err)
 		}
 	}
+	if ctx.GlobalBool(utils.StatusBarFlag.Name) {
+		startStatusBar(stack)
+	}
 }
@@ -0,0 +1,195 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/eth"
+	"github.com/fulcrumchain/indigo/internal/debug"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/log/term"
+	"github.com/fulcrumchain/indigo/node"
+)
+
+const (
+	statusRefresh    = time.Second // Interval between status area refreshes
+	statusRateWindow = 10          // Number of refreshes the import rate is averaged over
+)
+
+// Terminal colors of the status values.
+const (
+	colorRed    = 31
+	colorGreen  = 32
+	colorYellow = 33
+)
+
+// statusBar is a writer keeping a block of status lines at the bottom of the
+// terminal, below everything written through it.
+type statusBar struct {
+	out   io.Writer
+	lines []string // Status lines currently drawn
+	lock  sync.Mutex
+}
+
+// Write writes p above the status lines.
+func (s *statusBar) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.clear()
+	n, err := s.out.Write(p)
+	s.draw()
+	return n, err
+}
+
+// update replaces the status lines, or removes them if lines is empty.
+func (s *statusBar) update(lines []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.clear()
+	s.lines = lines
+	s.draw()
+}
+
+// clear erases the drawn status lines, leaving the cursor where they started.
+func (s *statusBar) clear() {
+	if len(s.lines) > 0 {
+		fmt.Fprintf(s.out, "\x1b[%dF\x1b[J", len(s.lines))
+	}
+}
+
+// draw prints the status lines, clipped to the width of the terminal so that
+// each takes up exactly one row.
+func (s *statusBar) draw() {
+	if len(s.lines) == 0 {
+		return
+	}
+	fmt.Fprint(s.out, "\x1b[?7l")
+	for _, line := range s.lines {
+		fmt.Fprintf(s.out, "%s\x1b[K\n", line)
+	}
+	fmt.Fprint(s.out, "\x1b[?7h")
+}
+
+// statusSample is a snapshot of the import counters.
+type statusSample struct {
+	time   time.Time
+	blocks uint64
+	txs    uint64
+}
+
+// startStatusBar shows the state of the node in a refreshing area below the
+// logs, until the node stops. The status area is only shown to terminals.
+func startStatusBar(stack *node.Node) {
+	if !term.IsTty(os.Stderr.Fd()) || os.Getenv("TERM") == "dumb" {
+		log.Warn("Status bar needs a terminal, disabling")
+		return
+	}
+	var indigo *eth.Indigo
+	if err := stack.Service(&indigo); err != nil {
+		log.Warn("Status bar needs a full node, disabling", "err", err)
+		return
+	}
+	bar := new(statusBar)
+	debug.RedirectLogs(func(out io.Writer) io.Writer {
+		bar.out = out
+		return bar
+	})
+	go runStatusBar(bar, stack, indigo)
+}
+
+// runStatusBar counts the imported blocks and refreshes the status area until
+// the node stops.
+func runStatusBar(bar *statusBar, stack *node.Node, indigo *eth.Indigo) {
+	events := make(chan core.ChainEvent, 64)
+	sub := indigo.BlockChain().SubscribeChainEvent(events)
+	defer sub.Unsubscribe()
+
+	quit := make(chan struct{})
+	go func() {
+		stack.Wait()
+		close(quit)
+	}()
+	refresh := time.NewTicker(statusRefresh)
+	defer refresh.Stop()
+
+	var (
+		blocks, txs uint64
+		samples     = []statusSample{{time: time.Now()}}
+	)
+	for {
+		select {
+		case ev := <-events:
+			blocks++
+			txs += uint64(len(ev.Block.Transactions()))
+
+		case now := <-refresh.C:
+			samples = append(samples, statusSample{time: now, blocks: blocks, txs: txs})
+			if len(samples) > statusRateWindow {
+				samples = samples[1:]
+			}
+			bar.update(renderStatus(stack, indigo, samples))
+
+		case <-quit:
+			bar.update(nil)
+			return
+		}
+	}
+}
+
+// renderStatus formats the status lines of the node: the chain head and sync
+// progress, followed by the peers, the transaction pool and the import rate.
+func renderStatus(stack *node.Node, indigo *eth.Indigo, samples []statusSample) []string {
+	head := indigo.BlockChain().CurrentBlock()
+	age := time.Since(time.Unix(head.Time().Int64(), 0)).Round(time.Second)
+
+	sync := colorize(colorGreen, "synced")
+	if progress := indigo.Downloader().Progress(); indigo.Downloader().Synchronising() && progress.HighestBlock > progress.CurrentBlock {
+		percent := 100 * float64(progress.CurrentBlock) / float64(progress.HighestBlock)
+		sync = colorize(colorYellow, fmt.Sprintf("syncing %.1f%% (%d/%d)", percent, progress.CurrentBlock, progress.HighestBlock))
+	}
+	peers, maxPeers := stack.Server().PeerCount(), stack.Server().MaxPeers
+	peerColor := colorGreen
+	if peers == 0 {
+		peerColor = colorRed
+	}
+	pending, queued := indigo.TxPool().Stats()
+
+	var blockRate, txRate float64
+	first, last := samples[0], samples[len(samples)-1]
+	if elapsed := last.time.Sub(first.time).Seconds(); elapsed > 0 {
+		blockRate = float64(last.blocks-first.blocks) / elapsed
+		txRate = float64(last.txs-first.txs) / elapsed
+	}
+	return []string{
+		fmt.Sprintf("head %s %x… %s ago │ %s", colorize(colorGreen, fmt.Sprintf("#%d", head.NumberU64())), head.Hash().Bytes()[:4], age, sync),
+		fmt.Sprintf("peers %s │ txpool %d pending, %d queued │ import %.2f blk/s, %.1f tx/s",
+			colorize(peerColor, fmt.Sprintf("%d/%d", peers, maxPeers)), pending, queued, blockRate, txRate),
+	}
+}
+
+// colorize wraps s in the escape sequences of a terminal color.
+func colorize(color int, s string) string {
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, s)
+}
@@ -0,0 +1,42 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Tests that output written through the status bar is printed above the status
+// lines, which are redrawn after it.
+func TestStatusBarWrite(t *testing.T) {
+	out := new(bytes.Buffer)
+	bar := &statusBar{out: out}
+
+	bar.Write([]byte("first\n"))
+	bar.update([]string{"a", "b"})
+	bar.Write([]byte("second\n"))
+	bar.update(nil)
+
+	want := "first\n" +
+		"\x1b[?7la\x1b[K\nb\x1b[K\n\x1b[?7h" +
+		"\x1b[2F\x1b[J" + "second\n" + "\x1b[?7la\x1b[K\nb\x1b[K\n\x1b[?7h" +
+		"\x1b[2F\x1b[J"
+	if have := out.String(); have != want {
+		t.Errorf("output mismatch:\nhave %q\nwant %q", have, want)
+	}
+}
@@ -71,15 +71,27 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
+			utils.ExternalSignerFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
+			utils.RoleFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.SyncIngressFlag,
+			utils.SyncIngressBurstFlag,
+			utils.SyncEgressFlag,
+			utils.SyncEgressBurstFlag,
 			utils.NetStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
 			utils.LightKDFFlag,
+			utils.KeyStoreKDFFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptPFlag,
+			utils.KeyStoreArgon2TimeFlag,
+			utils.KeyStoreArgon2MemoryFlag,
+			utils.KeyStoreArgon2ThreadsFlag,
 		},
 	},
 	{Name: "DEVELOPER CHAIN",
@@ -105,11 +117,16 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
 			utils.TxPoolPriceBumpFlag,
+			utils.TxPoolLocalSamePriceFlag,
 			utils.TxPoolAccountSlotsFlag,
 			utils.TxPoolGlobalSlotsFlag,
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
+			utils.TxPoolAccountQuotaFlag,
+			utils.TxPoolAccountQuotaBumpFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolLifetimeTiersFlag,
+			utils.TxPoolTenantsFlag,
 		},
 	},
 	{
@@ -118,7 +135,18 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheTrieFlag,
 			utils.TrieCacheGenFlag,
+			utils.SnapshotFlag,
+			utils.ReceiptRetentionFlag,
+			utils.TxWorkersFlag,
+			utils.HistoryCacheFlag,
+			utils.HistoryIntervalFlag,
+			utils.HistoryLifetimeFlag,
+			utils.AddressIndexFlag,
+			utils.InternalIndexFlag,
+			utils.BloomSectionRateFlag,
+			utils.BloomIOBudgetFlag,
 		},
 	},
 	{
@@ -140,6 +168,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSCompressionFlag,
+			utils.WSMaxFrameSizeFlag,
+			utils.WSMaxMessageSizeFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
@@ -147,6 +178,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
+			utils.FinalitySafeDepthFlag,
+			utils.FinalityFinalizedDepthFlag,
 		},
 	},
 	{
@@ -157,11 +190,21 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.BootnodesV5Flag,
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
+			utils.PeersAdaptiveFlag,
+			utils.PeersMinFlag,
+			utils.PeersRoleFlag,
 			utils.MaxPendingPeersFlag,
+			utils.RekeyIntervalFlag,
+			utils.BannedClientsFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
+			utils.HeadBeaconFlag,
 			utils.NetrestrictFlag,
+			utils.FirewallInboundAllowFlag,
+			utils.FirewallInboundDenyFlag,
+			utils.FirewallOutboundAllowFlag,
+			utils.FirewallOutboundDenyFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -173,6 +216,8 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MinerThreadsFlag,
 			utils.EtherbaseFlag,
 			utils.TargetGasLimitFlag,
+			utils.GasLimitTargetFlag,
+			utils.TxOrderCommitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
 		},
@@ -182,12 +227,16 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoDepthWeightFlag,
+			utils.GpoLatencyWeightFlag,
+			utils.GpoLatencyTargetFlag,
 		},
 	},
 	{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.VMStatsFlag,
 		},
 	},
 	{
@@ -198,6 +247,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TracingSampleRateFlag,
 			utils.FakePoWFlag,
 			utils.NoCompactionFlag,
+			utils.StatusBarFlag,
 		}, debug.Flags...),
 	},
 	{
@@ -220,6 +270,70 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.ArchiveSecretFlag,
 			utils.ArchiveAgeFlag,
 			utils.ArchivePeriodFlag,
+			utils.ArchiveRetentionFlag,
+		},
+	},
+	{
+		Name: "IMPORT POLICY",
+		Flags: []cli.Flag{
+			utils.ImportPolicyEndpointFlag,
+			utils.ImportPolicyTimeoutFlag,
+			utils.ImportPolicyFailOpenFlag,
+			utils.BlocklistAddressesFlag,
+			utils.BlocklistCodeHashesFlag,
+			utils.BlocklistAllowCallsFlag,
+		},
+	},
+	{
+		Name: "ADDRESS WATCHING",
+		Flags: []cli.Flag{
+			utils.WatchAddressesFlag,
+			utils.WatchConfirmationsFlag,
+		},
+	},
+	{
+		Name: "TRANSACTION SCHEDULING",
+		Flags: []cli.Flag{
+			utils.ScheduleFlag,
+		},
+	},
+	{
+		Name: "CHAIN WATCHDOG",
+		Flags: []cli.Flag{
+			utils.WatchdogThresholdFlag,
+			utils.WatchdogHaltFlag,
+		},
+	},
+	{
+		Name: "STATE DIAGNOSTICS",
+		Flags: []cli.Flag{
+			utils.ServeStateDiagFlag,
+		},
+	},
+	{
+		Name: "DATABASE SUPERVISOR",
+		Flags: []cli.Flag{
+			utils.DBSupervisorIntervalFlag,
+			utils.DBSupervisorRestartFlag,
+			utils.DBSupervisorResyncFlag,
+			utils.DBSupervisorSourceFlag,
+		},
+	},
+	{
+		Name: "SQL MIRROR",
+		Flags: []cli.Flag{
+			utils.SQLMirrorDriverFlag,
+			utils.SQLMirrorDSNFlag,
+			utils.SQLMirrorConfirmationsFlag,
+		},
+	},
+	{
+		Name: "HEALTH CHECKS",
+		Flags: []cli.Flag{
+			utils.HealthMinPeersFlag,
+			utils.HealthMaxSyncLagFlag,
+			utils.HealthMaxBlockAgeFlag,
+			utils.HealthMaxPoolFillFlag,
 		},
 	},
 	{
@@ -211,6 +211,22 @@ func cmdLineOverride(currentConfig *bzzapi.Config, ctx *cli.Context) *bzzapi.Con
 		currentConfig.BootNodes = ctx.GlobalString(utils.BootnodesFlag.Name)
 	}
 
+	if endpoint := ctx.GlobalString(utils.ArchiveEndpointFlag.Name); endpoint != "" {
+		currentConfig.Archive.Endpoint = endpoint
+	}
+
+	if bucket := ctx.GlobalString(utils.ArchiveBucketFlag.Name); bucket != "" {
+		currentConfig.Archive.Bucket = bucket
+	}
+
+	if id := ctx.GlobalString(utils.ArchiveIDFlag.Name); id != "" {
+		currentConfig.Archive.ID = id
+	}
+
+	if secret := ctx.GlobalString(utils.ArchiveSecretFlag.Name); secret != "" {
+		currentConfig.Archive.Secret = secret
+	}
+
 	return currentConfig
 
 }
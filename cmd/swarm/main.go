@@ -340,6 +340,10 @@ DEPRECATED: use 'swarm db clean'.
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 		utils.PasswordFileFlag,
+		utils.ArchiveEndpointFlag,
+		utils.ArchiveBucketFlag,
+		utils.ArchiveIDFlag,
+		utils.ArchiveSecretFlag,
 		// bzzd-specific flags
 		CorsStringFlag,
 		EnsAPIFlag,
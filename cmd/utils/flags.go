@@ -33,9 +33,11 @@ import (
 	"github.com/fulcrumchain/indigo/accounts/keystore"
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/fdlimit"
+	"github.com/fulcrumchain/indigo/common/hexutil"
 	"github.com/fulcrumchain/indigo/consensus"
 	"github.com/fulcrumchain/indigo/consensus/clique"
 	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/importhook"
 	"github.com/fulcrumchain/indigo/core/state"
 	"github.com/fulcrumchain/indigo/core/vm"
 	"github.com/fulcrumchain/indigo/crypto"
@@ -55,6 +57,7 @@ import (
 	"github.com/fulcrumchain/indigo/p2p/nat"
 	"github.com/fulcrumchain/indigo/p2p/netutil"
 	"github.com/fulcrumchain/indigo/params"
+	"github.com/fulcrumchain/indigo/rpc"
 	whisper "github.com/fulcrumchain/indigo/whisper/whisperv5"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -126,6 +129,10 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer (url or path to ipc file) to delegate signing to",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 60=mainnet, 31337=testnet)",
@@ -167,11 +174,31 @@ var (
 		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
 		Value: &defaultSyncMode,
 	}
+	RoleFlag = cli.StringFlag{
+		Name:  "role",
+		Usage: `Node role preset, overridden by the config file and other flags ("validator", "rpc", "archive", "light-gateway")`,
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	SyncIngressFlag = cli.Uint64Flag{
+		Name:  "sync.ingress",
+		Usage: "Maximum inbound sync traffic in bytes/sec (0 = unlimited)",
+	}
+	SyncIngressBurstFlag = cli.Uint64Flag{
+		Name:  "sync.ingressburst",
+		Usage: "Inbound sync traffic allowed in excess of the rate in bytes (0 = one second worth)",
+	}
+	SyncEgressFlag = cli.Uint64Flag{
+		Name:  "sync.egress",
+		Usage: "Maximum outbound sync traffic in bytes/sec (0 = unlimited)",
+	}
+	SyncEgressBurstFlag = cli.Uint64Flag{
+		Name:  "sync.egressburst",
+		Usage: "Outbound sync traffic allowed in excess of the rate in bytes (0 = one second worth)",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -186,6 +213,31 @@ var (
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	KeyStoreKDFFlag = cli.StringFlag{
+		Name:  "keystore.kdf",
+		Usage: `Key-derivation function of new key files ("scrypt" or "argon2id")`,
+		Value: keystore.KDFScrypt,
+	}
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.n",
+		Usage: "Scrypt CPU/memory cost parameter N of new key files (0 = standard)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.p",
+		Usage: "Scrypt parallelization parameter P of new key files (0 = standard)",
+	}
+	KeyStoreArgon2TimeFlag = cli.UintFlag{
+		Name:  "keystore.argon2.time",
+		Usage: "Argon2id number of passes of new key files (0 = standard)",
+	}
+	KeyStoreArgon2MemoryFlag = cli.UintFlag{
+		Name:  "keystore.argon2.memory",
+		Usage: "Argon2id memory in KiB of new key files (0 = standard)",
+	}
+	KeyStoreArgon2ThreadsFlag = cli.UintFlag{
+		Name:  "keystore.argon2.threads",
+		Usage: "Argon2id parallelism of new key files (0 = standard)",
+	}
 	// Dashboard settings
 	DashboardEnabledFlag = cli.BoolFlag{
 		Name:  "dashboard",
@@ -231,6 +283,10 @@ var (
 		Usage: "Price bump percentage to replace an already existing transaction",
 		Value: eth.DefaultConfig.TxPool.PriceBump,
 	}
+	TxPoolLocalSamePriceFlag = cli.BoolFlag{
+		Name:  "txpool.localsameprice",
+		Usage: "Allow local transactions to be replaced without raising the gas price",
+	}
 	TxPoolAccountSlotsFlag = cli.Uint64Flag{
 		Name:  "txpool.accountslots",
 		Usage: "Minimum number of executable transaction slots guaranteed per account",
@@ -251,11 +307,29 @@ var (
 		Usage: "Maximum number of non-executable transaction slots for all accounts",
 		Value: eth.DefaultConfig.TxPool.GlobalQueue,
 	}
+	TxPoolAccountQuotaFlag = cli.Uint64Flag{
+		Name:  "txpool.accountquota",
+		Usage: "Maximum number of pending and queued transactions per account, including those submitted over RPC (0 = no limit)",
+		Value: eth.DefaultConfig.TxPool.AccountQuota,
+	}
+	TxPoolAccountQuotaBumpFlag = cli.Uint64Flag{
+		Name:  "txpool.accountquotabump",
+		Usage: "Gas price bump percentage over an account's highest priced transaction to exceed its quota (0 = never)",
+		Value: eth.DefaultConfig.TxPool.AccountQuotaBump,
+	}
 	TxPoolLifetimeFlag = cli.DurationFlag{
 		Name:  "txpool.lifetime",
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: eth.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolLifetimeTiersFlag = cli.StringFlag{
+		Name:  "txpool.lifetimetiers",
+		Usage: "Queue lifetimes of groups of accounts, local ones included, 0 for never expiring (e.g. partners=24h:0x1f..+0x2e..,bots=5m:0x3d..)",
+	}
+	TxPoolTenantsFlag = cli.StringFlag{
+		Name:  "txpool.tenants",
+		Usage: "Pooled transaction quotas and inclusion weights of RPC tenants (e.g. alice=64:2,bob=16)",
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -272,11 +346,59 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	CacheTrieFlag = cli.IntFlag{
+		Name:  "cache.trie",
+		Usage: "Megabytes of memory allocated to the clean trie node cache shared by all state readers (0 = disabled)",
+		Value: eth.DefaultConfig.TrieClean,
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
 		Value: int(state.MaxTrieCacheGen),
 	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Maintain a flat state snapshot to speed up state reads on recent blocks",
+	}
+	ReceiptRetentionFlag = cli.Uint64Flag{
+		Name:  "receiptretention",
+		Usage: "Number of recent blocks to retain receipts and logs for (0 = all)",
+	}
+	HistoryCacheFlag = cli.IntFlag{
+		Name:  "cache.history",
+		Usage: "Megabytes of memory allocated to caching the states of popular historical blocks (0 = disabled)",
+	}
+	HistoryIntervalFlag = cli.Uint64Flag{
+		Name:  "cache.history.interval",
+		Usage: "Cache the states of the historical blocks whose numbers are multiples of this",
+		Value: eth.DefaultConfig.HistoryInterval,
+	}
+	HistoryLifetimeFlag = cli.DurationFlag{
+		Name:  "cache.history.lifetime",
+		Usage: "Maximum amount of time a historical state is cached",
+		Value: eth.DefaultConfig.HistoryLifetime,
+	}
+	TxWorkersFlag = cli.IntFlag{
+		Name:  "txworkers",
+		Usage: "Number of workers executing independent block transactions in parallel (0 or 1 = serial)",
+		Value: eth.DefaultConfig.TxWorkers,
+	}
+	AddressIndexFlag = cli.BoolFlag{
+		Name:  "addrindex",
+		Usage: "Index transactions by sender and recipient address (enables eth_getTransactionsByAddress)",
+	}
+	InternalIndexFlag = cli.BoolFlag{
+		Name:  "internalindex",
+		Usage: "Index value transfers made by contracts by sender and recipient address (enables eth_getInternalTransactions, requires --gcmode=archive)",
+	}
+	BloomSectionRateFlag = cli.Float64Flag{
+		Name:  "bloom.sectionrate",
+		Usage: "Maximum log bloom sections indexed per second while catching up (0 = unlimited)",
+	}
+	BloomIOBudgetFlag = cli.Uint64Flag{
+		Name:  "bloom.iobudget",
+		Usage: "Disk traffic in MB per second the node may use while catching up on log bloom indexing (0 = unlimited)",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -289,7 +411,15 @@ var (
 	}
 	TargetGasLimitFlag = cli.Uint64Flag{
 		Name:  "targetgaslimit",
-		Usage: "Target gas limit sets the artificial target gas floor for the blocks to mine",
+		Usage: "Target gas limit sets the artificial target gas floor for the blocks to mine, above which the limit follows usage",
+	}
+	GasLimitTargetFlag = cli.Uint64Flag{
+		Name:  "miner.gaslimittarget",
+		Usage: "Gas limit the sealed blocks move toward, regardless of usage and --targetgaslimit, refused if the chain configuration defines a gas limit (0 = usage based)",
+	}
+	TxOrderCommitFlag = cli.BoolFlag{
+		Name:  "txordercommit",
+		Usage: "Seal transactions in order of arrival, committing to the arrival stamped candidates in the block extra-data",
 	}
 	EtherbaseFlag = cli.StringFlag{
 		Name:  "etherbase",
@@ -321,6 +451,10 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMStatsFlag = cli.BoolFlag{
+		Name:  "vmstats",
+		Usage: "Aggregate opcode and contract gas statistics of block processing (debug_vmStats)",
+	}
 	// Logging and debug settings
 	NetStatsURLFlag = cli.StringFlag{
 		Name:  "netstats",
@@ -346,6 +480,10 @@ var (
 		Name:  "nocompaction",
 		Usage: "Disables db compaction after import",
 	}
+	StatusBarFlag = cli.BoolFlag{
+		Name:  "statusbar",
+		Usage: "Show a refreshing node status area below the logs when running in a terminal",
+	}
 	// RPC settings
 	RPCEnabledFlag = cli.BoolFlag{
 		Name:  "rpc",
@@ -408,6 +546,20 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSCompressionFlag = cli.BoolFlag{
+		Name:  "wscompression",
+		Usage: "Compress WS-RPC messages for the clients offering permessage-deflate",
+	}
+	WSMaxFrameSizeFlag = cli.Int64Flag{
+		Name:  "wsmaxframe",
+		Usage: "Maximum size in bytes of a frame received over WS-RPC",
+		Value: rpc.DefaultWebsocketOptions.MaxFrameSize,
+	}
+	WSMaxMessageSizeFlag = cli.Int64Flag{
+		Name:  "wsmaxmessage",
+		Usage: "Maximum size in bytes of a (decompressed) message received over WS-RPC",
+		Value: rpc.DefaultWebsocketOptions.MaxMessageSize,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -423,11 +575,34 @@ var (
 		Usage: "Maximum number of network peers (network disabled if set to 0)",
 		Value: 25,
 	}
+	PeersAdaptiveFlag = cli.BoolFlag{
+		Name:  "peers.adaptive",
+		Usage: "Lower the eth peer target under high CPU or disk load, and raise it back when idle",
+	}
+	PeersMinFlag = cli.IntFlag{
+		Name:  "peers.min",
+		Usage: "Minimum eth peer target when scaling with the load",
+		Value: 0,
+	}
+	PeersRoleFlag = cli.StringFlag{
+		Name:  "peers.role",
+		Usage: `Node role bounding the eth peer target ("sealer", "observer" or "archive", derived if empty)`,
+		Value: "",
+	}
 	MaxPendingPeersFlag = cli.IntFlag{
 		Name:  "maxpendpeers",
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: 0,
 	}
+	RekeyIntervalFlag = cli.DurationFlag{
+		Name:  "rekeyinterval",
+		Usage: "Period after which dialed peer connections renegotiate their session keys (0 = disabled)",
+		Value: node.DefaultConfig.P2P.RekeyInterval,
+	}
+	BannedClientsFlag = cli.StringFlag{
+		Name:  "bannedclients",
+		Usage: "Comma separated regular expressions of peer client names to reject at handshake",
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
@@ -469,10 +644,30 @@ var (
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 	}
+	HeadBeaconFlag = cli.DurationFlag{
+		Name:  "beacon.interval",
+		Usage: "Interval of the chain head announcements to discovery nodes (0 = disabled)",
+	}
 	NetrestrictFlag = cli.StringFlag{
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	FirewallInboundAllowFlag = cli.StringFlag{
+		Name:  "firewall.inbound.allow",
+		Usage: "Only accept peer connections from the given IP networks (CIDR masks)",
+	}
+	FirewallInboundDenyFlag = cli.StringFlag{
+		Name:  "firewall.inbound.deny",
+		Usage: "Reject peer connections from the given IP networks (CIDR masks)",
+	}
+	FirewallOutboundAllowFlag = cli.StringFlag{
+		Name:  "firewall.outbound.allow",
+		Usage: "Only dial peers in the given IP networks (CIDR masks)",
+	}
+	FirewallOutboundDenyFlag = cli.StringFlag{
+		Name:  "firewall.outbound.deny",
+		Usage: "Never dial peers in the given IP networks (CIDR masks)",
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -492,6 +687,19 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: eth.DefaultConfig.GPO.Percentile,
 	}
+	GpoDepthWeightFlag = cli.Float64Flag{
+		Name:  "gpodepthweight",
+		Usage: "Gas price increase per block of pending transaction gas in excess of a block (0 = disabled)",
+	}
+	GpoLatencyWeightFlag = cli.Float64Flag{
+		Name:  "gpolatencyweight",
+		Usage: "Gas price increase per latency target transactions wait for inclusion in excess of it (0 = disabled)",
+	}
+	GpoLatencyTargetFlag = cli.DurationFlag{
+		Name:  "gpolatencytarget",
+		Usage: "Inclusion latency of pending transactions considered uncongested",
+		Value: eth.DefaultConfig.GPO.LatencyTarget,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -534,6 +742,138 @@ var (
 		Usage: "How often the archive process runs.",
 		Value: archive.DefaultArchivePeriod,
 	}
+	ArchiveRetentionFlag = cli.StringFlag{
+		Name:  "archiveretention",
+		Usage: "Per type archive ages overriding --archiveage, e.g. \"receipts=1000000,body=500000,header=never\".",
+	}
+
+	// Import policy settings
+	ImportPolicyEndpointFlag = cli.StringFlag{
+		Name:  "importpolicy",
+		Usage: "gRPC endpoint (host:port) of an external policy service screening imported blocks and transactions",
+	}
+	ImportPolicyTimeoutFlag = cli.DurationFlag{
+		Name:  "importpolicy.timeout",
+		Usage: "Maximum time to wait for a verdict from the import policy service",
+		Value: importhook.DefaultConfig.Timeout,
+	}
+	ImportPolicyFailOpenFlag = cli.BoolFlag{
+		Name:  "importpolicy.failopen",
+		Usage: "Accept blocks and transactions if the import policy service fails or times out (default rejects them)",
+	}
+
+	// Contract blocklist settings
+	BlocklistAddressesFlag = cli.StringFlag{
+		Name:  "blocklist.addresses",
+		Usage: "Comma separated contract addresses the transaction pool and eth_call refuse to interact with",
+	}
+	BlocklistCodeHashesFlag = cli.StringFlag{
+		Name:  "blocklist.codehashes",
+		Usage: "Comma separated code hashes of contracts the transaction pool and eth_call refuse to interact with",
+	}
+	BlocklistAllowCallsFlag = cli.BoolFlag{
+		Name:  "blocklist.allowcalls",
+		Usage: "Allow eth_call to execute blocklisted contracts",
+	}
+
+	// Block tag settings
+	FinalitySafeDepthFlag = cli.Uint64Flag{
+		Name:  "finality.safedepth",
+		Usage: "Number of blocks below the head selected by the \"safe\" block tag",
+		Value: eth.DefaultConfig.Finality.SafeDepth,
+	}
+	FinalityFinalizedDepthFlag = cli.Uint64Flag{
+		Name:  "finality.finalizeddepth",
+		Usage: "Number of blocks below the head selected by the \"finalized\" block tag",
+		Value: eth.DefaultConfig.Finality.FinalizedDepth,
+	}
+
+	// Address watching settings
+	WatchAddressesFlag = cli.StringFlag{
+		Name:  "watch.addresses",
+		Usage: "Comma separated addresses whose native and ERC-20 credits and debits are tracked (enables the watch API)",
+	}
+	WatchConfirmationsFlag = cli.Uint64Flag{
+		Name:  "watch.confirmations",
+		Usage: "Number of blocks built on top of a block before the events of the watched addresses in it are reported",
+	}
+
+	// Transaction scheduling settings
+	ScheduleFlag = cli.BoolFlag{
+		Name:  "schedule",
+		Usage: "Hold signed transactions until a broadcast block or time (enables the schedule API)",
+	}
+
+	// Chain watchdog settings
+	WatchdogThresholdFlag = cli.Float64Flag{
+		Name:  "watchdog.threshold",
+		Usage: "Fraction of peers with another genesis or an incompatible fork raising the chain watchdog alarm (0 = disabled)",
+	}
+	WatchdogHaltFlag = cli.BoolFlag{
+		Name:  "watchdog.halt",
+		Usage: "Refuse to seal blocks while the chain watchdog alarm is raised",
+	}
+
+	// State diagnostics settings
+	ServeStateDiagFlag = cli.BoolFlag{
+		Name:  "statediag.serve",
+		Usage: "Execute recent blocks again for peers diagnosing a state root mismatch",
+	}
+
+	// Database supervisor settings
+	DBSupervisorIntervalFlag = cli.DurationFlag{
+		Name:  "dbsupervisor.interval",
+		Usage: "Interval of the chain database integrity checks (0 = disabled)",
+	}
+	DBSupervisorRestartFlag = cli.BoolFlag{
+		Name:  "dbsupervisor.restart",
+		Usage: "Shut down on chain database corruption, for the process manager to restart the node",
+	}
+	DBSupervisorResyncFlag = cli.BoolFlag{
+		Name:  "dbsupervisor.resync",
+		Usage: "Quarantine a chain database marked corrupted on start and sync again, instead of refusing to start",
+	}
+	DBSupervisorSourceFlag = DirectoryFlag{
+		Name:  "dbsupervisor.source",
+		Usage: "Directory of exported chain segments imported on resync, before syncing from peers",
+	}
+
+	// SQL mirror settings
+	SQLMirrorDriverFlag = cli.StringFlag{
+		Name:  "sqlmirror.driver",
+		Usage: "Driver of the database mirroring the canonical chain metadata (sqlite3, postgres)",
+		Value: eth.DefaultConfig.SQLMirror.Driver,
+	}
+	SQLMirrorDSNFlag = cli.StringFlag{
+		Name:  "sqlmirror.dsn",
+		Usage: "Data source name of the database mirroring the canonical chain metadata (empty = disabled)",
+	}
+	SQLMirrorConfirmationsFlag = cli.Uint64Flag{
+		Name:  "sqlmirror.confirmations",
+		Usage: "Number of blocks built on top of a block before it is mirrored",
+	}
+
+	// Health check settings
+	HealthMinPeersFlag = cli.IntFlag{
+		Name:  "health.minpeers",
+		Usage: "Minimum number of peers for the node to report ready on /ready (0 = unchecked)",
+		Value: eth.DefaultConfig.Health.MinPeers,
+	}
+	HealthMaxSyncLagFlag = cli.Uint64Flag{
+		Name:  "health.maxsynclag",
+		Usage: "Maximum number of blocks behind the highest known one for the node to report ready (0 = unchecked)",
+		Value: eth.DefaultConfig.Health.MaxSyncLag,
+	}
+	HealthMaxBlockAgeFlag = cli.DurationFlag{
+		Name:  "health.maxblockage",
+		Usage: "Maximum age of the head block for the node to report ready (0 = unchecked)",
+		Value: eth.DefaultConfig.Health.MaxBlockAge,
+	}
+	HealthMaxPoolFillFlag = cli.Float64Flag{
+		Name:  "health.maxpoolfill",
+		Usage: "Maximum fraction of the transaction pool in use for the node to report ready (0 = unchecked)",
+		Value: eth.DefaultConfig.Health.MaxPoolFill,
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -679,6 +1019,15 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSCompressionFlag.Name) {
+		cfg.WSCompression = ctx.GlobalBool(WSCompressionFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSMaxFrameSizeFlag.Name) {
+		cfg.WSMaxFrameSize = ctx.GlobalInt64(WSMaxFrameSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSMaxMessageSizeFlag.Name) {
+		cfg.WSMaxMessageSize = ctx.GlobalInt64(WSMaxMessageSizeFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -766,6 +1115,34 @@ func MakePasswordList(ctx *cli.Context) []string {
 	return lines
 }
 
+// setFirewall creates the connection rules from the command line flags,
+// leaving the configured rules untouched if none are given.
+func setFirewall(ctx *cli.Context, cfg *p2p.Config) {
+	rules := new(netutil.ConnPolicy)
+	if cfg.Firewall != nil {
+		rules = cfg.Firewall.Copy()
+	}
+	set := false
+	for flag, list := range map[string]*netutil.Netlist{
+		FirewallInboundAllowFlag.Name:  &rules.InboundAllow,
+		FirewallInboundDenyFlag.Name:   &rules.InboundDeny,
+		FirewallOutboundAllowFlag.Name: &rules.OutboundAllow,
+		FirewallOutboundDenyFlag.Name:  &rules.OutboundDeny,
+	} {
+		if !ctx.GlobalIsSet(flag) {
+			continue
+		}
+		masks, err := netutil.ParseNetlist(ctx.GlobalString(flag))
+		if err != nil {
+			Fatalf("Option %q: %v", flag, err)
+		}
+		*list, set = *masks, true
+	}
+	if set {
+		cfg.Firewall = rules
+	}
+}
+
 func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	setNodeKey(ctx, cfg)
 	setNAT(ctx, cfg)
@@ -798,6 +1175,17 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(BannedClientsFlag.Name) {
+		cfg.BannedClients = nil
+		for _, pattern := range strings.Split(ctx.GlobalString(BannedClientsFlag.Name), ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				cfg.BannedClients = append(cfg.BannedClients, pattern)
+			}
+		}
+	}
+	if ctx.GlobalIsSet(RekeyIntervalFlag.Name) {
+		cfg.RekeyInterval = ctx.GlobalDuration(RekeyIntervalFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
@@ -819,6 +1207,7 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	setFirewall(ctx, cfg)
 
 	if ctx.GlobalBool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -852,9 +1241,13 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	setKeyStoreKDF(ctx, &cfg.KeyStoreKDF)
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -864,6 +1257,15 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoDepthWeightFlag.Name) {
+		cfg.DepthWeight = ctx.GlobalFloat64(GpoDepthWeightFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoLatencyWeightFlag.Name) {
+		cfg.LatencyWeight = ctx.GlobalFloat64(GpoLatencyWeightFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoLatencyTargetFlag.Name) {
+		cfg.LatencyTarget = ctx.GlobalDuration(GpoLatencyTargetFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -882,6 +1284,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolPriceBumpFlag.Name) {
 		cfg.PriceBump = ctx.GlobalUint64(TxPoolPriceBumpFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolLocalSamePriceFlag.Name) {
+		cfg.LocalSamePriceReplace = ctx.GlobalBool(TxPoolLocalSamePriceFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolAccountSlotsFlag.Name) {
 		cfg.AccountSlots = ctx.GlobalUint64(TxPoolAccountSlotsFlag.Name)
 	}
@@ -894,9 +1299,59 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolGlobalQueueFlag.Name) {
 		cfg.GlobalQueue = ctx.GlobalUint64(TxPoolGlobalQueueFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolAccountQuotaFlag.Name) {
+		cfg.AccountQuota = ctx.GlobalUint64(TxPoolAccountQuotaFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPoolAccountQuotaBumpFlag.Name) {
+		cfg.AccountQuotaBump = ctx.GlobalUint64(TxPoolAccountQuotaBumpFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.GlobalDuration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolLifetimeTiersFlag.Name) {
+		tiers, err := core.ParseLifetimeTiers(ctx.GlobalString(TxPoolLifetimeTiersFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", TxPoolLifetimeTiersFlag.Name, err)
+		}
+		cfg.LifetimeTiers = tiers
+	}
+	if ctx.GlobalIsSet(TxPoolTenantsFlag.Name) {
+		tenants, err := core.ParseTenantQuotas(ctx.GlobalString(TxPoolTenantsFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", TxPoolTenantsFlag.Name, err)
+		}
+		cfg.Tenants = tenants
+	}
+}
+
+// setKeyStoreKDF configures the key derivation of new key files from the
+// command line flags.
+func setKeyStoreKDF(ctx *cli.Context, cfg *keystore.KDFConfig) {
+	if ctx.GlobalIsSet(KeyStoreKDFFlag.Name) {
+		cfg.KDF = ctx.GlobalString(KeyStoreKDFFlag.Name)
+		if err := cfg.Validate(); err != nil {
+			Fatalf("Option %q: %v", KeyStoreKDFFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		cfg.ScryptN = ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		cfg.ScryptP = ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreArgon2TimeFlag.Name) {
+		cfg.Argon2Time = uint32(ctx.GlobalUint(KeyStoreArgon2TimeFlag.Name))
+	}
+	if ctx.GlobalIsSet(KeyStoreArgon2MemoryFlag.Name) {
+		cfg.Argon2Memory = uint32(ctx.GlobalUint(KeyStoreArgon2MemoryFlag.Name))
+	}
+	if ctx.GlobalIsSet(KeyStoreArgon2ThreadsFlag.Name) {
+		threads := ctx.GlobalUint(KeyStoreArgon2ThreadsFlag.Name)
+		if threads > 255 {
+			Fatalf("Option %q: must be at most 255", KeyStoreArgon2ThreadsFlag.Name)
+		}
+		cfg.Argon2Threads = uint8(threads)
+	}
 }
 
 func setArchive(ctx *cli.Context, cfg *archive.Config) {
@@ -918,6 +1373,62 @@ func setArchive(ctx *cli.Context, cfg *archive.Config) {
 	if ctx.GlobalIsSet(ArchivePeriodFlag.Name) {
 		cfg.Period = ctx.GlobalDuration(ArchivePeriodFlag.Name)
 	}
+	if ctx.GlobalIsSet(ArchiveRetentionFlag.Name) {
+		retention, err := archive.ParseRetention(ctx.GlobalString(ArchiveRetentionFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", ArchiveRetentionFlag.Name, err)
+		}
+		cfg.Retention = retention
+	}
+}
+
+func setImportPolicy(ctx *cli.Context, cfg *importhook.Config) {
+	if ctx.GlobalIsSet(ImportPolicyEndpointFlag.Name) {
+		cfg.Endpoint = ctx.GlobalString(ImportPolicyEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(ImportPolicyTimeoutFlag.Name) {
+		cfg.Timeout = ctx.GlobalDuration(ImportPolicyTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(ImportPolicyFailOpenFlag.Name) {
+		cfg.FailOpen = ctx.GlobalBool(ImportPolicyFailOpenFlag.Name)
+	}
+}
+
+func setBlocklist(ctx *cli.Context, cfg *core.BlocklistConfig) {
+	if ctx.GlobalIsSet(BlocklistAddressesFlag.Name) {
+		for _, addr := range strings.Split(ctx.GlobalString(BlocklistAddressesFlag.Name), ",") {
+			if addr = strings.TrimSpace(addr); !common.IsHexAddress(addr) {
+				Fatalf("Invalid blocklist address %q", addr)
+			}
+			cfg.Addresses = append(cfg.Addresses, common.HexToAddress(addr))
+		}
+	}
+	if ctx.GlobalIsSet(BlocklistCodeHashesFlag.Name) {
+		for _, hash := range strings.Split(ctx.GlobalString(BlocklistCodeHashesFlag.Name), ",") {
+			blob, err := hexutil.Decode(strings.TrimSpace(hash))
+			if err != nil || len(blob) != common.HashLength {
+				Fatalf("Invalid blocklist code hash %q", hash)
+			}
+			cfg.CodeHashes = append(cfg.CodeHashes, common.BytesToHash(blob))
+		}
+	}
+	if ctx.GlobalIsSet(BlocklistAllowCallsFlag.Name) {
+		cfg.AllowCalls = ctx.GlobalBool(BlocklistAllowCallsFlag.Name)
+	}
+}
+
+func setWatch(ctx *cli.Context, cfg *eth.WatchConfig) {
+	if ctx.GlobalIsSet(WatchAddressesFlag.Name) {
+		for _, addr := range strings.Split(ctx.GlobalString(WatchAddressesFlag.Name), ",") {
+			if addr = strings.TrimSpace(addr); !common.IsHexAddress(addr) {
+				Fatalf("Invalid watched address %q", addr)
+			}
+			cfg.Addresses = append(cfg.Addresses, common.HexToAddress(addr))
+		}
+	}
+	if ctx.GlobalIsSet(WatchConfirmationsFlag.Name) {
+		cfg.Confirmations = ctx.GlobalUint64(WatchConfirmationsFlag.Name)
+	}
 }
 
 // checkExclusive verifies that only a single isntance of the provided flags was
@@ -981,6 +1492,57 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setArchive(ctx, &cfg.Archive)
+	setImportPolicy(ctx, &cfg.ImportPolicy)
+	setBlocklist(ctx, &cfg.Blocklist)
+	setWatch(ctx, &cfg.Watch)
+	if ctx.GlobalIsSet(ScheduleFlag.Name) {
+		cfg.Schedule = ctx.GlobalBool(ScheduleFlag.Name)
+	}
+	if ctx.GlobalIsSet(HeadBeaconFlag.Name) {
+		cfg.HeadBeacon = ctx.GlobalDuration(HeadBeaconFlag.Name)
+	}
+	if ctx.GlobalIsSet(WatchdogThresholdFlag.Name) {
+		cfg.Watchdog.Threshold = ctx.GlobalFloat64(WatchdogThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(WatchdogHaltFlag.Name) {
+		cfg.Watchdog.Halt = ctx.GlobalBool(WatchdogHaltFlag.Name)
+	}
+	if ctx.GlobalIsSet(ServeStateDiagFlag.Name) {
+		cfg.ServeStateDiag = ctx.GlobalBool(ServeStateDiagFlag.Name)
+	}
+	if ctx.GlobalIsSet(DBSupervisorIntervalFlag.Name) {
+		cfg.DBSupervisor.Interval = ctx.GlobalDuration(DBSupervisorIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(DBSupervisorRestartFlag.Name) {
+		cfg.DBSupervisor.Restart = ctx.GlobalBool(DBSupervisorRestartFlag.Name)
+	}
+	if ctx.GlobalIsSet(DBSupervisorResyncFlag.Name) {
+		cfg.DBSupervisor.Resync = ctx.GlobalBool(DBSupervisorResyncFlag.Name)
+	}
+	if ctx.GlobalIsSet(DBSupervisorSourceFlag.Name) {
+		cfg.DBSupervisor.Source = ctx.GlobalString(DBSupervisorSourceFlag.Name)
+	}
+	if ctx.GlobalIsSet(SQLMirrorDriverFlag.Name) {
+		cfg.SQLMirror.Driver = ctx.GlobalString(SQLMirrorDriverFlag.Name)
+	}
+	if ctx.GlobalIsSet(SQLMirrorDSNFlag.Name) {
+		cfg.SQLMirror.DSN = ctx.GlobalString(SQLMirrorDSNFlag.Name)
+	}
+	if ctx.GlobalIsSet(SQLMirrorConfirmationsFlag.Name) {
+		cfg.SQLMirror.Confirmations = ctx.GlobalUint64(SQLMirrorConfirmationsFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthMinPeersFlag.Name) {
+		cfg.Health.MinPeers = ctx.GlobalInt(HealthMinPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthMaxSyncLagFlag.Name) {
+		cfg.Health.MaxSyncLag = ctx.GlobalUint64(HealthMaxSyncLagFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthMaxBlockAgeFlag.Name) {
+		cfg.Health.MaxBlockAge = ctx.GlobalDuration(HealthMaxBlockAgeFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthMaxPoolFillFlag.Name) {
+		cfg.Health.MaxPoolFill = ctx.GlobalFloat64(HealthMaxPoolFillFlag.Name)
+	}
 
 	switch {
 	case ctx.GlobalIsSet(SyncModeFlag.Name):
@@ -999,20 +1561,86 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
+	if ctx.GlobalIsSet(PeersAdaptiveFlag.Name) {
+		cfg.PeerScaling.Adaptive = ctx.GlobalBool(PeersAdaptiveFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeersMinFlag.Name) {
+		cfg.PeerScaling.MinPeers = ctx.GlobalInt(PeersMinFlag.Name)
+	}
+	if ctx.GlobalIsSet(PeersRoleFlag.Name) {
+		cfg.PeerScaling.Role = ctx.GlobalString(PeersRoleFlag.Name)
+	}
+	if ctx.GlobalIsSet(FinalitySafeDepthFlag.Name) {
+		cfg.Finality.SafeDepth = ctx.GlobalUint64(FinalitySafeDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(FinalityFinalizedDepthFlag.Name) {
+		cfg.Finality.FinalizedDepth = ctx.GlobalUint64(FinalityFinalizedDepthFlag.Name)
+	}
+	if cfg.Finality.SafeDepth > cfg.Finality.FinalizedDepth {
+		Fatalf("--%s must not exceed --%s", FinalitySafeDepthFlag.Name, FinalityFinalizedDepthFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	if ctx.GlobalIsSet(GCModeFlag.Name) {
+		if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
+			Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+		}
+		cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	}
+
+	if ctx.GlobalIsSet(SyncIngressFlag.Name) {
+		cfg.SyncBandwidth.Ingress = ctx.GlobalUint64(SyncIngressFlag.Name)
+	}
+	if ctx.GlobalIsSet(SyncIngressBurstFlag.Name) {
+		cfg.SyncBandwidth.IngressBurst = ctx.GlobalUint64(SyncIngressBurstFlag.Name)
+	}
+	if ctx.GlobalIsSet(SyncEgressFlag.Name) {
+		cfg.SyncBandwidth.Egress = ctx.GlobalUint64(SyncEgressFlag.Name)
+	}
+	if ctx.GlobalIsSet(SyncEgressBurstFlag.Name) {
+		cfg.SyncBandwidth.EgressBurst = ctx.GlobalUint64(SyncEgressBurstFlag.Name)
 	}
-	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(CacheTrieFlag.Name) {
+		cfg.TrieClean = ctx.GlobalInt(CacheTrieFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalBool(SnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReceiptRetentionFlag.Name) {
+		cfg.ReceiptRetention = ctx.GlobalUint64(ReceiptRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxWorkersFlag.Name) {
+		cfg.TxWorkers = ctx.GlobalInt(TxWorkersFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryCacheFlag.Name) {
+		cfg.HistoryCache = ctx.GlobalInt(HistoryCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryIntervalFlag.Name) {
+		cfg.HistoryInterval = ctx.GlobalUint64(HistoryIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryLifetimeFlag.Name) {
+		cfg.HistoryLifetime = ctx.GlobalDuration(HistoryLifetimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.GlobalBool(AddressIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(InternalIndexFlag.Name) {
+		cfg.InternalIndex = ctx.GlobalBool(InternalIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(BloomSectionRateFlag.Name) {
+		cfg.BloomThrottle.SectionRate = ctx.GlobalFloat64(BloomSectionRateFlag.Name)
+	}
+	if ctx.GlobalIsSet(BloomIOBudgetFlag.Name) {
+		cfg.BloomThrottle.IOBudget = ctx.GlobalUint64(BloomIOBudgetFlag.Name) * 1024 * 1024
+	}
 	if ctx.GlobalIsSet(MinerThreadsFlag.Name) {
 		cfg.MinerThreads = ctx.GlobalInt(MinerThreadsFlag.Name)
 	}
@@ -1025,10 +1653,19 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(GasPriceFlag.Name) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
+	if ctx.GlobalIsSet(GasLimitTargetFlag.Name) {
+		cfg.GasLimitTarget = ctx.GlobalUint64(GasLimitTargetFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxOrderCommitFlag.Name) {
+		cfg.TxOrderCommit = ctx.GlobalBool(TxOrderCommitFlag.Name)
+	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(VMStatsFlag.Name) {
+		cfg.VMStats = ctx.GlobalBool(VMStatsFlag.Name)
+	}
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1190,6 +1827,12 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieNodeLimit: eth.DefaultConfig.TrieCache,
 		TrieTimeLimit: eth.DefaultConfig.TrieTimeout,
+		Snapshot:      ctx.GlobalBool(SnapshotFlag.Name),
+
+		TrieCleanLimit: ctx.GlobalInt(CacheTrieFlag.Name),
+
+		ReceiptRetention: ctx.GlobalUint64(ReceiptRetentionFlag.Name),
+		TxWorkers:        ctx.GlobalInt(TxWorkersFlag.Name),
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
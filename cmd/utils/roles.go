@@ -0,0 +1,125 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fulcrumchain/indigo/eth"
+	"github.com/fulcrumchain/indigo/eth/downloader"
+	"github.com/fulcrumchain/indigo/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// NodeRole is a preset of the cache split, peer counts, exposed APIs, pruning
+// and transaction pool sizes fitting a kind of node. It is applied over the
+// defaults, so the configuration file and the flags still override it.
+type NodeRole struct {
+	Name  string
+	Usage string
+	apply func(node *node.Config, eth *eth.Config)
+}
+
+// NodeRoles are the presets selectable with --role.
+var NodeRoles = []NodeRole{
+	{
+		Name:  "validator",
+		Usage: "Block producer: state heavy caches, deep transaction pool, no RPC APIs beyond the basics",
+		apply: func(node *node.Config, eth *eth.Config) {
+			node.P2P.MaxPeers = 25
+			node.HTTPModules = []string{"eth", "net", "web3"}
+			node.WSModules = []string{"eth", "net", "web3"}
+
+			eth.DatabaseCache, eth.TrieCache = 1024, 1024
+			eth.LightServ = 0
+			eth.TxPool.GlobalSlots = 65536
+			eth.TxPool.GlobalQueue = 16384
+		},
+	},
+	{
+		Name:  "rpc",
+		Usage: "API server: read heavy caches, many peers, transaction pool guarded by per-account quotas",
+		apply: func(node *node.Config, eth *eth.Config) {
+			node.P2P.MaxPeers = 50
+			node.HTTPModules = []string{"eth", "net", "web3", "txpool"}
+			node.WSModules = []string{"eth", "net", "web3", "txpool"}
+
+			eth.DatabaseCache, eth.TrieCache = 2048, 512
+			eth.LightServ = 0
+			eth.TxPool.GlobalSlots = 16384
+			eth.TxPool.GlobalQueue = 4096
+			eth.TxPool.AccountQuota = 64
+		},
+	},
+	{
+		Name:  "archive",
+		Usage: "Historical state server: full sync without pruning, debug APIs, small transaction pool",
+		apply: func(node *node.Config, eth *eth.Config) {
+			node.P2P.MaxPeers = 25
+			node.HTTPModules = []string{"eth", "net", "web3", "debug"}
+			node.WSModules = []string{"eth", "net", "web3", "debug"}
+
+			eth.SyncMode = downloader.FullSync
+			eth.NoPruning = true
+			eth.DatabaseCache, eth.TrieCache = 3072, 1024
+			eth.LightServ = 0
+			eth.TxPool.GlobalSlots = 4096
+			eth.TxPool.GlobalQueue = 1024
+		},
+	},
+	{
+		Name:  "light-gateway",
+		Usage: "Light client server: serves LES to many clients over discovery v5",
+		apply: func(node *node.Config, eth *eth.Config) {
+			node.P2P.MaxPeers = 250
+			node.P2P.DiscoveryV5 = true
+			node.HTTPModules = []string{"eth", "net", "web3"}
+			node.WSModules = []string{"eth", "net", "web3"}
+
+			eth.DatabaseCache, eth.TrieCache = 1536, 512
+			eth.LightServ = 50
+			eth.LightPeers = 200
+		},
+	},
+}
+
+// ApplyNodeRole configures the node and the eth service as the named role.
+func ApplyNodeRole(name string, node *node.Config, eth *eth.Config) error {
+	var names []string
+	for _, role := range NodeRoles {
+		if role.Name == name {
+			role.apply(node, eth)
+			return nil
+		}
+		names = append(names, role.Name)
+	}
+	return fmt.Errorf("unknown node role %q, want one of %s", name, strings.Join(names, ", "))
+}
+
+// SetRoleConfig applies the role preset selected on the command line, if any.
+// It is to be called before loading the configuration file and applying the
+// other flags, which take precedence.
+func SetRoleConfig(ctx *cli.Context, node *node.Config, eth *eth.Config) {
+	name := ctx.GlobalString(RoleFlag.Name)
+	if name == "" {
+		return
+	}
+	if err := ApplyNodeRole(name, node, eth); err != nil {
+		Fatalf("Option %q: %v", RoleFlag.Name, err)
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/eth"
+	"github.com/fulcrumchain/indigo/eth/downloader"
+	"github.com/fulcrumchain/indigo/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// Tests that the role presets configure the node coherently, and refuse
+// unknown roles.
+func TestNodeRoles(t *testing.T) {
+	for _, role := range NodeRoles {
+		nodeCfg, ethCfg := node.DefaultConfig, eth.DefaultConfig
+		if err := ApplyNodeRole(role.Name, &nodeCfg, &ethCfg); err != nil {
+			t.Fatalf("%s: failed to apply: %v", role.Name, err)
+		}
+		if nodeCfg.P2P.MaxPeers <= ethCfg.LightPeers && ethCfg.LightServ > 0 {
+			t.Errorf("%s: %d peers leave no room for eth peers besides %d light ones", role.Name, nodeCfg.P2P.MaxPeers, ethCfg.LightPeers)
+		}
+		if ethCfg.NoPruning && ethCfg.SyncMode != downloader.FullSync {
+			t.Errorf("%s: archive without full sync", role.Name)
+		}
+	}
+	nodeCfg, ethCfg := node.DefaultConfig, eth.DefaultConfig
+	if err := ApplyNodeRole("archive", &nodeCfg, &ethCfg); err != nil || !ethCfg.NoPruning {
+		t.Errorf("archive role not archiving: err %v, pruning %v", err, !ethCfg.NoPruning)
+	}
+	if err := ApplyNodeRole("miner", &nodeCfg, &ethCfg); err == nil {
+		t.Error("unknown role applied")
+	}
+}
+
+// Tests that flags override the role preset.
+func TestNodeRoleOverride(t *testing.T) {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	RoleFlag.Apply(set)
+	TxPoolAccountQuotaFlag.Apply(set)
+	if err := set.Parse([]string{"--role", "rpc", "--txpool.accountquota", "8"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := cli.NewContext(nil, set, nil)
+
+	nodeCfg, ethCfg := node.DefaultConfig, eth.DefaultConfig
+	SetRoleConfig(ctx, &nodeCfg, &ethCfg)
+	if ethCfg.TxPool.AccountQuota != 64 || ethCfg.TxPool.GlobalSlots != 16384 {
+		t.Fatalf("role not applied: quota %d, slots %d", ethCfg.TxPool.AccountQuota, ethCfg.TxPool.GlobalSlots)
+	}
+	setTxPool(ctx, &ethCfg.TxPool)
+	if ethCfg.TxPool.AccountQuota != 8 || ethCfg.TxPool.GlobalSlots != 16384 {
+		t.Errorf("flag not overriding role: quota %d, slots %d", ethCfg.TxPool.AccountQuota, ethCfg.TxPool.GlobalSlots)
+	}
+}
@@ -57,6 +57,33 @@ func (api *API) GetSnapshotAtHash(ctx context.Context, hash common.Hash) (*Snaps
 	return api.clique.snapshot(ctx, api.chain, header.Number.Uint64(), header.Hash(), nil)
 }
 
+// ExportCheckpoint retrieves the state snapshot at a given checkpoint block
+// (current checkpoint if none requested), signed by the local signer.
+func (api *API) ExportCheckpoint(ctx context.Context, number *rpc.BlockNumber) (*Checkpoint, error) {
+	// Retrieve the requested block number (or last checkpoint if none requested)
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		current := api.chain.CurrentHeader().Number.Uint64()
+		header = api.chain.GetHeaderByNumber(current - current%checkpointInterval)
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.clique.snapshot(ctx, api.chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return api.clique.exportCheckpoint(snap)
+}
+
+// ImportCheckpoint verifies and stores a signed state snapshot, allowing the
+// headers after it to be validated without the vote history before it.
+func (api *API) ImportCheckpoint(checkpoint *Checkpoint) error {
+	return api.clique.importCheckpoint(api.chain, checkpoint)
+}
+
 // GetSigners retrieves the list of authorized signers at the specified block.
 func (api *API) GetSigners(ctx context.Context, number *rpc.BlockNumber) ([]common.Address, error) {
 	// Retrieve the requested block number (or current if none requested)
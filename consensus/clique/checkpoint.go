@@ -0,0 +1,183 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/fulcrumchain/indigo/accounts"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/consensus"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/log"
+)
+
+var (
+	// errNoCheckpointSigner is returned if a checkpoint is exported by a node
+	// that is not authorized to sign.
+	errNoCheckpointSigner = errors.New("no local signer to sign the checkpoint with")
+
+	// errUnalignedCheckpoint is returned if a checkpoint is requested for a block
+	// that is not a multiple of the snapshot checkpoint interval.
+	errUnalignedCheckpoint = fmt.Errorf("checkpoint block not a multiple of %d", checkpointInterval)
+
+	// errInvalidCheckpointSignature is returned if the signature of a checkpoint
+	// does not recover to its claimed signer.
+	errInvalidCheckpointSignature = errors.New("invalid checkpoint signature")
+
+	// errUnauthorizedCheckpoint is returned if a checkpoint is signed by an
+	// account not authorized to sign at the checkpoint block, or by one not
+	// authorized in the latest snapshot trusted locally.
+	errUnauthorizedCheckpoint = errors.New("checkpoint signer not authorized at checkpoint block")
+
+	// errUnknownCheckpointBlock is returned if a checkpoint is imported for a
+	// block not available in the local chain.
+	errUnknownCheckpointBlock = errors.New("checkpoint block not available locally")
+)
+
+// Checkpoint is a voting snapshot signed by one of its authorized signers. It
+// lets a recovering node validate the headers following the snapshot block
+// without replaying the vote history leading up to it.
+type Checkpoint struct {
+	Snapshot  *Snapshot      `json:"snapshot"`
+	Signer    common.Address `json:"signer"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// checkpointHash returns the hash a checkpoint signer signs, covering the JSON
+// encoding of the snapshot.
+func checkpointHash(snap *Snapshot) (common.Hash, error) {
+	blob, err := json.Marshal(snap)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(blob), nil
+}
+
+// exportCheckpoint signs a snapshot with the local signer.
+func (c *Clique) exportCheckpoint(snap *Snapshot) (*Checkpoint, error) {
+	if snap.Number%checkpointInterval != 0 {
+		return nil, errUnalignedCheckpoint
+	}
+	c.lock.RLock()
+	signer, signFn := c.signer, c.signFn
+	c.lock.RUnlock()
+
+	if signFn == nil {
+		return nil, errNoCheckpointSigner
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		return nil, errUnauthorizedCheckpoint
+	}
+	hash, err := checkpointHash(snap)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoint{Snapshot: snap, Signer: signer, Signature: sig}, nil
+}
+
+// importCheckpoint verifies a signed snapshot and stores it, so the voting
+// state of the following headers is derived from it instead of from the vote
+// history before it. The checkpoint block must be part of the local chain, and
+// the checkpoint must be signed by a signer authorized both by the checkpoint
+// and by the latest snapshot trusted locally at or before it.
+func (c *Clique) importCheckpoint(chain consensus.ChainReader, cp *Checkpoint) error {
+	snap := cp.Snapshot
+	if snap == nil || snap.Signers == nil {
+		return errors.New("checkpoint snapshot missing")
+	}
+	if snap.Number%checkpointInterval != 0 {
+		return errUnalignedCheckpoint
+	}
+	hash, err := checkpointHash(snap)
+	if err != nil {
+		return err
+	}
+	pubkey, err := crypto.Ecrecover(hash.Bytes(), cp.Signature)
+	if err != nil {
+		return errInvalidCheckpointSignature
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	if signer != cp.Signer {
+		return errInvalidCheckpointSignature
+	}
+	if _, ok := snap.Signers[signer]; !ok {
+		return errUnauthorizedCheckpoint
+	}
+	header := chain.GetHeaderByNumber(snap.Number)
+	if header == nil {
+		return errUnknownCheckpointBlock
+	}
+	if header.Hash() != snap.Hash {
+		return fmt.Errorf("checkpoint block #%d [%x…] conflicts with local block [%x…]", snap.Number, snap.Hash[:4], header.Hash().Bytes()[:4])
+	}
+	trusted, err := c.trustedSnapshot(chain, snap.Number)
+	if err != nil {
+		return err
+	}
+	if _, ok := trusted.Signers[signer]; !ok {
+		return errUnauthorizedCheckpoint
+	}
+	if snap.Voters == nil {
+		snap.Voters = make(map[common.Address]struct{})
+	}
+	if snap.Tally == nil {
+		snap.Tally = make(map[common.Address]Tally)
+	}
+	snap.config = c.config
+	snap.sigcache = c.signatures
+
+	if err := snap.store(c.db); err != nil {
+		return err
+	}
+	c.recents.Add(snap.Hash, snap)
+
+	log.Info("Imported voting checkpoint", "number", snap.Number, "hash", snap.Hash, "signer", signer, "trusted", trusted.Number)
+	return nil
+}
+
+// trustedSnapshot returns the latest voting snapshot available locally for a
+// canonical checkpoint block at or before number, falling back to the genesis
+// snapshot. No votes are replayed to produce it.
+func (c *Clique) trustedSnapshot(chain consensus.ChainReader, number uint64) (*Snapshot, error) {
+	for number -= number % checkpointInterval; number > 0; number -= checkpointInterval {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			continue
+		}
+		if s, ok := c.recents.Get(header.Hash()); ok {
+			return s.(*Snapshot), nil
+		}
+		if s, err := loadSnapshot(c.config, c.signatures, c.db, header.Hash()); err == nil {
+			return s, nil
+		}
+	}
+	genesis := chain.GetHeaderByNumber(0)
+	if genesis == nil {
+		return nil, errUnknownCheckpointBlock
+	}
+	return c.snapshot(context.Background(), chain, 0, genesis.Hash(), nil)
+}
@@ -0,0 +1,134 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package clique
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/accounts"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// Tests that a checkpoint exported by an authorized signer can be imported by
+// another node, and that tampered, unauthorized, unknown or conflicting ones
+// are refused.
+func TestCheckpointExportImport(t *testing.T) {
+	pool := newTesterAccountPool()
+
+	// newChain creates a database with a genesis block authorizing the signers
+	newChain := func(signers ...string) ethdb.Database {
+		genesis := &core.Genesis{
+			ExtraData: make([]byte, extraVanity),
+			Signer:    make([]byte, signatureLength),
+		}
+		for _, signer := range signers {
+			genesis.Signers = append(genesis.Signers, pool.address(signer))
+			genesis.Voters = append(genesis.Voters, pool.address(signer))
+		}
+		db := ethdb.NewMemDatabase()
+		genesis.MustCommit(db)
+		return db
+	}
+	// export signs the genesis snapshot of the chain as the given signer
+	export := func(db ethdb.Database, signer string) (*Checkpoint, error) {
+		engine := New(&params.CliqueConfig{Epoch: 30000}, db)
+		engine.Authorize(pool.address(signer), func(account accounts.Account, hash []byte) ([]byte, error) {
+			return crypto.Sign(hash, pool.accounts[signer])
+		})
+		snap, err := engine.snapshot(context.Background(), &testerChainReader{db: db}, 0, core.GetCanonicalHash(db, 0), nil)
+		if err != nil {
+			t.Fatalf("failed to create snapshot: %v", err)
+		}
+		return engine.exportCheckpoint(snap)
+	}
+	// sign signs an arbitrary snapshot as the given signer
+	sign := func(snap *Snapshot, signer string) *Checkpoint {
+		hash, err := checkpointHash(snap)
+		if err != nil {
+			t.Fatalf("failed to hash snapshot: %v", err)
+		}
+		sig, err := crypto.Sign(hash.Bytes(), pool.accounts[signer])
+		if err != nil {
+			t.Fatalf("failed to sign snapshot: %v", err)
+		}
+		return &Checkpoint{Snapshot: snap, Signer: pool.address(signer), Signature: sig}
+	}
+	// importInto round trips the checkpoint through JSON and imports it
+	importInto := func(db ethdb.Database, cp *Checkpoint) error {
+		blob, err := json.Marshal(cp)
+		if err != nil {
+			t.Fatalf("failed to encode checkpoint: %v", err)
+		}
+		decoded := new(Checkpoint)
+		if err := json.Unmarshal(blob, decoded); err != nil {
+			t.Fatalf("failed to decode checkpoint: %v", err)
+		}
+		engine := New(&params.CliqueConfig{Epoch: 30000}, db)
+		if err := engine.importCheckpoint(&testerChainReader{db: db}, decoded); err != nil {
+			return err
+		}
+		if _, ok := engine.recents.Get(decoded.Snapshot.Hash); !ok {
+			t.Errorf("imported snapshot not cached")
+		}
+		if _, err := loadSnapshot(engine.config, engine.signatures, db, decoded.Snapshot.Hash); err != nil {
+			t.Errorf("imported snapshot not stored: %v", err)
+		}
+		return nil
+	}
+	source := newChain("A", "B")
+
+	cp, err := export(source, "A")
+	if err != nil {
+		t.Fatalf("failed to export checkpoint: %v", err)
+	}
+	if err := importInto(newChain("A", "B"), cp); err != nil {
+		t.Errorf("failed to import checkpoint: %v", err)
+	}
+	// Tampering with the snapshot invalidates the signature
+	tampered := *cp
+	tampered.Snapshot = cp.Snapshot.copy()
+	tampered.Snapshot.Signers[pool.address("C")] = 0
+	if err := importInto(newChain("A", "B"), &tampered); err != errInvalidCheckpointSignature {
+		t.Errorf("tampered checkpoint: error mismatch: have %v, want %v", err, errInvalidCheckpointSignature)
+	}
+	// Signers authorized only by the checkpoint itself are not trusted
+	forged := cp.Snapshot.copy()
+	forged.Signers[pool.address("C")] = 0
+	if err := importInto(newChain("A", "B"), sign(forged, "C")); err != errUnauthorizedCheckpoint {
+		t.Errorf("self authorized checkpoint: error mismatch: have %v, want %v", err, errUnauthorizedCheckpoint)
+	}
+	// Checkpoints for blocks missing locally are refused
+	unknown := cp.Snapshot.copy()
+	unknown.Number, unknown.Hash = checkpointInterval, common.Hash{0x01}
+	if err := importInto(newChain("A", "B"), sign(unknown, "A")); err != errUnknownCheckpointBlock {
+		t.Errorf("unknown block checkpoint: error mismatch: have %v, want %v", err, errUnknownCheckpointBlock)
+	}
+	// Signers not authorized at the checkpoint can't export it
+	if _, err := export(source, "C"); err != errUnauthorizedCheckpoint {
+		t.Errorf("unauthorized export: error mismatch: have %v, want %v", err, errUnauthorizedCheckpoint)
+	}
+	// Checkpoints conflicting with the local chain are refused
+	if err := importInto(newChain("A"), cp); err == nil {
+		t.Errorf("conflicting checkpoint imported")
+	}
+}
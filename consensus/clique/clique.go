@@ -306,7 +306,7 @@ func (c *Clique) verifyHeader(ctx context.Context, chain consensus.ChainReader,
 	number := header.Number.Uint64()
 
 	// Checkpoint blocks need to enforce zero beneficiary
-	checkpoint := (number % c.config.Epoch) == 0
+	checkpoint := c.config.IsCheckpoint(number)
 	if checkpoint && len(header.Extra) > extraVanity {
 		return errInvalidCheckpointBeneficiary
 	}
@@ -397,7 +397,7 @@ func (c *Clique) verifyCascadingFields(ctx context.Context, chain consensus.Chai
 	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
 		return consensus.ErrUnknownAncestor
 	}
-	if parent.Time.Uint64()+c.config.Period > header.Time.Uint64() {
+	if parent.Time.Uint64()+c.config.PeriodAt(number) > header.Time.Uint64() {
 		return ErrInvalidTimestamp
 	}
 	// Retrieve the snapshot needed to verify this header and cache it
@@ -406,7 +406,7 @@ func (c *Clique) verifyCascadingFields(ctx context.Context, chain consensus.Chai
 		return err
 	}
 	// If the block is a checkpoint block, verify the signer list
-	if number%c.config.Epoch == 0 {
+	if c.config.IsCheckpoint(number) {
 		for i, signer := range snap.signers() {
 			if signer != header.Signers[i] {
 				return errInvalidCheckpointSigners
@@ -568,7 +568,7 @@ func (c *Clique) Prepare(ctx context.Context, chain consensus.ChainReader, heade
 
 	header.Extra = ExtraEnsureVanity(header.Extra)
 	//if not checkpoint
-	if number%c.config.Epoch != 0 {
+	if !c.config.IsCheckpoint(number) {
 		c.lock.RLock()
 
 		// Gather all the proposals that make sense voting on
@@ -593,7 +593,7 @@ func (c *Clique) Prepare(ctx context.Context, chain consensus.ChainReader, heade
 		c.lock.RUnlock()
 	}
 
-	if number%c.config.Epoch == 0 {
+	if c.config.IsCheckpoint(number) {
 		header.Signers = snap.signers()
 		header.Voters = snap.voters()
 	}
@@ -606,7 +606,7 @@ func (c *Clique) Prepare(ctx context.Context, chain consensus.ChainReader, heade
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
-	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(c.config.Period))
+	header.Time = new(big.Int).Add(parent.Time, new(big.Int).SetUint64(c.config.PeriodAt(number)))
 	if header.Time.Int64() < time.Now().Unix() {
 		header.Time = big.NewInt(time.Now().Unix())
 	}
@@ -635,7 +635,7 @@ func (c *Clique) Seal(ctx context.Context, chain consensus.ChainReader, block *t
 		return nil, errUnknownBlock
 	}
 	// For 0-period chains, refuse to seal empty blocks (no reward but would spin sealing)
-	if c.config.Period == 0 && len(block.Transactions()) == 0 {
+	if c.config.PeriodAt(number) == 0 && len(block.Transactions()) == 0 {
 		return nil, errWaitTransactions
 	}
 	// Don't hold the signer fields for the entire sealing procedure
@@ -756,6 +756,16 @@ func ExtraVanity(extra []byte) []byte {
 	return extra[:extraVanity]
 }
 
+// ExtraReplaceVanity returns a copy of extra with the vanity replaced, keeping any vote which follows it.
+func ExtraReplaceVanity(extra []byte, vanity []byte) []byte {
+	cpy := make([]byte, extraVanity, len(extra)+extraVanity)
+	copy(cpy, ExtraEnsureVanity(common.CopyBytes(vanity)))
+	if len(extra) > extraVanity {
+		cpy = append(cpy, extra[extraVanity:]...)
+	}
+	return cpy
+}
+
 // ExtraAppendVote appends a vote to extra data as 20 bytes of address and a single byte for voter or signer election.
 func ExtraAppendVote(extra []byte, candidate common.Address, voter bool) []byte {
 	extra = append(extra, candidate[:]...)
@@ -73,6 +73,18 @@ func (test *extraDataTest) run(t *testing.T) {
 	if !bytes.Equal(test.data, extra) {
 		t.Errorf("expected:\n\t%q\ngot:\n\t%q", test.data, extra)
 	}
+
+	// Replacing the vanity must keep the vote and leave the original untouched
+	replaced := ExtraReplaceVanity(test.data, []byte("replaced"))
+	if vanity := string(bytes.TrimRight(ExtraVanity(replaced), "\x00")); vanity != "replaced" {
+		t.Errorf("expected replaced vanity %q but got %q", "replaced", vanity)
+	}
+	if ExtraHasVote(replaced) != ExtraHasVote(test.data) || ExtraCandidate(replaced) != test.candidate {
+		t.Errorf("vote changed by vanity replacement: %q", replaced)
+	}
+	if vanity := string(bytes.TrimRight(ExtraVanity(test.data), "\x00")); vanity != test.vanity {
+		t.Errorf("original vanity modified to %q", vanity)
+	}
 }
 
 func TestCalcDifficulty(t *testing.T) {
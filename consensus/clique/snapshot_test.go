@@ -70,8 +70,8 @@ func (ap *testerAccountPool) address(account string) common.Address {
 	return crypto.PubkeyToAddress(ap.accounts[account].PublicKey)
 }
 
-// testerChainReader implements consensus.ChainReader to access the genesis
-// block. All other methods and requests will panic.
+// testerChainReader implements consensus.ChainReader to access the canonical
+// headers. All other methods and requests will panic.
 type testerChainReader struct {
 	db ethdb.Database
 }
@@ -82,10 +82,7 @@ func (r *testerChainReader) GetHeader(common.Hash, uint64) *types.Header { panic
 func (r *testerChainReader) GetBlock(common.Hash, uint64) *types.Block   { panic("not supported") }
 func (r *testerChainReader) GetHeaderByHash(common.Hash) *types.Header   { panic("not supported") }
 func (r *testerChainReader) GetHeaderByNumber(number uint64) *types.Header {
-	if number == 0 {
-		return core.GetHeader(r.db, core.GetCanonicalHash(r.db, 0), 0)
-	}
-	panic("not supported")
+	return core.GetHeader(r.db, core.GetCanonicalHash(r.db, number), number)
 }
 
 // Tests that voting is evaluated correctly for various simple and complex scenarios.
@@ -0,0 +1,367 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const (
+	// badBlockTraceLimit is the maximum number of EVM steps traced per
+	// transaction of a bad block.
+	badBlockTraceLimit = 50000
+
+	// badBlockTraceInterval is the minimum time between two bad block traces.
+	badBlockTraceInterval = time.Minute
+)
+
+var badBlockPrefix = []byte("bad-block-") // badBlockPrefix + hash -> bad block
+
+// BadBlock is a block which failed validation on import, along with the
+// receipts produced before the failure and the trace of its execution.
+type BadBlock struct {
+	Block    *types.Block
+	Receipts types.Receipts
+	Error    string
+	Time     time.Time
+	Trace    []*BadBlockTxTrace
+}
+
+// BadBlockTxTrace is the structured EVM trace of a transaction of a bad block.
+type BadBlockTxTrace struct {
+	TxHash     common.Hash    `json:"txHash"`
+	Gas        uint64         `json:"gas"`
+	Failed     bool           `json:"failed"`
+	Error      string         `json:"error,omitempty"` // Error invalidating the block at the transaction
+	StructLogs []vm.StructLog `json:"structLogs"`
+}
+
+// badBlockRLP is the storage encoding of a bad block.
+type badBlockRLP struct {
+	Block    *types.Block
+	Receipts types.ReceiptsForStorage
+	Error    string
+	Time     uint64
+	Trace    []byte // JSON encoded, as struct logs have no RLP encoding
+}
+
+func badBlockKey(hash common.Hash) []byte {
+	return append(append([]byte{}, badBlockPrefix...), hash.Bytes()...)
+}
+
+// WriteBadBlock stores a bad block.
+func WriteBadBlock(db ethdb.Putter, bad *BadBlock) error {
+	trace, err := json.Marshal(bad.Trace)
+	if err != nil {
+		return err
+	}
+	data, err := rlp.EncodeToBytes(&badBlockRLP{
+		Block:    bad.Block,
+		Receipts: types.ReceiptsForStorage(bad.Receipts),
+		Error:    bad.Error,
+		Time:     uint64(bad.Time.Unix()),
+		Trace:    trace,
+	})
+	if err != nil {
+		return err
+	}
+	return db.Put(badBlockKey(bad.Block.Hash()), data)
+}
+
+// decodeBadBlock decodes a bad block from its storage encoding.
+func decodeBadBlock(data []byte) (*BadBlock, error) {
+	var stored badBlockRLP
+	if err := rlp.DecodeBytes(data, &stored); err != nil {
+		return nil, err
+	}
+	bad := &BadBlock{
+		Block:    stored.Block,
+		Receipts: types.Receipts(stored.Receipts),
+		Error:    stored.Error,
+		Time:     time.Unix(int64(stored.Time), 0),
+	}
+	if err := json.Unmarshal(stored.Trace, &bad.Trace); err != nil {
+		return nil, err
+	}
+	return bad, nil
+}
+
+// GetBadBlock retrieves a bad block by hash, or nil if not found.
+func GetBadBlock(db DatabaseReader, hash common.Hash) *BadBlock {
+	data, _ := db.Get(badBlockKey(hash))
+	if len(data) == 0 {
+		return nil
+	}
+	bad, err := decodeBadBlock(data)
+	if err != nil {
+		log.Error("Invalid bad block RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return bad
+}
+
+// GetBadBlocks retrieves all the stored bad blocks, the most recent first.
+func GetBadBlocks(db ethdb.Database) ([]*BadBlock, error) {
+	var blocks []*BadBlock
+
+	it := db.NewPrefixIterator(badBlockPrefix)
+	for it.Next() {
+		bad, err := decodeBadBlock(it.Value())
+		if err != nil {
+			log.Error("Invalid bad block RLP", "key", fmt.Sprintf("%x", it.Key()), "err", err)
+			continue
+		}
+		blocks = append(blocks, bad)
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Time.After(blocks[j].Time) })
+	return blocks, nil
+}
+
+// DeleteBadBlock removes a stored bad block.
+func DeleteBadBlock(db DatabaseDeleter, hash common.Hash) {
+	db.Delete(badBlockKey(hash))
+}
+
+// captureBadBlock stores a block failing execution or state validation on
+// import. Only the last badBlockLimit bad blocks are kept. The trace of its
+// execution is added in the background, as tracing holds up the import if done
+// inline, and at most once per badBlockTraceInterval to bound the work peers
+// can cause by sending invalid blocks. Untraced blocks can still be traced on
+// demand through ReplayBadBlock.
+func (bc *BlockChain) captureBadBlock(block *types.Block, receipts types.Receipts, err error) {
+	if GetBadBlock(bc.db, block.Hash()) != nil {
+		return
+	}
+	bad := &BadBlock{
+		Block:    block,
+		Receipts: receipts,
+		Error:    err.Error(),
+		Time:     time.Now(),
+	}
+	if err := WriteBadBlock(bc.db, bad); err != nil {
+		log.Error("Failed to store bad block", "hash", block.Hash(), "err", err)
+		return
+	}
+	blocks, err := GetBadBlocks(bc.db)
+	if err != nil {
+		log.Error("Failed to list bad blocks", "err", err)
+		return
+	}
+	for i := badBlockLimit; i < len(blocks); i++ {
+		DeleteBadBlock(bc.db, blocks[i].Block.Hash())
+	}
+	bc.traceBadBlock(block)
+}
+
+// traceBadBlock traces the execution of a stored bad block on top of its parent
+// state in the background, adding the trace to the stored block. The trace is
+// skipped if another one is running or one started less than
+// badBlockTraceInterval ago.
+func (bc *BlockChain) traceBadBlock(block *types.Block) {
+	bc.badTraceLock.Lock()
+	if bc.badTracing || time.Since(bc.badTraceLast) < badBlockTraceInterval {
+		bc.badTraceLock.Unlock()
+		log.Debug("Skipping bad block trace", "number", block.Number(), "hash", block.Hash())
+		return
+	}
+	bc.badTracing, bc.badTraceLast = true, time.Now()
+	bc.badTraceLock.Unlock()
+
+	bc.wg.Add(1)
+	go func() {
+		defer bc.wg.Done()
+		defer func() {
+			bc.badTraceLock.Lock()
+			bc.badTracing = false
+			bc.badTraceLock.Unlock()
+		}()
+		parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+		if parent == nil {
+			return
+		}
+		statedb, err := bc.StateAt(parent.Root())
+		if err != nil {
+			return
+		}
+		_, _, trace, _ := bc.traceBlock(context.Background(), block, statedb)
+
+		// The block may have been pruned by newer ones while tracing
+		bad := GetBadBlock(bc.db, block.Hash())
+		if bad == nil {
+			return
+		}
+		bad.Trace = trace
+		if err := WriteBadBlock(bc.db, bad); err != nil {
+			log.Error("Failed to store bad block trace", "hash", block.Hash(), "err", err)
+		}
+	}()
+}
+
+// traceBlock executes the transactions of a block on top of statedb one by
+// one, recording their structured EVM traces. Execution stops at the first
+// transaction invalidating the block.
+func (bc *BlockChain) traceBlock(ctx context.Context, block *types.Block, statedb *state.StateDB) (types.Receipts, uint64, []*BadBlockTxTrace, error) {
+	var (
+		header   = block.Header()
+		signer   = types.MakeSigner(bc.chainConfig, header.Number)
+		gp       = new(GasPool).AddGas(block.GasLimit())
+		usedGas  = new(uint64)
+		receipts types.Receipts
+		traces   []*BadBlockTxTrace
+	)
+	evmContext := NewEVMContextLite(header, bc, nil)
+	for i, tx := range block.Transactions() {
+		logger := vm.NewStructLogger(&vm.LogConfig{DisableMemory: true, DisableStorage: true, Limit: badBlockTraceLimit})
+		vmenv := vm.NewEVM(evmContext, statedb, bc.chainConfig, vm.Config{Debug: true, Tracer: logger})
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, gas, err := ApplyTransaction(ctx, vmenv, bc.chainConfig, gp, statedb, header, tx, usedGas, signer)
+
+		trace := &BadBlockTxTrace{TxHash: tx.Hash(), Gas: gas, StructLogs: logger.StructLogs()}
+		traces = append(traces, trace)
+		if err != nil {
+			trace.Error = err.Error()
+			return receipts, *usedGas, traces, err
+		}
+		trace.Failed = receipt.Status == types.ReceiptStatusFailed
+		receipts = append(receipts, receipt)
+	}
+	bc.engine.Finalize(ctx, bc, header, statedb, block.Transactions(), receipts, false)
+	return receipts, *usedGas, traces, nil
+}
+
+// BadBlockReplay is the outcome of importing a bad block again on top of its
+// parent state.
+type BadBlockReplay struct {
+	Hash    common.Hash        `json:"hash"`
+	Number  uint64             `json:"number"`
+	Valid   bool               `json:"valid"`
+	Error   string             `json:"error,omitempty"`
+	GasUsed uint64             `json:"gasUsed"`
+	Root    common.Hash        `json:"root"` // State root computed by the replay
+	Trace   []*BadBlockTxTrace `json:"trace,omitempty"`
+}
+
+// ReplayBadBlock validates and executes a stored bad block again on top of its
+// parent state, without importing it, tracing the execution if requested.
+func (bc *BlockChain) ReplayBadBlock(ctx context.Context, hash common.Hash, trace bool) (*BadBlockReplay, error) {
+	bad := GetBadBlock(bc.db, hash)
+	if bad == nil {
+		return nil, fmt.Errorf("bad block %x not found", hash)
+	}
+	block := bad.Block
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x of bad block not found", block.ParentHash())
+	}
+	statedb, err := bc.StateAt(parent.Root())
+	if err != nil {
+		return nil, fmt.Errorf("parent state of bad block not available: %v", err)
+	}
+	replay := &BadBlockReplay{Hash: hash, Number: block.NumberU64()}
+
+	err = bc.engine.VerifyHeader(ctx, bc, block.Header())
+	if err == nil {
+		err = bc.Validator().ValidateBody(ctx, block)
+	}
+	if err == nil {
+		var receipts types.Receipts
+		if trace {
+			receipts, replay.GasUsed, replay.Trace, err = bc.traceBlock(ctx, block, statedb)
+		} else {
+			receipts, _, replay.GasUsed, err = bc.Processor().Process(ctx, block, statedb, bc.vmConfig)
+		}
+		if err == nil {
+			err = bc.Validator().ValidateState(ctx, block, parent, statedb, receipts, replay.GasUsed)
+		}
+		replay.Root = statedb.IntermediateRoot(bc.chainConfig.IsEIP158(block.Number()))
+	}
+	replay.Valid = err == nil
+	if err != nil {
+		replay.Error = err.Error()
+	}
+	return replay, nil
+}
+
+// BadBlockArgs represents the entries in the list returned when bad blocks are queried.
+type BadBlockArgs struct {
+	Hash     common.Hash        `json:"hash"`
+	Header   *types.Header      `json:"header"`
+	RLP      hexutil.Bytes      `json:"rlp,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Time     *time.Time         `json:"time,omitempty"`
+	Receipts types.Receipts     `json:"receipts,omitempty"`
+	Trace    []*BadBlockTxTrace `json:"trace,omitempty"`
+}
+
+// BadBlocks returns a list of the last 'bad blocks' that the client has seen on
+// the network, the stored ones with their execution traces if requested.
+func (bc *BlockChain) BadBlocks(trace bool) ([]BadBlockArgs, error) {
+	stored, err := GetBadBlocks(bc.db)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		blocks = make([]BadBlockArgs, 0, len(stored)+bc.badBlocks.Len())
+		seen   = make(map[common.Hash]bool)
+	)
+	for _, bad := range stored {
+		data, err := rlp.EncodeToBytes(bad.Block)
+		if err != nil {
+			return nil, err
+		}
+		t := bad.Time
+		args := BadBlockArgs{
+			Hash:     bad.Block.Hash(),
+			Header:   bad.Block.Header(),
+			RLP:      data,
+			Error:    bad.Error,
+			Time:     &t,
+			Receipts: bad.Receipts,
+		}
+		if trace {
+			args.Trace = bad.Trace
+		}
+		blocks = append(blocks, args)
+		seen[args.Hash] = true
+	}
+	// Blocks failing to be stored are only known by header
+	for _, hash := range bc.badBlocks.Keys() {
+		if hdr, exist := bc.badBlocks.Peek(hash); exist && !seen[hash.(common.Hash)] {
+			header := hdr.(*types.Header)
+			blocks = append(blocks, BadBlockArgs{Hash: header.Hash(), Header: header})
+		}
+	}
+	return blocks, nil
+}
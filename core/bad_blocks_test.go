@@ -0,0 +1,164 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+func TestBadBlockStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	for i := 0; i < 3; i++ {
+		bad := &BadBlock{
+			Block:    types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i))}),
+			Receipts: types.Receipts{types.NewReceipt(nil, false, uint64(i))},
+			Error:    "invalid block",
+			Time:     time.Unix(int64(1000+i), 0),
+			Trace:    []*BadBlockTxTrace{{TxHash: common.Hash{byte(i)}, Gas: 21000}},
+		}
+		if err := WriteBadBlock(db, bad); err != nil {
+			t.Fatalf("failed to write bad block: %v", err)
+		}
+	}
+	blocks, err := GetBadBlocks(db)
+	if err != nil {
+		t.Fatalf("failed to list bad blocks: %v", err)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("bad block count mismatch: have %d, want 3", len(blocks))
+	}
+	for i, bad := range blocks {
+		if n := bad.Block.NumberU64(); n != uint64(2-i) {
+			t.Errorf("bad block %d: number mismatch: have %d, want %d", i, n, 2-i)
+		}
+		if len(bad.Receipts) != 1 || bad.Receipts[0].CumulativeGasUsed != uint64(2-i) {
+			t.Errorf("bad block %d: receipts mismatch: %v", i, bad.Receipts)
+		}
+		if len(bad.Trace) != 1 || bad.Trace[0].TxHash != (common.Hash{byte(2 - i)}) {
+			t.Errorf("bad block %d: trace mismatch: %v", i, bad.Trace)
+		}
+	}
+	hash := blocks[0].Block.Hash()
+	if bad := GetBadBlock(db, hash); bad == nil || bad.Error != "invalid block" {
+		t.Fatalf("bad block mismatch: %v", bad)
+	}
+	DeleteBadBlock(db, hash)
+	if bad := GetBadBlock(db, hash); bad != nil {
+		t.Fatalf("deleted bad block retrieved")
+	}
+}
+
+// Tests that blocks failing state validation are stored along with the trace of
+// their execution, and that they are rejected again when replayed.
+func TestBadBlockCapture(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db       = ethdb.NewMemDatabase()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xaa}
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address:  {Balance: big.NewInt(1000000000)},
+				contract: {Balance: new(big.Int), Code: []byte{0x60, 0x01, 0x60, 0x00, 0x55}}, // sstore(0, 1)
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		engine  = clique.NewFaker()
+		signer  = types.HomesteadSigner{}
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := GenerateChain(ctx, gspec.Config, genesis, engine, db, 2, func(ctx context.Context, i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, new(big.Int), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(ctx, tx)
+	})
+	root := blocks[1].Root()
+	header := blocks[1].Header()
+	header.Root = common.Hash{0x01}
+	blocks[1] = types.NewBlockWithHeader(header).WithBody(blocks[1].Transactions(), blocks[1].Uncles())
+
+	if _, err := blockchain.InsertChain(ctx, blocks); err == nil {
+		t.Fatalf("bad block imported")
+	}
+	bad := GetBadBlock(db, blocks[1].Hash())
+	if bad == nil {
+		t.Fatalf("bad block not stored")
+	}
+	if bad.Error == "" {
+		t.Errorf("bad block error missing")
+	}
+	if len(bad.Receipts) != 1 {
+		t.Errorf("bad block receipts mismatch: have %d, want 1", len(bad.Receipts))
+	}
+	// The trace is added in the background
+	for deadline := time.Now().Add(5 * time.Second); len(bad.Trace) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		bad = GetBadBlock(db, blocks[1].Hash())
+	}
+	if len(bad.Trace) != 1 || len(bad.Trace[0].StructLogs) == 0 {
+		t.Fatalf("bad block trace missing: %v", bad.Trace)
+	}
+	var stored bool
+	for _, step := range bad.Trace[0].StructLogs {
+		stored = stored || step.Op == vm.SSTORE
+	}
+	if !stored {
+		t.Errorf("storage write missing from trace: %v", bad.Trace[0].StructLogs)
+	}
+
+	args, err := blockchain.BadBlocks(true)
+	if err != nil {
+		t.Fatalf("failed to list bad blocks: %v", err)
+	}
+	if len(args) != 1 || args[0].Hash != blocks[1].Hash() || len(args[0].Trace) != 1 || len(args[0].RLP) == 0 {
+		t.Fatalf("bad blocks mismatch: %v", args)
+	}
+
+	replay, err := blockchain.ReplayBadBlock(ctx, blocks[1].Hash(), true)
+	if err != nil {
+		t.Fatalf("failed to replay bad block: %v", err)
+	}
+	if replay.Valid || replay.Error == "" {
+		t.Errorf("replayed bad block accepted")
+	}
+	if replay.Root != root {
+		t.Errorf("replayed state root mismatch: have %x, want %x", replay.Root, root)
+	}
+	if len(replay.Trace) != 1 {
+		t.Errorf("replay trace mismatch: have %d transactions, want 1", len(replay.Trace))
+	}
+	if _, err := blockchain.ReplayBadBlock(ctx, common.Hash{0x02}, false); err == nil {
+		t.Errorf("unknown bad block replayed")
+	}
+}
@@ -72,6 +72,17 @@ func (v *BlockValidator) ValidateBody(ctx context.Context, block *types.Block) e
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	for i, tx := range block.Transactions() {
+		if tx.Type() == types.LegacyTxType {
+			continue
+		}
+		if !v.config.IsTypedTx(header.Number) {
+			return fmt.Errorf("transaction %d: typed transaction before fork: %v", i, types.ErrTxTypeNotSupported)
+		}
+		if err := types.ValidateTxType(tx); err != nil {
+			return fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
 	return nil
 }
 
@@ -98,7 +109,7 @@ func (v *BlockValidator) ValidateState(ctx context.Context, block, parent *types
 	// Validate the state root against the received state root and throw
 	// an error if they don't match.
 	if root := statedb.IntermediateRoot(v.config.IsEIP158(header.Number)); header.Root != root {
-		return fmt.Errorf("invalid merkle root #%s (remote: %x local: %x)", header.Number, header.Root, root)
+		return &StateRootError{Number: header.Number, Remote: header.Root, Local: root}
 	}
 	return nil
 }
@@ -133,3 +144,51 @@ func CalcGasLimit(parent *types.Block) uint64 {
 	}
 	return limit
 }
+
+// CalcGasLimitTarget computes the gas limit of the next block after parent,
+// moving it toward target as fast as the protocol allows (parentGasLimit / 1024 - 1
+// per block). The limit never drops below MinGasLimit.
+func CalcGasLimitTarget(parent *types.Block, target uint64) uint64 {
+	return calcGasLimitToward(parent, target, 0)
+}
+
+// CalcChainGasLimit computes the gas limit of the next block after parent
+// following the gas limit trajectory of the chain configuration, or the usage
+// based strategy if the chain has none.
+func CalcChainGasLimit(config *params.ChainConfig, parent *types.Block) uint64 {
+	if config == nil || config.GasLimit == nil {
+		return CalcGasLimit(parent)
+	}
+	return calcGasLimitToward(parent, config.GasLimit.Target, config.GasLimit.Delta)
+}
+
+// calcGasLimitToward moves the gas limit of parent toward target by at most
+// maxDelta, bounded by the protocol maximum (0 = protocol maximum).
+func calcGasLimitToward(parent *types.Block, target, maxDelta uint64) uint64 {
+	if target < params.MinGasLimit {
+		target = params.MinGasLimit
+	}
+	delta := parent.GasLimit()/params.GasLimitBoundDivisor - 1
+	if maxDelta != 0 && maxDelta < delta {
+		delta = maxDelta
+	}
+
+	limit := parent.GasLimit()
+	switch {
+	case limit < target:
+		limit += delta
+		if limit > target {
+			limit = target
+		}
+	case limit > target:
+		if limit-target < delta {
+			limit = target
+		} else {
+			limit -= delta
+		}
+	}
+	if limit < params.MinGasLimit {
+		limit = params.MinGasLimit
+	}
+	return limit
+}
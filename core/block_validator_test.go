@@ -214,3 +214,52 @@ func testHeaderConcurrentAbortion(t *testing.T, threads int) {
 		t.Errorf("verification count too large: have %d, want below %d", verified, 2*threads)
 	}
 }
+
+// Tests that the targeted gas limit moves toward the target within the bounds
+// allowed per block.
+func TestCalcGasLimitTarget(t *testing.T) {
+	tests := []struct {
+		parent uint64
+		target uint64
+		want   uint64
+	}{
+		{parent: 8000000, target: 8000000, want: 8000000},
+		{parent: 8000000, target: 10000000, want: 8000000 + 8000000/1024 - 1},
+		{parent: 8000000, target: 6000000, want: 8000000 - 8000000/1024 + 1},
+		{parent: 8000000, target: 8000100, want: 8000100},
+		{parent: 8000000, target: 7999900, want: 7999900},
+		{parent: params.MinGasLimit, target: 0, want: params.MinGasLimit},
+	}
+	for i, tt := range tests {
+		parent := types.NewBlockWithHeader(&types.Header{GasLimit: tt.parent})
+		if have := CalcGasLimitTarget(parent, tt.target); have != tt.want {
+			t.Errorf("test %d: gas limit mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the gas limit follows the trajectory of the chain configuration,
+// bounded by its delta, and the usage based strategy without one.
+func TestCalcChainGasLimit(t *testing.T) {
+	tests := []struct {
+		config *params.GasLimitConfig
+		parent uint64
+		want   uint64
+	}{
+		{config: nil, parent: params.TargetGasLimit, want: CalcGasLimit(types.NewBlockWithHeader(&types.Header{GasLimit: params.TargetGasLimit}))},
+		{config: &params.GasLimitConfig{Target: 10000000}, parent: 8000000, want: 8000000 + 8000000/1024 - 1},
+		{config: &params.GasLimitConfig{Target: 10000000, Delta: 1000}, parent: 8000000, want: 8001000},
+		{config: &params.GasLimitConfig{Target: 6000000, Delta: 1000}, parent: 8000000, want: 7999000},
+		{config: &params.GasLimitConfig{Target: 8000500, Delta: 1000}, parent: 8000000, want: 8000500},
+		{config: &params.GasLimitConfig{Target: 10000000, Delta: 100000}, parent: 8000000, want: 8000000 + 8000000/1024 - 1},
+	}
+	for i, tt := range tests {
+		config := *params.TestChainConfig
+		config.GasLimit = tt.config
+
+		parent := types.NewBlockWithHeader(&types.Header{GasLimit: tt.parent})
+		if have := CalcChainGasLimit(&config, parent); have != tt.want {
+			t.Errorf("test %d: gas limit mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
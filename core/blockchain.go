@@ -37,6 +37,7 @@ import (
 	"github.com/fulcrumchain/indigo/common/mclock"
 	"github.com/fulcrumchain/indigo/consensus"
 	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/state/snapshot"
 	"github.com/fulcrumchain/indigo/core/types"
 	"github.com/fulcrumchain/indigo/core/vm"
 	"github.com/fulcrumchain/indigo/crypto"
@@ -63,6 +64,15 @@ const (
 	badBlockLimit       = 10
 	triesInMemory       = 128
 
+	// snapshotLayers is the number of in-memory diff layers kept on top of the
+	// persistent state snapshot. It must stay below triesInMemory, so that the
+	// trie of the snapshot's disk layer is still available for generation.
+	snapshotLayers = 64
+
+	// historyLifetime is the time after which cached historical states expire
+	// if not configured otherwise.
+	historyLifetime = time.Hour
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 )
@@ -70,9 +80,18 @@ const (
 // CacheConfig contains the configuration values for the trie caching/pruning
 // that's resident in a blockchain.
 type CacheConfig struct {
-	Disabled      bool          // Whether to disable trie write caching (archive node)
-	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
-	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	Disabled       bool          // Whether to disable trie write caching (archive node)
+	TrieNodeLimit  int           // Memory limit (MB) at which to flush the current in-memory trie to disk
+	TrieTimeLimit  time.Duration // Time limit after which to flush the current in-memory trie to disk
+	TrieCleanLimit int           // Memory allowance (MB) of the clean trie node cache shared by all state readers, 0 to disable
+	Snapshot       bool          // Whether to maintain a flat state snapshot for faster state reads
+
+	ReceiptRetention uint64 // Number of recent blocks to retain receipts and logs for, 0 to keep all
+	TxWorkers        int    // Number of workers executing independent transactions in parallel, 0 or 1 to execute serially
+
+	HistoryCache    int           // Memory budget (MB) of the historical state cache, 0 to disable
+	HistoryInterval uint64        // Blocks whose numbers are multiples of it are popular enough to cache their states
+	HistoryLifetime time.Duration // Time after which a cached historical state expires, 0 for an hour
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -102,7 +121,9 @@ type BlockChain struct {
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
+	reorgFeed     event.Feed
 	logsFeed      event.Feed
+	badBlockFeed  event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -114,11 +135,18 @@ type BlockChain struct {
 	currentBlock     *types.Block // Current head of the block chain
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
-	stateCache   state.Database // State database to reuse between imports (contains state cache)
-	bodyCache    *lru.Cache     // Cache for the most recent block bodies
-	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
-	blockCache   *lru.Cache     // Cache for the most recent entire blocks
-	futureBlocks *lru.Cache     // future blocks are blocks added for later processing
+	stateCache   state.Database      // State database to reuse between imports (contains state cache)
+	snaps        *snapshot.Tree      // Flat state snapshot, nil if disabled
+	histStates   *state.HistoryCache // Flat states of popular historical blocks, nil if disabled
+	bodyCache    *lru.Cache          // Cache for the most recent block bodies
+	bodyRLPCache *lru.Cache          // Cache for the most recent block bodies in RLP encoded format
+	blockCache   *lru.Cache          // Cache for the most recent entire blocks
+	futureBlocks *lru.Cache          // future blocks are blocks added for later processing
+
+	receiptTail      uint64        // Oldest block with retained receipts (atomic)
+	receiptRetention uint64        // Number of recent blocks to retain receipts for, 0 to keep all
+	pruneHead        uint64        // Latest head to prune receipts relative to (atomic)
+	pruneCh          chan struct{} // Signals the receipt pruner about a new head, nil if disabled
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
@@ -132,7 +160,12 @@ type BlockChain struct {
 	vmConfig   vm.Config
 	parWorkers int // Number of workers to spawn for parallel tasks.
 
-	badBlocks *lru.Cache // Bad block cache
+	importGuard *ImportGuard // Optional external policy consulted before block imports
+
+	badBlocks    *lru.Cache // Bad block cache
+	badTraceLock sync.Mutex // Protects the bad block trace rate limit
+	badTraceLast time.Time  // Time the last bad block trace started
+	badTracing   bool       // Whether a bad block trace is running
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -145,6 +178,13 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			TrieTimeLimit: 5 * time.Minute,
 		}
 	}
+	if err := vm.CheckPrecompiles(chainConfig); err != nil {
+		return nil, err
+	}
+	var cleans *trie.CleanCache
+	if cacheConfig.TrieCleanLimit > 0 {
+		cleans = trie.NewCleanCache(cacheConfig.TrieCleanLimit * 1024 * 1024)
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
@@ -156,7 +196,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		cacheConfig:  cacheConfig,
 		db:           db,
 		triegc:       prque.New(),
-		stateCache:   state.NewDatabase(db),
+		stateCache:   state.NewDatabaseWithCache(db, cleans),
 		quit:         make(chan struct{}),
 		bodyCache:    bodyCache,
 		bodyRLPCache: bodyRLPCache,
@@ -182,6 +222,20 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	if cacheConfig.Snapshot {
+		bc.snaps = snapshot.New(db, bc.stateCache.TrieDB(), bc.CurrentBlock().Root())
+	}
+	if cacheConfig.HistoryCache > 0 {
+		lifetime := cacheConfig.HistoryLifetime
+		if lifetime <= 0 {
+			lifetime = historyLifetime
+		}
+		bc.histStates = state.NewHistoryCache(bc.stateCache, cacheConfig.HistoryCache*1024*1024, lifetime)
+	}
+	bc.receiptTail = GetReceiptTail(db)
+	if cacheConfig.ReceiptRetention > 0 {
+		bc.startReceiptPruner(cacheConfig.ReceiptRetention)
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -375,6 +429,21 @@ func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.processor = processor
 }
 
+// SetImportGuard sets the policy consulted before importing blocks. A nil guard
+// permits all blocks.
+func (bc *BlockChain) SetImportGuard(guard *ImportGuard) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+	bc.importGuard = guard
+}
+
+// ImportGuard returns the policy consulted before importing blocks.
+func (bc *BlockChain) ImportGuard() *ImportGuard {
+	bc.procmu.RLock()
+	defer bc.procmu.RUnlock()
+	return bc.importGuard
+}
+
 // SetValidator sets the validator which is used to validate incoming blocks.
 func (bc *BlockChain) SetValidator(validator Validator) {
 	bc.procmu.Lock()
@@ -403,9 +472,71 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	if bc.snaps != nil {
+		return state.NewWithSnapshot(root, bc.stateCache, bc.snaps.Snapshot(root))
+	}
 	return state.New(root, bc.stateCache)
 }
 
+// TrieCleanCache retrieves the clean trie node cache of the chain, for other
+// readers of the state to share it. It is nil if disabled.
+func (bc *BlockChain) TrieCleanCache() *trie.CleanCache {
+	return bc.stateCache.TrieDB().CleanCache()
+}
+
+// HistoricalStateAt returns a new mutable state for the given header. States
+// of popular historical blocks are served from the historical state cache,
+// which materializes them as they are read.
+func (bc *BlockChain) HistoricalStateAt(header *types.Header) (*state.StateDB, error) {
+	if bc.histStates == nil || !bc.popularState(header.Number.Uint64()) {
+		return bc.StateAt(header.Root)
+	}
+	if bc.snaps != nil {
+		if snap := bc.snaps.Snapshot(header.Root); snap != nil {
+			return state.NewWithSnapshot(header.Root, bc.stateCache, snap)
+		}
+	}
+	return state.NewWithSnapshot(header.Root, bc.stateCache, bc.histStates.Snapshot(header.Root))
+}
+
+// popularState reports whether the state of a block is worth caching: that of
+// blocks at round numbers and, on clique chains, at epoch boundaries where
+// explorers look up the signer set. The head state is left to the snapshot.
+func (bc *BlockChain) popularState(number uint64) bool {
+	if number == bc.CurrentBlock().NumberU64() {
+		return false
+	}
+	if interval := bc.cacheConfig.HistoryInterval; interval > 0 && number%interval == 0 {
+		return true
+	}
+	if clique := bc.chainConfig.Clique; clique != nil && clique.IsCheckpoint(number) {
+		return true
+	}
+	return false
+}
+
+// AccountModified reports whether the account was modified by the state
+// transition from parent to root, if known without accessing the states.
+func (bc *BlockChain) AccountModified(root, parent common.Hash, addr common.Address) (modified bool, known bool) {
+	if root == parent {
+		return false, true
+	}
+	if bc.snaps == nil {
+		return false, false
+	}
+	return bc.snaps.AccountModified(root, parent, crypto.Keccak256Hash(addr.Bytes()))
+}
+
+// SnapshotProgress reports the state of the flat state snapshot, or nil if
+// snapshots are disabled.
+func (bc *BlockChain) SnapshotProgress() *snapshot.Progress {
+	if bc.snaps == nil {
+		return nil
+	}
+	progress := bc.snaps.Progress()
+	return &progress
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -661,6 +792,10 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Persist the state snapshot of the head block, which is written below.
+	if bc.snaps != nil {
+		bc.snaps.Stop(bc.CurrentBlock().Root())
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -932,6 +1067,15 @@ func (bc *BlockChain) WriteBlockWithState(ctx context.Context, block *types.Bloc
 	if err != nil {
 		return NonStatTy, err
 	}
+	if bc.snaps != nil {
+		if destructs, accounts, storage := state.SnapshotChanges(); accounts != nil {
+			if parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1); parent != nil {
+				if err := bc.snaps.Update(root, parent.Root, destructs, accounts, storage); err != nil {
+					log.Warn("Failed to update state snapshot", "number", block.Number(), "hash", block.Hash(), "err", err)
+				}
+			}
+		}
+	}
 	triedb := bc.stateCache.TrieDB()
 
 	// If we're running an archive node, always flush
@@ -1019,6 +1163,12 @@ func (bc *BlockChain) WriteBlockWithState(ctx context.Context, block *types.Bloc
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		if bc.snaps != nil {
+			if err := bc.snaps.Cap(root, snapshotLayers); err != nil {
+				log.Warn("Failed to cap state snapshot", "root", root, "err", err)
+			}
+		}
+		bc.schedulePrune(block.NumberU64())
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
@@ -1202,6 +1352,10 @@ func (bc *BlockChain) insertChain(ctx context.Context, chain types.Blocks) (int,
 			bc.reportBlock(block, nil, err)
 			return i, events, coalescedLogs, err
 		}
+		// Give the external import policy, if any, a chance to veto the block.
+		if err := bc.ImportGuard().CheckBlock(ctx, block); err != nil {
+			return i, events, coalescedLogs, err
+		}
 		// Create a new statedb using the parent block and report an
 		// error if it fails.
 		var parent *types.Block
@@ -1210,7 +1364,7 @@ func (bc *BlockChain) insertChain(ctx context.Context, chain types.Blocks) (int,
 		} else {
 			parent = chain[i-1]
 		}
-		state, err := state.New(parent.Root(), bc.stateCache)
+		state, err := bc.StateAt(parent.Root())
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
@@ -1218,12 +1372,14 @@ func (bc *BlockChain) insertChain(ctx context.Context, chain types.Blocks) (int,
 		receipts, logs, usedGas, err := bc.processor.Process(ctx, block, state, bc.vmConfig)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
+			bc.captureBadBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
 		}
 		// Validate the state using the default validator
 		err = bc.Validator().ValidateState(ctx, block, parent, state, receipts, usedGas)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
+			bc.captureBadBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
 		}
 		proctime := time.Since(bstart)
@@ -1431,6 +1587,18 @@ func (bc *BlockChain) reorg(ctx context.Context, oldBlock, newBlock *types.Block
 				bc.chainSideFeed.Send(ChainSideEvent{Block: block})
 			}
 		}()
+		ev := ChainReorgEvent{
+			CommonAncestor: commonBlock,
+			Dropped:        make(types.Blocks, len(oldChain)),
+			Added:          make(types.Blocks, len(newChain)),
+		}
+		for i, block := range oldChain {
+			ev.Dropped[len(oldChain)-1-i] = block
+		}
+		for i, block := range newChain {
+			ev.Added[len(newChain)-1-i] = block
+		}
+		go bc.reorgFeed.Send(ev)
 	}
 
 	return nil
@@ -1476,24 +1644,6 @@ func (bc *BlockChain) update() {
 	}
 }
 
-// BadBlockArgs represents the entries in the list returned when bad blocks are queried.
-type BadBlockArgs struct {
-	Hash   common.Hash   `json:"hash"`
-	Header *types.Header `json:"header"`
-}
-
-// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
-func (bc *BlockChain) BadBlocks() ([]BadBlockArgs, error) {
-	headers := make([]BadBlockArgs, 0, bc.badBlocks.Len())
-	for _, hash := range bc.badBlocks.Keys() {
-		if hdr, exist := bc.badBlocks.Peek(hash); exist {
-			header := hdr.(*types.Header)
-			headers = append(headers, BadBlockArgs{header.Hash(), header})
-		}
-	}
-	return headers, nil
-}
-
 // addBadBlock adds a bad block to the bad-block LRU cache
 func (bc *BlockChain) addBadBlock(block *types.Block) {
 	bc.badBlocks.Add(block.Header().Hash(), block.Header())
@@ -1502,6 +1652,7 @@ func (bc *BlockChain) addBadBlock(block *types.Block) {
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	bc.addBadBlock(block)
+	bc.badBlockFeed.Send(BadBlockEvent{Block: block, Error: err})
 
 	log.Error(fmt.Sprintf(`
 ########## BAD BLOCK #########
@@ -1633,6 +1784,9 @@ func (bc *BlockChain) GetHeaderByNumber(number uint64) *types.Header {
 // Config retrieves the blockchain's chain configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 
+// GetVMConfig returns the block chain VM config.
+func (bc *BlockChain) GetVMConfig() *vm.Config { return &bc.vmConfig }
+
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus.Engine { return bc.engine }
 
@@ -1656,7 +1810,17 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeChainReorgEvent registers a subscription of ChainReorgEvent.
+func (bc *BlockChain) SubscribeChainReorgEvent(ch chan<- ChainReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
+
+// SubscribeBadBlockEvent registers a subscription of BadBlockEvent.
+func (bc *BlockChain) SubscribeBadBlockEvent(ch chan<- BadBlockEvent) event.Subscription {
+	return bc.scope.Track(bc.badBlockFeed.Subscribe(ch))
+}
@@ -1073,6 +1073,65 @@ done:
 
 }
 
+// Tests that a reorg posts the dropped and added blocks in ascending order.
+func TestReorgEvent(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db      = ethdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+		engine  = clique.NewFaker()
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+
+	chain, _ := GenerateChain(ctx, gspec.Config, genesis, engine, db, 3, func(ctx context.Context, i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(ctx, chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	replacementBlocks, _ := GenerateChain(ctx, gspec.Config, genesis, engine, db, 4, func(ctx context.Context, i int, gen *BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+		if i == 2 {
+			gen.SetDifficulty(9)
+		}
+	})
+	reorgCh := make(chan ChainReorgEvent, 1)
+	blockchain.SubscribeChainReorgEvent(reorgCh)
+	if _, err := blockchain.InsertChain(ctx, replacementBlocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+
+	select {
+	case ev := <-reorgCh:
+		if ev.CommonAncestor.Hash() != genesis.Hash() {
+			t.Errorf("common ancestor mismatch: have %x, want %x", ev.CommonAncestor.Hash(), genesis.Hash())
+		}
+		if len(ev.Dropped) != len(chain) {
+			t.Fatalf("dropped block count mismatch: have %d, want %d", len(ev.Dropped), len(chain))
+		}
+		for i, block := range ev.Dropped {
+			if block.Hash() != chain[i].Hash() {
+				t.Errorf("dropped block %d mismatch: have %x, want %x", i, block.Hash(), chain[i].Hash())
+			}
+		}
+		if len(ev.Added) != 3 {
+			t.Fatalf("added block count mismatch: have %d, want 3", len(ev.Added))
+		}
+		for i, block := range ev.Added {
+			if block.Hash() != replacementBlocks[i].Hash() {
+				t.Errorf("added block %d mismatch: have %x, want %x", i, block.Hash(), replacementBlocks[i].Hash())
+			}
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for reorg event")
+	}
+	select {
+	case ev := <-reorgCh:
+		t.Errorf("unexpected reorg event: %+v", ev)
+	case <-time.After(250 * time.Millisecond):
+	}
+}
+
 // Tests if the canonical block can be fetched from the database during chain insertion.
 func TestCanonicalBlockRetrieval(t *testing.T) {
 	ctx := context.Background()
@@ -1451,3 +1510,164 @@ func TestLargeReorgTrieGC(t *testing.T) {
 		}
 	}
 }
+
+// Tests that state reads served from the flat snapshot match the tries, and
+// that the snapshot is persisted and reloaded across restarts.
+func TestSnapshotStateReads(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db      = ethdb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		engine  = clique.NewFaker()
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	const n = 2 * snapshotLayers
+	blocks, _ := GenerateChain(ctx, gspec.Config, genesis, engine, db, n, func(ctx context.Context, i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i%8 + 1)}, big.NewInt(int64(i+1)), 21000, new(big.Int), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(ctx, tx)
+	})
+	diskdb := ethdb.NewMemDatabase()
+	gspec.MustCommit(diskdb)
+
+	cacheConfig := &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, Snapshot: true}
+	chain, err := NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	waitSnapshot := func(chain *BlockChain) {
+		for i := 0; i < 500 && chain.SnapshotProgress().Generating; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if chain.SnapshotProgress().Generating {
+			t.Fatalf("snapshot generation timed out")
+		}
+	}
+	waitSnapshot(chain)
+	for i := range blocks {
+		if _, err := chain.InsertChain(ctx, blocks[i:i+1]); err != nil {
+			t.Fatalf("block %d: failed to insert into chain: %v", i, err)
+		}
+	}
+	if progress := chain.SnapshotProgress(); progress.Layers != snapshotLayers || progress.Root != blocks[n-snapshotLayers-1].Root() {
+		t.Fatalf("snapshot layers mismatch: have %d at %x, want %d at %x", progress.Layers, progress.Root, snapshotLayers, blocks[n-snapshotLayers-1].Root())
+	}
+	addrs := []common.Address{address}
+	for i := 1; i <= 8; i++ {
+		addrs = append(addrs, common.Address{byte(i)})
+	}
+	for _, block := range blocks[n-snapshotLayers-1:] {
+		snapdb, err := chain.StateAt(block.Root())
+		if err != nil {
+			t.Fatalf("block %d: failed to open state: %v", block.NumberU64(), err)
+		}
+		triedb, _ := state.New(block.Root(), chain.stateCache)
+		for _, addr := range addrs {
+			if have, want := snapdb.GetBalance(addr), triedb.GetBalance(addr); have.Cmp(want) != 0 {
+				t.Errorf("block %d, account %x: balance mismatch: have %v, want %v", block.NumberU64(), addr, have, want)
+			}
+			if have, want := snapdb.GetNonce(addr), triedb.GetNonce(addr); have != want {
+				t.Errorf("block %d, account %x: nonce mismatch: have %d, want %d", block.NumberU64(), addr, have, want)
+			}
+		}
+	}
+	// Restart the chain and ensure the snapshot is picked up at the head
+	chain.Stop()
+
+	chain, err = NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to recreate tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if progress := chain.SnapshotProgress(); progress.Generating || progress.Root != blocks[n-1].Root() {
+		t.Fatalf("snapshot not reloaded: generating %v at %x, want head %x", progress.Generating, progress.Root, blocks[n-1].Root())
+	}
+	snapdb, _ := chain.State()
+	triedb, _ := state.New(blocks[n-1].Root(), chain.stateCache)
+	for _, addr := range addrs {
+		if have, want := snapdb.GetBalance(addr), triedb.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("reloaded account %x: balance mismatch: have %v, want %v", addr, have, want)
+		}
+	}
+}
+
+// Tests that receipts older than the retention are pruned, keeping the blocks,
+// and that pruned ranges are reported as such.
+func TestReceiptRetention(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db      = ethdb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{Config: params.TestChainConfig, Alloc: GenesisAlloc{address: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		engine  = clique.NewFaker()
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	const n = 3 * minReceiptRetention
+	blocks, _ := GenerateChain(ctx, gspec.Config, genesis, engine, db, n, func(ctx context.Context, i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{1}, big.NewInt(1), 21000, new(big.Int), nil), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(ctx, tx)
+	})
+	diskdb := ethdb.NewMemDatabase()
+	gspec.MustCommit(diskdb)
+
+	cacheConfig := &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, ReceiptRetention: minReceiptRetention}
+	chain, err := NewBlockChain(diskdb, cacheConfig, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if _, err := chain.InsertChain(ctx, blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	const tail = n - minReceiptRetention
+	for i := 0; i < 500 && chain.ReceiptTail() < tail; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	chain.Stop()
+
+	if have := GetReceiptTail(diskdb); have != tail {
+		t.Fatalf("receipt tail mismatch: have %d, want %d", have, tail)
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		receipts := GetBlockReceipts(diskdb, block.Hash(), number)
+		err := chain.CheckReceiptsRetained(number)
+		if number < tail {
+			if len(receipts) != 0 {
+				t.Errorf("block %d: receipts not pruned", number)
+			}
+			if _, ok := err.(*ReceiptsPrunedError); !ok {
+				t.Errorf("block %d: pruned error mismatch: have %v", number, err)
+			}
+		} else {
+			if len(receipts) != 1 {
+				t.Errorf("block %d: receipts missing", number)
+			}
+			if err != nil {
+				t.Errorf("block %d: unexpected error: %v", number, err)
+			}
+		}
+		if GetBody(diskdb, block.Hash(), number) == nil {
+			t.Errorf("block %d: body missing", number)
+		}
+	}
+	// A restarted chain must pick up the persisted tail.
+	chain, err = NewBlockChain(diskdb, nil, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer chain.Stop()
+	if have := chain.ReceiptTail(); have != tail {
+		t.Errorf("reopened receipt tail mismatch: have %d, want %d", have, tail)
+	}
+}
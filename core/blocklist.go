@@ -0,0 +1,119 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/log"
+)
+
+// ErrBlockedContract is returned if a transaction or call targets a contract
+// on the node's blocklist.
+var ErrBlockedContract = errors.New("contract blocked by node policy")
+
+// BlocklistConfig lists the contracts the node refuses to interact with, by
+// address or by the hash of their code, catching redeployed copies too.
+type BlocklistConfig struct {
+	Addresses  []common.Address `json:"addresses" toml:",omitempty"`
+	CodeHashes []common.Hash    `json:"codeHashes" toml:",omitempty"`
+	AllowCalls bool             `json:"allowCalls" toml:",omitempty"` // Whether eth_call may still execute blocked contracts
+}
+
+// Blocklist is the set of contracts transactions to which are rejected by the
+// pool, and calls to which are refused by the RPC API. It can be changed at
+// runtime and is safe for concurrent use.
+type Blocklist struct {
+	addresses  map[common.Address]struct{}
+	codeHashes map[common.Hash]struct{}
+	allowCalls bool
+	lock       sync.RWMutex
+}
+
+// NewBlocklist creates a blocklist from its configuration.
+func NewBlocklist(config BlocklistConfig) *Blocklist {
+	bl := new(Blocklist)
+	bl.Set(config)
+	return bl
+}
+
+// Set replaces the contents of the blocklist.
+func (bl *Blocklist) Set(config BlocklistConfig) {
+	addresses := make(map[common.Address]struct{}, len(config.Addresses))
+	for _, addr := range config.Addresses {
+		addresses[addr] = struct{}{}
+	}
+	codeHashes := make(map[common.Hash]struct{}, len(config.CodeHashes))
+	for _, hash := range config.CodeHashes {
+		codeHashes[hash] = struct{}{}
+	}
+	bl.lock.Lock()
+	bl.addresses, bl.codeHashes, bl.allowCalls = addresses, codeHashes, config.AllowCalls
+	bl.lock.Unlock()
+
+	if len(addresses) > 0 || len(codeHashes) > 0 {
+		log.Info("Updated contract blocklist", "addresses", len(addresses), "codehashes", len(codeHashes), "allowcalls", config.AllowCalls)
+	}
+}
+
+// Config returns the current contents of the blocklist.
+func (bl *Blocklist) Config() BlocklistConfig {
+	bl.lock.RLock()
+	defer bl.lock.RUnlock()
+
+	config := BlocklistConfig{
+		Addresses:  make([]common.Address, 0, len(bl.addresses)),
+		CodeHashes: make([]common.Hash, 0, len(bl.codeHashes)),
+		AllowCalls: bl.allowCalls,
+	}
+	for addr := range bl.addresses {
+		config.Addresses = append(config.Addresses, addr)
+	}
+	for hash := range bl.codeHashes {
+		config.CodeHashes = append(config.CodeHashes, hash)
+	}
+	return config
+}
+
+// Blocked reports whether the contract at addr, running the code with the
+// given hash, is blocked. A nil blocklist blocks nothing.
+func (bl *Blocklist) Blocked(addr common.Address, codeHash common.Hash) bool {
+	if bl == nil {
+		return false
+	}
+	bl.lock.RLock()
+	defer bl.lock.RUnlock()
+
+	if _, ok := bl.addresses[addr]; ok {
+		return true
+	}
+	_, ok := bl.codeHashes[codeHash]
+	return ok
+}
+
+// CallsAllowed reports whether calls may execute blocked contracts.
+func (bl *Blocklist) CallsAllowed() bool {
+	if bl == nil {
+		return true
+	}
+	bl.lock.RLock()
+	defer bl.lock.RUnlock()
+
+	return bl.allowCalls
+}
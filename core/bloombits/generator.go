@@ -29,19 +29,28 @@ var errSectionOutOfBounds = errors.New("section out of bounds")
 // Generator takes a number of bloom filters and generates the rotated bloom bits
 // to be used for batched filtering.
 type Generator struct {
-	blooms   [types.BloomBitLength][]byte // Rotated blooms for per-bit matching
-	sections uint                         // Number of sections to batch together
-	nextBit  uint                         // Next bit to set when adding a bloom
+	blooms   [][]byte // Rotated blooms for per-bit matching
+	sections uint     // Number of sections to batch together
+	nextBit  uint     // Next bit to set when adding a bloom
 }
 
 // NewGenerator creates a rotated bloom generator that can iteratively fill a
 // batched bloom filter's bits.
 func NewGenerator(sections uint) (*Generator, error) {
+	return NewSizedGenerator(sections, types.BloomBitLength)
+}
+
+// NewSizedGenerator creates a rotated bloom generator for bloom filters of the
+// given number of bits, rather than the header sized ones.
+func NewSizedGenerator(sections, bits uint) (*Generator, error) {
 	if sections%8 != 0 {
 		return nil, errors.New("section count not multiple of 8")
 	}
-	b := &Generator{sections: sections}
-	for i := 0; i < types.BloomBitLength; i++ {
+	if bits == 0 || bits%8 != 0 {
+		return nil, errors.New("bloom size not multiple of 8")
+	}
+	b := &Generator{blooms: make([][]byte, bits), sections: sections}
+	for i := range b.blooms {
 		b.blooms[i] = make([]byte, sections/8)
 	}
 	return b, nil
@@ -50,6 +59,13 @@ func NewGenerator(sections uint) (*Generator, error) {
 // AddBloom takes a single bloom filter and sets the corresponding bit column
 // in memory accordingly.
 func (b *Generator) AddBloom(index uint, bloom types.Bloom) error {
+	return b.AddBits(index, bloom[:])
+}
+
+// AddBits takes a single bloom filter of the generator's size, its bits counted
+// from the end as in header blooms, and sets the corresponding bit column in
+// memory accordingly.
+func (b *Generator) AddBits(index uint, bloom []byte) error {
 	// Make sure we're not adding more bloom filters than our capacity
 	if b.nextBit >= b.sections {
 		return errSectionOutOfBounds
@@ -57,12 +73,15 @@ func (b *Generator) AddBloom(index uint, bloom types.Bloom) error {
 	if b.nextBit != index {
 		return errors.New("bloom filter with unexpected index")
 	}
+	if len(bloom)*8 != len(b.blooms) {
+		return errors.New("bloom filter with unexpected size")
+	}
 	// Rotate the bloom and insert into our collection
 	byteIndex := b.nextBit / 8
 	bitMask := byte(1) << byte(7-b.nextBit%8)
 
-	for i := 0; i < types.BloomBitLength; i++ {
-		bloomByteIndex := types.BloomByteLength - 1 - i/8
+	for i := range b.blooms {
+		bloomByteIndex := len(bloom) - 1 - i/8
 		bloomBitMask := byte(1) << byte(i%8)
 
 		if (bloom[bloomByteIndex] & bloomBitMask) != 0 {
@@ -80,7 +99,7 @@ func (b *Generator) Bitset(idx uint) ([]byte, error) {
 	if b.nextBit != b.sections {
 		return nil, errors.New("bloom not fully generated yet")
 	}
-	if idx >= b.sections {
+	if idx >= uint(len(b.blooms)) {
 		return nil, errSectionOutOfBounds
 	}
 	return b.blooms[idx], nil
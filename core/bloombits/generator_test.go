@@ -21,6 +21,7 @@ import (
 	"math/rand"
 	"testing"
 
+	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/core/types"
 )
 
@@ -58,3 +59,60 @@ func TestGenerator(t *testing.T) {
 		}
 	}
 }
+
+// Tests that bloom filters larger than the header ones are rotated alike.
+func TestSizedGenerator(t *testing.T) {
+	const bits = 4 * types.BloomBitLength
+
+	gen, err := NewSizedGenerator(8, bits)
+	if err != nil {
+		t.Fatalf("failed to create bloombit generator: %v", err)
+	}
+	if err := gen.AddBloom(0, types.Bloom{}); err == nil {
+		t.Fatalf("header bloom accepted by larger generator")
+	}
+	for i := 0; i < 8; i++ {
+		bloom := make([]byte, bits/8)
+		bloom[len(bloom)-1-(bits-1-i)/8] = 1 << byte((bits-1-i)%8) // bit bits-1-i
+		if err := gen.AddBits(uint(i), bloom); err != nil {
+			t.Fatalf("bloom %d: failed to add: %v", i, err)
+		}
+	}
+	for i := 0; i < 8; i++ {
+		have, err := gen.Bitset(uint(bits - 1 - i))
+		if err != nil {
+			t.Fatalf("bit %d: failed to retrieve bits: %v", bits-1-i, err)
+		}
+		if want := byte(1) << byte(7-i); have[0] != want {
+			t.Errorf("bit %d: bit vector mismatch have %08b, want %08b", bits-1-i, have[0], want)
+		}
+	}
+}
+
+// Tests that header sized log blooms match the header ones, and that larger
+// ones set the requested number of bits per key.
+func TestLogsBloom(t *testing.T) {
+	logs := []*types.Log{
+		{Address: common.Address{0x01}, Topics: []common.Hash{{0x02}, {0x03}}},
+		{Address: common.Address{0x04}},
+	}
+	want := types.BytesToBloom(types.LogsBloom(logs).Bytes())
+	if have := LogsBloom(logs, types.BloomBitLength, 3); !bytes.Equal(have, want[:]) {
+		t.Errorf("header sized bloom mismatch: have %x, want %x", have, want)
+	}
+	bloom := LogsBloom(logs[1:], 65536, 16)
+	var set int
+	for _, b := range bloom {
+		for ; b != 0; b &= b - 1 {
+			set++
+		}
+	}
+	if len(bloom) != 8192 || set == 0 || set > 16 {
+		t.Errorf("large bloom mismatch: %d bytes, %d bits set", len(bloom), set)
+	}
+	for _, idx := range calcBloomIndexes(logs[1].Address[:], 65536, 16) {
+		if bloom[len(bloom)-1-int(idx/8)]&(1<<(idx%8)) == 0 {
+			t.Errorf("bit %d of the address not set", idx)
+		}
+	}
+}
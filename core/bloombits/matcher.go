@@ -20,31 +20,54 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/bitutil"
+	"github.com/fulcrumchain/indigo/core/types"
 	"github.com/fulcrumchain/indigo/crypto"
 )
 
 // bloomIndexes represents the bit indexes inside the bloom filter that belong
 // to some key.
-type bloomIndexes [3]uint
+type bloomIndexes []uint
 
-// calcBloomIndexes returns the bloom filter bit indexes belonging to the given key.
-func calcBloomIndexes(b []byte) bloomIndexes {
+// calcBloomIndexes returns the bit indexes belonging to the given key in bloom
+// filters of the given size, a power of two, setting the given number of bits
+// per key. Header blooms set 3 of 2048 bits.
+func calcBloomIndexes(b []byte, bits, hashes uint) bloomIndexes {
 	h := crypto.Keccak256Hash(b)
 
-	var idxs bloomIndexes
-	for i := 0; i < len(idxs); i++ {
-		idxs[i] = (uint(h[2*i])<<8)&2047 + uint(h[2*i+1])
+	idxs := make(bloomIndexes, hashes)
+	for i := range idxs {
+		idxs[i] = (uint(h[2*i])<<8 | uint(h[2*i+1])) & (bits - 1)
 	}
 	return idxs
 }
 
+// LogsBloom returns the bloom filter of the given size and number of bits per
+// key over the addresses and topics of the logs, laid out as header blooms.
+func LogsBloom(logs []*types.Log, bits, hashes uint) []byte {
+	bloom := make([]byte, bits/8)
+	add := func(key []byte) {
+		for _, idx := range calcBloomIndexes(key, bits, hashes) {
+			bloom[len(bloom)-1-int(idx/8)] |= 1 << (idx % 8)
+		}
+	}
+	for _, log := range logs {
+		add(log.Address[:])
+		for _, topic := range log.Topics {
+			add(topic[:])
+		}
+	}
+	return bloom
+}
+
 // partialMatches with a non-nil vector represents a section in which some sub-
 // matchers have already found potential matches. Subsequent sub-matchers will
 // binary AND their matches with this vector. If vector is nil, it represents a
@@ -84,12 +107,20 @@ type Matcher struct {
 	deliveries chan *Retrieval      // Retriever processes waiting for task response deliveries
 
 	running uint32 // Atomic flag whether a session is live or not
+	err     error  // Invalid bloom parameters the matcher was created with
 }
 
 // NewMatcher creates a new pipeline for retrieving bloom bit streams and doing
 // address and topic filtering on them. Setting a filter component to `nil` is
 // allowed and will result in that filter rule being skipped (OR 0x11...1).
 func NewMatcher(sectionSize uint64, filters [][][]byte) *Matcher {
+	return NewSizedMatcher(sectionSize, types.BloomBitLength, 3, filters)
+}
+
+// NewSizedMatcher creates a matcher over an index of bloom filters of the given
+// size and number of bits per key, rather than the header blooms. A matcher
+// created with a number of bits per key it can't index refuses to start.
+func NewSizedMatcher(sectionSize uint64, bits, hashes uint, filters [][][]byte) *Matcher {
 	// Create the matcher instance
 	m := &Matcher{
 		sectionSize: sectionSize,
@@ -99,6 +130,11 @@ func NewMatcher(sectionSize uint64, filters [][][]byte) *Matcher {
 		retrievals:  make(chan chan *Retrieval),
 		deliveries:  make(chan *Retrieval),
 	}
+	// Each bit index is picked by two bytes of the key hash
+	if hashes == 0 || hashes > uint(len(common.Hash{})/2) {
+		m.err = fmt.Errorf("invalid bloom hash count %d", hashes)
+		return m
+	}
 	// Calculate the bloom bit indexes for the groups we're interested in
 	m.filters = nil
 
@@ -113,7 +149,7 @@ func NewMatcher(sectionSize uint64, filters [][][]byte) *Matcher {
 				bloomBits = nil
 				break
 			}
-			bloomBits[i] = calcBloomIndexes(clause)
+			bloomBits[i] = calcBloomIndexes(clause, bits, hashes)
 		}
 		// Accumulate the filter rules if no nil rule was within
 		if bloomBits != nil {
@@ -145,6 +181,9 @@ func (m *Matcher) addScheduler(idx uint) {
 // a given range of blocks. If there are no more matches in the range, the result
 // channel is closed.
 func (m *Matcher) Start(ctx context.Context, begin, end uint64, results chan uint64) (*MatcherSession, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
 	// Make sure we're not creating concurrent sessions
 	if atomic.SwapUint32(&m.running, 1) == 1 {
 		return nil, errors.New("matcher already running")
@@ -259,9 +298,11 @@ func (m *Matcher) run(begin, end uint64, buffer int, session *MatcherSession) ch
 // that address/topic, and binary AND-ing those vectors together.
 func (m *Matcher) subMatch(source chan *partialMatches, dist chan *request, bloom []bloomIndexes, session *MatcherSession) chan *partialMatches {
 	// Start the concurrent schedulers for each bit required by the bloom filter
-	sectionSources := make([][3]chan uint64, len(bloom))
-	sectionSinks := make([][3]chan []byte, len(bloom))
+	sectionSources := make([][]chan uint64, len(bloom))
+	sectionSinks := make([][]chan []byte, len(bloom))
 	for i, bits := range bloom {
+		sectionSources[i] = make([]chan uint64, len(bits))
+		sectionSinks[i] = make([]chan []byte, len(bits))
 		for j, bit := range bits {
 			sectionSources[i][j] = make(chan uint64, cap(source))
 			sectionSinks[i][j] = make(chan []byte, cap(source))
@@ -95,6 +95,17 @@ func TestMatcherShifted(t *testing.T) {
 	testMatcherBothModes(t, [][]bloomIndexes{{{16, 16, 16}}}, 9, 64, 0)
 }
 
+// Tests that matchers over blooms setting no bits per key, or more than a hash
+// can pick, refuse to start rather than hang.
+func TestSizedMatcherInvalidHashes(t *testing.T) {
+	filter := [][][]byte{{{0xde, 0xad}}}
+	for _, hashes := range []uint{0, 17} {
+		if _, err := NewSizedMatcher(testSectionSize, 2048, hashes, filter).Start(context.Background(), 0, 1, make(chan uint64)); err == nil {
+			t.Errorf("matcher with %d hashes started", hashes)
+		}
+	}
+}
+
 // Tests that matching on everything doesn't crash (special case internally).
 func TestWildcardMatcher(t *testing.T) {
 	testMatcherBothModes(t, nil, 0, 10000, 0)
@@ -108,6 +119,7 @@ func makeRandomIndexes(lengths []int, max int) [][]bloomIndexes {
 	for i, topics := range lengths {
 		res[i] = make([]bloomIndexes, topics)
 		for j := 0; j < topics; j++ {
+			res[i][j] = make(bloomIndexes, 3)
 			for k := 0; k < len(res[i][j]); k++ {
 				res[i][j][k] = uint(rand.Intn(max-1) + 2)
 			}
@@ -28,8 +28,28 @@ import (
 	"github.com/fulcrumchain/indigo/ethdb"
 	"github.com/fulcrumchain/indigo/event"
 	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
 )
 
+// indexRateWeight is the weight of a new measurement in the moving average of
+// the section processing rate.
+const indexRateWeight = 0.2
+
+// IndexerThrottle limits how fast a chain indexer catches up on a backlog of
+// sections, so backfilling an imported chain leaves disk capacity for the node.
+type IndexerThrottle struct {
+	SectionRate float64 `json:"sectionRate" toml:",omitempty"` // Maximum sections processed per second, 0 for unlimited
+	IOBudget    uint64  `json:"ioBudget" toml:",omitempty"`    // Disk bytes per second the node may use while indexing, 0 for unlimited
+}
+
+// ChainIndexerStatus reports the progress of a chain indexer.
+type ChainIndexerStatus struct {
+	Processed uint64          `json:"processed"` // Number of sections indexed
+	Pending   uint64          `json:"pending"`   // Number of complete sections waiting to be indexed
+	Rate      float64         `json:"rate"`      // Sections indexed per second while catching up, 0 once caught up
+	Throttle  IndexerThrottle `json:"throttle"`
+}
+
 // ChainIndexerBackend defines the methods needed to process chain segments in
 // the background and write the segment results into the database. These can be
 // used to create filter blooms or CHTs.
@@ -81,7 +101,9 @@ type ChainIndexer struct {
 	knownSections  uint64 // Number of sections known to be complete (block wise)
 	cascadedHead   uint64 // Block number of the last completed section cascaded to subindexers
 
-	throttling time.Duration // Disk throttling to prevent a heavy upgrade from hogging resources
+	throttling time.Duration   // Disk throttling to prevent a heavy upgrade from hogging resources
+	throttle   IndexerThrottle // Configurable pacing of the section processing while catching up
+	rate       float64         // Moving average of the sections processed per second
 
 	log  log.Logger
 	lock sync.RWMutex
@@ -122,6 +144,50 @@ func (c *ChainIndexer) AddKnownSectionHead(section uint64, shead common.Hash) {
 	c.setValidSections(section + 1)
 }
 
+// SetThrottle sets the limits on the pace sections are processed at while the
+// indexer is catching up.
+func (c *ChainIndexer) SetThrottle(throttle IndexerThrottle) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.throttle = throttle
+}
+
+// Status reports the progress of the indexer.
+func (c *ChainIndexer) Status() ChainIndexerStatus {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	status := ChainIndexerStatus{
+		Processed: c.storedSections,
+		Throttle:  c.throttle,
+	}
+	if c.knownSections > c.storedSections {
+		status.Pending = c.knownSections - c.storedSections
+		status.Rate = c.rate
+	}
+	return status
+}
+
+// pace returns how long to wait before processing the next section, given how
+// long the last one took and how many bytes the process moved on disk meanwhile.
+//
+// Caller must hold c.lock.
+func (c *ChainIndexer) pace(elapsed time.Duration, diskBytes uint64) time.Duration {
+	delay := c.throttling
+	if c.throttle.SectionRate > 0 {
+		if wait := time.Duration(float64(time.Second)/c.throttle.SectionRate) - elapsed; wait > delay {
+			delay = wait
+		}
+	}
+	if c.throttle.IOBudget > 0 {
+		if wait := time.Duration(float64(diskBytes)/float64(c.throttle.IOBudget)*float64(time.Second)) - elapsed; wait > delay {
+			delay = wait
+		}
+	}
+	return delay
+}
+
 // Start creates a goroutine to feed chain head events into the indexer for
 // cascading background processing. Children do not need to be started, they
 // are notified about new events by their parents.
@@ -263,8 +329,10 @@ func (c *ChainIndexer) newHead(head uint64, reorg bool) {
 // down into the processing backend.
 func (c *ChainIndexer) updateLoop() {
 	var (
-		updating bool
-		updated  time.Time
+		updating  bool
+		updated   time.Time
+		completed time.Time // Time the last section was processed
+		delay     time.Duration
 	)
 
 	for {
@@ -294,16 +362,44 @@ func (c *ChainIndexer) updateLoop() {
 				}
 				// Process the newly defined section in the background
 				c.lock.Unlock()
+				var disk metrics.DiskStats
+				diskErr := metrics.ReadDiskStats(&disk)
+				start := time.Now()
+
 				newHead, err := c.processSection(section, oldHead)
 				if err != nil {
 					c.log.Error("Section processing failed", "error", err)
 				}
+				elapsed := time.Since(start)
+				var diskBytes uint64
+				if diskErr == nil {
+					before := disk.ReadBytes + disk.WriteBytes
+					if metrics.ReadDiskStats(&disk) == nil && disk.ReadBytes+disk.WriteBytes > before {
+						diskBytes = uint64(disk.ReadBytes + disk.WriteBytes - before)
+					}
+				}
 				c.lock.Lock()
+				delay = c.pace(elapsed, diskBytes)
 
 				// If processing succeeded and no reorgs occcurred, mark the section completed
 				if err == nil && oldHead == c.SectionHead(section-1) {
 					c.setSectionHead(section, newHead)
 					c.setValidSections(section + 1)
+
+					// Track the processing rate, including the pacing delays
+					now := time.Now()
+					if !completed.IsZero() {
+						rate := 1 / now.Sub(completed).Seconds()
+						if c.rate == 0 {
+							c.rate = rate
+						} else {
+							c.rate += indexRateWeight * (rate - c.rate)
+						}
+					}
+					completed = now
+					if c.storedSections == c.knownSections {
+						completed, c.rate = time.Time{}, 0
+					}
 					if c.storedSections == c.knownSections && updating {
 						updating = false
 						c.log.Info("Finished upgrading chain index")
@@ -322,7 +418,7 @@ func (c *ChainIndexer) updateLoop() {
 			}
 			// If there are still further sections to process, reschedule
 			if c.knownSections > c.storedSections {
-				time.AfterFunc(c.throttling, func() {
+				time.AfterFunc(delay, func() {
 					select {
 					case c.update <- struct{}{}:
 					default:
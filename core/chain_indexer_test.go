@@ -234,3 +234,43 @@ func (b *testChainIndexBackend) Commit() error {
 	}
 	return nil
 }
+
+// Tests that the throttle paces the section processing by rate and disk usage.
+func TestChainIndexerPace(t *testing.T) {
+	c := &ChainIndexer{throttling: 10 * time.Millisecond}
+
+	tests := []struct {
+		throttle IndexerThrottle
+		elapsed  time.Duration
+		disk     uint64
+		want     time.Duration
+	}{
+		{IndexerThrottle{}, time.Second, 1 << 30, 10 * time.Millisecond},
+		{IndexerThrottle{SectionRate: 2}, 100 * time.Millisecond, 0, 400 * time.Millisecond},
+		{IndexerThrottle{SectionRate: 2}, time.Second, 0, 10 * time.Millisecond},
+		{IndexerThrottle{IOBudget: 1 << 20}, 500 * time.Millisecond, 4 << 20, 3500 * time.Millisecond},
+		{IndexerThrottle{IOBudget: 1 << 20}, 500 * time.Millisecond, 256 << 10, 10 * time.Millisecond},
+		{IndexerThrottle{SectionRate: 1, IOBudget: 1 << 20}, 0, 2 << 20, 2 * time.Second},
+	}
+	for i, tt := range tests {
+		c.throttle = tt.throttle
+		if delay := c.pace(tt.elapsed, tt.disk); delay != tt.want {
+			t.Errorf("test %d: delay mismatch: have %v, want %v", i, delay, tt.want)
+		}
+	}
+}
+
+// Tests that the status reports the indexing backlog.
+func TestChainIndexerStatus(t *testing.T) {
+	throttle := IndexerThrottle{SectionRate: 5}
+	c := &ChainIndexer{storedSections: 3, knownSections: 10, rate: 4.5}
+	c.SetThrottle(throttle)
+
+	if status := c.Status(); status.Processed != 3 || status.Pending != 7 || status.Rate != 4.5 || status.Throttle != throttle {
+		t.Errorf("catching up status mismatch: %+v", status)
+	}
+	c.storedSections = 10
+	if status := c.Status(); status.Processed != 10 || status.Pending != 0 || status.Rate != 0 {
+		t.Errorf("caught up status mismatch: %+v", status)
+	}
+}
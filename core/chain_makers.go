@@ -160,11 +160,11 @@ func GenerateChain(ctx context.Context, config *params.ChainConfig, first *types
 		b.header = &types.Header{
 			Root:       statedb.IntermediateRoot(b.config.IsEIP158(parent.Number())),
 			ParentHash: parent.Hash(),
-			GasLimit:   CalcGasLimit(parent),
+			GasLimit:   CalcChainGasLimit(config, parent),
 			Number:     new(big.Int).Add(parent.Number(), common.Big1),
 			Signers:    parent.Signers(),
 			Voters:     parent.Voters(),
-			Time:       new(big.Int).Add(parent.Time(), new(big.Int).SetUint64(config.Clique.Period)),
+			Time:       new(big.Int).Add(parent.Time(), new(big.Int).SetUint64(config.Clique.PeriodAt(parent.NumberU64()+1))),
 		}
 
 		// Execute any user modifications to the block and finalize it
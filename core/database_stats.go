@@ -0,0 +1,247 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/internal/jobs"
+)
+
+// Categories of the chain database entries reported by InspectDatabase.
+const (
+	DBHeaders         = "headers"
+	DBTotalDifficulty = "total difficulties"
+	DBCanonicalHashes = "canonical hashes"
+	DBHeaderNumbers   = "header numbers"
+	DBBodies          = "bodies"
+	DBReceipts        = "receipts"
+	DBTxLookups       = "transaction lookups"
+	DBBloomBits       = "bloom bits"
+	DBAddressIndex    = "address index"
+	DBInternalIndex   = "internal transaction index"
+	DBTrieNodes       = "trie nodes and code"
+	DBSnapshot        = "state snapshot"
+	DBPreimages       = "preimages"
+	DBIndexMeta       = "index metadata"
+	DBLightTries      = "light client tries"
+	DBMetadata        = "metadata"
+	DBOther           = "other"
+)
+
+var (
+	// Key prefixes of the database entries maintained outside this package,
+	// which can't be imported here.
+	snapshotAccountPrefix = []byte("a") // see core/state/snapshot
+	snapshotStoragePrefix = []byte("o") // see core/state/snapshot
+	lightTriePrefixes     = [][]byte{[]byte("chtRoot-"), []byte("cht-"), []byte("bltRoot-"), []byte("blt-")}
+
+	// metadataKeys are the singleton entries tracking the state of the chain.
+	metadataKeys = [][]byte{headHeaderKey, headBlockKey, headFastKey, receiptTailKey}
+)
+
+// dbCategory classifies a chain database entry by its key.
+func dbCategory(key []byte) string {
+	switch {
+	case len(key) == common.HashLength:
+		return DBTrieNodes
+	case bytes.HasPrefix(key, []byte(preimagePrefix)):
+		return DBPreimages
+	case bytes.HasPrefix(key, configPrefix):
+		return DBMetadata
+	case bytes.HasPrefix(key, BloomBitsIndexPrefix), bytes.HasPrefix(key, AddressIndexPrefix), bytes.HasPrefix(key, InternalIndexPrefix):
+		return DBIndexMeta
+	}
+	for _, prefix := range lightTriePrefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return DBLightTries
+		}
+	}
+	for _, meta := range metadataKeys {
+		if bytes.Equal(key, meta) {
+			return DBMetadata
+		}
+	}
+	switch {
+	case len(key) == 41 && key[0] == headerPrefix:
+		return DBHeaders
+	case len(key) == 42 && key[0] == headerPrefix && key[41] == tdSuffix:
+		return DBTotalDifficulty
+	case len(key) == 10 && key[0] == headerPrefix && key[9] == numSuffix:
+		return DBCanonicalHashes
+	case len(key) == 33 && key[0] == blockHashPrefix:
+		return DBHeaderNumbers
+	case len(key) == 41 && key[0] == bodyPrefix:
+		return DBBodies
+	case len(key) == 41 && key[0] == blockReceiptsPrefix:
+		return DBReceipts
+	case len(key) == 33 && key[0] == lookupPrefix:
+		return DBTxLookups
+	case len(key) == 43 && key[0] == bloomBitsPrefix:
+		return DBBloomBits
+	case len(key) == 33 && key[0] == addressTxPrefix:
+		return DBAddressIndex
+	case len(key) == 33 && key[0] == internalTxPrefix:
+		return DBInternalIndex
+	case len(key) == 33 && bytes.HasPrefix(key, snapshotAccountPrefix),
+		len(key) == 65 && bytes.HasPrefix(key, snapshotStoragePrefix):
+		return DBSnapshot
+	}
+	return DBOther
+}
+
+// DBCategoryStats are the number and size of the entries of a category.
+type DBCategoryStats struct {
+	Category string             `json:"category"`
+	Count    uint64             `json:"count"`
+	Size     common.StorageSize `json:"size"` // Total size of keys and values
+}
+
+// DBStats are the results of a chain database inspection. Sampled inspections
+// report estimates scaled up from the entries scanned.
+type DBStats struct {
+	Sample     uint64             `json:"sample"`  // One in Sample key ranges scanned, 1 for full scans
+	Scanned    uint64             `json:"scanned"` // Number of entries scanned
+	Count      uint64             `json:"count"`
+	Size       common.StorageSize `json:"size"`
+	Categories []*DBCategoryStats `json:"categories"` // Largest categories first
+	Elapsed    time.Duration      `json:"elapsed"`
+}
+
+// dbStripe is a range of the database key space scanned during an inspection,
+// whose entries are counted weight times.
+type dbStripe struct {
+	start, limit []byte
+	weight       float64
+}
+
+// dbStripes splits the key space by the first two bytes of the keys, to scan
+// one in sample of the ranges. Ranges holding entries keyed by block number
+// or by a long prefix are too dense to be sampled, and are always scanned.
+func dbStripes(sample uint64) []dbStripe {
+	dense := make(map[uint16]bool)
+	for _, prefix := range append([][]byte{[]byte(preimagePrefix), configPrefix, BloomBitsIndexPrefix, AddressIndexPrefix, InternalIndexPrefix}, append(lightTriePrefixes, metadataKeys...)...) {
+		dense[uint16(prefix[0])<<8|uint16(prefix[1])] = true
+	}
+	var (
+		full, sparse []dbStripe
+		selected     int
+	)
+	for i := 0; i <= 0xffff; i++ {
+		stripe := dbStripe{start: []byte{byte(i >> 8), byte(i)}, weight: 1}
+		if byte(i) == 0 {
+			stripe.start = stripe.start[:1]
+		}
+		if i == 0 {
+			stripe.start = nil
+		}
+		if next := i + 1; next <= 0xffff {
+			stripe.limit = []byte{byte(next >> 8), byte(next)}
+			if byte(next) == 0 {
+				stripe.limit = stripe.limit[:1]
+			}
+		}
+		switch {
+		case byte(i) == 0:
+			// Keys by block number (or shorter than two bytes) sit at the start
+			full = append(full, stripe)
+		case dense[uint16(i)]:
+			full = append(full, stripe)
+		default:
+			if uint64(len(sparse))%sample == 0 {
+				selected++
+			} else {
+				stripe.weight = 0
+			}
+			sparse = append(sparse, stripe)
+		}
+	}
+	stripes := full
+	for _, stripe := range sparse {
+		if stripe.weight > 0 {
+			stripe.weight = float64(len(sparse)) / float64(selected)
+			stripes = append(stripes, stripe)
+		}
+	}
+	// Scan in key order, for the database to read ahead
+	sort.Slice(stripes, func(i, j int) bool { return bytes.Compare(stripes[i].start, stripes[j].start) < 0 })
+	return stripes
+}
+
+// InspectDatabase reports the number and size of the chain database entries
+// by category. Databases are scanned fully if sample is at most 1, otherwise
+// only one in sample of the key ranges is scanned, and the results estimated.
+func InspectDatabase(ctx context.Context, db ethdb.Database, sample uint64) (*DBStats, error) {
+	start := time.Now()
+	if sample == 0 {
+		sample = 1
+	}
+	var (
+		scanned uint64
+		counts  = make(map[string]float64)
+		sizes   = make(map[string]float64)
+	)
+	add := func(key, value []byte, weight float64) {
+		category := dbCategory(key)
+		counts[category] += weight
+		sizes[category] += weight * float64(len(key)+len(value))
+		scanned++
+	}
+	if _, ok := db.(*ethdb.MemDatabase); ok {
+		// Memory databases are small enough to be scanned fully
+		sample = 1
+	}
+	stripes := dbStripes(sample)
+	for i, stripe := range stripes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		jobs.ReportProgress(ctx, uint64(i), uint64(len(stripes)))
+		it := db.NewRangeIterator(stripe.start, stripe.limit)
+		for it.Next() {
+			add(it.Key(), it.Value(), stripe.weight)
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	}
+	stats := &DBStats{Sample: sample, Scanned: scanned}
+	for category, count := range counts {
+		entry := &DBCategoryStats{
+			Category: category,
+			Count:    uint64(count + 0.5),
+			Size:     common.StorageSize(sizes[category]),
+		}
+		stats.Categories = append(stats.Categories, entry)
+		stats.Count += entry.Count
+		stats.Size += entry.Size
+	}
+	sort.Slice(stats.Categories, func(i, j int) bool {
+		if stats.Categories[i].Size != stats.Categories[j].Size {
+			return stats.Categories[i].Size > stats.Categories[j].Size
+		}
+		return stats.Categories[i].Category < stats.Categories[j].Category
+	})
+	stats.Elapsed = time.Since(start)
+	return stats, nil
+}
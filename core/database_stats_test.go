@@ -0,0 +1,142 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+// fillStatsDatabase writes a few headers, along with their number entries, and
+// many trie node look-alikes.
+func fillStatsDatabase(t *testing.T, db ethdb.Database, headers, nodes int) {
+	for i := 0; i < headers; i++ {
+		if err := WriteHeader(db, &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("test header")}); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+	}
+	for i := 0; i < nodes; i++ {
+		key := crypto.Keccak256(big.NewInt(int64(i)).Bytes())
+		if err := db.Put(key, make([]byte, 100)); err != nil {
+			t.Fatalf("failed to write node: %v", err)
+		}
+	}
+	if err := WriteHeadHeaderHash(db, common.Hash{1}); err != nil {
+		t.Fatalf("failed to write head header: %v", err)
+	}
+}
+
+func categoryStats(stats *DBStats, category string) *DBCategoryStats {
+	for _, entry := range stats.Categories {
+		if entry.Category == category {
+			return entry
+		}
+	}
+	return &DBCategoryStats{Category: category}
+}
+
+// Tests that the key space is split into contiguous ranges covering all keys.
+func TestDBStripes(t *testing.T) {
+	for _, sample := range []uint64{1, 16} {
+		stripes := dbStripes(sample)
+		var weight float64
+		for _, stripe := range stripes {
+			weight += stripe.weight
+		}
+		if weight < 65535.5 || weight > 65536.5 {
+			t.Errorf("sample %d: weight mismatch: have %v, want 65536", sample, weight)
+		}
+	}
+	stripes := dbStripes(1)
+	if stripes[0].start != nil {
+		t.Errorf("first range starts at %x", stripes[0].start)
+	}
+	for i := 1; i < len(stripes); i++ {
+		if string(stripes[i-1].limit) != string(stripes[i].start) {
+			t.Fatalf("range %d: gap or overlap: limit %x, next start %x", i, stripes[i-1].limit, stripes[i].start)
+		}
+	}
+	if last := stripes[len(stripes)-1]; last.limit != nil {
+		t.Errorf("last range ends at %x", last.limit)
+	}
+}
+
+func TestInspectDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := ethdb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	fillStatsDatabase(t, db, 100, 20000)
+
+	// Full scans count every entry
+	stats, err := InspectDatabase(context.Background(), db, 1)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	if stats.Scanned != stats.Count || stats.Count != 2*100+20000+1 {
+		t.Errorf("entry count mismatch: scanned %d, counted %d", stats.Scanned, stats.Count)
+	}
+	if n := categoryStats(stats, DBHeaders).Count; n != 100 {
+		t.Errorf("header count mismatch: have %d, want 100", n)
+	}
+	if n := categoryStats(stats, DBTrieNodes).Count; n != 20000 {
+		t.Errorf("trie node count mismatch: have %d, want 20000", n)
+	}
+	if size := categoryStats(stats, DBTrieNodes).Size; size != 20000*132 {
+		t.Errorf("trie node size mismatch: have %v, want %v", size, 20000*132)
+	}
+	if n := categoryStats(stats, DBMetadata).Count; n != 1 {
+		t.Errorf("metadata count mismatch: have %d, want 1", n)
+	}
+	// Sampled scans count dense ranges exactly and estimate the rest
+	stats, err = InspectDatabase(context.Background(), db, 8)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	if stats.Scanned >= 2*100+20000/2 {
+		t.Errorf("sampled inspection scanned %d entries", stats.Scanned)
+	}
+	if n := categoryStats(stats, DBHeaders).Count; n != 100 {
+		t.Errorf("sampled header count mismatch: have %d, want 100", n)
+	}
+	if n := categoryStats(stats, DBTrieNodes).Count; n < 18000 || n > 22000 {
+		t.Errorf("sampled trie node count off: have %d, want ~20000", n)
+	}
+	// In-memory databases are always scanned fully
+	mem := ethdb.NewMemDatabase()
+	fillStatsDatabase(t, mem, 10, 10)
+	if stats, err = InspectDatabase(context.Background(), mem, 8); err != nil {
+		t.Fatalf("failed to inspect memory database: %v", err)
+	}
+	if stats.Sample != 1 || stats.Count != 31 {
+		t.Errorf("memory database stats mismatch: sample %d, count %d", stats.Sample, stats.Count)
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 
 	"github.com/fulcrumchain/indigo/common"
@@ -54,6 +55,8 @@ const (
 	blockReceiptsPrefix byte = 'r' // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        byte = 'l' // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     byte = 'B' // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	addressTxPrefix     byte = 'A' // addressTxPrefix + address + num (uint64 big endian) + index (uint32 big endian) -> block hash
+	internalTxPrefix    byte = 'I' // internalTxPrefix + address + num (uint64 big endian) + index (uint32 big endian) -> internal transaction
 )
 
 // DBArchivePrefixes is the set of key prefixes which are eligible for archival.
@@ -76,11 +79,15 @@ var (
 	headBlockKey  = []byte("LastBlock")
 	headFastKey   = []byte("LastFast")
 
+	receiptTailKey = []byte("ReceiptTail") // receiptTailKey tracks the oldest block with retained receipts
+
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	AddressIndexPrefix   = []byte("iA") // AddressIndexPrefix is the data table of the address transaction indexer to track its progress
+	InternalIndexPrefix  = []byte("iI") // InternalIndexPrefix is the data table of the internal transaction indexer to track its progress
 
 	// used by old db, now only used for conversion
 	oldReceiptsPrefix = []byte("receipts-")
@@ -152,6 +159,16 @@ func GetHeadBlockHash(db DatabaseReader) common.Hash {
 	return common.BytesToHash(data)
 }
 
+// GetReceiptTail retrieves the number of the oldest block whose receipts have
+// not been pruned.
+func GetReceiptTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(receiptTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
 // GetHeadFastBlockHash retrieves the hash of the current canonical head block during
 // fast synchronization. The difference between this and GetHeadBlockHash is that
 // whereas the last block hash is only updated upon a full block import, the last
@@ -404,6 +421,15 @@ func WriteHeadBlockHash(db ethdb.Putter, hash common.Hash) error {
 	return nil
 }
 
+// WriteReceiptTail stores the number of the oldest block whose receipts have
+// not been pruned.
+func WriteReceiptTail(db ethdb.Putter, number uint64) error {
+	if err := db.Put(receiptTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store receipt tail", "err", err)
+	}
+	return nil
+}
+
 // WriteHeadFastBlockHash stores the fast head block's hash.
 func WriteHeadFastBlockHash(db ethdb.Putter, hash common.Hash) error {
 	if err := db.Put(headFastKey, hash.Bytes()); err != nil {
@@ -513,6 +539,150 @@ func WriteTxLookupEntries(db ethdb.Putter, block *types.Block) error {
 	return nil
 }
 
+// WriteAddressTx indexes the transaction at the given position of a block under
+// an address it was sent from or to.
+func WriteAddressTx(db ethdb.Putter, addr common.Address, number uint64, index uint32, blockHash common.Hash) error {
+	return db.Put(addressTxKey(addr, number, index), blockHash.Bytes())
+}
+
+// AddressTx is the position of a transaction indexed under an address.
+type AddressTx struct {
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Index       uint32
+}
+
+// GetAddressTxs retrieves the positions of the canonical transactions indexed
+// under an address within the given block range (both inclusive), in chain
+// order. The first skip matches are passed over and at most limit are returned.
+func GetAddressTxs(db ethdb.Database, addr common.Address, from, to uint64, skip, limit int) ([]AddressTx, error) {
+	start, end := addressTxKey(addr, from, 0), addressTxKey(addr, to, math.MaxUint32)
+
+	var (
+		txs       []AddressTx
+		canonical = make(map[uint64]common.Hash)
+	)
+	// add appends the entry if it belongs to the canonical chain, skipping the
+	// ones left behind by reorgs, and reports whether more entries are wanted.
+	add := func(key, value []byte) bool {
+		tx := AddressTx{
+			BlockHash:   common.BytesToHash(value),
+			BlockNumber: binary.BigEndian.Uint64(key[1+common.AddressLength:]),
+			Index:       binary.BigEndian.Uint32(key[1+common.AddressLength+8:]),
+		}
+		hash, ok := canonical[tx.BlockNumber]
+		if !ok {
+			hash = GetCanonicalHash(db, tx.BlockNumber)
+			canonical[tx.BlockNumber] = hash
+		}
+		if hash != tx.BlockHash {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		txs = append(txs, tx)
+		return len(txs) < limit
+	}
+	it := db.NewRangeIterator(start, append(end, 0))
+	defer it.Release()
+
+	for it.Next() && add(it.Key(), it.Value()) {
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// addressTxKey = addressTxPrefix + address + num (uint64 big endian) + index (uint32 big endian)
+func addressTxKey(addr common.Address, number uint64, index uint32) []byte {
+	key := make([]byte, 1+common.AddressLength+8+4)
+	key[0] = addressTxPrefix
+	copy(key[1:], addr[:])
+	binary.BigEndian.PutUint64(key[1+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[1+common.AddressLength+8:], index)
+	return key
+}
+
+// InternalTx is a value transfer made by a contract while executing a
+// transaction, indexed under its sender and recipient.
+type InternalTx struct {
+	BlockHash   common.Hash
+	BlockNumber uint64 `rlp:"-"`
+	Index       uint32 `rlp:"-"` // Position among the internal transactions of the block
+	TxHash      common.Hash
+	TxIndex     uint32
+	From        common.Address
+	To          common.Address
+	Value       *big.Int
+}
+
+// WriteInternalTx indexes an internal transaction of a block under an address
+// it was sent from or to.
+func WriteInternalTx(db ethdb.Putter, addr common.Address, number uint64, index uint32, tx *InternalTx) error {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	return db.Put(internalTxKey(addr, number, index), data)
+}
+
+// GetInternalTxs retrieves the canonical internal transactions indexed under an
+// address within the given block range (both inclusive), in chain order. The
+// first skip matches are passed over and at most limit are returned.
+func GetInternalTxs(db ethdb.Database, addr common.Address, from, to uint64, skip, limit int) ([]InternalTx, error) {
+	start, end := internalTxKey(addr, from, 0), internalTxKey(addr, to, math.MaxUint32)
+
+	var (
+		txs       []InternalTx
+		canonical = make(map[uint64]common.Hash)
+	)
+	it := db.NewRangeIterator(start, append(end, 0))
+	defer it.Release()
+
+	for it.Next() {
+		var tx InternalTx
+		if err := rlp.DecodeBytes(it.Value(), &tx); err != nil {
+			return nil, err
+		}
+		tx.BlockNumber = binary.BigEndian.Uint64(it.Key()[1+common.AddressLength:])
+		tx.Index = binary.BigEndian.Uint32(it.Key()[1+common.AddressLength+8:])
+
+		// Skip the entries left behind by reorgs
+		hash, ok := canonical[tx.BlockNumber]
+		if !ok {
+			hash = GetCanonicalHash(db, tx.BlockNumber)
+			canonical[tx.BlockNumber] = hash
+		}
+		if hash != tx.BlockHash {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if txs = append(txs, tx); len(txs) >= limit {
+			break
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return txs, nil
+}
+
+// internalTxKey = internalTxPrefix + address + num (uint64 big endian) + index (uint32 big endian)
+func internalTxKey(addr common.Address, number uint64, index uint32) []byte {
+	key := make([]byte, 1+common.AddressLength+8+4)
+	key[0] = internalTxPrefix
+	copy(key[1:], addr[:])
+	binary.BigEndian.PutUint64(key[1+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[1+common.AddressLength+8:], index)
+	return key
+}
+
 // WriteBloomBits writes the compressed bloom bits vector belonging to the given
 // section and bit index.
 func WriteBloomBits(db ethdb.Putter, bit uint, section uint64, head common.Hash, bits []byte) {
@@ -16,7 +16,13 @@
 
 package core
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/fulcrumchain/indigo/common"
+)
 
 var (
 	// ErrKnownBlock is returned when a block to import is already known locally.
@@ -33,3 +39,43 @@ var (
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
 )
+
+// StateRootError is returned when the state root computed by executing a block
+// differs from the one in its header.
+type StateRootError struct {
+	Number *big.Int
+	Remote common.Hash // Root in the header
+	Local  common.Hash // Root computed locally
+}
+
+func (e *StateRootError) Error() string {
+	return fmt.Sprintf("invalid merkle root #%s (remote: %x local: %x)", e.Number, e.Remote, e.Local)
+}
+
+// BlockNotFoundError is returned when a block requested by hash is not known.
+type BlockNotFoundError struct {
+	Hash common.Hash
+}
+
+func (e *BlockNotFoundError) Error() string {
+	return fmt.Sprintf("header for hash %x not found", e.Hash)
+}
+
+// ErrorCode returns the JSON-RPC error code recommended by EIP-1898 for missing
+// resources.
+func (e *BlockNotFoundError) ErrorCode() int { return -32001 }
+
+// NonCanonicalBlockError is returned when a block requested by hash is required
+// to be canonical, but is not part of the canonical chain (anymore).
+type NonCanonicalBlockError struct {
+	Hash   common.Hash
+	Number uint64
+}
+
+func (e *NonCanonicalBlockError) Error() string {
+	return fmt.Sprintf("block %d hash %x is not currently canonical", e.Number, e.Hash)
+}
+
+// ErrorCode returns the JSON-RPC error code, distinguishing reorged blocks from
+// other failures.
+func (e *NonCanonicalBlockError) ErrorCode() int { return 4445 }
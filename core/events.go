@@ -24,6 +24,28 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxLifecycleKind is the lifecycle transition reported by a TxLifecycleEvent.
+type TxLifecycleKind string
+
+const (
+	TxQueued   TxLifecycleKind = "queued"   // Entered the non-executable queue
+	TxPromoted TxLifecycleKind = "promoted" // Became executable
+	TxReplaced TxLifecycleKind = "replaced" // Replaced by a transaction with the same nonce
+	TxDropped  TxLifecycleKind = "dropped"  // Removed from the pool without being included
+	TxIncluded TxLifecycleKind = "included" // Removed from the pool after inclusion in a block
+)
+
+// TxLifecycleEvent is posted by the transaction pool whenever a pooled
+// transaction changes state.
+type TxLifecycleEvent struct {
+	Hash        common.Hash
+	Kind        TxLifecycleKind
+	Reason      string      // Reason a dropped transaction was dropped
+	ReplacedBy  common.Hash // Replacement of a replaced transaction
+	BlockHash   common.Hash // Block an included transaction was included in
+	BlockNumber uint64
+}
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
@@ -32,6 +54,13 @@ type PendingLogsEvent struct {
 // PendingStateEvent is posted pre mining and notifies of pending state changes.
 type PendingStateEvent struct{}
 
+// PendingBlockEvent is sent each time the miner updates the block it works on,
+// along with the receipts of its transactions.
+type PendingBlockEvent struct {
+	Block    *types.Block
+	Receipts types.Receipts
+}
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
@@ -49,3 +78,18 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// BadBlockEvent is posted when a block is rejected on import.
+type BadBlockEvent struct {
+	Block *types.Block
+	Error error
+}
+
+// ChainReorgEvent is posted when the canonical chain is reorganized. Dropped
+// holds the blocks removed from the canonical chain and Added the blocks that
+// replaced them, both in ascending order above CommonAncestor.
+type ChainReorgEvent struct {
+	CommonAncestor *types.Block
+	Dropped        types.Blocks
+	Added          types.Blocks
+}
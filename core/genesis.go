@@ -30,6 +30,7 @@ import (
 	"github.com/fulcrumchain/indigo/common/math"
 	"github.com/fulcrumchain/indigo/core/state"
 	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
 	"github.com/fulcrumchain/indigo/ethdb"
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/params"
@@ -167,6 +168,31 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	if genesis != nil && genesis.Config == nil {
 		return params.AllCliqueProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
+	if genesis != nil && genesis.Config.Clique != nil {
+		if err := genesis.Config.Clique.CheckSchedule(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
+	if genesis != nil && genesis.Config.GasLimit != nil {
+		if err := genesis.Config.GasLimit.Check(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
+	if genesis != nil && genesis.Config.GasSponsor != nil {
+		if err := genesis.Config.GasSponsor.Check(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
+	if genesis != nil && genesis.Config.Bloom != nil {
+		if err := genesis.Config.Bloom.Check(); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
+	if genesis != nil {
+		if err := vm.CheckPrecompiles(genesis.Config); err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+	}
 
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
@@ -267,6 +293,9 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 	}
 	if g.GasLimit == 0 {
 		head.GasLimit = params.GenesisGasLimit
+		if g.Config != nil && g.Config.GasLimit != nil && g.Config.GasLimit.Initial != 0 {
+			head.GasLimit = g.Config.GasLimit.Initial
+		}
 	}
 	if g.Difficulty == nil {
 		head.Difficulty = big.NewInt(1)
@@ -237,3 +237,21 @@ func TestGenesisAlloc_Total(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the initial gas limit of the chain configuration applies to
+// genesis blocks without one, and that unreachable targets are refused.
+func TestGenesisGasLimitConfig(t *testing.T) {
+	config := *params.TestChainConfig
+	config.GasLimit = &params.GasLimitConfig{Initial: 6000000, Target: 8000000}
+
+	if limit := (&Genesis{Config: &config}).ToBlock(nil).GasLimit(); limit != 6000000 {
+		t.Errorf("unset genesis gas limit mismatch: have %d, want %d", limit, 6000000)
+	}
+	if limit := (&Genesis{Config: &config, GasLimit: 7000000}).ToBlock(nil).GasLimit(); limit != 7000000 {
+		t.Errorf("set genesis gas limit mismatch: have %d, want %d", limit, 7000000)
+	}
+	config.GasLimit = &params.GasLimitConfig{Target: params.MinGasLimit - 1}
+	if _, _, err := SetupGenesisBlock(ethdb.NewMemDatabase(), &Genesis{Config: &config}); err == nil {
+		t.Errorf("unreachable gas limit target accepted")
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opencensus.io/trace"
+
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+var (
+	// ErrImportVetoed is returned if an import hook rejected a block or transaction.
+	ErrImportVetoed = errors.New("import vetoed by policy")
+
+	// ErrImportHookFailed is returned if an import hook failed to deliver a
+	// verdict and the guard is configured to fail closed.
+	ErrImportHookFailed = errors.New("import policy unavailable")
+)
+
+var (
+	importHookTimer    = metrics.NewTimer("chain/importhook/checks")
+	importHookVetoes   = metrics.NewCounter("chain/importhook/vetoes")
+	importHookFailures = metrics.NewCounter("chain/importhook/failures")
+)
+
+// ImportVerdict is the decision of an import hook about a block or transaction.
+type ImportVerdict struct {
+	Reject      bool              `json:"reject"`                // Whether to refuse the import
+	Reason      string            `json:"reason,omitempty"`      // Human readable explanation of a rejection
+	Annotations map[string]string `json:"annotations,omitempty"` // Free form labels attached to the item, logged on import
+}
+
+// ImportHook is an external policy consulted before blocks are imported into
+// the chain and before transactions are accepted into the pool. Hooks may veto
+// an import or annotate it.
+type ImportHook interface {
+	// CheckBlock is called for every block before it is processed.
+	CheckBlock(ctx context.Context, block *types.Block) (*ImportVerdict, error)
+
+	// CheckTransaction is called for every transaction before it enters the pool.
+	CheckTransaction(ctx context.Context, tx *types.Transaction) (*ImportVerdict, error)
+}
+
+// ImportGuard consults an ImportHook with a bounded wait, applying the failure
+// policy if the hook errors or times out. A nil guard permits everything.
+type ImportGuard struct {
+	hook     ImportHook
+	timeout  time.Duration // Maximum time to wait for a verdict, 0 for no limit
+	failOpen bool          // Whether to permit imports if no verdict is delivered
+}
+
+// NewImportGuard creates a guard around hook. If failOpen is set, imports are
+// permitted when the hook fails or doesn't answer within timeout; otherwise
+// they are rejected.
+func NewImportGuard(hook ImportHook, timeout time.Duration, failOpen bool) *ImportGuard {
+	return &ImportGuard{hook: hook, timeout: timeout, failOpen: failOpen}
+}
+
+// CheckBlock returns a non-nil error if the block must not be imported.
+func (g *ImportGuard) CheckBlock(ctx context.Context, block *types.Block) error {
+	if g == nil {
+		return nil
+	}
+	ctx, span := trace.StartSpan(ctx, "ImportGuard.CheckBlock")
+	defer span.End()
+
+	verdict, err := g.check(ctx, func(ctx context.Context) (*ImportVerdict, error) {
+		return g.hook.CheckBlock(ctx, block)
+	})
+	return g.apply(verdict, err, "block", "number", block.Number(), "hash", block.Hash())
+}
+
+// CheckTransaction returns a non-nil error if the transaction must not be
+// accepted.
+func (g *ImportGuard) CheckTransaction(ctx context.Context, tx *types.Transaction) error {
+	if g == nil {
+		return nil
+	}
+	ctx, span := trace.StartSpan(ctx, "ImportGuard.CheckTransaction")
+	defer span.End()
+
+	verdict, err := g.check(ctx, func(ctx context.Context) (*ImportVerdict, error) {
+		return g.hook.CheckTransaction(ctx, tx)
+	})
+	return g.apply(verdict, err, "transaction", "hash", tx.Hash())
+}
+
+// check runs fn with the configured timeout.
+func (g *ImportGuard) check(ctx context.Context, fn func(context.Context) (*ImportVerdict, error)) (*ImportVerdict, error) {
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+	type result struct {
+		verdict *ImportVerdict
+		err     error
+	}
+	start := time.Now()
+	defer importHookTimer.UpdateSince(start)
+
+	// Run the hook asynchronously, so misbehaving hooks ignoring the context
+	// can't stall the import.
+	done := make(chan result, 1)
+	go func() {
+		verdict, err := fn(ctx)
+		done <- result{verdict, err}
+	}()
+	select {
+	case res := <-done:
+		return res.verdict, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// apply turns a verdict, or the failure to get one, into an import decision.
+func (g *ImportGuard) apply(verdict *ImportVerdict, err error, kind string, logCtx ...interface{}) error {
+	if err != nil {
+		importHookFailures.Inc(1)
+		if g.failOpen {
+			log.Warn(fmt.Sprintf("Import policy failed, permitting %s", kind), append(logCtx, "err", err)...)
+			return nil
+		}
+		log.Warn(fmt.Sprintf("Import policy failed, rejecting %s", kind), append(logCtx, "err", err)...)
+		return fmt.Errorf("%v: %v", ErrImportHookFailed, err)
+	}
+	if verdict == nil {
+		return nil
+	}
+	if len(verdict.Annotations) > 0 {
+		log.Info(fmt.Sprintf("Import policy annotated %s", kind), append(logCtx, "annotations", verdict.Annotations)...)
+	}
+	if verdict.Reject {
+		importHookVetoes.Inc(1)
+		log.Warn(fmt.Sprintf("Import policy rejected %s", kind), append(logCtx, "reason", verdict.Reason)...)
+		return fmt.Errorf("%v: %s", ErrImportVetoed, verdict.Reason)
+	}
+	return nil
+}
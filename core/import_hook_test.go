@@ -0,0 +1,171 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// testImportHook is an ImportHook with canned behaviour.
+type testImportHook struct {
+	rejectBlocks map[common.Hash]bool
+	rejectTxs    map[common.Hash]bool
+	delay        time.Duration
+	err          error
+}
+
+func (h *testImportHook) verdict(ctx context.Context, reject bool) (*ImportVerdict, error) {
+	if h.delay > 0 {
+		select {
+		case <-time.After(h.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if h.err != nil {
+		return nil, h.err
+	}
+	if reject {
+		return &ImportVerdict{Reject: true, Reason: "screened"}, nil
+	}
+	return &ImportVerdict{Annotations: map[string]string{"risk": "low"}}, nil
+}
+
+func (h *testImportHook) CheckBlock(ctx context.Context, block *types.Block) (*ImportVerdict, error) {
+	return h.verdict(ctx, h.rejectBlocks[block.Hash()])
+}
+
+func (h *testImportHook) CheckTransaction(ctx context.Context, tx *types.Transaction) (*ImportVerdict, error) {
+	return h.verdict(ctx, h.rejectTxs[tx.Hash()])
+}
+
+// Tests that the guard applies verdicts and the failure policy.
+func TestImportGuard(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	tx := transaction(0, 100000, key)
+
+	tests := []struct {
+		hook     *testImportHook
+		failOpen bool
+		want     error
+	}{
+		{hook: &testImportHook{}, want: nil},
+		{hook: &testImportHook{rejectTxs: map[common.Hash]bool{tx.Hash(): true}}, want: ErrImportVetoed},
+		{hook: &testImportHook{rejectTxs: map[common.Hash]bool{tx.Hash(): true}}, failOpen: true, want: ErrImportVetoed},
+		{hook: &testImportHook{err: errors.New("boom")}, want: ErrImportHookFailed},
+		{hook: &testImportHook{err: errors.New("boom")}, failOpen: true, want: nil},
+		{hook: &testImportHook{delay: time.Second}, want: ErrImportHookFailed},
+		{hook: &testImportHook{delay: time.Second}, failOpen: true, want: nil},
+	}
+	for i, tt := range tests {
+		guard := NewImportGuard(tt.hook, 50*time.Millisecond, tt.failOpen)
+		err := guard.CheckTransaction(ctx, tx)
+		switch {
+		case tt.want == nil && err != nil:
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		case tt.want != nil && (err == nil || !strings.HasPrefix(err.Error(), tt.want.Error())):
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// A nil guard must permit everything.
+	var guard *ImportGuard
+	if err := guard.CheckTransaction(ctx, tx); err != nil {
+		t.Errorf("nil guard rejected transaction: %v", err)
+	}
+}
+
+// Tests that vetoed blocks are not imported into the chain.
+func TestImportHookBlockVeto(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db      = ethdb.NewMemDatabase()
+		engine  = clique.NewFaker()
+		genesis = new(Genesis).MustCommit(db)
+	)
+	blocks, _ := GenerateChain(ctx, params.TestChainConfig, genesis, engine, db, 4, func(ctx context.Context, i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	})
+	diskdb := ethdb.NewMemDatabase()
+	new(Genesis).MustCommit(diskdb)
+
+	chain, err := NewBlockChain(diskdb, nil, params.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	hook := &testImportHook{rejectBlocks: map[common.Hash]bool{blocks[2].Hash(): true}}
+	chain.SetImportGuard(NewImportGuard(hook, time.Second, false))
+
+	n, err := chain.InsertChain(ctx, blocks)
+	if err == nil || !strings.HasPrefix(err.Error(), ErrImportVetoed.Error()) {
+		t.Fatalf("insert error mismatch: have %v, want %v", err, ErrImportVetoed)
+	}
+	if n != 2 {
+		t.Errorf("failed block index mismatch: have %d, want 2", n)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 2 {
+		t.Errorf("head mismatch: have %d, want 2", head)
+	}
+	// Lifting the veto allows the rest of the chain in.
+	delete(hook.rejectBlocks, blocks[2].Hash())
+	if _, err := chain.InsertChain(ctx, blocks[2:]); err != nil {
+		t.Fatalf("failed to insert remaining blocks: %v", err)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 4 {
+		t.Errorf("head mismatch: have %d, want 4", head)
+	}
+}
+
+// Tests that vetoed transactions are not accepted into the pool.
+func TestImportHookTransactionVeto(t *testing.T) {
+	ctx := context.Background()
+	pool, key := setupTxPool(ctx)
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+
+	vetoed, accepted := transaction(0, 100000, key), transaction(1, 100000, key)
+	pool.SetImportGuard(NewImportGuard(&testImportHook{rejectTxs: map[common.Hash]bool{vetoed.Hash(): true}}, time.Second, false))
+
+	if err := pool.AddRemote(ctx, vetoed); err == nil || !strings.HasPrefix(err.Error(), ErrImportVetoed.Error()) {
+		t.Fatalf("vetoed transaction error mismatch: have %v, want %v", err, ErrImportVetoed)
+	}
+	if err := pool.AddRemote(ctx, accepted); err != nil {
+		t.Fatalf("failed to add permitted transaction: %v", err)
+	}
+	if pool.Get(vetoed.Hash()) != nil {
+		t.Errorf("vetoed transaction in pool")
+	}
+	if pool.Get(accepted.Hash()) == nil {
+		t.Errorf("permitted transaction missing from pool")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package importhook implements a core.ImportHook backed by an external policy
+// service reachable over gRPC.
+//
+// The service must implement the indigo.importhook.ImportPolicy service with
+// the unary methods CheckBlock and CheckTransaction. Messages are exchanged as
+// JSON (content-subtype "json"): requests are BlockRequest and TxRequest, the
+// response to both is a core.ImportVerdict.
+package importhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const (
+	checkBlockMethod       = "/indigo.importhook.ImportPolicy/CheckBlock"
+	checkTransactionMethod = "/indigo.importhook.ImportPolicy/CheckTransaction"
+)
+
+// Config configures the external import policy.
+type Config struct {
+	Endpoint string        `toml:",omitempty"` // gRPC endpoint (host:port) of the policy service, empty to disable.
+	Timeout  time.Duration `toml:",omitempty"` // Maximum time to wait for a verdict.
+	FailOpen bool          `toml:",omitempty"` // Permit imports if the service fails or times out, instead of rejecting them.
+}
+
+// DefaultConfig contains the default import policy settings.
+var DefaultConfig = Config{
+	Timeout: 2 * time.Second,
+}
+
+// TxRequest is the JSON request sent for a transaction.
+type TxRequest struct {
+	Hash     common.Hash     `json:"hash"`
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+	Value    *hexutil.Big    `json:"value"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Input    hexutil.Bytes   `json:"input"`
+	Raw      hexutil.Bytes   `json:"raw"` // RLP encoding of the signed transaction
+}
+
+// BlockRequest is the JSON request sent for a block.
+type BlockRequest struct {
+	Hash         common.Hash    `json:"hash"`
+	ParentHash   common.Hash    `json:"parentHash"`
+	Number       *hexutil.Big   `json:"number"`
+	Time         *hexutil.Big   `json:"timestamp"`
+	Coinbase     common.Address `json:"miner"`
+	Transactions []*TxRequest   `json:"transactions"`
+}
+
+// Client is a core.ImportHook consulting a remote policy service.
+type Client struct {
+	conn   *grpc.ClientConn
+	signer types.Signer
+}
+
+// Dial connects to the policy service at endpoint. Transaction senders are
+// derived with signer. The connection is established lazily, so an unreachable
+// service surfaces as a failed check rather than a startup error.
+func Dial(endpoint string, signer types.Signer) (*Client, error) {
+	conn, err := grpc.Dial(endpoint, grpc.WithInsecure())
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, signer: signer}, nil
+}
+
+// Close tears down the connection to the policy service.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// CheckBlock implements core.ImportHook.
+func (c *Client) CheckBlock(ctx context.Context, block *types.Block) (*core.ImportVerdict, error) {
+	req := &BlockRequest{
+		Hash:         block.Hash(),
+		ParentHash:   block.ParentHash(),
+		Number:       (*hexutil.Big)(block.Number()),
+		Time:         (*hexutil.Big)(block.Time()),
+		Coinbase:     block.Coinbase(),
+		Transactions: make([]*TxRequest, len(block.Transactions())),
+	}
+	for i, tx := range block.Transactions() {
+		txReq, err := c.newTxRequest(ctx, tx)
+		if err != nil {
+			return nil, err
+		}
+		req.Transactions[i] = txReq
+	}
+	verdict := new(core.ImportVerdict)
+	if err := c.conn.Invoke(ctx, checkBlockMethod, req, verdict, grpc.CallCustomCodec(jsonCodec{})); err != nil {
+		return nil, err
+	}
+	return verdict, nil
+}
+
+// CheckTransaction implements core.ImportHook.
+func (c *Client) CheckTransaction(ctx context.Context, tx *types.Transaction) (*core.ImportVerdict, error) {
+	req, err := c.newTxRequest(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	verdict := new(core.ImportVerdict)
+	if err := c.conn.Invoke(ctx, checkTransactionMethod, req, verdict, grpc.CallCustomCodec(jsonCodec{})); err != nil {
+		return nil, err
+	}
+	return verdict, nil
+}
+
+func (c *Client) newTxRequest(ctx context.Context, tx *types.Transaction) (*TxRequest, error) {
+	from, err := types.Sender(ctx, c.signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &TxRequest{
+		Hash:     tx.Hash(),
+		From:     from,
+		To:       tx.To(),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Value:    (*hexutil.Big)(tx.Value()),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Input:    tx.Data(),
+		Raw:      raw,
+	}, nil
+}
+
+// jsonCodec is a gRPC codec exchanging messages as JSON, so that the policy
+// service doesn't require generated protobuf bindings.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) String() string                             { return "json" }
@@ -0,0 +1,123 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package importhook
+
+import (
+	"context"
+	"math/big"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+)
+
+// testPolicy is a policy service rejecting transactions to a blocked address.
+type testPolicy struct {
+	blocked common.Address
+	methods []string
+}
+
+func (p *testPolicy) handle(srv interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	p.methods = append(p.methods, method)
+
+	var txs []*TxRequest
+	switch method {
+	case checkBlockMethod:
+		req := new(BlockRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		txs = req.Transactions
+	case checkTransactionMethod:
+		req := new(TxRequest)
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		txs = []*TxRequest{req}
+	}
+	verdict := &core.ImportVerdict{Annotations: map[string]string{"screened": "true"}}
+	for _, tx := range txs {
+		if tx.To != nil && *tx.To == p.blocked {
+			verdict.Reject, verdict.Reason = true, "blocked recipient"
+		}
+	}
+	return stream.SendMsg(verdict)
+}
+
+func TestClient(t *testing.T) {
+	policy := &testPolicy{blocked: common.Address{0xba, 0xd}}
+	server := grpc.NewServer(grpc.CustomCodec(jsonCodec{}), grpc.UnknownServiceHandler(policy.handle))
+	defer server.Stop()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+
+	signer := types.HomesteadSigner{}
+	client, err := Dial(listener.Addr().String(), signer)
+	if err != nil {
+		t.Fatalf("failed to dial policy service: %v", err)
+	}
+	defer client.Close()
+
+	key, _ := crypto.GenerateKey()
+	sign := func(to common.Address) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(0, to, big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+		return tx
+	}
+	ctx := context.Background()
+
+	verdict, err := client.CheckTransaction(ctx, sign(common.Address{1}))
+	if err != nil {
+		t.Fatalf("failed to check transaction: %v", err)
+	}
+	if verdict.Reject || verdict.Annotations["screened"] != "true" {
+		t.Errorf("permitted transaction verdict mismatch: %+v", verdict)
+	}
+	verdict, err = client.CheckTransaction(ctx, sign(policy.blocked))
+	if err != nil {
+		t.Fatalf("failed to check transaction: %v", err)
+	}
+	if !verdict.Reject || verdict.Reason != "blocked recipient" {
+		t.Errorf("blocked transaction verdict mismatch: %+v", verdict)
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{sign(common.Address{1}), sign(policy.blocked)}, nil, nil)
+	verdict, err = client.CheckBlock(ctx, block)
+	if err != nil {
+		t.Fatalf("failed to check block: %v", err)
+	}
+	if !verdict.Reject {
+		t.Errorf("block with blocked transaction permitted: %+v", verdict)
+	}
+	want := []string{checkTransactionMethod, checkTransactionMethod, checkBlockMethod}
+	if len(policy.methods) != len(want) {
+		t.Fatalf("method calls mismatch: have %v, want %v", policy.methods, want)
+	}
+	for i := range want {
+		if policy.methods[i] != want[i] {
+			t.Errorf("method call %d mismatch: have %s, want %s", i, policy.methods[i], want[i])
+		}
+	}
+}
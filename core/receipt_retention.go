@@ -0,0 +1,146 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/log"
+)
+
+const (
+	// minReceiptRetention is the smallest accepted receipt retention. Receipts of
+	// recent blocks are needed to emit removed logs on reorgs.
+	minReceiptRetention = triesInMemory
+
+	// receiptPruneChunk is the number of blocks pruned between progress updates.
+	receiptPruneChunk = 1024
+)
+
+// ReceiptsPrunedError is returned when the receipts and logs of a block have
+// been removed by the retention policy.
+type ReceiptsPrunedError struct {
+	Number uint64 // Requested block
+	Tail   uint64 // Oldest block with retained receipts
+}
+
+func (e *ReceiptsPrunedError) Error() string {
+	return fmt.Sprintf("receipts and logs of block %d pruned, history retained from block %d", e.Number, e.Tail)
+}
+
+// ErrorCode returns the JSON-RPC error code, distinguishing pruned history from
+// other failures.
+func (e *ReceiptsPrunedError) ErrorCode() int { return 4444 }
+
+// ReceiptTail returns the number of the oldest block whose receipts and logs
+// are retained.
+func (bc *BlockChain) ReceiptTail() uint64 {
+	return atomic.LoadUint64(&bc.receiptTail)
+}
+
+// CheckReceiptsRetained returns a *ReceiptsPrunedError if the receipts of the
+// block with the given number have been pruned.
+func (bc *BlockChain) CheckReceiptsRetained(number uint64) error {
+	if tail := bc.ReceiptTail(); number < tail {
+		return &ReceiptsPrunedError{Number: number, Tail: tail}
+	}
+	return nil
+}
+
+// startReceiptPruner launches the background pruning of receipts older than
+// retention blocks.
+func (bc *BlockChain) startReceiptPruner(retention uint64) {
+	if retention < minReceiptRetention {
+		log.Warn("Receipt retention too low, increasing", "provided", retention, "updated", minReceiptRetention)
+		retention = minReceiptRetention
+	}
+	bc.receiptRetention = retention
+	bc.pruneCh = make(chan struct{}, 1)
+
+	bc.wg.Add(1)
+	go bc.receiptPruner()
+	bc.schedulePrune(bc.CurrentBlock().NumberU64())
+}
+
+// schedulePrune requests pruning relative to the given head. Requests arriving
+// while the pruner is busy are coalesced into the latest head.
+func (bc *BlockChain) schedulePrune(head uint64) {
+	if bc.pruneCh == nil {
+		return
+	}
+	atomic.StoreUint64(&bc.pruneHead, head)
+	select {
+	case bc.pruneCh <- struct{}{}:
+	default:
+	}
+}
+
+func (bc *BlockChain) receiptPruner() {
+	defer bc.wg.Done()
+	for {
+		select {
+		case <-bc.pruneCh:
+			bc.pruneReceipts(atomic.LoadUint64(&bc.pruneHead))
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// pruneReceipts deletes the receipts of canonical blocks more than the retention
+// below head. The tail is advanced before deletion, so that readers see pruned
+// ranges as such rather than as missing receipts.
+func (bc *BlockChain) pruneReceipts(head uint64) {
+	if head <= bc.receiptRetention {
+		return
+	}
+	var (
+		limit = head - bc.receiptRetention
+		tail  = bc.ReceiptTail()
+		start = time.Now()
+		from  = tail
+	)
+	for tail < limit {
+		end := tail + receiptPruneChunk
+		if end > limit {
+			end = limit
+		}
+		atomic.StoreUint64(&bc.receiptTail, end)
+		for number := tail; number < end; number++ {
+			if hash := GetCanonicalHash(bc.db, number); hash != (common.Hash{}) {
+				DeleteBlockReceipts(bc.db, hash, number)
+			}
+		}
+		WriteReceiptTail(bc.db, end)
+		tail = end
+
+		select {
+		case <-bc.quit:
+			log.Info("Receipt pruning interrupted", "from", from, "tail", tail)
+			return
+		default:
+		}
+	}
+	if limit-from > receiptPruneChunk {
+		log.Info("Pruned old receipts", "from", from, "tail", tail, "elapsed", common.PrettyDuration(time.Since(start)))
+	} else if limit > from {
+		log.Debug("Pruned old receipts", "from", from, "tail", tail)
+	}
+}
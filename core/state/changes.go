@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+)
+
+// AccountChange is an account written to the state trie, with its consensus
+// encoding afterwards and the storage slots written.
+type AccountChange struct {
+	Address common.Address  `json:"address"`
+	Account hexutil.Bytes   `json:"account"` // RLP of the account, empty if deleted
+	Storage []StorageChange `json:"storage,omitempty"`
+}
+
+// StorageChange is a storage slot written to the trie of an account.
+type StorageChange struct {
+	Key   common.Hash `json:"key"`
+	Value common.Hash `json:"value"`
+}
+
+// changeRecorder collects the writes to the state tries between two calls to
+// StateDB.Changes.
+type changeRecorder struct {
+	reported map[common.Address][]byte                      // Account encodings last reported
+	accounts map[common.Address][]byte                      // Account encodings written since, nil if deleted
+	storage  map[common.Address]map[common.Hash]common.Hash // Storage slots written since
+}
+
+// account records an account written to the trie, nil if deleted.
+func (r *changeRecorder) account(addr common.Address, enc []byte) {
+	r.accounts[addr] = enc
+}
+
+// slot records a storage slot written to the trie of an account.
+func (r *changeRecorder) slot(addr common.Address, key, value common.Hash) {
+	if r.storage[addr] == nil {
+		r.storage[addr] = make(map[common.Hash]common.Hash)
+	}
+	r.storage[addr][key] = value
+}
+
+// TrackChanges starts recording the accounts and storage slots written to the
+// state tries, as reported by Changes.
+func (db *StateDB) TrackChanges() {
+	db.changes = &changeRecorder{
+		reported: make(map[common.Address][]byte),
+		accounts: make(map[common.Address][]byte),
+		storage:  make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+// Changes returns the accounts and storage slots written to the state tries by
+// the finalisations since TrackChanges or the previous call, ordered by address.
+// Accounts written again with the same content and no storage change are left
+// out. It returns nil if the changes are not tracked.
+func (db *StateDB) Changes() []*AccountChange {
+	r := db.changes
+	if r == nil {
+		return nil
+	}
+	var changes []*AccountChange
+	for addr, enc := range r.accounts {
+		prev, seen := r.reported[addr]
+		if seen && bytes.Equal(prev, enc) && len(r.storage[addr]) == 0 {
+			continue
+		}
+		r.reported[addr] = enc
+
+		change := &AccountChange{Address: addr, Account: common.CopyBytes(enc)}
+		for key, value := range r.storage[addr] {
+			change.Storage = append(change.Storage, StorageChange{Key: key, Value: value})
+		}
+		sort.Slice(change.Storage, func(i, j int) bool {
+			return bytes.Compare(change.Storage[i].Key[:], change.Storage[j].Key[:]) < 0
+		})
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(changes[i].Address[:], changes[j].Address[:]) < 0
+	})
+	r.accounts = make(map[common.Address][]byte)
+	r.storage = make(map[common.Address]map[common.Hash]common.Hash)
+	return changes
+}
@@ -76,9 +76,16 @@ type Trie interface {
 // intermediate trie-node memory pool between the low level storage layer and the
 // high level trie abstraction.
 func NewDatabase(db ethdb.Database) Database {
+	return NewDatabaseWithCache(db, nil)
+}
+
+// NewDatabaseWithCache creates a backing store for state, reading the trie nodes
+// persisted to disk through the given clean cache, which may be shared with the
+// other state databases of the same disk database.
+func NewDatabaseWithCache(db ethdb.Database, cleans *trie.CleanCache) Database {
 	csc, _ := lru.New(codeSizeCacheSize)
 	return &cachingDB{
-		db:            trie.NewDatabase(db),
+		db:            trie.NewDatabaseWithCache(db, cleans),
 		codeSizeCache: csc,
 	}
 }
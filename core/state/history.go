@@ -0,0 +1,251 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/trie"
+)
+
+var (
+	historyHitMeter   = metrics.NewMeter("state/history/hit")
+	historyMissMeter  = metrics.NewMeter("state/history/miss")
+	historyEvictMeter = metrics.NewMeter("state/history/evict")
+	historySizeGauge  = metrics.NewGauge("state/history/size")
+)
+
+// historyItemOverhead approximates the memory held by a cached item besides its
+// key and value.
+const historyItemOverhead = 64
+
+// HistoryCache keeps flat copies of historical states materialized as they are
+// read, so that repeated queries against the same past blocks are served from
+// memory instead of resolving trie nodes again. States are evicted least
+// recently used first once the cache outgrows its memory budget, and expire a
+// fixed time after they were admitted regardless of their use.
+type HistoryCache struct {
+	db       Database
+	budget   int           // Memory budget of all states in bytes
+	lifetime time.Duration // Time after which an admitted state expires
+
+	size   int                           // Memory held by all states in bytes
+	states map[common.Hash]*list.Element // Cached states by root
+	lru    *list.List                    // Cached states, most recently used first
+	lock   sync.Mutex
+}
+
+// NewHistoryCache creates a cache of historical states of db holding at most
+// budget bytes, each state expiring lifetime after admission.
+func NewHistoryCache(db Database, budget int, lifetime time.Duration) *HistoryCache {
+	return &HistoryCache{
+		db:       db,
+		budget:   budget,
+		lifetime: lifetime,
+		states:   make(map[common.Hash]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Snapshot returns the cached flat state of root, admitting it to the cache if
+// not already there. The returned snapshot is meant to be passed to
+// NewWithSnapshot.
+func (c *HistoryCache) Snapshot(root common.Hash) *HistoricalState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	if elem, ok := c.states[root]; ok {
+		state := elem.Value.(*HistoricalState)
+		if now.Before(state.expires) {
+			c.lru.MoveToFront(elem)
+			return state
+		}
+		c.remove(elem)
+	}
+	state := &HistoricalState{
+		cache:    c,
+		root:     root,
+		expires:  now.Add(c.lifetime),
+		accounts: make(map[common.Hash][]byte),
+		storage:  make(map[common.Hash]map[common.Hash][]byte),
+	}
+	c.states[root] = c.lru.PushFront(state)
+	return state
+}
+
+// Len returns the number of states in the cache.
+func (c *HistoryCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.lru.Len()
+}
+
+// Size returns the memory held by the states in the cache in bytes.
+func (c *HistoryCache) Size() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.size
+}
+
+// grow accounts for size more bytes held by state, evicting other states,
+// expired ones first, until the cache fits its budget. It returns false if the
+// state was evicted itself, in which case it must not memoize the item.
+func (c *HistoryCache) grow(state *HistoricalState, size int) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	elem, ok := c.states[state.root]
+	if !ok || elem.Value != state {
+		return false
+	}
+	// Drop the expired states first, then the least recently used other ones
+	now := time.Now()
+	for e := c.lru.Back(); e != nil; {
+		prev := e.Prev()
+		if !now.Before(e.Value.(*HistoricalState).expires) {
+			c.remove(e)
+		}
+		e = prev
+	}
+	if c.states[state.root] != elem {
+		return false
+	}
+	for c.size+size > c.budget {
+		victim := c.lru.Back()
+		if victim == elem {
+			victim = victim.Prev()
+		}
+		if victim == nil {
+			return false
+		}
+		c.remove(victim)
+		historyEvictMeter.Mark(1)
+	}
+	state.size += size
+	c.size += size
+	historySizeGauge.Update(int64(c.size))
+	return true
+}
+
+// remove drops a state from the cache. The caller holds the lock.
+func (c *HistoryCache) remove(elem *list.Element) {
+	state := elem.Value.(*HistoricalState)
+	c.lru.Remove(elem)
+	delete(c.states, state.root)
+	c.size -= state.size
+	historySizeGauge.Update(int64(c.size))
+}
+
+// HistoricalState is the flat state of a historical root materialized by a
+// HistoryCache. It implements snapshot.Snapshot, resolving the items not read
+// before from the tries of the root.
+type HistoricalState struct {
+	cache   *HistoryCache
+	root    common.Hash
+	expires time.Time
+	size    int // Memory held by the state, guarded by the cache lock
+
+	accounts map[common.Hash][]byte                 // RLP encoded accounts by hash, nil if missing
+	storage  map[common.Hash]map[common.Hash][]byte // RLP encoded slots by account and slot hash, nil if missing
+	lock     sync.Mutex
+}
+
+// Root implements snapshot.Snapshot.
+func (s *HistoricalState) Root() common.Hash {
+	return s.root
+}
+
+// Account implements snapshot.Snapshot.
+func (s *HistoricalState) Account(hash common.Hash) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.account(hash)
+}
+
+// account retrieves an account, memoizing it. The caller holds the lock.
+func (s *HistoricalState) account(hash common.Hash) ([]byte, error) {
+	if data, ok := s.accounts[hash]; ok {
+		historyHitMeter.Mark(1)
+		return data, nil
+	}
+	historyMissMeter.Mark(1)
+	data, err := s.resolve(s.root, hash)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache.grow(s, len(hash)+len(data)+historyItemOverhead) {
+		s.accounts[hash] = data
+	}
+	return data, nil
+}
+
+// Storage implements snapshot.Snapshot.
+func (s *HistoricalState) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if data, ok := s.storage[accountHash][storageHash]; ok {
+		historyHitMeter.Mark(1)
+		return data, nil
+	}
+	enc, err := s.account(accountHash)
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	var account Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return nil, err
+	}
+	historyMissMeter.Mark(1)
+	data, err := s.resolve(account.Root, storageHash)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache.grow(s, 2*len(storageHash)+len(data)+historyItemOverhead) {
+		slots := s.storage[accountHash]
+		if slots == nil {
+			slots = make(map[common.Hash][]byte)
+			s.storage[accountHash] = slots
+		}
+		slots[storageHash] = data
+	}
+	return data, nil
+}
+
+// resolve retrieves the value of a hashed key from the trie of root. The trie
+// is opened again on every miss rather than kept, as the nodes it resolves would
+// otherwise stay in memory outside of the cache budget.
+func (s *HistoricalState) resolve(root, hash common.Hash) ([]byte, error) {
+	t, err := trie.New(root, s.cache.db.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	data, err := t.TryGet(hash[:])
+	if len(data) == 0 {
+		data = nil
+	}
+	return data, err
+}
@@ -0,0 +1,125 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+// makeHistoricalState commits a state with a few accounts and storage slots,
+// returning its root.
+func makeHistoricalState(t *testing.T, db Database, seed byte) common.Hash {
+	state, _ := New(common.Hash{}, db)
+	for i := byte(0); i < 4; i++ {
+		addr := common.Address{seed, i}
+		state.SetBalance(addr, big.NewInt(int64(seed)*100+int64(i)))
+		state.SetNonce(addr, uint64(i))
+		state.SetState(addr, common.Hash{i}, common.Hash{seed, i})
+	}
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return root
+}
+
+func TestHistoryCache(t *testing.T) {
+	db := NewDatabase(ethdb.NewMemDatabase())
+	root := makeHistoricalState(t, db, 1)
+	cache := NewHistoryCache(db, 1024*1024, time.Hour)
+
+	// Read the state through the cache twice, the second time from memory only
+	var size int
+	for round := 0; round < 2; round++ {
+		state, err := NewWithSnapshot(root, db, cache.Snapshot(root))
+		if err != nil {
+			t.Fatalf("round %d: failed to open state: %v", round, err)
+		}
+		for i := byte(0); i < 4; i++ {
+			addr := common.Address{1, i}
+			if balance := state.GetBalance(addr); balance.Int64() != 100+int64(i) {
+				t.Errorf("round %d: balance mismatch for %x: have %v, want %d", round, addr, balance, 100+int64(i))
+			}
+			if nonce := state.GetNonce(addr); nonce != uint64(i) {
+				t.Errorf("round %d: nonce mismatch for %x: have %d, want %d", round, addr, nonce, i)
+			}
+			if value := state.GetState(addr, common.Hash{i}); value != (common.Hash{1, i}) {
+				t.Errorf("round %d: slot mismatch for %x: have %x", round, addr, value)
+			}
+			if value := state.GetState(addr, common.Hash{0xff}); value != (common.Hash{}) {
+				t.Errorf("round %d: missing slot mismatch for %x: have %x", round, addr, value)
+			}
+		}
+		if state.Exist(common.Address{0xff}) {
+			t.Errorf("round %d: missing account exists", round)
+		}
+		if round == 0 {
+			size = cache.Size()
+		}
+	}
+	if cache.Len() != 1 || size == 0 || cache.Size() != size {
+		t.Errorf("cache usage mismatch: %d states, %d bytes after the first round, %d after the second", cache.Len(), size, cache.Size())
+	}
+	snap := cache.Snapshot(root)
+	if len(snap.accounts) != 5 || len(snap.storage) != 4 {
+		t.Errorf("materialized items mismatch: %d accounts, storage of %d accounts", len(snap.accounts), len(snap.storage))
+	}
+}
+
+func TestHistoryCacheBudget(t *testing.T) {
+	db := NewDatabase(ethdb.NewMemDatabase())
+	roots := []common.Hash{makeHistoricalState(t, db, 1), makeHistoricalState(t, db, 2)}
+
+	// Materialize a state to measure it, then fit the budget to it
+	cache := NewHistoryCache(db, 1024*1024, time.Hour)
+	snap := cache.Snapshot(roots[0])
+	for i := byte(0); i < 4; i++ {
+		state, _ := NewWithSnapshot(roots[0], db, snap)
+		state.GetState(common.Address{1, i}, common.Hash{i})
+	}
+	size := cache.Size()
+
+	// Materializing another state evicts the least recently used one
+	cache = NewHistoryCache(db, size, time.Hour)
+	for n, root := range roots {
+		for i := byte(0); i < 4; i++ {
+			state, _ := NewWithSnapshot(root, db, cache.Snapshot(root))
+			state.GetState(common.Address{byte(n + 1), i}, common.Hash{i})
+		}
+	}
+	if cache.Len() != 1 || cache.Size() > size {
+		t.Fatalf("cache usage mismatch: %d states, %d bytes, budget %d", cache.Len(), cache.Size(), size)
+	}
+	if _, ok := cache.states[roots[1]]; !ok {
+		t.Errorf("most recently used state evicted")
+	}
+	// States expire regardless of their use
+	cache = NewHistoryCache(db, size, time.Millisecond)
+	old := cache.Snapshot(roots[0])
+	time.Sleep(5 * time.Millisecond)
+	if cache.Snapshot(roots[0]) == old {
+		t.Errorf("expired state served")
+	}
+}
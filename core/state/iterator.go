@@ -152,3 +152,11 @@ func (it *NodeIterator) retrieve() bool {
 	}
 	return true
 }
+
+// Code returns the contract code if the current entry is one, nil otherwise.
+func (it *NodeIterator) Code() []byte {
+	if it.state == nil || it.dataIt != nil {
+		return nil
+	}
+	return it.code
+}
@@ -0,0 +1,318 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/common/math"
+	"github.com/fulcrumchain/indigo/crypto"
+)
+
+const (
+	maxElements = 256  // Maximum number of array elements decoded
+	maxBytes    = 4096 // Maximum length of the strings and byte arrays decoded
+)
+
+// Storage reads the value of a storage slot of a contract.
+type Storage func(slot common.Hash) common.Hash
+
+// Value is a decoded state variable, struct member, array element or mapping
+// entry.
+type Value struct {
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Slot    common.Hash `json:"slot"`
+	Offset  int         `json:"offset,omitempty"`
+	Value   string      `json:"value,omitempty"`   // Decoded value of scalars, strings and byte arrays
+	Length  *uint64     `json:"length,omitempty"`  // Length of dynamic arrays, strings and byte arrays
+	Members []*Value    `json:"members,omitempty"` // Struct members, array elements or mapping entries
+}
+
+// Decode reads the state variables of a contract with the given layout. As the
+// keys of mappings are not stored, only the entries of the given keys are read.
+// Keys are listed by path: the variable label for its outer mapping, followed by
+// ".member" for mappings within structs and "[]" for mappings within arrays and
+// mappings, e.g. "allowance[]" for the inner keys of a nested mapping. Keys are
+// hex for addresses and bytes, decimal or hex for integers, and raw for strings.
+// Arrays are cut short after 256 elements and byte arrays after 4096 bytes.
+func Decode(l *Layout, storage Storage, keys map[string][]string) ([]*Value, error) {
+	d := &decoder{layout: l, storage: storage, keys: keys}
+
+	values := make([]*Value, 0, len(l.Storage))
+	for _, v := range l.Storage {
+		slot, ok := new(big.Int).SetString(v.Slot, 10)
+		if !ok {
+			return nil, fmt.Errorf("variable %q: invalid slot %q", v.Label, v.Slot)
+		}
+		value, err := d.decode(v.Label, v.Label, v.Type, slot, v.Offset)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// decoder walks the types of a layout, reading the slots they occupy.
+type decoder struct {
+	layout  *Layout
+	storage Storage
+	keys    map[string][]string
+}
+
+// decode reads a value of the given type at a slot and offset. The name is the
+// one reported, the path the one keys are listed under.
+func (d *decoder) decode(name, path, id string, slot *big.Int, offset int) (*Value, error) {
+	t := d.layout.Types[id]
+	if t == nil {
+		return nil, fmt.Errorf("%s: unknown type %s", name, id)
+	}
+	size, err := strconv.Atoi(t.NumberOfBytes)
+	if err != nil || size <= 0 {
+		return nil, fmt.Errorf("%s: invalid size %q of type %s", name, t.NumberOfBytes, id)
+	}
+	value := &Value{Name: name, Type: t.Label, Slot: slotHash(slot), Offset: offset}
+
+	switch t.Encoding {
+	case EncodingInplace:
+		switch {
+		case t.Base != "":
+			match := staticArrayType.FindStringSubmatch(id)
+			if match == nil {
+				return nil, fmt.Errorf("%s: unknown length of type %s", name, id)
+			}
+			length, err := strconv.ParseUint(match[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid length of type %s", name, id)
+			}
+			if value.Members, err = d.elements(name, path, t.Base, slot, length); err != nil {
+				return nil, err
+			}
+		case t.Members != nil:
+			for _, m := range t.Members {
+				mslot, ok := new(big.Int).SetString(m.Slot, 10)
+				if !ok {
+					return nil, fmt.Errorf("%s.%s: invalid slot %q", name, m.Label, m.Slot)
+				}
+				member, err := d.decode(name+"."+m.Label, path+"."+m.Label, m.Type, mslot.Add(mslot, slot), m.Offset)
+				if err != nil {
+					return nil, err
+				}
+				value.Members = append(value.Members, member)
+			}
+		default:
+			if offset+size > 32 {
+				return nil, fmt.Errorf("%s: %d bytes at offset %d overflow the slot", name, size, offset)
+			}
+			word := d.storage(value.Slot)
+			value.Value = formatValue(t.Label, word[32-offset-size:32-offset])
+		}
+
+	case EncodingMapping:
+		keyType := d.layout.Types[t.Key]
+		if keyType == nil {
+			return nil, fmt.Errorf("%s: unknown key type %s", name, t.Key)
+		}
+		for _, key := range d.keys[path] {
+			enc, err := encodeKey(keyType, key)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid key %q: %v", name, key, err)
+			}
+			entry := new(big.Int).SetBytes(crypto.Keccak256(enc, value.Slot[:]))
+			member, err := d.decode(name+"["+key+"]", path+"[]", t.Value, entry, 0)
+			if err != nil {
+				return nil, err
+			}
+			value.Members = append(value.Members, member)
+		}
+
+	case EncodingDynamicArray:
+		length := d.storage(value.Slot).Big()
+		if !length.IsUint64() {
+			return nil, fmt.Errorf("%s: invalid length %v", name, length)
+		}
+		n := length.Uint64()
+		value.Length = &n
+
+		data := new(big.Int).SetBytes(crypto.Keccak256(value.Slot[:]))
+		if value.Members, err = d.elements(name, path, t.Base, data, n); err != nil {
+			return nil, err
+		}
+
+	case EncodingBytes:
+		data, length := d.readBytes(value.Slot)
+		value.Length = &length
+		if t.Label == "string" {
+			value.Value = string(data)
+		} else {
+			value.Value = hexutil.Encode(data)
+		}
+
+	default:
+		return nil, fmt.Errorf("%s: unknown encoding %q of type %s", name, t.Encoding, id)
+	}
+	return value, nil
+}
+
+// elements reads the elements of an array starting at a slot. Elements of up to
+// 16 bytes are packed several per slot, larger ones start a slot each.
+func (d *decoder) elements(name, path, base string, slot *big.Int, length uint64) ([]*Value, error) {
+	t := d.layout.Types[base]
+	if t == nil {
+		return nil, fmt.Errorf("%s: unknown element type %s", name, base)
+	}
+	size, err := strconv.ParseUint(t.NumberOfBytes, 10, 64)
+	if err != nil || size == 0 {
+		return nil, fmt.Errorf("%s: invalid size %q of type %s", name, t.NumberOfBytes, base)
+	}
+	if length > maxElements {
+		length = maxElements
+	}
+	var elements []*Value
+	for i := uint64(0); i < length; i++ {
+		var (
+			index  uint64
+			offset int
+		)
+		if size <= 16 {
+			perSlot := 32 / size
+			index, offset = i/perSlot, int(i%perSlot*size)
+		} else {
+			index = i * ((size + 31) / 32)
+		}
+		eslot := new(big.Int).Add(slot, new(big.Int).SetUint64(index))
+		element, err := d.decode(fmt.Sprintf("%s[%d]", name, i), path+"[]", base, eslot, offset)
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, element)
+	}
+	return elements, nil
+}
+
+// readBytes reads a string or byte array stored at a slot, returning its content,
+// cut short after maxBytes, and its length. Up to 31 bytes are stored in the slot
+// along with twice the length, longer content from the hash of the slot on with
+// twice the length plus one in the slot.
+func (d *decoder) readBytes(slot common.Hash) ([]byte, uint64) {
+	word := d.storage(slot)
+	if word[31]&1 == 0 {
+		length := uint64(word[31] / 2)
+		if length > 31 {
+			length = 31
+		}
+		return common.CopyBytes(word[:length]), length
+	}
+	length := new(big.Int).Rsh(word.Big(), 1)
+	if !length.IsUint64() {
+		return nil, 0
+	}
+	n := length.Uint64()
+	read := n
+	if read > maxBytes {
+		read = maxBytes
+	}
+	var (
+		data = make([]byte, 0, read+31)
+		next = new(big.Int).SetBytes(crypto.Keccak256(slot[:]))
+	)
+	for uint64(len(data)) < read {
+		chunk := d.storage(slotHash(next))
+		data = append(data, chunk[:]...)
+		next.Add(next, common.Big1)
+	}
+	return data[:read], n
+}
+
+// formatValue formats a value stored in place according to its type label.
+func formatValue(label string, b []byte) string {
+	switch {
+	case label == "bool":
+		return strconv.FormatBool(b[len(b)-1] != 0)
+	case strings.HasPrefix(label, "address"), strings.HasPrefix(label, "contract "):
+		return common.BytesToAddress(b).Hex()
+	case strings.HasPrefix(label, "uint"), strings.HasPrefix(label, "enum "):
+		return new(big.Int).SetBytes(b).String()
+	case strings.HasPrefix(label, "int"):
+		v := new(big.Int).SetBytes(b)
+		if b[0]&0x80 != 0 {
+			v.Sub(v, new(big.Int).Lsh(common.Big1, uint(8*len(b))))
+		}
+		return v.String()
+	default:
+		return hexutil.Encode(b)
+	}
+}
+
+// encodeKey encodes a mapping key the way Solidity hashes it with the slot:
+// strings and byte arrays as is, value types padded to 32 bytes.
+func encodeKey(t *Type, key string) ([]byte, error) {
+	switch {
+	case t.Encoding == EncodingBytes && t.Label == "string":
+		return []byte(key), nil
+	case t.Encoding == EncodingBytes:
+		return hexutil.Decode(key)
+	case t.Label == "bool":
+		v, err := strconv.ParseBool(key)
+		if err != nil {
+			return nil, err
+		}
+		if v {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+	case strings.HasPrefix(t.Label, "address"), strings.HasPrefix(t.Label, "contract "):
+		if !common.IsHexAddress(key) {
+			return nil, fmt.Errorf("invalid address")
+		}
+		return common.LeftPadBytes(common.HexToAddress(key).Bytes(), 32), nil
+	case strings.HasPrefix(t.Label, "uint"), strings.HasPrefix(t.Label, "enum "):
+		v, ok := math.ParseBig256(key)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer")
+		}
+		return math.PaddedBigBytes(v, 32), nil
+	case strings.HasPrefix(t.Label, "int"):
+		v, ok := new(big.Int).SetString(key, 0)
+		if !ok || v.BitLen() > 255 {
+			return nil, fmt.Errorf("invalid integer")
+		}
+		return math.PaddedBigBytes(math.U256(v), 32), nil
+	case strings.HasPrefix(t.Label, "bytes"):
+		b, err := hexutil.Decode(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 32 {
+			return nil, fmt.Errorf("%d bytes", len(b))
+		}
+		return common.RightPadBytes(b, 32), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", t.Label)
+	}
+}
+
+// slotHash converts a slot number to its key, wrapping around 2^256.
+func slotHash(slot *big.Int) common.Hash {
+	return common.BytesToHash(math.PaddedBigBytes(math.U256(new(big.Int).Set(slot)), 32))
+}
@@ -0,0 +1,169 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package layout decodes the raw storage of contracts into named variables using
+// the storage layouts emitted by the Solidity compiler (solc --storage-layout).
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+)
+
+// Type encodings of the storage layout.
+const (
+	EncodingInplace      = "inplace"       // Value stored in place, possibly packed with others
+	EncodingMapping      = "mapping"       // Entries stored at the hash of their key and the slot
+	EncodingDynamicArray = "dynamic_array" // Length in the slot, elements from its hash on
+	EncodingBytes        = "bytes"         // Short in the slot, long from its hash on
+)
+
+var layoutPrefix = []byte("storage-layout-") // layoutPrefix + address -> storage layout
+
+// staticArrayType matches the identifiers of fixed size arrays, capturing the length.
+var staticArrayType = regexp.MustCompile(`^t_array\(.+\)(\d+)_storage$`)
+
+// Layout is the storage layout of a contract, as emitted by the Solidity compiler.
+type Layout struct {
+	Storage []*Variable      `json:"storage"`
+	Types   map[string]*Type `json:"types"`
+}
+
+// Variable is a state variable of a contract, or a member of a struct.
+type Variable struct {
+	Label  string `json:"label"`
+	Offset int    `json:"offset"` // Byte offset within the slot, from the right
+	Slot   string `json:"slot"`   // Decimal slot, relative to the struct for members
+	Type   string `json:"type"`
+}
+
+// Type describes how a type of the layout is stored.
+type Type struct {
+	Encoding      string      `json:"encoding"`
+	Label         string      `json:"label"`
+	NumberOfBytes string      `json:"numberOfBytes"`
+	Key           string      `json:"key,omitempty"`     // Key type of mappings
+	Value         string      `json:"value,omitempty"`   // Value type of mappings
+	Base          string      `json:"base,omitempty"`    // Element type of arrays
+	Members       []*Variable `json:"members,omitempty"` // Members of structs
+}
+
+// Validate checks that every variable refers to a known type with a valid slot,
+// and that the types are consistent with their encoding.
+func (l *Layout) Validate() error {
+	if len(l.Storage) == 0 {
+		return fmt.Errorf("no state variables")
+	}
+	for _, v := range l.Storage {
+		if err := l.validateVariable(v, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxTypeDepth bounds the nesting of types, guarding against recursive layouts.
+const maxTypeDepth = 32
+
+func (l *Layout) validateVariable(v *Variable, depth int) error {
+	if _, ok := new(big.Int).SetString(v.Slot, 10); !ok {
+		return fmt.Errorf("variable %q: invalid slot %q", v.Label, v.Slot)
+	}
+	if v.Offset < 0 || v.Offset > 31 {
+		return fmt.Errorf("variable %q: invalid offset %d", v.Label, v.Offset)
+	}
+	if err := l.validateType(v.Type, depth); err != nil {
+		return fmt.Errorf("variable %q: %v", v.Label, err)
+	}
+	return nil
+}
+
+func (l *Layout) validateType(id string, depth int) error {
+	if depth > maxTypeDepth {
+		return fmt.Errorf("type %s nested too deep", id)
+	}
+	t := l.Types[id]
+	if t == nil {
+		return fmt.Errorf("unknown type %s", id)
+	}
+	size, err := strconv.Atoi(t.NumberOfBytes)
+	if err != nil || size <= 0 {
+		return fmt.Errorf("type %s: invalid size %q", id, t.NumberOfBytes)
+	}
+	switch t.Encoding {
+	case EncodingInplace:
+		switch {
+		case t.Base != "":
+			if !staticArrayType.MatchString(id) {
+				return fmt.Errorf("type %s: unknown array length", id)
+			}
+			return l.validateType(t.Base, depth+1)
+		case t.Members != nil:
+			for _, m := range t.Members {
+				if err := l.validateVariable(m, depth+1); err != nil {
+					return fmt.Errorf("type %s: %v", id, err)
+				}
+			}
+		case size > 32:
+			return fmt.Errorf("type %s: value of %d bytes", id, size)
+		}
+	case EncodingMapping:
+		if err := l.validateType(t.Key, depth+1); err != nil {
+			return err
+		}
+		return l.validateType(t.Value, depth+1)
+	case EncodingDynamicArray:
+		return l.validateType(t.Base, depth+1)
+	case EncodingBytes:
+	default:
+		return fmt.Errorf("type %s: unknown encoding %q", id, t.Encoding)
+	}
+	return nil
+}
+
+func layoutKey(addr common.Address) []byte {
+	return append(append([]byte{}, layoutPrefix...), addr.Bytes()...)
+}
+
+// Write stores the storage layout of a contract.
+func Write(db ethdb.Putter, addr common.Address, l *Layout) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return db.Put(layoutKey(addr), data)
+}
+
+// Read retrieves the storage layout of a contract, or nil if none was stored.
+func Read(db ethdb.Database, addr common.Address) *Layout {
+	data, _ := db.Get(layoutKey(addr))
+	if len(data) == 0 {
+		return nil
+	}
+	l := new(Layout)
+	if err := json.Unmarshal(data, l); err != nil {
+		log.Error("Invalid storage layout", "address", addr, "err", err)
+		return nil
+	}
+	return l
+}
@@ -0,0 +1,234 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package layout
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+// testLayout is the layout solc emits for:
+//
+//	contract Test {
+//	    struct S { uint256 a; mapping(uint256 => bool) flags; }
+//
+//	    address owner;
+//	    bool paused;
+//	    int16 delta;
+//	    mapping(address => uint256) balances;
+//	    uint64[] list;
+//	    string name;
+//	    S s;
+//	    uint128[3] fixed;
+//	    mapping(address => mapping(address => uint256)) allowance;
+//	    string desc;
+//	}
+const testLayout = `{
+  "storage": [
+    {"astId": 1, "contract": "Test.sol:Test", "label": "owner", "offset": 0, "slot": "0", "type": "t_address"},
+    {"astId": 2, "contract": "Test.sol:Test", "label": "paused", "offset": 20, "slot": "0", "type": "t_bool"},
+    {"astId": 3, "contract": "Test.sol:Test", "label": "delta", "offset": 0, "slot": "1", "type": "t_int16"},
+    {"astId": 4, "contract": "Test.sol:Test", "label": "balances", "offset": 0, "slot": "2", "type": "t_mapping(t_address,t_uint256)"},
+    {"astId": 5, "contract": "Test.sol:Test", "label": "list", "offset": 0, "slot": "3", "type": "t_array(t_uint64)dyn_storage"},
+    {"astId": 6, "contract": "Test.sol:Test", "label": "name", "offset": 0, "slot": "4", "type": "t_string_storage"},
+    {"astId": 7, "contract": "Test.sol:Test", "label": "s", "offset": 0, "slot": "5", "type": "t_struct(S)8_storage"},
+    {"astId": 9, "contract": "Test.sol:Test", "label": "fixed", "offset": 0, "slot": "7", "type": "t_array(t_uint128)3_storage"},
+    {"astId": 10, "contract": "Test.sol:Test", "label": "allowance", "offset": 0, "slot": "9", "type": "t_mapping(t_address,t_mapping(t_address,t_uint256))"},
+    {"astId": 11, "contract": "Test.sol:Test", "label": "desc", "offset": 0, "slot": "10", "type": "t_string_storage"}
+  ],
+  "types": {
+    "t_address": {"encoding": "inplace", "label": "address", "numberOfBytes": "20"},
+    "t_bool": {"encoding": "inplace", "label": "bool", "numberOfBytes": "1"},
+    "t_int16": {"encoding": "inplace", "label": "int16", "numberOfBytes": "2"},
+    "t_uint64": {"encoding": "inplace", "label": "uint64", "numberOfBytes": "8"},
+    "t_uint128": {"encoding": "inplace", "label": "uint128", "numberOfBytes": "16"},
+    "t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"},
+    "t_string_storage": {"encoding": "bytes", "label": "string", "numberOfBytes": "32"},
+    "t_array(t_uint64)dyn_storage": {"base": "t_uint64", "encoding": "dynamic_array", "label": "uint64[]", "numberOfBytes": "32"},
+    "t_array(t_uint128)3_storage": {"base": "t_uint128", "encoding": "inplace", "label": "uint128[3]", "numberOfBytes": "64"},
+    "t_mapping(t_address,t_uint256)": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => uint256)", "numberOfBytes": "32", "value": "t_uint256"},
+    "t_mapping(t_address,t_mapping(t_address,t_uint256))": {"encoding": "mapping", "key": "t_address", "label": "mapping(address => mapping(address => uint256))", "numberOfBytes": "32", "value": "t_mapping(t_address,t_uint256)"},
+    "t_mapping(t_uint256,t_bool)": {"encoding": "mapping", "key": "t_uint256", "label": "mapping(uint256 => bool)", "numberOfBytes": "32", "value": "t_bool"},
+    "t_struct(S)8_storage": {"encoding": "inplace", "label": "struct Test.S", "numberOfBytes": "64", "members": [
+      {"astId": 12, "contract": "Test.sol:Test", "label": "a", "offset": 0, "slot": "0", "type": "t_uint256"},
+      {"astId": 13, "contract": "Test.sol:Test", "label": "flags", "offset": 0, "slot": "1", "type": "t_mapping(t_uint256,t_bool)"}
+    ]}
+  }
+}`
+
+// testStorage fills the storage of the contract above.
+func testStorage(owner, spender common.Address) map[common.Hash]common.Hash {
+	var (
+		storage = make(map[common.Hash]common.Hash)
+		slot    = func(n int64) common.Hash { return common.BigToHash(big.NewInt(n)) }
+		hashed  = func(key []byte, n common.Hash) common.Hash {
+			return crypto.Keccak256Hash(common.LeftPadBytes(key, 32), n[:])
+		}
+		word common.Hash
+	)
+	// owner and paused packed in slot 0, delta alone in slot 1
+	copy(word[12:], owner[:])
+	word[11] = 1
+	storage[slot(0)] = word
+
+	storage[slot(1)] = common.BytesToHash([]byte{0xff, 0xfe})
+
+	// balances[owner] = 100
+	storage[hashed(owner[:], slot(2))] = slot(100)
+
+	// list = [1, 2, 3], four per slot
+	storage[slot(3)] = slot(3)
+	word = common.Hash{}
+	word[31], word[23], word[15] = 1, 2, 3
+	storage[crypto.Keccak256Hash(slot(3).Bytes())] = word
+
+	// name = "indigo", short
+	word = common.Hash{}
+	copy(word[:], "indigo")
+	word[31] = 2 * 6
+	storage[slot(4)] = word
+
+	// s.a = 7, s.flags[1] = true
+	storage[slot(5)] = slot(7)
+	storage[hashed([]byte{1}, slot(6))] = slot(1)
+
+	// fixed = [1, 2, 3], two per slot
+	word = common.Hash{}
+	word[31], word[15] = 1, 2
+	storage[slot(7)] = word
+	storage[slot(8)] = slot(3)
+
+	// allowance[owner][spender] = 5
+	storage[hashed(spender[:], hashed(owner[:], slot(9)))] = slot(5)
+
+	// desc, long
+	desc := "a description longer than thirty-one bytes"
+	storage[slot(10)] = slot(int64(2*len(desc) + 1))
+	data := crypto.Keccak256Hash(slot(10).Bytes()).Big()
+	storage[common.BigToHash(data)] = common.BytesToHash([]byte(desc[:32]))
+	storage[common.BigToHash(data.Add(data, common.Big1))] = common.BytesToHash(common.RightPadBytes([]byte(desc[32:]), 32))
+
+	return storage
+}
+
+// Tests that the variables of a contract are decoded from its raw storage.
+func TestDecode(t *testing.T) {
+	var (
+		owner   = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		spender = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		storage = testStorage(owner, spender)
+	)
+	l := new(Layout)
+	if err := json.Unmarshal([]byte(testLayout), l); err != nil {
+		t.Fatalf("failed to parse layout: %v", err)
+	}
+	if err := l.Validate(); err != nil {
+		t.Fatalf("failed to validate layout: %v", err)
+	}
+	keys := map[string][]string{
+		"balances":    {owner.Hex()},
+		"s.flags":     {"1"},
+		"allowance":   {owner.Hex()},
+		"allowance[]": {spender.Hex()},
+	}
+	values, err := Decode(l, func(slot common.Hash) common.Hash { return storage[slot] }, keys)
+	if err != nil {
+		t.Fatalf("failed to decode storage: %v", err)
+	}
+	have := make(map[string]string)
+	var flatten func(values []*Value)
+	flatten = func(values []*Value) {
+		for _, v := range values {
+			if v.Value != "" {
+				have[v.Name] = v.Value
+			}
+			flatten(v.Members)
+		}
+	}
+	flatten(values)
+
+	want := map[string]string{
+		"owner":                         owner.Hex(),
+		"paused":                        "true",
+		"delta":                         "-2",
+		"list[0]":                       "1",
+		"list[1]":                       "2",
+		"list[2]":                       "3",
+		"name":                          "indigo",
+		"s.a":                           "7",
+		"s.flags[1]":                    "true",
+		"fixed[0]":                      "1",
+		"fixed[1]":                      "2",
+		"fixed[2]":                      "3",
+		"desc":                          "a description longer than thirty-one bytes",
+		"balances[" + owner.Hex() + "]": "100",
+		"allowance[" + owner.Hex() + "][" + spender.Hex() + "]": "5",
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("decoded values mismatch:\nhave %v\nwant %v", have, want)
+	}
+	if list := values[4]; list.Length == nil || *list.Length != 3 {
+		t.Errorf("list length mismatch: have %v, want 3", list.Length)
+	}
+}
+
+// Tests that inconsistent layouts are rejected.
+func TestValidate(t *testing.T) {
+	tests := []string{
+		`{"storage": [], "types": {}}`,
+		`{"storage": [{"label": "x", "offset": 0, "slot": "0", "type": "t_missing"}], "types": {}}`,
+		`{"storage": [{"label": "x", "offset": 0, "slot": "zero", "type": "t_uint256"}], "types": {"t_uint256": {"encoding": "inplace", "label": "uint256", "numberOfBytes": "32"}}}`,
+		`{"storage": [{"label": "x", "offset": 0, "slot": "0", "type": "t_uint256"}], "types": {"t_uint256": {"encoding": "packed", "label": "uint256", "numberOfBytes": "32"}}}`,
+		`{"storage": [{"label": "x", "offset": 0, "slot": "0", "type": "t_m"}], "types": {"t_m": {"encoding": "mapping", "key": "t_m", "value": "t_m", "label": "m", "numberOfBytes": "32"}}}`,
+	}
+	for i, test := range tests {
+		l := new(Layout)
+		if err := json.Unmarshal([]byte(test), l); err != nil {
+			t.Fatalf("test %d: failed to parse layout: %v", i, err)
+		}
+		if err := l.Validate(); err == nil {
+			t.Errorf("test %d: invalid layout accepted", i)
+		}
+	}
+}
+
+// Tests that layouts are stored per contract.
+func TestReadWrite(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	l := new(Layout)
+	if err := json.Unmarshal([]byte(testLayout), l); err != nil {
+		t.Fatalf("failed to parse layout: %v", err)
+	}
+	if Read(db, common.Address{1}) != nil {
+		t.Fatalf("layout found before writing")
+	}
+	if err := Write(db, common.Address{1}, l); err != nil {
+		t.Fatalf("failed to write layout: %v", err)
+	}
+	if stored := Read(db, common.Address{1}); !reflect.DeepEqual(stored, l) {
+		t.Errorf("stored layout mismatch")
+	}
+	if Read(db, common.Address{2}) != nil {
+		t.Errorf("layout found for another contract")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+)
+
+// diffLayer holds the flat state changes of a single block on top of a parent
+// layer. Its contents are immutable once created, only the parent link and the
+// stale flag change.
+type diffLayer struct {
+	root common.Hash
+
+	destructs map[common.Hash]struct{}               // Accounts deleted in this block, with their storage
+	accounts  map[common.Hash][]byte                 // Modified accounts, nil for deleted ones
+	storage   map[common.Hash]map[common.Hash][]byte // Modified storage slots, nil for cleared ones
+
+	lock   sync.RWMutex
+	parent snapshot
+	stale  bool
+}
+
+// newDiffLayer creates a new diff layer on top of parent.
+func newDiffLayer(parent snapshot, root common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer {
+	if destructs == nil {
+		destructs = make(map[common.Hash]struct{})
+	}
+	if accounts == nil {
+		accounts = make(map[common.Hash][]byte)
+	}
+	if storage == nil {
+		storage = make(map[common.Hash]map[common.Hash][]byte)
+	}
+	return &diffLayer{
+		root:      root,
+		parent:    parent,
+		destructs: destructs,
+		accounts:  accounts,
+		storage:   storage,
+	}
+}
+
+// Root implements Snapshot.
+func (dl *diffLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent implements snapshot.
+func (dl *diffLayer) Parent() snapshot {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+	return dl.parent
+}
+
+// Stale implements snapshot.
+func (dl *diffLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+	return dl.stale
+}
+
+func (dl *diffLayer) setParent(parent snapshot) {
+	dl.lock.Lock()
+	dl.parent = parent
+	dl.lock.Unlock()
+}
+
+func (dl *diffLayer) markStale() {
+	dl.lock.Lock()
+	dl.stale = true
+	dl.lock.Unlock()
+}
+
+// Account implements Snapshot, falling through to the parent layers for
+// accounts not modified in this one.
+func (dl *diffLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if data, ok := dl.accounts[hash]; ok {
+		dl.lock.RUnlock()
+		return data, nil
+	}
+	if _, ok := dl.destructs[hash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Account(hash)
+}
+
+// Storage implements Snapshot, falling through to the parent layers for slots
+// not modified in this one. Slots of accounts destructed in this layer, which
+// were not set again afterwards, are empty.
+func (dl *diffLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	if dl.stale {
+		dl.lock.RUnlock()
+		return nil, ErrSnapshotStale
+	}
+	if slots, ok := dl.storage[accountHash]; ok {
+		if data, ok := slots[storageHash]; ok {
+			dl.lock.RUnlock()
+			return data, nil
+		}
+	}
+	if _, ok := dl.destructs[accountHash]; ok {
+		dl.lock.RUnlock()
+		return nil, nil
+	}
+	parent := dl.parent
+	dl.lock.RUnlock()
+
+	return parent.Storage(accountHash, storageHash)
+}
+
+// modified reports whether the account was modified or deleted in this layer.
+func (dl *diffLayer) modified(hash common.Hash) bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if _, ok := dl.accounts[hash]; ok {
+		return true
+	}
+	_, ok := dl.destructs[hash]
+	return ok
+}
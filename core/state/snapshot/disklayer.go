@@ -0,0 +1,292 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/trie"
+)
+
+const (
+	// Data item prefixes (single bytes, distinct from the core chain data).
+	accountPrefix byte = 'a' // accountPrefix + account hash -> account trie value
+	storagePrefix byte = 'o' // storagePrefix + account hash + storage hash -> storage trie value
+)
+
+var (
+	snapshotRootKey      = []byte("SnapshotRoot")      // Root of the persisted disk layer
+	snapshotGeneratorKey = []byte("SnapshotGenerator") // Generation progress of the disk layer
+)
+
+func accountKey(hash common.Hash) []byte {
+	return append([]byte{accountPrefix}, hash[:]...)
+}
+
+func storageKey(accountHash, storageHash common.Hash) []byte {
+	return append(append([]byte{storagePrefix}, accountHash[:]...), storageHash[:]...)
+}
+
+// generatorStatus is the persisted generation progress of the disk layer.
+type generatorStatus struct {
+	Done     bool
+	Marker   []byte
+	Accounts uint64
+	Slots    uint64
+}
+
+// diskLayer is the persistent base layer of the snapshot. While it is being
+// generated, only accounts up to and including genMarker are covered.
+type diskLayer struct {
+	diskdb ethdb.Database
+	triedb *trie.Database
+
+	lock      sync.RWMutex
+	root      common.Hash
+	stale     bool
+	genMarker []byte // nil once generation is complete
+	genStats  generatorStatus
+	genStart  time.Time
+	genAbort  chan chan struct{} // Non-nil while the generator is running
+}
+
+// newDiskLayer creates an empty disk layer for root, to be generated.
+func newDiskLayer(diskdb ethdb.Database, triedb *trie.Database, root common.Hash) *diskLayer {
+	dl := &diskLayer{diskdb: diskdb, triedb: triedb, root: root, genMarker: []byte{}}
+	dl.persist(diskdb)
+	return dl
+}
+
+// loadDiskLayer loads the persisted disk layer, or returns nil if there is none.
+func loadDiskLayer(diskdb ethdb.Database, triedb *trie.Database) *diskLayer {
+	root, _ := diskdb.Get(snapshotRootKey)
+	if len(root) != common.HashLength {
+		return nil
+	}
+	blob, _ := diskdb.Get(snapshotGeneratorKey)
+	if len(blob) == 0 {
+		return nil
+	}
+	var status generatorStatus
+	if err := rlp.DecodeBytes(blob, &status); err != nil {
+		log.Warn("Failed to decode snapshot generator status", "err", err)
+		return nil
+	}
+	dl := &diskLayer{diskdb: diskdb, triedb: triedb, root: common.BytesToHash(root), genStats: status}
+	if !status.Done {
+		dl.genMarker = common.CopyBytes(status.Marker)
+		if dl.genMarker == nil {
+			dl.genMarker = []byte{}
+		}
+	}
+	return dl
+}
+
+// persist writes the root and generator progress of the layer. Callers must
+// hold the lock, or own the layer exclusively.
+func (dl *diskLayer) persist(w ethdb.Putter) {
+	status := dl.genStats
+	status.Done = dl.genMarker == nil
+	status.Marker = dl.genMarker
+	blob, err := rlp.EncodeToBytes(&status)
+	if err != nil {
+		log.Crit("Failed to encode snapshot generator status", "err", err)
+	}
+	if err := w.Put(snapshotRootKey, dl.root[:]); err != nil {
+		log.Crit("Failed to store snapshot root", "err", err)
+	}
+	if err := w.Put(snapshotGeneratorKey, blob); err != nil {
+		log.Crit("Failed to store snapshot generator status", "err", err)
+	}
+}
+
+// Root implements Snapshot.
+func (dl *diskLayer) Root() common.Hash {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+	return dl.root
+}
+
+// Parent implements snapshot, the disk layer has none.
+func (dl *diskLayer) Parent() snapshot {
+	return nil
+}
+
+// Stale implements snapshot.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+	return dl.stale
+}
+
+func (dl *diskLayer) markStale() {
+	dl.lock.Lock()
+	dl.stale = true
+	dl.lock.Unlock()
+}
+
+// covered reports whether the account with the given hash has been generated.
+// Callers must hold the lock.
+func (dl *diskLayer) covered(hash common.Hash) bool {
+	return dl.genMarker == nil || (len(dl.genMarker) > 0 && bytes.Compare(hash[:], dl.genMarker) <= 0)
+}
+
+// Account implements Snapshot.
+func (dl *diskLayer) Account(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(hash) {
+		return nil, ErrNotCoveredYet
+	}
+	data, _ := dl.diskdb.Get(accountKey(hash))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// Storage implements Snapshot.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	data, _ := dl.diskdb.Get(storageKey(accountHash, storageHash))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return data, nil
+}
+
+// flatten merges a diff layer, whose parent must be this disk layer, into the
+// persistent flat state and moves the layer's root to the diff's. Items beyond
+// the generation marker are skipped, the generator will pick them up from the
+// new root. The generator must not be running.
+func (dl *diskLayer) flatten(diff *diffLayer) error {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	batch := dl.diskdb.NewBatch()
+	for hash := range diff.destructs {
+		if !dl.covered(hash) {
+			continue
+		}
+		if err := dl.diskdb.Delete(accountKey(hash)); err != nil {
+			return err
+		}
+		if err := deletePrefix(dl.diskdb, append([]byte{storagePrefix}, hash[:]...)); err != nil {
+			return err
+		}
+	}
+	for hash, data := range diff.accounts {
+		if !dl.covered(hash) {
+			continue
+		}
+		if err := putOrDelete(dl.diskdb, batch, accountKey(hash), data); err != nil {
+			return err
+		}
+	}
+	for accountHash, slots := range diff.storage {
+		if !dl.covered(accountHash) {
+			continue
+		}
+		for storageHash, data := range slots {
+			if err := putOrDelete(dl.diskdb, batch, storageKey(accountHash, storageHash), data); err != nil {
+				return err
+			}
+		}
+	}
+	dl.root = diff.root
+	dl.persist(batch)
+	return batch.Write()
+}
+
+// putOrDelete stores data under key via batch, or deletes key directly from db
+// if data is empty (batches don't support deletion).
+func putOrDelete(db ethdb.Database, batch ethdb.Batch, key, data []byte) error {
+	if len(data) == 0 {
+		return db.Delete(key)
+	}
+	return batch.Put(key, data)
+}
+
+// progress returns the generation progress of the layer.
+func (dl *diskLayer) progress() Progress {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	p := Progress{
+		Root:       dl.root,
+		Generating: dl.genMarker != nil,
+		Accounts:   dl.genStats.Accounts,
+		Slots:      dl.genStats.Slots,
+	}
+	if dl.genMarker != nil {
+		p.Marker = common.BytesToHash(dl.genMarker)
+		if !dl.genStart.IsZero() {
+			p.Elapsed = time.Since(dl.genStart)
+		}
+	}
+	return p
+}
+
+// deletePrefix removes all keys with the given prefix from db.
+func deletePrefix(db ethdb.Database, prefix []byte) error {
+	var keys [][]byte
+	it := db.NewPrefixIterator(prefix)
+	for it.Next() {
+		keys = append(keys, common.CopyBytes(it.Key()))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := db.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wipeSnapshot removes all flat state and snapshot metadata from db.
+func wipeSnapshot(db ethdb.Database) error {
+	if err := db.Delete(snapshotRootKey); err != nil {
+		return err
+	}
+	for _, prefix := range []byte{accountPrefix, storagePrefix} {
+		if err := deletePrefix(db, []byte{prefix}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/trie"
+)
+
+// emptyRoot is the known root hash of an empty trie.
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// generationLogInterval is the time between progress reports of the generator.
+const generationLogInterval = 8 * time.Second
+
+// account mirrors the consensus representation of an account, as stored in
+// the account trie.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// generate starts generating the uncovered part of the layer in the background.
+// It is a no-op if the layer is fully generated or the generator is running.
+func (dl *diskLayer) generate() {
+	dl.lock.Lock()
+	defer dl.lock.Unlock()
+
+	if dl.genMarker == nil || dl.genAbort != nil || dl.stale {
+		return
+	}
+	dl.genAbort = make(chan chan struct{})
+	dl.genStart = time.Now()
+	go dl.generateRange(dl.root, common.CopyBytes(dl.genMarker), dl.genAbort)
+}
+
+// stopGeneration halts the background generator, if running, and waits for it
+// to persist its progress.
+func (dl *diskLayer) stopGeneration() {
+	dl.lock.Lock()
+	abort := dl.genAbort
+	dl.genAbort = nil
+	dl.lock.Unlock()
+
+	if abort != nil {
+		done := make(chan struct{})
+		abort <- done
+		<-done
+	}
+}
+
+// generateRange iterates the account trie at root from marker onwards, writing
+// every account and its storage slots into the flat state.
+func (dl *diskLayer) generateRange(root common.Hash, marker []byte, abort chan chan struct{}) {
+	var (
+		batch   = dl.diskdb.NewBatch()
+		logged  = time.Now()
+		last    = marker
+		done    chan struct{}
+		failure error
+	)
+	// flush writes out the batch and advances the marker to the last fully
+	// generated account.
+	flush := func(complete bool) {
+		dl.lock.Lock()
+		defer dl.lock.Unlock()
+
+		if complete {
+			dl.genMarker = nil
+		} else {
+			dl.genMarker = last
+		}
+		dl.persist(batch)
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to write state snapshot", "err", err)
+		}
+		batch.Reset()
+	}
+	accTrie, err := trie.New(root, dl.triedb)
+	if err != nil {
+		failure = err
+	} else {
+		it := trie.NewIterator(accTrie.NodeIterator(marker))
+	loop:
+		for it.Next() {
+			select {
+			case done = <-abort:
+				break loop
+			default:
+			}
+			if len(marker) > 0 && bytes.Equal(it.Key, marker) {
+				continue
+			}
+			if err := dl.generateAccount(batch, common.BytesToHash(it.Key), it.Value); err != nil {
+				failure = err
+				break
+			}
+			last = common.CopyBytes(it.Key)
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				flush(false)
+			}
+			if time.Since(logged) > generationLogInterval {
+				dl.lock.RLock()
+				log.Info("Generating state snapshot", "root", root, "at", hexutil.Bytes(last), "accounts", dl.genStats.Accounts, "slots", dl.genStats.Slots, "elapsed", common.PrettyDuration(time.Since(dl.genStart)))
+				dl.lock.RUnlock()
+				logged = time.Now()
+			}
+		}
+		if failure == nil && done == nil {
+			failure = it.Err
+		}
+	}
+	complete := failure == nil && done == nil
+	flush(complete)
+
+	switch {
+	case failure != nil:
+		log.Error("State snapshot generation failed", "root", root, "err", failure)
+	case complete:
+		dl.lock.RLock()
+		log.Info("Generated state snapshot", "root", root, "accounts", dl.genStats.Accounts, "slots", dl.genStats.Slots, "elapsed", common.PrettyDuration(time.Since(dl.genStart)))
+		dl.lock.RUnlock()
+	}
+	if done == nil {
+		// Exited on our own, detach from the layer unless someone is already
+		// waiting to stop us.
+		dl.lock.Lock()
+		if dl.genAbort == abort {
+			dl.genAbort = nil
+			dl.lock.Unlock()
+			return
+		}
+		dl.lock.Unlock()
+		done = <-abort
+	}
+	close(done)
+}
+
+// generateAccount writes a single account and all its storage slots into batch.
+func (dl *diskLayer) generateAccount(batch ethdb.Batch, hash common.Hash, data []byte) error {
+	var acc account
+	if err := rlp.DecodeBytes(data, &acc); err != nil {
+		return err
+	}
+	if err := batch.Put(accountKey(hash), data); err != nil {
+		return err
+	}
+	var slots uint64
+	if acc.Root != emptyRoot {
+		storageTrie, err := trie.New(acc.Root, dl.triedb)
+		if err != nil {
+			return err
+		}
+		it := trie.NewIterator(storageTrie.NodeIterator(nil))
+		for it.Next() {
+			if err := batch.Put(storageKey(hash, common.BytesToHash(it.Key)), common.CopyBytes(it.Value)); err != nil {
+				return err
+			}
+			slots++
+		}
+		if it.Err != nil {
+			return it.Err
+		}
+	}
+	dl.lock.Lock()
+	dl.genStats.Accounts++
+	dl.genStats.Slots += slots
+	dl.lock.Unlock()
+	return nil
+}
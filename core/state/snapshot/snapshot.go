@@ -0,0 +1,258 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a flat, hash-keyed view of the account and
+// storage tries, maintained alongside the tries so that state reads for recent
+// blocks can be served without trie traversal.
+//
+// The snapshot is made up of a single persistent disk layer, holding the flat
+// state of one particular root, and a tree of in-memory diff layers on top of
+// it, one per recently imported block. Once the diff layers grow too deep, the
+// bottom ones are flattened into the disk layer.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/trie"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying layer
+	// has been flattened into the disk layer or discarded, and it should not be
+	// used any more.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the requested item
+	// has not been generated into the disk layer yet.
+	ErrNotCoveredYet = errors.New("not covered yet")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+// Missing items are returned as nil without an error. Callers must fall back
+// to the tries on any error.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the RLP encoded account (as stored in the
+	// account trie) associated with a particular hash.
+	Account(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the RLP encoded storage slot (as stored in the
+	// storage trie) associated with a particular account and slot hash.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of Snapshot, exposing the parent layer.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the layer below this one, or nil for the disk layer.
+	Parent() snapshot
+
+	// Stale reports whether the layer has been invalidated.
+	Stale() bool
+}
+
+// Progress reports the state of the snapshot, including background generation.
+type Progress struct {
+	Root       common.Hash   `json:"root"`       // Root of the disk layer
+	Layers     int           `json:"layers"`     // Number of in-memory diff layers
+	Generating bool          `json:"generating"` // Whether the disk layer is still being generated
+	Marker     common.Hash   `json:"marker"`     // Last account hash generated into the disk layer
+	Accounts   uint64        `json:"accounts"`   // Number of accounts generated
+	Slots      uint64        `json:"slots"`      // Number of storage slots generated
+	Elapsed    time.Duration `json:"elapsed"`    // Time spent generating since the last (re)start
+}
+
+// Tree is an in-memory tree of diff layers on top of a persistent disk layer.
+// All methods are safe for concurrent use.
+type Tree struct {
+	diskdb ethdb.Database
+	triedb *trie.Database
+
+	lock   sync.RWMutex
+	disk   *diskLayer
+	layers map[common.Hash]snapshot // All live layers, keyed by root, including the disk layer
+}
+
+// New opens the snapshot stored in diskdb. If it doesn't match root, or no
+// snapshot exists yet, the flat state is wiped and regenerated from the tries
+// in the background.
+func New(diskdb ethdb.Database, triedb *trie.Database, root common.Hash) *Tree {
+	t := &Tree{diskdb: diskdb, triedb: triedb}
+	if disk := loadDiskLayer(diskdb, triedb); disk != nil && disk.root == root {
+		log.Info("Loaded state snapshot", "root", root, "generating", disk.genMarker != nil)
+		t.setDisk(disk)
+		if disk.genMarker != nil {
+			disk.generate()
+		}
+		return t
+	}
+	t.rebuild(root)
+	return t
+}
+
+// setDisk resets the tree to a sole disk layer. Callers must hold the lock.
+func (t *Tree) setDisk(disk *diskLayer) {
+	t.disk = disk
+	t.layers = map[common.Hash]snapshot{disk.root: disk}
+}
+
+// rebuild discards all layers and starts generating a new disk layer for root.
+// Callers must hold the lock.
+func (t *Tree) rebuild(root common.Hash) {
+	if t.disk != nil {
+		t.disk.stopGeneration()
+		t.disk.markStale()
+	}
+	for _, layer := range t.layers {
+		if diff, ok := layer.(*diffLayer); ok {
+			diff.markStale()
+		}
+	}
+	log.Info("Rebuilding state snapshot", "root", root)
+	if err := wipeSnapshot(t.diskdb); err != nil {
+		log.Error("Failed to wipe state snapshot", "err", err)
+	}
+	disk := newDiskLayer(t.diskdb, t.triedb, root)
+	t.setDisk(disk)
+	disk.generate()
+}
+
+// Snapshot returns the layer for the given state root, or nil if no layer is
+// available for it.
+func (t *Tree) Snapshot(root common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if layer, ok := t.layers[root]; ok {
+		return layer
+	}
+	return nil
+}
+
+// AccountModified reports whether the account was modified by the state
+// transition from parent to root. The answer is only known while the diff
+// layer of the transition is held in memory, known is false otherwise.
+func (t *Tree) AccountModified(root, parent, hash common.Hash) (modified bool, known bool) {
+	t.lock.RLock()
+	layer, ok := t.layers[root]
+	t.lock.RUnlock()
+
+	diff, ok := layer.(*diffLayer)
+	if !ok || diff.Stale() {
+		return false, false
+	}
+	if base := diff.Parent(); base == nil || base.Root() != parent {
+		return false, false
+	}
+	return diff.modified(hash), true
+}
+
+// Update adds a new diff layer for root on top of the layer for parent. Blocks
+// whose parent layer is unknown (e.g. side chains below the disk layer) are
+// silently ignored.
+func (t *Tree) Update(root, parent common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	if root == parent {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if _, ok := t.layers[root]; ok {
+		return nil
+	}
+	base, ok := t.layers[parent]
+	if !ok {
+		log.Trace("Skipping snapshot layer with unknown parent", "root", root, "parent", parent)
+		return nil
+	}
+	t.layers[root] = newDiffLayer(base, root, destructs, accounts, storage)
+	return nil
+}
+
+// Cap flattens the diff layers below root into the disk layer, so that at most
+// layers diff layers remain between the two. Layers not descending from the new
+// disk layer are discarded. If root is unknown, the snapshot is regenerated.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	head, ok := t.layers[root]
+	if !ok {
+		t.rebuild(root)
+		return nil
+	}
+	// Collect the diff layers from root down to the disk layer.
+	var diffs []*diffLayer
+	for layer := head; layer != nil; layer = layer.Parent() {
+		if diff, ok := layer.(*diffLayer); ok {
+			diffs = append(diffs, diff)
+		}
+	}
+	if len(diffs) <= layers {
+		return nil
+	}
+	// Flatten the surplus layers into disk, oldest first.
+	t.disk.stopGeneration()
+	for i := len(diffs) - 1; i >= layers; i-- {
+		if err := t.disk.flatten(diffs[i]); err != nil {
+			return fmt.Errorf("failed to flatten snapshot layer %x: %v", diffs[i].root, err)
+		}
+		diffs[i].markStale()
+	}
+	if layers > 0 {
+		diffs[layers-1].setParent(t.disk)
+	}
+	t.disk.generate()
+
+	// Drop every layer which no longer descends from the disk layer.
+	t.layers = map[common.Hash]snapshot{t.disk.root: t.disk}
+	for _, diff := range diffs[:layers] {
+		t.layers[diff.root] = diff
+	}
+	return nil
+}
+
+// Progress reports the current state of the snapshot.
+func (t *Tree) Progress() Progress {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	progress := t.disk.progress()
+	progress.Layers = len(t.layers) - 1
+	return progress
+}
+
+// Stop flattens all diff layers below root into the disk layer and halts
+// background generation, persisting its progress so it can resume on restart.
+func (t *Tree) Stop(root common.Hash) {
+	if err := t.Cap(root, 0); err != nil {
+		log.Error("Failed to persist state snapshot", "err", err)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.disk.stopGeneration()
+}
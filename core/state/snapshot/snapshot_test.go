@@ -0,0 +1,228 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/trie"
+)
+
+// testAccount returns the RLP of an account with the given balance and
+// storage root.
+func testAccount(balance int64, root common.Hash) []byte {
+	blob, _ := rlp.EncodeToBytes(&account{Balance: big.NewInt(balance), Root: root, CodeHash: crypto.Keccak256(nil)})
+	return blob
+}
+
+func testSlot(value byte) []byte {
+	blob, _ := rlp.EncodeToBytes([]byte{value})
+	return blob
+}
+
+// makeState builds a committed account trie with n accounts, each with slots
+// storage entries, and returns its root.
+func makeState(t *testing.T, triedb *trie.Database, n, slots int) common.Hash {
+	accTrie, _ := trie.New(common.Hash{}, triedb)
+	for i := 0; i < n; i++ {
+		root := emptyRoot
+		if slots > 0 {
+			storageTrie, _ := trie.New(common.Hash{}, triedb)
+			for j := 0; j < slots; j++ {
+				storageTrie.Update(crypto.Keccak256([]byte{byte(i), byte(j)}), testSlot(byte(j+1)))
+			}
+			var err error
+			if root, err = storageTrie.Commit(nil); err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+		}
+		accTrie.Update(crypto.Keccak256([]byte{byte(i)}), testAccount(int64(i+1), root))
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush tries: %v", err)
+	}
+	return root
+}
+
+// waitGeneration blocks until the disk layer of the tree is fully generated.
+func waitGeneration(t *testing.T, tree *Tree) Progress {
+	for i := 0; i < 500; i++ {
+		if progress := tree.Progress(); !progress.Generating {
+			return progress
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("snapshot generation timed out")
+	return Progress{}
+}
+
+// Tests that a snapshot generated from the tries contains all accounts and
+// storage slots.
+func TestGeneration(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+		root   = makeState(t, triedb, 16, 4)
+	)
+	tree := New(diskdb, triedb, root)
+	progress := waitGeneration(t, tree)
+	if progress.Accounts != 16 || progress.Slots != 64 {
+		t.Fatalf("generated counts mismatch: have %d/%d, want 16/64", progress.Accounts, progress.Slots)
+	}
+	snap := tree.Snapshot(root)
+	if snap == nil {
+		t.Fatalf("no snapshot for root %x", root)
+	}
+	for i := 0; i < 16; i++ {
+		data, err := snap.Account(crypto.Keccak256Hash([]byte{byte(i)}))
+		if err != nil {
+			t.Fatalf("account %d: failed to retrieve: %v", i, err)
+		}
+		var acc account
+		if err := rlp.DecodeBytes(data, &acc); err != nil {
+			t.Fatalf("account %d: failed to decode: %v", i, err)
+		}
+		if acc.Balance.Int64() != int64(i+1) {
+			t.Errorf("account %d: balance mismatch: have %v, want %d", i, acc.Balance, i+1)
+		}
+		slot, err := snap.Storage(crypto.Keccak256Hash([]byte{byte(i)}), crypto.Keccak256Hash([]byte{byte(i), 2}))
+		if err != nil || !bytes.Equal(slot, testSlot(3)) {
+			t.Errorf("account %d: slot mismatch: have %x (%v), want %x", i, slot, err, testSlot(3))
+		}
+	}
+	if data, err := snap.Account(common.Hash{0xff}); data != nil || err != nil {
+		t.Errorf("missing account: have %x (%v), want nil", data, err)
+	}
+	// Reopening the database at the same root must not regenerate.
+	reopened := New(diskdb, triedb, root)
+	if progress := reopened.Progress(); progress.Generating || progress.Accounts != 16 {
+		t.Errorf("reopened snapshot regenerating: %+v", progress)
+	}
+}
+
+// Tests that diff layers shadow their parents, including account destruction.
+func TestDiffLayers(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+		root   = makeState(t, triedb, 4, 2)
+	)
+	tree := New(diskdb, triedb, root)
+	waitGeneration(t, tree)
+
+	var (
+		acc0  = crypto.Keccak256Hash([]byte{0})
+		acc1  = crypto.Keccak256Hash([]byte{1})
+		slot0 = crypto.Keccak256Hash([]byte{0, 0})
+		slot1 = crypto.Keccak256Hash([]byte{1, 0})
+	)
+	// Layer 1 modifies account 0 and a slot of it, layer 2 destructs account 1.
+	root1, root2 := common.Hash{0x01}, common.Hash{0x02}
+	tree.Update(root1, root, nil, map[common.Hash][]byte{acc0: testAccount(100, emptyRoot)}, map[common.Hash]map[common.Hash][]byte{acc0: {slot0: testSlot(0xaa)}})
+	tree.Update(root2, root1, map[common.Hash]struct{}{acc1: {}}, nil, nil)
+
+	snap := tree.Snapshot(root2)
+	if data, _ := snap.Account(acc0); !bytes.Equal(data, testAccount(100, emptyRoot)) {
+		t.Errorf("account 0 not updated: %x", data)
+	}
+	if data, _ := snap.Storage(acc0, slot0); !bytes.Equal(data, testSlot(0xaa)) {
+		t.Errorf("slot of account 0 not updated: %x", data)
+	}
+	if data, _ := snap.Account(acc1); data != nil {
+		t.Errorf("destructed account 1 still present: %x", data)
+	}
+	if data, _ := snap.Storage(acc1, slot1); data != nil {
+		t.Errorf("storage of destructed account 1 still present: %x", data)
+	}
+	// The parent layers must be unaffected.
+	if data, _ := tree.Snapshot(root).Account(acc1); data == nil {
+		t.Errorf("account 1 missing from disk layer")
+	}
+	// Flatten everything into disk and ensure the outcome is the same.
+	old := tree.Snapshot(root1)
+	if err := tree.Cap(root2, 0); err != nil {
+		t.Fatalf("failed to cap snapshot: %v", err)
+	}
+	if _, err := old.Account(acc0); err != ErrSnapshotStale {
+		t.Errorf("flattened layer not stale: %v", err)
+	}
+	if progress := tree.Progress(); progress.Root != root2 || progress.Layers != 0 {
+		t.Errorf("disk layer mismatch: have %x/%d, want %x/0", progress.Root, progress.Layers, root2)
+	}
+	snap = tree.Snapshot(root2)
+	if data, _ := snap.Account(acc0); !bytes.Equal(data, testAccount(100, emptyRoot)) {
+		t.Errorf("flattened account 0 not updated: %x", data)
+	}
+	if data, _ := snap.Storage(acc0, slot0); !bytes.Equal(data, testSlot(0xaa)) {
+		t.Errorf("flattened slot of account 0 not updated: %x", data)
+	}
+	if data, _ := snap.Account(acc1); data != nil {
+		t.Errorf("flattened destructed account 1 still present: %x", data)
+	}
+	if data, _ := snap.Storage(acc1, slot1); data != nil {
+		t.Errorf("flattened storage of destructed account 1 still present: %x", data)
+	}
+}
+
+// Tests that capping keeps the requested number of layers and drops side
+// branches which don't descend from the new disk layer.
+func TestCap(t *testing.T) {
+	var (
+		diskdb = ethdb.NewMemDatabase()
+		triedb = trie.NewDatabase(diskdb)
+		root   = makeState(t, triedb, 2, 0)
+	)
+	tree := New(diskdb, triedb, root)
+	waitGeneration(t, tree)
+
+	parent := root
+	for i := 1; i <= 4; i++ {
+		child := common.Hash{byte(i)}
+		tree.Update(child, parent, nil, nil, nil)
+		parent = child
+	}
+	side := common.Hash{0xee}
+	tree.Update(side, root, nil, nil, nil)
+
+	if err := tree.Cap(common.Hash{4}, 2); err != nil {
+		t.Fatalf("failed to cap snapshot: %v", err)
+	}
+	if progress := tree.Progress(); progress.Root != (common.Hash{2}) || progress.Layers != 2 {
+		t.Errorf("capped tree mismatch: have %x/%d, want %x/2", progress.Root, progress.Layers, common.Hash{2})
+	}
+	for _, root := range []common.Hash{{2}, {3}, {4}} {
+		if tree.Snapshot(root) == nil {
+			t.Errorf("layer %x missing", root)
+		}
+	}
+	for _, root := range []common.Hash{root, {1}, side} {
+		if tree.Snapshot(root) != nil {
+			t.Errorf("layer %x not dropped", root)
+		}
+	}
+}
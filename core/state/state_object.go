@@ -177,8 +177,23 @@ func (so *stateObject) GetState(db Database, key common.Hash) common.Hash {
 	if exists {
 		return value
 	}
-	// Load from DB in case it is missing.
-	enc, err := so.getTrie(db).TryGet(key[:])
+	// Load from the snapshot or DB in case it is missing.
+	var (
+		enc []byte
+		err error
+	)
+	snap := so.db.snap
+	if snap != nil {
+		if _, destructed := so.db.snapDestructs[so.addrHash]; destructed {
+			snap = nil
+		}
+	}
+	if snap != nil {
+		enc, err = snap.Storage(so.addrHash, crypto.Keccak256Hash(key[:]))
+	}
+	if snap == nil || err != nil {
+		enc, err = so.getTrie(db).TryGet(key[:])
+	}
 	if err != nil {
 		so.setError(err)
 		return common.Hash{}
@@ -217,15 +232,32 @@ func (so *stateObject) setState(key, value common.Hash) {
 // updateTrie writes cached storage modifications into the object's storage trie.
 func (so *stateObject) updateTrie(db Database) Trie {
 	tr := so.getTrie(db)
+
+	var slots map[common.Hash][]byte
+	if so.db.snap != nil && len(so.dirtyStorage) > 0 {
+		if slots = so.db.snapStorage[so.addrHash]; slots == nil {
+			slots = make(map[common.Hash][]byte)
+			so.db.snapStorage[so.addrHash] = slots
+		}
+	}
 	for key, value := range so.dirtyStorage {
 		delete(so.dirtyStorage, key)
+		if so.db.changes != nil {
+			so.db.changes.slot(so.address, key, value)
+		}
 		if (value == common.Hash{}) {
 			so.setError(tr.TryDelete(key[:]))
+			if slots != nil {
+				slots[crypto.Keccak256Hash(key[:])] = nil
+			}
 			continue
 		}
 		// Encoding []byte cannot fail, ok to ignore the error.
 		v, _ := rlp.EncodeToBytes(bytes.TrimLeft(value[:], "\x00"))
 		so.setError(tr.TryUpdate(key[:], v))
+		if slots != nil {
+			slots[crypto.Keccak256Hash(key[:])] = v
+		}
 	}
 	return tr
 }
@@ -26,6 +26,7 @@ import (
 	"go.opencensus.io/trace"
 
 	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/state/snapshot"
 	"github.com/fulcrumchain/indigo/core/types"
 	"github.com/fulcrumchain/indigo/crypto"
 	"github.com/fulcrumchain/indigo/log"
@@ -55,6 +56,16 @@ type StateDB struct {
 	db   Database
 	trie Trie
 
+	// Optional flat state snapshot of the root the state was opened at, along
+	// with the flattened changes to feed into the next snapshot layer.
+	snap          snapshot.Snapshot
+	snapDestructs map[common.Hash]struct{}
+	snapAccounts  map[common.Hash][]byte
+	snapStorage   map[common.Hash]map[common.Hash][]byte
+
+	// Writes to the state tries recorded for diagnostics, nil if not tracked
+	changes *changeRecorder
+
 	// This map holds 'live' objects, which will get modified while processing a state transition.
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
@@ -78,18 +89,39 @@ type StateDB struct {
 
 // Create a new state from a given trie
 func New(root common.Hash, db Database) (*StateDB, error) {
+	return NewWithSnapshot(root, db, nil)
+}
+
+// NewWithSnapshot creates a new state from a given trie, serving account and
+// storage reads from snap where possible. The snapshot must be for the same
+// root. Changes to the state are recorded for building the next snapshot layer,
+// see SnapshotChanges.
+func NewWithSnapshot(root common.Hash, db Database, snap snapshot.Snapshot) (*StateDB, error) {
 	tr, err := db.OpenTrie(root)
 	if err != nil {
 		return nil, err
 	}
-	return &StateDB{
+	sdb := &StateDB{
 		db:                db,
 		trie:              tr,
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
-	}, nil
+	}
+	if snap != nil && snap.Root() == root {
+		sdb.snap = snap
+		sdb.snapDestructs = make(map[common.Hash]struct{})
+		sdb.snapAccounts = make(map[common.Hash][]byte)
+		sdb.snapStorage = make(map[common.Hash]map[common.Hash][]byte)
+	}
+	return sdb, nil
+}
+
+// SnapshotChanges returns the flattened account and storage changes recorded
+// since the state was opened, or nils if it wasn't opened with a snapshot.
+func (db *StateDB) SnapshotChanges() (destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) {
+	return db.snapDestructs, db.snapAccounts, db.snapStorage
 }
 
 // Reset clears out all emphemeral state objects from the state db, but keeps
@@ -100,6 +132,7 @@ func (db *StateDB) Reset(root common.Hash) error {
 		return err
 	}
 	db.trie = tr
+	db.snap, db.snapDestructs, db.snapAccounts, db.snapStorage = nil, nil, nil, nil
 	db.stateObjects = make(map[common.Address]*stateObject)
 	db.stateObjectsDirty = make(map[common.Address]struct{})
 	db.thash = common.Hash{}
@@ -401,6 +434,12 @@ func (db *StateDB) updateStateObject(stateObject *stateObject) error {
 	if err != nil {
 		panic(fmt.Errorf("can't encode object at %x: %v", addr[:], err))
 	}
+	if db.snap != nil {
+		db.snapAccounts[stateObject.addrHash] = data
+	}
+	if db.changes != nil {
+		db.changes.account(addr, data)
+	}
 	return db.trie.TryUpdate(addr[:], data)
 }
 
@@ -408,9 +447,23 @@ func (db *StateDB) updateStateObject(stateObject *stateObject) error {
 func (db *StateDB) deleteStateObject(stateObject *stateObject) error {
 	stateObject.deleted = true
 	addr := stateObject.Address()
+	if db.snap != nil {
+		db.snapDestruct(stateObject.addrHash)
+	}
+	if db.changes != nil {
+		db.changes.account(addr, nil)
+	}
 	return db.trie.TryDelete(addr[:])
 }
 
+// snapDestruct records the deletion of an account and all its storage,
+// dropping any changes recorded for it so far.
+func (db *StateDB) snapDestruct(addrHash common.Hash) {
+	db.snapDestructs[addrHash] = struct{}{}
+	delete(db.snapAccounts, addrHash)
+	delete(db.snapStorage, addrHash)
+}
+
 // Retrieve a state object given my the address. Returns nil if not found.
 func (db *StateDB) getStateObject(addr common.Address) (stateObject *stateObject, err error) {
 	// Prefer 'live' objects.
@@ -421,8 +474,14 @@ func (db *StateDB) getStateObject(addr common.Address) (stateObject *stateObject
 		return obj, nil
 	}
 
-	// Load the object from the database.
-	enc, err := db.trie.TryGet(addr[:])
+	// Load the object from the snapshot if available, or the trie otherwise.
+	var enc []byte
+	if db.snap != nil {
+		enc, err = db.snap.Account(crypto.Keccak256Hash(addr[:]))
+	}
+	if db.snap == nil || err != nil {
+		enc, err = db.trie.TryGet(addr[:])
+	}
 	if len(enc) == 0 {
 		return nil, err
 	}
@@ -468,6 +527,11 @@ func (db *StateDB) createObject(addr common.Address) (*stateObject, *stateObject
 	}
 	newobj := newObject(db, addr, Account{}, db.MarkStateObjectDirty)
 	newobj.setNonce(0) // sets the object to dirty
+	if prev != nil && db.snap != nil {
+		// The new object starts with empty storage, the old one must not be
+		// read from the snapshot any more.
+		db.snapDestruct(prev.addrHash)
+	}
 	if prev == nil {
 		db.journal = append(db.journal, createObjectChange{account: &addr})
 	} else {
@@ -547,9 +611,42 @@ func (db *StateDB) Copy(ctx context.Context) *StateDB {
 	for hash, preimage := range db.preimages {
 		state.preimages[hash] = preimage
 	}
+	if db.snap != nil {
+		state.snap = db.snap
+		state.snapDestructs = make(map[common.Hash]struct{}, len(db.snapDestructs))
+		for hash := range db.snapDestructs {
+			state.snapDestructs[hash] = struct{}{}
+		}
+		state.snapAccounts = make(map[common.Hash][]byte, len(db.snapAccounts))
+		for hash, data := range db.snapAccounts {
+			state.snapAccounts[hash] = data
+		}
+		state.snapStorage = make(map[common.Hash]map[common.Hash][]byte, len(db.snapStorage))
+		for hash, slots := range db.snapStorage {
+			cpy := make(map[common.Hash][]byte, len(slots))
+			for key, data := range slots {
+				cpy[key] = data
+			}
+			state.snapStorage[hash] = cpy
+		}
+	}
 	return state
 }
 
+// MergeAccounts adopts the state of the given accounts from src, which must be a
+// Copy of db that has diverged only in accounts not modified in db since. Only
+// accounts modified in src are merged. The merge is not journalled, so it must
+// not happen while any snapshots of db are pending.
+func (db *StateDB) MergeAccounts(src *StateDB, addrs []common.Address) {
+	for _, addr := range addrs {
+		if _, dirty := src.stateObjectsDirty[addr]; !dirty {
+			continue
+		}
+		db.stateObjects[addr] = src.stateObjects[addr].deepCopy(db, db.MarkStateObjectDirty)
+		db.stateObjectsDirty[addr] = struct{}{}
+	}
+}
+
 // Snapshot returns an identifier for the current revision of the state.
 func (db *StateDB) Snapshot() int {
 	id := db.nextRevisionId
@@ -169,6 +169,58 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+// TestMergeAccounts tests that accounts modified in independent copies of a
+// state can be merged back, without clobbering accounts the copies didn't modify.
+func TestMergeAccounts(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	orig, _ := New(common.Hash{}, NewDatabase(db))
+
+	addrs := make([]common.Address, 4)
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress([]byte{byte(i + 1)})
+		orig.AddBalance(addrs[i], big.NewInt(100))
+	}
+	orig.Finalise(false)
+
+	// Modify disjoint accounts in two copies
+	left, right := orig.Copy(context.Background()), orig.Copy(context.Background())
+	left.AddBalance(addrs[0], big.NewInt(1))
+	left.SetNonce(addrs[1], 5)
+	right.SubBalance(addrs[2], big.NewInt(100))
+	left.Finalise(true)
+	right.Finalise(true)
+
+	orig.MergeAccounts(left, addrs[:2])
+	orig.MergeAccounts(right, addrs[2:])
+
+	if have := orig.GetBalance(addrs[0]); have.Cmp(big.NewInt(101)) != 0 {
+		t.Errorf("merged balance mismatch: have %v, want %v", have, 101)
+	}
+	if have := orig.GetNonce(addrs[1]); have != 5 {
+		t.Errorf("merged nonce mismatch: have %v, want %v", have, 5)
+	}
+	if orig.Exist(addrs[2]) {
+		t.Errorf("emptied account not deleted")
+	}
+	if have := orig.GetBalance(addrs[3]); have.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("untouched balance mismatch: have %v, want %v", have, 100)
+	}
+	// The merged state must hash to the same root as the sequential changes
+	orig.Finalise(true)
+	root := orig.IntermediateRoot(true)
+
+	want, _ := New(common.Hash{}, NewDatabase(ethdb.NewMemDatabase()))
+	for _, addr := range addrs {
+		want.AddBalance(addr, big.NewInt(100))
+	}
+	want.AddBalance(addrs[0], big.NewInt(1))
+	want.SetNonce(addrs[1], 5)
+	want.SubBalance(addrs[2], big.NewInt(100))
+	if have := want.IntermediateRoot(true); have != root {
+		t.Errorf("merged root mismatch: have %x, want %x", root, have)
+	}
+}
+
 func TestSnapshotRandom(t *testing.T) {
 	config := &quick.Config{MaxCount: 1000}
 	err := quick.Check((*snapshotTest).run, config)
@@ -0,0 +1,126 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Witness collects the trie nodes and contract code read from a state database,
+// keyed by their hash.
+type Witness struct {
+	nodes map[common.Hash][]byte
+	codes map[common.Hash][]byte
+	lock  sync.Mutex
+}
+
+// NewWitness creates an empty witness.
+func NewWitness() *Witness {
+	return &Witness{
+		nodes: make(map[common.Hash][]byte),
+		codes: make(map[common.Hash][]byte),
+	}
+}
+
+func (w *Witness) addNode(hash common.Hash, blob []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.nodes[hash] = common.CopyBytes(blob)
+}
+
+func (w *Witness) addCode(hash common.Hash, code []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.codes[hash] = common.CopyBytes(code)
+}
+
+// Nodes returns the trie nodes collected, ordered by hash.
+func (w *Witness) Nodes() [][]byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return sortedBlobs(w.nodes)
+}
+
+// Codes returns the contract code collected, ordered by hash.
+func (w *Witness) Codes() [][]byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return sortedBlobs(w.codes)
+}
+
+func sortedBlobs(blobs map[common.Hash][]byte) [][]byte {
+	hashes := make([]common.Hash, 0, len(blobs))
+	for hash := range blobs {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	list := make([][]byte, len(hashes))
+	for i, hash := range hashes {
+		list[i] = blobs[hash]
+	}
+	return list
+}
+
+// NewWitnessDatabase creates a state database reading through db, collecting
+// into the witness every trie node and contract code read. The state opened on
+// it should only be used for execution, never committed.
+func NewWitnessDatabase(db Database, witness *Witness) Database {
+	csc, _ := lru.New(codeSizeCacheSize)
+	return &witnessDB{
+		cachingDB: &cachingDB{
+			db:            trie.NewRecordingDatabase(db.TrieDB(), witness.addNode),
+			codeSizeCache: csc,
+		},
+		code:    db,
+		witness: witness,
+	}
+}
+
+// witnessDB is a state database collecting the trie nodes and code it reads.
+type witnessDB struct {
+	*cachingDB
+	code    Database // Database the contract code is read from, bypassing the node recording
+	witness *Witness
+}
+
+// ContractCode retrieves a particular contract's code, adding it to the witness.
+func (db *witnessDB) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := db.code.ContractCode(addrHash, codeHash)
+	if err == nil {
+		db.witness.addCode(codeHash, code)
+	}
+	return code, err
+}
+
+// ContractCodeSize retrieves a particular contract's code size, adding the code
+// to the witness as it is needed to prove the size.
+func (db *witnessDB) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
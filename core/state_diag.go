@@ -0,0 +1,217 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+)
+
+// StateDiag is the execution of a block broken down by transaction: the state
+// root after each and the accounts and storage slots each wrote. Comparing the
+// diagnostics of two nodes disagreeing on the state root of a block pinpoints
+// the transaction and the keys at which their executions diverge.
+type StateDiag struct {
+	Hash  common.Hash  `json:"hash"`
+	Steps []*StateStep `json:"steps"`           // One per transaction, then the finalisation
+	Error string       `json:"error,omitempty"` // Error invalidating the block, cutting the steps short
+}
+
+// StateStep is the state root after a transaction of a block, or after its
+// finalisation, along with the accounts and storage slots written.
+type StateStep struct {
+	TxHash  common.Hash            `json:"txHash"` // Zero for the finalisation
+	Root    common.Hash            `json:"root"`
+	Changes []*state.AccountChange `json:"changes"`
+}
+
+// StateDiag executes a block on top of its parent state, recording the state
+// root and the writes of every transaction.
+func (bc *BlockChain) StateDiag(ctx context.Context, block *types.Block) (*StateDiag, error) {
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	statedb, err := bc.StateAt(parent.Root())
+	if err != nil {
+		return nil, fmt.Errorf("parent state not available: %v", err)
+	}
+	statedb.TrackChanges()
+
+	var (
+		header      = block.Header()
+		signer      = types.MakeSigner(bc.chainConfig, header.Number)
+		gp          = new(GasPool).AddGas(block.GasLimit())
+		usedGas     = new(uint64)
+		receipts    types.Receipts
+		deleteEmpty = bc.chainConfig.IsEIP158(header.Number)
+		diag        = &StateDiag{Hash: block.Hash()}
+	)
+	evmContext := NewEVMContextLite(header, bc, nil)
+	for i, tx := range block.Transactions() {
+		vmenv := vm.NewEVM(evmContext, statedb, bc.chainConfig, bc.vmConfig)
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, _, err := ApplyTransaction(ctx, vmenv, bc.chainConfig, gp, statedb, header, tx, usedGas, signer)
+		if err != nil {
+			diag.Error = fmt.Sprintf("transaction %d: %v", i, err)
+			return diag, nil
+		}
+		receipts = append(receipts, receipt)
+		diag.Steps = append(diag.Steps, &StateStep{
+			TxHash:  tx.Hash(),
+			Root:    statedb.IntermediateRoot(deleteEmpty),
+			Changes: statedb.Changes(),
+		})
+	}
+	bc.engine.Finalize(ctx, bc, header, statedb, block.Transactions(), receipts, false)
+	diag.Steps = append(diag.Steps, &StateStep{
+		Root:    statedb.IntermediateRoot(deleteEmpty),
+		Changes: statedb.Changes(),
+	})
+	return diag, nil
+}
+
+// StateDivergence is the first step at which two executions of a block reach
+// different state roots, with the writes they disagree on.
+type StateDivergence struct {
+	Hash       common.Hash      `json:"hash"`
+	Step       int              `json:"step"`   // Index of the transaction, or their count for the finalisation
+	TxHash     common.Hash      `json:"txHash"` // Zero for the finalisation
+	LocalRoot  common.Hash      `json:"localRoot"`
+	RemoteRoot common.Hash      `json:"remoteRoot"`
+	Keys       []*KeyDivergence `json:"keys"`
+}
+
+// KeyDivergence is an account or a storage slot written differently by two
+// executions of a transaction.
+type KeyDivergence struct {
+	Address common.Address `json:"address"`
+	Slot    *common.Hash   `json:"slot,omitempty"` // Nil for the account itself
+	Local   *hexutil.Bytes `json:"local"`          // Nil if not written locally
+	Remote  *hexutil.Bytes `json:"remote"`         // Nil if not written remotely
+}
+
+// CompareStateDiags returns the first step at which a local and a remote
+// execution of the same block diverge, or nil if they agree throughout.
+func CompareStateDiags(local, remote *StateDiag) *StateDivergence {
+	for i := 0; i < len(local.Steps) || i < len(remote.Steps); i++ {
+		var (
+			div          = &StateDivergence{Hash: local.Hash, Step: i}
+			lstep, rstep *StateStep
+		)
+		if i < len(local.Steps) {
+			lstep = local.Steps[i]
+			div.TxHash, div.LocalRoot = lstep.TxHash, lstep.Root
+		}
+		if i < len(remote.Steps) {
+			rstep = remote.Steps[i]
+			div.TxHash, div.RemoteRoot = rstep.TxHash, rstep.Root
+		}
+		if lstep != nil && rstep != nil && lstep.TxHash == rstep.TxHash && lstep.Root == rstep.Root {
+			continue
+		}
+		div.Keys = compareChanges(lstep, rstep)
+		return div
+	}
+	return nil
+}
+
+// compareChanges returns the accounts and storage slots written differently by
+// two executions of a step, either of which may be missing.
+func compareChanges(local, remote *StateStep) []*KeyDivergence {
+	type written struct {
+		local, remote []byte
+		lset, rset    bool
+	}
+	var (
+		accounts = make(map[common.Address]*written)
+		slots    = make(map[common.Address]map[common.Hash]*written)
+	)
+	collect := func(step *StateStep, isLocal bool) {
+		if step == nil {
+			return
+		}
+		for _, change := range step.Changes {
+			if accounts[change.Address] == nil {
+				accounts[change.Address] = new(written)
+				slots[change.Address] = make(map[common.Hash]*written)
+			}
+			w := accounts[change.Address]
+			if isLocal {
+				w.local, w.lset = change.Account, true
+			} else {
+				w.remote, w.rset = change.Account, true
+			}
+			for _, slot := range change.Storage {
+				s := slots[change.Address][slot.Key]
+				if s == nil {
+					s = new(written)
+					slots[change.Address][slot.Key] = s
+				}
+				if isLocal {
+					s.local, s.lset = common.CopyBytes(slot.Value[:]), true
+				} else {
+					s.remote, s.rset = common.CopyBytes(slot.Value[:]), true
+				}
+			}
+		}
+	}
+	collect(local, true)
+	collect(remote, false)
+
+	differs := func(w *written) bool {
+		return w.lset != w.rset || !bytes.Equal(w.local, w.remote)
+	}
+	value := func(b []byte, set bool) *hexutil.Bytes {
+		if !set {
+			return nil
+		}
+		v := hexutil.Bytes(common.CopyBytes(b))
+		return &v
+	}
+	var keys []*KeyDivergence
+	for addr, w := range accounts {
+		if differs(w) {
+			keys = append(keys, &KeyDivergence{Address: addr, Local: value(w.local, w.lset), Remote: value(w.remote, w.rset)})
+		}
+		for key, s := range slots[addr] {
+			if differs(s) {
+				slot := key
+				keys = append(keys, &KeyDivergence{Address: addr, Slot: &slot, Local: value(s.local, s.lset), Remote: value(s.remote, s.rset)})
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Address != keys[j].Address {
+			return bytes.Compare(keys[i].Address[:], keys[j].Address[:]) < 0
+		}
+		if keys[i].Slot == nil || keys[j].Slot == nil {
+			return keys[i].Slot == nil && keys[j].Slot != nil
+		}
+		return bytes.Compare(keys[i].Slot[:], keys[j].Slot[:]) < 0
+	})
+	return keys
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+// Tests that the state diagnostics of a block record the root and the writes of
+// every transaction, and that comparing them pinpoints a diverging transaction.
+func TestStateDiag(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db       = ethdb.NewMemDatabase()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xaa}
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address:  {Balance: big.NewInt(1000000000)},
+				contract: {Balance: new(big.Int), Code: []byte{0x60, 0x01, 0x60, 0x00, 0x55}}, // sstore(0, 1)
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		engine  = clique.NewFaker()
+		signer  = types.HomesteadSigner{}
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := GenerateChain(ctx, gspec.Config, genesis, engine, db, 2, func(ctx context.Context, i int, block *BlockGen) {
+		transfer, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(ctx, transfer)
+		call, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, new(big.Int), nil), signer, key)
+		block.AddTx(ctx, call)
+	})
+	if _, err := blockchain.InsertChain(ctx, blocks[:1]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	diag, err := blockchain.StateDiag(ctx, blocks[0])
+	if err != nil {
+		t.Fatalf("failed to diagnose block: %v", err)
+	}
+	if diag.Error != "" || len(diag.Steps) != 3 {
+		t.Fatalf("step count mismatch: have %d (%s), want 3", len(diag.Steps), diag.Error)
+	}
+	if diag.Steps[2].Root != blocks[0].Root() || diag.Steps[2].TxHash != (common.Hash{}) {
+		t.Errorf("final step mismatch: have root %x, want %x", diag.Steps[2].Root, blocks[0].Root())
+	}
+	var recipient, stored bool
+	for _, change := range diag.Steps[0].Changes {
+		recipient = recipient || change.Address == (common.Address{0x01})
+	}
+	for _, change := range diag.Steps[1].Changes {
+		if change.Address == contract {
+			stored = len(change.Storage) == 1 && change.Storage[0].Value == common.BigToHash(big.NewInt(1))
+		}
+		if change.Address == (common.Address{0x01}) {
+			t.Errorf("unchanged account reported again")
+		}
+	}
+	if !recipient || !stored {
+		t.Errorf("writes missing: recipient %v, storage %v", recipient, stored)
+	}
+
+	// Diagnostics survive the network encoding and agree with themselves
+	enc, err := rlp.EncodeToBytes(diag)
+	if err != nil {
+		t.Fatalf("failed to encode diagnostics: %v", err)
+	}
+	remote := new(StateDiag)
+	if err := rlp.DecodeBytes(enc, remote); err != nil {
+		t.Fatalf("failed to decode diagnostics: %v", err)
+	}
+	if div := CompareStateDiags(diag, remote); div != nil {
+		t.Fatalf("identical executions diverge: %+v", div)
+	}
+	// A diverging storage write is pinpointed
+	remote.Steps[1].Root = common.Hash{0x01}
+	for _, change := range remote.Steps[1].Changes {
+		if change.Address == contract {
+			change.Storage[0].Value = common.BigToHash(big.NewInt(2))
+		}
+	}
+	div := CompareStateDiags(diag, remote)
+	if div == nil || div.Step != 1 || div.TxHash != blocks[0].Transactions()[1].Hash() {
+		t.Fatalf("divergence mismatch: %+v", div)
+	}
+	if len(div.Keys) != 1 || div.Keys[0].Address != contract || div.Keys[0].Slot == nil || *div.Keys[0].Slot != (common.Hash{}) {
+		t.Fatalf("diverging keys mismatch: %+v", div.Keys)
+	}
+
+	// Blocks rejected for their state root are reported as such
+	bad := make(chan BadBlockEvent, 1)
+	sub := blockchain.SubscribeBadBlockEvent(bad)
+	defer sub.Unsubscribe()
+
+	header := blocks[1].Header()
+	header.Root = common.Hash{0x01}
+	if _, err := blockchain.InsertChain(ctx, types.Blocks{types.NewBlockWithHeader(header).WithBody(blocks[1].Transactions(), nil)}); err == nil {
+		t.Fatalf("bad block imported")
+	}
+	select {
+	case ev := <-bad:
+		if _, ok := ev.Error.(*StateRootError); !ok {
+			t.Errorf("bad block error mismatch: have %T, want *StateRootError", ev.Error)
+		}
+	default:
+		t.Errorf("no bad block event")
+	}
+}
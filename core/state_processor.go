@@ -35,18 +35,23 @@ import (
 //
 // StateProcessor implements Processor.
 type StateProcessor struct {
-	config *params.ChainConfig // Chain configuration options
-	bc     *BlockChain         // Canonical block chain
-	engine consensus.Engine    // Consensus engine used for block rewards
+	config  *params.ChainConfig // Chain configuration options
+	bc      *BlockChain         // Canonical block chain
+	engine  consensus.Engine    // Consensus engine used for block rewards
+	workers int                 // Number of workers executing independent transactions in parallel
 }
 
 // NewStateProcessor initialises a new StateProcessor.
 func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine) *StateProcessor {
-	return &StateProcessor{
+	p := &StateProcessor{
 		config: config,
 		bc:     bc,
 		engine: engine,
 	}
+	if bc != nil && bc.cacheConfig != nil {
+		p.workers = bc.cacheConfig.TxWorkers
+	}
+	return p
 }
 
 // Process processes the state changes according to the Ethereum rules by running
@@ -76,20 +81,43 @@ func (p *StateProcessor) Process(ctx context.Context, block *types.Block, stated
 	// Create a new emv context and environment.
 	evmContext := NewEVMContextLite(header, p.bc, nil)
 	vmenv := vm.NewEVM(evmContext, statedb, p.config, cfg)
+	signer := types.MakeSigner(p.config, header.Number)
 
-	// Iterate over and process the individual transactions
-	for i, tx := range txs {
-		_, span := trace.StartSpan(ctx, "StateDB.Prepare")
-		statedb.Prepare(tx.Hash(), block.Hash(), i)
-		span.End()
+	// Without intermediate roots in the receipts, runs of independent transactions
+	// may be executed in parallel.
+	parallel := p.workers > 1 && p.config.IsByzantium(header.Number)
 
-		receipt, _, err := ApplyTransaction(ctx, vmenv, p.config, gp, statedb, header, tx, usedGas, types.MakeSigner(p.config, header.Number))
-		if err != nil {
-			return nil, nil, 0, err
+	// Iterate over and process the individual transactions
+	for i := 0; i < len(txs); {
+		n := 1
+		if parallel {
+			if run := independentTxs(p.config, header.Number, statedb, signer, evmContext.Coinbase, txs[i:]); run >= minParallelTxs {
+				err := p.applyParallel(ctx, block, statedb, evmContext, cfg, signer, gp, usedGas, receipts, i, i+run)
+				if err == nil {
+					i += run
+					continue
+				}
+				log.Debug("Falling back to serial transaction execution", "number", header.Number, "from", i, "txs", run, "err", err)
+				parallelFallbackMeter.Mark(1)
+				n = run
+			} else if run > 1 {
+				n = run
+			}
+		}
+		for end := i + n; i < end; i++ {
+			tx := txs[i]
+			_, span := trace.StartSpan(ctx, "StateDB.Prepare")
+			statedb.Prepare(tx.Hash(), block.Hash(), i)
+			span.End()
+
+			receipt, _, err := ApplyTransaction(ctx, vmenv, p.config, gp, statedb, header, tx, usedGas, signer)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+
+			receipts[i] = receipt
+			allLogs = append(allLogs, receipt.Logs...)
 		}
-
-		receipts[i] = receipt
-		allLogs = append(allLogs, receipt.Logs...)
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
 	_ = p.engine.Finalize(ctx, p.bc, header, statedb, block.Transactions(), receipts, false)
@@ -0,0 +1,222 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+
+	"go.opencensus.io/trace"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// minParallelTxs is the smallest run of independent transactions worth the
+// overhead of executing in parallel.
+const minParallelTxs = 8
+
+var (
+	parallelTxMeter       = metrics.NewMeter("chain/txs/parallel") // Transactions executed in parallel
+	parallelFallbackMeter = metrics.NewMeter("chain/txs/fallback") // Parallel runs re-executed serially
+
+	errSharedAccounts = errors.New("transactions share accounts")
+	errParallelLogs   = errors.New("transaction emitted logs")
+)
+
+// independentTxs returns the length of the leading run of txs whose accounts
+// are known before execution: calls to accounts without code, touching only
+// the sender, the recipient, the gas sponsor if any and the fee recipient.
+// Transactions of such a run sharing no accounts other than the fee recipient
+// can be executed in any order, as long as the fee recipient isn't read by any
+// of them.
+//
+// Contract creations and calls may touch arbitrary accounts, and end the run.
+func independentTxs(config *params.ChainConfig, num *big.Int, statedb *state.StateDB, signer types.Signer, coinbase common.Address, txs types.Transactions) int {
+	for i, tx := range txs {
+		to := tx.To()
+		if to == nil || *to == coinbase || statedb.GetCodeSize(*to) != 0 {
+			return i
+		}
+		// Senders are cached by the import, so this rarely recovers signatures.
+		from, err := types.Sender(context.Background(), signer, tx)
+		if err != nil || from == coinbase || config.GasPayer(num, from, to, tx.GasPrice()) == coinbase {
+			return i
+		}
+	}
+	return len(txs)
+}
+
+// txAccounts are the accounts touched by an independent transaction, besides
+// the fee recipient.
+func txAccounts(config *params.ChainConfig, num *big.Int, signer types.Signer, tx *types.Transaction) []common.Address {
+	from, _ := types.Sender(context.Background(), signer, tx) // validated by independentTxs
+
+	accounts := []common.Address{from}
+	if to := *tx.To(); to != from {
+		accounts = append(accounts, to)
+	}
+	if payer := config.GasPayer(num, from, tx.To(), tx.GasPrice()); payer != from && payer != *tx.To() {
+		accounts = append(accounts, payer)
+	}
+	return accounts
+}
+
+// parallelWorker executes a subset of a run of independent transactions on its
+// own copy of the state.
+type parallelWorker struct {
+	statedb  *state.StateDB
+	txs      []int            // Indexes of the transactions to execute, in block order
+	accounts []common.Address // Accounts touched by the transactions
+	err      error
+}
+
+// applyParallel executes the independent transactions txs[from:to] of block in
+// parallel, and merges their effects into statedb. Transactions sharing an
+// account are executed by the same worker, in block order.
+//
+// If any transaction fails, or the results can't be reconciled with serial
+// execution, an error is returned and statedb, gp and usedGas are left intact,
+// so that the run can be executed serially instead.
+func (p *StateProcessor) applyParallel(ctx context.Context, block *types.Block, statedb *state.StateDB, evmContext vm.Context, cfg vm.Config, signer types.Signer, gp *GasPool, usedGas *uint64, receipts types.Receipts, from, to int) error {
+	ctx, span := trace.StartSpan(ctx, "StateProcessor.applyParallel")
+	defer span.End()
+	span.AddAttributes(trace.Int64Attribute("txs", int64(to-from)))
+
+	txs := block.Transactions()
+
+	// Group the transactions by the accounts they share, merging groups as shared
+	// accounts are found.
+	parent := make(map[common.Address]common.Address)
+
+	var find func(addr common.Address) common.Address
+	find = func(addr common.Address) common.Address {
+		if up, ok := parent[addr]; ok && up != addr {
+			root := find(up)
+			parent[addr] = root
+			return root
+		}
+		parent[addr] = addr
+		return addr
+	}
+	for i := from; i < to; i++ {
+		accounts := txAccounts(p.config, block.Number(), signer, txs[i])
+		root := find(accounts[0])
+		for _, addr := range accounts[1:] {
+			if other := find(addr); other != root {
+				parent[other] = root
+			}
+		}
+	}
+	// Spread the groups over the workers in order of their first transaction,
+	// keeping the assignment deterministic.
+	workers := make([]*parallelWorker, p.workers)
+	for i := range workers {
+		workers[i] = new(parallelWorker)
+	}
+	assigned := make(map[common.Address]*parallelWorker)
+	for i := from; i < to; i++ {
+		accounts := txAccounts(p.config, block.Number(), signer, txs[i])
+
+		root := find(accounts[0])
+		w, ok := assigned[root]
+		if !ok {
+			w = workers[len(assigned)%len(workers)]
+			assigned[root] = w
+		}
+		w.txs = append(w.txs, i)
+		w.accounts = append(w.accounts, accounts...)
+	}
+	if len(assigned) < 2 {
+		return errSharedAccounts
+	}
+	if len(assigned) < len(workers) {
+		workers = workers[:len(assigned)]
+	}
+	// Execute the transactions of each worker on its own copy of the state
+	coinbase := evmContext.Coinbase
+	baseFees := statedb.GetBalance(coinbase)
+
+	results := make(types.Receipts, len(txs))
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		w.statedb = statedb.Copy(ctx)
+
+		wg.Add(1)
+		go func(w *parallelWorker) {
+			defer wg.Done()
+
+			var (
+				vmenv = vm.NewEVM(evmContext, w.statedb, p.config, cfg)
+				wgp   = new(GasPool).AddGas(block.GasLimit())
+				used  uint64
+			)
+			for _, i := range w.txs {
+				w.statedb.Prepare(txs[i].Hash(), block.Hash(), i)
+
+				receipt, _, err := ApplyTransaction(ctx, vmenv, p.config, wgp, w.statedb, block.Header(), txs[i], &used, signer)
+				if err != nil {
+					w.err = err
+					return
+				}
+				if len(receipt.Logs) > 0 {
+					w.err = errParallelLogs
+					return
+				}
+				results[i] = receipt
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, w := range workers {
+		if w.err != nil {
+			return w.err
+		}
+	}
+	// Make sure the block gas limit would have admitted the transactions in order
+	available, cumulative := gp.Gas(), *usedGas
+	for i := from; i < to; i++ {
+		if available < txs[i].Gas() {
+			return ErrGasLimitReached
+		}
+		available -= results[i].GasUsed
+		cumulative += results[i].GasUsed
+		results[i].CumulativeGasUsed = cumulative
+	}
+	// Everything checks out, adopt the results of the workers
+	fees := new(big.Int)
+	for _, w := range workers {
+		statedb.MergeAccounts(w.statedb, w.accounts)
+		fees.Add(fees, new(big.Int).Sub(w.statedb.GetBalance(coinbase), baseFees))
+	}
+	statedb.AddBalance(coinbase, fees)
+	statedb.Finalise(true)
+
+	gp.SubGas(gp.Gas() - available)
+	*usedGas = cumulative
+	copy(receipts[from:to], results[from:to])
+
+	parallelTxMeter.Mark(int64(to - from))
+	return nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 	"testing"
@@ -118,3 +119,159 @@ func TestStateProcessor(t *testing.T) {
 	}
 	t.Logf("process() duration: %s", time.Since(start))
 }
+
+// Tests that executing independent transactions in parallel yields the same
+// state, receipts and gas usage as executing them serially.
+func TestStateProcessorParallel(t *testing.T) {
+	ctx := context.Background()
+
+	keys := make([]*ecdsa.PrivateKey, 32)
+	alloc := make(GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = GenesisAccount{Balance: big.NewInt(1000000000)}
+	}
+	genesis := &Genesis{
+		Config:     params.TestChainConfig,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+	bc, err := newTestBlockChainWithGenesis(ctx, false, false, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Stop()
+
+	header := &types.Header{
+		ParentHash: bc.CurrentBlock().Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   bc.GasLimit(),
+	}
+	coinbase, _ := bc.engine.Author(header)
+
+	var (
+		signer = types.NewEIP155Signer(genesis.Config.ChainId)
+		nonces = make([]uint64, len(keys))
+		txs    types.Transactions
+	)
+	transfer := func(from int, to common.Address) {
+		tx, _ := types.SignTx(types.NewTransaction(nonces[from], to, big.NewInt(100), 21000, big.NewInt(1), nil), signer, keys[from])
+		txs = append(txs, tx)
+		nonces[from]++
+	}
+	// Independent transfers, some sharing senders or recipients
+	for i := 0; i < 24; i++ {
+		transfer(i, common.Address{0xaa, byte(i % 6)})
+	}
+	transfer(0, crypto.PubkeyToAddress(keys[1].PublicKey))
+	transfer(2, crypto.PubkeyToAddress(keys[2].PublicKey))
+
+	// A contract creation breaking the run
+	tx, _ := types.SignTx(types.NewContractCreation(nonces[24], new(big.Int), 100000, big.NewInt(1), []byte{0x00}), signer, keys[24])
+	txs = append(txs, tx)
+	nonces[24]++
+
+	// A run of transfers from a single sender, which can't be parallelised
+	for i := 0; i < minParallelTxs; i++ {
+		transfer(25, common.Address{0xbb, byte(i)})
+	}
+	// A run of transfers to the fee recipient, which isn't independent
+	for i := 26; i < len(keys); i++ {
+		transfer(i, coinbase)
+	}
+	block := types.NewBlock(header, txs, nil, nil)
+
+	process := func(workers int) (common.Hash, types.Receipts, uint64) {
+		statedb, err := state.New(bc.CurrentBlock().Root(), bc.stateCache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := NewStateProcessor(genesis.Config, bc, bc.engine)
+		p.workers = workers
+
+		receipts, _, usedGas, err := p.Process(ctx, block, statedb, vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to process block with %d workers: %v", workers, err)
+		}
+		return statedb.IntermediateRoot(true), receipts, usedGas
+	}
+	serialRoot, serialReceipts, serialGas := process(1)
+	for _, workers := range []int{2, 4, 64} {
+		root, receipts, usedGas := process(workers)
+		if root != serialRoot {
+			t.Errorf("%d workers: state root mismatch: have %x, want %x", workers, root, serialRoot)
+		}
+		if usedGas != serialGas {
+			t.Errorf("%d workers: used gas mismatch: have %d, want %d", workers, usedGas, serialGas)
+		}
+		if types.DeriveSha(receipts) != types.DeriveSha(serialReceipts) {
+			t.Errorf("%d workers: receipts mismatch", workers)
+		}
+	}
+}
+
+// Tests that the gas of sponsored transactions is debited from the sponsor, both
+// when executed serially and in parallel.
+func TestStateProcessorGasSponsor(t *testing.T) {
+	ctx := context.Background()
+
+	sponsor := common.Address{0xaa}
+	value := big.NewInt(100)
+
+	keys := make([]*ecdsa.PrivateKey, 2*minParallelTxs)
+	senders := make([]common.Address, len(keys))
+	alloc := GenesisAlloc{sponsor: {Balance: big.NewInt(1000000000)}}
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		senders[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[senders[i]] = GenesisAccount{Balance: value}
+	}
+	config := *params.TestChainConfig
+	config.GasSponsor = &params.GasSponsorConfig{Sponsor: sponsor, Block: big.NewInt(0), MaxGasPrice: big.NewInt(1), Senders: senders}
+
+	genesis := &Genesis{
+		Config:     &config,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+	bc, err := newTestBlockChainWithGenesis(ctx, false, false, genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Stop()
+
+	signer := types.NewEIP155Signer(genesis.Config.ChainId)
+	txs := make(types.Transactions, len(keys))
+	for i, key := range keys {
+		txs[i], _ = types.SignTx(types.NewTransaction(0, common.Address{0xbb, byte(i)}, value, 21000, big.NewInt(1), nil), signer, key)
+	}
+	block := types.NewBlock(&types.Header{
+		ParentHash: bc.CurrentBlock().Hash(),
+		Number:     big.NewInt(1),
+		GasLimit:   bc.GasLimit(),
+	}, txs, nil, nil)
+
+	for _, workers := range []int{1, 4} {
+		statedb, err := state.New(bc.CurrentBlock().Root(), bc.stateCache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := NewStateProcessor(genesis.Config, bc, bc.engine)
+		p.workers = workers
+
+		if _, _, usedGas, err := p.Process(ctx, block, statedb, vm.Config{}); err != nil {
+			t.Fatalf("%d workers: failed to process block: %v", workers, err)
+		} else if usedGas != uint64(len(txs))*21000 {
+			t.Errorf("%d workers: used gas mismatch: have %d, want %d", workers, usedGas, len(txs)*21000)
+		}
+		want := big.NewInt(1000000000 - int64(len(txs))*21000)
+		if balance := statedb.GetBalance(sponsor); balance.Cmp(want) != 0 {
+			t.Errorf("%d workers: sponsor balance mismatch: have %v, want %v", workers, balance, want)
+		}
+		for i, sender := range senders {
+			if balance := statedb.GetBalance(sender); balance.Sign() != 0 {
+				t.Errorf("%d workers: sender %d balance mismatch: have %v, want 0", workers, i, balance)
+			}
+		}
+	}
+}
@@ -54,6 +54,7 @@ type StateTransition struct {
 	gas        uint64
 	gasPrice   *big.Int
 	initialGas uint64
+	payer      common.Address // Account paying for the gas, the sender unless sponsored
 	value      *big.Int
 	data       []byte
 	state      vm.StateDB
@@ -150,8 +151,10 @@ func (st *StateTransition) useGas(amount uint64) error {
 }
 
 func (st *StateTransition) buyGas() error {
+	st.payer = st.evm.ChainConfig().GasPayer(st.evm.BlockNumber, st.msg.From(), st.msg.To(), st.gasPrice)
+
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if st.state.GetBalance(st.msg.From()).Cmp(mgval) < 0 {
+	if st.state.GetBalance(st.payer).Cmp(mgval) < 0 {
 		return errInsufficientBalanceForGas
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -160,7 +163,7 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	st.state.SubBalance(st.msg.From(), mgval)
+	st.state.SubBalance(st.payer, mgval)
 	return nil
 }
 
@@ -237,7 +240,7 @@ func (st *StateTransition) refundGas() {
 
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(st.msg.From(), remaining)
+	st.state.AddBalance(st.payer, remaining)
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
@@ -345,26 +345,44 @@ func (l *txList) Overlaps(tx *types.Transaction) bool {
 	return l.txs.Get(tx.Nonce()) != nil
 }
 
-// Add tries to insert a new transaction into the list, returning whether the
-// transaction was accepted, and if yes, any previous transaction it replaced.
+// Add tries to insert a new transaction into the list, returning any previous
+// transaction it replaced, or the reason why it was rejected. Replacements must
+// raise the gas price by priceBump percent, or if samePrice is set, must not
+// lower it.
 //
 // If the new transaction is accepted into the list, the lists' cost and gas
 // thresholds are also potentially updated.
-func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
+func (l *txList) Add(tx *types.Transaction, priceBump uint64, samePrice bool) (*types.Transaction, error) {
 	// If there's an older better transaction, abort
 	old := l.txs.Get(tx.Nonce())
 	if old != nil {
-		threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
-		// Have to ensure that the new gas price is higher than the old gas
-		// price as well as checking the percentage threshold to ensure that
-		// this is accurate for low (Wei-level) gas price replacements
-		if old.CmpGasPriceTx(tx) >= 0 || tx.CmpGasPrice(threshold) < 0 {
-			return false, nil
+		if err := checkReplacement(old, tx, priceBump, samePrice); err != nil {
+			return nil, err
 		}
 	}
 	// Otherwise overwrite the old transaction with the current one
 	l.add(tx)
-	return true, old
+	return old, nil
+}
+
+// checkReplacement returns why tx may not replace old, or nil if it may.
+func checkReplacement(old, tx *types.Transaction, priceBump uint64, samePrice bool) error {
+	switch cmp := old.CmpGasPriceTx(tx); {
+	case cmp > 0:
+		return ErrReplaceLowerPrice
+	case cmp == 0 && samePrice:
+		return nil
+	case cmp == 0:
+		return ErrReplaceSamePrice
+	}
+	// Have to ensure that the new gas price is higher than the old gas
+	// price as well as checking the percentage threshold to ensure that
+	// this is accurate for low (Wei-level) gas price replacements
+	threshold := new(big.Int).Div(new(big.Int).Mul(old.GasPrice(), big.NewInt(100+int64(priceBump))), big.NewInt(100))
+	if tx.CmpGasPrice(threshold) < 0 {
+		return ErrReplaceUnderpriced
+	}
+	return nil
 }
 
 func (l *txList) add(tx *types.Transaction) {
@@ -385,15 +403,15 @@ func (l *txList) Forward(threshold uint64, fn func(*types.Transaction)) {
 }
 
 // Filter removes all transactions from the list with a cost or gas limit higher
-// than the provided thresholds. Every removed transaction is returned for any
-// post-removal maintenance. Strict-mode invalidated transactions are also
-// returned.
+// than the provided thresholds, the cost to the sender being given by cost. Every
+// removed transaction is returned for any post-removal maintenance. Strict-mode
+// invalidated transactions are also returned.
 //
 // This method uses the cached costcap and gascap to quickly decide if there's even
 // a point in calculating all the costs or if the balance covers all. If the threshold
 // is lower than the costgas cap, the caps will be reset to a new high after removing
 // the newly invalidated transactions.
-func (l *txList) Filter(costLimit *big.Int, gasLimit uint64, removed, invalid func(*types.Transaction)) {
+func (l *txList) Filter(costLimit *big.Int, gasLimit uint64, cost func(*types.Transaction) *big.Int, removed, invalid func(*types.Transaction)) {
 	// If all transactions are below the threshold, short circuit
 	if l.costcap.Cmp(costLimit) <= 0 && l.gascap <= gasLimit {
 		return
@@ -402,7 +420,7 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64, removed, invalid fu
 	l.gascap = gasLimit
 
 	filter := func(tx *types.Transaction) bool {
-		return tx.Cost().Cmp(costLimit) > 0 || tx.Gas() > gasLimit
+		return cost(tx).Cmp(costLimit) > 0 || tx.Gas() > gasLimit
 	}
 	l.txs.Filter(filter, l.strict, removed, invalid)
 }
@@ -24,8 +24,10 @@ import (
 	"math/big"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/golang-lru"
 	"go.opencensus.io/trace"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 
@@ -42,6 +44,10 @@ import (
 const (
 	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
 	chainHeadChanSize = 10
+
+	// txArrivalCacheSize is the number of transactions whose arrival times are
+	// remembered, past their inclusion in blocks.
+	txArrivalCacheSize = 65536
 )
 
 var (
@@ -61,12 +67,26 @@ var (
 
 	// ErrReplaceUnderpriced is returned if a transaction is attempted to be replaced
 	// with a different one without the required price bump.
-	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
+	ErrReplaceUnderpriced error = &ReplacementError{4450, "replacement transaction underpriced"}
+
+	// ErrReplaceLowerPrice is returned if a transaction is attempted to be replaced
+	// with a different one paying a lower gas price.
+	ErrReplaceLowerPrice error = &ReplacementError{4451, "replacement transaction underpriced: gas price lower than existing"}
+
+	// ErrReplaceSamePrice is returned if a transaction is attempted to be replaced
+	// with a different one paying the same gas price, which is only permitted for
+	// local accounts if the pool is configured so.
+	ErrReplaceSamePrice error = &ReplacementError{4452, "replacement transaction underpriced: gas price equal to existing"}
 
 	// ErrInsufficientFunds is returned if the total cost of executing a transaction
 	// is higher than the balance of the user's account.
 	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
 
+	// ErrSponsorFunds is returned if the gas of a sponsored transaction, added to
+	// that of the other pooled transactions it sponsors, costs more than the
+	// balance of the sponsor account.
+	ErrSponsorFunds = errors.New("insufficient sponsor funds for gas * price")
+
 	// ErrIntrinsicGas is returned if the transaction is specified to use less gas
 	// than required to start the invocation.
 	ErrIntrinsicGas = errors.New("intrinsic gas too low")
@@ -85,10 +105,24 @@ var (
 	ErrOversizedData = errors.New("oversized data")
 )
 
+// ReplacementError is returned if a transaction cannot replace the pooled one
+// with the same nonce. The messages all start like the historical generic one,
+// while the JSON-RPC error codes tell the unmet replacement conditions apart.
+type ReplacementError struct {
+	code int
+	msg  string
+}
+
+func (e *ReplacementError) Error() string { return e.msg }
+
+// ErrorCode returns the JSON-RPC error code of the replacement failure.
+func (e *ReplacementError) ErrorCode() int { return e.code }
+
 var (
 	evictionInterval    = time.Minute      // Time interval to check for evictable transactions
 	statsReportInterval = 10 * time.Second // Time interval to report transaction pool stats
 	resetInterval       = 1 * time.Second  // Time interval to reset the txpool
+	deadlineInterval    = 1 * time.Second  // Time interval to drop transactions past their deadline
 )
 
 var (
@@ -115,6 +149,7 @@ var (
 	journalInsertTimer   = metrics.NewTimer("txpool/journal/insert")
 	chainHeadGauge       = metrics.NewGauge("txpool/chain/head")
 	chainHeadTxsGauge    = metrics.NewGauge("txpool/chain/head/txs")
+	tenantQuotaCounter   = metrics.NewCounter("txpool/tenant/quota") // Rejected due to tenant quotas
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -146,12 +181,20 @@ type TxPoolConfig struct {
 	PriceLimit uint64 `toml:",omitempty"` // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 `toml:",omitempty"` // Minimum price bump percentage to replace an already existing transaction (nonce)
 
+	LocalSamePriceReplace bool `toml:",omitempty"` // Whether local accounts may replace transactions without raising the gas price
+
 	AccountSlots uint64 `toml:",omitempty"` // Minimum number of executable transaction slots guaranteed per account
 	GlobalSlots  uint64 `toml:",omitempty"` // Maximum number of executable transaction slots for all accounts
 	AccountQueue uint64 `toml:",omitempty"` // Maximum number of non-executable transaction slots permitted per account
 	GlobalQueue  uint64 `toml:",omitempty"` // Maximum number of non-executable transaction slots for all accounts
 
-	Lifetime time.Duration `toml:",omitempty"` // Maximum amount of time non-executable transaction are queued
+	AccountQuota     uint64 `toml:",omitempty"` // Maximum number of pending and queued transactions per account, 0 for no limit
+	AccountQuotaBump uint64 `toml:",omitempty"` // Gas price bump percentage over the account's highest priced transaction to exceed its quota, 0 to never
+
+	Lifetime      time.Duration  `toml:",omitempty"` // Maximum amount of time non-executable transaction are queued
+	LifetimeTiers []LifetimeTier `toml:",omitempty"` // Lifetimes overriding the above for groups of accounts, locals included
+
+	Tenants []TenantQuota `toml:",omitempty"` // Quotas of the transactions submitted by tenants of the RPC API
 }
 
 // DefaultTxPoolConfig contains the default configurations for the transaction
@@ -168,6 +211,8 @@ var DefaultTxPoolConfig = TxPoolConfig{
 	AccountQueue: 1024,
 	GlobalQueue:  8192,
 
+	AccountQuotaBump: 100,
+
 	Lifetime: 3 * time.Hour,
 }
 
@@ -226,6 +271,9 @@ type TxPool struct {
 	txFeed       event.Feed
 	txFeedBuf    chan *types.Transaction
 	scope        event.SubscriptionScope
+	txEventFeed  event.Feed
+	txEventBuf   chan TxLifecycleEvent
+	txEventScope event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
 	signer       types.Signer
@@ -234,18 +282,33 @@ type TxPool struct {
 	currentState  *state.StateDB      // Current state in the blockchain head
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
+	typedTx       int32               // Whether typed transactions are valid in the next block, accessed atomically
+	nextNumber    *big.Int            // Number of the next block, deciding gas sponsorship
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals    *accountSet // Set of local transaction to exempt from eviction rules
+	journal   *txJournal  // Journal of local transaction to back up to disk
+	replaying bool        // Whether the journal is being loaded, exempting it from account quotas
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
 	beats   map[common.Address]time.Time // Last heartbeat from each known account
 	all     *txLookup                    // All transactions to allow lookups
+	mined   map[common.Hash]*types.Block // Transactions included by the head being reset to
+	private privateTxs                   // Transactions kept out of the network and the journal
+	tenants *tenantTxs                   // Transactions submitted on behalf of RPC tenants
+
+	deadlines txDeadlines // Transactions only valid until a deadline
+	sponsors  txSponsors  // Gas sponsored for the pooled transactions
+
+	tiers    map[common.Address]*LifetimeTier // Queue lifetime overrides of accounts
+	arrivals *lru.Cache                       // Times recently added transactions were first seen
 
 	wg sync.WaitGroup // for shutdown sync
 
 	homestead bool
+
+	importGuard atomic.Value // *ImportGuard consulted before accepting transactions
+	blocklist   atomic.Value // *Blocklist of contracts transactions may not interact with
 }
 
 // NewTxPool creates a new transaction pool to gather, sort and filter inbound
@@ -270,7 +333,14 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 		txFeedBuf:   make(chan *types.Transaction, config.GlobalSlots/4),
-	}
+		txEventBuf:  make(chan TxLifecycleEvent, txEventChanSize),
+		private:     privateTxs{hashes: make(map[common.Hash]struct{})},
+		tenants:     newTenantTxs(config.Tenants),
+		deadlines:   newTxDeadlines(),
+		sponsors:    newTxSponsors(),
+		tiers:       newLifetimeTiers(config.LifetimeTiers),
+	}
+	pool.arrivals, _ = lru.New(txArrivalCacheSize)
 	pool.locals = newAccountSet(pool.signer)
 	pool.reset(ctx, nil, chain.CurrentBlock())
 
@@ -280,6 +350,9 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		pool.journal = newTxJournal(config.Journal)
 		if err := pool.journal.load(func(txs types.Transactions) []error {
 			// No need to lock since we're still setting up.
+			pool.replaying = true
+			defer func() { pool.replaying = false }()
+
 			return pool.addTxsLocked(ctx, txs, !pool.config.NoLocals)
 		}); err != nil {
 			log.Warn("Failed to load transaction journal", "err", err)
@@ -293,9 +366,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	// Subscribe events from blockchain.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 	// Spawn worker routines to run until chainHeadSub unsub.
-	pool.wg.Add(3)
+	pool.wg.Add(4)
 	go pool.loop()
 	go pool.feedLoop()
+	go pool.txEventLoop()
 
 	return pool
 }
@@ -318,6 +392,9 @@ func (pool *TxPool) loop() {
 	reset := time.NewTicker(resetInterval)
 	defer reset.Stop()
 
+	expire := time.NewTicker(deadlineInterval)
+	defer expire.Stop()
+
 	journal := time.NewTicker(pool.config.Rejournal)
 	defer journal.Stop()
 
@@ -421,22 +498,30 @@ func (pool *TxPool) loop() {
 			_, span := trace.StartSpan(context.Background(), "TxPool.loop-evict")
 			pool.mu.Lock()
 			for addr := range pool.queue {
-				// Skip local transactions from the eviction mechanism
-				if pool.locals.contains(addr) {
+				// Skip accounts exempt from the eviction mechanism, locals by default
+				_, lifetime := pool.queueLifetime(addr)
+				if lifetime == 0 {
 					continue
 				}
-				// Any non-locals old enough should be removed
-				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
+				// Any others old enough should be removed
+				if time.Since(pool.beats[addr]) > lifetime {
 					queued := pool.queue[addr]
 					for _, tx := range queued.txs.items {
-						pool.all.Remove(tx.Hash())
+						pool.dropTx(tx.Hash(), DropExpired)
 					}
 					delete(pool.queue, addr)
+					if pool.pending[addr] == nil {
+						delete(pool.beats, addr)
+					}
 				}
 			}
 			pool.mu.Unlock()
 			span.End()
 
+		// Handle transactions past their deadline
+		case <-expire.C:
+			pool.expireDeadlines(context.Background(), time.Now())
+
 		// Handle local transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
@@ -585,6 +670,17 @@ func (pool *TxPool) reset(ctx context.Context, oldBlock, newBlock *types.Block)
 	pool.currentState = statedb
 	pool.pendingState = state.ManageState(ctx, statedb)
 	pool.currentMaxGas = newBlock.GasLimit()
+	pool.nextNumber = new(big.Int).Add(newBlock.Number(), big.NewInt(1))
+	if pool.chainconfig.IsTypedTx(pool.nextNumber) {
+		atomic.StoreInt32(&pool.typedTx, 1)
+	} else {
+		atomic.StoreInt32(&pool.typedTx, 0)
+	}
+	pool.resetSponsored()
+
+	// Track the newly mined transactions to report their inclusion
+	pool.mined = pool.minedTxs(oldBlock, newBlock)
+	defer func() { pool.mined = nil }()
 
 	if l := len(reinject); l > 0 {
 		// Inject any transactions discarded due to reorgs.
@@ -612,12 +708,16 @@ func (pool *TxPool) reset(ctx context.Context, oldBlock, newBlock *types.Block)
 	// Check the queue and move transactions over to the pending if possible
 	// or remove those that have become invalid
 	pool.promoteExecutablesAll(ctx)
+	pool.prunePrivate()
+	pool.pruneTenants()
+	pool.pruneDeadlines()
 }
 
 // Stop terminates the transaction pool.
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
 	pool.scope.Close()
+	pool.txEventScope.Close()
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
@@ -650,6 +750,7 @@ func (pool *TxPool) SetGasPrice(ctx context.Context, price *big.Int) {
 	pool.all.ForEach(func(tx *types.Transaction) {
 		if tx.CmpGasPrice(price) < 0 && !pool.locals.containsTx(ctx, tx) {
 			pool.removeTx(ctx, tx)
+			pool.txEvent(TxLifecycleEvent{Hash: tx.Hash(), Kind: TxDropped, Reason: DropUnderpriced})
 		}
 	})
 	log.Info("Transaction pool price threshold updated", "price", price)
@@ -745,7 +846,8 @@ func (pool *TxPool) PendingList(ctx context.Context) types.Transactions {
 	return pending
 }
 
-// local retrieves all currently known local transactions. The returned
+// local retrieves all currently known local transactions, except the private
+// ones and those valid until a deadline, which are never journaled. The returned
 // transaction set is a copy and can be freely modified by calling code.
 func (pool *TxPool) local() (int, types.Transactions) {
 	var acts int
@@ -755,12 +857,12 @@ func (pool *TxPool) local() (int, types.Transactions) {
 		if pending := pool.pending[addr]; pending != nil {
 			ok = true
 			pending.txs.ensureCache()
-			txs = append(txs, pending.txs.cache...)
+			txs = pool.appendJournaled(txs, pending.txs.cache)
 		}
 		if queued := pool.queue[addr]; queued != nil {
 			ok = true
 			queued.txs.ensureCache()
-			txs = append(txs, queued.txs.cache...)
+			txs = pool.appendJournaled(txs, queued.txs.cache)
 		}
 		if ok {
 			acts++
@@ -769,12 +871,46 @@ func (pool *TxPool) local() (int, types.Transactions) {
 	return acts, txs
 }
 
+// journaled reports whether a local transaction is to be journaled, neither
+// submitted privately nor valid until a deadline only.
+func (pool *TxPool) journaled(hash common.Hash) bool {
+	return !pool.Withheld(hash)
+}
+
+// Withheld reports whether a pooled transaction must not be propagated to peers,
+// being submitted privately or valid until a deadline they don't know about.
+func (pool *TxPool) Withheld(hash common.Hash) bool {
+	if pool.IsPrivate(hash) {
+		return true
+	}
+	_, dated := pool.Deadline(hash)
+	return dated
+}
+
+// appendJournaled appends the transactions to be journaled to txs.
+func (pool *TxPool) appendJournaled(txs types.Transactions, add types.Transactions) types.Transactions {
+	for _, tx := range add {
+		if pool.journaled(tx.Hash()) {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
 // preValidateTx does preliminary transaction validation (a subset of validateTx), without requiring pool.mu to be held.
 func (pool *TxPool) preValidateTx(ctx context.Context, tx *types.Transaction, local bool) error {
 	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
 	if tx.Size() > 32*1024 {
 		return ErrOversizedData
 	}
+	// Typed transactions are only accepted once activated, and must pass the
+	// checks of their type.
+	if tx.Type() != types.LegacyTxType && atomic.LoadInt32(&pool.typedTx) == 0 {
+		return types.ErrTxTypeNotSupported
+	}
+	if err := types.ValidateTxType(tx); err != nil {
+		return err
+	}
 	// Transactions can't be negative. This may never happen using RLP decoded
 	// transactions but may occur if you create a transaction using the RPC.
 	if tx.Value().Sign() < 0 {
@@ -785,7 +921,21 @@ func (pool *TxPool) preValidateTx(ctx context.Context, tx *types.Transaction, lo
 	if err != nil {
 		return ErrInvalidSender
 	}
-	return nil
+	// Give the external import policy, if any, a chance to veto the transaction
+	guard, _ := pool.importGuard.Load().(*ImportGuard)
+	return guard.CheckTransaction(ctx, tx)
+}
+
+// SetImportGuard sets the policy consulted before accepting transactions. A nil
+// guard permits all transactions.
+func (pool *TxPool) SetImportGuard(guard *ImportGuard) {
+	pool.importGuard.Store(guard)
+}
+
+// SetBlocklist sets the contracts transactions to which are rejected. A nil
+// blocklist permits all transactions.
+func (pool *TxPool) SetBlocklist(blocklist *Blocklist) {
+	pool.blocklist.Store(blocklist)
 }
 
 // validateTx checks whether a transaction is valid according to the consensus
@@ -823,10 +973,16 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction, local
 		return ErrNonceTooLow
 	}
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
+	// cost == V + GP * GL, the sponsor paying for GP * GL if whitelisted
+	if pool.currentState.GetBalance(from).Cmp(pool.txCost(from, tx)) < 0 {
 		return ErrInsufficientFunds
 	}
+	if payer := pool.chainconfig.GasPayer(pool.nextNumber, from, tx.To(), tx.GasPrice()); payer != from {
+		gas := new(big.Int).Add(sponsoredGas(tx), pool.sponsoredCost(payer, from, tx))
+		if pool.currentState.GetBalance(payer).Cmp(gas) < 0 {
+			return ErrSponsorFunds
+		}
+	}
 	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.homestead)
 	if err != nil {
 		return err
@@ -834,9 +990,24 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction, local
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
+	// Reject interactions with contracts blocked by the node policy
+	if to := tx.To(); to != nil {
+		if blocklist, _ := pool.blocklist.Load().(*Blocklist); blocklist.Blocked(*to, pool.currentState.GetCodeHash(*to)) {
+			return ErrBlockedContract
+		}
+	}
 	return nil
 }
 
+// txCost returns the funds the sender needs for a transaction: its value if the
+// gas is sponsored, or the value and the gas otherwise.
+func (pool *TxPool) txCost(from common.Address, tx *types.Transaction) *big.Int {
+	if pool.chainconfig.GasPayer(pool.nextNumber, from, tx.To(), tx.GasPrice()) != from {
+		return tx.Value()
+	}
+	return tx.Cost()
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
 // later pending promotion and execution. If the transaction is a replacement for
 // an already pending or queued one, it overwrites the previous and returns this
@@ -874,22 +1045,34 @@ func (pool *TxPool) add(ctx context.Context, tx *types.Transaction, local bool)
 	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		return false, ErrPoolLimit
 	}
-	// If the transaction is replacing an already pending one, do directly
+	// If the sender holds its quota of transactions, reject unless outbidding itself
 	from, _ := types.Sender(ctx, pool.signer, tx) // already validated
+	if err := pool.checkAccountQuota(from, tx); err != nil {
+		if log.Tracing() {
+			log.Trace("Discarding transaction over account quota", "hash", hash, "from", from)
+		}
+		return false, err
+	}
+	// If the transaction is replacing an already pending one, do directly
 	if pending := pool.pending[from]; pending != nil && pending.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
-		inserted, old := pending.Add(tx, pool.config.PriceBump)
-		if !inserted {
+		old, err := pending.Add(tx, pool.config.PriceBump, pool.replaceAtSamePrice(from, local))
+		if err != nil {
 			pendingDiscardCounter.Inc(1)
-			return false, ErrReplaceUnderpriced
+			return false, err
 		}
 		// New transaction is better, replace old one
 		if old != nil {
 			pool.all.Remove(old.Hash())
+			pool.untrackSponsored(old.Hash())
 			pendingReplaceCounter.Inc(1)
+			pool.txEvent(TxLifecycleEvent{Hash: old.Hash(), Kind: TxReplaced, ReplacedBy: hash})
 		}
 		pool.all.Add(tx)
+		pool.trackSponsored(from, tx)
+		pool.arrivals.ContainsOrAdd(hash, t)
 		pool.journalTx(from, tx)
+		pool.txEvent(TxLifecycleEvent{Hash: hash, Kind: TxPromoted})
 
 		if log.Tracing() {
 			log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -906,6 +1089,7 @@ func (pool *TxPool) add(ctx context.Context, tx *types.Transaction, local bool)
 	if err != nil {
 		return false, err
 	}
+	pool.arrivals.ContainsOrAdd(hash, t)
 	// Mark local addresses and journal local transactions
 	if local {
 		pool.locals.add(from)
@@ -932,26 +1116,41 @@ func (pool *TxPool) enqueueTx(ctx context.Context, tx *types.Transaction) (bool,
 	if pool.queue[from] == nil {
 		pool.queue[from] = newTxList(false)
 	}
-	inserted, old := pool.queue[from].Add(tx, pool.config.PriceBump)
-	if !inserted {
+	old, err := pool.queue[from].Add(tx, pool.config.PriceBump, pool.replaceAtSamePrice(from, false))
+	if err != nil {
 		// An older transaction was better, discard this
 		queuedDiscardCounter.Inc(1)
-		return false, ErrReplaceUnderpriced
+		return false, err
 	}
 	// Discard any previous transaction and mark this
 	if old != nil {
 		pool.all.Remove(old.Hash())
+		pool.untrackSponsored(old.Hash())
 		queuedReplaceCounter.Inc(1)
+		pool.txEvent(TxLifecycleEvent{Hash: old.Hash(), Kind: TxReplaced, ReplacedBy: tx.Hash()})
 	}
 	pool.all.Add(tx)
+	pool.trackSponsored(from, tx)
+	pool.txEvent(TxLifecycleEvent{Hash: tx.Hash(), Kind: TxQueued})
+
+	// Start the eviction clock of accounts first seen through the queue
+	if _, ok := pool.beats[from]; !ok {
+		pool.beats[from] = time.Now()
+	}
 	return old != nil, nil
 }
 
+// replaceAtSamePrice reports whether transactions from the given account may
+// replace pooled ones without raising the gas price.
+func (pool *TxPool) replaceAtSamePrice(from common.Address, local bool) bool {
+	return pool.config.LocalSamePriceReplace && (local || pool.locals.contains(from))
+}
+
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
-	if pool.journal == nil || !pool.locals.contains(from) {
+	// Only journal if it's enabled and the transaction is local, public and undated
+	if pool.journal == nil || !pool.locals.contains(from) || !pool.journaled(tx.Hash()) {
 		return
 	}
 
@@ -975,10 +1174,10 @@ func (pool *TxPool) promoteTx(ctx context.Context, addr common.Address, hash com
 	if pool.pending[addr] == nil {
 		pool.pending[addr] = newTxList(true)
 	}
-	inserted, old := pool.pending[addr].Add(tx, pool.config.PriceBump)
-	if !inserted {
+	old, err := pool.pending[addr].Add(tx, pool.config.PriceBump, pool.replaceAtSamePrice(addr, false))
+	if err != nil {
 		// An older transaction was better, discard this
-		pool.all.Remove(hash)
+		pool.dropTx(hash, DropReplacementUnderpriced)
 
 		pendingDiscardCounter.Inc(1)
 		return false
@@ -986,12 +1185,16 @@ func (pool *TxPool) promoteTx(ctx context.Context, addr common.Address, hash com
 	// Otherwise discard any previous transaction and mark this
 	if old != nil {
 		pool.all.Remove(old.Hash())
+		pool.untrackSponsored(old.Hash())
 
 		pendingReplaceCounter.Inc(1)
+		pool.txEvent(TxLifecycleEvent{Hash: old.Hash(), Kind: TxReplaced, ReplacedBy: hash})
 	}
+	pool.txEvent(TxLifecycleEvent{Hash: hash, Kind: TxPromoted})
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all.Get(hash) == nil {
 		pool.all.Add(tx)
+		pool.trackSponsored(addr, tx)
 	}
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.beats[addr] = time.Now()
@@ -1037,6 +1240,16 @@ func (pool *TxPool) addTx(ctx context.Context, tx *types.Transaction, local bool
 	if pool.all.Get(tx.Hash()) != nil {
 		return fmt.Errorf("known tx: %x", tx.Hash())
 	}
+	if pool.pastDeadline(tx.Hash()) {
+		return ErrTxDeadline
+	}
+	// Track the deadline before adding the transaction, so it is never journaled
+	if deadline, ok := ValidUntilFromContext(ctx); ok {
+		if err := pool.trackDeadline(tx.Hash(), deadline); err != nil {
+			return err
+		}
+		defer pool.untrackDeadline(tx.Hash())
+	}
 	// If the transaction fails basic validation, discard it.
 	if err := pool.preValidateTx(ctx, tx, local); err != nil {
 		if log.Tracing() {
@@ -1190,6 +1403,16 @@ func (pool *TxPool) Get(hash common.Hash) *types.Transaction {
 	return pool.all.Get(hash)
 }
 
+// ArrivalTime returns when the transaction was first added to the pool, if it
+// was recently enough to be remembered. Arrival times outlive the inclusion of
+// transactions in blocks, so that the order of the latter can be audited.
+func (pool *TxPool) ArrivalTime(hash common.Hash) (time.Time, bool) {
+	if t, ok := pool.arrivals.Get(hash); ok {
+		return t.(time.Time), true
+	}
+	return time.Time{}, false
+}
+
 // removeTx removes a single transaction from pending or queue, moving all subsequent
 // transactions back to the future queue.
 // The caller must hold pool.mu and pool.all.mu.
@@ -1198,6 +1421,7 @@ func (pool *TxPool) removeTx(ctx context.Context, tx *types.Transaction) {
 	defer span.End()
 
 	delete(pool.all.all, tx.Hash())
+	pool.untrackSponsored(tx.Hash())
 
 	addr, _ := types.Sender(ctx, pool.signer, tx) // already validated during insertion
 
@@ -1214,11 +1438,13 @@ func (pool *TxPool) removeTx(ctx context.Context, tx *types.Transaction) {
 			// If no more transactions are left, remove the list.
 			if pending.Empty() {
 				delete(pool.pending, addr)
-				delete(pool.beats, addr)
 			}
 			// If we created a new queue, but no txs were invalidated, then remove it.
 			if queue.Empty() {
 				delete(pool.queue, addr)
+				if pending.Empty() {
+					delete(pool.beats, addr)
+				}
 			}
 			// Update the account nonce, if necessary.
 			stNonce := pool.pendingState.GetNonce(addr)
@@ -1233,6 +1459,9 @@ func (pool *TxPool) removeTx(ctx context.Context, tx *types.Transaction) {
 		_ = queue.Remove(tx, func(tx *types.Transaction) {})
 		if queue.Empty() {
 			delete(pool.queue, addr)
+			if pool.pending[addr] == nil {
+				delete(pool.beats, addr)
+			}
 		}
 	}
 }
@@ -1272,12 +1501,12 @@ func (pool *TxPool) promoteExecutable(ctx context.Context, addr common.Address,
 	tracing := log.Tracing()
 	// Drop all transactions that are deemed too old (low nonce)
 	remove := func(tx *types.Transaction) {
-		pool.all.Remove(tx.Hash())
+		pool.retireTx(tx.Hash())
 	}
 	if tracing {
 		remove = func(tx *types.Transaction) {
 			hash := tx.Hash()
-			pool.all.Remove(hash)
+			pool.retireTx(hash)
 			log.Trace("Removed old queued transaction", "hash", hash)
 		}
 	}
@@ -1285,18 +1514,19 @@ func (pool *TxPool) promoteExecutable(ctx context.Context, addr common.Address,
 
 	// Drop all transactions that are too costly (low balance or out of gas)
 	remove = func(tx *types.Transaction) {
-		pool.all.Remove(tx.Hash())
+		pool.dropTx(tx.Hash(), DropInsufficientFunds)
 		queuedNofundsCounter.Inc(1)
 	}
 	if tracing {
 		remove = func(tx *types.Transaction) {
 			hash := tx.Hash()
-			pool.all.Remove(hash)
+			pool.dropTx(hash, DropInsufficientFunds)
 			queuedNofundsCounter.Inc(1)
 			log.Trace("Removed unpayable queued transaction", "hash", hash)
 		}
 	}
-	queued.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, remove, func(*types.Transaction) {})
+	cost := func(tx *types.Transaction) *big.Int { return pool.txCost(addr, tx) }
+	queued.Filter(pool.currentState.GetBalance(addr), pool.currentMaxGas, cost, remove, func(*types.Transaction) {})
 
 	// Gather all executable transactions and promote them
 	promote := func(tx *types.Transaction) {
@@ -1320,13 +1550,13 @@ func (pool *TxPool) promoteExecutable(ctx context.Context, addr common.Address,
 	// Drop all transactions over the allowed limit
 	if !pool.locals.contains(addr) {
 		remove := func(tx *types.Transaction) {
-			pool.all.Remove(tx.Hash())
+			pool.dropTx(tx.Hash(), DropAccountLimit)
 			queuedRateLimitCounter.Inc(1)
 		}
 		if tracing {
 			remove = func(tx *types.Transaction) {
 				hash := tx.Hash()
-				pool.all.Remove(hash)
+				pool.dropTx(hash, DropAccountLimit)
 				queuedRateLimitCounter.Inc(1)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
@@ -1337,6 +1567,9 @@ func (pool *TxPool) promoteExecutable(ctx context.Context, addr common.Address,
 	// Delete the entire queued entry if it became empty.
 	if queued.Empty() {
 		delete(pool.queue, addr)
+		if pool.pending[addr] == nil {
+			delete(pool.beats, addr)
+		}
 	}
 }
 
@@ -1411,16 +1644,19 @@ func (pool *TxPool) finishPromotion(ctx context.Context) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.txs.items {
-					pool.all.Remove(tx.Hash())
+					pool.dropTx(tx.Hash(), DropQueueLimit)
 				}
 				delete(pool.queue, addr.address)
+				if pool.pending[addr.address] == nil {
+					delete(pool.beats, addr.address)
+				}
 				drop -= size
 				queuedRateLimitCounter.Inc(int64(size))
 				continue
 			}
 			// Otherwise drop only last few transactions
 			list.ForLast(int(drop), func(tx *types.Transaction) {
-				pool.all.Remove(tx.Hash())
+				pool.dropTx(tx.Hash(), DropQueueLimit)
 				drop--
 				queuedRateLimitCounter.Inc(1)
 			})
@@ -1431,7 +1667,7 @@ func (pool *TxPool) finishPromotion(ctx context.Context) {
 func (pool *TxPool) limitOffenders(offenders []common.Address, tracing bool) (removed uint64) {
 	var nonce uint64
 	remove := func(tx *types.Transaction) {
-		pool.all.Remove(tx.Hash())
+		pool.dropTx(tx.Hash(), DropPendingLimit)
 		if tx.Nonce() < nonce {
 			nonce = tx.Nonce()
 		}
@@ -1439,7 +1675,7 @@ func (pool *TxPool) limitOffenders(offenders []common.Address, tracing bool) (re
 	if tracing {
 		remove = func(tx *types.Transaction) {
 			hash := tx.Hash()
-			pool.all.Remove(hash)
+			pool.dropTx(hash, DropPendingLimit)
 			if tx.Nonce() < nonce {
 				nonce = tx.Nonce()
 			}
@@ -1474,12 +1710,12 @@ func (pool *TxPool) demoteUnexecutables(ctx context.Context) {
 		// Drop all transactions that are deemed too old (low nonce)
 		tracing := log.Tracing()
 		remove := func(tx *types.Transaction) {
-			pool.all.Remove(tx.Hash())
+			pool.retireTx(tx.Hash())
 		}
 		if tracing {
 			remove = func(tx *types.Transaction) {
 				hash := tx.Hash()
-				pool.all.Remove(hash)
+				pool.retireTx(hash)
 				log.Trace("Removed old pending transaction", "hash", hash)
 			}
 		}
@@ -1488,7 +1724,7 @@ func (pool *TxPool) demoteUnexecutables(ctx context.Context) {
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
 		bal := pool.currentState.GetBalance(addr)
 		remove = func(tx *types.Transaction) {
-			pool.all.Remove(tx.Hash())
+			pool.dropTx(tx.Hash(), DropInsufficientFunds)
 			pendingNofundsCounter.Inc(1)
 		}
 		queue := pool.queue[addr]
@@ -1496,39 +1732,48 @@ func (pool *TxPool) demoteUnexecutables(ctx context.Context) {
 			queue = newTxList(false)
 			pool.queue[addr] = queue
 		}
-		invalid := queue.add
+		invalid := func(tx *types.Transaction) {
+			queue.add(tx)
+			pool.txEvent(TxLifecycleEvent{Hash: tx.Hash(), Kind: TxQueued})
+		}
 		if tracing {
 			remove = func(tx *types.Transaction) {
 				hash := tx.Hash()
-				pool.all.Remove(hash)
+				pool.dropTx(hash, DropInsufficientFunds)
 				pendingNofundsCounter.Inc(1)
 				log.Trace("Removed unpayable pending transaction", "hash", hash)
 			}
 			invalid = func(tx *types.Transaction) {
 				log.Trace("Demoting pending transaction", "hash", tx.Hash())
 				queue.add(tx)
+				pool.txEvent(TxLifecycleEvent{Hash: tx.Hash(), Kind: TxQueued})
 			}
 		}
-		pending.Filter(bal, pool.currentMaxGas, remove, invalid)
+		cost := func(tx *types.Transaction) *big.Int { return pool.txCost(addr, tx) }
+		pending.Filter(bal, pool.currentMaxGas, cost, remove, invalid)
 
 		// If there's a gap in front, warn (should never happen) and postpone all transactions
 		if pending.Len() > 0 && pending.txs.Get(nonce) == nil {
 			for _, tx := range pending.txs.items {
 				log.Error("Demoting invalidated transaction", "hash", tx.Hash())
 				queue.add(tx)
+				pool.txEvent(TxLifecycleEvent{Hash: tx.Hash(), Kind: TxQueued})
 			}
 			delete(pool.pending, addr)
-			delete(pool.beats, addr)
 			continue
 		}
 		if pending.Empty() {
 			delete(pool.pending, addr)
-			delete(pool.beats, addr)
 		}
 		if queue.Empty() {
 			delete(pool.queue, addr)
+			if pending.Empty() {
+				delete(pool.beats, addr)
+			}
 		}
 	}
+	// Demote the sponsored transactions their sponsor can't pay for anymore
+	pool.demoteUnsponsored()
 }
 
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
@@ -0,0 +1,170 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+	"github.com/hashicorp/golang-lru"
+)
+
+// ErrTxDeadline is returned if a transaction is submitted valid until a time
+// which already passed, or added again after being dropped past its deadline.
+var ErrTxDeadline = errors.New("transaction deadline passed")
+
+var deadlineDropCounter = metrics.NewCounter("txpool/deadline/dropped") // Dropped due to their deadline passing
+
+// txExpiredCacheSize is the number of transactions dropped past their deadline
+// which are remembered, refusing them if added again.
+const txExpiredCacheSize = 16384
+
+type validUntilKey struct{}
+
+// WithValidUntil returns a copy of ctx submitting transactions to the pool valid
+// until the given time only.
+func WithValidUntil(ctx context.Context, deadline time.Time) context.Context {
+	return context.WithValue(ctx, validUntilKey{}, deadline)
+}
+
+// ValidUntilFromContext returns the time until which the transactions submitted
+// with ctx are valid, if limited.
+func ValidUntilFromContext(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(validUntilKey{}).(time.Time)
+	return deadline, ok
+}
+
+// txDeadlines is the set of pooled transactions valid until a deadline: once it
+// passes they are dropped from the pool, and the miner skips them if the block
+// it builds is timestamped after it. They are never journaled, so a restart
+// can't revive them without their deadline.
+//
+// The deadline is a local convention peers don't know about, so the dated
+// transactions are not propagated either: peers would keep them past it. Once
+// dropped they are remembered for a while, so that a copy relayed back by a
+// peer which got it elsewhere can't revive them.
+type txDeadlines struct {
+	times   map[common.Hash]time.Time
+	expired *lru.Cache // Transactions dropped past their deadline
+	lock    sync.RWMutex
+}
+
+func newTxDeadlines() txDeadlines {
+	expired, _ := lru.New(txExpiredCacheSize)
+	return txDeadlines{times: make(map[common.Hash]time.Time), expired: expired}
+}
+
+// trackDeadline records the deadline of a transaction about to be added to the
+// pool, refusing it if it already passed.
+func (pool *TxPool) trackDeadline(hash common.Hash, deadline time.Time) error {
+	if !time.Now().Before(deadline) {
+		return ErrTxDeadline
+	}
+	pool.deadlines.lock.Lock()
+	defer pool.deadlines.lock.Unlock()
+
+	pool.deadlines.times[hash] = deadline
+	return nil
+}
+
+// pastDeadline reports whether a transaction was dropped from the pool as its
+// deadline passed, and is to be refused if added again.
+func (pool *TxPool) pastDeadline(hash common.Hash) bool {
+	return pool.deadlines.expired.Contains(hash)
+}
+
+// untrackDeadline forgets the deadline of a transaction which didn't make it
+// into the pool.
+func (pool *TxPool) untrackDeadline(hash common.Hash) {
+	if pool.all.Get(hash) != nil {
+		return
+	}
+	pool.deadlines.lock.Lock()
+	defer pool.deadlines.lock.Unlock()
+
+	delete(pool.deadlines.times, hash)
+}
+
+// Deadline returns the time until which a pooled transaction is valid, if it
+// was submitted with one.
+func (pool *TxPool) Deadline(hash common.Hash) (time.Time, bool) {
+	pool.deadlines.lock.RLock()
+	defer pool.deadlines.lock.RUnlock()
+
+	deadline, ok := pool.deadlines.times[hash]
+	return deadline, ok
+}
+
+// Expired reports whether the deadline of a pooled transaction passed by the
+// given time, e.g. that of the block it is to be included in.
+func (pool *TxPool) Expired(hash common.Hash, at time.Time) bool {
+	deadline, ok := pool.Deadline(hash)
+	return ok && !at.Before(deadline)
+}
+
+// expireDeadlines drops the transactions whose deadline passed, reporting them
+// to the lifecycle event subscribers. The later transactions of their senders
+// are moved back to the queue, waiting for the nonce to be filled again.
+func (pool *TxPool) expireDeadlines(ctx context.Context, now time.Time) {
+	var expired []common.Hash
+
+	pool.deadlines.lock.Lock()
+	for hash, deadline := range pool.deadlines.times {
+		if !now.Before(deadline) {
+			expired = append(expired, hash)
+			delete(pool.deadlines.times, hash)
+			pool.deadlines.expired.Add(hash, struct{}{})
+		}
+	}
+	pool.deadlines.lock.Unlock()
+
+	if len(expired) == 0 {
+		return
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for _, hash := range expired {
+		tx := pool.all.Get(hash)
+		if tx == nil {
+			continue
+		}
+		log.Debug("Dropping transaction past its deadline", "hash", hash)
+		pool.removeTx(ctx, tx)
+		pool.txEvent(TxLifecycleEvent{Hash: hash, Kind: TxDropped, Reason: DropDeadline})
+		deadlineDropCounter.Inc(1)
+	}
+}
+
+// pruneDeadlines forgets the deadlines of the transactions which left the pool.
+// Once included in a block a transaction is no longer tracked, so if the block
+// is reorged out it is reinjected without a deadline.
+func (pool *TxPool) pruneDeadlines() {
+	pool.deadlines.lock.Lock()
+	defer pool.deadlines.lock.Unlock()
+
+	for hash := range pool.deadlines.times {
+		if pool.all.Get(hash) == nil {
+			delete(pool.deadlines.times, hash)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+// Reasons reported for transactions dropped from the pool.
+const (
+	DropUnderpriced            = "underpriced"                // Below the pool's gas price threshold
+	DropReplacementUnderpriced = "replacement underpriced"    // Same nonce as a better priced pending transaction
+	DropNonceTooLow            = "nonce too low"              // Nonce already used on chain by another transaction
+	DropInsufficientFunds      = "insufficient funds"         // Sender can't pay for gas and value anymore
+	DropAccountLimit           = "account limit"              // Sender exceeded its queue allowance
+	DropPendingLimit           = "pending limit"              // Pool exceeded its executable slots
+	DropQueueLimit             = "queue limit"                // Pool exceeded its non-executable slots
+	DropExpired                = "expired"                    // Queued for longer than the pool lifetime
+	DropDeadline               = "deadline passed"            // Not included before the deadline it was submitted with
+	DropSponsorFunds           = "insufficient sponsor funds" // Gas sponsor can't pay for all its pending transactions anymore
+)
+
+// txEventChanSize is the size of the buffer between the pool and the lifecycle
+// event subscribers.
+const txEventChanSize = 4096
+
+var txEventDropMeter = metrics.NewMeter("txpool/events/dropped") // Lifecycle events lost to slow subscribers
+
+// SubscribeTxLifecycleEvent registers a subscription of TxLifecycleEvent.
+// Events are delivered on a best effort basis: if subscribers fall behind,
+// events are dropped rather than stalling the pool.
+func (pool *TxPool) SubscribeTxLifecycleEvent(ch chan<- TxLifecycleEvent) event.Subscription {
+	return pool.txEventScope.Track(pool.txEventFeed.Subscribe(ch))
+}
+
+// txEvent queues a lifecycle event for delivery, if anyone is subscribed.
+func (pool *TxPool) txEvent(ev TxLifecycleEvent) {
+	if pool.txEventScope.Count() == 0 {
+		return
+	}
+	select {
+	case pool.txEventBuf <- ev:
+	default:
+		txEventDropMeter.Mark(1)
+	}
+}
+
+// dropTx removes a transaction from the lookup and reports it dropped.
+//
+// Caller must hold pool.mu.
+func (pool *TxPool) dropTx(hash common.Hash, reason string) {
+	pool.all.Remove(hash)
+	pool.untrackSponsored(hash)
+	pool.txEvent(TxLifecycleEvent{Hash: hash, Kind: TxDropped, Reason: reason})
+}
+
+// retireTx removes a transaction whose nonce has been used up on chain, and
+// reports it included if it was mined in the blocks the pool was reset to, or
+// dropped otherwise.
+//
+// Caller must hold pool.mu.
+func (pool *TxPool) retireTx(hash common.Hash) {
+	pool.all.Remove(hash)
+	pool.untrackSponsored(hash)
+	if block, ok := pool.mined[hash]; ok {
+		pool.txEvent(TxLifecycleEvent{Hash: hash, Kind: TxIncluded, BlockHash: block.Hash(), BlockNumber: block.NumberU64()})
+		return
+	}
+	pool.txEvent(TxLifecycleEvent{Hash: hash, Kind: TxDropped, Reason: DropNonceTooLow})
+}
+
+// minedTxs indexes the transactions included in the chain from newBlock back
+// to oldBlock, at most maxReorgDepth blocks deep. It returns nil if no one is
+// interested in lifecycle events.
+func (pool *TxPool) minedTxs(oldBlock, newBlock *types.Block) map[common.Hash]*types.Block {
+	if pool.txEventScope.Count() == 0 || newBlock == nil {
+		return nil
+	}
+	mined := make(map[common.Hash]*types.Block)
+	for block, depth := newBlock, 0; block != nil && depth < maxReorgDepth; depth++ {
+		if oldBlock != nil && block.Hash() == oldBlock.Hash() {
+			break
+		}
+		for _, tx := range block.Transactions() {
+			mined[tx.Hash()] = block
+		}
+		if block.NumberU64() == 0 {
+			break
+		}
+		block = pool.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	return mined
+}
+
+// txEventLoop delivers queued lifecycle events to the subscribers.
+func (pool *TxPool) txEventLoop() {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		case <-pool.chainHeadSub.Err():
+			return
+		case ev := <-pool.txEventBuf:
+			pool.txEventFeed.Send(ev)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/crypto"
+)
+
+// Tests that the transaction pool reports the lifecycle transitions of its
+// transactions to the lifecycle event subscribers.
+func TestTransactionLifecycleEvents(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	pool, key := setupTxPool(ctx)
+	defer pool.Stop()
+
+	events := make(chan TxLifecycleEvent, 32)
+	sub := pool.SubscribeTxLifecycleEvent(events)
+	defer sub.Unsubscribe()
+
+	expect := func(want ...TxLifecycleEvent) {
+		t.Helper()
+		for i, exp := range want {
+			select {
+			case have := <-events:
+				if have != exp {
+					t.Fatalf("event %d mismatch: have %+v, want %+v", i, have, exp)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("timed out waiting for event %d: %+v", i, exp)
+			}
+		}
+		select {
+		case have := <-events:
+			t.Fatalf("unexpected event: %+v", have)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	pool.mu.Lock()
+	pool.currentState.AddBalance(addr, big.NewInt(1000000))
+	pool.mu.Unlock()
+
+	// A gapped transaction is queued, and promoted once the gap is filled
+	tx1 := transaction(1, 100000, key)
+	if err := pool.AddRemote(ctx, tx1); err != nil {
+		t.Fatalf("failed to add gapped transaction: %v", err)
+	}
+	expect(TxLifecycleEvent{Hash: tx1.Hash(), Kind: TxQueued})
+
+	tx0 := transaction(0, 100000, key)
+	if err := pool.AddRemote(ctx, tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	expect(
+		TxLifecycleEvent{Hash: tx0.Hash(), Kind: TxQueued},
+		TxLifecycleEvent{Hash: tx0.Hash(), Kind: TxPromoted},
+		TxLifecycleEvent{Hash: tx1.Hash(), Kind: TxPromoted},
+	)
+	// A pending transaction is replaced by a better priced one
+	bump := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.AddRemote(ctx, bump); err != nil {
+		t.Fatalf("failed to add replacement: %v", err)
+	}
+	expect(
+		TxLifecycleEvent{Hash: tx0.Hash(), Kind: TxReplaced, ReplacedBy: bump.Hash()},
+		TxLifecycleEvent{Hash: bump.Hash(), Kind: TxPromoted},
+	)
+	// Raising the price threshold drops the cheaper remote transaction
+	pool.SetGasPrice(ctx, big.NewInt(2))
+	expect(TxLifecycleEvent{Hash: tx1.Hash(), Kind: TxDropped, Reason: DropUnderpriced})
+	// Using up the nonce on chain without the transaction drops it
+	pool.mu.Lock()
+	pool.currentState.SetNonce(addr, 1)
+	pool.reset(ctx, nil, nil)
+	pool.mu.Unlock()
+
+	expect(TxLifecycleEvent{Hash: bump.Hash(), Kind: TxDropped, Reason: DropNonceTooLow})
+}
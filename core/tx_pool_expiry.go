@@ -0,0 +1,208 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+)
+
+// LifetimeTier overrides the maximum time the non-executable transactions of a
+// group of accounts are queued. Tiers apply to local accounts too, which
+// otherwise never expire: a local account in a tier with a lifetime has its
+// queued transactions dropped like a remote one.
+type LifetimeTier struct {
+	Name     string
+	Accounts []common.Address
+	Lifetime time.Duration `toml:",omitempty"` // 0 for never expiring
+}
+
+// ParseLifetimeTiers parses a comma separated list of name=lifetime:accounts
+// tiers, the accounts separated by '+', e.g. "partners=24h:0x1f..+0x2e..,bots=5m:0x3d..".
+func ParseLifetimeTiers(spec string) ([]LifetimeTier, error) {
+	var (
+		tiers []LifetimeTier
+		names = make(map[string]bool)
+		seen  = make(map[common.Address]string)
+	)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid lifetime tier %q, want name=lifetime:accounts", field)
+		}
+		tier := LifetimeTier{Name: strings.TrimSpace(parts[0])}
+		if names[tier.Name] {
+			return nil, fmt.Errorf("duplicate lifetime tier %s", tier.Name)
+		}
+		names[tier.Name] = true
+
+		rule := strings.SplitN(parts[1], ":", 2)
+		if len(rule) != 2 {
+			return nil, fmt.Errorf("invalid lifetime tier %q, want name=lifetime:accounts", field)
+		}
+		lifetime, err := time.ParseDuration(strings.TrimSpace(rule[0]))
+		if err != nil || lifetime < 0 {
+			return nil, fmt.Errorf("invalid lifetime for tier %s: %q", tier.Name, rule[0])
+		}
+		tier.Lifetime = lifetime
+
+		for _, account := range strings.Split(rule[1], "+") {
+			account = strings.TrimSpace(account)
+			if !common.IsHexAddress(account) {
+				return nil, fmt.Errorf("invalid account in tier %s: %q", tier.Name, account)
+			}
+			addr := common.HexToAddress(account)
+			if other, ok := seen[addr]; ok {
+				return nil, fmt.Errorf("account %s in tiers %s and %s", addr.Hex(), other, tier.Name)
+			}
+			seen[addr] = tier.Name
+			tier.Accounts = append(tier.Accounts, addr)
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
+
+// newLifetimeTiers indexes the lifetime tiers by account.
+func newLifetimeTiers(tiers []LifetimeTier) map[common.Address]*LifetimeTier {
+	index := make(map[common.Address]*LifetimeTier)
+	for i := range tiers {
+		for _, addr := range tiers[i].Accounts {
+			index[addr] = &tiers[i]
+		}
+	}
+	return index
+}
+
+// queueLifetime returns the tier of an account, if any, and how long its
+// non-executable transactions are queued, 0 for ever. Tiers take precedence,
+// otherwise local accounts never expire.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) queueLifetime(addr common.Address) (string, time.Duration) {
+	if tier := pool.tiers[addr]; tier != nil {
+		return tier.Name, tier.Lifetime
+	}
+	if pool.locals.contains(addr) {
+		return "", 0
+	}
+	return "", pool.config.Lifetime
+}
+
+// TxPoolAccount is the state of the pooled transactions of an account, pointing
+// out why some of them are stuck.
+type TxPoolAccount struct {
+	Address   common.Address     `json:"address"`
+	Nonce     uint64             `json:"nonce"`     // Nonce of the account in the current state
+	NextNonce uint64             `json:"nextNonce"` // Nonce following the executable transactions
+	Local     bool               `json:"local"`
+	Pending   []*TxPoolAccountTx `json:"pending"`  // Executable transactions
+	Queued    []*TxPoolAccountTx `json:"queued"`   // Non-executable transactions
+	Gaps      []*NonceGap        `json:"gaps"`     // Missing nonces keeping the queued transactions from executing
+	Blocking  []*TxPoolAccountTx `json:"blocking"` // Executable transactions priced below the pool minimum, holding back later nonces
+
+	Tier      string     `json:"tier,omitempty"`
+	Lifetime  string     `json:"lifetime"`            // Time queued transactions are kept without activity, "never" if they don't expire
+	Expiry    *time.Time `json:"expiry,omitempty"`    // Time the queued transactions are dropped, nil if none or never
+	ExpiresIn string     `json:"expiresIn,omitempty"` // Time left before the queued transactions are dropped
+}
+
+// TxPoolAccountTx is a pooled transaction of an account.
+type TxPoolAccountTx struct {
+	Hash     common.Hash `json:"hash"`
+	Nonce    uint64      `json:"nonce"`
+	GasPrice *big.Int    `json:"gasPrice"`
+	Gas      uint64      `json:"gas"`
+}
+
+// NonceGap is a range of nonces missing from an account's transactions, along
+// with the number of queued transactions waiting on it.
+type NonceGap struct {
+	From    uint64 `json:"from"`
+	To      uint64 `json:"to"`
+	Blocked int    `json:"blocked"`
+}
+
+func newTxPoolAccountTx(tx *types.Transaction) *TxPoolAccountTx {
+	return &TxPoolAccountTx{Hash: tx.Hash(), Nonce: tx.Nonce(), GasPrice: tx.GasPrice(), Gas: tx.Gas()}
+}
+
+// InspectAccount reports the pooled transactions of an account, the nonce gaps
+// and underpriced transactions keeping them stuck, and when the queued ones
+// expire.
+func (pool *TxPool) InspectAccount(addr common.Address) *TxPoolAccount {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	account := &TxPoolAccount{
+		Address:  addr,
+		Nonce:    pool.currentState.GetNonce(addr),
+		Local:    pool.locals.contains(addr),
+		Pending:  []*TxPoolAccountTx{},
+		Queued:   []*TxPoolAccountTx{},
+		Gaps:     []*NonceGap{},
+		Blocking: []*TxPoolAccountTx{},
+	}
+	account.NextNonce = account.Nonce
+	if list := pool.pending[addr]; list != nil {
+		for _, tx := range list.Flatten() {
+			account.Pending = append(account.Pending, newTxPoolAccountTx(tx))
+			if tx.GasPrice().Cmp(pool.gasPrice) < 0 {
+				account.Blocking = append(account.Blocking, newTxPoolAccountTx(tx))
+			}
+			if tx.Nonce() >= account.NextNonce {
+				account.NextNonce = tx.Nonce() + 1
+			}
+		}
+	}
+	if list := pool.queue[addr]; list != nil {
+		queued := list.Flatten()
+		next := account.NextNonce
+		for i, tx := range queued {
+			account.Queued = append(account.Queued, newTxPoolAccountTx(tx))
+			if tx.Nonce() > next {
+				account.Gaps = append(account.Gaps, &NonceGap{From: next, To: tx.Nonce() - 1, Blocked: len(queued) - i})
+			}
+			if tx.Nonce() >= next {
+				next = tx.Nonce() + 1
+			}
+		}
+	}
+	tier, lifetime := pool.queueLifetime(addr)
+	account.Tier, account.Lifetime = tier, "never"
+	if lifetime > 0 {
+		account.Lifetime = lifetime.String()
+		if beat, ok := pool.beats[addr]; ok && len(account.Queued) > 0 {
+			expiry := beat.Add(lifetime)
+			left := time.Until(expiry)
+			if left < 0 {
+				left = 0
+			}
+			account.Expiry, account.ExpiresIn = &expiry, left.Round(time.Second).String()
+		}
+	}
+	return account
+}
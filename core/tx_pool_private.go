@@ -0,0 +1,80 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+)
+
+// privateTxs is the set of pooled transactions submitted privately: they are
+// never broadcast nor journaled, so only the local miner can include them.
+type privateTxs struct {
+	hashes map[common.Hash]struct{}
+	lock   sync.RWMutex
+}
+
+// AddPrivate enqueues a single local transaction into the pool if it is valid,
+// marking it private so it is kept out of the network and the journal. Once
+// included in a block the transaction is public, so if the block is reorged out
+// it is reinjected as a regular transaction.
+func (pool *TxPool) AddPrivate(ctx context.Context, tx *types.Transaction) error {
+	hash := tx.Hash()
+	if pool.all.Get(hash) != nil {
+		return fmt.Errorf("known tx: %x", hash)
+	}
+	// Mark the transaction before adding it, so it is never seen public
+	pool.private.lock.Lock()
+	pool.private.hashes[hash] = struct{}{}
+	pool.private.lock.Unlock()
+
+	if err := pool.addTx(ctx, tx, true); err != nil {
+		pool.private.lock.Lock()
+		delete(pool.private.hashes, hash)
+		pool.private.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// IsPrivate reports whether a pooled transaction was submitted privately and
+// must not be propagated.
+func (pool *TxPool) IsPrivate(hash common.Hash) bool {
+	pool.private.lock.RLock()
+	defer pool.private.lock.RUnlock()
+
+	_, ok := pool.private.hashes[hash]
+	return ok
+}
+
+// prunePrivate forgets the private transactions which left the pool.
+//
+// Caller must hold pool.mu.
+func (pool *TxPool) prunePrivate() {
+	pool.private.lock.Lock()
+	defer pool.private.lock.Unlock()
+
+	for hash := range pool.private.hashes {
+		if pool.all.Get(hash) == nil {
+			delete(pool.private.hashes, hash)
+		}
+	}
+}
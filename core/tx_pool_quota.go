@@ -0,0 +1,92 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+// ErrAccountQuota is returned if a sender holding as many pooled
+// transactions as the per-account quota allows submits another one, without
+// paying the gas price overriding the quota.
+var ErrAccountQuota error = &accountQuotaError{}
+
+type accountQuotaError struct{}
+
+func (e *accountQuotaError) Error() string { return "account quota exceeded" }
+
+// ErrorCode returns the JSON-RPC error code of quota rejections, telling them
+// apart from the pool being full.
+func (e *accountQuotaError) ErrorCode() int { return 4453 }
+
+var (
+	quotaRejectCounter   = metrics.NewCounter("txpool/quota/rejected")   // Refused as the sender holds its quota
+	quotaOverrideCounter = metrics.NewCounter("txpool/quota/overridden") // Admitted over the quota by outbidding the sender
+)
+
+// checkAccountQuota refuses a new transaction if its sender already holds
+// AccountQuota pending and queued transactions, unless it outbids the sender's
+// highest priced pooled transaction by AccountQuotaBump percent. As each
+// admitted transaction raises the bar, every transaction over the quota costs
+// more than the previous one. Replacements are not counted as new.
+//
+// The quota applies to transactions submitted through the RPC API as well, even
+// though they are local: only those loaded from the journal are exempt, as they
+// were already admitted before the restart.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) checkAccountQuota(from common.Address, tx *types.Transaction) error {
+	quota := pool.config.AccountQuota
+	if quota == 0 || pool.replaying {
+		return nil
+	}
+	var (
+		held    uint64
+		highest = new(big.Int)
+	)
+	for _, list := range []*txList{pool.pending[from], pool.queue[from]} {
+		if list == nil {
+			continue
+		}
+		if list.Overlaps(tx) {
+			return nil
+		}
+		for _, pooled := range list.txs.items {
+			held++
+			if pooled.GasPrice().Cmp(highest) > 0 {
+				highest.Set(pooled.GasPrice())
+			}
+		}
+	}
+	if held < quota {
+		return nil
+	}
+	if bump := pool.config.AccountQuotaBump; bump > 0 {
+		threshold := new(big.Int).Mul(highest, new(big.Int).SetUint64(100+bump))
+		threshold.Div(threshold, big.NewInt(100))
+		if tx.GasPrice().Cmp(threshold) >= 0 {
+			quotaOverrideCounter.Inc(1)
+			return nil
+		}
+	}
+	quotaRejectCounter.Inc(1)
+	return ErrAccountQuota
+}
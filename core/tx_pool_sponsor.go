@@ -0,0 +1,194 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+var sponsorDropCounter = metrics.NewCounter("txpool/sponsor/dropped") // Dropped as their sponsor can't pay for them all
+
+// sponsoredGas returns the gas a sponsor pays for a transaction up front.
+func sponsoredGas(tx *types.Transaction) *big.Int {
+	return new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+}
+
+// sponsoredTx is the payer of a pooled sponsored transaction and the gas it
+// pays for it.
+type sponsoredTx struct {
+	payer common.Address
+	gas   *big.Int
+}
+
+// txSponsors keeps a running total of the gas each sponsor pays for the pooled
+// transactions, sparing validation a walk over the whole pool.
+type txSponsors struct {
+	txs    map[common.Hash]sponsoredTx // Sponsored transactions in the pool
+	totals map[common.Address]*big.Int // Gas each sponsor pays for them all
+}
+
+func newTxSponsors() txSponsors {
+	return txSponsors{
+		txs:    make(map[common.Hash]sponsoredTx),
+		totals: make(map[common.Address]*big.Int),
+	}
+}
+
+// trackSponsored adds the gas of a transaction entering the pool to the total
+// of its sponsor, if any.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) trackSponsored(from common.Address, tx *types.Transaction) {
+	payer := pool.chainconfig.GasPayer(pool.nextNumber, from, tx.To(), tx.GasPrice())
+	if payer == from {
+		return
+	}
+	pool.untrackSponsored(tx.Hash())
+
+	gas := sponsoredGas(tx)
+	pool.sponsors.txs[tx.Hash()] = sponsoredTx{payer: payer, gas: gas}
+	if total, ok := pool.sponsors.totals[payer]; ok {
+		total.Add(total, gas)
+	} else {
+		pool.sponsors.totals[payer] = new(big.Int).Set(gas)
+	}
+}
+
+// untrackSponsored subtracts the gas of a transaction leaving the pool from the
+// total of its sponsor, if any.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) untrackSponsored(hash common.Hash) {
+	sponsored, ok := pool.sponsors.txs[hash]
+	if !ok {
+		return
+	}
+	delete(pool.sponsors.txs, hash)
+
+	total := pool.sponsors.totals[sponsored.payer]
+	if total.Sub(total, sponsored.gas); total.Sign() <= 0 {
+		delete(pool.sponsors.totals, sponsored.payer)
+	}
+}
+
+// resetSponsored recomputes the sponsor totals of the pooled transactions, as
+// which of them are sponsored depends on the number of the next block.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) resetSponsored() {
+	pool.sponsors = newTxSponsors()
+	for _, accounts := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range accounts {
+			for _, tx := range list.txs.items {
+				pool.trackSponsored(addr, tx)
+			}
+		}
+	}
+}
+
+// sponsoredCost returns the gas the payer sponsors for the pooled transactions,
+// but for the one the transaction from the sender would replace.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) sponsoredCost(payer common.Address, from common.Address, tx *types.Transaction) *big.Int {
+	cost := new(big.Int)
+	if total, ok := pool.sponsors.totals[payer]; ok {
+		cost.Set(total)
+	}
+	for _, accounts := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		if list := accounts[from]; list != nil {
+			if old := list.txs.Get(tx.Nonce()); old != nil {
+				if sponsored, ok := pool.sponsors.txs[old.Hash()]; ok && sponsored.payer == payer {
+					cost.Sub(cost, sponsored.gas)
+				}
+			}
+		}
+	}
+	return cost
+}
+
+// demoteUnsponsored drops the pending sponsored transactions their sponsor can't
+// pay for all together anymore, demoting the later transactions of their senders
+// back to the queue. Senders are served in address order, keeping the outcome
+// independent of map iteration.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) demoteUnsponsored() {
+	addrs := make([]common.Address, 0, len(pool.pending))
+	for addr := range pool.pending {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	budgets := make(map[common.Address]*big.Int)
+	for _, addr := range addrs {
+		pending := pool.pending[addr]
+
+		var unpaid *types.Transaction
+		for _, tx := range pending.Flatten() {
+			payer := pool.chainconfig.GasPayer(pool.nextNumber, addr, tx.To(), tx.GasPrice())
+			if payer == addr {
+				continue
+			}
+			budget, ok := budgets[payer]
+			if !ok {
+				budget = new(big.Int).Set(pool.currentState.GetBalance(payer))
+				budgets[payer] = budget
+			}
+			gas := sponsoredGas(tx)
+			if budget.Cmp(gas) < 0 {
+				unpaid = tx
+				break
+			}
+			budget.Sub(budget, gas)
+		}
+		if unpaid == nil {
+			continue
+		}
+		queue := pool.queue[addr]
+		if queue == nil {
+			queue = newTxList(false)
+			pool.queue[addr] = queue
+		}
+		unsponsored := func(tx *types.Transaction) bool { return tx.Nonce() == unpaid.Nonce() }
+		remove := func(tx *types.Transaction) {
+			pool.dropTx(tx.Hash(), DropSponsorFunds)
+			sponsorDropCounter.Inc(1)
+		}
+		invalid := func(tx *types.Transaction) {
+			queue.add(tx)
+			pool.txEvent(TxLifecycleEvent{Hash: tx.Hash(), Kind: TxQueued})
+		}
+		pending.txs.Filter(unsponsored, pending.strict, remove, invalid)
+
+		if pending.Empty() {
+			delete(pool.pending, addr)
+		}
+		if queue.Empty() {
+			delete(pool.queue, addr)
+			if pending.Empty() {
+				delete(pool.beats, addr)
+			}
+		}
+	}
+}
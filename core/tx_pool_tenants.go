@@ -0,0 +1,237 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+)
+
+// ErrTenantQuota is returned if a tenant of the RPC API submits a transaction
+// while holding as many pooled transactions as its quota allows.
+var ErrTenantQuota = errors.New("tenant transaction quota exceeded")
+
+// TenantQuota limits the transactions a tenant of the RPC API may keep in the
+// pool, and weighs their priority for inclusion by the miner.
+type TenantQuota struct {
+	Name   string
+	Slots  uint64 `toml:",omitempty"` // Maximum number of pooled transactions, 0 for no limit
+	Weight uint64 `toml:",omitempty"` // Multiplier of the gas price when ordering for inclusion, 0 for 1
+}
+
+// TenantStatus is the pool usage of a tenant.
+type TenantStatus struct {
+	Name   string `json:"name"`
+	Txs    int    `json:"txs"`
+	Slots  uint64 `json:"slots"`
+	Weight uint64 `json:"weight"`
+}
+
+// ParseTenantQuotas parses a comma separated list of name=slots[:weight] quotas,
+// e.g. "alice=64:2,bob=16".
+func ParseTenantQuotas(spec string) ([]TenantQuota, error) {
+	var quotas []TenantQuota
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid tenant quota %q, want name=slots[:weight]", field)
+		}
+		quota := TenantQuota{Name: strings.TrimSpace(parts[0])}
+		if seen[quota.Name] {
+			return nil, fmt.Errorf("duplicate quota for tenant %s", quota.Name)
+		}
+		seen[quota.Name] = true
+
+		limits := strings.SplitN(parts[1], ":", 2)
+		slots, err := strconv.ParseUint(strings.TrimSpace(limits[0]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slots for tenant %s: %v", quota.Name, err)
+		}
+		quota.Slots = slots
+		if len(limits) == 2 {
+			weight, err := strconv.ParseUint(strings.TrimSpace(limits[1]), 10, 64)
+			if err != nil || weight == 0 {
+				return nil, fmt.Errorf("invalid weight for tenant %s: %q", quota.Name, limits[1])
+			}
+			quota.Weight = weight
+		}
+		quotas = append(quotas, quota)
+	}
+	return quotas, nil
+}
+
+// tenantTxs is the set of pooled transactions submitted on behalf of tenants of
+// the RPC API, along with their quotas.
+type tenantTxs struct {
+	quotas map[string]TenantQuota
+	hashes map[common.Hash]string // Tenant of each transaction
+	counts map[string]int         // Number of transactions of each tenant
+	lock   sync.RWMutex
+}
+
+func newTenantTxs(quotas []TenantQuota) *tenantTxs {
+	t := &tenantTxs{
+		quotas: make(map[string]TenantQuota, len(quotas)),
+		hashes: make(map[common.Hash]string),
+		counts: make(map[string]int),
+	}
+	for _, quota := range quotas {
+		t.quotas[quota.Name] = quota
+	}
+	return t
+}
+
+// AddTenant enqueues a single local transaction submitted on behalf of a tenant
+// of the RPC API into the pool if it is valid, and the tenant holds fewer pooled
+// transactions than its quota allows.
+func (pool *TxPool) AddTenant(ctx context.Context, tenant string, tx *types.Transaction) error {
+	hash := tx.Hash()
+	if pool.all.Get(hash) != nil {
+		return fmt.Errorf("known tx: %x", hash)
+	}
+	// Reserve a slot before adding the transaction, so concurrent submissions
+	// can't exceed the quota
+	pool.tenants.lock.Lock()
+	if slots := pool.tenants.quotas[tenant].Slots; slots > 0 && uint64(pool.tenants.counts[tenant]) >= slots {
+		pool.pruneTenant(tenant)
+		if uint64(pool.tenants.counts[tenant]) >= slots {
+			pool.tenants.lock.Unlock()
+			tenantQuotaCounter.Inc(1)
+			return ErrTenantQuota
+		}
+	}
+	pool.tenants.hashes[hash] = tenant
+	pool.tenants.counts[tenant]++
+	pool.tenants.lock.Unlock()
+
+	if err := pool.addTx(ctx, tx, !pool.config.NoLocals); err != nil {
+		pool.tenants.lock.Lock()
+		pool.untrackTenant(hash)
+		pool.tenants.lock.Unlock()
+		return err
+	}
+	return nil
+}
+
+// TenantWeights reports whether any tenant has its transactions weighted for
+// inclusion.
+func (pool *TxPool) TenantWeights() bool {
+	pool.tenants.lock.RLock()
+	defer pool.tenants.lock.RUnlock()
+
+	for _, quota := range pool.tenants.quotas {
+		if quota.Weight > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// TxWeight returns the multiplier of the gas price of a pooled transaction when
+// ordering it for inclusion, that of its tenant or 1.
+func (pool *TxPool) TxWeight(hash common.Hash) uint64 {
+	pool.tenants.lock.RLock()
+	defer pool.tenants.lock.RUnlock()
+
+	tenant, ok := pool.tenants.hashes[hash]
+	if !ok {
+		return 1
+	}
+	if weight := pool.tenants.quotas[tenant].Weight; weight > 1 {
+		return weight
+	}
+	return 1
+}
+
+// Tenants returns the pool usage of the tenants with a quota or transactions,
+// sorted by name.
+func (pool *TxPool) Tenants() []TenantStatus {
+	pool.pruneTenants()
+
+	pool.tenants.lock.RLock()
+	defer pool.tenants.lock.RUnlock()
+
+	status := make(map[string]*TenantStatus)
+	for name, quota := range pool.tenants.quotas {
+		status[name] = &TenantStatus{Name: name, Slots: quota.Slots, Weight: quota.Weight}
+	}
+	for name, count := range pool.tenants.counts {
+		if status[name] == nil {
+			status[name] = &TenantStatus{Name: name}
+		}
+		status[name].Txs = count
+	}
+	result := make([]TenantStatus, 0, len(status))
+	for _, s := range status {
+		if s.Weight == 0 {
+			s.Weight = 1
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// pruneTenant forgets the transactions of a tenant which left the pool.
+//
+// Caller must hold pool.tenants.lock.
+func (pool *TxPool) pruneTenant(tenant string) {
+	for hash, owner := range pool.tenants.hashes {
+		if owner == tenant && pool.all.Get(hash) == nil {
+			pool.untrackTenant(hash)
+		}
+	}
+}
+
+// pruneTenants forgets the tenant transactions which left the pool.
+func (pool *TxPool) pruneTenants() {
+	pool.tenants.lock.Lock()
+	defer pool.tenants.lock.Unlock()
+
+	for hash := range pool.tenants.hashes {
+		if pool.all.Get(hash) == nil {
+			pool.untrackTenant(hash)
+		}
+	}
+}
+
+// untrackTenant forgets the tenant of a transaction.
+//
+// Caller must hold pool.tenants.lock.
+func (pool *TxPool) untrackTenant(hash common.Hash) {
+	tenant, ok := pool.tenants.hashes[hash]
+	if !ok {
+		return
+	}
+	delete(pool.tenants.hashes, hash)
+	if pool.tenants.counts[tenant]--; pool.tenants.counts[tenant] <= 0 {
+		delete(pool.tenants.counts, tenant)
+	}
+}
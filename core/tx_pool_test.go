@@ -24,6 +24,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -141,6 +142,28 @@ func validateTxPoolInternals(pool *TxPool) error {
 			return fmt.Errorf("pending nonce mismatch: have %v, want %v", nonce, last+1)
 		}
 	}
+	// Ensure the running sponsor totals match the pooled transactions
+	totals := make(map[common.Address]*big.Int)
+	for _, accounts := range []map[common.Address]*txList{pool.pending, pool.queue} {
+		for addr, list := range accounts {
+			for _, tx := range list.txs.items {
+				if payer := pool.chainconfig.GasPayer(pool.nextNumber, addr, tx.To(), tx.GasPrice()); payer != addr {
+					if totals[payer] == nil {
+						totals[payer] = new(big.Int)
+					}
+					totals[payer].Add(totals[payer], sponsoredGas(tx))
+				}
+			}
+		}
+	}
+	if len(totals) != len(pool.sponsors.totals) {
+		return fmt.Errorf("sponsor count mismatch: have %d, want %d", len(pool.sponsors.totals), len(totals))
+	}
+	for payer, total := range totals {
+		if have := pool.sponsors.totals[payer]; have == nil || have.Cmp(total) != 0 {
+			return fmt.Errorf("sponsored gas mismatch for %x: have %v, want %v", payer, have, total)
+		}
+	}
 	return nil
 }
 
@@ -256,6 +279,198 @@ func TestStateChangeDuringTransactionPoolReset(t *testing.T) {
 	}
 }
 
+// Tests that transactions to blocklisted contracts are rejected, matching both
+// their address and the hash of their code.
+func TestTransactionBlocklist(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	pool, key := setupTxPool(ctx)
+	defer pool.Stop()
+
+	var (
+		blocked   = common.Address{0x01}
+		copied    = common.Address{0x02}
+		unrelated = common.Address{0x03}
+		code      = []byte{0x60, 0x00}
+	)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.mu.Lock()
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+	pool.currentState.SetCode(copied, code)
+	pool.mu.Unlock()
+
+	pool.SetBlocklist(NewBlocklist(BlocklistConfig{
+		Addresses:  []common.Address{blocked},
+		CodeHashes: []common.Hash{crypto.Keccak256Hash(code)},
+	}))
+	for nonce, to := range []common.Address{blocked, copied} {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(nonce), to, big.NewInt(1), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+		if err := pool.AddRemote(ctx, tx); err != ErrBlockedContract {
+			t.Errorf("transaction to %x: error mismatch: have %v, want %v", to, err, ErrBlockedContract)
+		}
+	}
+	tx, _ := types.SignTx(types.NewTransaction(0, unrelated, big.NewInt(1), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err := pool.AddRemote(ctx, tx); err != nil {
+		t.Errorf("transaction to unrelated contract rejected: %v", err)
+	}
+}
+
+func TestTransactionPrivate(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	pool, key := setupTxPool(ctx)
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.mu.Lock()
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+	pool.mu.Unlock()
+
+	private, public := transaction(0, 100000, key), transaction(1, 100000, key)
+	if err := pool.AddPrivate(ctx, private); err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if err := pool.AddLocal(ctx, public); err != nil {
+		t.Fatalf("failed to add public transaction: %v", err)
+	}
+	if err := pool.AddPrivate(ctx, private); err == nil {
+		t.Errorf("known private transaction added twice")
+	}
+	// Both are executable for the local miner, only the public one is journaled
+	if pending := pool.Pending(ctx)[from]; len(pending) != 2 {
+		t.Fatalf("pending transactions mismatch: have %d, want 2", len(pending))
+	}
+	if !pool.IsPrivate(private.Hash()) || pool.IsPrivate(public.Hash()) {
+		t.Errorf("private marks mismatch: private %v, public %v", pool.IsPrivate(private.Hash()), pool.IsPrivate(public.Hash()))
+	}
+	pool.mu.Lock()
+	_, locals := pool.local()
+	pool.mu.Unlock()
+	if len(locals) != 1 || locals[0] != public {
+		t.Errorf("journaled transactions mismatch: have %d, want only the public one", len(locals))
+	}
+	// Marks are forgotten once the transactions leave the pool
+	pool.mu.Lock()
+	pool.removeTx(ctx, private)
+	pool.prunePrivate()
+	pool.mu.Unlock()
+	if pool.IsPrivate(private.Hash()) {
+		t.Errorf("private mark kept after removal")
+	}
+}
+
+// Tests that transactions submitted valid until a deadline are refused if it
+// passed, never journaled, and dropped once it passes along with a drop event.
+func TestTransactionDeadline(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	pool, key := setupTxPool(ctx)
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.mu.Lock()
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+	pool.mu.Unlock()
+
+	now := time.Now()
+	dated, undated := transaction(0, 100000, key), transaction(1, 100000, key)
+	if err := pool.AddLocal(WithValidUntil(ctx, now.Add(-time.Second)), dated); err != ErrTxDeadline {
+		t.Fatalf("past deadline error mismatch: have %v, want %v", err, ErrTxDeadline)
+	}
+	if _, ok := pool.Deadline(dated.Hash()); ok {
+		t.Fatalf("deadline of refused transaction kept")
+	}
+	if err := pool.AddLocal(WithValidUntil(ctx, now.Add(time.Hour)), dated); err != nil {
+		t.Fatalf("failed to add dated transaction: %v", err)
+	}
+	if err := pool.AddLocal(ctx, undated); err != nil {
+		t.Fatalf("failed to add undated transaction: %v", err)
+	}
+	if !pool.Expired(dated.Hash(), now.Add(2*time.Hour)) || pool.Expired(dated.Hash(), now) || pool.Expired(undated.Hash(), now.Add(2*time.Hour)) {
+		t.Errorf("expiry mismatch")
+	}
+	pool.mu.Lock()
+	_, locals := pool.local()
+	pool.mu.Unlock()
+	if len(locals) != 1 || locals[0] != undated {
+		t.Errorf("journaled transactions mismatch: have %d, want only the undated one", len(locals))
+	}
+	if !pool.Withheld(dated.Hash()) || pool.Withheld(undated.Hash()) {
+		t.Errorf("propagation mismatch: dated withheld %v, undated withheld %v", pool.Withheld(dated.Hash()), pool.Withheld(undated.Hash()))
+	}
+	// Once the deadline passes the transaction is dropped, gapping the undated one
+	events := make(chan TxLifecycleEvent, 8)
+	sub := pool.SubscribeTxLifecycleEvent(events)
+	defer sub.Unsubscribe()
+
+	pool.expireDeadlines(ctx, now.Add(2*time.Hour))
+	if pool.Get(dated.Hash()) != nil {
+		t.Fatalf("transaction kept past its deadline")
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 1 {
+		t.Errorf("pool mismatch: have %d pending and %d queued, want 0 and 1", pending, queued)
+	}
+	want := TxLifecycleEvent{Hash: dated.Hash(), Kind: TxDropped, Reason: DropDeadline}
+	for dropped := false; !dropped; {
+		select {
+		case ev := <-events:
+			dropped = ev == want
+		case <-time.After(time.Second):
+			t.Fatalf("drop event not sent")
+		}
+	}
+	if _, ok := pool.Deadline(dated.Hash()); ok {
+		t.Errorf("deadline kept after expiry")
+	}
+	// A copy relayed back by a peer, without the deadline, must not revive it
+	if err := pool.AddRemote(ctx, dated); err != ErrTxDeadline {
+		t.Errorf("re-added expired transaction error mismatch: have %v, want %v", err, ErrTxDeadline)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 1 {
+		t.Errorf("pool mismatch after re-add: have %d pending and %d queued, want 0 and 1", pending, queued)
+	}
+}
+
+// Tests that arrival times are recorded on first addition and outlive the
+// transactions in the pool.
+func TestTransactionArrivalTime(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	pool, key := setupTxPool(ctx)
+	defer pool.Stop()
+
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.mu.Lock()
+	pool.currentState.AddBalance(from, big.NewInt(1000000))
+	pool.mu.Unlock()
+
+	tx := transaction(0, 100000, key)
+	if _, ok := pool.ArrivalTime(tx.Hash()); ok {
+		t.Fatalf("arrival time known before addition")
+	}
+	before := time.Now()
+	if err := pool.AddRemote(ctx, tx); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	arrival, ok := pool.ArrivalTime(tx.Hash())
+	if !ok || arrival.Before(before) || arrival.After(time.Now()) {
+		t.Fatalf("arrival time mismatch: have %v/%v, want after %v", arrival, ok, before)
+	}
+	pool.mu.Lock()
+	pool.removeTx(ctx, tx)
+	pool.mu.Unlock()
+	if err := pool.AddRemote(ctx, tx); err != nil {
+		t.Fatalf("failed to re-add transaction: %v", err)
+	}
+	if readded, _ := pool.ArrivalTime(tx.Hash()); !readded.Equal(arrival) {
+		t.Errorf("arrival time changed on re-addition: have %v, want %v", readded, arrival)
+	}
+}
+
 func TestInvalidTransactions(t *testing.T) {
 	ctx := context.Background()
 	t.Parallel()
@@ -302,6 +517,372 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that the gas of sponsored transactions is checked against the balance
+// of the sponsor, the sender only needing to afford the value.
+func TestSponsoredTransactions(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	diskdb := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := newTestBlockChain(statedb, 1000000, new(event.Feed))
+
+	sponsor := common.Address{0xaa}
+	config := *params.TestChainConfig
+	config.GasSponsor = &params.GasSponsorConfig{
+		Sponsor:     sponsor,
+		Block:       big.NewInt(0),
+		MaxGasPrice: big.NewInt(10),
+		Contracts:   []common.Address{{}},
+	}
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	tx := transaction(0, 100000, key)
+
+	pool.mu.Lock()
+	pool.currentState.AddBalance(from, tx.Value())
+	pool.mu.Unlock()
+	if err := pool.AddRemote(ctx, tx); err != ErrSponsorFunds {
+		t.Fatalf("unfunded sponsor error mismatch: have %v, want %v", err, ErrSponsorFunds)
+	}
+	pool.mu.Lock()
+	pool.currentState.AddBalance(sponsor, sponsoredGas(tx))
+	pool.mu.Unlock()
+	if err := pool.AddRemote(ctx, tx); err != nil {
+		t.Fatalf("failed to add sponsored transaction: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatch: have %d, want %d", pending, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	// Sponsored transactions must survive the funds checks on pool resets
+	pool.mu.Lock()
+	pool.reset(ctx, nil, nil)
+	pool.mu.Unlock()
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Fatalf("pending transactions mismatch after reset: have %d, want %d", pending, 1)
+	}
+	// The sponsor must afford all its pooled transactions, not just the last one
+	if err := pool.AddRemote(ctx, transaction(1, 100000, key)); err != ErrSponsorFunds {
+		t.Fatalf("overcommitted sponsor error mismatch: have %v, want %v", err, ErrSponsorFunds)
+	}
+	// Transactions priced over the cap are paid for by their sender
+	if err := pool.AddRemote(ctx, pricedTransaction(1, 100000, big.NewInt(11), key)); err != ErrInsufficientFunds {
+		t.Fatalf("over cap error mismatch: have %v, want %v", err, ErrInsufficientFunds)
+	}
+	pool.mu.Lock()
+	pool.currentState.AddBalance(sponsor, sponsoredGas(tx))
+	pool.mu.Unlock()
+	if err := pool.AddRemote(ctx, transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add second sponsored transaction: %v", err)
+	}
+	// Replacing a sponsored transaction only needs the sponsor to afford the bump
+	if err := pool.AddRemote(ctx, pricedTransaction(1, 100000, big.NewInt(2), key)); err != ErrSponsorFunds {
+		t.Fatalf("unfunded replacement error mismatch: have %v, want %v", err, ErrSponsorFunds)
+	}
+	pool.mu.Lock()
+	pool.currentState.AddBalance(sponsor, sponsoredGas(tx))
+	pool.mu.Unlock()
+	if err := pool.AddRemote(ctx, pricedTransaction(1, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to replace sponsored transaction: %v", err)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	pool.mu.Lock()
+	pool.currentState.SubBalance(sponsor, sponsoredGas(tx))
+	pool.mu.Unlock()
+	// Once the sponsor can't pay for them all, the later ones are demoted
+	pool.mu.Lock()
+	pool.currentState.SubBalance(sponsor, sponsoredGas(tx))
+	pool.reset(ctx, nil, nil)
+	pool.mu.Unlock()
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("pool mismatch after sponsor spending: have %d pending and %d queued, want 1 and 0", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that tenants can't pool more transactions than their quota, and that
+// their transactions are weighted for inclusion.
+func TestTenantTransactions(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	diskdb := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := newTestBlockChain(statedb, 1000000, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Tenants = []TenantQuota{{Name: "alice", Slots: 2, Weight: 3}}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if !pool.TenantWeights() {
+		t.Fatal("tenant weights not reported")
+	}
+	key, _ := crypto.GenerateKey()
+	pool.mu.Lock()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	pool.mu.Unlock()
+
+	txs := make([]*types.Transaction, 5)
+	for i := range txs {
+		txs[i] = transaction(uint64(i), 100000, key)
+	}
+	for i := 0; i < 2; i++ {
+		if err := pool.AddTenant(ctx, "alice", txs[i]); err != nil {
+			t.Fatalf("failed to add tenant transaction %d: %v", i, err)
+		}
+	}
+	if err := pool.AddTenant(ctx, "alice", txs[2]); err != ErrTenantQuota {
+		t.Fatalf("over quota error mismatch: have %v, want %v", err, ErrTenantQuota)
+	}
+	// Tenants without quota are unlimited, transactions of other tenants unweighted
+	if err := pool.AddTenant(ctx, "bob", txs[2]); err != nil {
+		t.Fatalf("failed to add unlimited tenant transaction: %v", err)
+	}
+	if weight := pool.TxWeight(txs[0].Hash()); weight != 3 {
+		t.Errorf("tenant weight mismatch: have %d, want 3", weight)
+	}
+	if weight := pool.TxWeight(txs[2].Hash()); weight != 1 {
+		t.Errorf("default weight mismatch: have %d, want 1", weight)
+	}
+	want := []TenantStatus{{Name: "alice", Txs: 2, Slots: 2, Weight: 3}, {Name: "bob", Txs: 1, Weight: 1}}
+	if status := pool.Tenants(); !reflect.DeepEqual(status, want) {
+		t.Errorf("tenant status mismatch: have %+v, want %+v", status, want)
+	}
+	// Slots are freed once transactions leave the pool
+	pool.mu.Lock()
+	pool.removeTx(ctx, txs[1])
+	pool.mu.Unlock()
+
+	if err := pool.AddTenant(ctx, "alice", txs[3]); err != nil {
+		t.Fatalf("failed to add tenant transaction after removal: %v", err)
+	}
+}
+
+// Tests that accounts can't pool more transactions than their quota unless
+// outbidding themselves by the bump each time, whether submitted remotely or
+// locally, while replacements and journaled transactions are exempt.
+func TestAccountQuota(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	diskdb := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := newTestBlockChain(statedb, 1000000, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.AccountQuota = 2
+	config.AccountQuotaBump = 100
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	remote, _ := crypto.GenerateKey()
+	local, _ := crypto.GenerateKey()
+	pool.mu.Lock()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	pool.mu.Unlock()
+
+	tests := []struct {
+		tx  *types.Transaction
+		err error
+	}{
+		{pricedTransaction(0, 100000, big.NewInt(1), remote), nil},
+		{pricedTransaction(1, 100000, big.NewInt(1), remote), nil},
+		{pricedTransaction(2, 100000, big.NewInt(1), remote), ErrAccountQuota}, // Over the quota
+		{pricedTransaction(2, 100000, big.NewInt(2), remote), nil},             // Doubling the highest price
+		{pricedTransaction(3, 100000, big.NewInt(3), remote), ErrAccountQuota}, // Not doubling anymore
+		{pricedTransaction(3, 100000, big.NewInt(4), remote), nil},
+		{pricedTransaction(0, 100000, big.NewInt(2), remote), nil}, // Replacement
+	}
+	for i, tt := range tests {
+		if err := pool.AddRemote(ctx, tt.tx); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if code := ErrAccountQuota.(interface{ ErrorCode() int }).ErrorCode(); code != 4453 {
+		t.Errorf("error code mismatch: have %d, want 4453", code)
+	}
+	for i := uint64(0); i < 2; i++ {
+		if err := pool.AddLocal(ctx, transaction(i, 100000, local)); err != nil {
+			t.Errorf("local transaction %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.AddLocal(ctx, transaction(2, 100000, local)); err != ErrAccountQuota {
+		t.Errorf("local transaction over the quota: error mismatch: have %v, want %v", err, ErrAccountQuota)
+	}
+	// Transactions loaded from the journal were admitted before, don't refuse them
+	pool.mu.Lock()
+	pool.replaying = true
+	errs := pool.addTxsLocked(ctx, types.Transactions{transaction(2, 100000, local), transaction(3, 100000, local)}, true)
+	pool.replaying = false
+	pool.mu.Unlock()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("journaled transaction %d: failed to add: %v", i, err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 8 {
+		t.Errorf("pending transactions mismatch: have %d, want 8", pending)
+	}
+}
+
+func TestParseTenantQuotas(t *testing.T) {
+	quotas, err := ParseTenantQuotas("alice=64:2, bob=16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TenantQuota{{Name: "alice", Slots: 64, Weight: 2}, {Name: "bob", Slots: 16}}
+	if !reflect.DeepEqual(quotas, want) {
+		t.Errorf("quotas mismatch: have %+v, want %+v", quotas, want)
+	}
+	for _, spec := range []string{"alice", "alice=many", "alice=1:0", "=1", "alice=1,alice=2"} {
+		if _, err := ParseTenantQuotas(spec); err == nil {
+			t.Errorf("invalid spec %q accepted", spec)
+		}
+	}
+}
+
+func TestParseLifetimeTiers(t *testing.T) {
+	tiers, err := ParseLifetimeTiers("partners=24h:0x000000000000000000000000000000000000001f+0x000000000000000000000000000000000000002e, bots=0s:0x000000000000000000000000000000000000003d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LifetimeTier{
+		{Name: "partners", Accounts: []common.Address{common.HexToAddress("0x1f"), common.HexToAddress("0x2e")}, Lifetime: 24 * time.Hour},
+		{Name: "bots", Accounts: []common.Address{common.HexToAddress("0x3d")}},
+	}
+	if !reflect.DeepEqual(tiers, want) {
+		t.Errorf("tiers mismatch: have %+v, want %+v", tiers, want)
+	}
+	addr := "0x000000000000000000000000000000000000001f"
+	for _, spec := range []string{"partners", "partners=24h", "partners=long:" + addr, "partners=-1h:" + addr, "partners=1h:0x1f", "a=1h:" + addr + ",b=2h:" + addr, "a=1h:" + addr + ",a=2h:0x000000000000000000000000000000000000002e"} {
+		if _, err := ParseLifetimeTiers(spec); err == nil {
+			t.Errorf("invalid spec %q accepted", spec)
+		}
+	}
+}
+
+// Tests that account inspection reports the nonce gaps and underpriced
+// transactions keeping an account stuck, and when its queue expires.
+func TestInspectAccount(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	diskdb := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(diskdb))
+	blockchain := newTestBlockChain(statedb, 1000000, new(event.Feed))
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := testTxPoolConfig
+	config.LifetimeTiers = []LifetimeTier{{Name: "partners", Accounts: []common.Address{addr}, Lifetime: time.Hour}}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pool.mu.Lock()
+	pool.currentState.AddBalance(addr, big.NewInt(1000000000))
+	pool.mu.Unlock()
+
+	// An underpriced local transaction holds the account back, two gaps the queue
+	if err := pool.AddLocal(ctx, pricedTransaction(0, 100000, big.NewInt(0), key)); err != nil {
+		t.Fatalf("failed to add underpriced transaction: %v", err)
+	}
+	for _, nonce := range []uint64{1, 3, 4, 7} {
+		if err := pool.AddLocal(ctx, pricedTransaction(nonce, 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	account := pool.InspectAccount(addr)
+
+	nonces := func(txs []*TxPoolAccountTx) (list []uint64) {
+		for _, tx := range txs {
+			list = append(list, tx.Nonce)
+		}
+		return list
+	}
+	if have := nonces(account.Pending); !reflect.DeepEqual(have, []uint64{0, 1}) {
+		t.Errorf("pending mismatch: have %v, want [0 1]", have)
+	}
+	if have := nonces(account.Queued); !reflect.DeepEqual(have, []uint64{3, 4, 7}) {
+		t.Errorf("queued mismatch: have %v, want [3 4 7]", have)
+	}
+	if have := nonces(account.Blocking); !reflect.DeepEqual(have, []uint64{0}) {
+		t.Errorf("blocking mismatch: have %v, want [0]", have)
+	}
+	if want := []*NonceGap{{From: 2, To: 2, Blocked: 3}, {From: 5, To: 6, Blocked: 1}}; !reflect.DeepEqual(account.Gaps, want) {
+		t.Errorf("gaps mismatch: have %+v, want %+v", account.Gaps, want)
+	}
+	if account.Nonce != 0 || account.NextNonce != 2 || !account.Local {
+		t.Errorf("account mismatch: nonce %d, next %d, local %v", account.Nonce, account.NextNonce, account.Local)
+	}
+	if account.Tier != "partners" || account.Lifetime != "1h0m0s" || account.Expiry == nil {
+		t.Errorf("expiry mismatch: tier %q, lifetime %q, expiry %v", account.Tier, account.Lifetime, account.Expiry)
+	}
+}
+
+// Tests that the queued transactions of tiered accounts expire after the tier's
+// lifetime, regardless of locality.
+func TestTransactionQueueLifetimeTiers(t *testing.T) {
+	ctx := context.Background()
+	// Reduce the eviction interval to a testable amount
+	defer func(old time.Duration) { evictionInterval = old }(evictionInterval)
+	evictionInterval = time.Second
+
+	db := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := newTestBlockChain(statedb, 1000000, new(event.Feed))
+
+	local, _ := crypto.GenerateKey()
+	tiered, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	config := testTxPoolConfig
+	config.LifetimeTiers = []LifetimeTier{
+		{Name: "short", Accounts: []common.Address{crypto.PubkeyToAddress(local.PublicKey), crypto.PubkeyToAddress(tiered.PublicKey)}, Lifetime: time.Second},
+	}
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pool.mu.Lock()
+	for _, key := range []*ecdsa.PrivateKey{local, tiered, remote} {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+	}
+	pool.mu.Unlock()
+
+	if err := pool.AddLocal(ctx, pricedTransaction(1, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	for _, key := range []*ecdsa.PrivateKey{tiered, remote} {
+		if err := pool.AddRemote(ctx, pricedTransaction(1, 100000, big.NewInt(1), key)); err != nil {
+			t.Fatalf("failed to add remote transaction: %v", err)
+		}
+	}
+	if _, queued := pool.Stats(); queued != 3 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 3)
+	}
+	// Wait for eviction to drop the tiered accounts, keeping the remote one
+	time.Sleep(5 * time.Second)
+
+	if _, queued := pool.Stats(); queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -1010,8 +1591,10 @@ func testTransactionQueueGlobalLimiting(t *testing.T, nolocals bool) {
 //
 // This logic should not hold for local transactions, unless the local tracking
 // mechanism is disabled.
-func TestTransactionQueueTimeLimiting(t *testing.T)         { testTransactionQueueTimeLimiting(t, false) }
-func TestTransactionQueueTimeLimitingNoLocals(t *testing.T) { testTransactionQueueTimeLimiting(t, true) }
+func TestTransactionQueueTimeLimiting(t *testing.T) { testTransactionQueueTimeLimiting(t, false) }
+func TestTransactionQueueTimeLimitingNoLocals(t *testing.T) {
+	testTransactionQueueTimeLimiting(t, true)
+}
 
 func testTransactionQueueTimeLimiting(t *testing.T, nolocals bool) {
 	ctx := context.Background()
@@ -1128,8 +1711,10 @@ func TestTransactionPendingLimiting(t *testing.T) {
 
 // Tests that the transaction limits are enforced the same way irrelevant whether
 // the transactions are added one by one or in batches.
-func TestTransactionQueueLimitingEquivalency(t *testing.T)   { testTransactionLimitingEquivalency(t, 1) }
-func TestTransactionPendingLimitingEquivalency(t *testing.T) { testTransactionLimitingEquivalency(t, 0) }
+func TestTransactionQueueLimitingEquivalency(t *testing.T) { testTransactionLimitingEquivalency(t, 1) }
+func TestTransactionPendingLimitingEquivalency(t *testing.T) {
+	testTransactionLimitingEquivalency(t, 0)
+}
 
 func testTransactionLimitingEquivalency(t *testing.T, origin uint64) {
 	ctx := context.Background()
@@ -1686,8 +2271,8 @@ func TestTransactionReplacement(t *testing.T) {
 	if err := pool.AddRemote(ctx, pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
 		t.Fatalf("failed to add original cheap pending transaction: %v", err)
 	}
-	if err := pool.AddRemote(ctx, pricedTransaction(0, 100001, big.NewInt(1), key)); err != ErrReplaceUnderpriced {
-		t.Fatalf("original cheap pending transaction replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	if err := pool.AddRemote(ctx, pricedTransaction(0, 100001, big.NewInt(1), key)); err != ErrReplaceSamePrice {
+		t.Fatalf("original cheap pending transaction replacement error mismatch: have %v, want %v", err, ErrReplaceSamePrice)
 	}
 	if err := pool.AddRemote(ctx, pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
 		t.Fatalf("failed to replace original cheap pending transaction: %v", err)
@@ -1718,8 +2303,8 @@ func TestTransactionReplacement(t *testing.T) {
 	if err := pool.AddRemote(ctx, pricedTransaction(2, 100000, big.NewInt(1), key)); err != nil {
 		t.Fatalf("failed to add original cheap queued transaction: %v", err)
 	}
-	if err := pool.AddRemote(ctx, pricedTransaction(2, 100001, big.NewInt(1), key)); err != ErrReplaceUnderpriced {
-		t.Fatalf("original cheap queued transaction replacement error mismatch: have %v, want %v", err, ErrReplaceUnderpriced)
+	if err := pool.AddRemote(ctx, pricedTransaction(2, 100001, big.NewInt(1), key)); err != ErrReplaceSamePrice {
+		t.Fatalf("original cheap queued transaction replacement error mismatch: have %v, want %v", err, ErrReplaceSamePrice)
 	}
 	if err := pool.AddRemote(ctx, pricedTransaction(2, 100000, big.NewInt(2), key)); err != nil {
 		t.Fatalf("failed to replace original cheap queued transaction: %v", err)
@@ -1745,6 +2330,66 @@ func TestTransactionReplacement(t *testing.T) {
 	}
 }
 
+// Tests that local accounts may replace transactions without raising the gas
+// price if the pool is configured so, while remote ones may not.
+func TestTransactionReplacementLocalSamePrice(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	db := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := newTestBlockChain(statedb, 1000000, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.LocalSamePriceReplace = true
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Replace both a pending and a queued local transaction at the same price
+	for _, nonce := range []uint64{0, 2} {
+		if err := pool.AddLocal(ctx, pricedTransaction(nonce, 100000, big.NewInt(2), local)); err != nil {
+			t.Fatalf("nonce %d: failed to add local transaction: %v", nonce, err)
+		}
+		if err := pool.AddLocal(ctx, pricedTransaction(nonce, 100001, big.NewInt(2), local)); err != nil {
+			t.Fatalf("nonce %d: failed to replace local transaction at same price: %v", nonce, err)
+		}
+		if err := pool.AddLocal(ctx, pricedTransaction(nonce, 100002, big.NewInt(1), local)); err != ErrReplaceLowerPrice {
+			t.Fatalf("nonce %d: local replacement error mismatch: have %v, want %v", nonce, err, ErrReplaceLowerPrice)
+		}
+	}
+	// Remote accounts must still raise the price by the configured bump
+	if err := pool.AddRemote(ctx, pricedTransaction(0, 100000, big.NewInt(100), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	tests := []struct {
+		price int64
+		err   error
+		code  int
+	}{
+		{99, ErrReplaceLowerPrice, 4451},
+		{100, ErrReplaceSamePrice, 4452},
+		{100 + int64(testTxPoolConfig.PriceBump) - 1, ErrReplaceUnderpriced, 4450},
+	}
+	for _, tt := range tests {
+		err := pool.AddRemote(ctx, pricedTransaction(0, 100001, big.NewInt(tt.price), remote))
+		if err != tt.err {
+			t.Fatalf("price %d: remote replacement error mismatch: have %v, want %v", tt.price, err, tt.err)
+		}
+		if code := err.(interface{ ErrorCode() int }).ErrorCode(); code != tt.code {
+			t.Errorf("price %d: error code mismatch: have %d, want %d", tt.price, code, tt.code)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that local transactions are journaled to disk, but remote transactions
 // get discarded between restarts.
 func TestTransactionJournaling(t *testing.T)         { testTransactionJournaling(t, false) }
@@ -0,0 +1,92 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// senderCacheSize is the number of recovered senders kept by the process-wide
+// sender cache.
+const senderCacheSize = 131072
+
+var (
+	// senderCache maps transactions to their recovered senders across all
+	// decoded copies of a transaction, so that the pool, the block validation
+	// and the RPC handlers don't each recover the same signatures.
+	senderCache, _ = lru.New(senderCacheSize)
+
+	senderCacheHitMeter  = metrics.NewMeter("txs/sender/cache/hit")
+	senderCacheMissMeter = metrics.NewMeter("txs/sender/cache/miss")
+)
+
+// senderKey identifies a sender recovery, as the validity of a signature, and
+// so the recovered sender, depends on the signing rules.
+type senderKey struct {
+	hash    common.Hash
+	signer  byte
+	chainId uint64
+}
+
+const (
+	senderKeyFrontier byte = iota + 1
+	senderKeyHomestead
+	senderKeyEIP155
+)
+
+// newSenderKey returns the cache key of recovering the sender of tx with
+// signer, or false if the signer is not cacheable.
+func newSenderKey(signer Signer, tx *Transaction) (senderKey, bool) {
+	key := senderKey{hash: tx.Hash()}
+	switch s := signer.(type) {
+	case FrontierSigner:
+		key.signer = senderKeyFrontier
+	case HomesteadSigner:
+		key.signer = senderKeyHomestead
+	case EIP155Signer:
+		if !s.chainId.IsUint64() {
+			return key, false
+		}
+		key.signer, key.chainId = senderKeyEIP155, s.chainId.Uint64()
+	default:
+		return key, false
+	}
+	return key, true
+}
+
+// cachedSender returns the sender previously recovered for tx with signer.
+func cachedSender(signer Signer, tx *Transaction) (common.Address, bool) {
+	key, ok := newSenderKey(signer, tx)
+	if !ok {
+		return common.Address{}, false
+	}
+	if from, ok := senderCache.Get(key); ok {
+		senderCacheHitMeter.Mark(1)
+		return from.(common.Address), true
+	}
+	senderCacheMissMeter.Mark(1)
+	return common.Address{}, false
+}
+
+// cacheSender records the sender recovered for tx with signer.
+func cacheSender(signer Signer, tx *Transaction, from common.Address) {
+	if key, ok := newSenderKey(signer, tx); ok {
+		senderCache.Add(key, from)
+	}
+}
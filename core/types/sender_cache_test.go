@@ -0,0 +1,63 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+func TestSenderCache(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := NewEIP155Signer(big.NewInt(18))
+	tx, err := SignTx(NewTransaction(0, common.Address{}, new(big.Int), 0, new(big.Int), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := Sender(context.Background(), signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// A decoded copy of the transaction is served from the shared cache
+	enc, _ := rlp.EncodeToBytes(tx)
+	var cpy Transaction
+	if err := rlp.DecodeBytes(enc, &cpy); err != nil {
+		t.Fatal(err)
+	}
+	if from, ok := cachedSender(signer, &cpy); !ok || from != addr {
+		t.Errorf("cached sender mismatch: have %x (%v), want %x", from, ok, addr)
+	}
+	if from, err := Sender(context.Background(), signer, &cpy); err != nil || from != addr {
+		t.Errorf("copy sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// Recoveries under other signing rules don't share the entry
+	if _, ok := cachedSender(NewEIP155Signer(big.NewInt(19)), &cpy); ok {
+		t.Errorf("sender cached across chain ids")
+	}
+	if _, err := Sender(context.Background(), NewEIP155Signer(big.NewInt(19)), &cpy); err != ErrInvalidChainId {
+		t.Errorf("chain id error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+	if _, ok := cachedSender(HomesteadSigner{}, &cpy); ok {
+		t.Errorf("sender cached across signers")
+	}
+}
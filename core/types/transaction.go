@@ -20,6 +20,7 @@ import (
 	"container/heap"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"math/big"
 	"sync/atomic"
@@ -38,6 +39,7 @@ var (
 )
 
 type Transaction struct {
+	typ  uint8 // Envelope type, LegacyTxType for transactions without envelope
 	data txdata
 	// caches
 	hash atomic.Value
@@ -59,7 +61,8 @@ type txdata struct {
 	S *big.Int `json:"s" gencodec:"required"`
 
 	// This is only used when marshaling to JSON.
-	Hash *common.Hash `json:"hash" rlp:"-"`
+	Hash *common.Hash    `json:"hash" rlp:"-"`
+	Type *hexutil.Uint64 `json:"type,omitempty" rlp:"-"`
 }
 
 type txdataMarshaling struct {
@@ -81,6 +84,18 @@ func NewContractCreation(nonce uint64, amount *big.Int, gasLimit uint64, gasPric
 	return newTransaction(nonce, nil, amount, gasLimit, gasPrice, data)
 }
 
+// NewTypedTransaction creates a transaction of the given type, to be encoded in
+// the typed envelope. A nil recipient creates a contract.
+func NewTypedTransaction(typ uint8, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
+	if to != nil {
+		addr := *to
+		to = &addr
+	}
+	tx := newTransaction(nonce, to, amount, gasLimit, gasPrice, data)
+	tx.typ = typ
+	return tx
+}
+
 func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
 	if len(data) > 0 {
 		data = common.CopyBytes(data)
@@ -106,6 +121,12 @@ func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit
 	return &Transaction{data: d}
 }
 
+// Type returns the envelope type of the transaction, LegacyTxType for
+// transactions without envelope.
+func (tx *Transaction) Type() uint8 {
+	return tx.typ
+}
+
 // ChainId returns which chain id this transaction was signed for (if at all)
 func (tx *Transaction) ChainId() *big.Int {
 	return deriveChainId(tx.data.V)
@@ -125,19 +146,37 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder, encoding typed transactions as an RLP
+// string of their envelope.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.typ == LegacyTxType {
+		return rlp.Encode(w, &tx.data)
+	}
+	enc, err := tx.encodeTyped()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
+	kind, size, err := s.Kind()
+	if err != nil {
+		return err
+	}
+	if kind == rlp.List {
+		tx.typ = LegacyTxType
+		err = s.Decode(&tx.data)
+	} else {
+		var enc []byte
+		if enc, err = s.Bytes(); err == nil {
+			err = tx.decodeTyped(enc)
+		}
+	}
 	if err == nil {
 		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
 	}
-
 	return err
 }
 
@@ -146,6 +185,10 @@ func (tx *Transaction) MarshalJSON() ([]byte, error) {
 	hash := tx.Hash()
 	data := tx.data
 	data.Hash = &hash
+	if tx.typ != LegacyTxType {
+		typ := hexutil.Uint64(tx.typ)
+		data.Type = &typ
+	}
 	return data.MarshalJSON()
 }
 
@@ -155,6 +198,16 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if err := dec.UnmarshalJSON(input); err != nil {
 		return err
 	}
+	var typ uint8
+	if dec.Type != nil {
+		if *dec.Type > MaxTxType {
+			return fmt.Errorf("invalid transaction type %#x", uint64(*dec.Type))
+		}
+		typ, dec.Type = uint8(*dec.Type), nil
+		if _, ok := LookupTxType(typ); !ok {
+			return ErrTxTypeNotSupported
+		}
+	}
 	var V byte
 	if isProtectedV(dec.V) {
 		chainID := deriveChainId(dec.V).Uint64()
@@ -165,7 +218,7 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
 		return ErrInvalidSig
 	}
-	*tx = Transaction{data: dec}
+	*tx = Transaction{typ: typ, data: dec}
 	return nil
 }
 
@@ -188,13 +241,18 @@ func (tx *Transaction) To() *common.Address {
 	return &to
 }
 
-// Hash hashes the RLP encoding of tx.
+// Hash hashes the RLP encoding of tx, or the envelope of typed transactions.
 // It uniquely identifies the transaction.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.typ == LegacyTxType {
+		v = rlpHash(tx)
+	} else {
+		v = prefixedRlpHash(tx.typ, &tx.data)
+	}
 	tx.hash.Store(v)
 	return v
 }
@@ -206,7 +264,7 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	rlp.Encode(&c, tx)
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -241,7 +299,7 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	if err != nil {
 		return nil, err
 	}
-	cpy := &Transaction{data: tx.data}
+	cpy := &Transaction{typ: tx.typ, data: tx.data}
 	cpy.data.R, cpy.data.S, cpy.data.V = r, s, v
 	return cpy, nil
 }
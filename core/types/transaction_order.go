@@ -0,0 +1,267 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+)
+
+// TxArrival is a candidate transaction of a first come first served block,
+// stamped by the sealer with the time it arrived in Unix milliseconds, zero if
+// unknown.
+type TxArrival struct {
+	Hash common.Hash
+	Time uint64
+}
+
+// TxOrderCommitment returns the commitment of a sealer to the candidates it
+// ordered a block from, the hash of their arrival ordered list. As the list
+// stamps every pending transaction, including those left out of the block, the
+// sealer can't justify another order once the block is out.
+func TxOrderCommitment(candidates []TxArrival) common.Hash {
+	return rlpHash(candidates)
+}
+
+// txArrival is a transaction along with the time it was first seen.
+type txArrival struct {
+	tx    *Transaction
+	time  time.Time
+	known bool // Whether the arrival time is known
+}
+
+// TxByArrival implements the heap interface, ordering transactions by arrival
+// time. Transactions of unknown arrival go last, and ties are broken by hash to
+// keep the order deterministic.
+type TxByArrival []txArrival
+
+func (s TxByArrival) Len() int { return len(s) }
+func (s TxByArrival) Less(i, j int) bool {
+	if s[i].known != s[j].known {
+		return s[i].known
+	}
+	if !s[i].time.Equal(s[j].time) {
+		return s[i].time.Before(s[j].time)
+	}
+	hi, hj := s[i].tx.Hash(), s[j].tx.Hash()
+	return bytes.Compare(hi[:], hj[:]) < 0
+}
+func (s TxByArrival) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *TxByArrival) Push(x interface{}) {
+	*s = append(*s, x.(txArrival))
+}
+
+func (s *TxByArrival) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// TransactionsByArrivalAndNonce represents a set of transactions that can return
+// transactions in first come first served order, while supporting removing
+// entire batches of transactions for non-executable accounts.
+type TransactionsByArrivalAndNonce struct {
+	txs     map[common.Address]Transactions     // Per account nonce-sorted list of transactions
+	heads   TxByArrival                         // Next transaction for each unique account (arrival heap)
+	signer  Signer                              // Signer for the set of transactions
+	arrival func(common.Hash) (time.Time, bool) // Arrival time lookup of transactions
+
+	candidates []TxArrival // All transactions of the set in arrival order, as committed to
+}
+
+// NewTransactionsByArrivalAndNonce creates a transaction set that can retrieve
+// transactions by arrival time in a nonce-honouring way.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func NewTransactionsByArrivalAndNonce(ctx context.Context, signer Signer, txs map[common.Address]Transactions, arrival func(common.Hash) (time.Time, bool)) *TransactionsByArrivalAndNonce {
+	t := &TransactionsByArrivalAndNonce{
+		txs:     txs,
+		heads:   make(TxByArrival, 0, len(txs)),
+		signer:  signer,
+		arrival: arrival,
+	}
+	var all TxByArrival
+	for _, accTxs := range txs {
+		for _, tx := range accTxs {
+			all = append(all, t.newArrival(tx))
+		}
+	}
+	sort.Sort(all)
+	t.candidates = make([]TxArrival, len(all))
+	for i, arrival := range all {
+		t.candidates[i].Hash = arrival.tx.Hash()
+		if arrival.known {
+			t.candidates[i].Time = uint64(arrival.time.UnixNano() / int64(time.Millisecond))
+		}
+	}
+	for from, accTxs := range txs {
+		if len(accTxs) > 0 {
+			t.heads = append(t.heads, t.newArrival(accTxs[0]))
+			// Ensure the sender address is from the signer
+			acc, _ := Sender(ctx, signer, accTxs[0])
+			txs[acc] = accTxs[1:]
+			if from != acc {
+				delete(txs, from)
+			}
+		}
+	}
+	heap.Init(&t.heads)
+	return t
+}
+
+func (t *TransactionsByArrivalAndNonce) newArrival(tx *Transaction) txArrival {
+	time, known := t.arrival(tx.Hash())
+	return txArrival{tx: tx, time: time, known: known}
+}
+
+// Candidates returns all transactions of the set in arrival order, stamped with
+// their arrival times, for the sealer to commit to.
+func (t *TransactionsByArrivalAndNonce) Candidates() []TxArrival {
+	return t.candidates
+}
+
+// Peek returns the earliest arrived transaction.
+func (t *TransactionsByArrivalAndNonce) Peek() *Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0].tx
+}
+
+// Shift replaces the current head with the next one from the same account.
+func (t *TransactionsByArrivalAndNonce) Shift(ctx context.Context) {
+	acc, _ := Sender(ctx, t.signer, t.heads[0].tx)
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads[0], t.txs[acc] = t.newArrival(txs[0]), txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop removes the current head, *not* replacing it with the next one from the
+// same account. This should be used when a transaction cannot be executed and
+// hence all subsequent ones should be discarded from the same account.
+func (t *TransactionsByArrivalAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
+
+// txWeighted is a transaction along with its gas price scaled by its weight.
+type txWeighted struct {
+	tx    *Transaction
+	price *big.Int
+}
+
+// TxByWeightedPrice implements the heap interface, ordering transactions by
+// weighted gas price.
+type TxByWeightedPrice []txWeighted
+
+func (s TxByWeightedPrice) Len() int           { return len(s) }
+func (s TxByWeightedPrice) Less(i, j int) bool { return s[i].price.Cmp(s[j].price) > 0 }
+func (s TxByWeightedPrice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+func (s *TxByWeightedPrice) Push(x interface{}) {
+	*s = append(*s, x.(txWeighted))
+}
+
+func (s *TxByWeightedPrice) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	*s = old[0 : n-1]
+	return x
+}
+
+// TransactionsByWeightedPriceAndNonce represents a set of transactions that can
+// return transactions in a weighted profit-maximizing sorted order, while
+// supporting removing entire batches of transactions for non-executable
+// accounts.
+type TransactionsByWeightedPriceAndNonce struct {
+	txs    map[common.Address]Transactions // Per account nonce-sorted list of transactions
+	heads  TxByWeightedPrice               // Next transaction for each unique account (weighted price heap)
+	signer Signer                          // Signer for the set of transactions
+	weight func(common.Hash) uint64        // Gas price multiplier of transactions
+}
+
+// NewTransactionsByWeightedPriceAndNonce creates a transaction set that can
+// retrieve transactions by gas price multiplied by their weight in a
+// nonce-honouring way.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// if after providing it to the constructor.
+func NewTransactionsByWeightedPriceAndNonce(ctx context.Context, signer Signer, txs map[common.Address]Transactions, weight func(common.Hash) uint64) *TransactionsByWeightedPriceAndNonce {
+	t := &TransactionsByWeightedPriceAndNonce{
+		txs:    txs,
+		heads:  make(TxByWeightedPrice, 0, len(txs)),
+		signer: signer,
+		weight: weight,
+	}
+	for from, accTxs := range txs {
+		if len(accTxs) > 0 {
+			t.heads = append(t.heads, t.newWeighted(accTxs[0]))
+			// Ensure the sender address is from the signer
+			acc, _ := Sender(ctx, signer, accTxs[0])
+			txs[acc] = accTxs[1:]
+			if from != acc {
+				delete(txs, from)
+			}
+		}
+	}
+	heap.Init(&t.heads)
+	return t
+}
+
+func (t *TransactionsByWeightedPriceAndNonce) newWeighted(tx *Transaction) txWeighted {
+	price := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(t.weight(tx.Hash())))
+	return txWeighted{tx: tx, price: price}
+}
+
+// Peek returns the next transaction by weighted price.
+func (t *TransactionsByWeightedPriceAndNonce) Peek() *Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0].tx
+}
+
+// Shift replaces the current best head with the next one from the same account.
+func (t *TransactionsByWeightedPriceAndNonce) Shift(ctx context.Context) {
+	acc, _ := Sender(ctx, t.signer, t.heads[0].tx)
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		t.heads[0], t.txs[acc] = t.newWeighted(txs[0]), txs[1:]
+		heap.Fix(&t.heads, 0)
+	} else {
+		heap.Pop(&t.heads)
+	}
+}
+
+// Pop removes the best transaction, *not* replacing it with the next one from
+// the same account. This should be used when a transaction cannot be executed
+// and hence all subsequent ones should be discarded from the same account.
+func (t *TransactionsByWeightedPriceAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
@@ -71,7 +71,9 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 //
 // Sender may cache the address, allowing it to be used regardless of
 // signing method. The cache is invalidated if the cached signer does
-// not match the signer used in the current call.
+// not match the signer used in the current call. Recovered addresses are
+// also kept in a process-wide cache keyed by transaction hash, shared by
+// all decoded copies of a transaction.
 func Sender(ctx context.Context, signer Signer, tx *Transaction) (common.Address, error) {
 	if sc := tx.from.Load(); sc != nil {
 		sigCache := sc.(sigCache)
@@ -83,6 +85,11 @@ func Sender(ctx context.Context, signer Signer, tx *Transaction) (common.Address
 		}
 	}
 
+	// Other copies of the transaction may have been recovered already
+	if addr, ok := cachedSender(signer, tx); ok {
+		tx.from.Store(sigCache{signer: signer, from: addr})
+		return addr, nil
+	}
 	_, span := trace.StartSpan(ctx, "signer.Sender")
 	addr, err := signer.Sender(tx)
 	span.End()
@@ -91,6 +98,7 @@ func Sender(ctx context.Context, signer Signer, tx *Transaction) (common.Address
 	}
 
 	tx.from.Store(sigCache{signer: signer, from: addr})
+	cacheSender(signer, tx, addr)
 	return addr, nil
 }
 
@@ -159,7 +167,7 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	return sigHash(tx, []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
@@ -211,7 +219,7 @@ func (fs FrontierSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v *
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
-	return rlpHash([]interface{}{
+	return sigHash(tx, []interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
 		tx.data.GasLimit,
@@ -225,6 +233,15 @@ func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
 	return recoverPlain(fs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, false)
 }
 
+// sigHash hashes the signed fields of a transaction, prefixed by the type of
+// typed transactions so that signatures can't be replayed across types.
+func sigHash(tx *Transaction, fields []interface{}) common.Hash {
+	if tx.typ == LegacyTxType {
+		return rlpHash(fields)
+	}
+	return prefixedRlpHash(tx.typ, fields)
+}
+
 func recoverPlain(sighash common.Hash, R, S, Vb *big.Int, homestead bool) (common.Address, error) {
 	if Vb.BitLen() > 8 {
 		return common.Address{}, ErrInvalidSig
@@ -22,7 +22,10 @@ import (
 	"crypto/ecdsa"
 	"encoding/json"
 	"math/big"
+	"math/rand"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/crypto"
@@ -181,6 +184,154 @@ func TestTransactionPriceNonceSort(t *testing.T) {
 	}
 }
 
+// Tests that transactions can be correctly sorted according to their arrival
+// time, while still honouring nonce ordering within accounts.
+// Tests that transactions are ordered by gas price scaled by their weight, while
+// honouring the nonce order of each account.
+func TestTransactionWeightedPriceNonceSort(t *testing.T) {
+	ctx := context.Background()
+	keys := make([]*ecdsa.PrivateKey, 3)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := HomesteadSigner{}
+
+	// The second account pays less, but is weighted above the others
+	prices := []int64{10, 4, 7}
+	weights := make(map[common.Hash]uint64)
+	groups := map[common.Address]Transactions{}
+	for i, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for nonce := 0; nonce < 2; nonce++ {
+			tx, _ := SignTx(NewTransaction(uint64(nonce), common.Address{}, big.NewInt(100), 100, big.NewInt(prices[i]-2*int64(nonce)), nil), signer, key)
+			groups[addr] = append(groups[addr], tx)
+			if i == 1 {
+				weights[tx.Hash()] = 3
+			}
+		}
+	}
+	weight := func(hash common.Hash) uint64 {
+		if w, ok := weights[hash]; ok {
+			return w
+		}
+		return 1
+	}
+	txset := NewTransactionsByWeightedPriceAndNonce(ctx, signer, groups, weight)
+
+	var have []int64
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		have = append(have, tx.GasPrice().Int64())
+		txset.Shift(ctx)
+	}
+	// Weighted prices: 12, 6 for the second account, 10, 8 and 7, 5 for the others
+	want := []int64{4, 10, 8, 7, 2, 5}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("transaction order mismatch: have %v, want %v", have, want)
+	}
+}
+
+func TestTransactionArrivalNonceSort(t *testing.T) {
+	ctx := context.Background()
+	// Generate a batch of accounts to start with
+	keys := make([]*ecdsa.PrivateKey, 10)
+	for i := 0; i < len(keys); i++ {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	signer := HomesteadSigner{}
+
+	// Generate a batch of transactions with random arrivals, some unknown
+	start := time.Now()
+	arrivals := make(map[common.Hash]time.Time)
+	groups := map[common.Address]Transactions{}
+	for _, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for i := 0; i < 10; i++ {
+			tx, _ := SignTx(NewTransaction(uint64(i), common.Address{}, big.NewInt(100), 100, big.NewInt(1), nil), signer, key)
+			groups[addr] = append(groups[addr], tx)
+			if rand.Intn(10) > 0 {
+				arrivals[tx.Hash()] = start.Add(time.Duration(rand.Intn(1000)) * time.Millisecond)
+			}
+		}
+	}
+	arrival := func(hash common.Hash) (time.Time, bool) {
+		t, ok := arrivals[hash]
+		return t, ok
+	}
+	txset := NewTransactionsByArrivalAndNonce(ctx, signer, groups, arrival)
+
+	txs := Transactions{}
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		txs = append(txs, tx)
+		txset.Shift(ctx)
+	}
+	if len(txs) != 10*10 {
+		t.Errorf("expected %d transactions, found %d", 10*10, len(txs))
+	}
+	for i, txi := range txs {
+		fromi, _ := Sender(ctx, signer, txi)
+
+		// Make sure the nonce order is valid
+		for j, txj := range txs[i+1:] {
+			fromj, _ := Sender(ctx, signer, txj)
+
+			if fromi == fromj && txi.Nonce() > txj.Nonce() {
+				t.Errorf("invalid nonce ordering: tx #%d (A=%x N=%v) < tx #%d (A=%x N=%v)", i, fromi[:4], txi.Nonce(), i+j, fromj[:4], txj.Nonce())
+			}
+		}
+		// If the next tx has different from account, it must not have arrived earlier
+		if i+1 < len(txs) {
+			next := txs[i+1]
+			fromNext, _ := Sender(ctx, signer, next)
+			ti, knowni := arrival(txi.Hash())
+			tn, knownNext := arrival(next.Hash())
+			if fromi != fromNext && ((knowni && knownNext && tn.Before(ti)) || (!knowni && knownNext)) {
+				t.Errorf("invalid arrival ordering: tx #%d (A=%x T=%v) < tx #%d (A=%x T=%v)", i, fromi[:4], ti.Sub(start), i+1, fromNext[:4], tn.Sub(start))
+			}
+		}
+	}
+}
+
+func TestTxOrderCommitment(t *testing.T) {
+	ctx := context.Background()
+	key, _ := crypto.GenerateKey()
+	signer := HomesteadSigner{}
+
+	var txs Transactions
+	for i := 0; i < 3; i++ {
+		tx, _ := SignTx(NewTransaction(uint64(i), common.Address{}, big.NewInt(100), 100, big.NewInt(1), nil), signer, key)
+		txs = append(txs, tx)
+	}
+	// The candidates list every transaction in arrival order, even if the nonce
+	// order holds them back, and the unseen ones last
+	start := time.Unix(1000, 0)
+	arrivals := map[common.Hash]time.Time{txs[0].Hash(): start.Add(time.Second), txs[1].Hash(): start}
+	arrival := func(hash common.Hash) (time.Time, bool) {
+		t, ok := arrivals[hash]
+		return t, ok
+	}
+	from, _ := Sender(ctx, signer, txs[0])
+	set := NewTransactionsByArrivalAndNonce(ctx, signer, map[common.Address]Transactions{from: txs}, arrival)
+
+	candidates := set.Candidates()
+	want := []TxArrival{{txs[1].Hash(), 1000000}, {txs[0].Hash(), 1001000}, {txs[2].Hash(), 0}}
+	if !reflect.DeepEqual(candidates, want) {
+		t.Fatalf("candidates mismatch: have %v, want %v", candidates, want)
+	}
+	commitment := TxOrderCommitment(candidates)
+	if TxOrderCommitment([]TxArrival{want[0], want[1], want[2]}) != commitment {
+		t.Error("commitment not deterministic")
+	}
+	if TxOrderCommitment([]TxArrival{want[1], want[0], want[2]}) == commitment {
+		t.Error("commitment independent of order")
+	}
+	if TxOrderCommitment([]TxArrival{want[0], {txs[0].Hash(), 999000}, want[2]}) == commitment {
+		t.Error("commitment independent of arrival stamps")
+	}
+	if TxOrderCommitment(want[:2]) == commitment {
+		t.Error("commitment independent of contents")
+	}
+}
+
 // TestTransactionJSON tests serializing/de-serializing to/from JSON.
 func TestTransactionJSON(t *testing.T) {
 	key, err := crypto.GenerateKey()
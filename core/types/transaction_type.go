@@ -0,0 +1,184 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/crypto/sha3"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+/*
+Typed transaction envelope
+
+Legacy transactions are encoded as an RLP list of their fields. Typed
+transactions are instead encoded as an envelope of a single type byte followed
+by the RLP encoding of the fields:
+
+	envelope = type || rlp([nonce, gasPrice, gas, to, value, input, v, r, s])
+
+Within blocks and network messages the envelope is carried as an RLP string, so
+that it is never mistaken for a legacy transaction: a legacy encoding always
+starts with a list prefix (>= 0xc0), while types are restricted to 0x01-0x7f.
+The transaction hash and the hash signed by the sender both commit to the type
+byte, so a signed transaction can't be replayed as a transaction of another type.
+
+Types have to be registered with RegisterTxType before transactions of that
+type are decoded or accepted, and are only valid in blocks from the TypedTxBlock
+fork of the chain configuration on.
+*/
+
+// LegacyTxType is the type of transactions not wrapped in an envelope.
+const LegacyTxType = 0x00
+
+// MaxTxType is the highest type a typed transaction can have.
+const MaxTxType = 0x7f
+
+var (
+	// ErrTxTypeNotSupported is returned if a transaction is of an unregistered
+	// type, or of a type not yet activated on the chain.
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+
+	errEmptyTypedTx = errors.New("empty typed transaction bytes")
+)
+
+// TxType describes a type of transactions carried in the typed envelope.
+type TxType struct {
+	Name string
+
+	// Validate performs the type specific checks of a transaction, before it's
+	// accepted into the pool or a block. It may be nil.
+	Validate func(tx *Transaction) error
+}
+
+var (
+	txTypes    = make(map[uint8]TxType)
+	txTypesMux sync.RWMutex
+)
+
+// RegisterTxType registers a transaction type, allowing transactions of that
+// type to be decoded and validated. It panics if the type is out of range or
+// registered already.
+func RegisterTxType(typ uint8, t TxType) {
+	if typ == LegacyTxType || typ > MaxTxType {
+		panic(fmt.Sprintf("invalid transaction type %#x", typ))
+	}
+	txTypesMux.Lock()
+	defer txTypesMux.Unlock()
+
+	if _, ok := txTypes[typ]; ok {
+		panic(fmt.Sprintf("transaction type %#x registered twice", typ))
+	}
+	txTypes[typ] = t
+}
+
+// LookupTxType returns the description of a registered transaction type.
+func LookupTxType(typ uint8) (TxType, bool) {
+	if typ == LegacyTxType {
+		return TxType{Name: "legacy"}, true
+	}
+	txTypesMux.RLock()
+	defer txTypesMux.RUnlock()
+
+	t, ok := txTypes[typ]
+	return t, ok
+}
+
+// ValidateTxType checks that a transaction is of a registered type, and
+// passes the checks specific to that type.
+func ValidateTxType(tx *Transaction) error {
+	t, ok := LookupTxType(tx.typ)
+	if !ok {
+		return ErrTxTypeNotSupported
+	}
+	if t.Validate != nil {
+		return t.Validate(tx)
+	}
+	return nil
+}
+
+// prefixedRlpHash hashes the RLP encoding of x, prefixed by a type byte.
+func prefixedRlpHash(prefix byte, x interface{}) (h common.Hash) {
+	hw := sha3.NewKeccak256SingleSum()
+	hw.Write([]byte{prefix})
+	rlp.Encode(hw, x)
+	hw.Sum(h[:0])
+	return h
+}
+
+// encodeTyped returns the envelope of a typed transaction.
+func (tx *Transaction) encodeTyped() ([]byte, error) {
+	payload, err := rlp.EncodeToBytes(&tx.data)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{tx.typ}, payload...), nil
+}
+
+// decodeTyped decodes the envelope of a typed transaction.
+func (tx *Transaction) decodeTyped(b []byte) error {
+	if len(b) == 0 {
+		return errEmptyTypedTx
+	}
+	typ := b[0]
+	if typ == LegacyTxType || typ > MaxTxType {
+		return fmt.Errorf("invalid transaction type %#x", typ)
+	}
+	if _, ok := LookupTxType(typ); !ok {
+		return ErrTxTypeNotSupported
+	}
+	var data txdata
+	if err := rlp.DecodeBytes(b[1:], &data); err != nil {
+		return err
+	}
+	tx.typ, tx.data = typ, data
+	return nil
+}
+
+// MarshalBinary returns the canonical encoding of the transaction: the RLP
+// list of its fields for legacy transactions, or the envelope for typed ones.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.typ == LegacyTxType {
+		return rlp.EncodeToBytes(&tx.data)
+	}
+	return tx.encodeTyped()
+}
+
+// UnmarshalBinary decodes the canonical encoding of a transaction. For
+// compatibility, typed transactions wrapped in an RLP string are accepted too.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > MaxTxType {
+		// Legacy transaction or RLP wrapped envelope
+		var dec Transaction
+		if err := rlp.DecodeBytes(b, &dec); err != nil {
+			return err
+		}
+		*tx = dec
+		return nil
+	}
+	var dec Transaction
+	if err := dec.decodeTyped(b); err != nil {
+		return err
+	}
+	dec.size.Store(common.StorageSize(rlp.ListSize(uint64(len(b)))))
+	*tx = dec
+	return nil
+}
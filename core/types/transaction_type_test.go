@@ -0,0 +1,164 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const testTxType = 0x7f
+
+var errTestTxType = errors.New("test transaction without data")
+
+func init() {
+	RegisterTxType(testTxType, TxType{
+		Name: "test",
+		Validate: func(tx *Transaction) error {
+			if len(tx.Data()) == 0 {
+				return errTestTxType
+			}
+			return nil
+		},
+	})
+}
+
+func signedTypedTx(t *testing.T, typ uint8) (*Transaction, common.Address) {
+	key, _ := crypto.GenerateKey()
+	to := common.HexToAddress("b94f5374fce5edbc8e2a8697c15331677e6ebf0b")
+	tx := NewTypedTransaction(typ, 3, &to, big.NewInt(10), 2000, big.NewInt(1), common.FromHex("5544"))
+	signed, err := SignTx(tx, NewEIP155Signer(big.NewInt(1)), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return signed, crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func TestTypedTransactionEncoding(t *testing.T) {
+	tx, from := signedTypedTx(t, testTxType)
+
+	// The envelope is carried as an RLP string, and hashed with the type prefix
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	var envelope []byte
+	if err := rlp.DecodeBytes(enc, &envelope); err != nil {
+		t.Fatalf("typed transaction not encoded as RLP string: %v", err)
+	}
+	if envelope[0] != testTxType {
+		t.Fatalf("envelope type mismatch: have %#x, want %#x", envelope[0], testTxType)
+	}
+	if hash := crypto.Keccak256Hash(envelope); tx.Hash() != hash {
+		t.Errorf("hash mismatch: have %x, want %x", tx.Hash(), hash)
+	}
+	if tx.Size() != common.StorageSize(len(enc)) {
+		t.Errorf("size mismatch: have %v, want %d", tx.Size(), len(enc))
+	}
+	binary, err := tx.MarshalBinary()
+	if err != nil || !bytes.Equal(binary, envelope) {
+		t.Errorf("binary encoding mismatch: have %x (%v), want %x", binary, err, envelope)
+	}
+	// Decoding from either encoding yields the same transaction and sender
+	for _, input := range [][]byte{enc, envelope} {
+		var dec Transaction
+		if err := dec.UnmarshalBinary(input); err != nil {
+			t.Fatalf("failed to decode %x: %v", input, err)
+		}
+		if dec.Type() != testTxType || dec.Hash() != tx.Hash() {
+			t.Errorf("decoded transaction mismatch: type %#x, hash %x", dec.Type(), dec.Hash())
+		}
+		if sender, err := Sender(context.Background(), NewEIP155Signer(big.NewInt(1)), &dec); err != nil || sender != from {
+			t.Errorf("sender mismatch: have %x (%v), want %x", sender, err, from)
+		}
+	}
+	// Legacy transactions keep their encoding
+	legacy, err := rightvrsTx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode legacy transaction: %v", err)
+	}
+	if want, _ := rlp.EncodeToBytes(rightvrsTx); !bytes.Equal(legacy, want) {
+		t.Errorf("legacy encoding mismatch: have %x, want %x", legacy, want)
+	}
+}
+
+func TestTypedTransactionSignature(t *testing.T) {
+	tx, _ := signedTypedTx(t, testTxType)
+
+	// The signature doesn't recover to the same sender if the type is changed
+	signer := NewEIP155Signer(big.NewInt(1))
+	v, r, s := tx.RawSignatureValues()
+	legacy := NewTransaction(tx.Nonce(), *tx.To(), tx.Value(), tx.Gas(), tx.GasPrice(), tx.Data())
+	legacy.data.V, legacy.data.R, legacy.data.S = v, r, s
+
+	have, _ := Sender(context.Background(), signer, legacy)
+	want, _ := Sender(context.Background(), signer, tx)
+	if have == want {
+		t.Errorf("typed signature valid for legacy transaction")
+	}
+}
+
+func TestTypedTransactionUnsupported(t *testing.T) {
+	tx, _ := signedTypedTx(t, 0x42)
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	var dec Transaction
+	if err := dec.UnmarshalBinary(enc); err != ErrTxTypeNotSupported {
+		t.Errorf("unregistered type decoding error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	if err := ValidateTxType(tx); err != ErrTxTypeNotSupported {
+		t.Errorf("unregistered type validation error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	empty := NewTypedTransaction(testTxType, 0, nil, nil, 0, nil, nil)
+	if err := ValidateTxType(empty); err != errTestTxType {
+		t.Errorf("type check error mismatch: have %v, want %v", err, errTestTxType)
+	}
+	if err := ValidateTxType(rightvrsTx); err != nil {
+		t.Errorf("legacy transaction rejected: %v", err)
+	}
+}
+
+func TestTypedTransactionJSON(t *testing.T) {
+	tx, _ := signedTypedTx(t, testTxType)
+
+	data, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatalf("failed to marshal transaction: %v", err)
+	}
+	var dec Transaction
+	if err := json.Unmarshal(data, &dec); err != nil {
+		t.Fatalf("failed to unmarshal transaction: %v", err)
+	}
+	if dec.Type() != testTxType || dec.Hash() != tx.Hash() {
+		t.Errorf("decoded transaction mismatch: type %#x, hash %x", dec.Type(), dec.Hash())
+	}
+	// Legacy transactions don't carry a type field
+	legacy, _ := json.Marshal(rightvrsTx)
+	if bytes.Contains(legacy, []byte(`"type"`)) {
+		t.Errorf("legacy transaction JSON has type: %s", legacy)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/math"
 	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/crypto/bls"
 	"github.com/fulcrumchain/indigo/crypto/bn256"
 	"github.com/fulcrumchain/indigo/params"
 	"golang.org/x/crypto/ripemd160"
@@ -362,3 +363,54 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+// errBadBLSInput is returned if the BLS aggregate verification input is invalid.
+var errBadBLSInput = errors.New("bad BLS aggregate verification input")
+
+func init() {
+	if err := RegisterPrecompile("blsAggregateVerify", &blsAggregateVerify{}); err != nil {
+		panic(err)
+	}
+}
+
+// blsAggregateVerify implements an aggregate BLS12-381 signature verification
+// pre-compile, taking a 32 byte message, a 96 byte signature and any number of
+// 192 byte public keys. The keys must have proven possession of their secret
+// keys, which is up to the calling contract to ensure.
+type blsAggregateVerify struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract.
+func (c *blsAggregateVerify) RequiredGas(input []byte) uint64 {
+	keys := 0
+	if len(input) > 32+bls.SignatureLength {
+		keys = (len(input) - 32 - bls.SignatureLength) / bls.PublicKeyLength
+	}
+	return params.BLSAggregateVerifyBaseGas + uint64(keys)*params.BLSAggregateVerifyPerKeyGas
+}
+
+func (c *blsAggregateVerify) Run(input []byte) ([]byte, error) {
+	// Handle some corner cases cheaply
+	if len(input) <= 32+bls.SignatureLength || (len(input)-32-bls.SignatureLength)%bls.PublicKeyLength > 0 {
+		return nil, errBadBLSInput
+	}
+	msg, input := input[:32], input[32:]
+
+	sig, err := bls.SignatureFromBytes(input[:bls.SignatureLength])
+	if err != nil {
+		return nil, err
+	}
+	input = input[bls.SignatureLength:]
+
+	var pks []*bls.PublicKey
+	for i := 0; i < len(input); i += bls.PublicKeyLength {
+		pk, err := bls.PublicKeyFromBytes(input[i : i+bls.PublicKeyLength])
+		if err != nil {
+			return nil, err
+		}
+		pks = append(pks, pk)
+	}
+	if bls.FastAggregateVerify(pks, msg, sig) {
+		return true32Byte, nil
+	}
+	return false32Byte, nil
+}
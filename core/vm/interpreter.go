@@ -41,6 +41,8 @@ type Config struct {
 	DisableGasMetering bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// Stats aggregates opcode and contract execution statistics if set
+	Stats *Stats
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -134,9 +136,20 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		pcCopy  uint64 // needed for the deferred Tracer
 		gasCopy uint64 // for Tracer to log gas remaining before execution
 		logged  bool   // deferred Tracer should ignore already logged steps
+		// totals of the opcodes executed for the Stats
+		statOps, statGas uint64
 	)
 	contract.Input = input
 
+	if in.cfg.Stats != nil {
+		defer func() {
+			addr := contract.Address()
+			if contract.CodeAddr != nil {
+				addr = *contract.CodeAddr
+			}
+			in.cfg.Stats.recordCall(addr, statOps, statGas)
+		}()
+	}
 	if in.cfg.Debug {
 		defer func() {
 			if err != nil {
@@ -199,6 +212,19 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		if memorySize > 0 {
 			mem.Resize(memorySize)
 		}
+		if in.cfg.Stats != nil {
+			// the gas forwarded to nested calls is accounted to the callee
+			opGas := cost
+			switch op {
+			case CALL, CALLCODE, DELEGATECALL, STATICCALL:
+				if !in.cfg.DisableGasMetering {
+					opGas -= in.evm.callGasTemp
+				}
+			}
+			in.cfg.Stats.recordOp(op, opGas)
+			statOps++
+			statGas += opGas
+		}
 
 		if in.cfg.Debug {
 			in.cfg.Tracer.CaptureState(in.evm, pc, op, gasCopy, cost, mem, stack, contract, in.evm.depth, err)
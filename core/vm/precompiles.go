@@ -0,0 +1,96 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]PrecompiledContract)
+)
+
+// RegisterPrecompile makes a precompiled contract available to chains under the
+// given name. Chains activate it at an address and block of their choice via
+// params.ChainConfig.Precompiles. Registration is meant to happen when the
+// implementing package is initialised, before any chain is set up.
+func RegisterPrecompile(name string, p PrecompiledContract) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("precompile name is empty")
+	}
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("precompile %s already registered", name)
+	}
+	registry[name] = p
+	return nil
+}
+
+// CheckPrecompiles verifies that all extra precompiles of the chain config are
+// well formed, registered, and do not shadow the builtin contracts.
+func CheckPrecompiles(config *params.ChainConfig) error {
+	if err := config.CheckPrecompiles(); err != nil {
+		return err
+	}
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	for _, p := range config.Precompiles {
+		if _, ok := registry[p.Name]; !ok {
+			return fmt.Errorf("precompile %s not registered", p.Name)
+		}
+		if PrecompiledContractsByzantium[p.Address] != nil {
+			return fmt.Errorf("precompile %s shadows builtin contract at %x", p.Name, p.Address)
+		}
+	}
+	return nil
+}
+
+// ActivePrecompiles returns the precompiled contracts active at the given block,
+// the builtin ones of the current fork plus the extra ones the chain enabled.
+// The returned map must not be modified.
+func ActivePrecompiles(config *params.ChainConfig, num *big.Int) map[common.Address]PrecompiledContract {
+	precompiles := PrecompiledContractsHomestead
+	if config.IsByzantium(num) {
+		precompiles = PrecompiledContractsByzantium
+	}
+	extra := config.ActivePrecompiles(num)
+	if len(extra) == 0 {
+		return precompiles
+	}
+	active := make(map[common.Address]PrecompiledContract, len(precompiles)+len(extra))
+	for addr, p := range precompiles {
+		active[addr] = p
+	}
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	for _, p := range extra {
+		if contract, ok := registry[p.Name]; ok {
+			active[p.Address] = contract
+		}
+	}
+	return active
+}
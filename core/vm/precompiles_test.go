@@ -0,0 +1,189 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/crypto/bls"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// reverse is a test precompile returning its input reversed.
+type reverse struct{}
+
+func (reverse) RequiredGas(input []byte) uint64 { return 100 }
+
+func (reverse) Run(input []byte) ([]byte, error) {
+	out := make([]byte, len(input))
+	for i, b := range input {
+		out[len(input)-1-i] = b
+	}
+	return out, nil
+}
+
+func init() {
+	if err := RegisterPrecompile("test-reverse", reverse{}); err != nil {
+		panic(err)
+	}
+}
+
+func TestRegisterPrecompile(t *testing.T) {
+	if err := RegisterPrecompile("test-reverse", reverse{}); err == nil {
+		t.Errorf("duplicate registration accepted")
+	}
+	if err := RegisterPrecompile("", reverse{}); err == nil {
+		t.Errorf("unnamed registration accepted")
+	}
+}
+
+func TestCheckPrecompiles(t *testing.T) {
+	tests := []struct {
+		precompiles []*params.PrecompileConfig
+		ok          bool
+	}{
+		{[]*params.PrecompileConfig{{Name: "test-reverse", Address: common.Address{0x01, 0x00}, Block: big.NewInt(0)}}, true},
+		{[]*params.PrecompileConfig{{Name: "test-unknown", Address: common.Address{0x01, 0x00}, Block: big.NewInt(0)}}, false},
+		{[]*params.PrecompileConfig{{Name: "test-reverse", Address: common.BytesToAddress([]byte{1}), Block: big.NewInt(0)}}, false},
+		{[]*params.PrecompileConfig{{Name: "test-reverse", Address: common.Address{0x01, 0x00}}}, false},
+		{[]*params.PrecompileConfig{
+			{Name: "test-reverse", Address: common.Address{0x01, 0x00}, Block: big.NewInt(0)},
+			{Name: "test-reverse", Address: common.Address{0x01, 0x00}, Block: big.NewInt(5)},
+		}, false},
+	}
+	for i, tt := range tests {
+		config := *params.TestChainConfig
+		config.Precompiles = tt.precompiles
+		if err := CheckPrecompiles(&config); (err == nil) != tt.ok {
+			t.Errorf("test %d: error mismatch: have %v, want ok %t", i, err, tt.ok)
+		}
+	}
+}
+
+func TestPrecompileActivation(t *testing.T) {
+	addr := common.BytesToAddress([]byte{0x01, 0x00})
+	config := *params.TestChainConfig
+	config.Precompiles = []*params.PrecompileConfig{{Name: "test-reverse", Address: addr, Block: big.NewInt(5)}}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	input := []byte{1, 2, 3}
+	for _, tt := range []struct {
+		block int64
+		want  []byte
+	}{
+		{4, nil},
+		{5, []byte{3, 2, 1}},
+	} {
+		if _, ok := ActivePrecompiles(&config, big.NewInt(tt.block))[addr]; ok != (tt.want != nil) {
+			t.Errorf("block %d: activation mismatch: have %t", tt.block, ok)
+		}
+		ctx := Context{
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+			BlockNumber: big.NewInt(tt.block),
+		}
+		evm := NewEVM(ctx, statedb, &config, Config{})
+		ret, gas, err := evm.Call(AccountRef(common.Address{}), addr, input, 1000, new(big.Int))
+		if err != nil {
+			t.Fatalf("block %d: call failed: %v", tt.block, err)
+		}
+		if !bytes.Equal(ret, tt.want) {
+			t.Errorf("block %d: output mismatch: have %x, want %x", tt.block, ret, tt.want)
+		}
+		if tt.want != nil && gas != 900 {
+			t.Errorf("block %d: gas mismatch: have %d, want %d", tt.block, gas, 900)
+		}
+	}
+	// The builtin contracts must remain untouched by the activation.
+	if _, ok := PrecompiledContractsByzantium[addr]; ok {
+		t.Errorf("builtin precompiles modified")
+	}
+}
+
+func TestBLSAggregateVerify(t *testing.T) {
+	registryLock.RLock()
+	p := registry["blsAggregateVerify"]
+	registryLock.RUnlock()
+	if p == nil {
+		t.Fatal("blsAggregateVerify not registered")
+	}
+	input := blsAggregateInput(t, 3)
+	if gas := p.RequiredGas(input); gas != params.BLSAggregateVerifyBaseGas+3*params.BLSAggregateVerifyPerKeyGas {
+		t.Errorf("gas mismatch: have %d, want %d", gas, params.BLSAggregateVerifyBaseGas+3*params.BLSAggregateVerifyPerKeyGas)
+	}
+	if res, err := p.Run(input); err != nil || !bytes.Equal(res, true32Byte) {
+		t.Errorf("valid aggregate rejected: %x, %v", res, err)
+	}
+	// Dropping a key must invalidate the aggregate
+	if res, err := p.Run(input[:len(input)-bls.PublicKeyLength]); err != nil || !bytes.Equal(res, false32Byte) {
+		t.Errorf("aggregate with missing key accepted: %x, %v", res, err)
+	}
+	// Malformed inputs must be rejected outright
+	if _, err := p.Run(input[:32+bls.SignatureLength]); err == nil {
+		t.Error("input without keys accepted")
+	}
+	if _, err := p.Run(input[:len(input)-1]); err == nil {
+		t.Error("truncated input accepted")
+	}
+}
+
+// blsAggregateInput returns the input of the BLS aggregate verification of a
+// message signed by n keys.
+func blsAggregateInput(t testing.TB, n int) []byte {
+	msg := common.HexToHash("0xc0ffee").Bytes()
+
+	var (
+		sigs []*bls.Signature
+		pubs []byte
+	)
+	for i := 1; i <= n; i++ {
+		sk, err := bls.SecretKeyFromBytes(common.LeftPadBytes([]byte{byte(i >> 8), byte(i)}, bls.SecretKeyLength))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sk.Sign(msg))
+		pubs = append(pubs, sk.PublicKey().Bytes()...)
+	}
+	sig := bls.Aggregate(sigs).Bytes()
+
+	return append(append(append([]byte{}, msg...), sig...), pubs...)
+}
+
+// Benchmarks the BLS aggregate verification with growing key counts, which the
+// base and per key gas prices are derived from.
+func BenchmarkPrecompiledBLSAggregateVerify(b *testing.B) {
+	registryLock.RLock()
+	p := registry["blsAggregateVerify"]
+	registryLock.RUnlock()
+
+	for _, n := range []int{1, 16, 128} {
+		input := blsAggregateInput(b, n)
+		b.Run(fmt.Sprintf("keys=%d-Gas=%d", n, p.RequiredGas(input)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if res, err := p.Run(input); err != nil || !bytes.Equal(res, true32Byte) {
+					b.Fatalf("valid aggregate rejected: %x, %v", res, err)
+				}
+			}
+		})
+	}
+}
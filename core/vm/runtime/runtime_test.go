@@ -116,6 +116,65 @@ func TestCall(t *testing.T) {
 	}
 }
 
+func TestCallStats(t *testing.T) {
+	state, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	caller, callee := common.HexToAddress("0x0a"), common.HexToAddress("0x0b")
+	state.SetCode(caller, []byte{
+		byte(vm.PUSH1), 0, // out size
+		byte(vm.PUSH1), 0, // out offset
+		byte(vm.PUSH1), 0, // in size
+		byte(vm.PUSH1), 0, // in offset
+		byte(vm.PUSH1), 0, // value
+		byte(vm.PUSH1), 0x0b,
+		byte(vm.PUSH2), 0xff, 0xff, // gas
+		byte(vm.CALL),
+		byte(vm.STOP),
+	})
+	state.SetCode(callee, []byte{
+		byte(vm.PUSH1), 10,
+		byte(vm.PUSH1), 0,
+		byte(vm.MSTORE),
+		byte(vm.STOP),
+	})
+	stats := vm.NewStats()
+	if _, _, err := Call(caller, nil, &Config{State: state, EVMConfig: vm.Config{Stats: stats}}); err != nil {
+		t.Fatal("didn't expect error", err)
+	}
+	report := stats.Report(10)
+
+	ops := make(map[string]*vm.OpStats)
+	for _, op := range report.Ops {
+		ops[op.Op] = op
+	}
+	if ops["PUSH1"] == nil || ops["PUSH1"].Count != 8 || ops["PUSH1"].Gas != 24 {
+		t.Errorf("PUSH1 stats mismatch: %+v", ops["PUSH1"])
+	}
+	if ops["MSTORE"] == nil || ops["MSTORE"].Count != 1 || ops["MSTORE"].Gas != 6 {
+		t.Errorf("MSTORE stats mismatch: %+v", ops["MSTORE"])
+	}
+	// The gas forwarded by the call is accounted to the callee only
+	if ops["CALL"] == nil || ops["CALL"].Count != 1 || ops["CALL"].Gas >= 0xffff {
+		t.Errorf("CALL stats mismatch: %+v", ops["CALL"])
+	}
+	if len(report.Contracts) != 2 {
+		t.Fatalf("contract count mismatch: have %d, want 2", len(report.Contracts))
+	}
+	contracts := make(map[common.Address]*vm.ContractStats)
+	for _, contract := range report.Contracts {
+		contracts[contract.Address] = contract
+	}
+	if c := contracts[callee]; c == nil || c.Calls != 1 || c.Ops != 4 || c.Gas != 12 {
+		t.Errorf("callee stats mismatch: %+v", c)
+	}
+	if c := contracts[caller]; c == nil || c.Calls != 1 || c.Ops != 9 || c.Gas != 18+ops["CALL"].Gas+3 {
+		t.Errorf("caller stats mismatch: %+v", c)
+	}
+	stats.Reset()
+	if report := stats.Report(10); len(report.Ops) != 0 || len(report.Contracts) != 0 {
+		t.Errorf("stats not reset: %+v", report)
+	}
+}
+
 func BenchmarkCall(b *testing.B) {
 	var definition = `[{"constant":true,"inputs":[],"name":"seller","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"abort","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"value","outputs":[{"name":"","type":"uint256"}],"type":"function"},{"constant":false,"inputs":[],"name":"refund","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"buyer","outputs":[{"name":"","type":"address"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmReceived","outputs":[],"type":"function"},{"constant":true,"inputs":[],"name":"state","outputs":[{"name":"","type":"uint8"}],"type":"function"},{"constant":false,"inputs":[],"name":"confirmPurchase","outputs":[],"type":"function"},{"inputs":[],"type":"constructor"},{"anonymous":false,"inputs":[],"name":"Aborted","type":"event"},{"anonymous":false,"inputs":[],"name":"PurchaseConfirmed","type":"event"},{"anonymous":false,"inputs":[],"name":"ItemReceived","type":"event"},{"anonymous":false,"inputs":[],"name":"Refunded","type":"event"}]`
 
@@ -0,0 +1,185 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// maxStatsContracts is the number of contracts whose execution is tracked by
+// Stats. Once exceeded, the half of the contracts consuming the least gas is
+// forgotten.
+const maxStatsContracts = 16384
+
+// opCounters are the execution counters of an opcode.
+type opCounters struct {
+	count uint64
+	gas   uint64
+
+	countMeter gometrics.Counter
+	gasMeter   gometrics.Counter
+}
+
+// Stats aggregates the number of executions and the gas consumption of the
+// opcodes and contracts run by the interpreters configured with it. The gas of
+// calls is accounted to the callee, not to the call opcode of the caller.
+//
+// Stats are safe for concurrent use by parallel interpreters.
+type Stats struct {
+	ops [256]opCounters
+
+	contracts map[common.Address]*ContractStats
+	since     time.Time
+	lock      sync.Mutex
+}
+
+// NewStats creates an empty set of execution statistics, which are also
+// exported as metrics if the metrics system is enabled.
+func NewStats() *Stats {
+	s := &Stats{
+		contracts: make(map[common.Address]*ContractStats),
+		since:     time.Now(),
+	}
+	for i := range s.ops {
+		name := OpCode(i).String()
+		s.ops[i].countMeter = metrics.NewCounter("vm/op/" + name + "/count")
+		s.ops[i].gasMeter = metrics.NewCounter("vm/op/" + name + "/gas")
+	}
+	return s
+}
+
+// recordOp accounts an execution of op consuming gas.
+func (s *Stats) recordOp(op OpCode, gas uint64) {
+	counters := &s.ops[op]
+	atomic.AddUint64(&counters.count, 1)
+	atomic.AddUint64(&counters.gas, gas)
+
+	counters.countMeter.Inc(1)
+	counters.gasMeter.Inc(int64(gas))
+}
+
+// recordCall accounts a run of the code of a contract, executing ops opcodes
+// consuming gas.
+func (s *Stats) recordCall(addr common.Address, ops, gas uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stats, ok := s.contracts[addr]
+	if !ok {
+		if len(s.contracts) >= maxStatsContracts {
+			s.prune()
+		}
+		stats = &ContractStats{Address: addr}
+		s.contracts[addr] = stats
+	}
+	stats.Calls++
+	stats.Ops += ops
+	stats.Gas += gas
+}
+
+// prune forgets the half of the tracked contracts consuming the least gas.
+func (s *Stats) prune() {
+	contracts := make([]*ContractStats, 0, len(s.contracts))
+	for _, stats := range s.contracts {
+		contracts = append(contracts, stats)
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].Gas < contracts[j].Gas })
+	for _, stats := range contracts[:len(contracts)/2] {
+		delete(s.contracts, stats.Address)
+	}
+}
+
+// OpStats are the execution statistics of an opcode.
+type OpStats struct {
+	Op    string `json:"op"`
+	Count uint64 `json:"count"`
+	Gas   uint64 `json:"gas"`
+}
+
+// ContractStats are the execution statistics of the code of a contract.
+type ContractStats struct {
+	Address common.Address `json:"address"`
+	Calls   uint64         `json:"calls"` // Number of times the code was run
+	Ops     uint64         `json:"ops"`   // Number of opcodes executed
+	Gas     uint64         `json:"gas"`   // Gas consumed, excluding nested calls
+}
+
+// StatsReport is a snapshot of the execution statistics.
+type StatsReport struct {
+	Since     time.Time        `json:"since"`
+	Ops       []*OpStats       `json:"ops"`       // Opcodes by decreasing gas consumption
+	Contracts []*ContractStats `json:"contracts"` // Contracts by decreasing gas consumption
+}
+
+// Report returns the statistics of the executed opcodes and of the top
+// contracts consuming the most gas.
+func (s *Stats) Report(top int) *StatsReport {
+	report := &StatsReport{
+		Ops:       make([]*OpStats, 0),
+		Contracts: make([]*ContractStats, 0),
+	}
+	for i := range s.ops {
+		count := atomic.LoadUint64(&s.ops[i].count)
+		if count == 0 {
+			continue
+		}
+		report.Ops = append(report.Ops, &OpStats{
+			Op:    OpCode(i).String(),
+			Count: count,
+			Gas:   atomic.LoadUint64(&s.ops[i].gas),
+		})
+	}
+	sort.SliceStable(report.Ops, func(i, j int) bool { return report.Ops[i].Gas > report.Ops[j].Gas })
+
+	s.lock.Lock()
+	report.Since = s.since
+	for _, stats := range s.contracts {
+		copy := *stats
+		report.Contracts = append(report.Contracts, &copy)
+	}
+	s.lock.Unlock()
+
+	sort.Slice(report.Contracts, func(i, j int) bool {
+		if report.Contracts[i].Gas != report.Contracts[j].Gas {
+			return report.Contracts[i].Gas > report.Contracts[j].Gas
+		}
+		return report.Contracts[i].Calls > report.Contracts[j].Calls
+	})
+	if len(report.Contracts) > top {
+		report.Contracts = report.Contracts[:top]
+	}
+	return report
+}
+
+// Reset clears the statistics gathered so far. Exported metrics are not reset.
+func (s *Stats) Reset() {
+	for i := range s.ops {
+		atomic.StoreUint64(&s.ops[i].count, 0)
+		atomic.StoreUint64(&s.ops[i].gas, 0)
+	}
+	s.lock.Lock()
+	s.contracts = make(map[common.Address]*ContractStats)
+	s.since = time.Now()
+	s.lock.Unlock()
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// BlockWitness is everything needed to execute a block without holding the
+// state: the headers, trie nodes and contract code its execution reads. The
+// trie nodes are verified against the state root of the parent while they are
+// resolved, and the block against its own state root once executed.
+type BlockWitness struct {
+	Block   *types.Block
+	Headers []*types.Header // Parent first, then the ancestors read by BLOCKHASH
+	Nodes   [][]byte        // Trie nodes of the accounts and storage slots read
+	Codes   [][]byte        // Contract code read
+}
+
+// BlockWitness executes a block on top of its parent state, collecting the
+// headers, trie nodes and contract code it reads.
+func (bc *BlockChain) BlockWitness(ctx context.Context, block *types.Block) (*BlockWitness, error) {
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	witness := state.NewWitness()
+	statedb, err := state.New(parent.Root(), state.NewWitnessDatabase(bc.stateCache, witness))
+	if err != nil {
+		return nil, fmt.Errorf("parent state not available: %v", err)
+	}
+	chain := &witnessChain{
+		ChainReader: bc,
+		config:      bc.chainConfig,
+		engine:      bc.engine,
+		headers:     map[common.Hash]*types.Header{parent.Hash(): parent.Header()},
+	}
+	root, err := executeWitness(ctx, bc.chainConfig, bc.vmConfig, chain, block, statedb)
+	if err != nil {
+		return nil, err
+	}
+	if root != block.Root() {
+		return nil, &StateRootError{Number: block.Number(), Remote: block.Root(), Local: root}
+	}
+	headers := make([]*types.Header, 0, len(chain.headers))
+	for _, header := range chain.headers {
+		headers = append(headers, header)
+	}
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Number.Cmp(headers[j].Number) > 0
+	})
+	return &BlockWitness{
+		Block:   block,
+		Headers: headers,
+		Nodes:   witness.Nodes(),
+		Codes:   witness.Codes(),
+	}, nil
+}
+
+// VerifyBlockWitness executes the block of a witness using only the state and
+// headers it carries, checking the resulting state root against the block.
+func VerifyBlockWitness(ctx context.Context, config *params.ChainConfig, engine consensus.Engine, w *BlockWitness) error {
+	if len(w.Headers) == 0 || w.Headers[0].Hash() != w.Block.ParentHash() {
+		return fmt.Errorf("witness lacks parent %x", w.Block.ParentHash())
+	}
+	db := ethdb.NewMemDatabase()
+	for _, blob := range append(append([][]byte{}, w.Nodes...), w.Codes...) {
+		db.Put(crypto.Keccak256(blob), blob)
+	}
+	statedb, err := state.New(w.Headers[0].Root, state.NewDatabase(db))
+	if err != nil {
+		return fmt.Errorf("parent state not in witness: %v", err)
+	}
+	chain := &witnessChain{config: config, engine: engine, headers: make(map[common.Hash]*types.Header)}
+	for _, header := range w.Headers {
+		chain.headers[header.Hash()] = header
+	}
+	root, err := executeWitness(ctx, config, vm.Config{}, chain, w.Block, statedb)
+	if err != nil {
+		return err
+	}
+	if root != w.Block.Root() {
+		return &StateRootError{Number: w.Block.Number(), Remote: w.Block.Root(), Local: root}
+	}
+	return nil
+}
+
+// executeWitness applies the transactions of a block and finalises it, returning
+// the resulting state root. State missing from a witness reads as empty, so it
+// surfaces as a state root mismatch.
+func executeWitness(ctx context.Context, config *params.ChainConfig, cfg vm.Config, chain *witnessChain, block *types.Block, statedb *state.StateDB) (common.Hash, error) {
+	var (
+		header     = block.Header()
+		signer     = types.MakeSigner(config, header.Number)
+		gp         = new(GasPool).AddGas(block.GasLimit())
+		usedGas    = new(uint64)
+		receipts   types.Receipts
+		evmContext = NewEVMContextLite(header, chain, nil)
+	)
+	for i, tx := range block.Transactions() {
+		vmenv := vm.NewEVM(evmContext, statedb, config, cfg)
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		receipt, _, err := ApplyTransaction(ctx, vmenv, config, gp, statedb, header, tx, usedGas, signer)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		receipts = append(receipts, receipt)
+	}
+	chain.engine.Finalize(ctx, chain, header, statedb, block.Transactions(), receipts, false)
+	return statedb.IntermediateRoot(config.IsEIP158(header.Number)), nil
+}
+
+// witnessChain serves the headers of a witness, either recording those read
+// from a full chain or serving the recorded ones alone.
+type witnessChain struct {
+	consensus.ChainReader // Chain read through, nil when verifying
+
+	config  *params.ChainConfig
+	engine  consensus.Engine
+	headers map[common.Hash]*types.Header
+}
+
+func (c *witnessChain) Config() *params.ChainConfig { return c.config }
+func (c *witnessChain) Engine() consensus.Engine    { return c.engine }
+
+func (c *witnessChain) CurrentHeader() *types.Header {
+	if c.ChainReader != nil {
+		return c.ChainReader.CurrentHeader()
+	}
+	return nil
+}
+
+func (c *witnessChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if c.ChainReader == nil {
+		if header := c.headers[hash]; header != nil && header.Number.Uint64() == number {
+			return header
+		}
+		return nil
+	}
+	header := c.ChainReader.GetHeader(hash, number)
+	if header != nil {
+		c.headers[hash] = header
+	}
+	return header
+}
+
+func (c *witnessChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if c.ChainReader == nil {
+		return c.headers[hash]
+	}
+	header := c.ChainReader.GetHeaderByHash(hash)
+	if header != nil {
+		c.headers[hash] = header
+	}
+	return header
+}
+
+func (c *witnessChain) GetHeaderByNumber(number uint64) *types.Header {
+	if c.ChainReader == nil {
+		for _, header := range c.headers {
+			if header.Number.Uint64() == number {
+				return header
+			}
+		}
+		return nil
+	}
+	header := c.ChainReader.GetHeaderByNumber(number)
+	if header != nil {
+		c.headers[header.Hash()] = header
+	}
+	return header
+}
+
+func (c *witnessChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if c.ChainReader == nil {
+		return nil
+	}
+	return c.ChainReader.GetBlock(hash, number)
+}
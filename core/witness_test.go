@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+// Tests that the witness of a block is enough to execute it without the state,
+// and that an incomplete witness fails verification.
+func TestBlockWitness(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db       = ethdb.NewMemDatabase()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xaa}
+		gspec    = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address:  {Balance: big.NewInt(1000000000)},
+				contract: {Balance: new(big.Int), Code: []byte{0x60, 0x01, 0x60, 0x00, 0x55}}, // sstore(0, 1)
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		engine  = clique.NewFaker()
+		signer  = types.HomesteadSigner{}
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := GenerateChain(ctx, gspec.Config, genesis, engine, db, 2, func(ctx context.Context, i int, block *BlockGen) {
+		transfer, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i + 1)}, big.NewInt(1000), 21000, new(big.Int), nil), signer, key)
+		block.AddTx(ctx, transfer)
+		if i == 1 {
+			call, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, new(big.Int), nil), signer, key)
+			block.AddTx(ctx, call)
+		}
+	})
+	if _, err := blockchain.InsertChain(ctx, blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	witness, err := blockchain.BlockWitness(ctx, blocks[1])
+	if err != nil {
+		t.Fatalf("failed to collect witness: %v", err)
+	}
+	if len(witness.Headers) == 0 || witness.Headers[0].Hash() != blocks[0].Hash() {
+		t.Fatalf("witness lacks parent header")
+	}
+	if len(witness.Codes) != 1 {
+		t.Fatalf("code count mismatch: have %d, want 1", len(witness.Codes))
+	}
+	// The witness survives encoding and verifies on its own
+	enc, err := rlp.EncodeToBytes(witness)
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	decoded := new(BlockWitness)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatalf("failed to decode witness: %v", err)
+	}
+	if err := VerifyBlockWitness(ctx, gspec.Config, engine, decoded); err != nil {
+		t.Fatalf("failed to verify witness: %v", err)
+	}
+	// Incomplete witnesses are rejected
+	nocode := *decoded
+	nocode.Codes = nil
+	if err := VerifyBlockWitness(ctx, gspec.Config, engine, &nocode); err == nil {
+		t.Errorf("witness without code verified")
+	}
+	nonodes := *decoded
+	nonodes.Nodes = nil
+	if err := VerifyBlockWitness(ctx, gspec.Config, engine, &nonodes); err == nil {
+		t.Errorf("witness without trie nodes verified")
+	}
+}
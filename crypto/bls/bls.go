@@ -0,0 +1,259 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bls implements BLS signatures over the BLS12-381 curve, with public
+// keys in G₂ and signatures in G₁, and their aggregation.
+//
+// It follows the minimal-signature-size proof of possession ciphersuite of the
+// IRTF BLS signature draft, hashing messages to G₁ as per RFC 9380. The curve
+// arithmetic is done by blst, which is constant time for secret scalars.
+//
+// Points are serialized without compression as their big endian affine
+// coordinates, and deserialization checks subgroup membership.
+//
+// Signatures by many keys over the same message aggregate into one that is
+// verified with two pairings. As aggregated keys are open to rogue key attacks,
+// FastAggregateVerify must only be used with keys whose owners proved
+// possession of the secret key, see ProvePossession.
+package bls
+
+import (
+	"errors"
+	"io"
+
+	blst "github.com/supranational/blst/bindings/go"
+)
+
+const (
+	SecretKeyLength = 32  // Length of a serialized secret key
+	PublicKeyLength = 192 // Length of a serialized public key
+	SignatureLength = 96  // Length of a serialized signature
+)
+
+var (
+	// signDST separates the message hashes of signatures from other uses.
+	signDST = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+
+	// popDST separates the hashes of possession proofs from signatures, so
+	// that a proof can't be replayed as a signature over the public key.
+	popDST = []byte("BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+)
+
+var (
+	errInvalidSecretKey = errors.New("bls: invalid secret key")
+	errInvalidPublicKey = errors.New("bls: invalid public key")
+	errInvalidSignature = errors.New("bls: invalid signature")
+	errInfinityKey      = errors.New("bls: public key is the point at infinity")
+)
+
+// SecretKey is a BLS secret key, a non-zero scalar modulo the group order.
+type SecretKey struct {
+	k *blst.SecretKey
+}
+
+// PublicKey is a BLS public key, a non-identity element of G₂.
+type PublicKey struct {
+	p *blst.P2Affine
+}
+
+// Signature is a BLS signature or an aggregate of signatures, an element of G₁.
+type Signature struct {
+	p *blst.P1Affine
+}
+
+// GenerateKey derives a secret key from 32 bytes of keying material read from
+// rand.
+func GenerateKey(rand io.Reader) (*SecretKey, error) {
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(rand, ikm); err != nil {
+		return nil, err
+	}
+	k := blst.KeyGen(ikm)
+	for i := range ikm {
+		ikm[i] = 0
+	}
+	if k == nil {
+		return nil, errInvalidSecretKey
+	}
+	return &SecretKey{k}, nil
+}
+
+// SecretKeyFromBytes parses a big endian secret key.
+func SecretKeyFromBytes(b []byte) (*SecretKey, error) {
+	if len(b) != SecretKeyLength {
+		return nil, errInvalidSecretKey
+	}
+	k := new(blst.SecretKey).Deserialize(b)
+	if k == nil || !k.Valid() {
+		return nil, errInvalidSecretKey
+	}
+	return &SecretKey{k}, nil
+}
+
+// Bytes returns the big endian encoding of the secret key.
+func (sk *SecretKey) Bytes() []byte {
+	return sk.k.Serialize()
+}
+
+// PublicKey returns the public key belonging to the secret key.
+func (sk *SecretKey) PublicKey() *PublicKey {
+	return &PublicKey{new(blst.P2Affine).From(sk.k)}
+}
+
+// Sign signs a message.
+func (sk *SecretKey) Sign(msg []byte) *Signature {
+	return &Signature{new(blst.P1Affine).Sign(sk.k, msg, signDST)}
+}
+
+// ProvePossession signs the public key of the secret key, proving that its
+// owner knows the secret key rather than having derived the public key from
+// those of others.
+func (sk *SecretKey) ProvePossession() *Signature {
+	return &Signature{new(blst.P1Affine).Sign(sk.k, sk.PublicKey().Bytes(), popDST)}
+}
+
+// PublicKeyFromBytes parses a serialized public key, verifying that it is an
+// element of G₂ other than the identity.
+func PublicKeyFromBytes(b []byte) (*PublicKey, error) {
+	// Refuse the compression and infinity flags, which would otherwise make
+	// several encodings of the same key valid
+	if len(b) != PublicKeyLength || b[0]&0xe0 != 0 {
+		return nil, errInvalidPublicKey
+	}
+	if isZero(b) {
+		return nil, errInfinityKey
+	}
+	p := new(blst.P2Affine).Deserialize(b)
+	if p == nil || !p.KeyValidate() {
+		return nil, errInvalidPublicKey
+	}
+	return &PublicKey{p}, nil
+}
+
+// Bytes returns the serialized public key.
+func (pk *PublicKey) Bytes() []byte {
+	return pk.p.Serialize()
+}
+
+// Equal reports whether pk and other are the same key.
+func (pk *PublicKey) Equal(other *PublicKey) bool {
+	return pk.p.Equals(other.p)
+}
+
+// VerifyPossession verifies the proof that the owner of the public key knows
+// its secret key.
+func (pk *PublicKey) VerifyPossession(proof *Signature) bool {
+	return proof.p.Verify(false, pk.p, false, pk.Bytes(), popDST)
+}
+
+// SignatureFromBytes parses a serialized signature, verifying that it is an
+// element of G₁ other than the identity.
+func SignatureFromBytes(b []byte) (*Signature, error) {
+	if len(b) != SignatureLength || b[0]&0xe0 != 0 {
+		return nil, errInvalidSignature
+	}
+	p := new(blst.P1Affine).Deserialize(b)
+	if p == nil || !p.SigValidate(true) {
+		return nil, errInvalidSignature
+	}
+	return &Signature{p}, nil
+}
+
+// Bytes returns the serialized signature.
+func (sig *Signature) Bytes() []byte {
+	return sig.p.Serialize()
+}
+
+// Verify verifies the signature of a message by a public key.
+func Verify(pk *PublicKey, msg []byte, sig *Signature) bool {
+	return sig.p.Verify(false, pk.p, false, msg, signDST)
+}
+
+// Aggregate combines signatures into one, which is valid if and only if all
+// combined ones are, as far as anyone without the secret keys can tell.
+func Aggregate(sigs []*Signature) *Signature {
+	points := make([]*blst.P1Affine, len(sigs))
+	for i, sig := range sigs {
+		points[i] = sig.p
+	}
+	agg := new(blst.P1Aggregate)
+	agg.Aggregate(points, false)
+	return &Signature{agg.ToAffine()}
+}
+
+// AggregatePublicKeys combines public keys into one verifying the aggregate of
+// their signatures over a common message.
+func AggregatePublicKeys(pks []*PublicKey) (*PublicKey, error) {
+	points := make([]*blst.P2Affine, len(pks))
+	for i, pk := range pks {
+		points[i] = pk.p
+	}
+	agg := new(blst.P2Aggregate)
+	agg.Aggregate(points, false)
+
+	p := agg.ToAffine()
+	if p.Equals(new(blst.P2Affine)) {
+		return nil, errInfinityKey
+	}
+	return &PublicKey{p}, nil
+}
+
+// FastAggregateVerify verifies an aggregate of signatures by the given keys
+// over the same message. All keys must have proven possession of their secret
+// keys, otherwise one key can be forged to cancel out the others.
+func FastAggregateVerify(pks []*PublicKey, msg []byte, sig *Signature) bool {
+	if len(pks) == 0 {
+		return false
+	}
+	agg, err := AggregatePublicKeys(pks)
+	if err != nil {
+		return false
+	}
+	return Verify(agg, msg, sig)
+}
+
+// AggregateVerify verifies an aggregate of signatures by the given keys over
+// the respective messages, which must be distinct.
+func AggregateVerify(pks []*PublicKey, msgs [][]byte, sig *Signature) bool {
+	if len(pks) == 0 || len(pks) != len(msgs) {
+		return false
+	}
+	seen := make(map[string]bool, len(msgs))
+	for _, msg := range msgs {
+		if seen[string(msg)] {
+			return false
+		}
+		seen[string(msg)] = true
+	}
+	points := make([]*blst.P2Affine, len(pks))
+	for i, pk := range pks {
+		points[i] = pk.p
+	}
+	batch := make([]blst.Message, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = msg
+	}
+	return sig.p.AggregateVerify(false, points, false, batch, signDST)
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func newKeys(t *testing.T, n int) []*SecretKey {
+	keys := make([]*SecretKey, n)
+	for i := range keys {
+		key, err := GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+func TestSignVerify(t *testing.T) {
+	keys := newKeys(t, 2)
+	msg := []byte("checkpoint")
+
+	sig := keys[0].Sign(msg)
+	if !Verify(keys[0].PublicKey(), msg, sig) {
+		t.Errorf("valid signature rejected")
+	}
+	if Verify(keys[1].PublicKey(), msg, sig) {
+		t.Errorf("signature accepted for another key")
+	}
+	if Verify(keys[0].PublicKey(), []byte("other"), sig) {
+		t.Errorf("signature accepted for another message")
+	}
+	// Proofs of possession are no signatures and vice versa
+	proof := keys[0].ProvePossession()
+	if !keys[0].PublicKey().VerifyPossession(proof) {
+		t.Errorf("valid possession proof rejected")
+	}
+	if Verify(keys[0].PublicKey(), keys[0].PublicKey().Bytes(), proof) {
+		t.Errorf("possession proof accepted as signature")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	key := newKeys(t, 1)[0]
+
+	sk, err := SecretKeyFromBytes(key.Bytes())
+	if err != nil || !bytes.Equal(sk.Bytes(), key.Bytes()) {
+		t.Errorf("secret key round trip failed: %v", err)
+	}
+	pk, err := PublicKeyFromBytes(key.PublicKey().Bytes())
+	if err != nil || !pk.Equal(key.PublicKey()) {
+		t.Errorf("public key round trip failed: %v", err)
+	}
+	sig, err := SignatureFromBytes(key.Sign([]byte("msg")).Bytes())
+	if err != nil || !Verify(pk, []byte("msg"), sig) {
+		t.Errorf("signature round trip failed: %v", err)
+	}
+	if _, err := SecretKeyFromBytes(make([]byte, SecretKeyLength)); err == nil {
+		t.Errorf("zero secret key accepted")
+	}
+	if _, err := PublicKeyFromBytes(make([]byte, PublicKeyLength)); err == nil {
+		t.Errorf("infinity public key accepted")
+	}
+	// Flagged encodings of valid points must not be accepted as aliases
+	flagged := key.PublicKey().Bytes()
+	flagged[0] |= 0x80
+	if _, err := PublicKeyFromBytes(flagged); err == nil {
+		t.Errorf("flagged public key accepted")
+	}
+	flagged = key.Sign([]byte("msg")).Bytes()
+	flagged[0] |= 0x80
+	if _, err := SignatureFromBytes(flagged); err == nil {
+		t.Errorf("flagged signature accepted")
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	keys := newKeys(t, 3)
+	msg := []byte("checkpoint")
+
+	var (
+		pks  []*PublicKey
+		sigs []*Signature
+		msgs [][]byte
+	)
+	for i, key := range keys {
+		pks = append(pks, key.PublicKey())
+		sigs = append(sigs, key.Sign(msg))
+		msgs = append(msgs, []byte{byte(i)})
+	}
+	if !FastAggregateVerify(pks, msg, Aggregate(sigs)) {
+		t.Errorf("valid aggregate signature rejected")
+	}
+	if FastAggregateVerify(pks, msg, Aggregate(sigs[:2])) {
+		t.Errorf("aggregate missing a signature accepted")
+	}
+	if FastAggregateVerify(pks[:2], msg, Aggregate(sigs)) {
+		t.Errorf("aggregate accepted for missing key")
+	}
+	// Distinct messages
+	sigs = sigs[:0]
+	for i, key := range keys {
+		sigs = append(sigs, key.Sign(msgs[i]))
+	}
+	if !AggregateVerify(pks, msgs, Aggregate(sigs)) {
+		t.Errorf("valid aggregate of distinct messages rejected")
+	}
+	msgs[1], msgs[2] = msgs[2], msgs[1]
+	if AggregateVerify(pks, msgs, Aggregate(sigs)) {
+		t.Errorf("aggregate accepted for swapped messages")
+	}
+	msgs[1] = msgs[2]
+	if AggregateVerify(pks, msgs, Aggregate(sigs)) {
+		t.Errorf("aggregate accepted for repeated messages")
+	}
+}
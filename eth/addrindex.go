@@ -0,0 +1,106 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+const (
+	// addressIndexSection is the number of blocks indexed at once. Every block
+	// is its own section so that transactions are indexed as soon as imported.
+	addressIndexSection = 1
+
+	// addressIndexConfirms is the number of confirmation blocks before a block
+	// is indexed. Reorged blocks are reindexed, stale entries are filtered out
+	// against the canonical chain when read.
+	addressIndexConfirms = 0
+)
+
+// AddressIndexer implements a core.ChainIndexer, indexing the transactions of
+// every block under their sender, recipient and created contract address.
+type AddressIndexer struct {
+	db     ethdb.Database      // database instance to read blocks from and write the index into
+	config *params.ChainConfig // chain configuration to derive transaction senders with
+
+	batch ethdb.Batch // batch collecting the index entries of the current section
+}
+
+// NewAddressIndexer returns a chain indexer that maintains the address to
+// transaction index of the canonical chain.
+func NewAddressIndexer(db ethdb.Database, config *params.ChainConfig) *core.ChainIndexer {
+	backend := &AddressIndexer{
+		db:     db,
+		config: config,
+	}
+	table := ethdb.NewTable(db, string(core.AddressIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, addressIndexSection, addressIndexConfirms, 0, "addrindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (a *AddressIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	a.batch = a.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, indexing the transactions of
+// the block belonging to header.
+func (a *AddressIndexer) Process(header *types.Header) {
+	hash, number := header.Hash(), header.Number.Uint64()
+
+	body := core.GetBody(a.db, hash, number)
+	if body == nil {
+		// Blocks without bodies (e.g. fast sync gaps) have nothing to index
+		return
+	}
+	signer := types.MakeSigner(a.config, header.Number)
+	for i, tx := range body.Transactions {
+		from, err := types.Sender(context.Background(), signer, tx)
+		if err != nil {
+			continue
+		}
+		addrs := []common.Address{from}
+		if to := tx.To(); to != nil {
+			if *to != from {
+				addrs = append(addrs, *to)
+			}
+		} else {
+			addrs = append(addrs, crypto.CreateAddress(from, tx.Nonce()))
+		}
+		for _, addr := range addrs {
+			core.WriteAddressTx(a.batch, addr, number, uint32(i), hash)
+		}
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, flushing the index entries of
+// the section into the database.
+func (a *AddressIndexer) Commit() error {
+	if err := a.batch.Write(); err != nil {
+		return fmt.Errorf("failed to write address index: %v", err)
+	}
+	return nil
+}
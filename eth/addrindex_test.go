@@ -0,0 +1,95 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/eth/downloader"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// Tests that the address indexer files transactions under their sender, their
+// recipient and the contracts they create.
+func TestAddressIndexer(t *testing.T) {
+	ctx := context.Background()
+	recipient := common.Address{0x12, 0x34}
+	signer := types.HomesteadSigner{}
+
+	// Transfer to the recipient in every block, and create a contract in the third
+	generator := func(ctx context.Context, i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), recipient, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+		block.AddTx(ctx, tx)
+		if i == 2 {
+			tx, _ = types.SignTx(types.NewContractCreation(block.TxNonce(testBank), new(big.Int), 100000, nil, []byte{0x00}), signer, testBankKey)
+			block.AddTx(ctx, tx)
+		}
+	}
+	pm, db := newTestProtocolManagerMust(ctx, t, downloader.FullSync, 4, generator, nil)
+	defer pm.Stop()
+
+	indexer := &AddressIndexer{db: db, config: pm.blockchain.Config()}
+	for number := uint64(1); number <= 4; number++ {
+		if err := indexer.Reset(number-1, common.Hash{}); err != nil {
+			t.Fatalf("block %d: failed to reset indexer: %v", number, err)
+		}
+		indexer.Process(pm.blockchain.GetHeaderByNumber(number))
+		if err := indexer.Commit(); err != nil {
+			t.Fatalf("block %d: failed to commit index: %v", number, err)
+		}
+	}
+	contract := crypto.CreateAddress(testBank, 3)
+
+	tests := []struct {
+		addr        common.Address
+		from, to    uint64
+		skip, limit int
+		want        []uint64 // block numbers of the expected results
+	}{
+		{testBank, 0, 4, 0, 10, []uint64{1, 2, 3, 3, 4}},
+		{recipient, 0, 4, 0, 10, []uint64{1, 2, 3, 4}},
+		{recipient, 2, 3, 0, 10, []uint64{2, 3}},
+		{recipient, 0, 4, 1, 2, []uint64{2, 3}},
+		{contract, 0, 4, 0, 10, []uint64{3}},
+		{common.Address{0xff}, 0, 4, 0, 10, nil},
+	}
+	for i, tt := range tests {
+		txs, err := core.GetAddressTxs(db, tt.addr, tt.from, tt.to, tt.skip, tt.limit)
+		if err != nil {
+			t.Errorf("test %d: failed to query index: %v", i, err)
+			continue
+		}
+		if len(txs) != len(tt.want) {
+			t.Errorf("test %d: result count mismatch: have %d, want %d", i, len(txs), len(tt.want))
+			continue
+		}
+		for j, tx := range txs {
+			if tx.BlockNumber != tt.want[j] {
+				t.Errorf("test %d, result %d: block mismatch: have %d, want %d", i, j, tx.BlockNumber, tt.want[j])
+			}
+			if hash := pm.blockchain.GetBlockByNumber(tx.BlockNumber).Hash(); tx.BlockHash != hash {
+				t.Errorf("test %d, result %d: block hash mismatch: have %x, want %x", i, j, tx.BlockHash, hash)
+			}
+		}
+	}
+}
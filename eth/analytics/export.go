@@ -0,0 +1,206 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package analytics exports chain data into columnar files for offline analysis.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/internal/jobs"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// Chain is the source of the exported chain data.
+type Chain interface {
+	Config() *params.ChainConfig
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+}
+
+// Result reports the files written by an export.
+type Result struct {
+	First   uint64            `json:"first"`
+	Last    uint64            `json:"last"`
+	Format  string            `json:"format"`
+	Files   map[string]string `json:"files"` // Paths of the files by table
+	Rows    map[string]uint64 `json:"rows"`  // Number of rows by table
+	Elapsed time.Duration     `json:"elapsed"`
+}
+
+// Export writes the blocks, transactions, receipts and logs of the blocks
+// first to last into a file per table in dir, named after the table and the
+// range, such as blocks_100_199.parquet.
+func Export(ctx context.Context, chain Chain, dir, format string, first, last uint64) (*Result, error) {
+	if first > last {
+		return nil, fmt.Errorf("invalid block range %d-%d", first, last)
+	}
+	if format != FormatParquet && format != FormatCSV {
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result := &Result{
+		First:  first,
+		Last:   last,
+		Format: format,
+		Files:  make(map[string]string),
+		Rows:   make(map[string]uint64),
+	}
+	var (
+		files   []*os.File
+		writers = make(map[*Table]rowWriter)
+	)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, table := range Tables {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%d_%d.%s", table.Name, first, last, format))
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+
+		w, err := newRowWriter(f, table, format)
+		if err != nil {
+			return nil, err
+		}
+		writers[table] = w
+		result.Files[table.Name] = path
+	}
+	write := func(table *Table, values ...interface{}) error {
+		result.Rows[table.Name]++
+		return writers[table].WriteRow(values)
+	}
+	logged := time.Now()
+	for number := first; number <= last; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := exportBlock(chain, number, write); err != nil {
+			return nil, err
+		}
+		jobs.ReportProgress(ctx, number-first+1, last-first+1)
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting chain analytics", "number", number, "last", last, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	for _, table := range Tables {
+		if err := writers[table].Close(); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range files {
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
+	}
+	files = nil
+
+	result.Elapsed = time.Since(start)
+	log.Info("Exported chain analytics", "first", first, "last", last, "format", format, "dir", dir, "elapsed", common.PrettyDuration(result.Elapsed))
+	return result, nil
+}
+
+// exportBlock writes the rows of the tables for a block.
+func exportBlock(chain Chain, number uint64, write func(*Table, ...interface{}) error) error {
+	block := chain.GetBlockByNumber(number)
+	if block == nil {
+		return fmt.Errorf("block #%d not found", number)
+	}
+	var (
+		hash   = block.Hash().Hex()
+		num    = int64(number)
+		txs    = block.Transactions()
+		signer = types.MakeSigner(chain.Config(), block.Number())
+	)
+	receipts := chain.GetReceiptsByHash(block.Hash())
+	if len(receipts) != len(txs) {
+		return fmt.Errorf("receipts of block #%d not available", number)
+	}
+	err := write(BlocksTable,
+		num, hash, block.ParentHash().Hex(), block.Time().Int64(), block.Coinbase().Hex(),
+		block.Difficulty().String(), int64(block.GasLimit()), int64(block.GasUsed()), int64(len(txs)),
+		int64(block.Size()), hexutil.Encode(block.Extra()), block.Root().Hex(), block.TxHash().Hex(),
+		block.ReceiptHash().Hex(),
+	)
+	if err != nil {
+		return err
+	}
+	var logIndex int64
+	for i, tx := range txs {
+		from, err := types.Sender(context.Background(), signer, tx)
+		if err != nil {
+			return fmt.Errorf("invalid transaction %x in block #%d: %v", tx.Hash(), number, err)
+		}
+		var to string
+		if tx.To() != nil {
+			to = tx.To().Hex()
+		}
+		err = write(TransactionsTable,
+			num, hash, int64(i), tx.Hash().Hex(), from.Hex(), to, int64(tx.Nonce()),
+			tx.Value().String(), int64(tx.Gas()), tx.GasPrice().String(), hexutil.Encode(tx.Data()),
+		)
+		if err != nil {
+			return err
+		}
+		receipt := receipts[i]
+		var contract string
+		if tx.To() == nil {
+			contract = receipt.ContractAddress.Hex()
+		}
+		var postState string
+		if len(receipt.PostState) > 0 {
+			postState = hexutil.Encode(receipt.PostState)
+		}
+		err = write(ReceiptsTable,
+			num, hash, int64(i), tx.Hash().Hex(), int64(receipt.Status), int64(receipt.CumulativeGasUsed),
+			int64(receipt.GasUsed), contract, int64(len(receipt.Logs)), postState,
+		)
+		if err != nil {
+			return err
+		}
+		for _, l := range receipt.Logs {
+			var topics [4]string
+			for j := 0; j < len(l.Topics) && j < len(topics); j++ {
+				topics[j] = l.Topics[j].Hex()
+			}
+			err = write(LogsTable,
+				num, hash, int64(i), tx.Hash().Hex(), logIndex, l.Address.Hex(),
+				topics[0], topics[1], topics[2], topics[3], hexutil.Encode(l.Data),
+			)
+			if err != nil {
+				return err
+			}
+			logIndex++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// newTestChain creates a chain of n blocks, each calling a contract emitting a log.
+func newTestChain(t *testing.T, n int) *core.BlockChain {
+	ctx := context.Background()
+	var (
+		db       = ethdb.NewMemDatabase()
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xaa}
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address:  {Balance: big.NewInt(1000000000)},
+				contract: {Balance: new(big.Int), Code: []byte{0x60, 0x00, 0x60, 0x00, 0xa0}}, // log0(0, 0)
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		engine  = clique.NewFaker()
+	)
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	blocks, _ := core.GenerateChain(ctx, gspec.Config, genesis, engine, db, n, func(ctx context.Context, i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), contract, new(big.Int), 100000, new(big.Int), nil), types.HomesteadSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(ctx, tx)
+	})
+	if _, err := blockchain.InsertChain(ctx, blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return blockchain
+}
+
+func TestExportCSV(t *testing.T) {
+	chain := newTestChain(t, 3)
+	defer chain.Stop()
+
+	dir, err := ioutil.TempDir("", "analytics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	result, err := Export(context.Background(), chain, dir, FormatCSV, 1, 3)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	want := map[string]uint64{"blocks": 3, "transactions": 3, "receipts": 3, "logs": 3}
+	for _, table := range Tables {
+		if result.Rows[table.Name] != want[table.Name] {
+			t.Errorf("%s: row count mismatch: have %d, want %d", table.Name, result.Rows[table.Name], want[table.Name])
+		}
+		data, err := ioutil.ReadFile(result.Files[table.Name])
+		if err != nil {
+			t.Fatalf("%s: %v", table.Name, err)
+		}
+		records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: invalid CSV: %v", table.Name, err)
+		}
+		if len(records) != int(want[table.Name])+1 {
+			t.Fatalf("%s: record count mismatch: have %d, want %d", table.Name, len(records), want[table.Name]+1)
+		}
+		for i, column := range table.Columns {
+			if records[0][i] != column.Name {
+				t.Errorf("%s: column %d mismatch: have %s, want %s", table.Name, i, records[0][i], column.Name)
+			}
+		}
+	}
+	blocks, _ := ioutil.ReadFile(result.Files["blocks"])
+	records, _ := csv.NewReader(bytes.NewReader(blocks)).ReadAll()
+	for i, record := range records[1:] {
+		block := chain.GetBlockByNumber(uint64(i + 1))
+		if record[0] != block.Number().String() || record[1] != block.Hash().Hex() {
+			t.Errorf("block %d mismatch: %v", i+1, record)
+		}
+	}
+	if _, err := Export(context.Background(), chain, dir, FormatCSV, 3, 4); err == nil {
+		t.Errorf("export of missing blocks succeeded")
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	chain := newTestChain(t, 3)
+	defer chain.Stop()
+
+	dir, err := ioutil.TempDir("", "analytics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	result, err := Export(context.Background(), chain, dir, FormatParquet, 0, 3)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	data, err := ioutil.ReadFile(result.Files["blocks"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, parquetMagic) || !bytes.HasSuffix(data, parquetMagic) {
+		t.Fatalf("parquet magic missing")
+	}
+	size := binary.LittleEndian.Uint32(data[len(data)-8:])
+	meta := newThriftReader(data[len(data)-8-int(size) : len(data)-8]).readStruct()
+
+	if rows := meta[3].(int64); rows != 4 {
+		t.Errorf("row count mismatch: have %d, want 4", rows)
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != len(BlocksTable.Columns)+1 {
+		t.Fatalf("schema length mismatch: have %d, want %d", len(schema), len(BlocksTable.Columns)+1)
+	}
+	for i, column := range BlocksTable.Columns {
+		if name := string(schema[i+1].(map[int16]interface{})[4].([]byte)); name != column.Name {
+			t.Errorf("column %d name mismatch: have %s, want %s", i, name, column.Name)
+		}
+	}
+	// Read the block numbers back from the data page of the first column
+	groups := meta[4].([]interface{})
+	chunks := groups[0].(map[int16]interface{})[1].([]interface{})
+	offset := chunks[0].(map[int16]interface{})[2].(int64)
+
+	page := newThriftReader(data[offset:])
+	header := page.readStruct()
+	values := page.data[page.pos : page.pos+int(header[2].(int64))]
+	for i := 0; i < 4; i++ {
+		if n := binary.LittleEndian.Uint64(values[8*i:]); n != uint64(i) {
+			t.Errorf("block number %d mismatch: have %d", i, n)
+		}
+	}
+}
+
+// thriftReader decodes Thrift compact protocol structs into maps by field id.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+func newThriftReader(data []byte) *thriftReader {
+	return &thriftReader{data: data}
+}
+
+func (r *thriftReader) byte() byte {
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	r.pos += n
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n, k := binary.Uvarint(r.data[r.pos:])
+		r.pos += k
+		b := r.data[r.pos : r.pos+int(n)]
+		r.pos += int(n)
+		return b
+	case thriftList:
+		header := r.byte()
+		size, elem := int(header>>4), header&0x0f
+		if size == 15 {
+			n, k := binary.Uvarint(r.data[r.pos:])
+			r.pos += k
+			size = int(n)
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	}
+	panic("unsupported thrift type")
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	fields := make(map[int16]interface{})
+	var last int16
+	for {
+		header := r.byte()
+		if header == 0 {
+			return fields
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(header & 0x0f)
+		last = id
+	}
+}
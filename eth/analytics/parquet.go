@@ -0,0 +1,331 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+/*
+Parquet encoding
+
+Tables are written as Parquet files of required (non-null) columns, with one
+uncompressed, PLAIN encoded data page per column chunk. Rows are buffered in
+memory and flushed as a row group every parquetRowGroupSize rows. The file
+metadata is encoded with the Thrift compact protocol, as mandated by the format.
+*/
+
+// parquetRowGroupSize is the number of rows buffered per row group.
+const parquetRowGroupSize = 1 << 16
+
+var parquetMagic = []byte("PAR1")
+
+// Parquet physical types, repetition types, encodings and converted types.
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetUTF8 = 0
+
+	parquetDataPage     = 0
+	parquetUncompressed = 0
+)
+
+// parquetChunk is the location of a column chunk written to the file.
+type parquetChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+// parquetRowGroup is the location of a row group written to the file.
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	rows   int64
+}
+
+// parquetWriter writes the rows of a table into a Parquet file.
+type parquetWriter struct {
+	out     *bufio.Writer
+	columns []Column
+	offset  int64
+
+	pages  []bytes.Buffer // Values of the buffered rows, by column
+	rows   int64          // Number of buffered rows
+	groups []parquetRowGroup
+	total  int64
+}
+
+func newParquetWriter(w io.Writer, columns []Column) (*parquetWriter, error) {
+	pw := &parquetWriter{
+		out:     bufio.NewWriter(w),
+		columns: columns,
+		pages:   make([]bytes.Buffer, len(columns)),
+	}
+	if err := pw.write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *parquetWriter) write(data []byte) error {
+	n, err := pw.out.Write(data)
+	pw.offset += int64(n)
+	return err
+}
+
+func (pw *parquetWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(pw.columns) {
+		return fmt.Errorf("row has %d values, want %d", len(values), len(pw.columns))
+	}
+	var buf [8]byte
+	for i, value := range values {
+		page := &pw.pages[i]
+		switch pw.columns[i].Type {
+		case Int64:
+			binary.LittleEndian.PutUint64(buf[:], uint64(value.(int64)))
+			page.Write(buf[:])
+		case String:
+			s := value.(string)
+			binary.LittleEndian.PutUint32(buf[:4], uint32(len(s)))
+			page.Write(buf[:4])
+			page.WriteString(s)
+		}
+	}
+	pw.rows++
+	if pw.rows >= parquetRowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *parquetWriter) flush() error {
+	if pw.rows == 0 {
+		return nil
+	}
+	group := parquetRowGroup{rows: pw.rows}
+	for i := range pw.pages {
+		page := &pw.pages[i]
+
+		header := newThriftWriter()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(page.Len()))
+		header.i32(3, int32(page.Len()))
+		header.beginStruct(5)
+		header.i32(1, int32(pw.rows))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.end()
+
+		chunk := parquetChunk{offset: pw.offset, values: pw.rows}
+		if err := pw.write(header.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := pw.write(page.Bytes()); err != nil {
+			return err
+		}
+		chunk.size = pw.offset - chunk.offset
+		group.chunks = append(group.chunks, chunk)
+		page.Reset()
+	}
+	pw.groups = append(pw.groups, group)
+	pw.total += pw.rows
+	pw.rows = 0
+	return nil
+}
+
+// Close flushes the buffered rows and writes the file metadata.
+func (pw *parquetWriter) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	meta := newThriftWriter()
+	meta.i32(1, 1)
+
+	meta.beginList(2, thriftStruct, len(pw.columns)+1)
+	meta.beginElem()
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.endStruct()
+	for _, column := range pw.columns {
+		meta.beginElem()
+		meta.i32(1, column.physicalType())
+		meta.i32(3, parquetRequired)
+		meta.string(4, column.Name)
+		if column.Type == String {
+			meta.i32(6, parquetUTF8)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, pw.total)
+
+	meta.beginList(4, thriftStruct, len(pw.groups))
+	for _, group := range pw.groups {
+		var size int64
+		meta.beginElem()
+		meta.beginList(1, thriftStruct, len(group.chunks))
+		for i, chunk := range group.chunks {
+			column := pw.columns[i]
+			meta.beginElem()
+			meta.i64(2, chunk.offset)
+			meta.beginStruct(3)
+			meta.i32(1, column.physicalType())
+			meta.beginList(2, thriftI32, 2)
+			meta.listI32(parquetPlain)
+			meta.listI32(parquetRLE)
+			meta.beginList(3, thriftBinary, 1)
+			meta.listString(column.Name)
+			meta.i32(4, parquetUncompressed)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+			size += chunk.size
+		}
+		meta.i64(2, size)
+		meta.i64(3, group.rows)
+		meta.endStruct()
+	}
+	meta.string(6, "indigo analytics export")
+	meta.end()
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(meta.buf.Len()))
+	for _, data := range [][]byte{meta.buf.Bytes(), length[:], parquetMagic} {
+		if err := pw.write(data); err != nil {
+			return err
+		}
+	}
+	return pw.out.Flush()
+}
+
+func (c Column) physicalType() int32 {
+	if c.Type == Int64 {
+		return parquetInt64
+	}
+	return parquetByteArray
+}
+
+// Thrift compact protocol field types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structs with the compact protocol. Fields must
+// be written in increasing id order within a struct.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // Id of the last field written, by struct nesting level
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.buf.Write(buf[:binary.PutUvarint(buf[:], v)])
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(zigzag(int64(v)))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(zigzag(v))
+}
+
+func (w *thriftWriter) string(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.listString(s)
+}
+
+// beginStruct starts a struct field, terminated by endStruct.
+func (w *thriftWriter) beginStruct(id int16) {
+	w.field(id, thriftStruct)
+	w.beginElem()
+}
+
+// beginElem starts a struct element of a list, terminated by endStruct.
+func (w *thriftWriter) beginElem() {
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+// end terminates the top level struct.
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(0)
+}
+
+// beginList writes the header of a list field, to be followed by its elements.
+func (w *thriftWriter) beginList(id int16, elem byte, size int) {
+	w.field(id, thriftList)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		w.buf.WriteByte(0xf0 | elem)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) listI32(v int32) {
+	w.varint(zigzag(int64(v)))
+}
+
+func (w *thriftWriter) listString(s string) {
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package analytics
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ColumnType is the type of the values of a column.
+type ColumnType int
+
+const (
+	Int64  ColumnType = iota // Signed 64 bit integers
+	String                   // UTF-8 strings; hashes, addresses and binary data are 0x prefixed hex, big integers decimal
+)
+
+// Column is a column of an exported table.
+type Column struct {
+	Name string
+	Type ColumnType
+}
+
+// Table is the schema of an exported table. Columns are only ever appended to
+// the schemas, for exports to stay readable by existing consumers.
+type Table struct {
+	Name    string
+	Columns []Column
+}
+
+// Tables exported for a range of blocks. Values missing from a row, such as the
+// recipient of a contract creation or unused topics, are empty strings.
+var (
+	BlocksTable = &Table{Name: "blocks", Columns: []Column{
+		{"number", Int64},
+		{"hash", String},
+		{"parent_hash", String},
+		{"timestamp", Int64},
+		{"miner", String},
+		{"difficulty", String},
+		{"gas_limit", Int64},
+		{"gas_used", Int64},
+		{"transaction_count", Int64},
+		{"size", Int64},
+		{"extra_data", String},
+		{"state_root", String},
+		{"transactions_root", String},
+		{"receipts_root", String},
+	}}
+	TransactionsTable = &Table{Name: "transactions", Columns: []Column{
+		{"block_number", Int64},
+		{"block_hash", String},
+		{"transaction_index", Int64},
+		{"hash", String},
+		{"from", String},
+		{"to", String},
+		{"nonce", Int64},
+		{"value", String},
+		{"gas", Int64},
+		{"gas_price", String},
+		{"input", String},
+	}}
+	ReceiptsTable = &Table{Name: "receipts", Columns: []Column{
+		{"block_number", Int64},
+		{"block_hash", String},
+		{"transaction_index", Int64},
+		{"transaction_hash", String},
+		{"status", Int64},
+		{"cumulative_gas_used", Int64},
+		{"gas_used", Int64},
+		{"contract_address", String},
+		{"log_count", Int64},
+		{"post_state", String},
+	}}
+	LogsTable = &Table{Name: "logs", Columns: []Column{
+		{"block_number", Int64},
+		{"block_hash", String},
+		{"transaction_index", Int64},
+		{"transaction_hash", String},
+		{"log_index", Int64},
+		{"address", String},
+		{"topic0", String},
+		{"topic1", String},
+		{"topic2", String},
+		{"topic3", String},
+		{"data", String},
+	}}
+
+	// Tables lists the exported tables.
+	Tables = []*Table{BlocksTable, TransactionsTable, ReceiptsTable, LogsTable}
+)
+
+// Formats of the exported files.
+const (
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+)
+
+// rowWriter writes the rows of a table into a file.
+type rowWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// newRowWriter creates a writer of the rows of a table in the given format.
+func newRowWriter(w io.Writer, table *Table, format string) (rowWriter, error) {
+	switch format {
+	case FormatParquet:
+		return newParquetWriter(w, table.Columns)
+	case FormatCSV:
+		return newCSVWriter(w, table.Columns)
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// csvWriter writes the rows of a table as CSV, headed by the column names.
+type csvWriter struct {
+	out     *csv.Writer
+	columns []Column
+	record  []string
+}
+
+func newCSVWriter(w io.Writer, columns []Column) (*csvWriter, error) {
+	cw := &csvWriter{
+		out:     csv.NewWriter(w),
+		columns: columns,
+		record:  make([]string, len(columns)),
+	}
+	for i, column := range columns {
+		cw.record[i] = column.Name
+	}
+	if err := cw.out.Write(cw.record); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+func (cw *csvWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(cw.columns) {
+		return fmt.Errorf("row has %d values, want %d", len(values), len(cw.columns))
+	}
+	for i, value := range values {
+		switch cw.columns[i].Type {
+		case Int64:
+			cw.record[i] = strconv.FormatInt(value.(int64), 10)
+		case String:
+			cw.record[i] = value.(string)
+		}
+	}
+	return cw.out.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.out.Flush()
+	return cw.out.Error()
+}
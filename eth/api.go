@@ -19,17 +19,25 @@ package eth
 import (
 	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/hexutil"
 	"github.com/fulcrumchain/indigo/core"
 	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/state/layout"
 	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/eth/analytics"
+	"github.com/fulcrumchain/indigo/eth/downloader"
+	"github.com/fulcrumchain/indigo/eth/era"
+	"github.com/fulcrumchain/indigo/eth/statearchive"
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/miner"
 	"github.com/fulcrumchain/indigo/params"
@@ -59,6 +67,93 @@ func (api *PublicEthereumAPI) Coinbase() (common.Address, error) {
 	return api.Etherbase()
 }
 
+// maxBalanceChangeBlocks is the maximum number of blocks scanned for balance
+// changes by a single request.
+const maxBalanceChangeBlocks = 10000
+
+// BalanceChange is the change of the balance of an account in a block.
+type BalanceChange struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Balance     *hexutil.Big   `json:"balance"` // Balance after the block
+	Delta       *hexutil.Big   `json:"delta"`   // Signed change of the balance in the block
+}
+
+// GetBalanceChanges returns the changes of the balance of an account in the
+// canonical blocks of the given range (both inclusive), omitting the blocks
+// leaving it unchanged. Blocks recent enough for their state transitions to be
+// held by the state snapshot are skipped unless they modified the account, the
+// states of the others must be available.
+func (api *PublicEthereumAPI) GetBalanceChanges(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber) ([]*BalanceChange, error) {
+	bc := api.e.BlockChain()
+	head := bc.CurrentBlock().NumberU64()
+
+	fromBlock, toBlock = api.e.resolveBlockNumber(fromBlock), api.e.resolveBlockNumber(toBlock)
+	from, to := uint64(fromBlock), uint64(toBlock)
+	if fromBlock < 0 {
+		from = head
+	}
+	if toBlock < 0 {
+		to = head
+	}
+	switch {
+	case from > to:
+		return nil, fmt.Errorf("invalid block range %d-%d", from, to)
+	case to > head:
+		return nil, fmt.Errorf("block #%d not found", to)
+	case to-from >= maxBalanceChangeBlocks:
+		return nil, fmt.Errorf("block range too large (%d>%d)", to-from+1, maxBalanceChangeBlocks)
+	}
+	return balanceChanges(ctx, bc, address, from, to)
+}
+
+// balanceChanges scans the canonical blocks in [from, to] for the changes of
+// the balance of an account.
+func balanceChanges(ctx context.Context, bc *core.BlockChain, address common.Address, from, to uint64) ([]*BalanceChange, error) {
+	var (
+		balance = new(big.Int)
+		parent  common.Hash // State root before the current block
+	)
+	if from > 0 {
+		header := bc.GetHeaderByNumber(from - 1)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", from-1)
+		}
+		statedb, err := bc.StateAt(header.Root)
+		if err != nil {
+			return nil, fmt.Errorf("state of block #%d not available: %v", from-1, err)
+		}
+		balance, parent = statedb.GetBalance(address), header.Root
+	}
+	changes := make([]*BalanceChange, 0)
+	for number := from; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header := bc.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		if modified, known := bc.AccountModified(header.Root, parent, address); modified || !known {
+			statedb, err := bc.StateAt(header.Root)
+			if err != nil {
+				return nil, fmt.Errorf("state of block #%d not available: %v", number, err)
+			}
+			if current := statedb.GetBalance(address); current.Cmp(balance) != 0 {
+				changes = append(changes, &BalanceChange{
+					BlockNumber: hexutil.Uint64(number),
+					BlockHash:   header.Hash(),
+					Balance:     (*hexutil.Big)(current),
+					Delta:       (*hexutil.Big)(new(big.Int).Sub(current, balance)),
+				})
+				balance = current
+			}
+		}
+		parent = header.Root
+	}
+	return changes, nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -151,12 +246,89 @@ func (api *PrivateMinerAPI) SetGasPrice(ctx context.Context, gasPrice hexutil.Bi
 	return true
 }
 
+// SetGasLimitTarget sets the gas limit that sealed blocks move toward. A zero
+// target restores the default usage based strategy. It fails on chains whose
+// configuration defines the gas limit trajectory.
+func (api *PrivateMinerAPI) SetGasLimitTarget(target hexutil.Uint64) (bool, error) {
+	if err := api.e.Miner().SetGasLimitTarget(uint64(target)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetTxOrderCommit toggles first come first served transaction ordering, with
+// sealed blocks committing to their arrival stamped candidates in their
+// extra-data.
+func (api *PrivateMinerAPI) SetTxOrderCommit(commit bool) bool {
+	api.e.Miner().SetTxOrderCommit(commit)
+	return true
+}
+
 // SetEtherbase sets the etherbase of the miner
 func (api *PrivateMinerAPI) SetEtherbase(etherbase common.Address) bool {
 	api.e.SetEtherbase(etherbase)
 	return true
 }
 
+// PendingBlock is the block the miner works on, as pushed by the pendingBlock
+// subscription.
+type PendingBlock struct {
+	Header            *types.Header    `json:"header"`
+	Transactions      interface{}      `json:"transactions"`      // Hashes, or full transactions if requested
+	CumulativeGasUsed []hexutil.Uint64 `json:"cumulativeGasUsed"` // Gas used by the block after each transaction
+}
+
+// newPendingBlock assembles the notification of a pending block event.
+func newPendingBlock(ev core.PendingBlockEvent, fullTx bool) *PendingBlock {
+	txs := ev.Block.Transactions()
+	pending := &PendingBlock{
+		Header:            ev.Block.Header(),
+		CumulativeGasUsed: make([]hexutil.Uint64, len(ev.Receipts)),
+	}
+	if fullTx {
+		pending.Transactions = txs
+	} else {
+		hashes := make([]common.Hash, len(txs))
+		for i, tx := range txs {
+			hashes[i] = tx.Hash()
+		}
+		pending.Transactions = hashes
+	}
+	for i, receipt := range ev.Receipts {
+		pending.CumulativeGasUsed[i] = hexutil.Uint64(receipt.CumulativeGasUsed)
+	}
+	return pending
+}
+
+// PendingBlock pushes the block the miner works on each time it is updated,
+// with the hashes of its transactions, or the full transactions if fullTx is
+// set.
+func (api *PrivateMinerAPI) PendingBlock(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blocks := make(chan core.PendingBlockEvent, 16)
+		sub := api.e.Miner().SubscribePendingBlockEvent(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-blocks:
+				notifier.Notify(rpcSub.ID, newPendingBlock(ev, fullTx != nil && *fullTx))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
 // PrivateAdminAPI is the collection of Indigo full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -203,6 +375,69 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// ExportAnalytics exports the blocks, transactions, receipts and logs of a range
+// of blocks into a Parquet or CSV file per table in dir, for offline analysis.
+func (api *PrivateAdminAPI) ExportAnalytics(ctx context.Context, dir, format string, first, last rpc.BlockNumber) (*analytics.Result, error) {
+	head := api.eth.BlockChain().CurrentBlock().NumberU64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number = api.eth.config.Finality.Resolve(number, head); number < 0 {
+			return head
+		}
+		return uint64(number)
+	}
+	from, to := resolve(first), resolve(last)
+	if to > head {
+		return nil, fmt.Errorf("block #%d not yet imported, head is #%d", to, head)
+	}
+	return analytics.Export(ctx, api.eth.BlockChain(), dir, strings.ToLower(format), from, to)
+}
+
+// ExportChainSegments exports the chain into gzip compressed segments of
+// segmentSize blocks and their receipts in dir, along with an index of their
+// ranges and checksums. Unchanged segments of a previous export are kept.
+func (api *PrivateAdminAPI) ExportChainSegments(ctx context.Context, dir string, segmentSize *uint64) (*era.Index, error) {
+	size := uint64(era.DefaultSegmentSize)
+	if segmentSize != nil {
+		size = *segmentSize
+	}
+	return era.Export(ctx, api.eth.BlockChain(), dir, size)
+}
+
+// ImportChainSegments verifies and imports the segments exported into dir. By
+// default the blocks are only stored along with their receipts if the node
+// fast syncs and has no blocks yet, leaving the state of the head to be synced
+// from the network, and executed otherwise.
+func (api *PrivateAdminAPI) ImportChainSegments(ctx context.Context, dir string, full *bool) (*era.ImportResult, error) {
+	chain := api.eth.BlockChain()
+	empty := chain.CurrentBlock().NumberU64() == 0
+	fast := api.eth.config.SyncMode == downloader.FastSync && empty
+	if full != nil {
+		if !*full && !empty {
+			return nil, errors.New("segments can only be imported without execution into an empty chain")
+		}
+		fast = !*full
+	}
+	return era.Import(ctx, chain, dir, fast)
+}
+
+// ExportStateSnapshot exports the state of a block, the head by default, into a
+// verifiable archive in dir, for other nodes to import without syncing it.
+func (api *PrivateAdminAPI) ExportStateSnapshot(ctx context.Context, dir string, blockNr *rpc.BlockNumber) (*statearchive.Manifest, error) {
+	chain := api.eth.BlockChain()
+	number := chain.CurrentBlock().NumberU64()
+	if blockNr != nil && *blockNr >= 0 {
+		number = uint64(*blockNr)
+	}
+	return statearchive.Export(ctx, chain, number, dir)
+}
+
+// ImportStateSnapshot verifies and imports the state archived in dir. The block
+// it belongs to has to be imported first, e.g. with importChainSegments, and
+// becomes the head of the chain once its state is complete.
+func (api *PrivateAdminAPI) ImportStateSnapshot(ctx context.Context, dir string) (*statearchive.ImportResult, error) {
+	return statearchive.Import(ctx, api.eth.BlockChain(), api.eth.ChainDb(), dir)
+}
+
 func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	for _, b := range bs {
 		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
@@ -263,6 +498,137 @@ func (api *PrivateAdminAPI) ImportChain(ctx context.Context, file string) (bool,
 	return true, nil
 }
 
+// SetSyncBandwidth changes the caps of the sync traffic, in bytes per second.
+// Zero rates lift the caps.
+func (api *PrivateAdminAPI) SetSyncBandwidth(limits downloader.Bandwidth) bool {
+	api.eth.Downloader().SetBandwidth(limits)
+	log.Info("Changed sync bandwidth caps", "ingress", limits.Ingress, "egress", limits.Egress)
+	return true
+}
+
+// SyncBandwidth retrieves the current caps of the sync traffic.
+func (api *PrivateAdminAPI) SyncBandwidth() downloader.Bandwidth {
+	return api.eth.Downloader().Bandwidth()
+}
+
+// PeerScores retrieves the reputation of the recently seen peers, keyed by
+// their short id.
+func (api *PrivateAdminAPI) PeerScores() map[string]PeerScore {
+	return api.eth.protocolManager.reputation.scores()
+}
+
+// BanPeer disconnects a peer, given by node id or enode URL, and refuses it
+// for the given number of seconds, an hour by default.
+func (api *PrivateAdminAPI) BanPeer(id string, seconds *uint64) (bool, error) {
+	short, err := shortPeerID(id)
+	if err != nil {
+		return false, err
+	}
+	duration := defaultBanDuration
+	if seconds != nil {
+		duration = time.Duration(*seconds) * time.Second
+	}
+	api.eth.protocolManager.reputation.ban(short, duration)
+	return true, nil
+}
+
+// UnbanPeer lifts the ban of a peer and resets its reputation, returning
+// whether the peer was banned.
+func (api *PrivateAdminAPI) UnbanPeer(id string) (bool, error) {
+	short, err := shortPeerID(id)
+	if err != nil {
+		return false, err
+	}
+	return api.eth.protocolManager.reputation.unban(short), nil
+}
+
+// Blocklist retrieves the contracts the transaction pool and eth_call refuse
+// to interact with.
+func (api *PrivateAdminAPI) Blocklist() core.BlocklistConfig {
+	return api.eth.blocklist.Config()
+}
+
+// SetBlocklist replaces the contracts the transaction pool and eth_call refuse
+// to interact with. Transactions already pooled are not affected.
+func (api *PrivateAdminAPI) SetBlocklist(config core.BlocklistConfig) bool {
+	api.eth.blocklist.Set(config)
+	return true
+}
+
+// Tenants retrieves the transaction pool usage and quotas of the tenants of the
+// RPC API.
+func (api *PrivateAdminAPI) Tenants() []core.TenantStatus {
+	return api.eth.txPool.Tenants()
+}
+
+// PeerScaling retrieves the role, bounds and current value of the eth peer
+// target, along with the last measured load.
+func (api *PrivateAdminAPI) PeerScaling() PeerScaling {
+	return api.eth.protocolManager.scaler.status()
+}
+
+// SetPeerScaling changes the role, bounds or adaptiveness of the eth peer
+// target, disconnecting the worst reputed peers above the new target.
+func (api *PrivateAdminAPI) SetPeerScaling(args PeerScalingArgs) (PeerScaling, error) {
+	scaler := api.eth.protocolManager.scaler
+	if err := scaler.update(args); err != nil {
+		return PeerScaling{}, err
+	}
+	return scaler.status(), nil
+}
+
+// HeadBeacons returns the chain heads last announced over discovery by the
+// nodes of the network, highest first, flagging those the local chain agrees
+// with.
+func (api *PrivateAdminAPI) HeadBeacons() ([]*HeadBeacon, error) {
+	if api.eth.beacons == nil {
+		return nil, errors.New("head beacons not running")
+	}
+	return api.eth.beacons.Heads(), nil
+}
+
+// ChainWatchdog returns the peers advertising another genesis than the local
+// one or an incompatible fork, and whether the watchdog raised its alarm.
+func (api *PrivateAdminAPI) ChainWatchdog() (*WatchdogStatus, error) {
+	if api.eth.watchdog == nil {
+		return nil, errWatchdogDisabled
+	}
+	return api.eth.watchdog.Status(), nil
+}
+
+// DbSupervisor returns the number of integrity checks run on the chain database
+// and the corruption detected, if any.
+func (api *PrivateAdminAPI) DbSupervisor() (*DBSupervisorStatus, error) {
+	if api.eth.dbSuper == nil {
+		return nil, errDBSupervisorDisabled
+	}
+	return api.eth.dbSuper.status(), nil
+}
+
+// SqlMirror returns the last block mirrored into the relational database and
+// the last failure to mirror, if any.
+func (api *PrivateAdminAPI) SqlMirror() (*SQLMirrorStatus, error) {
+	if api.eth.sqlMirror == nil {
+		return nil, errSQLMirrorDisabled
+	}
+	return api.eth.sqlMirror.status(), nil
+}
+
+// RegisterStorageLayout stores the Solidity storage layout of a contract, as
+// emitted by solc --storage-layout, used to decode its storage.
+func (api *PrivateAdminAPI) RegisterStorageLayout(address common.Address, l *layout.Layout) (bool, error) {
+	if l == nil {
+		return false, errors.New("missing storage layout")
+	}
+	if err := l.Validate(); err != nil {
+		return false, err
+	}
+	if err := layout.Write(api.eth.ChainDb(), address, l); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PublicDebugAPI is the collection of Indigo full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -277,6 +643,7 @@ func NewPublicDebugAPI(eth *Indigo) *PublicDebugAPI {
 
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(ctx context.Context, blockNr rpc.BlockNumber) (state.Dump, error) {
+	blockNr = api.eth.resolveBlockNumber(blockNr)
 	if blockNr == rpc.PendingBlockNumber {
 		// If we're dumping the pending state, we need to request
 		// both the pending block as well as the pending state from
@@ -319,10 +686,171 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return db.Get(hash.Bytes())
 }
 
-// GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network
-// and returns them as a JSON list of block-hashes
-func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]core.BadBlockArgs, error) {
-	return api.eth.BlockChain().BadBlocks()
+// GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network,
+// with the errors rejecting them and the receipts produced before the failures. The
+// structured EVM traces of the blocks are included if trace is set.
+func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context, trace *bool) ([]core.BadBlockArgs, error) {
+	return api.eth.BlockChain().BadBlocks(trace != nil && *trace)
+}
+
+// ReplayBadBlock executes a stored bad block again on top of its parent state,
+// reporting whether it is still rejected and the state root it results in.
+func (api *PrivateDebugAPI) ReplayBadBlock(ctx context.Context, hash common.Hash, trace *bool) (*core.BadBlockReplay, error) {
+	return api.eth.BlockChain().ReplayBadBlock(ctx, hash, trace != nil && *trace)
+}
+
+// stateDiagBlock retrieves a block known locally, or rejected on import.
+func (api *PrivateDebugAPI) stateDiagBlock(hash common.Hash) (*types.Block, error) {
+	if block := api.eth.BlockChain().GetBlockByHash(hash); block != nil {
+		return block, nil
+	}
+	if bad := core.GetBadBlock(api.eth.ChainDb(), hash); bad != nil {
+		return bad.Block, nil
+	}
+	return nil, fmt.Errorf("block %x not found", hash)
+}
+
+// StateDiag executes a block, canonical or rejected, on top of its parent state,
+// returning the state root after each transaction and the accounts and storage
+// slots each wrote.
+func (api *PrivateDebugAPI) StateDiag(ctx context.Context, hash common.Hash) (*core.StateDiag, error) {
+	block, err := api.stateDiagBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	return api.eth.BlockChain().StateDiag(ctx, block)
+}
+
+// DiagnoseStateRoot compares the local execution of a block with the one of a
+// peer, pinpointing the first transaction and the keys at which they diverge.
+func (api *PrivateDebugAPI) DiagnoseStateRoot(ctx context.Context, hash common.Hash) (*StateDiagReport, error) {
+	block, err := api.stateDiagBlock(hash)
+	if err != nil {
+		return nil, err
+	}
+	return api.eth.stateDiags.diagnose(ctx, block), nil
+}
+
+// StateDiagReports returns the last diagnosed blocks, including those rejected
+// for their state root, most recent first.
+func (api *PrivateDebugAPI) StateDiagReports() []*StateDiagReport {
+	return api.eth.stateDiags.Reports()
+}
+
+// GetBlockWitness executes a block on top of its parent state, returning the
+// RLP encoded witness of the headers, trie nodes and contract code it reads.
+func (api *PrivateDebugAPI) GetBlockWitness(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	block := api.eth.BlockChain().GetBlockByHash(hash)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	witness, err := api.eth.BlockChain().BlockWitness(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(witness)
+}
+
+// VerifyBlockWitness executes the block of an RLP encoded witness using only the
+// state it carries, checking the resulting state root.
+func (api *PrivateDebugAPI) VerifyBlockWitness(ctx context.Context, blob hexutil.Bytes) error {
+	witness := new(core.BlockWitness)
+	if err := rlp.DecodeBytes(blob, witness); err != nil {
+		return err
+	}
+	return core.VerifyBlockWitness(ctx, api.eth.BlockChain().Config(), api.eth.Engine(), witness)
+}
+
+// GetDecodedStorage decodes the storage of a contract at a block into its named
+// variables using the registered storage layout. Mapping entries are decoded for
+// the given keys only, listed by variable path, see layout.Decode.
+func (api *PrivateDebugAPI) GetDecodedStorage(ctx context.Context, address common.Address, blockNr rpc.BlockNumber, keys map[string][]string) ([]*layout.Value, error) {
+	l := layout.Read(api.eth.ChainDb(), address)
+	if l == nil {
+		return nil, fmt.Errorf("no storage layout registered for %x", address)
+	}
+	statedb, header, err := api.eth.ApiBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil || header == nil {
+		return nil, fmt.Errorf("block #%d not found", blockNr)
+	}
+	var dbErr error
+	values, err := layout.Decode(l, func(slot common.Hash) common.Hash {
+		value, err := statedb.GetStateErr(address, slot)
+		if err != nil && dbErr == nil {
+			dbErr = err
+		}
+		return value
+	}, keys)
+	if err != nil {
+		return nil, err
+	}
+	return values, dbErr
+}
+
+// SnapshotProgress returns the state of the flat state snapshot, including
+// the progress of its background generation.
+func (api *PrivateDebugAPI) SnapshotProgress() (map[string]interface{}, error) {
+	progress := api.eth.BlockChain().SnapshotProgress()
+	if progress == nil {
+		return nil, errors.New("state snapshot disabled")
+	}
+	return map[string]interface{}{
+		"root":       progress.Root,
+		"layers":     hexutil.Uint64(progress.Layers),
+		"generating": progress.Generating,
+		"marker":     progress.Marker,
+		"accounts":   hexutil.Uint64(progress.Accounts),
+		"slots":      hexutil.Uint64(progress.Slots),
+		"elapsed":    progress.Elapsed.String(),
+	}, nil
+}
+
+// DbStats reports the number and size of the chain database entries by key
+// prefix. The database is scanned fully unless a sample rate is given, in which
+// case only one in sample of the key ranges is scanned and the rest estimated.
+func (api *PrivateDebugAPI) DbStats(ctx context.Context, sample *uint64) (*core.DBStats, error) {
+	rate := uint64(1)
+	if sample != nil {
+		rate = *sample
+	}
+	return core.InspectDatabase(ctx, api.eth.ChainDb(), rate)
+}
+
+// BloomIndexerStatus reports the progress of the bloombits indexer, including
+// the sections still waiting to be indexed and the rate they are indexed at.
+func (api *PrivateDebugAPI) BloomIndexerStatus() core.ChainIndexerStatus {
+	return api.eth.bloomIndexer.Status()
+}
+
+// defaultVmStatsContracts is the number of contracts reported by debug_vmStats
+// if not specified.
+const defaultVmStatsContracts = 20
+
+// VmStats reports the opcodes executed during block processing, and the top
+// contracts consuming the most gas, by default 20 of them.
+func (api *PrivateDebugAPI) VmStats(top *int) (*vm.StatsReport, error) {
+	stats := api.eth.BlockChain().GetVMConfig().Stats
+	if stats == nil {
+		return nil, errors.New("vm statistics disabled")
+	}
+	n := defaultVmStatsContracts
+	if top != nil {
+		n = *top
+	}
+	return stats.Report(n), nil
+}
+
+// ResetVmStats clears the VM statistics gathered so far.
+func (api *PrivateDebugAPI) ResetVmStats() error {
+	stats := api.eth.BlockChain().GetVMConfig().Stats
+	if stats == nil {
+		return errors.New("vm statistics disabled")
+	}
+	stats.Reset()
+	return nil
 }
 
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
@@ -434,11 +962,11 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 		return nil, fmt.Errorf("start block height (%d) must be less than end block height (%d)", startBlock.Number().Uint64(), endBlock.Number().Uint64())
 	}
 
-	oldTrie, err := trie.NewSecure(startBlock.Root(), trie.NewDatabase(api.eth.chainDb), 0)
+	oldTrie, err := trie.NewSecure(startBlock.Root(), trie.NewDatabaseWithCache(api.eth.chainDb, api.eth.blockchain.TrieCleanCache()), 0)
 	if err != nil {
 		return nil, err
 	}
-	newTrie, err := trie.NewSecure(endBlock.Root(), trie.NewDatabase(api.eth.chainDb), 0)
+	newTrie, err := trie.NewSecure(endBlock.Root(), trie.NewDatabaseWithCache(api.eth.chainDb, api.eth.blockchain.TrieCleanCache()), 0)
 	if err != nil {
 		return nil, err
 	}
@@ -18,7 +18,9 @@ package eth
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"time"
 
 	"go.opencensus.io/trace"
 
@@ -50,6 +52,10 @@ func (b *EthApiBackend) ChainConfig() *params.ChainConfig {
 	return b.eth.chainConfig
 }
 
+func (b *EthApiBackend) Blocklist() *core.Blocklist {
+	return b.eth.blocklist
+}
+
 func (b *EthApiBackend) InitialSupply() *big.Int {
 	return b.initialSupply
 }
@@ -75,6 +81,7 @@ func (b *EthApiBackend) SetHead(number uint64) {
 func (b *EthApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	ctx, span := trace.StartSpan(ctx, "EthApiBackend.HeaderByNumber")
 	defer span.End()
+	blockNr = b.eth.resolveBlockNumber(blockNr)
 	// Pending block is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
 		block := b.eth.miner.PendingBlock(ctx)
@@ -90,10 +97,34 @@ func (b *EthApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	return b.eth.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
+func (b *EthApiBackend) HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error) {
+	ctx, span := trace.StartSpan(ctx, "EthApiBackend.HeaderByHash")
+	defer span.End()
+	return b.eth.blockchain.GetHeaderByHash(blockHash), nil
+}
+
+func (b *EthApiBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.HeaderByNumber(ctx, blockNr)
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := b.eth.blockchain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, &core.BlockNotFoundError{Hash: hash}
+		}
+		if blockNrOrHash.RequireCanonical && core.GetCanonicalHash(b.eth.chainDb, header.Number.Uint64()) != hash {
+			return nil, &core.NonCanonicalBlockError{Hash: hash, Number: header.Number.Uint64()}
+		}
+		return header, nil
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
 func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
 	ctx, span := trace.StartSpan(ctx, "EthApiBackend.BlockByNumber")
 	defer span.End()
 	span.AddAttributes(trace.Int64Attribute("num", int64(blockNr)))
+	blockNr = b.eth.resolveBlockNumber(blockNr)
 	// Pending block is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
 		block := b.eth.miner.PendingBlock(ctx)
@@ -106,14 +137,14 @@ func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	return b.eth.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
 
-func (b *EthApiBackend) StateQuery(ctx context.Context, blockNr rpc.BlockNumber, fn func(*state.StateDB) error) error {
+func (b *EthApiBackend) StateQuery(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, fn func(*state.StateDB) error) error {
 	ctx, span := trace.StartSpan(ctx, "EthApiBackend.StateQuery")
 	defer span.End()
 	// Pending state is only known by the miner
-	if blockNr == rpc.PendingBlockNumber {
+	if blockNr, ok := blockNrOrHash.Number(); ok && blockNr == rpc.PendingBlockNumber {
 		return b.eth.miner.PendingQuery(fn)
 	}
-	header, err := b.HeaderByNumber(ctx, blockNr)
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
 	if header == nil || err != nil {
 		return err
 	}
@@ -137,7 +168,19 @@ func (b *EthApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	if header == nil || err != nil {
 		return nil, nil, err
 	}
-	stateDb, err := b.eth.BlockChain().StateAt(header.Root)
+	stateDb, err := b.eth.BlockChain().HistoricalStateAt(header)
+	return stateDb, header, err
+}
+
+func (b *EthApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.StateAndHeaderByNumber(ctx, blockNr)
+	}
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	stateDb, err := b.eth.BlockChain().HistoricalStateAt(header)
 	return stateDb, header, err
 }
 
@@ -150,7 +193,11 @@ func (b *EthApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*t
 func (b *EthApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
 	ctx, span := trace.StartSpan(ctx, "EthApiBackend.GetReceipts")
 	defer span.End()
-	return core.GetBlockReceipts(b.eth.chainDb, blockHash, core.GetBlockNumber(b.eth.chainDb, blockHash)), nil
+	number := core.GetBlockNumber(b.eth.chainDb, blockHash)
+	if err := b.eth.blockchain.CheckReceiptsRetained(number); err != nil {
+		return nil, err
+	}
+	return core.GetBlockReceipts(b.eth.chainDb, blockHash, number), nil
 }
 
 func (b *EthApiBackend) GetTd(blockHash common.Hash) *big.Int {
@@ -170,6 +217,10 @@ func (b *EthApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEven
 	return b.eth.BlockChain().SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *EthApiBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeChainReorgEvent(ch)
+}
+
 func (b *EthApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.BlockChain().SubscribeChainEvent(ch)
 }
@@ -186,10 +237,51 @@ func (b *EthApiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 	return b.eth.BlockChain().SubscribeLogsEvent(ch)
 }
 
+func (b *EthApiBackend) GetAddressTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.AddressTx, error) {
+	if b.eth.addrIndexer == nil {
+		return nil, errors.New("address index not enabled")
+	}
+	return core.GetAddressTxs(b.eth.chainDb, addr, from, to, skip, limit)
+}
+
+func (b *EthApiBackend) GetInternalTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.InternalTx, error) {
+	if b.eth.intIndexer == nil {
+		return nil, errors.New("internal transaction index not enabled")
+	}
+	return core.GetInternalTxs(b.eth.chainDb, addr, from, to, skip, limit)
+}
+
+// SendTx adds a transaction to the local pool. Transactions valid until a
+// deadline are refused unless the local miner is running, as they are withheld
+// from peers and nothing else could include them.
 func (b *EthApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if err := b.checkDeadline(ctx); err != nil {
+		return err
+	}
+	if tenant, ok := rpc.TenantFromContext(ctx); ok && tenant != "" {
+		return b.eth.txPool.AddTenant(ctx, tenant, signedTx)
+	}
 	return b.eth.txPool.AddLocal(ctx, signedTx)
 }
 
+// SendPrivateTx adds a transaction to the local pool without propagating it.
+// Deadlines are refused unless the local miner is running, as for SendTx.
+func (b *EthApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	if err := b.checkDeadline(ctx); err != nil {
+		return err
+	}
+	return b.eth.txPool.AddPrivate(ctx, signedTx)
+}
+
+// checkDeadline refuses transactions submitted valid until a deadline if the
+// local miner is not running.
+func (b *EthApiBackend) checkDeadline(ctx context.Context) error {
+	if _, ok := core.ValidUntilFromContext(ctx); ok && !b.eth.IsMining() {
+		return errors.New("transaction deadlines require the local miner to be running")
+	}
+	return nil
+}
+
 func (b *EthApiBackend) GetPoolTransactions() types.Transactions {
 	ctx := context.TODO()
 	return b.eth.txPool.PendingList(ctx)
@@ -199,6 +291,14 @@ func (b *EthApiBackend) GetPoolTransaction(hash common.Hash) *types.Transaction
 	return b.eth.txPool.Get(hash)
 }
 
+func (b *EthApiBackend) TxArrivalTime(hash common.Hash) (time.Time, bool) {
+	return b.eth.txPool.ArrivalTime(hash)
+}
+
+func (b *EthApiBackend) TxOrderCandidates(commitment common.Hash) ([]types.TxArrival, bool) {
+	return b.eth.miner.TxOrderCandidates(commitment)
+}
+
 func (b *EthApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
 	return b.eth.txPool.State().GetNonce(addr), nil
 }
@@ -215,10 +315,18 @@ func (b *EthApiBackend) TxPoolContent(ctx context.Context) (map[common.Address]t
 	return b.eth.TxPool().Content(ctx)
 }
 
+func (b *EthApiBackend) TxPoolAccount(addr common.Address) (*core.TxPoolAccount, error) {
+	return b.eth.TxPool().InspectAccount(addr), nil
+}
+
 func (b *EthApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.TxPool().SubscribeNewTxsEvent(ch)
 }
 
+func (b *EthApiBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeTxLifecycleEvent(ch)
+}
+
 func (b *EthApiBackend) Downloader() *downloader.Downloader {
 	return b.eth.Downloader()
 }
@@ -245,7 +353,12 @@ func (b *EthApiBackend) AccountManager() *accounts.Manager {
 
 func (b *EthApiBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.eth.bloomIndexer.Sections()
-	return params.BloomBitsBlocks, sections
+	return b.eth.chainConfig.BloomParams().SectionSize, sections
+}
+
+func (b *EthApiBackend) BloomParams() (uint, uint) {
+	bloom := b.eth.chainConfig.BloomParams()
+	return bloom.Bits, bloom.Hashes
 }
 
 func (b *EthApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
@@ -17,13 +17,23 @@
 package eth
 
 import (
+	"context"
+	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
 	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
 	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -89,3 +99,103 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that balance changes are reported for the blocks modifying the balance
+// of an account, whether they are checked against the state snapshot or not.
+func TestBalanceChanges(t *testing.T) {
+	for _, snapshot := range []bool{false, true} {
+		testBalanceChanges(t, snapshot)
+	}
+}
+
+func testBalanceChanges(t *testing.T, snapshot bool) {
+	var (
+		ctx       = context.Background()
+		db        = ethdb.NewMemDatabase()
+		engine    = clique.NewFaker()
+		recipient = common.Address{0x12, 0x34}
+		gspec     = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}},
+			Signer: make([]byte, 65),
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	cacheConfig := &core.CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: time.Minute, Snapshot: snapshot}
+	blockchain, err := core.NewBlockChain(db, cacheConfig, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	// Transfer to the recipient in blocks 2 and 4
+	chain, _ := core.GenerateChain(ctx, gspec.Config, genesis, engine, db, 5, func(ctx context.Context, i int, block *core.BlockGen) {
+		if i == 1 || i == 3 {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), recipient, big.NewInt(int64(1000*(i+1))), params.TxGas, nil, nil), types.HomesteadSigner{}, testBankKey)
+			block.AddTx(ctx, tx)
+		}
+	})
+	if _, err := blockchain.InsertChain(ctx, chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if snapshot {
+		if _, known := blockchain.AccountModified(chain[2].Root(), chain[1].Root(), recipient); !known {
+			t.Errorf("snapshot transition of block 3 unknown")
+		}
+	}
+	tests := []struct {
+		addr     common.Address
+		from, to uint64
+		want     []string // block:balance:delta
+	}{
+		{recipient, 0, 5, []string{"2:2000:2000", "4:6000:4000"}},
+		{recipient, 3, 5, []string{"4:6000:4000"}},
+		{recipient, 3, 3, nil},
+		{testBank, 0, 5, []string{"0:1000000:1000000", "2:998000:-2000", "4:994000:-4000"}},
+		{testBank, 1, 5, []string{"2:998000:-2000", "4:994000:-4000"}},
+	}
+	for i, tt := range tests {
+		changes, err := balanceChanges(ctx, blockchain, tt.addr, tt.from, tt.to)
+		if err != nil {
+			t.Errorf("snapshot %v, test %d: failed to retrieve changes: %v", snapshot, i, err)
+			continue
+		}
+		var have []string
+		for _, change := range changes {
+			if hash := blockchain.GetHeaderByNumber(uint64(change.BlockNumber)).Hash(); change.BlockHash != hash {
+				t.Errorf("snapshot %v, test %d: block %d hash mismatch", snapshot, i, change.BlockNumber)
+			}
+			have = append(have, fmt.Sprintf("%d:%v:%v", change.BlockNumber, change.Balance.ToInt(), change.Delta.ToInt()))
+		}
+		if !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("snapshot %v, test %d: changes mismatch: have %v, want %v", snapshot, i, have, tt.want)
+		}
+	}
+}
+
+func TestNewPendingBlock(t *testing.T) {
+	txs := types.Transactions{
+		types.NewTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil),
+		types.NewTransaction(1, common.Address{0x02}, big.NewInt(1), 50000, big.NewInt(1), nil),
+	}
+	receipts := types.Receipts{
+		types.NewReceipt(nil, false, 21000),
+		types.NewReceipt(nil, false, 61000),
+	}
+	header := &types.Header{Number: big.NewInt(7), GasLimit: 1000000, GasUsed: 61000}
+	ev := core.PendingBlockEvent{Block: types.NewBlock(header, txs, nil, receipts), Receipts: receipts}
+
+	pending := newPendingBlock(ev, false)
+	if pending.Header.Number.Uint64() != 7 || pending.Header.GasUsed != 61000 {
+		t.Errorf("header mismatch: have number %v, gas used %d", pending.Header.Number, pending.Header.GasUsed)
+	}
+	if want := []common.Hash{txs[0].Hash(), txs[1].Hash()}; !reflect.DeepEqual(pending.Transactions, want) {
+		t.Errorf("transaction hashes mismatch: have %v, want %v", pending.Transactions, want)
+	}
+	if want := []hexutil.Uint64{21000, 61000}; !reflect.DeepEqual(pending.CumulativeGasUsed, want) {
+		t.Errorf("cumulative gas mismatch: have %v, want %v", pending.CumulativeGasUsed, want)
+	}
+	if full, ok := newPendingBlock(ev, true).Transactions.(types.Transactions); !ok || len(full) != 2 || full[1].Hash() != txs[1].Hash() {
+		t.Errorf("full transactions mismatch: have %v", newPendingBlock(ev, true).Transactions)
+	}
+}
@@ -95,6 +95,7 @@ func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.Block
 	// Fetch the block interval that we want to trace
 	var from, to *types.Block
 
+	start, end = api.eth.resolveBlockNumber(start), api.eth.resolveBlockNumber(end)
 	switch start {
 	case rpc.PendingBlockNumber:
 		from = api.eth.miner.PendingBlock(ctx)
@@ -134,7 +135,7 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 
 	// Ensure we have a valid starting state before doing any work
 	origin := start.NumberU64()
-	database := state.NewDatabase(api.eth.ChainDb())
+	database := state.NewDatabaseWithCache(api.eth.ChainDb(), api.eth.blockchain.TrieCleanCache())
 
 	if number := start.NumberU64(); number > 0 {
 		start = api.eth.blockchain.GetBlock(start.ParentHash(), start.NumberU64()-1)
@@ -342,7 +343,7 @@ func (api *PrivateDebugAPI) TraceBlockByNumber(ctx context.Context, number rpc.B
 	// Fetch the block that we want to trace
 	var block *types.Block
 
-	switch number {
+	switch number = api.eth.resolveBlockNumber(number); number {
 	case rpc.PendingBlockNumber:
 		block = api.eth.miner.PendingBlock(ctx)
 	case rpc.LatestBlockNumber:
@@ -479,7 +480,7 @@ func (api *PrivateDebugAPI) computeStateDB(ctx context.Context, block *types.Blo
 	}
 	// Otherwise try to reexec blocks until we find a state or reach our limit
 	origin := block.NumberU64()
-	database := state.NewDatabase(api.eth.ChainDb())
+	database := state.NewDatabaseWithCache(api.eth.ChainDb(), api.eth.blockchain.TrieCleanCache())
 
 	for i := uint64(0); i < reexec; i++ {
 		block = api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
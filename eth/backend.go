@@ -18,10 +18,12 @@
 package eth
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 
@@ -31,16 +33,20 @@ import (
 	"github.com/fulcrumchain/indigo/consensus/clique"
 	"github.com/fulcrumchain/indigo/core"
 	"github.com/fulcrumchain/indigo/core/bloombits"
+	"github.com/fulcrumchain/indigo/core/importhook"
 	"github.com/fulcrumchain/indigo/core/types"
 	"github.com/fulcrumchain/indigo/core/vm"
 	"github.com/fulcrumchain/indigo/eth/downloader"
+	"github.com/fulcrumchain/indigo/eth/era"
 	"github.com/fulcrumchain/indigo/eth/filters"
 	"github.com/fulcrumchain/indigo/eth/gasprice"
 	"github.com/fulcrumchain/indigo/ethdb"
 	"github.com/fulcrumchain/indigo/ethdb/archive"
 	"github.com/fulcrumchain/indigo/event"
 	"github.com/fulcrumchain/indigo/internal/ethapi"
+	"github.com/fulcrumchain/indigo/internal/jobs"
 	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
 	"github.com/fulcrumchain/indigo/miner"
 	"github.com/fulcrumchain/indigo/node"
 	"github.com/fulcrumchain/indigo/p2p"
@@ -73,12 +79,26 @@ type Indigo struct {
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
 
+	importHook *importhook.Client // Connection to the external import policy, if any
+	blocklist  *core.Blocklist    // Contracts transactions and calls to are refused
+	congestion *poolCongestion    // Transaction pool congestion fed to the gas price oracle, if weighted
+	watcher    *watcher           // Credits and debits of the watched addresses, nil if none
+	scheduler  *scheduler         // Transactions held for a later broadcast, nil if disabled
+	beacons    *headBeacons       // Chain heads announced over discovery, nil until started
+	watchdog   *chainWatchdog     // Observer of the peer chain configurations, nil if disabled
+	stateDiags *stateDiagnostics  // Comparison of the blocks rejected for their state root with peers
+	dbSuper    *dbSupervisor      // Checker of the chain database integrity, nil if disabled
+	sqlMirror  *sqlMirror         // Relational mirror of the canonical chain metadata, nil if disabled
+	jobs       *jobs.Manager      // Background jobs run through the debug API
+
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *accounts.Manager
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	addrIndexer   *core.ChainIndexer             // Address transaction indexer, nil if disabled
+	intIndexer    *core.ChainIndexer             // Internal transaction indexer, nil if disabled
 
 	ApiBackend *EthApiBackend
 
@@ -94,6 +114,12 @@ type Indigo struct {
 
 func (gc *Indigo) AddLesServer(ls LesServer) {
 	gc.lesServer = ls
+
+	// Light clients retrieve the header blooms in sections of the default size
+	if bloom := gc.chainConfig.BloomParams(); !bloom.HeaderSized() || bloom.SectionSize != params.BloomBitsBlocks {
+		log.Warn("Bloom trie not served to light clients, bloom parameters customised")
+		return
+	}
 	ls.SetBloomBitsIndexer(gc.bloomIndexer)
 }
 
@@ -106,6 +132,23 @@ func New(sctx *node.ServiceContext, config *Config) (*Indigo, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.InternalIndex && !config.NoPruning {
+		return nil, errors.New("internal transaction index requires an archive node (--gcmode=archive)")
+	}
+	if role := config.PeerScaling.Role; role != "" {
+		if _, ok := peerRoleShares[role]; !ok {
+			return nil, fmt.Errorf("invalid peer role %q", role)
+		}
+	}
+	// Start over if the supervisor found the chain database corrupted
+	var resynced bool
+	if dir := sctx.ResolvePath("chaindata"); dir != "" {
+		quarantined, err := quarantineChainDB(dir, sctx.ResolvePath(dbCorruptedMarker), config.DBSupervisor.Resync)
+		if err != nil {
+			return nil, err
+		}
+		resynced = quarantined
+	}
 	chainDb, err := CreateDB(sctx, config, "chaindata")
 	if err != nil {
 		return nil, err
@@ -114,15 +157,19 @@ func New(sctx *node.ServiceContext, config *Config) (*Indigo, error) {
 	stopDbUpgrade := upgradeDeduplicateData(chainDb)
 
 	if config.Archive.Endpoint != "" {
-		ar, err := archive.NewArchive(config.Archive)
+		arConfig := config.Archive
+		if arConfig.SpillDir == "" {
+			arConfig.SpillDir = sctx.ResolvePath("archivespill")
+		}
+		ar, err := archive.NewArchive(arConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to connect to archive: %s", err)
 		}
-		ar.Meter("db/archive/chaindata/")
+		ar.Meter(metrics.DB("chaindata"))
 		if ldb, ok := chainDb.(*ethdb.LDBDatabase); !ok {
 			return nil, fmt.Errorf("only ethdb.LDBDatabase maybe be archived, but found: %T", chainDb)
-		} else {
-			chainDb = archive.NewDB(ldb, ar)
+		} else if chainDb, err = archive.NewDB(ldb, ar); err != nil {
+			return nil, fmt.Errorf("failed to open archive queue: %s", err)
 		}
 	}
 
@@ -153,10 +200,12 @@ func New(sctx *node.ServiceContext, config *Config) (*Indigo, error) {
 		gasPrice:       config.GasPrice,
 		etherbase:      config.Etherbase,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
-		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
+		bloomIndexer:   NewSizedBloomIndexer(chainDb, chainConfig.BloomParams()),
+		jobs:           jobs.NewManager(maxRunningJobs, jobRetention),
 	}
 
 	log.Info("Initialising Indigo protocol", "versions", ProtocolVersions, "network", config.NetworkId)
+	metrics.SetNodeLabel(metrics.LabelNetwork, strconv.FormatUint(config.NetworkId, 10))
 
 	if !config.SkipBcVersionCheck {
 		bcVersion := core.GetBlockChainVersion(chainDb)
@@ -167,12 +216,19 @@ func New(sctx *node.ServiceContext, config *Config) (*Indigo, error) {
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, TrieCleanLimit: config.TrieClean, Snapshot: config.Snapshot, ReceiptRetention: config.ReceiptRetention, TxWorkers: config.TxWorkers, HistoryCache: config.HistoryCache, HistoryInterval: config.HistoryInterval, HistoryLifetime: config.HistoryLifetime}
 	)
+	if config.VMStats {
+		vmConfig.Stats = vm.NewStats()
+	}
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, eth.chainConfig, eth.engine, vmConfig)
 	if err != nil {
 		return nil, err
 	}
+	log.SetChainHead(func() (uint64, [32]byte) {
+		head := eth.blockchain.CurrentBlock()
+		return head.NumberU64(), head.Hash()
+	})
 	if arDB, ok := eth.chainDb.(*archive.DB); ok {
 		arDB.Start(func(prefix byte) uint64 {
 			switch prefix {
@@ -194,20 +250,83 @@ func New(sctx *node.ServiceContext, config *Config) (*Indigo, error) {
 			log.Error("Cannot write chain config during rewind", "hash", genesisHash, "err", err)
 		}
 	}
+	eth.bloomIndexer.SetThrottle(config.BloomThrottle)
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.AddressIndex {
+		eth.addrIndexer = NewAddressIndexer(chainDb, eth.chainConfig)
+		eth.addrIndexer.Start(eth.blockchain)
+	}
+	if config.InternalIndex {
+		eth.intIndexer = NewInternalIndexer(chainDb, eth.blockchain)
+		eth.intIndexer.Start(eth.blockchain)
+	}
+	if len(config.Watch.Addresses) > 0 {
+		eth.watcher = newWatcher(config.Watch, eth.blockchain, chainDb)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = sctx.ResolvePath(config.TxPool.Journal)
 	}
 	eth.txPool = core.NewTxPool(config.TxPool, eth.chainConfig, eth.blockchain)
+	eth.blocklist = core.NewBlocklist(config.Blocklist)
+	eth.txPool.SetBlocklist(eth.blocklist)
+
+	// Install the external import policy, if any, on both chain and pool.
+	hook := config.ImportHook
+	if hook == nil && config.ImportPolicy.Endpoint != "" {
+		client, err := importhook.Dial(config.ImportPolicy.Endpoint, types.NewEIP155Signer(eth.chainConfig.ChainId))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to import policy: %v", err)
+		}
+		eth.importHook, hook = client, client
+	}
+	if hook != nil {
+		guard := core.NewImportGuard(hook, config.ImportPolicy.Timeout, config.ImportPolicy.FailOpen)
+		eth.blockchain.SetImportGuard(guard)
+		eth.txPool.SetImportGuard(guard)
+	}
 
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	eth.protocolManager.peerScaling = config.PeerScaling
+	eth.protocolManager.peerRole = eth.peerRole
+	eth.protocolManager.downloader.SetBandwidth(config.SyncBandwidth)
+	eth.protocolManager.serveStateDiags = config.ServeStateDiag
+	eth.stateDiags = newStateDiagnostics(eth.blockchain, eth.protocolManager.requestStateDiag)
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
 	if err := eth.miner.SetExtra(makeExtraData(config.ExtraData)); err != nil {
 		log.Error("Cannot set extra chain data", "err", err)
 	}
+	if err := eth.miner.SetGasLimitTarget(config.GasLimitTarget); err != nil {
+		log.Error("Cannot set gas limit target", "err", err)
+	}
+	eth.miner.SetTxOrderCommit(config.TxOrderCommit)
+	if config.Watchdog.Threshold > 0 {
+		eth.watchdog = newChainWatchdog(config.Watchdog, eth.blockchain.Genesis().Hash(), eth.protocolManager.localForkID, eth.miner.SetHalted)
+		eth.protocolManager.watchdog = eth.watchdog
+	}
+	if config.DBSupervisor.Interval > 0 {
+		eth.dbSuper = newDBSupervisor(config.DBSupervisor, eth.blockchain, chainDb, sctx.ResolvePath(dbCorruptedMarker), interruptProcess)
+	}
+	if resynced && config.DBSupervisor.Source != "" {
+		result, err := era.Import(context.Background(), eth.blockchain, config.DBSupervisor.Source, config.SyncMode == downloader.FastSync)
+		if err != nil {
+			log.Error("Failed to resync from chain segments", "dir", config.DBSupervisor.Source, "err", err)
+		} else {
+			log.Info("Resynced from chain segments", "dir", config.DBSupervisor.Source, "imported", result.Imported, "elapsed", common.PrettyDuration(result.Elapsed))
+		}
+	}
+	if config.SQLMirror.DSN != "" {
+		if eth.sqlMirror, err = newSQLMirror(config.SQLMirror, eth.blockchain, chainDb); err != nil {
+			return nil, err
+		}
+	}
+	if config.Schedule {
+		eth.scheduler = newScheduler(eth.blockchain, chainDb, func(tx *types.Transaction) error {
+			return eth.txPool.AddLocal(context.Background(), tx)
+		})
+	}
 
 	eth.ApiBackend = &EthApiBackend{
 		eth: eth,
@@ -220,6 +339,10 @@ func New(sctx *node.ServiceContext, config *Config) (*Indigo, error) {
 		gpoParams.Default = config.GasPrice
 	}
 	eth.ApiBackend.gpo = gasprice.NewOracle(eth.ApiBackend, gpoParams)
+	if gpoParams.DepthWeight > 0 || gpoParams.LatencyWeight > 0 {
+		eth.congestion = newPoolCongestion(eth.txPool)
+		eth.ApiBackend.gpo.SetCongestion(eth.congestion)
+	}
 
 	return eth, nil
 }
@@ -250,7 +373,7 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Data
 		return nil, err
 	}
 	if db, ok := db.(*ethdb.LDBDatabase); ok {
-		db.Meter("db/chaindata/")
+		db.Meter(metrics.DB("chaindata"))
 	}
 	return db, nil
 }
@@ -303,6 +426,14 @@ func (gc *Indigo) APIs() []rpc.API {
 			Namespace: "debug",
 			Version:   "1.0",
 			Service:   NewPrivateDebugAPI(gc.chainConfig, gc),
+		}, {
+			Namespace: "watch",
+			Version:   "1.0",
+			Service:   NewPrivateWatchAPI(gc),
+		}, {
+			Namespace: "schedule",
+			Version:   "1.0",
+			Service:   NewPrivateScheduleAPI(gc),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
@@ -357,11 +488,14 @@ func (gc *Indigo) StartMining(local bool) error {
 		return fmt.Errorf("etherbase missing: %v", err)
 	}
 	if clique, ok := gc.engine.(*clique.Clique); ok {
+		// The sealing key may live in the keystore or in an external signer,
+		// whichever wallet holds the etherbase signs the blocks.
 		wallet, err := gc.accountManager.Find(accounts.Account{Address: eb})
 		if wallet == nil || err != nil {
-			log.Error("Etherbase account unavailable locally", "err", err)
+			log.Error("Etherbase account unavailable locally or in external signer", "err", err)
 			return fmt.Errorf("signer missing: %v", err)
 		}
+		log.Info("Authorized clique sealer", "address", eb, "wallet", wallet.URL())
 		clique.Authorize(eb, wallet.SignHash)
 	}
 	if local {
@@ -399,6 +533,19 @@ func (gc *Indigo) Protocols() []p2p.Protocol {
 	return append(gc.protocolManager.SubProtocols, gc.lesServer.Protocols()...)
 }
 
+// peerRole derives the role of the node bounding its peer target from the
+// mining and pruning modes.
+func (gc *Indigo) peerRole() string {
+	switch {
+	case gc.IsMining():
+		return PeerRoleSealer
+	case gc.config.NoPruning:
+		return PeerRoleArchive
+	default:
+		return PeerRoleObserver
+	}
+}
+
 // Start implements node.Service, starting all internal goroutines needed by the
 // Indigo protocol implementation.
 func (gc *Indigo) Start(srvr *p2p.Server) error {
@@ -421,12 +568,14 @@ func (gc *Indigo) Start(srvr *p2p.Server) error {
 	if gc.lesServer != nil {
 		gc.lesServer.Start(srvr)
 	}
+	gc.beacons = newHeadBeacons(gc.blockchain, srvr, gc.networkId, gc.config.HeadBeacon)
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // Indigo protocol.
 func (gc *Indigo) Stop() error {
+	gc.jobs.Stop()
 	if gc.stopDbUpgrade != nil {
 		if err := gc.stopDbUpgrade(); err != nil {
 			log.Error("Cannot stop db upgrade", "err", err)
@@ -435,15 +584,53 @@ func (gc *Indigo) Stop() error {
 	if err := gc.bloomIndexer.Close(); err != nil {
 		log.Error("Cannot stop bloom indexer", "err", err)
 	}
+	if gc.addrIndexer != nil {
+		if err := gc.addrIndexer.Close(); err != nil {
+			log.Error("Cannot stop address indexer", "err", err)
+		}
+	}
+	if gc.intIndexer != nil {
+		if err := gc.intIndexer.Close(); err != nil {
+			log.Error("Cannot stop internal transaction indexer", "err", err)
+		}
+	}
+	if gc.watcher != nil {
+		gc.watcher.stop()
+	}
+	if gc.scheduler != nil {
+		gc.scheduler.stop()
+	}
+	if gc.beacons != nil {
+		gc.beacons.stop()
+	}
+	gc.stateDiags.stop()
+	if gc.watchdog != nil {
+		gc.watchdog.stop()
+	}
+	if gc.dbSuper != nil {
+		gc.dbSuper.stop()
+	}
+	if gc.sqlMirror != nil {
+		gc.sqlMirror.stop()
+	}
 	gc.blockchain.Stop()
+	log.SetChainHead(nil)
 	gc.protocolManager.Stop()
 	if gc.lesServer != nil {
 		gc.lesServer.Stop()
 	}
+	if gc.congestion != nil {
+		gc.congestion.stop()
+	}
 	gc.txPool.Stop()
 	gc.miner.Stop()
 	gc.eventMux.Stop()
 
+	if gc.importHook != nil {
+		if err := gc.importHook.Close(); err != nil {
+			log.Error("Cannot close import policy connection", "err", err)
+		}
+	}
 	gc.chainDb.Close()
 	close(gc.shutdownChan)
 
@@ -17,6 +17,8 @@
 package eth
 
 import (
+	"bytes"
+	"encoding/json"
 	"time"
 
 	"github.com/fulcrumchain/indigo/common"
@@ -50,6 +52,7 @@ const (
 // startBloomHandlers starts a batch of goroutines to accept bloom bit database
 // retrievals from possibly a range of filters and serving the data to satisfy.
 func (gc *Indigo) startBloomHandlers() {
+	size := gc.chainConfig.BloomParams().SectionSize
 	for i := 0; i < bloomServiceThreads; i++ {
 		go func() {
 			for {
@@ -61,9 +64,9 @@ func (gc *Indigo) startBloomHandlers() {
 					task := <-request
 					task.Bitsets = make([][]byte, len(task.Sections))
 					for i, section := range task.Sections {
-						head := core.GetCanonicalHash(gc.chainDb, (section+1)*params.BloomBitsBlocks-1)
+						head := core.GetCanonicalHash(gc.chainDb, (section+1)*size-1)
 						if compVector, err := core.GetBloomBits(gc.chainDb, task.Bit, section, head); err == nil {
-							if blob, err := bitutil.DecompressBytes(compVector, int(params.BloomBitsBlocks)/8); err == nil {
+							if blob, err := bitutil.DecompressBytes(compVector, int(size)/8); err == nil {
 								task.Bitsets[i] = blob
 							} else {
 								task.Error = err
@@ -89,58 +92,120 @@ const (
 	bloomThrottling = 100 * time.Millisecond
 )
 
+// bloomParamsKey is the key of the bloom parameters the index was built with in
+// the index table.
+var bloomParamsKey = []byte("params")
+
 // BloomIndexer implements a core.ChainIndexer, building up a rotated bloom bits index
-// for the Indigo header bloom filters, permitting blazing fast filtering.
+// for the Indigo header bloom filters, permitting blazing fast filtering. Larger
+// bloom filters than the header ones are computed from the receipts.
 type BloomIndexer struct {
-	size uint64 // section size to generate bloombits for
+	size   uint64 // section size to generate bloombits for
+	bits   uint   // size of the bloom filters indexed
+	hashes uint   // number of bits set per address and topic
 
 	db  ethdb.Database       // database instance to write index data and metadata into
 	gen *bloombits.Generator // generator to rotate the bloom bits crating the bloom index
 
 	section uint64      // Section is the section number being processed currently
 	head    common.Hash // Head is the hash of the last header processed
+	missing int         // Number of blocks of the section whose receipts were missing
 }
 
 // NewBloomIndexer returns a chain indexer that generates bloom bits data for the
 // canonical chain for fast logs filtering.
 func NewBloomIndexer(db ethdb.Database, size uint64) *core.ChainIndexer {
+	return NewSizedBloomIndexer(db, params.BloomConfig{SectionSize: size, Bits: params.HeaderBloomBits, Hashes: params.HeaderBloomHashes})
+}
+
+// NewSizedBloomIndexer returns a chain indexer that generates bloom bits data
+// with the given parameters. If the existing index was generated with other
+// parameters, it is generated again from scratch.
+func NewSizedBloomIndexer(db ethdb.Database, bloom params.BloomConfig) *core.ChainIndexer {
 	backend := &BloomIndexer{
-		db:   db,
-		size: size,
+		db:     db,
+		size:   bloom.SectionSize,
+		bits:   bloom.Bits,
+		hashes: bloom.Hashes,
 	}
 	table := ethdb.NewTable(db, string(core.BloomBitsIndexPrefix))
 
-	return core.NewChainIndexer(db, table, backend, size, bloomConfirms, bloomThrottling, "bloombits")
+	// Indexes predating the parameters were generated from the header blooms
+	stored := params.BloomConfig{SectionSize: bloom.SectionSize, Bits: params.HeaderBloomBits, Hashes: params.HeaderBloomHashes}
+	if blob, _ := table.Get(bloomParamsKey); len(blob) > 0 {
+		if err := json.Unmarshal(blob, &stored); err != nil {
+			log.Error("Invalid bloom index parameters", "err", err)
+		}
+	}
+	if stored != bloom {
+		log.Warn("Bloom parameters changed, regenerating log index", "sectionsize", bloom.SectionSize, "bits", bloom.Bits, "hashes", bloom.Hashes)
+		if err := table.Delete([]byte("count")); err != nil {
+			log.Error("Failed to reset bloom index", "err", err)
+		}
+	}
+	if blob, err := json.Marshal(bloom); err != nil {
+		log.Error("Failed to encode bloom index parameters", "err", err)
+	} else if err := table.Put(bloomParamsKey, blob); err != nil {
+		log.Error("Failed to store bloom index parameters", "err", err)
+	}
+	return core.NewChainIndexer(db, table, backend, bloom.SectionSize, bloomConfirms, bloomThrottling, "bloombits")
 }
 
 // Reset implements core.ChainIndexerBackend, starting a new bloombits index
 // section.
 func (b *BloomIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
-	gen, err := bloombits.NewGenerator(uint(b.size))
-	b.gen, b.section, b.head = gen, section, common.Hash{}
+	gen, err := bloombits.NewSizedGenerator(uint(b.size), b.bits)
+	b.gen, b.section, b.head, b.missing = gen, section, common.Hash{}, 0
 	return err
 }
 
 // Process implements core.ChainIndexerBackend, adding a new header's bloom into
 // the index.
 func (b *BloomIndexer) Process(header *types.Header) {
-	if err := b.gen.AddBloom(uint(header.Number.Uint64()-b.section*b.size), header.Bloom); err != nil {
+	index := uint(header.Number.Uint64() - b.section*b.size)
+
+	var err error
+	if b.bits == params.HeaderBloomBits && b.hashes == params.HeaderBloomHashes {
+		err = b.gen.AddBloom(index, header.Bloom)
+	} else {
+		err = b.gen.AddBits(index, b.receiptsBloom(header))
+	}
+	if err != nil {
 		log.Error("Cannot add bloom to indexer")
 	}
 	b.head = header.Hash()
 }
 
+// receiptsBloom computes the bloom filter of a block from its receipts. If they
+// are missing while the header bloom shows logs, every bit is set, so that the
+// block is searched rather than skipped.
+func (b *BloomIndexer) receiptsBloom(header *types.Header) []byte {
+	receipts := core.GetBlockReceipts(b.db, header.Hash(), header.Number.Uint64())
+	if receipts == nil && header.Bloom != (types.Bloom{}) {
+		b.missing++
+		return bytes.Repeat([]byte{0xff}, int(b.bits/8))
+	}
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		logs = append(logs, receipt.Logs...)
+	}
+	return bloombits.LogsBloom(logs, b.bits, b.hashes)
+}
+
 // Commit implements core.ChainIndexerBackend, finalizing the bloom section and
 // writing it out into the database.
 func (b *BloomIndexer) Commit() error {
+	if b.missing > 0 {
+		log.Warn("Receipts missing for bloom index, blocks always searched", "section", b.section, "blocks", b.missing)
+	}
 	batch := b.db.NewBatch()
 
-	for i := 0; i < types.BloomBitLength; i++ {
-		bits, err := b.gen.Bitset(uint(i))
+	for i := uint(0); i < b.bits; i++ {
+		bits, err := b.gen.Bitset(i)
 		if err != nil {
 			return err
 		}
-		core.WriteBloomBits(batch, uint(i), b.section, b.head, bitutil.CompressBytes(bits))
+		core.WriteBloomBits(batch, i, b.section, b.head, bitutil.CompressBytes(bits))
 	}
 	return batch.Write()
 }
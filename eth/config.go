@@ -18,11 +18,13 @@ package eth
 
 import (
 	"math/big"
+	"runtime"
 	"time"
 
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/hexutil"
 	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/importhook"
 	"github.com/fulcrumchain/indigo/eth/downloader"
 	"github.com/fulcrumchain/indigo/eth/gasprice"
 	"github.com/fulcrumchain/indigo/ethdb/archive"
@@ -37,13 +39,23 @@ var DefaultConfig = Config{
 	DatabaseCache: 768,
 	TrieCache:     256,
 	TrieTimeout:   60 * time.Minute,
+	TrieClean:     256,
+	TxWorkers:     runtime.NumCPU(),
 	GasPrice:      gasprice.Default,
 
-	TxPool: core.DefaultTxPoolConfig,
+	HistoryInterval: 1000,
+	HistoryLifetime: time.Hour,
+
+	TxPool:       core.DefaultTxPoolConfig,
+	ImportPolicy: importhook.DefaultConfig,
 	GPO: gasprice.Config{
-		Blocks:     5,
-		Percentile: 60,
+		Blocks:        5,
+		Percentile:    60,
+		LatencyTarget: gasprice.DefaultLatencyTarget,
 	},
+	Finality:  DefaultFinalityConfig,
+	SQLMirror: SQLMirrorConfig{Driver: SQLMirrorSQLite},
+	Health:    DefaultHealthConfig,
 }
 
 //go:generate gencodec -type Config -field-override configMarshaling -formats toml -out gen_config.go
@@ -58,22 +70,45 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// Caps of the sync traffic in bytes per second, unlimited if zero
+	SyncBandwidth downloader.Bandwidth `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Adaptive peer target options
+	PeerScaling PeerScalingConfig `toml:",omitempty"`
+
+	// Pacing of the bloombits indexer while catching up
+	BloomThrottle core.IndexerThrottle `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	TrieCache          int
 	TrieTimeout        time.Duration
+	TrieClean          int    `toml:",omitempty"` // Memory allowance (MB) of the clean trie node cache shared by all state readers
+	Snapshot           bool   // Maintain a flat state snapshot for faster state reads
+	ReceiptRetention   uint64 `toml:",omitempty"` // Number of recent blocks to retain receipts and logs for, 0 to keep all
+	TxWorkers          int    `toml:",omitempty"` // Number of workers executing independent transactions in parallel
+	AddressIndex       bool   `toml:",omitempty"` // Index transactions by sender and recipient address
+	InternalIndex      bool   `toml:",omitempty"` // Index value transfers made by contracts by sender and recipient address
+
+	// Historical state cache options, for explorers repeatedly querying the
+	// states of popular past blocks
+	HistoryCache    int           `toml:",omitempty"` // Memory budget (MB) of the cache, 0 to disable
+	HistoryInterval uint64        `toml:",omitempty"` // Blocks whose numbers are multiples of it have their states cached
+	HistoryLifetime time.Duration `toml:",omitempty"` // Time after which a cached state expires
 
 	// Mining-related options
-	Etherbase    common.Address `toml:",omitempty"`
-	MinerThreads int            `toml:",omitempty"`
-	ExtraData    []byte         `toml:",omitempty"`
-	GasPrice     *big.Int
+	Etherbase      common.Address `toml:",omitempty"`
+	MinerThreads   int            `toml:",omitempty"`
+	ExtraData      []byte         `toml:",omitempty"`
+	GasPrice       *big.Int
+	GasLimitTarget uint64 `toml:",omitempty"` // Gas limit sealed blocks move toward, 0 for the usage based strategy
+	TxOrderCommit  bool   `toml:",omitempty"` // Order transactions by arrival and commit to the stamped candidates in the extra-data
 
 	// Transaction pool options
 	TxPool core.TxPoolConfig
@@ -84,11 +119,49 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Enables opcode and contract execution statistics of block processing
+	VMStats bool `toml:",omitempty"`
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
 	// Archive options.
 	Archive archive.Config `toml:",omitempty"`
+
+	// Import policy options. ImportHook installs an in-process policy, which
+	// takes precedence over a remote ImportPolicy endpoint.
+	ImportPolicy importhook.Config `toml:",omitempty"`
+	ImportHook   core.ImportHook   `toml:"-"`
+
+	// Contracts the transaction pool and eth_call refuse to interact with
+	Blocklist core.BlocklistConfig `toml:",omitempty"`
+
+	// Confirmation depths of the "safe" and "finalized" block tags
+	Finality FinalityConfig
+
+	// Addresses whose credits and debits are tracked, disabled if none
+	Watch WatchConfig `toml:",omitempty"`
+
+	// Hold transactions submitted for a later broadcast block or time
+	Schedule bool `toml:",omitempty"`
+
+	// Interval of the chain head beacons sent over discovery, 0 to disable
+	HeadBeacon time.Duration `toml:",omitempty"`
+
+	// Alarm and sealing halt on peers diverging from the local chain configuration
+	Watchdog WatchdogConfig `toml:",omitempty"`
+
+	// Execute recent blocks again for peers diagnosing a state root mismatch
+	ServeStateDiag bool `toml:",omitempty"`
+
+	// Integrity checks of the chain database, and resync once found corrupted
+	DBSupervisor DBSupervisorConfig `toml:",omitempty"`
+
+	// Relational database mirroring the canonical chain metadata
+	SQLMirror SQLMirrorConfig `toml:",omitempty"`
+
+	// Thresholds of the readiness checks served over HTTP
+	Health HealthConfig `toml:",omitempty"`
 }
 
 type configMarshaling struct {
@@ -0,0 +1,136 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+)
+
+const (
+	// latencyWeight is the weight of a new inclusion in the moving average of
+	// the inclusion latency.
+	latencyWeight = 0.05
+
+	// maxTrackedTxs bounds the number of executable transactions whose
+	// promotion time is remembered.
+	maxTrackedTxs = 16384
+)
+
+// poolCongestion measures the congestion of the transaction pool for the gas
+// price oracle: the gas of the executable transactions, and a moving average
+// of the time transactions stay executable before being included.
+type poolCongestion struct {
+	pool *core.TxPool
+
+	promoted map[common.Hash]time.Time // Time tracked transactions became executable
+	latency  time.Duration             // Moving average of the inclusion latency
+	lock     sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newPoolCongestion creates a congestion tracker following the lifecycle events
+// of the pool.
+func newPoolCongestion(pool *core.TxPool) *poolCongestion {
+	c := &poolCongestion{
+		pool:     pool,
+		promoted: make(map[common.Hash]time.Time),
+		quit:     make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.loop()
+	return c
+}
+
+// loop tracks the promotions and inclusions of pooled transactions.
+func (c *poolCongestion) loop() {
+	defer c.wg.Done()
+
+	events := make(chan core.TxLifecycleEvent, 256)
+	sub := c.pool.SubscribeTxLifecycleEvent(events)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-events:
+			c.track(ev, time.Now())
+		case <-sub.Err():
+			return
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// track updates the tracker with a lifecycle event observed at now.
+func (c *poolCongestion) track(ev core.TxLifecycleEvent, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	switch ev.Kind {
+	case core.TxPromoted:
+		if _, ok := c.promoted[ev.Hash]; !ok && len(c.promoted) < maxTrackedTxs {
+			c.promoted[ev.Hash] = now
+		}
+	case core.TxIncluded:
+		promoted, ok := c.promoted[ev.Hash]
+		if !ok {
+			return
+		}
+		delete(c.promoted, ev.Hash)
+
+		latency := now.Sub(promoted)
+		if c.latency == 0 {
+			c.latency = latency
+		} else {
+			c.latency += time.Duration(latencyWeight * float64(latency-c.latency))
+		}
+	case core.TxReplaced, core.TxDropped, core.TxQueued:
+		delete(c.promoted, ev.Hash)
+	}
+}
+
+// PendingGas implements gasprice.Congestion, returning the total gas of the
+// executable transactions in the pool.
+func (c *poolCongestion) PendingGas() uint64 {
+	var gas uint64
+	for _, tx := range c.pool.PendingList(context.Background()) {
+		gas += tx.Gas()
+	}
+	return gas
+}
+
+// InclusionLatency implements gasprice.Congestion, returning the moving average
+// of the time transactions stayed executable in the pool before inclusion.
+func (c *poolCongestion) InclusionLatency() time.Duration {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.latency
+}
+
+// stop terminates the tracker.
+func (c *poolCongestion) stop() {
+	close(c.quit)
+	c.wg.Wait()
+}
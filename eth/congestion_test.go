@@ -0,0 +1,58 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+)
+
+func TestPoolCongestionLatency(t *testing.T) {
+	c := &poolCongestion{promoted: make(map[common.Hash]time.Time)}
+
+	var (
+		start = time.Now()
+		a     = common.Hash{0x01}
+		b     = common.Hash{0x02}
+		d     = common.Hash{0x03}
+	)
+	c.track(core.TxLifecycleEvent{Hash: a, Kind: core.TxPromoted}, start)
+	c.track(core.TxLifecycleEvent{Hash: b, Kind: core.TxPromoted}, start)
+	c.track(core.TxLifecycleEvent{Hash: d, Kind: core.TxPromoted}, start)
+
+	// The first inclusion seeds the average, later ones move it by their weight
+	c.track(core.TxLifecycleEvent{Hash: a, Kind: core.TxIncluded}, start.Add(10*time.Second))
+	if latency := c.InclusionLatency(); latency != 10*time.Second {
+		t.Errorf("seeded latency mismatch: have %v, want %v", latency, 10*time.Second)
+	}
+	c.track(core.TxLifecycleEvent{Hash: b, Kind: core.TxIncluded}, start.Add(30*time.Second))
+	if latency, want := c.InclusionLatency(), 11*time.Second; latency != want {
+		t.Errorf("averaged latency mismatch: have %v, want %v", latency, want)
+	}
+	// Dropped and untracked transactions do not count
+	c.track(core.TxLifecycleEvent{Hash: d, Kind: core.TxDropped}, start)
+	c.track(core.TxLifecycleEvent{Hash: d, Kind: core.TxIncluded}, start.Add(time.Hour))
+	if latency, want := c.InclusionLatency(), 11*time.Second; latency != want {
+		t.Errorf("latency changed by untracked inclusion: have %v, want %v", latency, want)
+	}
+	if len(c.promoted) != 0 {
+		t.Errorf("tracked transactions leaked: %d", len(c.promoted))
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/trie"
+	lerrors "github.com/syndtr/goleveldb/leveldb/errors"
+)
+
+// dbCorruptedMarker is the file left next to the chain database once it is
+// found corrupted, keeping the node from starting on it again.
+const dbCorruptedMarker = "chaindata.corrupted"
+
+var (
+	errDBSupervisorDisabled = errors.New("database supervisor not enabled")
+
+	dbCorruptedGauge = metrics.NewGauge("eth/dbsupervisor/corrupted")
+)
+
+// DBSupervisorConfig sets how often the chain database is checked, and what is
+// done once it is found corrupted.
+type DBSupervisorConfig struct {
+	Interval time.Duration `toml:",omitempty"` // Interval of the database checks, 0 to disable
+	Restart  bool          `toml:",omitempty"` // Whether the node shuts down on corruption, for its process manager to restart it
+	Resync   bool          `toml:",omitempty"` // Whether a database marked corrupted is quarantined on start and synced again
+	Source   string        `toml:",omitempty"` // Directory of exported chain segments imported on resync, before syncing from peers
+}
+
+// DBFault is the corruption detected in the chain database.
+type DBFault struct {
+	Time   time.Time `json:"time"`
+	Head   uint64    `json:"head"` // Number of the head block when detected
+	Reason string    `json:"reason"`
+}
+
+// DBSupervisorStatus is the last verdict of the database supervisor.
+type DBSupervisorStatus struct {
+	Checks    uint64    `json:"checks"`
+	LastCheck time.Time `json:"lastCheck"`
+	Fault     *DBFault  `json:"fault"` // Nil while the database is sound
+	Marker    string    `json:"marker,omitempty"`
+}
+
+// dbSupervisor periodically checks that the head of the chain is readable in
+// full, its header, body and state, and watches the blocks rejected for errors
+// of the database rather than of the block. Missing trie nodes of the head are
+// never expected, as pruning keeps its state. Once corruption is detected, the
+// database is marked so the node refuses to start on it again, or quarantines
+// it and syncs again if so configured, and the node optionally shuts down for
+// its process manager to restart it.
+type dbSupervisor struct {
+	config   DBSupervisorConfig
+	chain    *core.BlockChain
+	db       ethdb.Database
+	marker   string // Path of the corruption marker, empty for ephemeral nodes
+	shutdown func() // Shuts the node down for a restart
+
+	checks    uint64
+	lastCheck time.Time
+	fault     *DBFault
+	lock      sync.Mutex // Protects the status fields
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newDBSupervisor starts a supervisor checking the given chain and database.
+func newDBSupervisor(config DBSupervisorConfig, chain *core.BlockChain, db ethdb.Database, marker string, shutdown func()) *dbSupervisor {
+	s := &dbSupervisor{
+		config:   config,
+		chain:    chain,
+		db:       db,
+		marker:   marker,
+		shutdown: shutdown,
+		quit:     make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.loop()
+	return s
+}
+
+// loop checks the database at a fixed interval, and right away whenever a
+// block is rejected.
+func (s *dbSupervisor) loop() {
+	defer s.wg.Done()
+
+	badCh := make(chan core.BadBlockEvent, 16)
+	sub := s.chain.SubscribeBadBlockEvent(badCh)
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.check(now)
+		case ev := <-badCh:
+			if isDBCorruption(ev.Error) {
+				s.corrupted(time.Now(), fmt.Sprintf("block #%d %x rejected: %v", ev.Block.NumberU64(), ev.Block.Hash(), ev.Error))
+			} else {
+				s.check(time.Now())
+			}
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// isDBCorruption reports whether an error stems from the database being
+// corrupted or missing data.
+func isDBCorruption(err error) bool {
+	if _, ok := err.(*trie.MissingNodeError); ok {
+		return true
+	}
+	return lerrors.IsCorrupted(err)
+}
+
+// check verifies that the head block and its state are readable, returning
+// false if the database is found corrupted.
+func (s *dbSupervisor) check(now time.Time) bool {
+	s.lock.Lock()
+	s.checks++
+	s.lastCheck = now
+	s.lock.Unlock()
+
+	head := s.chain.CurrentBlock()
+	hash, number := head.Hash(), head.NumberU64()
+
+	var reason string
+	switch {
+	case core.GetHeader(s.db, hash, number) == nil:
+		reason = fmt.Sprintf("header of head block #%d %x unreadable", number, hash)
+	case core.GetBody(s.db, hash, number) == nil:
+		reason = fmt.Sprintf("body of head block #%d %x unreadable", number, hash)
+	default:
+		if _, err := s.chain.StateAt(head.Root()); err != nil {
+			reason = fmt.Sprintf("state of head block #%d %x unreadable: %v", number, hash, err)
+		}
+	}
+	if reason == "" {
+		return true
+	}
+	s.corrupted(now, reason)
+	return false
+}
+
+// corrupted raises the alert, marks the database and shuts the node down if
+// configured. Only the first fault is acted upon.
+func (s *dbSupervisor) corrupted(now time.Time, reason string) {
+	s.lock.Lock()
+	if s.fault != nil {
+		s.lock.Unlock()
+		return
+	}
+	fault := &DBFault{Time: now, Head: s.chain.CurrentBlock().NumberU64(), Reason: reason}
+	s.fault = fault
+	s.lock.Unlock()
+
+	dbCorruptedGauge.Update(1)
+	log.Error("Chain database corrupted", "reason", reason, "marker", s.marker, "resync", s.config.Resync)
+
+	if s.marker != "" {
+		if err := writeDBFault(s.marker, fault); err != nil {
+			log.Error("Failed to mark chain database corrupted", "marker", s.marker, "err", err)
+		}
+	}
+	if s.config.Restart && s.shutdown != nil {
+		log.Warn("Shutting down on chain database corruption")
+		s.shutdown()
+	}
+}
+
+// status returns the number of checks run and the fault detected, if any.
+func (s *dbSupervisor) status() *DBSupervisorStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return &DBSupervisorStatus{Checks: s.checks, LastCheck: s.lastCheck, Fault: s.fault, Marker: s.marker}
+}
+
+// stop terminates the supervisor.
+func (s *dbSupervisor) stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// writeDBFault stores the fault in the corruption marker.
+func writeDBFault(marker string, fault *DBFault) error {
+	blob, err := json.MarshalIndent(fault, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(marker, blob, 0600)
+}
+
+// quarantineChainDB checks for a corruption marker left by the supervisor next
+// to the chain database in dir. If resync is allowed, the database is moved
+// aside for inspection and the marker removed, so the node starts over with an
+// empty one; otherwise starting is refused. It reports whether the database was
+// quarantined.
+func quarantineChainDB(dir, marker string, resync bool) (bool, error) {
+	blob, err := ioutil.ReadFile(marker)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	fault := new(DBFault)
+	if err := json.Unmarshal(blob, fault); err != nil {
+		fault.Reason = "unknown"
+	}
+	if !resync {
+		return false, fmt.Errorf("chain database marked corrupted at %v: %s (enable resync or remove %s to start anyway)", fault.Time, fault.Reason, marker)
+	}
+	quarantine := fmt.Sprintf("%s.quarantined-%d", dir, time.Now().Unix())
+	if err := os.Rename(dir, quarantine); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := os.Remove(marker); err != nil {
+		return false, err
+	}
+	log.Warn("Quarantined corrupted chain database", "path", quarantine, "reason", fault.Reason)
+	return true, nil
+}
+
+// interruptProcess shuts the node down as if interrupted by the user.
+func interruptProcess() {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(syscall.SIGTERM)
+	}
+	if err != nil {
+		log.Error("Failed to shut down", "err", err)
+	}
+}
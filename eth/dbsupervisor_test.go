@@ -0,0 +1,112 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/eth/downloader"
+)
+
+// Tests that a head block missing from the database is detected, marking the
+// database corrupted and shutting the node down once.
+func TestDBSupervisorCheck(t *testing.T) {
+	pm, db := newTestProtocolManagerMust(context.Background(), t, downloader.FullSync, 4, nil, nil)
+	defer pm.Stop()
+
+	dir, err := ioutil.TempDir("", "dbsupervisor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var shutdowns int
+	s := &dbSupervisor{
+		config:   DBSupervisorConfig{Restart: true},
+		chain:    pm.blockchain,
+		db:       db,
+		marker:   filepath.Join(dir, dbCorruptedMarker),
+		shutdown: func() { shutdowns++ },
+	}
+	if !s.check(time.Now()) {
+		t.Fatalf("sound database reported corrupted: %v", s.status().Fault)
+	}
+	head := pm.blockchain.CurrentBlock()
+	core.DeleteBody(db, head.Hash(), head.NumberU64())
+
+	for i := 0; i < 2; i++ {
+		if s.check(time.Now()) {
+			t.Fatalf("check %d: missing head body not detected", i)
+		}
+	}
+	status := s.status()
+	if status.Checks != 3 || status.Fault == nil || status.Fault.Head != head.NumberU64() {
+		t.Errorf("status mismatch: %+v", status)
+	}
+	if shutdowns != 1 {
+		t.Errorf("shutdowns mismatch: have %d, want 1", shutdowns)
+	}
+	if _, err := os.Stat(s.marker); err != nil {
+		t.Errorf("corruption marker not written: %v", err)
+	}
+}
+
+// Tests that a database marked corrupted is quarantined on start only if resync
+// is allowed.
+func TestQuarantineChainDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dbsupervisor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		chaindata = filepath.Join(dir, "chaindata")
+		marker    = filepath.Join(dir, dbCorruptedMarker)
+	)
+	if err := os.Mkdir(chaindata, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if quarantined, err := quarantineChainDB(chaindata, marker, true); quarantined || err != nil {
+		t.Fatalf("unmarked database: quarantined %v, err %v", quarantined, err)
+	}
+	if err := writeDBFault(marker, &DBFault{Time: time.Now(), Reason: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if quarantined, err := quarantineChainDB(chaindata, marker, false); quarantined || err == nil {
+		t.Fatalf("marked database without resync: quarantined %v, err %v", quarantined, err)
+	}
+	if quarantined, err := quarantineChainDB(chaindata, marker, true); !quarantined || err != nil {
+		t.Fatalf("marked database with resync: quarantined %v, err %v", quarantined, err)
+	}
+	if _, err := os.Stat(chaindata); !os.IsNotExist(err) {
+		t.Errorf("database left in place: %v", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("marker left in place: %v", err)
+	}
+	moved, _ := filepath.Glob(chaindata + ".quarantined-*")
+	if len(moved) != 1 {
+		t.Errorf("quarantined databases mismatch: have %v, want 1", moved)
+	}
+}
@@ -91,6 +91,7 @@ var (
 	errCancelHeaderProcessing  = errors.New("header processing canceled (requested)")
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
 	errNoSyncActive            = errors.New("no sync active")
+	errTerminated              = errors.New("downloader terminated")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
 )
 
@@ -145,6 +146,12 @@ type Downloader struct {
 	quitCh   chan struct{} // Quit channel to signal termination
 	quitLock sync.RWMutex  // Lock to prevent double closes
 
+	// Bandwidth throttling
+	bandwidth     Bandwidth    // Currently configured caps of the sync traffic
+	bandwidthLock sync.RWMutex // Lock protecting the configured caps
+	ingress       *throttle    // Allowance of inbound sync data
+	egress        *throttle    // Allowance of outbound sync requests
+
 	// Testing hooks
 	syncInitHook     func(uint64, uint64)  // Method to call upon initiating a new sync run
 	bodyFetchHook    func([]*types.Header) // Method to call upon starting a block body fetch
@@ -226,6 +233,8 @@ func New(mode SyncMode, stateDb ethdb.Database, mux *event.TypeMux, chain BlockC
 		stateCh:        make(chan dataPack),
 		stateSyncStart: make(chan *stateSync),
 		trackStateReq:  make(chan *stateReq),
+		ingress:        new(throttle),
+		egress:         new(throttle),
 	}
 	go dl.qosTuner()
 	go dl.stateFetcher()
@@ -272,6 +281,7 @@ func (d *Downloader) Synchronising() bool {
 func (d *Downloader) RegisterPeer(id string, version int, peer Peer) error {
 	logger := log.New("peer", id)
 	logger.Trace("Registering sync peer")
+	peer = &throttledPeer{Peer: peer, egress: d.egress}
 	if err := d.peers.Register(newPeerConnection(id, version, peer, logger)); err != nil {
 		logger.Error("Failed to register sync peer", "err", err)
 		return err
@@ -1121,8 +1131,8 @@ func (d *Downloader) fetchParts(ctx context.Context, errCancel error, deliveryCh
 			idles, total := idle()
 
 			for _, peer := range idles {
-				// Short circuit if throttling activated
-				if throttle() {
+				// Short circuit if throttling activated or the bandwidth overdrawn
+				if throttle() || d.bandwidthDelay() > 0 {
 					throttled = true
 					break
 				}
@@ -1449,7 +1459,11 @@ func (d *Downloader) processFastSyncContent(ctx context.Context, latest *types.H
 			if oldPivot != P {
 				stateSync.Cancel()
 				<-done // Wait for cancel to be received.
-				stateSync = d.syncState(P.Header.Root)
+				if oldPivot == nil {
+					stateSync = d.syncState(P.Header.Root)
+				} else {
+					stateSync = d.healState(P.Header.Root)
+				}
 				defer stateSync.Cancel()
 				go func() {
 					if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
@@ -1579,6 +1593,10 @@ func (d *Downloader) deliver(id string, destCh chan dataPack, packet dataPack, i
 	if cancel == nil {
 		return errNoSyncActive
 	}
+	// Charge the delivery to the ingress allowance, holding back further requests
+	if d.ingress.limited() {
+		d.ingress.reserve(packet.Size())
+	}
 	select {
 	case destCh <- packet:
 		return nil
@@ -40,4 +40,6 @@ var (
 
 	stateInMeter   = metrics.NewMeter("eth/downloader/states/in")
 	stateDropMeter = metrics.NewMeter("eth/downloader/states/drop")
+
+	throttleMeter = metrics.NewMeter("eth/downloader/throttle")
 )
@@ -60,7 +60,8 @@ type peerConnection struct {
 	receiptThroughput float64 // Number of receipts measured to be retrievable per second
 	stateThroughput   float64 // Number of node data pieces measured to be retrievable per second
 
-	rtt time.Duration // Request round trip time to track responsiveness (QoS)
+	rtt          time.Duration // Request round trip time to track responsiveness (QoS)
+	stateLatency time.Duration // Moving average of the node data response latency (0 = unmeasured)
 
 	headerStarted  time.Time // Time instance when the last header fetch was started
 	blockStarted   time.Time // Time instance when the last block (body) fetch was started
@@ -140,6 +141,7 @@ func (p *peerConnection) Reset() {
 	p.blockThroughput = 0
 	p.receiptThroughput = 0
 	p.stateThroughput = 0
+	p.stateLatency = 0
 
 	p.lacking = make(map[common.Hash]struct{})
 }
@@ -255,6 +257,16 @@ func (p *peerConnection) SetReceiptsIdle(delivered int) {
 // data retrieval requests. Its estimated state retrieval throughput is updated
 // with that measured just now.
 func (p *peerConnection) SetNodeDataIdle(delivered int) {
+	p.lock.Lock()
+	if delivered == 0 {
+		p.stateLatency = rttMaxEstimate
+	} else if elapsed := time.Since(p.stateStarted); p.stateLatency == 0 {
+		p.stateLatency = elapsed
+	} else {
+		p.stateLatency = time.Duration((1-measurementImpact)*float64(p.stateLatency) + measurementImpact*float64(elapsed))
+	}
+	p.lock.Unlock()
+
 	p.setIdle(p.stateStarted, delivered, &p.stateThroughput, &p.stateIdle)
 }
 
@@ -321,6 +333,15 @@ func (p *peerConnection) NodeDataCapacity(targetRTT time.Duration) int {
 	return int(math.Min(1+math.Max(1, p.stateThroughput*float64(targetRTT)/float64(time.Second)), float64(MaxStateFetch)))
 }
 
+// NodeDataLatency retrieves the measured node data response latency of the
+// peer, or zero if no request has been answered yet.
+func (p *peerConnection) NodeDataLatency() time.Duration {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.stateLatency
+}
+
 // MarkLacking appends a new entity to the set of items (blocks, receipts, states)
 // that a peer is known not to have (i.e. have been requested before). If the
 // set reaches its maximum allowed capacity, items are randomly dropped off.
@@ -549,6 +570,25 @@ func (ps *peerSet) idlePeers(minProtocol, maxProtocol int, idleCheck func(*peerC
 	return idle, total
 }
 
+// medianNodeDataLatency returns the median node data response latency of the
+// peers measured so far, or zero if none were.
+func (ps *peerSet) medianNodeDataLatency() time.Duration {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	latencies := make([]float64, 0, len(ps.peers))
+	for _, p := range ps.peers {
+		if latency := p.NodeDataLatency(); latency > 0 {
+			latencies = append(latencies, float64(latency))
+		}
+	}
+	if len(latencies) == 0 {
+		return 0
+	}
+	sort.Float64s(latencies)
+	return time.Duration(latencies[len(latencies)/2])
+}
+
 // medianRTT returns the median RTT of the peerset, considering only the tuning
 // peers if there are more peers available.
 func (ps *peerSet) medianRTT() time.Duration {
@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"hash"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -36,13 +38,25 @@ import (
 // stateReq represents a batch of state fetch requests groupped together into
 // a single data retrieval network packet.
 type stateReq struct {
-	items    []common.Hash              // Hashes of the state items to download
-	tasks    map[common.Hash]*stateTask // Download tasks to track previous attempts
-	timeout  time.Duration              // Maximum round trip time for this to complete
-	timer    *time.Timer                // Timer to fire when the RTT timeout expires
-	peer     *peerConnection            // Peer that we're requesting from
-	response [][]byte                   // Response data of the peer (nil for timeouts)
-	dropped  bool                       // Flag whether the peer dropped off early
+	items     []common.Hash              // Hashes of the state items to download
+	tasks     map[common.Hash]*stateTask // Download tasks to track previous attempts
+	timeout   time.Duration              // Maximum round trip time for this to complete
+	timer     *time.Timer                // Timer to fire when the RTT timeout expires
+	slowTimer *time.Timer                // Timer to fire when the peer lags behind its expected latency
+	peer      *peerConnection            // Peer that we're requesting from
+	response  [][]byte                   // Response data of the peer (nil for timeouts)
+	dropped   bool                       // Flag whether the peer dropped off early
+
+	processed  bool // Flag whether the request was processed by the sync (sync loop only)
+	rebalanced bool // Flag whether the tasks were handed out to other peers (sync loop only)
+}
+
+// stop cancels the timers of the request.
+func (req *stateReq) stop() {
+	req.timer.Stop()
+	if req.slowTimer != nil {
+		req.slowTimer.Stop()
+	}
 }
 
 // timedOut returns if this request timed out.
@@ -57,11 +71,50 @@ type stateSyncStats struct {
 	duplicate  uint64 // Number of state entries downloaded twice
 	unexpected uint64 // Number of non-requested state entries received
 	pending    uint64 // Number of still pending state entries
+	healed     uint64 // Number of state entries processed after a pivot move
+	bytes      uint64 // Number of state bytes written to the database
+
+	nodeRate float64   // Moving average of state entries processed per second
+	byteRate float64   // Moving average of state bytes written per second
+	updated  time.Time // Time of the last rate update
 }
 
+// stateRateWeight is the weight of the latest commit in the moving averages
+// of the state download rates.
+const stateRateWeight = 0.1
+
+const (
+	// stateSlowPeerFactor is the multiple of the median node data latency above
+	// which a peer is considered slow and only assigned minimal batches.
+	stateSlowPeerFactor = 3
+
+	// stateSlowPeerItems is the number of state items assigned to a slow peer.
+	stateSlowPeerItems = 2
+
+	// stateRebalanceFactor is the multiple of a peer's expected latency after
+	// which its pending state items are also handed out to other peers.
+	stateRebalanceFactor = 2
+
+	// minStateRebalance is the minimum time a state request is given before its
+	// items are handed out to other peers.
+	minStateRebalance = time.Second
+)
+
 // syncState starts downloading state with the given root hash.
 func (d *Downloader) syncState(root common.Hash) *stateSync {
+	return d.startStateSync(newStateSync(d, root))
+}
+
+// healState starts downloading state with the given root hash after the pivot
+// moved, counting the retrieved entries as healing of the previous state.
+func (d *Downloader) healState(root common.Hash) *stateSync {
 	s := newStateSync(d, root)
+	s.healing = true
+	return d.startStateSync(s)
+}
+
+// startStateSync hands a state sync over to the state fetcher.
+func (d *Downloader) startStateSync(s *stateSync) *stateSync {
 	select {
 	case d.stateSyncStart <- s:
 	case <-d.quitCh:
@@ -94,13 +147,15 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 	var (
 		active   = make(map[string]*stateReq) // Currently in-flight requests
 		finished []*stateReq                  // Completed or failed requests
+		stalled  []*stateReq                  // Active requests lagging behind their peer's latency
 		timeout  = make(chan *stateReq)       // Timed out active requests
+		slow     = make(chan *stateReq)       // Lagging active requests
 	)
 	defer func() {
 		// Cancel active request timers on exit. Also set peers to idle so they're
 		// available for the next sync.
 		for _, req := range active {
-			req.timer.Stop()
+			req.stop()
 			req.peer.SetNodeDataIdle(len(req.items))
 		}
 	}()
@@ -123,6 +178,14 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			deliverReq = finished[0]
 			deliverReqCh = s.deliver
 		}
+		var (
+			stalledReq   *stateReq
+			stalledReqCh chan *stateReq
+		)
+		if len(stalled) > 0 {
+			stalledReq = stalled[0]
+			stalledReqCh = s.stalled
+		}
 
 		select {
 		// The stateSync lifecycle:
@@ -139,6 +202,12 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 			finished[len(finished)-1] = nil
 			finished = finished[:len(finished)-1]
 
+		// Send the next lagging request to the current sync for rebalancing:
+		case stalledReqCh <- stalledReq:
+			copy(stalled, stalled[1:])
+			stalled[len(stalled)-1] = nil
+			stalled = stalled[:len(stalled)-1]
+
 		// Handle incoming state packs:
 		case pack := <-d.stateCh:
 			// Discard any data not requested (or previsouly timed out)
@@ -148,7 +217,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 				continue
 			}
 			// Finalize the request and queue up for processing
-			req.timer.Stop()
+			req.stop()
 			req.response = pack.(*statePack).states
 
 			finished = append(finished, req)
@@ -162,7 +231,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 				continue
 			}
 			// Finalize the request and queue up for processing
-			req.timer.Stop()
+			req.stop()
 			req.dropped = true
 
 			finished = append(finished, req)
@@ -177,9 +246,17 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 				continue
 			}
 			// Move the timed out data back into the download queue
+			req.stop()
 			finished = append(finished, req)
 			delete(active, req.peer.id)
 
+		// Handle requests lagging behind their peer's expected latency:
+		case req := <-slow:
+			if active[req.peer.id] != req {
+				continue
+			}
+			stalled = append(stalled, req)
+
 		// Track outgoing state requests:
 		case req := <-d.trackStateReq:
 			// If an active request already exists for this peer, we have a problem. In
@@ -192,7 +269,7 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 				log.Warn("Busy peer assigned new state fetch", "peer", old.peer.id)
 
 				// Make sure the previous one doesn't get siletly lost
-				old.timer.Stop()
+				old.stop()
 				old.dropped = true
 
 				finished = append(finished, old)
@@ -206,6 +283,15 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 					// timer is fired just before exiting runStateSync.
 				}
 			})
+			// Start a timer to hand the items out to other peers if the peer lags.
+			if wait := d.rebalanceDelay(req.peer); wait < req.timeout {
+				req.slowTimer = time.AfterFunc(wait, func() {
+					select {
+					case slow <- req:
+					case <-s.done:
+					}
+				})
+			}
 			active[req.peer.id] = req
 		}
 	}
@@ -222,8 +308,10 @@ type stateSync struct {
 
 	numUncommitted   int
 	bytesUncommitted int
+	healing          bool // Whether the sync follows a pivot move
 
 	deliver    chan *stateReq // Delivery channel multiplexing peer responses
+	stalled    chan *stateReq // Channel of active requests lagging behind their peer
 	cancel     chan struct{}  // Channel to signal a termination request
 	cancelOnce sync.Once      // Ensures cancel only ever gets called once
 	done       chan struct{}  // Channel to signal termination completion
@@ -245,6 +333,7 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
 		deliver: make(chan *stateReq),
+		stalled: make(chan *stateReq),
 		cancel:  make(chan struct{}),
 		done:    make(chan struct{}),
 	}
@@ -293,12 +382,21 @@ func (s *stateSync) loop() (err error) {
 		if err = s.commit(false); err != nil {
 			return err
 		}
-		s.assignTasks()
+		// Assign tasks unless the bandwidth is overdrawn, retrying once repaid
+		var throttled <-chan time.Time
+		if delay := s.d.bandwidthDelay(); delay > 0 {
+			throttled = time.After(delay)
+		} else {
+			s.assignTasks()
+		}
 		// Tasks assigned, wait for something to happen
 		select {
 		case <-newPeer:
 			// New peer arrived, try to assign it download tasks
 
+		case <-throttled:
+			// Bandwidth allowance repaid, assign the held back tasks
+
 		case <-s.cancel:
 			return errCancelStateFetch
 
@@ -320,6 +418,10 @@ func (s *stateSync) loop() (err error) {
 				return err
 			}
 			req.peer.SetNodeDataIdle(len(req.response))
+
+		case req := <-s.stalled:
+			// Request lagging, let faster peers retrieve its items too
+			s.rebalance(req)
 		}
 	}
 	return nil
@@ -337,7 +439,7 @@ func (s *stateSync) commit(force bool) error {
 	if err := b.Write(); err != nil {
 		return fmt.Errorf("DB write error: %v", err)
 	}
-	s.updateStats(s.numUncommitted, 0, 0, time.Since(start))
+	s.updateStats(s.numUncommitted, s.bytesUncommitted, 0, 0, time.Since(start))
 	s.numUncommitted = 0
 	s.bytesUncommitted = 0
 	return nil
@@ -345,20 +447,50 @@ func (s *stateSync) commit(force bool) error {
 
 // assignTasks attempts to assing new tasks to all idle peers, either from the
 // batch currently being retried, or fetching new data from the trie sync itself.
+// The tasks are striped across the idle peers by their response latency, the
+// fastest peers being served first.
 func (s *stateSync) assignTasks() {
 	ctx, span := trace.StartSpan(context.Background(), "stateSync.assignTasks")
 	defer span.End()
-	// Iterate over all idle peers and try to assign them state fetches
+	// Order the idle peers by latency and work out their capacities
 	peers, _ := s.d.peers.NodeDataIdlePeers()
+	if len(peers) == 0 {
+		return
+	}
+	var (
+		median    = s.d.peers.medianNodeDataLatency()
+		latencies = make(map[string]time.Duration, len(peers))
+	)
 	for _, p := range peers {
-		// Assign a batch of fetches proportional to the estimated latency/bandwidth
-		cap := p.NodeDataCapacity(s.d.requestRTT())
+		latency := p.NodeDataLatency()
+		if latency == 0 {
+			latency = median
+		}
+		latencies[p.id] = latency
+	}
+	sort.SliceStable(peers, func(i, j int) bool { return latencies[peers[i].id] < latencies[peers[j].id] })
+
+	var (
+		lats  = make([]time.Duration, len(peers))
+		caps  = make([]int, len(peers))
+		total int
+	)
+	for i, p := range peers {
+		lats[i] = latencies[p.id]
+		caps[i] = p.NodeDataCapacity(s.d.requestRTT())
+		total += caps[i]
+	}
+	s.refillTasks(total)
+	allowances := stateAllowances(lats, caps, median, len(s.tasks))
+
+	// Iterate over all idle peers and try to assign them state fetches
+	for i, p := range peers {
 		req := &stateReq{peer: p, timeout: s.d.requestTTL()}
-		s.fillTasks(cap, req)
+		s.fillTasks(allowances[i], req)
 
 		// If the peer was assigned tasks to fetch, send the network request
 		if len(req.items) > 0 {
-			req.peer.log.Trace("Requesting new batch of data", "type", "state", "count", len(req.items))
+			req.peer.log.Trace("Requesting new batch of data", "type", "state", "count", len(req.items), "latency", lats[i])
 			select {
 			case s.d.trackStateReq <- req:
 				req.peer.FetchNodeData(ctx, req.items)
@@ -369,16 +501,74 @@ func (s *stateSync) assignTasks() {
 	}
 }
 
+// stateAllowances splits the available state tasks among peers in proportion
+// to their inverse latency, capped by the individual peer capacities. Peers much
+// slower than the median are only given minimal batches, so that they can't
+// hold up large parts of the trie.
+func stateAllowances(latencies []time.Duration, caps []int, median time.Duration, available int) []int {
+	var (
+		weights = make([]float64, len(latencies))
+		total   float64
+	)
+	for i, latency := range latencies {
+		if latency <= 0 {
+			latency = rttMinEstimate
+		}
+		weights[i] = 1 / latency.Seconds()
+		total += weights[i]
+	}
+	allowances := make([]int, len(caps))
+	for i := range caps {
+		share := int(math.Ceil(float64(available) * weights[i] / total))
+		if share < 1 {
+			share = 1
+		}
+		if share > caps[i] {
+			share = caps[i]
+		}
+		if median > 0 && latencies[i] > stateSlowPeerFactor*median && share > stateSlowPeerItems {
+			share = stateSlowPeerItems
+		}
+		allowances[i] = share
+	}
+	return allowances
+}
+
+// rebalanceDelay returns how long a state request to the peer may take before
+// its items are handed out to other peers too.
+func (d *Downloader) rebalanceDelay(p *peerConnection) time.Duration {
+	expected := p.NodeDataLatency()
+	if expected == 0 {
+		expected = d.peers.medianNodeDataLatency()
+	}
+	if expected == 0 {
+		return d.requestTTL()
+	}
+	if wait := stateRebalanceFactor * expected; wait > minStateRebalance {
+		return wait
+	}
+	return minStateRebalance
+}
+
+// rebalance places the pending tasks of a lagging request back into the task
+// queue for other peers to retrieve, while the original request stays active.
+// Whichever delivery arrives first is used, the other counts as duplicate.
+func (s *stateSync) rebalance(req *stateReq) {
+	if req.processed || req.rebalanced {
+		return
+	}
+	req.rebalanced = true
+	for hash, task := range req.tasks {
+		s.tasks[hash] = task
+	}
+	log.Trace("Rebalancing lagging state request", "peer", req.peer.id, "count", len(req.tasks))
+}
+
 // fillTasks fills the given request object with a maximum of n state download
 // tasks to send to the remote peer.
 func (s *stateSync) fillTasks(n int, req *stateReq) {
-	// Refill available tasks from the scheduler.
-	if len(s.tasks) < n {
-		new := s.sched.Missing(n - len(s.tasks))
-		for _, hash := range new {
-			s.tasks[hash] = &stateTask{make(map[string]struct{})}
-		}
-	}
+	s.refillTasks(n)
+
 	// Find tasks that haven't been tried with the request's peer.
 	req.items = make([]common.Hash, 0, n)
 	req.tasks = make(map[common.Hash]*stateTask, n)
@@ -399,6 +589,16 @@ func (s *stateSync) fillTasks(n int, req *stateReq) {
 	}
 }
 
+// refillTasks tops up the available tasks to n from the scheduler.
+func (s *stateSync) refillTasks(n int) {
+	if len(s.tasks) < n {
+		new := s.sched.Missing(n - len(s.tasks))
+		for _, hash := range new {
+			s.tasks[hash] = &stateTask{make(map[string]struct{})}
+		}
+	}
+}
+
 // process iterates over a batch of delivered state data, injecting each item
 // into a running state sync, re-queuing any items that were requested but not
 // delivered.
@@ -408,11 +608,12 @@ func (s *stateSync) process(req *stateReq) error {
 
 	defer func(start time.Time) {
 		if duplicate > 0 || unexpected > 0 {
-			s.updateStats(0, duplicate, unexpected, time.Since(start))
+			s.updateStats(0, 0, duplicate, unexpected, time.Since(start))
 		}
 	}(time.Now())
 
 	// Iterate over all the delivered data and inject one-by-one into the trie
+	req.processed = true
 	progress := false
 
 	for _, blob := range req.response {
@@ -432,6 +633,12 @@ func (s *stateSync) process(req *stateReq) error {
 		if _, ok := req.tasks[hash]; ok {
 			delete(req.tasks, hash)
 		}
+		// The item may have been queued for other peers by a rebalance
+		delete(s.tasks, hash)
+	}
+	// Unfulfilled tasks of a rebalanced request are already queued elsewhere
+	if req.rebalanced {
+		return nil
 	}
 	// Put unfulfilled tasks back into the retry queue
 	npeers := s.d.peers.Len()
@@ -467,7 +674,7 @@ func (s *stateSync) processNodeData(blob []byte) (bool, common.Hash, error) {
 
 // updateStats bumps the various state sync progress counters and displays a log
 // message for the user to see.
-func (s *stateSync) updateStats(written, duplicate, unexpected int, duration time.Duration) {
+func (s *stateSync) updateStats(written, bytes, duplicate, unexpected int, duration time.Duration) {
 	s.d.syncStatsLock.Lock()
 	defer s.d.syncStatsLock.Unlock()
 
@@ -475,8 +682,69 @@ func (s *stateSync) updateStats(written, duplicate, unexpected int, duration tim
 	s.d.syncStatsState.processed += uint64(written)
 	s.d.syncStatsState.duplicate += uint64(duplicate)
 	s.d.syncStatsState.unexpected += uint64(unexpected)
+	s.d.syncStatsState.bytes += uint64(bytes)
+	if s.healing {
+		s.d.syncStatsState.healed += uint64(written)
+	}
+	if written > 0 {
+		s.d.syncStatsState.updateRates(written, bytes, time.Now())
+	}
 
 	if written > 0 || duplicate > 0 || unexpected > 0 {
 		log.Info("Imported new state entries", "count", written, "elapsed", common.PrettyDuration(duration), "processed", s.d.syncStatsState.processed, "pending", s.d.syncStatsState.pending, "retry", len(s.tasks), "duplicate", s.d.syncStatsState.duplicate, "unexpected", s.d.syncStatsState.unexpected)
 	}
 }
+
+// updateRates folds a commit of written entries and bytes into the moving
+// averages of the download rates.
+func (stats *stateSyncStats) updateRates(written, bytes int, now time.Time) {
+	if !stats.updated.IsZero() {
+		if elapsed := now.Sub(stats.updated).Seconds(); elapsed > 0 {
+			nodeRate, byteRate := float64(written)/elapsed, float64(bytes)/elapsed
+			if stats.nodeRate == 0 {
+				stats.nodeRate, stats.byteRate = nodeRate, byteRate
+			} else {
+				stats.nodeRate += stateRateWeight * (nodeRate - stats.nodeRate)
+				stats.byteRate += stateRateWeight * (byteRate - stats.byteRate)
+			}
+		}
+	}
+	stats.updated = now
+}
+
+// StateSyncProgress reports the progress of the state download of a fast sync.
+type StateSyncProgress struct {
+	Processed  uint64        // Number of state entries downloaded
+	Pending    uint64        // Number of state entries known but not yet downloaded
+	Healed     uint64        // Number of state entries downloaded to catch up with pivot moves
+	Duplicate  uint64        // Number of state entries downloaded twice
+	Unexpected uint64        // Number of non-requested state entries received
+	Bytes      uint64        // Number of state bytes written to the database
+	NodeRate   float64       // State entries downloaded per second
+	ByteRate   float64       // State bytes written per second
+	ETA        time.Duration // Estimated time to download the pending entries, zero if unknown
+}
+
+// StateProgress retrieves the progress of the state download. As entries are
+// only discovered while their parents are processed, the ETA estimates the
+// time to download the currently known entries and grows as the trie unfolds.
+func (d *Downloader) StateProgress() StateSyncProgress {
+	d.syncStatsLock.RLock()
+	defer d.syncStatsLock.RUnlock()
+
+	stats := d.syncStatsState
+	progress := StateSyncProgress{
+		Processed:  stats.processed,
+		Pending:    stats.pending,
+		Healed:     stats.healed,
+		Duplicate:  stats.duplicate,
+		Unexpected: stats.unexpected,
+		Bytes:      stats.bytes,
+		NodeRate:   stats.nodeRate,
+		ByteRate:   stats.byteRate,
+	}
+	if stats.nodeRate > 0 {
+		progress.ETA = time.Duration(float64(stats.pending) / stats.nodeRate * float64(time.Second))
+	}
+	return progress
+}
@@ -0,0 +1,115 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+)
+
+// Tests that the state download rates are averaged over the commits, and that
+// the ETA is derived from the pending entries and the entry rate.
+func TestStateProgress(t *testing.T) {
+	d := new(Downloader)
+	if progress := d.StateProgress(); progress.ETA != 0 || progress.NodeRate != 0 {
+		t.Fatalf("progress without commits has rates: %+v", progress)
+	}
+	start := time.Now()
+	stats := &d.syncStatsState
+	stats.updateRates(100, 1000, start)
+	if stats.nodeRate != 0 {
+		t.Fatalf("rate set by first commit: %v", stats.nodeRate)
+	}
+	stats.updateRates(100, 1000, start.Add(time.Second))
+	if stats.nodeRate != 100 || stats.byteRate != 1000 {
+		t.Fatalf("initial rates mismatch: have %v/%v, want 100/1000", stats.nodeRate, stats.byteRate)
+	}
+	stats.updateRates(300, 3000, start.Add(2*time.Second))
+	if stats.nodeRate != 120 || stats.byteRate != 1200 {
+		t.Fatalf("averaged rates mismatch: have %v/%v, want 120/1200", stats.nodeRate, stats.byteRate)
+	}
+	stats.pending = 600
+	if eta := d.StateProgress().ETA; eta != 5*time.Second {
+		t.Errorf("ETA mismatch: have %v, want 5s", eta)
+	}
+}
+
+// Tests that state tasks are striped across peers by latency, and that slow
+// peers are only given minimal batches.
+func TestStateAllowances(t *testing.T) {
+	tests := []struct {
+		latencies []time.Duration
+		caps      []int
+		median    time.Duration
+		available int
+		want      []int
+	}{
+		// Unmeasured peers share the tasks evenly
+		{[]time.Duration{0, 0}, []int{100, 100}, 0, 100, []int{50, 50}},
+		// Faster peers get proportionally more
+		{[]time.Duration{100 * time.Millisecond, 300 * time.Millisecond}, []int{100, 100}, 100 * time.Millisecond, 100, []int{75, 25}},
+		// Shares are capped by the capacities
+		{[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, []int{10, 100}, 100 * time.Millisecond, 100, []int{10, 50}},
+		// Every peer gets something to measure it
+		{[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond}, []int{10, 10}, 100 * time.Millisecond, 0, []int{1, 1}},
+		// Slow peers only get minimal batches
+		{[]time.Duration{100 * time.Millisecond, 100 * time.Millisecond, time.Second}, []int{384, 384, 384}, 100 * time.Millisecond, 1000, []int{384, 384, stateSlowPeerItems}},
+	}
+	for i, tt := range tests {
+		have := stateAllowances(tt.latencies, tt.caps, tt.median, tt.available)
+		if len(have) != len(tt.want) {
+			t.Fatalf("test %d: allowance count mismatch: have %d, want %d", i, len(have), len(tt.want))
+		}
+		for j := range have {
+			if have[j] != tt.want[j] {
+				t.Errorf("test %d: allowances mismatch: have %v, want %v", i, have, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// Tests that rebalancing a lagging request queues its pending tasks for other
+// peers, only once and only while the request is unprocessed.
+func TestStateRebalance(t *testing.T) {
+	s := &stateSync{tasks: make(map[common.Hash]*stateTask)}
+	task := &stateTask{attempts: map[string]struct{}{"slow": {}}}
+	req := &stateReq{
+		peer:  &peerConnection{id: "slow"},
+		tasks: map[common.Hash]*stateTask{{0x01}: task},
+	}
+	s.rebalance(req)
+	if !req.rebalanced || s.tasks[common.Hash{0x01}] != task {
+		t.Fatalf("lagging request not rebalanced: %v", s.tasks)
+	}
+	delete(s.tasks, common.Hash{0x01})
+	s.rebalance(req)
+	if len(s.tasks) != 0 {
+		t.Fatalf("request rebalanced twice")
+	}
+	req = &stateReq{
+		peer:      &peerConnection{id: "slow"},
+		tasks:     map[common.Hash]*stateTask{{0x02}: task},
+		processed: true,
+	}
+	s.rebalance(req)
+	if len(s.tasks) != 0 {
+		t.Fatalf("processed request rebalanced")
+	}
+}
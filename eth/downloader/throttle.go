@@ -0,0 +1,176 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Contains the bandwidth throttling of the sync traffic.
+
+package downloader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+)
+
+// requestOverhead is the approximate size of a data request besides the hashes
+// it contains, used to charge the egress allowance.
+const requestOverhead = 32
+
+// Bandwidth caps the sync traffic of the downloader, in bytes per second. A zero
+// rate leaves the direction unlimited, a zero burst defaults to one second worth
+// of traffic.
+type Bandwidth struct {
+	Ingress      uint64 `json:"ingress"`      // Allowance of inbound sync data
+	IngressBurst uint64 `json:"ingressBurst"` // Inbound data allowed in excess of the rate
+	Egress       uint64 `json:"egress"`       // Allowance of outbound sync requests
+	EgressBurst  uint64 `json:"egressBurst"`  // Outbound data allowed in excess of the rate
+}
+
+// throttle is a token bucket limiting the rate of a data stream.
+type throttle struct {
+	rate   float64   // Allowed bytes per second, zero if unlimited
+	burst  float64   // Maximum number of bytes allowed at once
+	tokens float64   // Bytes currently allowed, negative if overdrawn
+	last   time.Time // Time the tokens were last refilled
+	lock   sync.Mutex
+}
+
+// set changes the rate and burst of the throttle, keeping any overdraft.
+func (t *throttle) set(rate, burst uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if burst == 0 {
+		burst = rate
+	}
+	if t.rate == 0 {
+		t.tokens = float64(burst)
+	}
+	t.rate, t.burst, t.last = float64(rate), float64(burst), time.Now()
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+}
+
+// limited returns whether the throttle caps the data stream at all.
+func (t *throttle) limited() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.rate > 0
+}
+
+// reserve charges size bytes to the throttle, returning how long the caller
+// should wait before transferring them.
+func (t *throttle) reserve(size common.StorageSize) time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.rate == 0 {
+		return 0
+	}
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.last = now
+
+	t.tokens -= float64(size)
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
+
+// delay returns how long the stream must pause until the overdraft of the
+// throttle is repaid, zero if it isn't overdrawn.
+func (t *throttle) delay() time.Duration {
+	return t.reserve(0)
+}
+
+// SetBandwidth changes the caps of the sync traffic, taking effect for all
+// subsequent requests and deliveries.
+func (d *Downloader) SetBandwidth(limits Bandwidth) {
+	d.bandwidthLock.Lock()
+	defer d.bandwidthLock.Unlock()
+
+	d.bandwidth = limits
+	d.ingress.set(limits.Ingress, limits.IngressBurst)
+	d.egress.set(limits.Egress, limits.EgressBurst)
+}
+
+// bandwidthDelay returns how long new data requests must be held back until
+// both the ingress and egress allowances are repaid, zero if they may be sent.
+// Deliveries are charged when they arrive, so throttling before the requests
+// are issued keeps their timers from running while the traffic is capped.
+func (d *Downloader) bandwidthDelay() time.Duration {
+	delay := d.ingress.delay()
+	if egress := d.egress.delay(); egress > delay {
+		delay = egress
+	}
+	if delay > 0 {
+		throttleMeter.Mark(1)
+	}
+	return delay
+}
+
+// Bandwidth retrieves the current caps of the sync traffic.
+func (d *Downloader) Bandwidth() Bandwidth {
+	d.bandwidthLock.RLock()
+	defer d.bandwidthLock.RUnlock()
+
+	return d.bandwidth
+}
+
+// throttledPeer wraps a sync peer, charging its data requests to the egress
+// allowance of the downloader. The requests are sent right away, the overdraft
+// holding back the next ones before their timers start.
+type throttledPeer struct {
+	Peer
+	egress *throttle
+}
+
+func (p *throttledPeer) RequestHeadersByHash(ctx context.Context, h common.Hash, amount int, skip int, reverse bool) error {
+	p.egress.reserve(requestOverhead + common.HashLength)
+	return p.Peer.RequestHeadersByHash(ctx, h, amount, skip, reverse)
+}
+
+func (p *throttledPeer) RequestHeadersByNumber(ctx context.Context, i uint64, amount int, skip int, reverse bool) error {
+	p.egress.reserve(requestOverhead)
+	return p.Peer.RequestHeadersByNumber(ctx, i, amount, skip, reverse)
+}
+
+func (p *throttledPeer) RequestBodies(ctx context.Context, hashes []common.Hash) error {
+	p.egress.reserve(requestSize(hashes))
+	return p.Peer.RequestBodies(ctx, hashes)
+}
+
+func (p *throttledPeer) RequestReceipts(ctx context.Context, hashes []common.Hash) error {
+	p.egress.reserve(requestSize(hashes))
+	return p.Peer.RequestReceipts(ctx, hashes)
+}
+
+func (p *throttledPeer) RequestNodeData(ctx context.Context, hashes []common.Hash) error {
+	p.egress.reserve(requestSize(hashes))
+	return p.Peer.RequestNodeData(ctx, hashes)
+}
+
+// requestSize approximates the size of a request for the given hashes.
+func requestSize(hashes []common.Hash) common.StorageSize {
+	return common.StorageSize(requestOverhead + len(hashes)*common.HashLength)
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the throttle lets bursts through and delays traffic beyond them
+// proportionally to the rate.
+func TestThrottle(t *testing.T) {
+	th := new(throttle)
+	if delay := th.reserve(1 << 30); delay != 0 {
+		t.Fatalf("unlimited throttle delayed traffic by %v", delay)
+	}
+	th.set(1000, 2000)
+	if delay := th.reserve(2000); delay != 0 {
+		t.Fatalf("burst delayed by %v", delay)
+	}
+	delay := th.reserve(500)
+	if delay < 450*time.Millisecond || delay > 500*time.Millisecond {
+		t.Fatalf("overdraft delay mismatch: have %v, want ~500ms", delay)
+	}
+	// Raising the rate repays the overdraft faster
+	th.set(10000, 0)
+	delay = th.reserve(500)
+	if delay < 90*time.Millisecond || delay > 100*time.Millisecond {
+		t.Fatalf("raised rate delay mismatch: have %v, want ~100ms", delay)
+	}
+	// Lifting the cap lets everything through
+	th.set(0, 0)
+	if delay := th.reserve(1 << 30); delay != 0 {
+		t.Fatalf("lifted throttle delayed traffic by %v", delay)
+	}
+}
+
+// Tests that the throttle reports its overdraft without charging it further.
+func TestThrottleDelay(t *testing.T) {
+	th := new(throttle)
+	th.set(1000, 1000)
+	if delay := th.delay(); delay != 0 {
+		t.Fatalf("repaid throttle delay mismatch: have %v, want 0", delay)
+	}
+	th.reserve(1500)
+	for i := 0; i < 2; i++ {
+		if delay := th.delay(); delay < 450*time.Millisecond || delay > 500*time.Millisecond {
+			t.Fatalf("check %d: overdraft delay mismatch: have %v, want ~500ms", i, delay)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era exports the chain into fixed-size compressed segments of blocks
+// and receipts, and imports them back, to seed new nodes from static storage.
+//
+// An export directory holds the segments, named after the number of their first
+// block, and an index.json listing the block range, last block hash, size and
+// SHA-256 checksum of every segment. A segment is a gzip compressed stream of
+// RLP encoded [block, receipts] entries.
+package era
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/internal/jobs"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const (
+	// Version is the version of the segment format written by Export.
+	Version = 1
+
+	// DefaultSegmentSize is the default number of blocks per segment.
+	DefaultSegmentSize = 8192
+
+	// IndexFile is the name of the index within an export directory.
+	IndexFile = "index.json"
+
+	importBatchSize = 2048 // Number of blocks inserted at once during import
+)
+
+var errNoSegments = errors.New("no segments to import")
+
+// Index describes the segments of an export.
+type Index struct {
+	Version     uint        `json:"version"`
+	Genesis     common.Hash `json:"genesis"`
+	SegmentSize uint64      `json:"segmentSize"`
+	Segments    []Segment   `json:"segments"`
+}
+
+// Segment describes a file of consecutive blocks.
+type Segment struct {
+	File     string      `json:"file"`
+	First    uint64      `json:"first"`
+	Last     uint64      `json:"last"`
+	LastHash common.Hash `json:"lastHash"`
+	Size     int64       `json:"size"`
+	Checksum string      `json:"sha256"`
+}
+
+// entry is a block along with its receipts, as stored in a segment.
+type entry struct {
+	Block    *types.Block
+	Receipts []*types.ReceiptForStorage
+}
+
+// ExportChain is the source of the exported chain data.
+type ExportChain interface {
+	Genesis() *types.Block
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+}
+
+// segmentFile returns the file name of the segment starting at first.
+func segmentFile(first uint64) string {
+	return fmt.Sprintf("%010d.era.gz", first)
+}
+
+// ReadIndex reads the index of the export in dir.
+func ReadIndex(dir string) (*Index, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, IndexFile))
+	if err != nil {
+		return nil, err
+	}
+	index := new(Index)
+	if err := json.Unmarshal(blob, index); err != nil {
+		return nil, fmt.Errorf("invalid segment index: %v", err)
+	}
+	if index.Version != Version {
+		return nil, fmt.Errorf("unsupported segment version %d", index.Version)
+	}
+	return index, nil
+}
+
+// writeIndex atomically replaces the index of the export in dir.
+func writeIndex(dir string, index *Index) error {
+	blob, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, IndexFile)
+	if err := ioutil.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// checksum returns the hex encoded SHA-256 hash and the size of a file.
+func checksum(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// Export writes the chain from genesis to the current head into segments of
+// segmentSize blocks in dir. Complete segments left in dir by a previous
+// export of the same chain are kept if they still match their checksum and the
+// chain, so exports can be refreshed incrementally.
+func Export(ctx context.Context, chain ExportChain, dir string, segmentSize uint64) (*Index, error) {
+	if segmentSize == 0 {
+		return nil, errors.New("zero segment size")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var (
+		start = time.Now()
+		head  = chain.CurrentBlock().NumberU64()
+		index = &Index{Version: Version, Genesis: chain.Genesis().Hash(), SegmentSize: segmentSize}
+		old   = make(map[uint64]Segment)
+	)
+	if prev, err := ReadIndex(dir); err == nil && prev.Genesis == index.Genesis && prev.SegmentSize == segmentSize {
+		for _, segment := range prev.Segments {
+			old[segment.First] = segment
+		}
+	}
+	total := head/segmentSize + 1
+	for i := uint64(0); i < total; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		first := i * segmentSize
+		last := first + segmentSize - 1
+		if last > head {
+			last = head
+		}
+		if segment, ok := old[first]; ok && reusable(chain, dir, segment, last) {
+			index.Segments = append(index.Segments, segment)
+		} else {
+			segment, err := exportSegment(chain, dir, first, last)
+			if err != nil {
+				return nil, err
+			}
+			index.Segments = append(index.Segments, *segment)
+			log.Info("Exported chain segment", "first", first, "last", last, "size", common.StorageSize(segment.Size))
+		}
+		jobs.ReportProgress(ctx, i+1, total)
+	}
+	if err := writeIndex(dir, index); err != nil {
+		return nil, err
+	}
+	log.Info("Exported chain segments", "dir", dir, "segments", len(index.Segments), "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+	return index, nil
+}
+
+// reusable reports whether a previously exported segment covers the blocks
+// first to last of the chain unchanged.
+func reusable(chain ExportChain, dir string, segment Segment, last uint64) bool {
+	if segment.Last != last {
+		return false
+	}
+	if block := chain.GetBlockByNumber(last); block == nil || block.Hash() != segment.LastHash {
+		return false
+	}
+	sum, _, err := checksum(filepath.Join(dir, segment.File))
+	return err == nil && sum == segment.Checksum
+}
+
+// exportSegment writes the blocks first to last into a segment file.
+func exportSegment(chain ExportChain, dir string, first, last uint64) (*Segment, error) {
+	var (
+		name = segmentFile(first)
+		path = filepath.Join(dir, name)
+	)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path + ".tmp")
+	defer f.Close()
+
+	var (
+		gz       = gzip.NewWriter(f)
+		lastHash common.Hash
+	)
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		receipts := chain.GetReceiptsByHash(block.Hash())
+		if len(receipts) != len(block.Transactions()) {
+			return nil, fmt.Errorf("receipts of block #%d not available", number)
+		}
+		stored := make([]*types.ReceiptForStorage, len(receipts))
+		for i, receipt := range receipts {
+			stored[i] = (*types.ReceiptForStorage)(receipt)
+		}
+		if err := rlp.Encode(gz, &entry{Block: block, Receipts: stored}); err != nil {
+			return nil, err
+		}
+		lastHash = block.Hash()
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	sum, size, err := checksum(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+	return &Segment{File: name, First: first, Last: last, LastHash: lastHash, Size: size, Checksum: sum}, nil
+}
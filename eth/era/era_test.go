@@ -0,0 +1,179 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+	testGenesis = &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testAddress:          {Balance: big.NewInt(1000000000)},
+			common.Address{0xaa}: {Balance: new(big.Int), Code: []byte{0x60, 0x00, 0x60, 0x00, 0xa0}}, // log0(0, 0)
+		},
+	}
+)
+
+// newTestChain creates a chain with the genesis of testGenesis, inserting n
+// blocks each calling a contract emitting a log.
+func newTestChain(t *testing.T, n int) *core.BlockChain {
+	ctx := context.Background()
+	db := ethdb.NewMemDatabase()
+	genesis := testGenesis.MustCommit(db)
+	engine := clique.NewFaker()
+
+	chain, err := core.NewBlockChain(db, nil, testGenesis.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if n == 0 {
+		return chain
+	}
+	blocks, _ := core.GenerateChain(ctx, testGenesis.Config, genesis, engine, db, n, func(ctx context.Context, i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), common.Address{0xaa}, new(big.Int), 100000, new(big.Int), nil), types.HomesteadSigner{}, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(ctx, tx)
+	})
+	if _, err := chain.InsertChain(ctx, blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := newTestChain(t, 10)
+	defer src.Stop()
+
+	dir, err := ioutil.TempDir("", "era")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	index, err := Export(ctx, src, dir, 4)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(index.Segments) != 3 || index.Segments[2].First != 8 || index.Segments[2].Last != 10 {
+		t.Fatalf("segments mismatch: %+v", index.Segments)
+	}
+	head := src.CurrentBlock()
+	for _, fast := range []bool{false, true} {
+		dst := newTestChain(t, 0)
+		result, err := Import(ctx, dst, dir, fast)
+		if err != nil {
+			t.Fatalf("fast %v: failed to import: %v", fast, err)
+		}
+		if result.Imported != 10 || result.Skipped != 1 {
+			t.Errorf("fast %v: import counts mismatch: %+v", fast, result)
+		}
+		current := dst.CurrentBlock()
+		if fast {
+			current = dst.CurrentFastBlock()
+		}
+		if current.Hash() != head.Hash() {
+			t.Errorf("fast %v: head mismatch: have #%d, want #%d", fast, current.NumberU64(), head.NumberU64())
+		}
+		if receipts := dst.GetReceiptsByHash(head.Hash()); len(receipts) != 1 || len(receipts[0].Logs) != 1 {
+			t.Errorf("fast %v: receipts of head not imported: %v", fast, receipts)
+		}
+		dst.Stop()
+	}
+}
+
+func TestExportIncremental(t *testing.T) {
+	ctx := context.Background()
+	chain := newTestChain(t, 6)
+	defer chain.Stop()
+
+	dir, err := ioutil.TempDir("", "era")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := Export(ctx, chain, dir, 4); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	// Complete segments are kept, so their files aren't rewritten
+	full := filepath.Join(dir, segmentFile(0))
+	old, _ := os.Stat(full)
+	os.Chtimes(full, old.ModTime().Add(-2*time.Second), old.ModTime().Add(-2*time.Second))
+	before, _ := os.Stat(full)
+
+	index, err := Export(ctx, chain, dir, 4)
+	if err != nil {
+		t.Fatalf("failed to re-export: %v", err)
+	}
+	after, _ := os.Stat(full)
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("unchanged segment rewritten")
+	}
+	if len(index.Segments) != 2 {
+		t.Errorf("segment count mismatch: have %d, want 2", len(index.Segments))
+	}
+}
+
+func TestImportCorrupt(t *testing.T) {
+	ctx := context.Background()
+	src := newTestChain(t, 3)
+	defer src.Stop()
+
+	dir, err := ioutil.TempDir("", "era")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	index, err := Export(ctx, src, dir, 4)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	path := filepath.Join(dir, index.Segments[0].File)
+	blob, _ := ioutil.ReadFile(path)
+	blob[len(blob)/2] ^= 0xff
+	ioutil.WriteFile(path, blob, 0644)
+
+	dst := newTestChain(t, 0)
+	defer dst.Stop()
+	if _, err := Import(ctx, dst, dir, false); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("corrupt segment error mismatch: %v", err)
+	}
+}
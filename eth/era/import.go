@@ -0,0 +1,208 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/internal/jobs"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+// ImportChain is the chain segments are imported into.
+type ImportChain interface {
+	Genesis() *types.Block
+	HasBlock(hash common.Hash, number uint64) bool
+	InsertChain(ctx context.Context, chain types.Blocks) (int, error)
+	InsertHeaderChain(ctx context.Context, chain []*types.Header, checkFreq int) (int, error)
+	InsertReceiptChain(ctx context.Context, blockChain types.Blocks, receiptChain []types.Receipts) (int, error)
+}
+
+// ImportResult reports the outcome of an import.
+type ImportResult struct {
+	Segments int           `json:"segments"`
+	Imported uint64        `json:"imported"` // Number of blocks inserted
+	Skipped  uint64        `json:"skipped"`  // Number of blocks already known
+	Fast     bool          `json:"fast"`
+	Elapsed  time.Duration `json:"elapsed"`
+}
+
+// Import verifies and inserts the segments of the export in dir. In full mode
+// the blocks are executed, while in fast mode only the headers are verified,
+// and the blocks and receipts are stored without state, as fast sync does;
+// the state of the head then has to be synced from the network.
+func Import(ctx context.Context, chain ImportChain, dir string, fast bool) (*ImportResult, error) {
+	index, err := ReadIndex(dir)
+	if err != nil {
+		return nil, err
+	}
+	if index.Genesis != chain.Genesis().Hash() {
+		return nil, fmt.Errorf("genesis mismatch: segments %x, chain %x", index.Genesis, chain.Genesis().Hash())
+	}
+	if len(index.Segments) == 0 {
+		return nil, errNoSegments
+	}
+	var (
+		start  = time.Now()
+		result = &ImportResult{Fast: fast}
+		next   uint64
+	)
+	for i, segment := range index.Segments {
+		if segment.First != next || segment.Last < segment.First {
+			return nil, fmt.Errorf("segment %s: non contiguous range %d-%d, expected first %d", segment.File, segment.First, segment.Last, next)
+		}
+		next = segment.Last + 1
+
+		if err := importSegment(ctx, chain, dir, segment, fast, result); err != nil {
+			return nil, fmt.Errorf("segment %s: %v", segment.File, err)
+		}
+		result.Segments++
+		jobs.ReportProgress(ctx, uint64(i+1), uint64(len(index.Segments)))
+		log.Info("Imported chain segment", "first", segment.First, "last", segment.Last, "fast", fast)
+	}
+	result.Elapsed = time.Since(start)
+	log.Info("Imported chain segments", "dir", dir, "segments", result.Segments, "imported", result.Imported, "skipped", result.Skipped, "elapsed", common.PrettyDuration(result.Elapsed))
+	return result, nil
+}
+
+// importSegment verifies the checksum of a segment and inserts its blocks.
+func importSegment(ctx context.Context, chain ImportChain, dir string, segment Segment, fast bool, result *ImportResult) error {
+	path := filepath.Join(dir, segment.File)
+	sum, _, err := checksum(path)
+	if err != nil {
+		return err
+	}
+	if sum != segment.Checksum {
+		return fmt.Errorf("checksum mismatch: have %s, want %s", sum, segment.Checksum)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	stream := rlp.NewStream(gz, 0)
+
+	var (
+		blocks   = make(types.Blocks, 0, importBatchSize)
+		receipts = make([]types.Receipts, 0, importBatchSize)
+		number   = segment.First
+		lastHash common.Hash
+	)
+	flush := func() error {
+		if len(blocks) == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := insert(ctx, chain, blocks, receipts, fast); err != nil {
+			return err
+		}
+		result.Imported += uint64(len(blocks))
+		blocks, receipts = blocks[:0], receipts[:0]
+		return nil
+	}
+	for ; ; number++ {
+		var e entry
+		if err := stream.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("block #%d: failed to decode: %v", number, err)
+		}
+		if e.Block.NumberU64() != number {
+			return fmt.Errorf("block number mismatch: have %d, want %d", e.Block.NumberU64(), number)
+		}
+		lastHash = e.Block.Hash()
+		if chain.HasBlock(lastHash, number) {
+			result.Skipped++
+			continue
+		}
+		rs := make(types.Receipts, len(e.Receipts))
+		for i, r := range e.Receipts {
+			rs[i] = (*types.Receipt)(r)
+		}
+		if err := verifyBody(e.Block, rs); err != nil {
+			return fmt.Errorf("block #%d: %v", number, err)
+		}
+		blocks, receipts = append(blocks, e.Block), append(receipts, rs)
+
+		if len(blocks) == importBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	if number != segment.Last+1 || lastHash != segment.LastHash {
+		return fmt.Errorf("segment ends at #%d [%x…], index lists #%d [%x…]", number-1, lastHash[:4], segment.Last, segment.LastHash[:4])
+	}
+	return nil
+}
+
+// verifyBody checks that the transactions, uncles and receipts of a block match
+// the roots of its header, as fast mode stores them without execution.
+func verifyBody(block *types.Block, receipts types.Receipts) error {
+	header := block.Header()
+	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
+		return fmt.Errorf("transaction root mismatch: have %x, want %x", hash, header.TxHash)
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
+		return fmt.Errorf("uncle root mismatch: have %x, want %x", hash, header.UncleHash)
+	}
+	if hash := types.DeriveSha(receipts); hash != header.ReceiptHash {
+		return fmt.Errorf("receipt root mismatch: have %x, want %x", hash, header.ReceiptHash)
+	}
+	return nil
+}
+
+// insert inserts a batch of consecutive blocks, executing them in full mode, or
+// storing the verified headers along with the blocks and receipts in fast mode.
+func insert(ctx context.Context, chain ImportChain, blocks types.Blocks, receipts []types.Receipts, fast bool) error {
+	if !fast {
+		if _, err := chain.InsertChain(ctx, blocks); err != nil {
+			return fmt.Errorf("failed to insert blocks: %v", err)
+		}
+		return nil
+	}
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if _, err := chain.InsertHeaderChain(ctx, headers, 1); err != nil {
+		return fmt.Errorf("failed to insert headers: %v", err)
+	}
+	if _, err := chain.InsertReceiptChain(ctx, blocks, receipts); err != nil {
+		return fmt.Errorf("failed to insert receipts: %v", err)
+	}
+	return nil
+}
@@ -28,9 +28,11 @@ import (
 	"github.com/fulcrumchain/indigo"
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core"
 	"github.com/fulcrumchain/indigo/core/types"
 	"github.com/fulcrumchain/indigo/ethdb"
 	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/rpc"
 )
 
@@ -81,6 +83,8 @@ func (api *PublicFilterAPI) timeoutLoop() {
 	ticker := time.NewTicker(5 * time.Minute)
 	for {
 		<-ticker.C
+		pruneLogJournals(api.chainDb, time.Now())
+
 		api.filtersMu.Lock()
 		for id, f := range api.filters {
 			select {
@@ -233,8 +237,75 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// ReorgBlock identifies a block in a chain reorganization notification.
+type ReorgBlock struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+}
+
+// ChainReorg is the notification sent for a chain reorganization. The dropped
+// and added blocks are listed in ascending order above the common ancestor.
+type ChainReorg struct {
+	CommonAncestor ReorgBlock   `json:"commonAncestor"`
+	Dropped        []ReorgBlock `json:"dropped"`
+	Added          []ReorgBlock `json:"added"`
+}
+
+func newReorgBlocks(blocks types.Blocks) []ReorgBlock {
+	out := make([]ReorgBlock, len(blocks))
+	for i, block := range blocks {
+		out[i] = ReorgBlock{Number: hexutil.Uint64(block.NumberU64()), Hash: block.Hash()}
+	}
+	return out
+}
+
+// newChainReorg converts a chain reorg event into its notification.
+func newChainReorg(ev core.ChainReorgEvent) *ChainReorg {
+	return &ChainReorg{
+		CommonAncestor: ReorgBlock{Number: hexutil.Uint64(ev.CommonAncestor.NumberU64()), Hash: ev.CommonAncestor.Hash()},
+		Dropped:        newReorgBlocks(ev.Dropped),
+		Added:          newReorgBlocks(ev.Added),
+	}
+}
+
+// ChainReorg sends a notification each time the canonical chain is reorganized,
+// listing the blocks that were dropped and added above the common ancestor.
+func (api *PublicFilterAPI) ChainReorg(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ChainReorgEvent)
+		reorgsSub := api.events.SubscribeChainReorgs(reorgs)
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, newChainReorg(ev))
+			case <-rpcSub.Err():
+				reorgsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				reorgsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
-func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
+// Logs reorged out are always retracted, with removed set, before the logs replacing
+// them, and no log is delivered twice. A subscription that ended, e.g. on a dropped
+// connection, can be resumed within a grace period by passing its ID: the logs it
+// delivered that were reorged out since are retracted, and the ones it missed are
+// delivered, without repeating the ones it got.
+func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria, resume *rpc.ID) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -243,26 +314,75 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	var (
 		rpcSub      = notifier.CreateSubscription()
 		matchedLogs = make(chan []*types.Log)
+		journal     = new(logJournal)
 	)
-
+	if resume != nil {
+		if journal = readLogJournal(api.chainDb, *resume, time.Now()); journal == nil {
+			return nil, fmt.Errorf("log subscription %s unknown or expired", *resume)
+		}
+	}
 	logsSub, err := api.events.SubscribeLogs(indigo.FilterQuery(crit), matchedLogs)
 	if err != nil {
 		return nil, err
 	}
-
+	// The journal is only touched by the delivery loop below, which persists it
+	// periodically if changed, and when the connection drops
+	var dirty bool
+	deliver := func(logs []*types.Log) {
+		logs = journal.filter(logs)
+		if len(logs) == 0 {
+			return
+		}
+		for _, l := range logs {
+			notifier.Notify(rpcSub.ID, l)
+		}
+		dirty = true
+	}
+	persist := func() {
+		journal.Updated = time.Now().Unix()
+		writeLogJournal(api.chainDb, rpcSub.ID, journal)
+		dirty = false
+	}
+	// Catch up on the logs a resumed subscription missed in the background,
+	// holding back the new ones meanwhile, deduped by the journal
+	var catchup chan []*types.Log
+	if resume != nil {
+		catchup = make(chan []*types.Log, 1)
+		go func() {
+			catchup <- api.missedLogs(journal, crit)
+			deleteLogJournal(api.chainDb, *resume)
+		}()
+	}
 	go func() {
+		flush := time.NewTicker(logJournalFlush)
+		defer flush.Stop()
 
+		var held [][]*types.Log
 		for {
 			select {
 			case logs := <-matchedLogs:
-				for _, log := range logs {
-					notifier.Notify(rpcSub.ID, &log)
+				if catchup != nil {
+					held = append(held, logs)
+					continue
+				}
+				deliver(logs)
+			case missed := <-catchup:
+				deliver(missed)
+				for _, logs := range held {
+					deliver(logs)
+				}
+				catchup, held = nil, nil
+			case <-flush.C:
+				if dirty {
+					persist()
 				}
 			case <-rpcSub.Err(): // client send an unsubscribe request
 				logsSub.Unsubscribe()
+				deleteLogJournal(api.chainDb, rpcSub.ID)
 				return
-			case <-notifier.Closed(): // connection dropped
+			case <-notifier.Closed(): // connection dropped, the journal is kept for resuming
 				logsSub.Unsubscribe()
+				persist()
 				return
 			}
 		}
@@ -271,6 +391,21 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// missedLogs returns the logs a resumed subscription has to catch up on: the
+// retractions of the delivered logs reorged out since, and the logs of the
+// blocks from the last one delivered to the head.
+func (api *PublicFilterAPI) missedLogs(journal *logJournal, crit FilterCriteria) []*types.Log {
+	logs := journal.stale(api.chainDb)
+	if head, ok := journal.head(); ok {
+		missed, err := New(api.backend, int64(head), rpc.LatestBlockNumber.Int64(), crit.Addresses, crit.Topics).Logs(context.Background())
+		if err != nil {
+			log.Warn("Failed to retrieve the logs missed by a resumed subscription", "err", err)
+		}
+		logs = append(logs, filterLogs(missed, crit.FromBlock, crit.ToBlock, crit.Addresses, crit.Topics)...)
+	}
+	return logs
+}
+
 // FilterCriteria represents a request to create a new filter.
 //
 // TODO(karalabe): Kill this in favor of indigo.FilterQuery.
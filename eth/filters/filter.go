@@ -39,8 +39,10 @@ type Backend interface {
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
+	BloomParams() (bits, hashes uint) // Size of the indexed bloom filters and bits set per key
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
@@ -79,6 +81,7 @@ func New(backend Backend, begin, end int64, addresses []common.Address, topics [
 	}
 	// Assemble and return the filter
 	size, _ := backend.BloomStatus()
+	bits, hashes := backend.BloomParams()
 
 	return &Filter{
 		backend:   backend,
@@ -87,7 +90,7 @@ func New(backend Backend, begin, end int64, addresses []common.Address, topics [
 		addresses: addresses,
 		topics:    topics,
 		db:        backend.ChainDb(),
-		matcher:   bloombits.NewMatcher(size, filters),
+		matcher:   bloombits.NewSizedMatcher(size, bits, hashes, filters),
 	}
 }
 
@@ -101,6 +104,16 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	}
 	head := header.Number.Uint64()
 
+	// Resolve the finality tags to the blocks they currently select
+	for _, number := range []*int64{&f.begin, &f.end} {
+		if tag := rpc.BlockNumber(*number); tag == rpc.SafeBlockNumber || tag == rpc.FinalizedBlockNumber {
+			header, err := f.backend.HeaderByNumber(ctx, tag)
+			if header == nil {
+				return nil, err
+			}
+			*number = header.Number.Int64()
+		}
+	}
 	if f.begin == -1 {
 		f.begin = int64(head)
 	}
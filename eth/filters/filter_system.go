@@ -52,6 +52,9 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// ChainReorgSubscription queries the blocks dropped and added by chain
+	// reorganizations
+	ChainReorgSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -67,6 +70,8 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// reorgChanSize is the size of channel listening to ChainReorgEvent.
+	reorgChanSize = 10
 )
 
 var (
@@ -81,6 +86,7 @@ type subscription struct {
 	logs      chan []*types.Log
 	hashes    chan []common.Hash
 	headers   chan *types.Header
+	reorgs    chan core.ChainReorgEvent
 	installed chan struct{} // closed when the filter is installed
 	err       chan error    // closed when the filter is uninstalled
 }
@@ -98,6 +104,7 @@ type EventSystem struct {
 	logsSub       event.Subscription         // Subscription for new log event
 	rmLogsSub     event.Subscription         // Subscription for removed log event
 	chainSub      event.Subscription         // Subscription for new chain event
+	reorgSub      event.Subscription         // Subscription for chain reorg event
 	pendingLogSub *event.TypeMuxSubscription // Subscription for pending log event
 
 	// Channels
@@ -107,6 +114,7 @@ type EventSystem struct {
 	logsCh    chan []*types.Log          // Channel to receive new log event
 	rmLogsCh  chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh   chan core.ChainEvent       // Channel to receive new chain event
+	reorgCh   chan core.ChainReorgEvent  // Channel to receive chain reorg event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -126,6 +134,7 @@ func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventS
 		logsCh:    make(chan []*types.Log, logsChanSize),
 		rmLogsCh:  make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		chainCh:   make(chan core.ChainEvent, chainEvChanSize),
+		reorgCh:   make(chan core.ChainReorgEvent, reorgChanSize),
 	}
 
 	// Subscribe events
@@ -133,11 +142,12 @@ func NewEventSystem(mux *event.TypeMux, backend Backend, lightMode bool) *EventS
 	m.logsSub = m.backend.SubscribeLogsEvent(m.logsCh)
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
+	m.reorgSub = m.backend.SubscribeChainReorgEvent(m.reorgCh)
 	// TODO(rjl493456442): use feed to subscribe pending log event
 	m.pendingLogSub = m.mux.Subscribe(core.PendingLogsEvent{})
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil ||
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.reorgSub == nil ||
 		m.pendingLogSub.Closed() {
 		log.Crit("Subscribe for event system failed")
 	}
@@ -174,6 +184,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.reorgs:
 			}
 		}
 
@@ -207,6 +218,10 @@ func (es *EventSystem) SubscribeLogs(crit indigo.FilterQuery, logs chan []*types
 		to = rpc.BlockNumber(crit.ToBlock.Int64())
 	}
 
+	// finality tags select past blocks, there's nothing to follow
+	if from < rpc.PendingBlockNumber || to < rpc.PendingBlockNumber {
+		return nil, fmt.Errorf("safe and finalized tags are not supported by log subscriptions")
+	}
 	// only interested in pending logs
 	if from == rpc.PendingBlockNumber && to == rpc.PendingBlockNumber {
 		return es.subscribePendingLogs(crit, logs), nil
@@ -241,6 +256,7 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit indigo.FilterQuery, logs c
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		reorgs:    make(chan core.ChainReorgEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -258,6 +274,7 @@ func (es *EventSystem) subscribeLogs(crit indigo.FilterQuery, logs chan []*types
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		reorgs:    make(chan core.ChainReorgEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -275,6 +292,7 @@ func (es *EventSystem) subscribePendingLogs(crit indigo.FilterQuery, logs chan [
 		logs:      logs,
 		hashes:    make(chan []common.Hash),
 		headers:   make(chan *types.Header),
+		reorgs:    make(chan core.ChainReorgEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -291,6 +309,7 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 		logs:      make(chan []*types.Log),
 		hashes:    make(chan []common.Hash),
 		headers:   headers,
+		reorgs:    make(chan core.ChainReorgEvent),
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -307,6 +326,24 @@ func (es *EventSystem) SubscribePendingTxs(hashes chan []common.Hash) *Subscript
 		logs:      make(chan []*types.Log),
 		hashes:    hashes,
 		headers:   make(chan *types.Header),
+		reorgs:    make(chan core.ChainReorgEvent),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeChainReorgs creates a subscription that writes the blocks dropped
+// and added whenever the canonical chain is reorganized.
+func (es *EventSystem) SubscribeChainReorgs(reorgs chan core.ChainReorgEvent) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       ChainReorgSubscription,
+		created:   time.Now(),
+		logs:      make(chan []*types.Log),
+		hashes:    make(chan []common.Hash),
+		headers:   make(chan *types.Header),
+		reorgs:    reorgs,
 		installed: make(chan struct{}),
 		err:       make(chan error),
 	}
@@ -379,6 +416,12 @@ func (es *EventSystem) broadcastChain(filters filterIndex, ev core.ChainEvent) {
 
 }
 
+func (es *EventSystem) broadcastChainReorg(filters filterIndex, ev core.ChainReorgEvent) {
+	for _, f := range filters[ChainReorgSubscription] {
+		f.reorgs <- ev
+	}
+}
+
 func (es *EventSystem) lightFilterNewHead(newHeader *types.Header, callBack func(*types.Header, bool)) {
 	oldh := es.lastHead
 	es.lastHead = newHeader
@@ -445,6 +488,7 @@ func (es *EventSystem) eventLoop() {
 		es.logsSub.Unsubscribe()
 		es.rmLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.reorgSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -463,6 +507,8 @@ func (es *EventSystem) eventLoop() {
 			es.broadcastRemovedLogs(index, ev)
 		case ev := <-es.chainCh:
 			es.broadcastChain(index, ev)
+		case ev := <-es.reorgCh:
+			es.broadcastChainReorg(index, ev)
 		case ev, active := <-es.pendingLogSub.Chan():
 			if !active { // system stopped
 				return
@@ -498,6 +544,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-es.chainSub.Err():
 			return
+		case <-es.reorgSub.Err():
+			return
 		}
 	}
 }
@@ -45,6 +45,7 @@ type testBackend struct {
 	rmLogsFeed *event.Feed
 	logsFeed   *event.Feed
 	chainFeed  *event.Feed
+	reorgFeed  *event.Feed
 }
 
 func (b *testBackend) ChainDb() ethdb.Database {
@@ -89,10 +90,18 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.reorgFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
 
+func (b *testBackend) BloomParams() (uint, uint) {
+	return params.HeaderBloomBits, params.HeaderBloomHashes
+}
+
 func (b *testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	requests := make(chan chan *bloombits.Retrieval)
 
@@ -136,7 +145,7 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = core.GenesisBlockForTesting(db, common.Address{1}, common.Big256)
 		chain, _    = core.GenerateChain(ctx, params.TestChainConfig, genesis, clique.NewFaker(), db, 10, nil)
@@ -182,6 +191,58 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestChainReorgSubscription tests if chain reorg subscriptions receive the
+// posted reorg events and the notification lists the blocks in order.
+func TestChainReorgSubscription(t *testing.T) {
+	ctx := context.Background()
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = ethdb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		reorgFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, reorgFeed}
+		api        = NewPublicFilterAPI(backend, false)
+		genesis    = core.GenesisBlockForTesting(db, common.Address{1}, common.Big256)
+		dropped, _ = core.GenerateChain(ctx, params.TestChainConfig, genesis, clique.NewFaker(), db, 2, nil)
+		added, _   = core.GenerateChain(ctx, params.TestChainConfig, genesis, clique.NewFaker(), db, 3, func(ctx context.Context, i int, gen *core.BlockGen) {
+			gen.SetCoinbase(common.Address{2})
+		})
+	)
+	reorgs := make(chan core.ChainReorgEvent)
+	sub := api.events.SubscribeChainReorgs(reorgs)
+	defer sub.Unsubscribe()
+
+	reorgFeed.Send(core.ChainReorgEvent{CommonAncestor: genesis, Dropped: dropped, Added: added})
+
+	select {
+	case ev := <-reorgs:
+		reorg := newChainReorg(ev)
+		if reorg.CommonAncestor.Hash != genesis.Hash() || reorg.CommonAncestor.Number != 0 {
+			t.Errorf("common ancestor mismatch: have %+v, want %x", reorg.CommonAncestor, genesis.Hash())
+		}
+		if len(reorg.Dropped) != len(dropped) || len(reorg.Added) != len(added) {
+			t.Fatalf("block count mismatch: have %d/%d, want %d/%d", len(reorg.Dropped), len(reorg.Added), len(dropped), len(added))
+		}
+		for i, block := range added {
+			if reorg.Added[i].Hash != block.Hash() || uint64(reorg.Added[i].Number) != block.NumberU64() {
+				t.Errorf("added block %d mismatch: have %+v, want %d %x", i, reorg.Added[i], block.NumberU64(), block.Hash())
+			}
+		}
+		for i, block := range dropped {
+			if reorg.Dropped[i].Hash != block.Hash() || uint64(reorg.Dropped[i].Number) != block.NumberU64() {
+				t.Errorf("dropped block %d mismatch: have %+v, want %d %x", i, reorg.Dropped[i], block.NumberU64(), block.Hash())
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for reorg event")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -193,7 +254,7 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -253,7 +314,7 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -302,16 +363,18 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
 	// different situations where log filter creation should fail.
-	// Reason: fromBlock > toBlock
+	// Reason: fromBlock > toBlock, or finality tags which can't be followed
 	testCases := []FilterCriteria{
 		0: {FromBlock: big.NewInt(rpc.PendingBlockNumber.Int64()), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())},
 		1: {FromBlock: big.NewInt(rpc.PendingBlockNumber.Int64()), ToBlock: big.NewInt(100)},
 		2: {FromBlock: big.NewInt(rpc.LatestBlockNumber.Int64()), ToBlock: big.NewInt(100)},
+		3: {FromBlock: big.NewInt(rpc.SafeBlockNumber.Int64()), ToBlock: big.NewInt(rpc.LatestBlockNumber.Int64())},
+		4: {FromBlock: big.NewInt(0), ToBlock: big.NewInt(rpc.FinalizedBlockNumber.Int64())},
 	}
 
 	for i, test := range testCases {
@@ -332,7 +395,7 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -451,7 +514,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -0,0 +1,232 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+const (
+	// logJournalGrace is the time a log subscription can be resumed after it
+	// ended, e.g. by the connection dropping.
+	logJournalGrace = 5 * time.Minute
+
+	// logJournalDepth is the number of blocks below the highest delivered one
+	// whose logs are remembered, covering the reorgs to expect.
+	logJournalDepth = 64
+
+	// logJournalLimit is the maximum number of delivered logs remembered, the
+	// oldest ones being forgotten first.
+	logJournalLimit = 4096
+
+	// logJournalFlush is the interval at which the journal of a live
+	// subscription is persisted if it changed, besides when it ends.
+	logJournalFlush = 10 * time.Second
+)
+
+// logJournalPrefix is the database table of the log delivery journals, keyed by
+// subscription ID.
+var logJournalPrefix = []byte("logjournal-")
+
+// logJournal records the logs delivered to a subscription, so that logs reorged
+// out are retracted before their replacements, whichever order the chain
+// reports them in, and that no log is delivered twice, including to the
+// subscription resuming it after a reconnect. It is kept in memory while the
+// subscription is live, and persisted periodically and when it ends.
+type logJournal struct {
+	Updated   int64        `json:"updated"`   // Unix time of the last delivery
+	Delivered []*types.Log `json:"delivered"` // Logs delivered and not retracted, in chain order
+}
+
+// logID identifies a log within the chain.
+type logID struct {
+	block common.Hash
+	index uint
+}
+
+func idOf(l *types.Log) logID {
+	return logID{l.BlockHash, l.Index}
+}
+
+// filter returns the logs of a batch to notify, in order: the delivered logs
+// of blocks replaced by the batch retracted first, newest first, then the logs
+// not delivered yet. Retractions of logs never delivered, or already retracted,
+// are dropped.
+func (j *logJournal) filter(logs []*types.Log) []*types.Log {
+	delivered := make(map[logID]int, len(j.Delivered))
+	for i, l := range j.Delivered {
+		delivered[idOf(l)] = i
+	}
+	var (
+		out     []*types.Log
+		dropped = make(map[int]bool)
+	)
+	retract := func(i int) {
+		if dropped[i] {
+			return
+		}
+		dropped[i] = true
+		removed := *j.Delivered[i]
+		removed.Removed = true
+		out = append(out, &removed)
+	}
+	for _, l := range logs {
+		if l.Removed {
+			if i, ok := delivered[idOf(l)]; ok {
+				retract(i)
+			}
+			continue
+		}
+		// Retract the delivered logs of other blocks at or above this height
+		for i := len(j.Delivered) - 1; i >= 0; i-- {
+			if d := j.Delivered[i]; d.BlockNumber >= l.BlockNumber && d.BlockHash != l.BlockHash {
+				retract(i)
+			}
+		}
+		if i, ok := delivered[idOf(l)]; ok && !dropped[i] {
+			continue
+		}
+		out = append(out, l)
+		delivered[idOf(l)] = -1 // Delivered in this batch
+	}
+	// Rebuild the journal from the retained and new logs
+	var kept []*types.Log
+	for i, l := range j.Delivered {
+		if !dropped[i] {
+			kept = append(kept, l)
+		}
+	}
+	for _, l := range out {
+		if !l.Removed {
+			kept = append(kept, l)
+		}
+	}
+	sort.SliceStable(kept, func(a, b int) bool {
+		if kept[a].BlockNumber != kept[b].BlockNumber {
+			return kept[a].BlockNumber < kept[b].BlockNumber
+		}
+		return kept[a].Index < kept[b].Index
+	})
+	if n := len(kept); n > 0 && kept[n-1].BlockNumber > logJournalDepth {
+		floor := kept[n-1].BlockNumber - logJournalDepth
+		first := sort.Search(n, func(i int) bool { return kept[i].BlockNumber >= floor })
+		kept = kept[first:]
+	}
+	if len(kept) > logJournalLimit {
+		kept = kept[len(kept)-logJournalLimit:]
+	}
+	j.Delivered = kept
+	return out
+}
+
+// head returns the number of the highest block with a delivered log, and
+// whether there is any.
+func (j *logJournal) head() (uint64, bool) {
+	if len(j.Delivered) == 0 {
+		return 0, false
+	}
+	return j.Delivered[len(j.Delivered)-1].BlockNumber, true
+}
+
+// stale returns the delivered logs of blocks no longer canonical, flagged as
+// removed, newest first, for a resumed subscription to retract.
+func (j *logJournal) stale(db ethdb.Database) []*types.Log {
+	var (
+		removed   []*types.Log
+		canonical = make(map[uint64]common.Hash)
+	)
+	for i := len(j.Delivered) - 1; i >= 0; i-- {
+		l := j.Delivered[i]
+		hash, ok := canonical[l.BlockNumber]
+		if !ok {
+			hash = core.GetCanonicalHash(db, l.BlockNumber)
+			canonical[l.BlockNumber] = hash
+		}
+		if hash != l.BlockHash {
+			r := *l
+			r.Removed = true
+			removed = append(removed, &r)
+		}
+	}
+	return removed
+}
+
+func logJournalKey(id rpc.ID) []byte {
+	return append(append([]byte{}, logJournalPrefix...), id...)
+}
+
+// readLogJournal loads the journal of a subscription, nil if there is none or
+// if it expired.
+func readLogJournal(db ethdb.Database, id rpc.ID, now time.Time) *logJournal {
+	blob, err := db.Get(logJournalKey(id))
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	j := new(logJournal)
+	if err := json.Unmarshal(blob, j); err != nil {
+		log.Warn("Invalid log delivery journal", "id", id, "err", err)
+		return nil
+	}
+	if now.Sub(time.Unix(j.Updated, 0)) > logJournalGrace {
+		return nil
+	}
+	return j
+}
+
+// writeLogJournal stores the journal of a subscription.
+func writeLogJournal(db ethdb.Database, id rpc.ID, j *logJournal) {
+	blob, err := json.Marshal(j)
+	if err == nil {
+		err = db.Put(logJournalKey(id), blob)
+	}
+	if err != nil {
+		log.Warn("Failed to store log delivery journal", "id", id, "err", err)
+	}
+}
+
+// deleteLogJournal removes the journal of a subscription.
+func deleteLogJournal(db ethdb.Database, id rpc.ID) {
+	if err := db.Delete(logJournalKey(id)); err != nil {
+		log.Warn("Failed to delete log delivery journal", "id", id, "err", err)
+	}
+}
+
+// pruneLogJournals removes the journals that can no longer be resumed.
+func pruneLogJournals(db ethdb.Database, now time.Time) {
+	it := db.NewPrefixIterator(logJournalPrefix)
+	defer it.Release()
+
+	var expired [][]byte
+	for it.Next() {
+		var j logJournal
+		if err := json.Unmarshal(it.Value(), &j); err != nil || now.Sub(time.Unix(j.Updated, 0)) > logJournalGrace {
+			expired = append(expired, common.CopyBytes(it.Key()))
+		}
+	}
+	for _, key := range expired {
+		db.Delete(key)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+func journalLog(number uint64, block byte, index uint) *types.Log {
+	return &types.Log{BlockNumber: number, BlockHash: common.Hash{block}, Index: index, Topics: []common.Hash{}, Data: []byte{}}
+}
+
+func removedLog(l *types.Log) *types.Log {
+	r := *l
+	r.Removed = true
+	return &r
+}
+
+// Tests that the journal retracts the logs of replaced blocks before their
+// replacements, whichever order the chain reports them in, and drops duplicates.
+func TestLogJournalFilter(t *testing.T) {
+	var (
+		a1, a2 = journalLog(1, 0xa1, 0), journalLog(2, 0xa2, 1)
+		b2, b3 = journalLog(2, 0xb2, 1), journalLog(3, 0xb3, 2)
+	)
+	type step struct {
+		in, want []*types.Log
+	}
+	steps := []step{
+		{[]*types.Log{a1, a2}, []*types.Log{a1, a2}},
+		{[]*types.Log{a2}, nil},                                      // Duplicate delivery
+		{[]*types.Log{b2, b3}, []*types.Log{removedLog(a2), b2, b3}}, // Replacement reported before the removal
+		{[]*types.Log{removedLog(a2)}, nil},                          // Removal already reported
+		{[]*types.Log{removedLog(b3)}, []*types.Log{removedLog(b3)}},
+		{[]*types.Log{removedLog(b3)}, nil},
+		{[]*types.Log{removedLog(journalLog(5, 0xff, 0))}, nil}, // Never delivered
+	}
+	j := new(logJournal)
+	for i, step := range steps {
+		have := j.filter(step.in)
+		if len(have) != len(step.want) {
+			t.Fatalf("step %d: log count mismatch: have %d, want %d", i, len(have), len(step.want))
+		}
+		for k := range have {
+			if have[k].BlockHash != step.want[k].BlockHash || have[k].Index != step.want[k].Index || have[k].Removed != step.want[k].Removed {
+				t.Errorf("step %d, log %d: mismatch: have %+v, want %+v", i, k, have[k], step.want[k])
+			}
+		}
+	}
+	if head, _ := j.head(); head != 2 || len(j.Delivered) != 2 {
+		t.Errorf("journal mismatch: head %d, delivered %d", head, len(j.Delivered))
+	}
+}
+
+// Tests that the journal only remembers the most recent logs delivered.
+func TestLogJournalLimit(t *testing.T) {
+	logs := make([]*types.Log, logJournalLimit+10)
+	for i := range logs {
+		logs[i] = journalLog(1, 0xa1, uint(i))
+	}
+	j := new(logJournal)
+	if have := j.filter(logs); len(have) != len(logs) {
+		t.Fatalf("delivered log count mismatch: have %d, want %d", len(have), len(logs))
+	}
+	if len(j.Delivered) != logJournalLimit || j.Delivered[0].Index != 10 {
+		t.Errorf("journal mismatch: %d logs from index %d, want %d from 10", len(j.Delivered), j.Delivered[0].Index, logJournalLimit)
+	}
+}
+
+// Tests that journals are only resumed within the grace period.
+func TestLogJournalGrace(t *testing.T) {
+	var (
+		db  = ethdb.NewMemDatabase()
+		id  = rpc.ID("0x01")
+		now = time.Now()
+	)
+	writeLogJournal(db, id, &logJournal{Updated: now.Unix(), Delivered: []*types.Log{journalLog(1, 0xa1, 0)}})
+
+	j := readLogJournal(db, id, now.Add(logJournalGrace/2))
+	if j == nil || len(j.Delivered) != 1 || j.Delivered[0].BlockHash != (common.Hash{0xa1}) {
+		t.Fatalf("journal not resumed: %+v", j)
+	}
+	if j := readLogJournal(db, id, now.Add(2*logJournalGrace)); j != nil {
+		t.Errorf("expired journal resumed")
+	}
+	pruneLogJournals(db, now.Add(2*logJournalGrace))
+	if ok, _ := db.Has(logJournalKey(id)); ok {
+		t.Errorf("expired journal not pruned")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import "github.com/fulcrumchain/indigo/rpc"
+
+// FinalityConfig sets how deep below the chain head a block has to be to be
+// selected by the "safe" and "finalized" block tags. Clique has no finality
+// gadget, so reorgs are only bounded by the confirmation depth.
+type FinalityConfig struct {
+	SafeDepth      uint64 `toml:",omitempty"`
+	FinalizedDepth uint64 `toml:",omitempty"`
+}
+
+// DefaultFinalityConfig contains the default confirmation depths.
+var DefaultFinalityConfig = FinalityConfig{
+	SafeDepth:      12,
+	FinalizedDepth: 64,
+}
+
+// Resolve resolves the "safe" and "finalized" tags to the number of the block
+// they select on a chain with the given head, or the genesis block while the
+// chain is shorter than the depth. Other block numbers are returned unchanged.
+func (c FinalityConfig) Resolve(number rpc.BlockNumber, head uint64) rpc.BlockNumber {
+	var depth uint64
+	switch number {
+	case rpc.SafeBlockNumber:
+		depth = c.SafeDepth
+	case rpc.FinalizedBlockNumber:
+		depth = c.FinalizedDepth
+	default:
+		return number
+	}
+	if head < depth {
+		return rpc.EarliestBlockNumber
+	}
+	return rpc.BlockNumber(head - depth)
+}
+
+// resolveBlockNumber resolves the "safe" and "finalized" tags against the
+// current head of the chain.
+func (gc *Indigo) resolveBlockNumber(number rpc.BlockNumber) rpc.BlockNumber {
+	return gc.config.Finality.Resolve(number, gc.blockchain.CurrentBlock().NumberU64())
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+func TestFinalityResolve(t *testing.T) {
+	config := FinalityConfig{SafeDepth: 4, FinalizedDepth: 16}
+
+	tests := []struct {
+		number rpc.BlockNumber
+		head   uint64
+		want   rpc.BlockNumber
+	}{
+		{rpc.SafeBlockNumber, 100, 96},
+		{rpc.FinalizedBlockNumber, 100, 84},
+		{rpc.SafeBlockNumber, 3, rpc.EarliestBlockNumber},
+		{rpc.FinalizedBlockNumber, 16, rpc.EarliestBlockNumber},
+		{rpc.LatestBlockNumber, 100, rpc.LatestBlockNumber},
+		{rpc.PendingBlockNumber, 100, rpc.PendingBlockNumber},
+		{42, 100, 42},
+	}
+	for i, tt := range tests {
+		if have := config.Resolve(tt.number, tt.head); have != tt.want {
+			t.Errorf("test %d: resolved number mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"sort"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// forkID is the fork identifier advertised to eth/64 peers, as in EIP-2124: the
+// CRC32 checksum of the genesis hash and of the fork blocks already passed, and
+// the next fork block scheduled, 0 if none. Unlike a hash of the whole chain
+// configuration, it only changes when nodes schedule different forks, and tells
+// a node that is merely behind from one that diverged.
+type forkID struct {
+	Hash [4]byte
+	Next uint64
+}
+
+// forkFilter derives the local fork identifiers and checks the remote ones
+// against them.
+type forkFilter struct {
+	forks []uint64  // Fork blocks in ascending order, excluding the genesis
+	sums  [][4]byte // Checksum after passing the forks before the same index
+}
+
+// newForkFilter creates a fork filter for a chain configuration and genesis.
+func newForkFilter(config *params.ChainConfig, genesis common.Hash) *forkFilter {
+	f := &forkFilter{forks: gatherForks(config)}
+
+	hash := crc32.ChecksumIEEE(genesis[:])
+	f.sums = append(f.sums, checksumBytes(hash))
+	for _, fork := range f.forks {
+		var blob [8]byte
+		binary.BigEndian.PutUint64(blob[:], fork)
+		hash = crc32.Update(hash, crc32.IEEETable, blob[:])
+		f.sums = append(f.sums, checksumBytes(hash))
+	}
+	return f
+}
+
+// checksumBytes converts a CRC32 checksum into its big endian representation.
+func checksumBytes(hash uint32) [4]byte {
+	var blob [4]byte
+	binary.BigEndian.PutUint32(blob[:], hash)
+	return blob
+}
+
+// gatherForks returns the sorted, deduplicated blocks at which the chain
+// configuration changes the rules, excluding those active from the genesis.
+func gatherForks(config *params.ChainConfig) []uint64 {
+	var forks []uint64
+	for _, block := range []*big.Int{
+		config.HomesteadBlock, config.EIP150Block, config.EIP155Block,
+		config.EIP158Block, config.ByzantiumBlock, config.TypedTxBlock,
+	} {
+		if block != nil {
+			forks = append(forks, block.Uint64())
+		}
+	}
+	for _, precompile := range config.Precompiles {
+		if precompile.Block != nil {
+			forks = append(forks, precompile.Block.Uint64())
+		}
+	}
+	if config.GasSponsor != nil && config.GasSponsor.Block != nil {
+		forks = append(forks, config.GasSponsor.Block.Uint64())
+	}
+	if config.Clique != nil {
+		for _, fork := range config.Clique.Schedule {
+			forks = append(forks, fork.Block)
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i] < forks[j] })
+
+	var unique []uint64
+	for _, fork := range forks {
+		if fork > 0 && (len(unique) == 0 || unique[len(unique)-1] != fork) {
+			unique = append(unique, fork)
+		}
+	}
+	return unique
+}
+
+// String implements fmt.Stringer.
+func (id forkID) String() string {
+	return fmt.Sprintf("%x/%d", id.Hash, id.Next)
+}
+
+// id returns the local fork identifier at the given head block.
+func (f *forkFilter) id(head uint64) forkID {
+	for i, fork := range f.forks {
+		if head < fork {
+			return forkID{Hash: f.sums[i], Next: fork}
+		}
+	}
+	return forkID{Hash: f.sums[len(f.forks)]}
+}
+
+// compatible reports whether a remote fork identifier is compatible with the
+// local chain at the given head block, following the EIP-2124 rules:
+//   - Same checksum: compatible unless the remote announces a fork the local
+//     chain already passed without activating it.
+//   - Checksum of a past local state: compatible if the remote announces the
+//     next local fork, as it is only behind.
+//   - Checksum of a future local state: compatible, the local node is behind.
+//   - Any other checksum is incompatible.
+func (f *forkFilter) compatible(head uint64, remote forkID) bool {
+	local := f.id(head)
+	if remote.Hash == local.Hash {
+		return remote.Next == 0 || head < remote.Next
+	}
+	for i, sum := range f.sums {
+		if sum != remote.Hash {
+			continue
+		}
+		// The remote is behind if the fork following its checksum was passed
+		if i < len(f.forks) && f.forks[i] <= head {
+			return remote.Next == f.forks[i]
+		}
+		return true
+	}
+	return false
+}
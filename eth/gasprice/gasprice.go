@@ -18,9 +18,11 @@ package gasprice
 
 import (
 	"context"
+	"math"
 	"math/big"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/core/types"
@@ -33,6 +35,15 @@ var (
 	maxPrice = big.NewInt(500 * params.Shannon)
 )
 
+const (
+	// DefaultLatencyTarget is the inclusion latency considered uncongested.
+	DefaultLatencyTarget = 15 * time.Second
+
+	// maxCongestion caps each congestion signal, bounding how far congestion
+	// can raise the suggestion above the block prices.
+	maxCongestion = 4.0
+)
+
 // Backend is a subset of the methods from the interface ethapi.Backend.
 type Backend interface {
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
@@ -44,6 +55,24 @@ type Config struct {
 	Blocks     int
 	Percentile int
 	Default    *big.Int `toml:",omitempty"`
+
+	// Weights of the transaction pool congestion signals. The pending gas in
+	// excess of a block and the inclusion latency in excess of the target each
+	// raise the suggestion by their weight per block or target exceeded.
+	DepthWeight   float64       `toml:",omitempty"`
+	LatencyWeight float64       `toml:",omitempty"`
+	LatencyTarget time.Duration `toml:",omitempty"`
+}
+
+// Congestion reports the state of the transaction pool, signalling demand
+// before it shows up in the fullness of blocks.
+type Congestion interface {
+	// PendingGas returns the total gas of the executable pooled transactions.
+	PendingGas() uint64
+
+	// InclusionLatency returns the average time recently included transactions
+	// spent executable in the pool, or zero if unknown.
+	InclusionLatency() time.Duration
 }
 
 // Oracle recommends gas prices based on the content of recent
@@ -56,6 +85,8 @@ type Oracle struct {
 	lastHead  common.Hash
 	lastPrice *big.Int
 
+	congestion Congestion // Source of the congestion signals, nil if unavailable
+
 	fetchLock sync.Mutex
 }
 
@@ -72,12 +103,27 @@ func NewOracle(backend Backend, cfg Config) *Oracle {
 	if cfg.Default == nil {
 		cfg.Default = Default
 	}
+	if cfg.DepthWeight < 0 {
+		cfg.DepthWeight = 0
+	}
+	if cfg.LatencyWeight < 0 {
+		cfg.LatencyWeight = 0
+	}
+	if cfg.LatencyTarget <= 0 {
+		cfg.LatencyTarget = DefaultLatencyTarget
+	}
 	return &Oracle{
 		backend: backend,
 		cfg:     cfg,
 	}
 }
 
+// SetCongestion sets the source of the transaction pool congestion signals
+// blended into the suggestions. It must be called before the oracle is used.
+func (gpo *Oracle) SetCongestion(congestion Congestion) {
+	gpo.congestion = congestion
+}
+
 // SuggestPrice returns the recommended gas price.
 func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	gpo.lastMu.RLock()
@@ -128,6 +174,7 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	}
 	sort.Sort(bigIntArray(blockPrices))
 	price := blockPrices[(len(blockPrices)-1)*gpo.cfg.Percentile/100]
+	price = gpo.congested(price, head)
 
 	if price.Cmp(maxPrice) > 0 {
 		price = new(big.Int).Set(maxPrice)
@@ -140,6 +187,33 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return price, nil
 }
 
+// congested raises a price by the congestion of the transaction pool: the
+// number of blocks the pending transactions exceed the head block's gas limit
+// by, and the number of latency targets their inclusion is late by, each
+// weighted by the configuration.
+func (gpo *Oracle) congested(price *big.Int, head *types.Header) *big.Int {
+	if gpo.congestion == nil || (gpo.cfg.DepthWeight == 0 && gpo.cfg.LatencyWeight == 0) {
+		return price
+	}
+	var depth, latency float64
+	if gpo.cfg.DepthWeight > 0 && head.GasLimit > 0 {
+		depth = float64(gpo.congestion.PendingGas())/float64(head.GasLimit) - 1
+	}
+	if gpo.cfg.LatencyWeight > 0 {
+		latency = float64(gpo.congestion.InclusionLatency())/float64(gpo.cfg.LatencyTarget) - 1
+	}
+	depth = math.Max(0, math.Min(depth, maxCongestion))
+	latency = math.Max(0, math.Min(latency, maxCongestion))
+
+	factor := 1 + gpo.cfg.DepthWeight*depth + gpo.cfg.LatencyWeight*latency
+	if factor == 1 {
+		return price
+	}
+	// Scale in parts per million to stay in integer arithmetic
+	scaled := new(big.Int).Mul(price, big.NewInt(int64(factor*1e6)))
+	return scaled.Div(scaled, big.NewInt(1e6))
+}
+
 type result struct {
 	price *big.Int
 	err   error
@@ -5,6 +5,7 @@ import (
 	"crypto/ecdsa"
 	"math/big"
 	"testing"
+	"time"
 
 	"math/rand"
 
@@ -240,6 +241,45 @@ func TestOracle_SuggestPrice(t *testing.T) {
 
 }
 
+type testCongestion struct {
+	gas     uint64
+	latency time.Duration
+}
+
+func (c *testCongestion) PendingGas() uint64              { return c.gas }
+func (c *testCongestion) InclusionLatency() time.Duration { return c.latency }
+
+func TestOracle_SuggestPriceCongestion(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     Config
+		congestion testCongestion
+		exp        uint64
+	}{
+		{"unweighted", Config{}, testCongestion{gas: 10 * params.TxGas, latency: time.Minute}, 1000},
+		{"uncongested", Config{DepthWeight: 1, LatencyWeight: 1, LatencyTarget: 10 * time.Second}, testCongestion{gas: params.TxGas, latency: 5 * time.Second}, 1000},
+		{"deep", Config{DepthWeight: 0.5}, testCongestion{gas: 3 * params.TxGas}, 2000},
+		{"late", Config{LatencyWeight: 0.25, LatencyTarget: 10 * time.Second}, testCongestion{latency: 30 * time.Second}, 1500},
+		{"deep and late", Config{DepthWeight: 0.5, LatencyWeight: 0.25, LatencyTarget: 10 * time.Second}, testCongestion{gas: 3 * params.TxGas, latency: 30 * time.Second}, 2500},
+		{"capped", Config{DepthWeight: 1}, testCongestion{gas: 100 * params.TxGas}, 5000},
+	}
+	for _, tt := range tests {
+		tt.config.Blocks, tt.config.Percentile = 1, 60
+
+		backend := newTestBackend(block{full: true, txs: []tx{{price: 1000}}})
+		o := NewOracle(backend, tt.config)
+		o.SetCongestion(&tt.congestion)
+
+		got, err := o.SuggestPrice(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got.Uint64() != tt.exp {
+			t.Errorf("%s: price mismatch: have %s, want %d", tt.name, got, tt.exp)
+		}
+	}
+}
+
 type suggestPriceTest struct {
 	name    string
 	exp     uint64
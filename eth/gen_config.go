@@ -9,6 +9,7 @@ import (
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/hexutil"
 	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/importhook"
 	"github.com/fulcrumchain/indigo/eth/downloader"
 	"github.com/fulcrumchain/indigo/eth/gasprice"
 	"github.com/fulcrumchain/indigo/ethdb/archive"
@@ -23,44 +24,98 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		SyncBandwidth           downloader.Bandwidth `toml:",omitempty"`
+		LightServ               int                  `toml:",omitempty"`
+		LightPeers              int                  `toml:",omitempty"`
+		PeerScaling             PeerScalingConfig    `toml:",omitempty"`
+		BloomThrottle           core.IndexerThrottle `toml:",omitempty"`
+		SkipBcVersionCheck      bool                 `toml:"-"`
+		DatabaseHandles         int                  `toml:"-"`
 		DatabaseCache           int
 		TrieCache               int
 		TrieTimeout             time.Duration
+		TrieClean               int `toml:",omitempty"`
+		Snapshot                bool
+		ReceiptRetention        uint64         `toml:",omitempty"`
+		TxWorkers               int            `toml:",omitempty"`
+		AddressIndex            bool           `toml:",omitempty"`
+		InternalIndex           bool           `toml:",omitempty"`
+		HistoryCache            int            `toml:",omitempty"`
+		HistoryInterval         uint64         `toml:",omitempty"`
+		HistoryLifetime         time.Duration  `toml:",omitempty"`
 		Etherbase               common.Address `toml:",omitempty"`
 		MinerThreads            int            `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		GasLimitTarget          uint64 `toml:",omitempty"`
+		TxOrderCommit           bool   `toml:",omitempty"`
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
-		DocRoot                 string         `toml:"-"`
-		Archive                 archive.Config `toml:",omitempty"`
+		VMStats                 bool                 `toml:",omitempty"`
+		DocRoot                 string               `toml:"-"`
+		Archive                 archive.Config       `toml:",omitempty"`
+		ImportPolicy            importhook.Config    `toml:",omitempty"`
+		ImportHook              core.ImportHook      `toml:"-"`
+		Blocklist               core.BlocklistConfig `toml:",omitempty"`
+		Finality                FinalityConfig
+		Watch                   WatchConfig        `toml:",omitempty"`
+		Schedule                bool               `toml:",omitempty"`
+		HeadBeacon              time.Duration      `toml:",omitempty"`
+		Watchdog                WatchdogConfig     `toml:",omitempty"`
+		ServeStateDiag          bool               `toml:",omitempty"`
+		DBSupervisor            DBSupervisorConfig `toml:",omitempty"`
+		SQLMirror               SQLMirrorConfig    `toml:",omitempty"`
+		Health                  HealthConfig       `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
+	enc.SyncBandwidth = c.SyncBandwidth
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.PeerScaling = c.PeerScaling
+	enc.BloomThrottle = c.BloomThrottle
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
 	enc.TrieCache = c.TrieCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.TrieClean = c.TrieClean
+	enc.Snapshot = c.Snapshot
+	enc.ReceiptRetention = c.ReceiptRetention
+	enc.TxWorkers = c.TxWorkers
+	enc.AddressIndex = c.AddressIndex
+	enc.InternalIndex = c.InternalIndex
+	enc.HistoryCache = c.HistoryCache
+	enc.HistoryInterval = c.HistoryInterval
+	enc.HistoryLifetime = c.HistoryLifetime
 	enc.Etherbase = c.Etherbase
 	enc.MinerThreads = c.MinerThreads
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
+	enc.GasLimitTarget = c.GasLimitTarget
+	enc.TxOrderCommit = c.TxOrderCommit
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.VMStats = c.VMStats
 	enc.DocRoot = c.DocRoot
 	enc.Archive = c.Archive
+	enc.ImportPolicy = c.ImportPolicy
+	enc.ImportHook = c.ImportHook
+	enc.Blocklist = c.Blocklist
+	enc.Finality = c.Finality
+	enc.Watch = c.Watch
+	enc.Schedule = c.Schedule
+	enc.HeadBeacon = c.HeadBeacon
+	enc.Watchdog = c.Watchdog
+	enc.ServeStateDiag = c.ServeStateDiag
+	enc.DBSupervisor = c.DBSupervisor
+	enc.SQLMirror = c.SQLMirror
+	enc.Health = c.Health
 	return &enc, nil
 }
 
@@ -71,22 +126,49 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		SyncBandwidth           *downloader.Bandwidth `toml:",omitempty"`
+		LightServ               *int                  `toml:",omitempty"`
+		LightPeers              *int                  `toml:",omitempty"`
+		PeerScaling             *PeerScalingConfig    `toml:",omitempty"`
+		BloomThrottle           *core.IndexerThrottle `toml:",omitempty"`
+		SkipBcVersionCheck      *bool                 `toml:"-"`
+		DatabaseHandles         *int                  `toml:"-"`
 		DatabaseCache           *int
 		TrieCache               *int
 		TrieTimeout             *time.Duration
+		TrieClean               *int `toml:",omitempty"`
+		Snapshot                *bool
+		ReceiptRetention        *uint64         `toml:",omitempty"`
+		TxWorkers               *int            `toml:",omitempty"`
+		AddressIndex            *bool           `toml:",omitempty"`
+		InternalIndex           *bool           `toml:",omitempty"`
+		HistoryCache            *int            `toml:",omitempty"`
+		HistoryInterval         *uint64         `toml:",omitempty"`
+		HistoryLifetime         *time.Duration  `toml:",omitempty"`
 		Etherbase               *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		GasLimitTarget          *uint64 `toml:",omitempty"`
+		TxOrderCommit           *bool   `toml:",omitempty"`
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
-		DocRoot                 *string         `toml:"-"`
-		Archive                 *archive.Config `toml:",omitempty"`
+		VMStats                 *bool                 `toml:",omitempty"`
+		DocRoot                 *string               `toml:"-"`
+		Archive                 *archive.Config       `toml:",omitempty"`
+		ImportPolicy            *importhook.Config    `toml:",omitempty"`
+		ImportHook              core.ImportHook       `toml:"-"`
+		Blocklist               *core.BlocklistConfig `toml:",omitempty"`
+		Finality                *FinalityConfig
+		Watch                   *WatchConfig        `toml:",omitempty"`
+		Schedule                *bool               `toml:",omitempty"`
+		HeadBeacon              *time.Duration      `toml:",omitempty"`
+		Watchdog                *WatchdogConfig     `toml:",omitempty"`
+		ServeStateDiag          *bool               `toml:",omitempty"`
+		DBSupervisor            *DBSupervisorConfig `toml:",omitempty"`
+		SQLMirror               *SQLMirrorConfig    `toml:",omitempty"`
+		Health                  *HealthConfig       `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -104,12 +186,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
+	if dec.SyncBandwidth != nil {
+		c.SyncBandwidth = *dec.SyncBandwidth
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.PeerScaling != nil {
+		c.PeerScaling = *dec.PeerScaling
+	}
+	if dec.BloomThrottle != nil {
+		c.BloomThrottle = *dec.BloomThrottle
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -125,6 +216,33 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.TrieClean != nil {
+		c.TrieClean = *dec.TrieClean
+	}
+	if dec.Snapshot != nil {
+		c.Snapshot = *dec.Snapshot
+	}
+	if dec.ReceiptRetention != nil {
+		c.ReceiptRetention = *dec.ReceiptRetention
+	}
+	if dec.TxWorkers != nil {
+		c.TxWorkers = *dec.TxWorkers
+	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.InternalIndex != nil {
+		c.InternalIndex = *dec.InternalIndex
+	}
+	if dec.HistoryCache != nil {
+		c.HistoryCache = *dec.HistoryCache
+	}
+	if dec.HistoryInterval != nil {
+		c.HistoryInterval = *dec.HistoryInterval
+	}
+	if dec.HistoryLifetime != nil {
+		c.HistoryLifetime = *dec.HistoryLifetime
+	}
 	if dec.Etherbase != nil {
 		c.Etherbase = *dec.Etherbase
 	}
@@ -137,6 +255,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
+	if dec.GasLimitTarget != nil {
+		c.GasLimitTarget = *dec.GasLimitTarget
+	}
+	if dec.TxOrderCommit != nil {
+		c.TxOrderCommit = *dec.TxOrderCommit
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
@@ -146,11 +270,50 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.VMStats != nil {
+		c.VMStats = *dec.VMStats
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
 	if dec.Archive != nil {
 		c.Archive = *dec.Archive
 	}
+	if dec.ImportPolicy != nil {
+		c.ImportPolicy = *dec.ImportPolicy
+	}
+	if dec.ImportHook != nil {
+		c.ImportHook = dec.ImportHook
+	}
+	if dec.Blocklist != nil {
+		c.Blocklist = *dec.Blocklist
+	}
+	if dec.Finality != nil {
+		c.Finality = *dec.Finality
+	}
+	if dec.Watch != nil {
+		c.Watch = *dec.Watch
+	}
+	if dec.Schedule != nil {
+		c.Schedule = *dec.Schedule
+	}
+	if dec.HeadBeacon != nil {
+		c.HeadBeacon = *dec.HeadBeacon
+	}
+	if dec.Watchdog != nil {
+		c.Watchdog = *dec.Watchdog
+	}
+	if dec.ServeStateDiag != nil {
+		c.ServeStateDiag = *dec.ServeStateDiag
+	}
+	if dec.DBSupervisor != nil {
+		c.DBSupervisor = *dec.DBSupervisor
+	}
+	if dec.SQLMirror != nil {
+		c.SQLMirror = *dec.SQLMirror
+	}
+	if dec.Health != nil {
+		c.Health = *dec.Health
+	}
 	return nil
 }
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -61,8 +62,18 @@ var (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protocolError is a violation of the protocol by the remote peer.
+type protocolError struct {
+	code errCode
+	msg  string
+}
+
+func (err *protocolError) Error() string {
+	return fmt.Sprintf("%v - %v", err.code, err.msg)
+}
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protocolError{code: code, msg: fmt.Sprintf(format, v...)}
 }
 
 type ProtocolManager struct {
@@ -74,11 +85,21 @@ type ProtocolManager struct {
 	txpool      txPool
 	blockchain  *core.BlockChain
 	chainconfig *params.ChainConfig
-	maxPeers    int
+	forkFilter  *forkFilter // Fork identifiers advertised to and checked for eth/64 peers
+
+	peerScaling PeerScalingConfig // Settings of the adaptive peer target, set before Start
+	peerRole    func() string     // Role of the node if not configured, set before Start
+	scaler      *peerScaler
+	watchdog    *chainWatchdog // Observer of the peer chain configurations, set before Start if any
+
+	serveStateDiags bool               // Whether block executions are diagnosed for peers, set before Start
+	stateDiagBusy   int32              // Whether a block is being diagnosed for a peer
+	stateDiags      *stateDiagRequests // Pending requests for the diagnostics of peers
 
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
+	reputation *reputation
 
 	SubProtocols []p2p.Protocol
 
@@ -108,6 +129,8 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		txpool:      txpool,
 		blockchain:  blockchain,
 		chainconfig: config,
+		forkFilter:  newForkFilter(config, blockchain.Genesis().Hash()),
+		stateDiags:  newStateDiagRequests(),
 		peers:       newPeerSet(),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
@@ -155,13 +178,16 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 				}
 				return nil
 			},
+			Lane: messageLane,
 		})
 	}
 	if len(manager.SubProtocols) == 0 {
 		return nil, errIncompatibleConfig
 	}
+	manager.reputation = newReputation(manager.removePeer)
+
 	// Construct the different synchronisation mechanisms
-	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.dropStallingPeer)
 
 	getBlock := func(ctx context.Context, hash common.Hash) *types.Block {
 		ctx, span := trace.StartSpan(ctx, "getBlock")
@@ -183,11 +209,61 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertChain(ctx, blocks)
 	}
-	manager.fetcher = fetcher.New(getBlock, verifyHeader, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(getBlock, verifyHeader, manager.BroadcastBlock, heighter, inserter, manager.dropInvalidPeer)
 
 	return manager, nil
 }
 
+// dropStallingPeer is called by the downloader to drop a peer timing out or
+// stalling the sync, penalizing its reputation.
+func (pm *ProtocolManager) dropStallingPeer(id string) {
+	pm.reputation.timeout(id)
+	pm.removePeer(id)
+}
+
+// dropInvalidPeer is called by the fetcher to drop a peer propagating invalid
+// blocks, penalizing its reputation.
+func (pm *ProtocolManager) dropInvalidPeer(id string) {
+	pm.reputation.invalid(id)
+	pm.removePeer(id)
+}
+
+// rateDelivery updates the reputation of a peer on the outcome of delivering
+// requested data to the downloader.
+func (pm *ProtocolManager) rateDelivery(p *peer, items int, err error) {
+	switch {
+	case err != nil:
+		pm.reputation.useless(p.id)
+	case items > 0:
+		pm.reputation.useful(p.id)
+	}
+}
+
+// shedPeers disconnects the worst reputed untrusted peers until at most target
+// peers are left.
+func (pm *ProtocolManager) shedPeers(target int) {
+	excess := pm.peers.Len() - target
+	if excess <= 0 {
+		return
+	}
+	scores := pm.reputation.scores()
+	peers := pm.peers.All()
+	sort.Slice(peers, func(i, j int) bool {
+		return scores[peers[i].id].Score < scores[peers[j].id].Score
+	})
+	for _, p := range peers {
+		if excess == 0 {
+			break
+		}
+		if p.Peer.Info().Network.Trusted {
+			continue
+		}
+		p.Log().Debug("Shedding Indigo peer above target", "target", target)
+		p.Peer.Disconnect(p2p.DiscTooManyPeers)
+		excess--
+	}
+}
+
 func (pm *ProtocolManager) removePeer(id string) {
 	// Short circuit if the peer was already removed
 	peer := pm.peers.Peer(id)
@@ -210,7 +286,9 @@ func (pm *ProtocolManager) removePeer(id string) {
 }
 
 func (pm *ProtocolManager) Start(maxPeers int) {
-	pm.maxPeers = maxPeers
+	pm.scaler = newPeerScaler(pm.peerScaling, maxPeers, pm.peerRole, pm.shedPeers)
+	pm.scaler.adjust()
+	go pm.scaler.loop()
 
 	// broadcast transactions
 	pm.txsCh = make(chan core.NewTxsEvent, txChanSize)
@@ -227,9 +305,15 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	go pm.txResyncLoop()
 }
 
+// localForkID returns the fork identifier of the local chain at its head.
+func (pm *ProtocolManager) localForkID() forkID {
+	return pm.forkFilter.id(pm.blockchain.CurrentHeader().Number.Uint64())
+}
+
 func (pm *ProtocolManager) Stop() {
 	log.Info("Stopping Indigo protocol")
 
+	pm.scaler.stop()
 	pm.txsSub.Unsubscribe()        // quits txBroadcastLoop
 	pm.minedBlockSub.Unsubscribe() // quits blockBroadcastLoop
 
@@ -259,10 +343,15 @@ func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *p
 // handle is the callback invoked to manage the life cycle of an eth peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	// Ignore maxPeers if this is a trusted peer
-	if pm.peers.Len() >= pm.maxPeers && !p.Peer.Info().Network.Trusted {
+	// Ignore the peer target if this is a trusted peer
+	if pm.peers.Len() >= pm.scaler.Target() && !p.Peer.Info().Network.Trusted {
 		return p2p.DiscTooManyPeers
 	}
+	// Refuse banned peers, even if trusted
+	if pm.reputation.banned(p.id) {
+		p.Log().Debug("Refusing banned Indigo peer")
+		return p2p.DiscUselessPeer
+	}
 	p.Log().Debug("Indigo peer connected", "name", p.Name())
 
 	// Execute the Indigo handshake
@@ -273,10 +362,25 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		number  = head.Number.Uint64()
 		td      = pm.blockchain.GetTd(hash, number)
 	)
-	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash()); err != nil {
+	if err := p.Handshake(pm.networkId, td, hash, genesis.Hash(), pm.forkFilter.id(number)); err != nil {
 		p.Log().Debug("Indigo handshake failed", "err", err)
+		// Peers refused for their genesis only count if configured locally, as
+		// anyone can otherwise dial in to tip the watchdog
+		if perr, ok := err.(*protocolError); ok && perr.code == ErrGenesisBlockMismatch && p.network == pm.networkId && pm.watchdog != nil {
+			if info := p.Peer.Info(); info.Network.Static || info.Network.Trusted {
+				pm.watchdog.refused(p.id, p.genesis, time.Now())
+			}
+		}
 		return err
 	}
+	compatible := p.forkID == nil || pm.forkFilter.compatible(number, *p.forkID)
+	if !compatible {
+		p.Log().Debug("Peer advertises an incompatible fork identifier", "fork", *p.forkID, "local", pm.forkFilter.id(number))
+	}
+	if pm.watchdog != nil {
+		pm.watchdog.connected(p.id, p.genesis, p.forkID, compatible, time.Now())
+		defer pm.watchdog.disconnected(p.id)
+	}
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
@@ -299,6 +403,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Indigo message handling failed", "err", err)
+			if _, ok := err.(*protocolError); ok {
+				pm.reputation.invalid(p.id)
+			}
 			return err
 		}
 	}
@@ -432,6 +539,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if err != nil {
 				log.Debug("Failed to deliver headers", "err", err)
 			}
+			pm.rateDelivery(p, len(headers), err)
 		}
 
 	case msg.Code == GetBlockBodiesMsg:
@@ -486,6 +594,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			if err != nil {
 				log.Debug("Failed to deliver bodies", "err", err)
 			}
+			pm.rateDelivery(p, len(trasactions), err)
 		}
 
 	case p.version >= eth63 && msg.Code == GetNodeDataMsg:
@@ -523,9 +632,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Deliver all to the downloader
-		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
+		err := pm.downloader.DeliverNodeData(p.id, data)
+		if err != nil {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
+		pm.rateDelivery(p, len(data), err)
 
 	case p.version >= eth63 && msg.Code == GetReceiptsMsg:
 		// Decode the retrieval message
@@ -571,9 +682,41 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		// Deliver all to the downloader
-		if err := pm.downloader.DeliverReceipts(p.id, receipts); err != nil {
+		err := pm.downloader.DeliverReceipts(p.id, receipts)
+		if err != nil {
 			log.Debug("Failed to deliver receipts", "err", err)
 		}
+		pm.rateDelivery(p, len(receipts), err)
+
+	case p.version >= eth65 && msg.Code == GetStateDiagMsg:
+		// Execute the requested block again in the background, if recent, and
+		// send the state it went through
+		var hash common.Hash
+		if err := msg.Decode(&hash); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if !p.allowStateDiag(time.Now()) {
+			return p.SendStateDiag(ctx, hash, nil)
+		}
+		pm.wg.Add(1)
+		go func() {
+			defer pm.wg.Done()
+
+			ctx := context.Background()
+			if err := p.SendStateDiag(ctx, hash, pm.serveStateDiag(ctx, hash)); err != nil {
+				p.Log().Debug("Failed to send state diagnostics", "err", err)
+			}
+		}()
+
+	case p.version >= eth65 && msg.Code == StateDiagMsg:
+		// The state diagnostics of a block arrived to one of our previous requests
+		var data stateDiagData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if !pm.stateDiags.deliver(p.id, &data) {
+			p.Log().Debug("Unrequested state diagnostics", "hash", data.Hash)
+		}
 
 	case msg.Code == NewBlockHashesMsg:
 		var announces newBlockHashesData
@@ -729,11 +872,27 @@ func (pm *ProtocolManager) BroadcastBlock(ctx context.Context, block *types.Bloc
 // BroadcastTxs propagates a batch of transactions to a subset of peers which are not known to already have them.
 // Returns without blocking after launching each peer send in separate concurrent goroutines.
 func (pm *ProtocolManager) BroadcastTxs(ctx context.Context, txs types.Transactions) {
+	txs = pm.publicTxs(txs)
+	if len(txs) == 0 {
+		return
+	}
 	for p, txs := range pm.peers.PeersWithoutTxs(ctx, txs) {
 		p.SendTransactionsAsync(txs)
 	}
 }
 
+// publicTxs filters out the transactions withheld from peers, those submitted
+// privately or valid until a deadline, which are kept for the local miner only.
+func (pm *ProtocolManager) publicTxs(txs types.Transactions) types.Transactions {
+	public := txs[:0:0]
+	for _, tx := range txs {
+		if !pm.txpool.Withheld(tx.Hash()) {
+			public = append(public, tx)
+		}
+	}
+	return public
+}
+
 // Mined broadcast loop
 func (pm *ProtocolManager) minedBroadcastLoop() {
 	// automatically stops if unsubscribe
@@ -0,0 +1,217 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/p2p/discover"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const (
+	headBeaconLifetime = 10 * time.Minute // Time after which the head of a silent node is forgotten
+	maxHeadBeacons     = 1024             // Maximum number of nodes whose heads are tracked
+)
+
+// headBeaconPayload is the chain head announced in discovery beacons.
+type headBeaconPayload struct {
+	Genesis   common.Hash
+	NetworkId uint64
+	Number    uint64
+	Hash      common.Hash
+}
+
+// HeadBeacon is the last chain head announced by a node of the network.
+type HeadBeacon struct {
+	ID        string         `json:"id"`
+	Addr      string         `json:"addr"`
+	Number    hexutil.Uint64 `json:"number"`
+	Hash      common.Hash    `json:"hash"`
+	Received  time.Time      `json:"received"`
+	Canonical *bool          `json:"canonical"` // Whether the head is in the local chain, nil if beyond its head
+}
+
+// beaconServer is the part of the p2p server exchanging discovery beacons.
+type beaconServer interface {
+	SendBeacon(payload []byte) (int, error)
+	SubscribeBeacons(ch chan<- discover.Beacon) event.Subscription
+}
+
+// headBeacons announces the local chain head over discovery at a fixed interval,
+// and tracks the heads announced by other nodes of the same network, so that
+// forks can be spotted without protocol connections to every node.
+type headBeacons struct {
+	chain     *core.BlockChain
+	srv       beaconServer
+	networkId uint64
+	interval  time.Duration // Interval of the local announcements, 0 to only listen
+
+	heads map[discover.NodeID]*HeadBeacon
+	lock  sync.RWMutex // Protects heads
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newHeadBeacons starts announcing the local chain head and tracking those of
+// other nodes.
+func newHeadBeacons(chain *core.BlockChain, srv beaconServer, networkId uint64, interval time.Duration) *headBeacons {
+	b := &headBeacons{
+		chain:     chain,
+		srv:       srv,
+		networkId: networkId,
+		interval:  interval,
+		heads:     make(map[discover.NodeID]*HeadBeacon),
+		quit:      make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.loop()
+	return b
+}
+
+// loop sends the local head and collects the received ones.
+func (b *headBeacons) loop() {
+	defer b.wg.Done()
+
+	beacons := make(chan discover.Beacon, 64)
+	sub := b.srv.SubscribeBeacons(beacons)
+	defer sub.Unsubscribe()
+
+	var announce <-chan time.Time
+	if b.interval > 0 {
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+		announce = ticker.C
+	}
+	expire := time.NewTicker(headBeaconLifetime / 10)
+	defer expire.Stop()
+
+	for {
+		select {
+		case <-announce:
+			b.announce()
+		case beacon := <-beacons:
+			b.receive(beacon, time.Now())
+		case now := <-expire.C:
+			b.expire(now)
+		case <-sub.Err():
+			return
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// announce sends the local chain head to the nodes of the discovery table.
+func (b *headBeacons) announce() {
+	head := b.chain.CurrentBlock()
+	payload, err := rlp.EncodeToBytes(&headBeaconPayload{
+		Genesis:   b.chain.Genesis().Hash(),
+		NetworkId: b.networkId,
+		Number:    head.NumberU64(),
+		Hash:      head.Hash(),
+	})
+	if err != nil {
+		log.Error("Failed to encode head beacon", "err", err)
+		return
+	}
+	sent, err := b.srv.SendBeacon(payload)
+	if err != nil {
+		log.Warn("Failed to send head beacon", "err", err)
+		return
+	}
+	log.Trace("Sent head beacon", "number", head.NumberU64(), "hash", head.Hash(), "nodes", sent)
+}
+
+// receive records the head announced in a beacon, if from the same network.
+func (b *headBeacons) receive(beacon discover.Beacon, now time.Time) {
+	var payload headBeaconPayload
+	if err := rlp.DecodeBytes(beacon.Payload, &payload); err != nil {
+		log.Trace("Invalid head beacon", "id", beacon.From, "err", err)
+		return
+	}
+	if payload.Genesis != b.chain.Genesis().Hash() || payload.NetworkId != b.networkId {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if _, ok := b.heads[beacon.From]; !ok && len(b.heads) >= maxHeadBeacons {
+		return
+	}
+	b.heads[beacon.From] = &HeadBeacon{
+		ID:       beacon.From.String(),
+		Addr:     beacon.Addr.String(),
+		Number:   hexutil.Uint64(payload.Number),
+		Hash:     payload.Hash,
+		Received: now,
+	}
+}
+
+// expire forgets the heads of the nodes silent for longer than the lifetime.
+func (b *headBeacons) expire(now time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for id, head := range b.heads {
+		if now.Sub(head.Received) > headBeaconLifetime {
+			delete(b.heads, id)
+		}
+	}
+}
+
+// Heads returns the last heads announced by the nodes of the network, highest
+// first, flagging whether each is part of the local chain.
+func (b *headBeacons) Heads() []*HeadBeacon {
+	b.lock.RLock()
+	heads := make([]*HeadBeacon, 0, len(b.heads))
+	for _, head := range b.heads {
+		cpy := *head
+		heads = append(heads, &cpy)
+	}
+	b.lock.RUnlock()
+
+	local := b.chain.CurrentBlock().NumberU64()
+	for _, head := range heads {
+		if uint64(head.Number) <= local {
+			header := b.chain.GetHeaderByNumber(uint64(head.Number))
+			canonical := header != nil && header.Hash() == head.Hash
+			head.Canonical = &canonical
+		}
+	}
+	sort.Slice(heads, func(i, j int) bool {
+		if heads[i].Number != heads[j].Number {
+			return heads[i].Number > heads[j].Number
+		}
+		return heads[i].ID < heads[j].ID
+	})
+	return heads
+}
+
+// stop terminates the announcements and the tracking.
+func (b *headBeacons) stop() {
+	close(b.quit)
+	b.wg.Wait()
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/p2p/discover"
+	"github.com/fulcrumchain/indigo/params"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+// beaconTestServer records the beacons sent through it.
+type beaconTestServer struct {
+	sent [][]byte
+	feed event.Feed
+}
+
+func (s *beaconTestServer) SendBeacon(payload []byte) (int, error) {
+	s.sent = append(s.sent, payload)
+	return 1, nil
+}
+
+func (s *beaconTestServer) SubscribeBeacons(ch chan<- discover.Beacon) event.Subscription {
+	return s.feed.Subscribe(ch)
+}
+
+// Tests that the local head is announced, and that the heads announced by nodes
+// of the same network are tracked and checked against the local chain.
+func TestHeadBeacons(t *testing.T) {
+	var (
+		db            = ethdb.NewMemDatabase()
+		gspec         = &core.Genesis{Config: params.TestChainConfig}
+		genesis       = gspec.MustCommit(db)
+		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, clique.NewFaker(), vm.Config{})
+	)
+	defer blockchain.Stop()
+
+	chain, _ := core.GenerateChain(context.Background(), gspec.Config, genesis, clique.NewFaker(), db, 4, nil)
+	if _, err := blockchain.InsertChain(context.Background(), chain[:2]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	srv := new(beaconTestServer)
+	b := newHeadBeacons(blockchain, srv, 1, 0)
+	defer b.stop()
+
+	b.announce()
+	var local headBeaconPayload
+	if len(srv.sent) != 1 {
+		t.Fatalf("sent beacon count mismatch: have %d, want 1", len(srv.sent))
+	}
+	if err := rlp.DecodeBytes(srv.sent[0], &local); err != nil {
+		t.Fatalf("failed to decode beacon: %v", err)
+	}
+	if local.Genesis != genesis.Hash() || local.NetworkId != 1 || local.Number != 2 || local.Hash != chain[1].Hash() {
+		t.Errorf("announced head mismatch: %+v", local)
+	}
+
+	now := time.Now()
+	announce := func(id byte, genesis common.Hash, network, number uint64, hash common.Hash, at time.Time) {
+		payload, _ := rlp.EncodeToBytes(&headBeaconPayload{Genesis: genesis, NetworkId: network, Number: number, Hash: hash})
+		b.receive(discover.Beacon{From: discover.NodeID{id}, Addr: &net.UDPAddr{IP: net.IP{10, 0, 0, id}, Port: 30303}, Payload: payload}, at)
+	}
+	announce(1, genesis.Hash(), 1, 2, chain[1].Hash(), now)                 // agrees with the local chain
+	announce(2, genesis.Hash(), 1, 1, common.Hash{0xff}, now)               // forked
+	announce(3, genesis.Hash(), 1, 4, chain[3].Hash(), now)                 // ahead
+	announce(4, common.Hash{0x01}, 1, 2, chain[1].Hash(), now)              // other genesis
+	announce(5, genesis.Hash(), 2, 2, chain[1].Hash(), now)                 // other network
+	announce(6, genesis.Hash(), 1, 1, chain[0].Hash(), now.Add(-time.Hour)) // silent since
+
+	heads := b.Heads()
+	if len(heads) != 4 {
+		t.Fatalf("head count mismatch: have %d, want 4", len(heads))
+	}
+	if heads[0].ID != (discover.NodeID{3}).String() || heads[0].Canonical != nil {
+		t.Errorf("head ahead of the local chain mismatch: %+v", heads[0])
+	}
+	if heads[1].ID != (discover.NodeID{1}).String() || heads[1].Canonical == nil || !*heads[1].Canonical {
+		t.Errorf("canonical head mismatch: %+v", heads[1])
+	}
+	if heads[2].ID != (discover.NodeID{2}).String() || heads[2].Canonical == nil || *heads[2].Canonical {
+		t.Errorf("forked head mismatch: %+v", heads[2])
+	}
+	b.expire(now)
+	if heads := b.Heads(); len(heads) != 3 {
+		t.Errorf("head count after expiry mismatch: have %d, want 3", len(heads))
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/node"
+)
+
+// HealthConfig sets the thresholds of the readiness checks served on /ready.
+// Zero disables a check.
+type HealthConfig struct {
+	MinPeers    int           `toml:",omitempty"` // Minimum number of peers connected
+	MaxSyncLag  uint64        `toml:",omitempty"` // Maximum number of blocks the head is behind the highest known one
+	MaxBlockAge time.Duration `toml:",omitempty"` // Maximum age of the head block
+	MaxPoolFill float64       `toml:",omitempty"` // Maximum fraction of the transaction pool slots in use
+}
+
+// DefaultHealthConfig tolerates a small sync lag, without checking the block
+// age as private chains may idle.
+var DefaultHealthConfig = HealthConfig{
+	MinPeers:    1,
+	MaxSyncLag:  32,
+	MaxPoolFill: 0.95,
+}
+
+// HealthChecks implements node.HealthChecker, reporting the availability of the
+// chain database, which the node can't live without, and the peer count, sync
+// status, head block age and transaction pool fill it is ready to serve with.
+func (s *Indigo) HealthChecks() []node.HealthCheck {
+	return []node.HealthCheck{
+		s.databaseHealth(),
+		s.peersHealth(),
+		s.syncHealth(),
+		s.blockAgeHealth(time.Now()),
+		s.txPoolHealth(),
+	}
+}
+
+// databaseHealth checks that the head of the chain is readable.
+func (s *Indigo) databaseHealth() node.HealthCheck {
+	check := node.HealthCheck{Name: "database"}
+	if s.dbSuper != nil {
+		if fault := s.dbSuper.status().Fault; fault != nil {
+			check.Reason = "corrupted: " + fault.Reason
+			return check
+		}
+	}
+	hash := core.GetHeadBlockHash(s.chainDb)
+	if hash == (common.Hash{}) {
+		check.Reason = "head block hash unreadable"
+		return check
+	}
+	check.Live, check.Ready, check.Value = true, true, hash
+	return check
+}
+
+// peersHealth checks that enough peers are connected.
+func (s *Indigo) peersHealth() node.HealthCheck {
+	peers := s.protocolManager.peers.Len()
+	check := node.HealthCheck{Name: "peers", Live: true, Ready: true, Value: peers}
+	if min := s.config.Health.MinPeers; peers < min {
+		check.Ready, check.Reason = false, fmt.Sprintf("%d peers connected, %d required", peers, min)
+	}
+	return check
+}
+
+// syncHealth checks that the head is close to the highest block known from
+// peers, and that fast sync is done.
+func (s *Indigo) syncHealth() node.HealthCheck {
+	var (
+		head    = s.blockchain.CurrentBlock().NumberU64()
+		highest = s.protocolManager.downloader.Progress().HighestBlock
+		lag     uint64
+	)
+	if highest > head {
+		lag = highest - head
+	}
+	check := node.HealthCheck{Name: "sync", Live: true, Ready: true, Value: lag}
+	switch {
+	case atomic.LoadUint32(&s.protocolManager.fastSync) == 1:
+		check.Ready, check.Reason = false, "fast sync in progress"
+	case s.config.Health.MaxSyncLag > 0 && lag > s.config.Health.MaxSyncLag:
+		check.Ready, check.Reason = false, fmt.Sprintf("head #%d is %d blocks behind #%d", head, lag, highest)
+	}
+	return check
+}
+
+// blockAgeHealth checks that the head block is recent, reporting its age in
+// seconds.
+func (s *Indigo) blockAgeHealth(now time.Time) node.HealthCheck {
+	head := s.blockchain.CurrentBlock()
+	age := now.Sub(time.Unix(head.Time().Int64(), 0))
+	if age < 0 {
+		age = 0
+	}
+	check := node.HealthCheck{Name: "blockAge", Live: true, Ready: true, Value: int64(age / time.Second)}
+	if max := s.config.Health.MaxBlockAge; max > 0 && age > max {
+		check.Ready, check.Reason = false, fmt.Sprintf("head #%d is %v old, at most %v allowed", head.NumberU64(), common.PrettyDuration(age), max)
+	}
+	return check
+}
+
+// txPoolHealth checks that the transaction pool has room left.
+func (s *Indigo) txPoolHealth() node.HealthCheck {
+	pending, queued := s.txPool.Stats()
+	capacity := s.config.TxPool.GlobalSlots + s.config.TxPool.GlobalQueue
+
+	var fill float64
+	if capacity > 0 {
+		fill = float64(pending+queued) / float64(capacity)
+	}
+	check := node.HealthCheck{Name: "txpool", Live: true, Ready: true, Value: fill}
+	if max := s.config.Health.MaxPoolFill; max > 0 && fill > max {
+		check.Ready, check.Reason = false, fmt.Sprintf("%d pending and %d queued transactions fill %.0f%% of the pool", pending, queued, fill*100)
+	}
+	return check
+}
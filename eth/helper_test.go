@@ -91,9 +91,10 @@ func newTestProtocolManagerMust(ctx context.Context, t *testing.T, mode download
 
 // testTxPool is a fake, helper transaction pool for testing purposes
 type testTxPool struct {
-	txFeed event.Feed
-	pool   []*types.Transaction        // Collection of all transactions
-	added  chan<- []*types.Transaction // Notification channel for new transactions
+	txFeed  event.Feed
+	pool    []*types.Transaction        // Collection of all transactions
+	added   chan<- []*types.Transaction // Notification channel for new transactions
+	private map[common.Hash]bool        // Transactions submitted privately
 
 	lock sync.RWMutex // Protects the transaction pool
 }
@@ -135,6 +136,14 @@ func (p *testTxPool) PendingList(ctx context.Context) types.Transactions {
 	return pending
 }
 
+// Withheld reports whether a transaction was marked private.
+func (p *testTxPool) Withheld(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.private[hash]
+}
+
 func (p *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return p.txFeed.Subscribe(ch)
 }
@@ -182,21 +191,24 @@ func newTestPeer(ctx context.Context, name string, version int, pm *ProtocolMana
 			head    = pm.blockchain.CurrentHeader()
 			td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
 		)
-		tp.handshake(nil, td, head.Hash(), genesis.Hash())
+		tp.handshake(nil, td, head.Hash(), genesis.Hash(), pm.localForkID())
 	}
 	return tp, errc
 }
 
 // handshake simulates a trivial handshake that expects the same state from the
 // remote side as we are simulating locally.
-func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash) {
-	msg := &statusData{
+func (p *testPeer) handshake(t *testing.T, td *big.Int, head common.Hash, genesis common.Hash, fork forkID) {
+	var msg interface{} = &statusData{
 		ProtocolVersion: uint32(p.version),
 		NetworkId:       DefaultConfig.NetworkId,
 		TD:              td,
 		CurrentBlock:    head,
 		GenesisBlock:    genesis,
 	}
+	if p.version >= eth64 {
+		msg = &statusData64{uint32(p.version), DefaultConfig.NetworkId, td, head, genesis, fork}
+	}
 	if err := p2p.ExpectMsg(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status recv: %v", err)
 	}
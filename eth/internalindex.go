@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+// InternalIndexer implements a core.ChainIndexer, indexing the value transfers
+// made by contracts in every block under their sender and recipient. Blocks
+// are re-executed with a tracer, which needs the state of their parents, so
+// the index requires an archive node. A section containing a block that can't
+// be re-executed fails instead of being stored with gaps.
+type InternalIndexer struct {
+	db    ethdb.Database   // database instance to write the index into
+	chain *core.BlockChain // blockchain to re-execute the indexed blocks on
+
+	batch ethdb.Batch // batch collecting the index entries of the current section
+	err   error       // first failure to index a block of the current section
+}
+
+// NewInternalIndexer returns a chain indexer that maintains the address to
+// internal transaction index of the canonical chain.
+func NewInternalIndexer(db ethdb.Database, chain *core.BlockChain) *core.ChainIndexer {
+	backend := &InternalIndexer{
+		db:    db,
+		chain: chain,
+	}
+	table := ethdb.NewTable(db, string(core.InternalIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, addressIndexSection, addressIndexConfirms, 0, "internalindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new section.
+func (ix *InternalIndexer) Reset(section uint64, lastSectionHead common.Hash) error {
+	ix.batch, ix.err = ix.db.NewBatch(), nil
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, indexing the internal
+// transactions of the block belonging to header.
+func (ix *InternalIndexer) Process(header *types.Header) {
+	if ix.err != nil {
+		return
+	}
+	hash, number := header.Hash(), header.Number.Uint64()
+
+	block := ix.chain.GetBlock(hash, number)
+	if block == nil {
+		ix.err = fmt.Errorf("block #%d [%x…] not found", number, hash[:4])
+		return
+	}
+	if len(block.Transactions()) == 0 {
+		return
+	}
+	transfers, err := blockTransfers(ix.chain, block)
+	if err != nil {
+		ix.err = fmt.Errorf("failed to trace block #%d [%x…]: %v", number, hash[:4], err)
+		return
+	}
+	var index uint32
+	for i, tx := range block.Transactions() {
+		for _, t := range transfers[i] {
+			if !t.internal {
+				continue
+			}
+			entry := &core.InternalTx{
+				BlockHash: hash,
+				TxHash:    tx.Hash(),
+				TxIndex:   uint32(i),
+				From:      t.from,
+				To:        t.to,
+				Value:     t.value,
+			}
+			core.WriteInternalTx(ix.batch, t.from, number, index, entry)
+			if t.to != t.from {
+				core.WriteInternalTx(ix.batch, t.to, number, index, entry)
+			}
+			index++
+		}
+	}
+}
+
+// Commit implements core.ChainIndexerBackend, flushing the index entries of
+// the section into the database, unless a block of it failed to be indexed.
+func (ix *InternalIndexer) Commit() error {
+	if ix.err != nil {
+		return ix.err
+	}
+	if err := ix.batch.Write(); err != nil {
+		return fmt.Errorf("failed to write internal transaction index: %v", err)
+	}
+	return nil
+}
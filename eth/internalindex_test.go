@@ -0,0 +1,122 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// Tests that the internal transaction indexer files the value transfers made by
+// contracts under their sender and recipient, but not the plain transfers.
+func TestInternalIndexer(t *testing.T) {
+	ctx := context.Background()
+	var (
+		recipient = common.Address{0x12, 0x34}
+		forwarder = common.Address{0xf0} // Forwards its value to the recipient
+
+		db     = ethdb.NewMemDatabase()
+		engine = clique.NewFaker()
+		signer = types.HomesteadSigner{}
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank:  {Balance: big.NewInt(1000000)},
+				forwarder: {Code: append(append([]byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x34, 0x73}, recipient[:]...), 0x5a, 0xf1, 0x50, 0x00), Balance: new(big.Int)},
+			},
+		}
+		genesis       = gspec.MustCommit(db)
+		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	)
+	defer blockchain.Stop()
+
+	// Forward a value in every block, and transfer directly in the second
+	chain, _ := core.GenerateChain(ctx, gspec.Config, genesis, engine, db, 3, func(ctx context.Context, i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), forwarder, big.NewInt(int64(i+1)), 100000, nil, nil), signer, testBankKey)
+		block.AddTx(ctx, tx)
+		if i == 1 {
+			tx, _ = types.SignTx(types.NewTransaction(block.TxNonce(testBank), recipient, big.NewInt(1000), params.TxGas, nil, nil), signer, testBankKey)
+			block.AddTx(ctx, tx)
+		}
+	})
+	if _, err := blockchain.InsertChain(ctx, chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	indexer := &InternalIndexer{db: db, chain: blockchain}
+	for number := uint64(1); number <= 3; number++ {
+		if err := indexer.Reset(number-1, common.Hash{}); err != nil {
+			t.Fatalf("block %d: failed to reset indexer: %v", number, err)
+		}
+		indexer.Process(blockchain.GetHeaderByNumber(number))
+		if err := indexer.Commit(); err != nil {
+			t.Fatalf("block %d: failed to commit index: %v", number, err)
+		}
+	}
+	tests := []struct {
+		addr        common.Address
+		from, to    uint64
+		skip, limit int
+		want        []uint64 // block numbers, and values, of the expected results
+	}{
+		{forwarder, 0, 3, 0, 10, []uint64{1, 2, 3}},
+		{recipient, 0, 3, 0, 10, []uint64{1, 2, 3}},
+		{recipient, 2, 3, 0, 10, []uint64{2, 3}},
+		{recipient, 0, 3, 1, 1, []uint64{2}},
+		{testBank, 0, 3, 0, 10, nil},
+	}
+	for i, tt := range tests {
+		txs, err := core.GetInternalTxs(db, tt.addr, tt.from, tt.to, tt.skip, tt.limit)
+		if err != nil {
+			t.Errorf("test %d: failed to query index: %v", i, err)
+			continue
+		}
+		if len(txs) != len(tt.want) {
+			t.Errorf("test %d: result count mismatch: have %d, want %d", i, len(txs), len(tt.want))
+			continue
+		}
+		for j, tx := range txs {
+			block := blockchain.GetBlockByNumber(tx.BlockNumber)
+			if tx.BlockNumber != tt.want[j] {
+				t.Errorf("test %d, result %d: block mismatch: have %d, want %d", i, j, tx.BlockNumber, tt.want[j])
+			}
+			if tx.BlockHash != block.Hash() || tx.TxHash != block.Transactions()[tx.TxIndex].Hash() {
+				t.Errorf("test %d, result %d: position mismatch: have %x/%d", i, j, tx.BlockHash, tx.TxIndex)
+			}
+			if tx.From != forwarder || tx.To != recipient || tx.Value.Uint64() != tt.want[j] {
+				t.Errorf("test %d, result %d: transfer mismatch: have %x -> %x %v", i, j, tx.From, tx.To, tx.Value)
+			}
+		}
+	}
+	// Sections with blocks that can't be re-executed fail instead of being
+	// stored with gaps
+	if err := indexer.Reset(4, common.Hash{}); err != nil {
+		t.Fatalf("failed to reset indexer: %v", err)
+	}
+	indexer.Process(&types.Header{Number: big.NewInt(4)})
+	if err := indexer.Commit(); err == nil {
+		t.Fatalf("section with a missing block committed")
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/internal/jobs"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+const (
+	maxRunningJobs = 4         // Maximum number of background jobs running at once
+	jobRetention   = time.Hour // Time the results of finished jobs are kept for
+)
+
+// decodeJobParams decodes the positional parameters of a job into args.
+// Missing trailing parameters leave their args untouched.
+func decodeJobParams(params []json.RawMessage, args ...interface{}) error {
+	if len(params) > len(args) {
+		return fmt.Errorf("too many arguments, want at most %d", len(args))
+	}
+	for i, param := range params {
+		if err := json.Unmarshal(param, args[i]); err != nil {
+			return fmt.Errorf("invalid argument %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// jobFunc binds a debug or admin method to its parameters, to be run as a job.
+func (api *PrivateDebugAPI) jobFunc(method string, params []json.RawMessage) (jobs.Func, error) {
+	switch method {
+	case "debug_traceBlockByNumber":
+		var (
+			number rpc.BlockNumber
+			config *TraceConfig
+		)
+		if err := decodeJobParams(params, &number, &config); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return api.TraceBlockByNumber(ctx, number, config)
+		}, nil
+
+	case "debug_traceBlockByHash":
+		var (
+			hash   common.Hash
+			config *TraceConfig
+		)
+		if err := decodeJobParams(params, &hash, &config); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return api.TraceBlockByHash(ctx, hash, config)
+		}, nil
+
+	case "debug_traceTransaction":
+		var (
+			hash   common.Hash
+			config *TraceConfig
+		)
+		if err := decodeJobParams(params, &hash, &config); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return api.TraceTransaction(ctx, hash, config)
+		}, nil
+
+	case "debug_dumpBlock":
+		var number rpc.BlockNumber
+		if err := decodeJobParams(params, &number); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return NewPublicDebugAPI(api.eth).DumpBlock(ctx, number)
+		}, nil
+
+	case "debug_dbStats":
+		var sample *uint64
+		if err := decodeJobParams(params, &sample); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return api.DbStats(ctx, sample)
+		}, nil
+
+	case "admin_exportAnalytics":
+		var (
+			dir, format string
+			first, last rpc.BlockNumber
+		)
+		if err := decodeJobParams(params, &dir, &format, &first, &last); err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) (interface{}, error) {
+			return NewPrivateAdminAPI(api.eth).ExportAnalytics(ctx, dir, format, first, last)
+		}, nil
+	}
+	return nil, fmt.Errorf("method %s can't be run as a job", method)
+}
+
+// SubmitJob starts running an expensive debug or admin method in the
+// background, such as debug_traceBlockByNumber or admin_exportAnalytics,
+// returning the id of the job to poll its status and result with.
+func (api *PrivateDebugAPI) SubmitJob(method string, params []json.RawMessage) (string, error) {
+	fn, err := api.jobFunc(method, params)
+	if err != nil {
+		return "", err
+	}
+	return api.eth.jobs.Submit(method, fn)
+}
+
+// JobStatus reports the state and progress of a job.
+func (api *PrivateDebugAPI) JobStatus(id string) (*jobs.JobStatus, error) {
+	return api.eth.jobs.Status(id)
+}
+
+// JobResult returns the result of a finished job, or the error it failed with.
+func (api *PrivateDebugAPI) JobResult(id string) (interface{}, error) {
+	return api.eth.jobs.Result(id)
+}
+
+// CancelJob aborts a running job, or forgets the result of a finished one.
+func (api *PrivateDebugAPI) CancelJob(id string) (bool, error) {
+	if err := api.eth.jobs.Cancel(id); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Jobs reports the state of the running jobs and of the jobs whose results
+// are still kept.
+func (api *PrivateDebugAPI) Jobs() []*jobs.JobStatus {
+	return api.eth.jobs.Jobs()
+}
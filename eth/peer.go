@@ -28,6 +28,7 @@ import (
 	"go.opencensus.io/trace"
 
 	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
 	"github.com/fulcrumchain/indigo/core/types"
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/p2p"
@@ -162,6 +163,12 @@ type peer struct {
 	td   *big.Int
 	lock sync.RWMutex
 
+	network uint64      // Network advertised in the handshake
+	genesis common.Hash // Genesis advertised in the handshake
+	forkID  *forkID     // Fork identifier advertised in the handshake, nil before eth/64
+
+	stateDiagServed time.Time // Time the last state diagnostics request of the peer was served
+
 	knownTxs    knownHashes // Set of transaction hashes known to be known by this peer
 	knownBlocks knownHashes // Set of block hashes known to be known by this peer
 
@@ -399,6 +406,11 @@ func (p *peer) SendReceiptsRLP(ctx context.Context, receipts []rlp.RawValue) err
 	return p2p.SendCtx(ctx, p.rw, ReceiptsMsg, receipts)
 }
 
+// SendStateDiag sends the state diagnostics of a block, nil if not available.
+func (p *peer) SendStateDiag(ctx context.Context, hash common.Hash, diag *core.StateDiag) error {
+	return p2p.SendCtx(ctx, p.rw, StateDiagMsg, &stateDiagData{Hash: hash, Diag: diag})
+}
+
 // RequestOneHeader is a wrapper around the header query functions to fetch a
 // single header. It is used solely by the fetcher.
 func (p *peer) RequestOneHeader(ctx context.Context, hash common.Hash) error {
@@ -443,9 +455,17 @@ func (p *peer) RequestReceipts(ctx context.Context, hashes []common.Hash) error
 	return p2p.SendCtx(ctx, p.rw, GetReceiptsMsg, hashes)
 }
 
+// RequestStateDiag fetches the per transaction state roots and writes of a block
+// from a remote node.
+func (p *peer) RequestStateDiag(ctx context.Context, hash common.Hash) error {
+	p.Log().Debug("Fetching state diagnostics", "hash", hash)
+	return p2p.SendCtx(ctx, p.rw, GetStateDiagMsg, hash)
+}
+
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks. Since eth/64 the fork
+// identifiers are exchanged too, without being required to be compatible.
+func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, fork forkID) error {
 	ctx, span := trace.StartSpan(context.Background(), "peer.Handshake-send-StatusMsg")
 	defer span.End()
 
@@ -454,6 +474,17 @@ func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 	var status statusData // safe to read after two values have been received from errc
 
 	go func() {
+		if p.version >= eth64 {
+			errc <- p2p.SendCtx(ctx, p.rw, StatusMsg, &statusData64{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				TD:              td,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+				ForkID:          fork,
+			})
+			return
+		}
 		errc <- p2p.SendCtx(ctx, p.rw, StatusMsg, &statusData{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
@@ -497,9 +528,17 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
+	if p.version >= eth64 {
+		var status64 statusData64
+		if err := msg.Decode(&status64); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		*status = statusData{status64.ProtocolVersion, status64.NetworkId, status64.TD, status64.CurrentBlock, status64.GenesisBlock}
+		p.forkID = &status64.ForkID
+	} else if err := msg.Decode(&status); err != nil {
 		return errResp(ErrDecode, "msg %v: %v", msg, err)
 	}
+	p.network, p.genesis = status.NetworkId, status.GenesisBlock
 	if status.GenesisBlock != genesis {
 		return errResp(ErrGenesisBlockMismatch, "%x (!= %x)", status.GenesisBlock[:8], genesis[:8])
 	}
@@ -0,0 +1,317 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/elastic/gosigar"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+const (
+	peerScaleInterval = 30 * time.Second // Interval between peer target adjustments
+	peerScaleHighLoad = 0.8              // Load above which the peer target is lowered
+	peerScaleLowLoad  = 0.5              // Load below which the peer target is raised
+	peerScaleSteps    = 4                // Number of adjustments to cross the whole range
+
+	defaultPeerIOBudget = 64 * 1024 * 1024 // Disk throughput considered full IO load
+)
+
+// Node roles the peer target is bounded by.
+const (
+	PeerRoleSealer   = "sealer"   // Block producer, few well connected peers keep block propagation fast
+	PeerRoleObserver = "observer" // Regular node, serving as many peers as allowed
+	PeerRoleArchive  = "archive"  // Archive node, leaving headroom for historical queries
+)
+
+// peerRoleShares are the fractions of the maximum peer count each role uses.
+var peerRoleShares = map[string]float64{
+	PeerRoleSealer:   0.5,
+	PeerRoleObserver: 1.0,
+	PeerRoleArchive:  0.75,
+}
+
+var peerTargetGauge = metrics.NewGauge("eth/peers/target")
+
+// PeerScalingConfig contains the settings of the adaptive peer target.
+type PeerScalingConfig struct {
+	Adaptive bool   `toml:",omitempty"` // Whether to scale the peer target with the load
+	Role     string `toml:",omitempty"` // Role of the node, derived from the mining and pruning modes if empty
+	MinPeers int    `toml:",omitempty"` // Lower bound of the peer target
+	IOBudget uint64 `toml:",omitempty"` // Disk throughput in bytes per second considered full IO load
+}
+
+// PeerScaling reports the state of the adaptive peer target.
+type PeerScaling struct {
+	Role     string  `json:"role"`
+	Adaptive bool    `json:"adaptive"`
+	MinPeers int     `json:"minPeers"`
+	MaxPeers int     `json:"maxPeers"`
+	Target   int     `json:"target"`
+	CPULoad  float64 `json:"cpuLoad"`
+	IOLoad   float64 `json:"ioLoad"`
+}
+
+// PeerScalingArgs are the changes to the adaptive peer target requested over
+// the admin API. Missing fields are left unchanged, an empty role restores the
+// derived one.
+type PeerScalingArgs struct {
+	Role     *string `json:"role"`
+	Adaptive *bool   `json:"adaptive"`
+	MinPeers *int    `json:"minPeers"`
+	MaxPeers *int    `json:"maxPeers"`
+}
+
+// peerScaler adjusts the number of eth peers accepted to the role of the node
+// and the load of the machine, within the configured bounds.
+type peerScaler struct {
+	config   PeerScalingConfig
+	maxPeers int                       // Upper bound of the peer target
+	target   int                       // Current peer target
+	cpuLoad  float64                   // Last measured CPU load
+	ioLoad   float64                   // Last measured IO load
+	role     func() string             // Role derived from the node configuration
+	load     func() (float64, float64) // Measures the CPU and IO load
+	shed     func(n int)               // Disconnects peers above the target
+
+	quit chan struct{}
+	lock sync.RWMutex
+}
+
+func newPeerScaler(config PeerScalingConfig, maxPeers int, role func() string, shed func(n int)) *peerScaler {
+	s := &peerScaler{
+		config:   config,
+		maxPeers: maxPeers,
+		role:     role,
+		shed:     shed,
+		quit:     make(chan struct{}),
+	}
+	s.load = newLoadMeter(config.IOBudget).measure
+	s.target = s.bounds().max
+	peerTargetGauge.Update(int64(s.target))
+	return s
+}
+
+// peerBounds are the limits of the peer target of a role.
+type peerBounds struct {
+	role     string
+	min, max int
+}
+
+// bounds returns the limits of the peer target. It must be called with the
+// lock held.
+func (s *peerScaler) bounds() peerBounds {
+	role := s.config.Role
+	if role == "" && s.role != nil {
+		role = s.role()
+	}
+	share, ok := peerRoleShares[role]
+	if !ok {
+		role, share = PeerRoleObserver, 1.0
+	}
+	max := int(math.Ceil(float64(s.maxPeers) * share))
+	min := s.config.MinPeers
+	if min > max {
+		min = max
+	}
+	if min < 0 {
+		min = 0
+	}
+	return peerBounds{role: role, min: min, max: max}
+}
+
+// Target returns the number of eth peers currently accepted.
+func (s *peerScaler) Target() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.target
+}
+
+// adjust measures the load and moves the peer target one step towards the
+// lower bound under high load and towards the upper bound under low load.
+// Without adaptive scaling the target is pinned to the upper bound.
+func (s *peerScaler) adjust() {
+	s.lock.Lock()
+	bounds := s.bounds()
+	target := bounds.max
+	if s.config.Adaptive {
+		s.cpuLoad, s.ioLoad = s.load()
+		load := math.Max(s.cpuLoad, s.ioLoad)
+
+		step := (bounds.max - bounds.min + peerScaleSteps - 1) / peerScaleSteps
+		if step < 1 {
+			step = 1
+		}
+		target = s.target
+		switch {
+		case load > peerScaleHighLoad:
+			target -= step
+		case load < peerScaleLowLoad:
+			target += step
+		}
+		if target < bounds.min {
+			target = bounds.min
+		}
+		if target > bounds.max {
+			target = bounds.max
+		}
+	}
+	old := s.target
+	s.target = target
+	s.lock.Unlock()
+
+	metrics.SetNodeLabel(metrics.LabelRole, bounds.role)
+
+	if target != old {
+		log.Debug("Adjusted eth peer target", "role", bounds.role, "old", old, "new", target)
+		peerTargetGauge.Update(int64(target))
+	}
+	if target < old && s.shed != nil {
+		s.shed(target)
+	}
+}
+
+// status reports the state of the peer target.
+func (s *peerScaler) status() PeerScaling {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	bounds := s.bounds()
+	return PeerScaling{
+		Role:     bounds.role,
+		Adaptive: s.config.Adaptive,
+		MinPeers: bounds.min,
+		MaxPeers: bounds.max,
+		Target:   s.target,
+		CPULoad:  s.cpuLoad,
+		IOLoad:   s.ioLoad,
+	}
+}
+
+// update applies the changes requested over the admin API.
+func (s *peerScaler) update(args PeerScalingArgs) error {
+	if args.Role != nil && *args.Role != "" {
+		if _, ok := peerRoleShares[*args.Role]; !ok {
+			return fmt.Errorf("unknown peer role %q", *args.Role)
+		}
+	}
+	if args.MinPeers != nil && *args.MinPeers < 0 {
+		return fmt.Errorf("negative minimum peer count %d", *args.MinPeers)
+	}
+	if args.MaxPeers != nil && *args.MaxPeers < 0 {
+		return fmt.Errorf("negative maximum peer count %d", *args.MaxPeers)
+	}
+	s.lock.Lock()
+	if args.Role != nil {
+		s.config.Role = *args.Role
+	}
+	if args.MinPeers != nil {
+		s.config.MinPeers = *args.MinPeers
+	}
+	if args.MaxPeers != nil {
+		s.maxPeers = *args.MaxPeers
+	}
+	if args.Adaptive != nil {
+		s.config.Adaptive = *args.Adaptive
+	}
+	// Keep the target within the new bounds right away
+	bounds := s.bounds()
+	if !s.config.Adaptive || s.target > bounds.max {
+		s.target = bounds.max
+	}
+	if s.target < bounds.min {
+		s.target = bounds.min
+	}
+	target := s.target
+	s.lock.Unlock()
+
+	log.Info("Changed eth peer scaling", "role", bounds.role, "min", bounds.min, "max", bounds.max, "target", target)
+	peerTargetGauge.Update(int64(target))
+	if s.shed != nil {
+		s.shed(target)
+	}
+	return nil
+}
+
+// loop periodically adjusts the peer target until stopped.
+func (s *peerScaler) loop() {
+	ticker := time.NewTicker(peerScaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.adjust()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *peerScaler) stop() {
+	close(s.quit)
+}
+
+// loadMeter measures the system CPU load and the disk throughput of the
+// process between consecutive calls.
+type loadMeter struct {
+	budget uint64
+	cpu    gosigar.Cpu
+	disk   metrics.DiskStats
+	time   time.Time
+}
+
+func newLoadMeter(budget uint64) *loadMeter {
+	if budget == 0 {
+		budget = defaultPeerIOBudget
+	}
+	m := &loadMeter{budget: budget, time: time.Now()}
+	m.cpu.Get()
+	metrics.ReadDiskStats(&m.disk)
+	return m
+}
+
+// measure returns the CPU load as the busy share of the CPU time, and the IO
+// load as the share of the disk budget used since the last measurement.
+func (m *loadMeter) measure() (cpuLoad float64, ioLoad float64) {
+	var cpu gosigar.Cpu
+	if err := cpu.Get(); err == nil {
+		busy := float64(cpu.User + cpu.Nice + cpu.Sys + cpu.Irq + cpu.SoftIrq + cpu.Stolen)
+		busy -= float64(m.cpu.User + m.cpu.Nice + m.cpu.Sys + m.cpu.Irq + m.cpu.SoftIrq + m.cpu.Stolen)
+		if total := float64(cpu.Total() - m.cpu.Total()); total > 0 {
+			cpuLoad = busy / total
+		}
+		m.cpu = cpu
+	}
+	now := time.Now()
+	var disk metrics.DiskStats
+	if err := metrics.ReadDiskStats(&disk); err == nil {
+		bytes := float64(disk.ReadBytes + disk.WriteBytes - m.disk.ReadBytes - m.disk.WriteBytes)
+		if elapsed := now.Sub(m.time).Seconds(); elapsed > 0 {
+			ioLoad = bytes / elapsed / float64(m.budget)
+		}
+		m.disk = disk
+	}
+	m.time = now
+	return cpuLoad, ioLoad
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import "testing"
+
+func TestPeerScalerRoles(t *testing.T) {
+	role := PeerRoleObserver
+	s := newPeerScaler(PeerScalingConfig{}, 50, func() string { return role }, nil)
+
+	tests := []struct {
+		role, config string
+		target       int
+	}{
+		{PeerRoleObserver, "", 50},
+		{PeerRoleSealer, "", 25},
+		{PeerRoleArchive, "", 38},
+		{PeerRoleSealer, PeerRoleObserver, 50},
+		{"unknown", "", 50},
+	}
+	for i, tt := range tests {
+		role, s.config.Role = tt.role, tt.config
+		s.adjust()
+		if target := s.Target(); target != tt.target {
+			t.Errorf("test %d: target mismatch: have %d, want %d", i, target, tt.target)
+		}
+	}
+}
+
+func TestPeerScalerLoad(t *testing.T) {
+	var (
+		load float64
+		shed []int
+	)
+	s := newPeerScaler(PeerScalingConfig{Adaptive: true, MinPeers: 10}, 50, nil, func(n int) { shed = append(shed, n) })
+	s.load = func() (float64, float64) { return load, load / 2 }
+
+	// High load steps the target down to the lower bound, shedding peers each time
+	load = 0.9
+	for _, want := range []int{40, 30, 20, 10, 10} {
+		s.adjust()
+		if target := s.Target(); target != want {
+			t.Fatalf("high load target mismatch: have %d, want %d", target, want)
+		}
+	}
+	if len(shed) != 4 || shed[3] != 10 {
+		t.Errorf("shedding mismatch: have %v, want 4 rounds down to 10", shed)
+	}
+	// Moderate load keeps the target, low load raises it back
+	load = 0.6
+	s.adjust()
+	if target := s.Target(); target != 10 {
+		t.Errorf("moderate load target mismatch: have %d, want 10", target)
+	}
+	load = 0.1
+	for _, want := range []int{20, 30, 40, 50, 50} {
+		s.adjust()
+		if target := s.Target(); target != want {
+			t.Fatalf("low load target mismatch: have %d, want %d", target, want)
+		}
+	}
+	if status := s.status(); status.CPULoad != 0.1 || status.IOLoad != 0.05 {
+		t.Errorf("load not reported: %+v", status)
+	}
+}
+
+func TestPeerScalerUpdate(t *testing.T) {
+	s := newPeerScaler(PeerScalingConfig{Adaptive: true}, 50, nil, nil)
+	s.load = func() (float64, float64) { return 0.9, 0 }
+	s.adjust()
+
+	role, max := PeerRoleSealer, 30
+	if err := s.update(PeerScalingArgs{Role: &role, MaxPeers: &max}); err != nil {
+		t.Fatalf("failed to update scaling: %v", err)
+	}
+	if status := s.status(); status.Role != PeerRoleSealer || status.MaxPeers != 15 || status.Target != 15 {
+		t.Errorf("status mismatch after update: %+v", status)
+	}
+	// Invalid updates are rejected without applying any change
+	bad, min := "miner", -1
+	if err := s.update(PeerScalingArgs{Role: &bad}); err == nil {
+		t.Errorf("unknown role accepted")
+	}
+	if err := s.update(PeerScalingArgs{Role: &role, MinPeers: &min}); err == nil {
+		t.Errorf("negative minimum accepted")
+	}
+	// Disabling scaling pins the target to the upper bound
+	adaptive := false
+	if err := s.update(PeerScalingArgs{Adaptive: &adaptive}); err != nil {
+		t.Fatalf("failed to update scaling: %v", err)
+	}
+	if status := s.status(); status.Adaptive || status.Target != 15 {
+		t.Errorf("status mismatch after disabling: %+v", status)
+	}
+}
@@ -35,16 +35,18 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
+	eth65 = 65
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "eth"
 
 // Supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth65, eth64, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{19, 17, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -65,8 +67,25 @@ const (
 	NodeDataMsg    = p2p.NodeDataMsg
 	GetReceiptsMsg = p2p.GetReceiptsMsg
 	ReceiptsMsg    = p2p.ReceiptsMsg
+
+	// Protocol messages belonging to eth/65
+	GetStateDiagMsg = p2p.GetStateDiagMsg
+	StateDiagMsg    = p2p.StateDiagMsg
 )
 
+// messageLane classifies the outbound messages of the protocol, so that block
+// propagation isn't held up by serving sync data or gossiping transactions.
+func messageLane(code uint64) p2p.Lane {
+	switch code {
+	case NewBlockMsg, NewBlockHashesMsg:
+		return p2p.LaneConsensus
+	case TxMsg, BlockHeadersMsg, BlockBodiesMsg, NodeDataMsg, ReceiptsMsg, StateDiagMsg:
+		return p2p.LaneBulk
+	default:
+		return p2p.LaneDefault
+	}
+}
+
 type errCode int
 
 const (
@@ -109,6 +128,9 @@ type txPool interface {
 	// PendingList is like Pending, but only txs.
 	PendingList(ctx context.Context) types.Transactions
 
+	// Withheld should report whether a transaction must not be propagated.
+	Withheld(hash common.Hash) bool
+
 	// SubscribeNewTxsEvent should return an event subscription of
 	// NewTxsEvent and send events to the given channel.
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
@@ -123,6 +145,18 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// statusData64 is the network packet for the status message since eth/64, also
+// advertising the fork identifier so that peers scheduling different forks can
+// be told apart before the fork block.
+type statusData64 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+	ForkID          forkID
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
@@ -187,3 +221,10 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// stateDiagData is the network packet answering a state diagnostics request,
+// without diagnostics if the block or its parent state is not available.
+type stateDiagData struct {
+	Hash common.Hash
+	Diag *core.StateDiag `rlp:"nil"`
+}
@@ -94,6 +94,68 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that eth/64 peers exchange their fork identifiers, and that peers of
+// incompatible forks are reported to the watchdog, while those refused for
+// their genesis are only if configured locally.
+func TestStatusMsgWatchdog64(t *testing.T) {
+	ctx := context.Background()
+	pm, _ := newTestProtocolManagerMust(ctx, t, downloader.FullSync, 0, nil, nil)
+	var (
+		genesis = pm.blockchain.Genesis()
+		head    = pm.blockchain.CurrentHeader()
+		td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+	)
+	defer pm.Stop()
+
+	pm.watchdog = newChainWatchdog(WatchdogConfig{Threshold: 0.5}, genesis.Hash(), pm.localForkID, nil)
+	defer pm.watchdog.stop()
+
+	tests := []struct {
+		genesis   common.Hash
+		fork      forkID
+		connected bool
+		reported  bool
+	}{
+		{genesis.Hash(), forkID{Hash: [4]byte{0xff}}, true, true},
+		{common.Hash{3}, pm.localForkID(), false, false},
+	}
+	for i, test := range tests {
+		p, errc := newTestPeer(ctx, fmt.Sprintf("peer %d", i), eth64, pm, false)
+		local := &statusData64{uint32(eth64), DefaultConfig.NetworkId, td, head.Hash(), genesis.Hash(), pm.localForkID()}
+		if err := p2p.ExpectMsg(p.app, StatusMsg, local); err != nil {
+			t.Fatalf("test %d: status recv: %v", i, err)
+		}
+		remote := &statusData64{uint32(eth64), DefaultConfig.NetworkId, td, head.Hash(), test.genesis, test.fork}
+		if err := p2p.Send(p.app, StatusMsg, remote); err != nil {
+			t.Fatalf("test %d: status send: %v", i, err)
+		}
+		if test.connected {
+			for start := time.Now(); pm.peers.Len() == 0 && time.Since(start) < 2*time.Second; {
+				time.Sleep(10 * time.Millisecond)
+			}
+		} else {
+			select {
+			case <-errc:
+			case <-time.After(2 * time.Second):
+				t.Fatalf("test %d: protocol did not shut down within 2 seconds", i)
+			}
+		}
+		var found bool
+		for _, peer := range pm.watchdog.Status().Diverging {
+			if peer.ID == p.id {
+				found = true
+				if peer.Genesis != test.genesis || peer.Connected != test.connected {
+					t.Errorf("test %d: diverging peer mismatch: %+v", i, peer)
+				}
+			}
+		}
+		if found != test.reported {
+			t.Errorf("test %d: watchdog report mismatch: have %v, want %v", i, found, test.reported)
+		}
+		defer p.close()
+	}
+}
+
 // This test checks that received transactions are added to the local pool.
 func TestRecvTransactions62(t *testing.T) { testRecvTransactions(t, 62) }
 func TestRecvTransactions63(t *testing.T) { testRecvTransactions(t, 63) }
@@ -182,6 +244,39 @@ func testSendTransactions(t *testing.T, protocol int) {
 	wg.Wait()
 }
 
+// Tests that privately submitted transactions are never sent to peers.
+func TestSendTransactionsPrivate(t *testing.T) {
+	ctx := context.Background()
+	pm, _ := newTestProtocolManagerMust(ctx, t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	var (
+		public  = newTestTransaction(testAccount, 0, 0)
+		private = newTestTransaction(testAccount, 1, 0)
+		pool    = pm.txpool.(*testTxPool)
+	)
+	pool.private = map[common.Hash]bool{private.Hash(): true}
+	pool.AddRemotes(ctx, []*types.Transaction{public, private})
+
+	p, _ := newTestPeer(ctx, "peer", 63, pm, true)
+	defer p.close()
+
+	msg, err := p.app.ReadMsg()
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	var txs []*types.Transaction
+	if err := msg.Decode(&txs); err != nil {
+		t.Fatalf("failed to decode transactions: %v", err)
+	}
+	if len(txs) != 1 || txs[0].Hash() != public.Hash() {
+		t.Errorf("transactions mismatch: have %d, want only the public one", len(txs))
+	}
+	if txs := pm.publicTxs(types.Transactions{private, public}); len(txs) != 1 || txs[0] != public {
+		t.Errorf("broadcast filter mismatch: have %d transactions, want only the public one", len(txs))
+	}
+}
+
 // Tests that the custom union field encoder and decoder works correctly.
 func TestGetBlockHeadersDataEncodeDecode(t *testing.T) {
 	// Create a "random" hash for testing
@@ -0,0 +1,240 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/p2p/discover"
+)
+
+const (
+	scoreTimeout = -20 // Penalty for timed out or stalled requests
+	scoreInvalid = -50 // Penalty for invalid messages, headers or blocks
+	scoreUseless = -5  // Penalty for unrequested or otherwise useless data
+	scoreUseful  = 1   // Reward for useful deliveries
+
+	scoreMax      = 100              // Highest score good behaviour can accumulate
+	scoreBan      = -100             // Score at which peers are disconnected and banned
+	scoreHalfLife = 30 * time.Minute // Time for scores to decay half way back to zero
+
+	defaultBanDuration = time.Hour   // Duration of automatic bans
+	reputationExpiry   = time.Hour   // Time after which neutral entries of unseen peers are dropped
+	reputationPrune    = time.Minute // Minimum time between sweeps for stale entries
+)
+
+var (
+	errInvalidPeerID = errors.New("invalid peer id")
+
+	peerBanMeter = metrics.NewMeter("eth/peers/bans")
+)
+
+// PeerScore reports the reputation of a peer.
+type PeerScore struct {
+	Score       float64    `json:"score"`
+	Timeouts    uint64     `json:"timeouts"`
+	Invalid     uint64     `json:"invalid"`
+	Useless     uint64     `json:"useless"`
+	Useful      uint64     `json:"useful"`
+	Updated     time.Time  `json:"updated"`
+	BannedUntil *time.Time `json:"bannedUntil,omitempty"`
+	Manual      bool       `json:"manual,omitempty"` // Whether the ban was set by an operator
+}
+
+// reputation scores peers on their behaviour, disconnecting and temporarily
+// banning the ones whose score drops too low. Peers are identified by their
+// short id, which is stable across reconnections.
+type reputation struct {
+	peers      map[string]*PeerScore
+	disconnect func(id string) // Callback to drop a connected peer
+	pruned     time.Time       // Time of the last sweep for stale entries
+	now        func() time.Time
+	lock       sync.Mutex
+}
+
+func newReputation(disconnect func(id string)) *reputation {
+	return &reputation{
+		peers:      make(map[string]*PeerScore),
+		disconnect: disconnect,
+		now:        time.Now,
+	}
+}
+
+// entry returns the score of a peer, decayed to the current time, creating it
+// if it doesn't exist yet.
+func (r *reputation) entry(id string) *PeerScore {
+	now := r.now()
+	score, ok := r.peers[id]
+	if !ok {
+		score = &PeerScore{Updated: now}
+		r.peers[id] = score
+	}
+	if elapsed := now.Sub(score.Updated); elapsed > 0 {
+		score.Score *= math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+	}
+	score.Updated = now
+	return score
+}
+
+// adjust changes the score of a peer and counts the event, banning the peer
+// if its score drops too low.
+func (r *reputation) adjust(id string, delta float64, count func(*PeerScore)) {
+	r.lock.Lock()
+	if now := r.now(); now.Sub(r.pruned) >= reputationPrune {
+		r.prune(now)
+	}
+	score := r.entry(id)
+	count(score)
+	score.Score = math.Min(score.Score+delta, scoreMax)
+
+	ban := score.Score <= scoreBan && score.BannedUntil == nil
+	if ban {
+		until := r.now().Add(defaultBanDuration)
+		score.BannedUntil = &until
+		log.Info("Banning misbehaving peer", "peer", id, "score", int(score.Score), "until", until)
+		peerBanMeter.Mark(1)
+	}
+	r.lock.Unlock()
+
+	if ban && r.disconnect != nil {
+		r.disconnect(id)
+	}
+}
+
+// timeout penalizes a peer for a timed out or stalled request.
+func (r *reputation) timeout(id string) {
+	r.adjust(id, scoreTimeout, func(s *PeerScore) { s.Timeouts++ })
+}
+
+// invalid penalizes a peer for an invalid message or block.
+func (r *reputation) invalid(id string) {
+	r.adjust(id, scoreInvalid, func(s *PeerScore) { s.Invalid++ })
+}
+
+// useless penalizes a peer for data that was not requested or not useful.
+func (r *reputation) useless(id string) {
+	r.adjust(id, scoreUseless, func(s *PeerScore) { s.Useless++ })
+}
+
+// useful rewards a peer for a useful delivery.
+func (r *reputation) useful(id string) {
+	r.adjust(id, scoreUseful, func(s *PeerScore) { s.Useful++ })
+}
+
+// banned reports whether a peer is currently banned, lifting expired bans.
+func (r *reputation) banned(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	score, ok := r.peers[id]
+	if !ok || score.BannedUntil == nil {
+		return false
+	}
+	if r.now().Before(*score.BannedUntil) {
+		return true
+	}
+	// Ban expired, give the peer a fresh start
+	score.Score, score.BannedUntil, score.Manual = 0, nil, false
+	return false
+}
+
+// ban bans a peer for the given duration, disconnecting it if connected.
+func (r *reputation) ban(id string, duration time.Duration) {
+	r.lock.Lock()
+	score := r.entry(id)
+	until := r.now().Add(duration)
+	score.BannedUntil, score.Manual = &until, true
+	r.lock.Unlock()
+
+	log.Info("Banning peer", "peer", id, "until", until)
+	if r.disconnect != nil {
+		r.disconnect(id)
+	}
+}
+
+// unban lifts the ban of a peer and resets its score.
+func (r *reputation) unban(id string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	score, ok := r.peers[id]
+	if !ok {
+		return false
+	}
+	wasBanned := score.BannedUntil != nil
+	score.Score, score.BannedUntil, score.Manual = 0, nil, false
+	return wasBanned
+}
+
+// prune lifts expired bans and drops the neutral entries of peers which haven't
+// been seen for a while, keeping the map bounded by the recently active peers.
+func (r *reputation) prune(now time.Time) {
+	r.pruned = now
+	for id, score := range r.peers {
+		if score.BannedUntil != nil {
+			if now.Before(*score.BannedUntil) {
+				continue
+			}
+			score.Score, score.BannedUntil, score.Manual = 0, nil, false
+		}
+		idle := now.Sub(score.Updated)
+		if idle <= reputationExpiry {
+			continue
+		}
+		if decayed := score.Score * math.Pow(0.5, float64(idle)/float64(scoreHalfLife)); math.Abs(decayed) < 1 {
+			delete(r.peers, id)
+		}
+	}
+}
+
+// scores returns a copy of the tracked peer scores, dropping neutral entries
+// which haven't been updated for a while.
+func (r *reputation) scores() map[string]PeerScore {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.prune(r.now())
+	scores := make(map[string]PeerScore, len(r.peers))
+	for id := range r.peers {
+		scores[id] = *r.entry(id)
+	}
+	return scores
+}
+
+// shortPeerID converts a node id, enode URL or short peer id into the short
+// id peers are tracked by.
+func shortPeerID(id string) (string, error) {
+	if strings.HasPrefix(id, "enode://") {
+		node, err := discover.ParseNode(id)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(node.ID[:8]), nil
+	}
+	blob, err := hex.DecodeString(strings.TrimPrefix(id, "0x"))
+	if err != nil || len(blob) < 8 {
+		return "", errInvalidPeerID
+	}
+	return hex.EncodeToString(blob[:8]), nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestReputation creates a reputation tracker with a manual clock, recording
+// the disconnected peers.
+func newTestReputation() (*reputation, *time.Time, *[]string) {
+	var (
+		now     = time.Unix(1500000000, 0)
+		dropped []string
+	)
+	r := newReputation(func(id string) { dropped = append(dropped, id) })
+	r.now = func() time.Time { return now }
+	return r, &now, &dropped
+}
+
+func TestReputationBan(t *testing.T) {
+	r, now, dropped := newTestReputation()
+
+	r.useful("a")
+	r.timeout("a")
+	r.useless("a")
+	if score := r.scores()["a"]; score.Score != -24 || score.Useful != 1 || score.Timeouts != 1 || score.Useless != 1 {
+		t.Errorf("score mismatch: %+v", score)
+	}
+	// Invalid messages quickly get the peer banned
+	r.invalid("a")
+	if len(*dropped) != 0 || r.banned("a") {
+		t.Fatalf("peer banned too early")
+	}
+	r.invalid("a")
+	if len(*dropped) != 1 || !r.banned("a") {
+		t.Fatalf("misbehaving peer not banned: dropped %v", *dropped)
+	}
+	// Bans expire, restoring a clean reputation
+	*now = now.Add(defaultBanDuration)
+	if r.banned("a") {
+		t.Errorf("ban not expired")
+	}
+	if score := r.scores()["a"]; score.Score != 0 || score.BannedUntil != nil {
+		t.Errorf("score not reset: %+v", score)
+	}
+}
+
+func TestReputationDecay(t *testing.T) {
+	r, now, _ := newTestReputation()
+
+	r.invalid("a")
+	*now = now.Add(scoreHalfLife)
+	if score := r.scores()["a"]; score.Score != scoreInvalid/2 {
+		t.Errorf("decayed score mismatch: have %v, want %v", score.Score, scoreInvalid/2)
+	}
+	// Neutral entries of peers not seen for a while are dropped
+	*now = now.Add(20 * scoreHalfLife)
+	if _, ok := r.scores()["a"]; ok {
+		t.Errorf("stale entry not dropped")
+	}
+}
+
+func TestReputationPrune(t *testing.T) {
+	r, now, _ := newTestReputation()
+
+	r.useless("a")
+	r.invalid("b")
+	r.invalid("b")
+
+	// Scoring other peers sweeps the stale neutral entries, but keeps bans
+	*now = now.Add(20 * scoreHalfLife)
+	r.useful("c")
+	if _, ok := r.peers["a"]; ok {
+		t.Errorf("stale entry not pruned")
+	}
+	if _, ok := r.peers["b"]; ok {
+		t.Errorf("stale entry with expired ban not pruned")
+	}
+	r.ban("d", 24*time.Hour)
+	*now = now.Add(20 * scoreHalfLife)
+	r.useful("c")
+	if _, ok := r.peers["d"]; !ok {
+		t.Errorf("banned entry pruned")
+	}
+}
+
+func TestReputationManualBan(t *testing.T) {
+	r, now, dropped := newTestReputation()
+
+	r.ban("a", time.Minute)
+	if len(*dropped) != 1 || !r.banned("a") || !r.scores()["a"].Manual {
+		t.Fatalf("manual ban not applied: dropped %v", *dropped)
+	}
+	if !r.unban("a") || r.banned("a") {
+		t.Fatalf("manual ban not lifted")
+	}
+	if r.unban("b") {
+		t.Errorf("unknown peer reported banned")
+	}
+	r.ban("a", time.Minute)
+	*now = now.Add(time.Minute)
+	if r.banned("a") {
+		t.Errorf("manual ban not expired")
+	}
+}
+
+func TestShortPeerID(t *testing.T) {
+	const (
+		id    = "a979fb575495b8d6db44f750317d0f4622bf4c2aa3365d6af7c284339968eef29b69ad0dce72a4d8db5ebb4968de0e3bec910127f134779fbcb0cb6d3331163c"
+		short = "a979fb575495b8d6"
+	)
+	for _, input := range []string{id, "0x" + id, short, "enode://" + id + "@127.0.0.1:30303"} {
+		if have, err := shortPeerID(input); err != nil || have != short {
+			t.Errorf("%s: short id mismatch: have %s (%v), want %s", input, have, err, short)
+		}
+	}
+	for _, input := range []string{"", "a979", "zz79fb575495b8d6"} {
+		if _, err := shortPeerID(input); err == nil {
+			t.Errorf("%q: invalid id accepted", input)
+		}
+	}
+}
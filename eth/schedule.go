@@ -0,0 +1,363 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+// scheduleCheckInterval is how often the transactions scheduled for a time are
+// checked for being due, in addition to every new chain head.
+const scheduleCheckInterval = time.Second
+
+var (
+	schedulePrefix = "schedule-" // Database table of the scheduled transactions, keyed by hash
+
+	errScheduleDisabled = errors.New("transaction scheduling not enabled")
+	errScheduleMissing  = errors.New("broadcast block or time required")
+	errScheduleKnown    = errors.New("transaction already scheduled")
+)
+
+// ScheduledTx is a signed transaction held until its broadcast block and time.
+type ScheduledTx struct {
+	Hash  common.Hash     `json:"hash"`
+	From  common.Address  `json:"from"`
+	Nonce hexutil.Uint64  `json:"nonce"`
+	Block *hexutil.Uint64 `json:"block"` // Chain head number from which it is broadcast, nil if any
+	Time  *hexutil.Uint64 `json:"time"`  // Unix time from which it is broadcast, nil if any
+	Error string          `json:"error,omitempty"`
+	Raw   hexutil.Bytes   `json:"raw"`
+}
+
+// storedScheduledTx is the database representation of a scheduled transaction.
+// Zero block and time numbers stand for no condition, and a non-empty error
+// marks a transaction the pool refused when it was due.
+type storedScheduledTx struct {
+	Raw   []byte
+	From  common.Address
+	Block uint64
+	Time  uint64
+	Error string
+}
+
+// due reports whether the transaction is to be broadcast at the given chain head
+// and time.
+func (s *storedScheduledTx) due(number uint64, now time.Time) bool {
+	return s.Error == "" && number >= s.Block && uint64(now.Unix()) >= s.Time
+}
+
+// scheduleChain is the part of the blockchain the scheduler follows.
+type scheduleChain interface {
+	CurrentBlock() *types.Block
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// scheduler holds signed transactions until the chain head reaches their
+// broadcast block and their broadcast time has passed, then adds them to the
+// transaction pool. Pending transactions survive restarts. Those the pool
+// refuses are kept with the error until cancelled.
+type scheduler struct {
+	chain scheduleChain
+	send  func(*types.Transaction) error // Adds a due transaction to the pool
+	db    ethdb.Database                 // Table to store the transactions in
+
+	txs  map[common.Hash]*storedScheduledTx
+	lock sync.Mutex // Protects txs and the database table
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newScheduler creates a scheduler, reloading the transactions held before the
+// last shutdown.
+func newScheduler(chain scheduleChain, chainDb ethdb.Database, send func(*types.Transaction) error) *scheduler {
+	s := &scheduler{
+		chain: chain,
+		send:  send,
+		db:    ethdb.NewTable(chainDb, schedulePrefix),
+		txs:   make(map[common.Hash]*storedScheduledTx),
+		quit:  make(chan struct{}),
+	}
+	it := s.db.NewRangeIterator(nil, nil)
+	for it.Next() {
+		stored := new(storedScheduledTx)
+		if err := rlp.DecodeBytes(it.Value(), stored); err != nil {
+			log.Error("Invalid scheduled transaction RLP", "hash", common.BytesToHash(it.Key()), "err", err)
+			continue
+		}
+		s.txs[common.BytesToHash(it.Key())] = stored
+	}
+	if err := it.Error(); err != nil {
+		log.Error("Failed to load scheduled transactions", "err", err)
+	}
+	it.Release()
+
+	s.wg.Add(1)
+	go s.loop()
+	return s
+}
+
+// loop broadcasts the transactions becoming due on new chain heads or as time
+// passes.
+func (s *scheduler) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case head := <-heads:
+			s.broadcast(head.Block.NumberU64(), time.Now())
+		case <-ticker.C:
+			s.broadcast(s.chain.CurrentBlock().NumberU64(), time.Now())
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// broadcast adds the transactions due at the given chain head and time to the
+// pool, in nonce order per sender.
+func (s *scheduler) broadcast(number uint64, now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var due []common.Hash
+	for hash, stored := range s.txs {
+		if stored.due(number, now) {
+			due = append(due, hash)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	type dueTx struct {
+		hash common.Hash
+		from common.Address
+		tx   *types.Transaction
+	}
+	txs := make([]dueTx, 0, len(due))
+	for _, hash := range due {
+		stored := s.txs[hash]
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(stored.Raw); err != nil {
+			log.Error("Invalid scheduled transaction", "hash", hash, "err", err)
+			s.fail(hash, err)
+			continue
+		}
+		txs = append(txs, dueTx{hash, stored.From, tx})
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].from != txs[j].from {
+			return txs[i].from.Hex() < txs[j].from.Hex()
+		}
+		return txs[i].tx.Nonce() < txs[j].tx.Nonce()
+	})
+	for _, entry := range txs {
+		hash := entry.hash
+		if err := s.send(entry.tx); err != nil {
+			log.Warn("Scheduled transaction refused", "hash", hash, "err", err)
+			s.fail(hash, err)
+			continue
+		}
+		log.Info("Broadcast scheduled transaction", "hash", hash, "number", number)
+		if err := s.remove(hash); err != nil {
+			log.Error("Failed to delete scheduled transaction", "hash", hash, "err", err)
+		}
+	}
+}
+
+// add holds a signed transaction until the given broadcast block and time.
+func (s *scheduler) add(tx *types.Transaction, from common.Address, block, timestamp uint64) error {
+	if block == 0 && timestamp == 0 {
+		return errScheduleMissing
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hash := tx.Hash()
+	if _, ok := s.txs[hash]; ok {
+		return errScheduleKnown
+	}
+	return s.store(hash, &storedScheduledTx{Raw: raw, From: from, Block: block, Time: timestamp})
+}
+
+// cancel drops a scheduled transaction, reporting whether it was held.
+func (s *scheduler) cancel(hash common.Hash) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.txs[hash]; !ok {
+		return false, nil
+	}
+	return true, s.remove(hash)
+}
+
+// list returns the scheduled transactions ordered by sender and nonce.
+func (s *scheduler) list() []*ScheduledTx {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	list := make([]*ScheduledTx, 0, len(s.txs))
+	for hash, stored := range s.txs {
+		scheduled := &ScheduledTx{
+			Hash:  hash,
+			From:  stored.From,
+			Error: stored.Error,
+			Raw:   common.CopyBytes(stored.Raw),
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(stored.Raw); err == nil {
+			scheduled.Nonce = hexutil.Uint64(tx.Nonce())
+		}
+		if stored.Block != 0 {
+			block := hexutil.Uint64(stored.Block)
+			scheduled.Block = &block
+		}
+		if stored.Time != 0 {
+			timestamp := hexutil.Uint64(stored.Time)
+			scheduled.Time = &timestamp
+		}
+		list = append(list, scheduled)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].From != list[j].From {
+			return list[i].From.Hex() < list[j].From.Hex()
+		}
+		return list[i].Nonce < list[j].Nonce
+	})
+	return list
+}
+
+// store persists a scheduled transaction. Caller must hold the lock.
+func (s *scheduler) store(hash common.Hash, stored *storedScheduledTx) error {
+	data, err := rlp.EncodeToBytes(stored)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Put(hash[:], data); err != nil {
+		return err
+	}
+	s.txs[hash] = stored
+	return nil
+}
+
+// fail marks a scheduled transaction as refused, keeping it until cancelled.
+// Caller must hold the lock.
+func (s *scheduler) fail(hash common.Hash, err error) {
+	stored := *s.txs[hash]
+	stored.Error = err.Error()
+	if err := s.store(hash, &stored); err != nil {
+		log.Error("Failed to store scheduled transaction", "hash", hash, "err", err)
+	}
+}
+
+// remove deletes a scheduled transaction. Caller must hold the lock.
+func (s *scheduler) remove(hash common.Hash) error {
+	delete(s.txs, hash)
+	return s.db.Delete(hash[:])
+}
+
+// stop terminates the scheduler.
+func (s *scheduler) stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// PrivateScheduleAPI holds signed transactions for a later broadcast.
+type PrivateScheduleAPI struct {
+	eth *Indigo
+}
+
+// NewPrivateScheduleAPI creates a new API for the scheduled transactions.
+func NewPrivateScheduleAPI(eth *Indigo) *PrivateScheduleAPI {
+	return &PrivateScheduleAPI{eth: eth}
+}
+
+// Submit holds a signed transaction until the chain head reaches the given block
+// number and the given unix time has passed, at least one of which is required,
+// then adds it to the transaction pool. It returns the transaction hash.
+func (api *PrivateScheduleAPI) Submit(ctx context.Context, encodedTx hexutil.Bytes, block, timestamp *hexutil.Uint64) (common.Hash, error) {
+	s := api.eth.scheduler
+	if s == nil {
+		return common.Hash{}, errScheduleDisabled
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
+		return common.Hash{}, err
+	}
+	signer := types.MakeSigner(api.eth.chainConfig, api.eth.blockchain.CurrentBlock().Number())
+	from, err := types.Sender(ctx, signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var number, at uint64
+	if block != nil {
+		number = uint64(*block)
+	}
+	if timestamp != nil {
+		at = uint64(*timestamp)
+	}
+	if err := s.add(tx, from, number, at); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Scheduled transaction", "hash", tx.Hash(), "from", from, "nonce", tx.Nonce(), "block", number, "time", at)
+	return tx.Hash(), nil
+}
+
+// List returns the transactions held, along with the errors of those the pool
+// refused when they were due.
+func (api *PrivateScheduleAPI) List() ([]*ScheduledTx, error) {
+	s := api.eth.scheduler
+	if s == nil {
+		return nil, errScheduleDisabled
+	}
+	return s.list(), nil
+}
+
+// Cancel drops a held transaction, reporting whether it was scheduled.
+func (api *PrivateScheduleAPI) Cancel(hash common.Hash) (bool, error) {
+	s := api.eth.scheduler
+	if s == nil {
+		return false, errScheduleDisabled
+	}
+	return s.cancel(hash)
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/event"
+)
+
+// scheduleTestChain is a chain stuck at block 1.
+type scheduleTestChain struct {
+	feed event.Feed
+}
+
+func (c *scheduleTestChain) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+}
+
+func (c *scheduleTestChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// scheduleTestPool records the transactions sent to it, refusing those with the
+// nonce set in refuse.
+type scheduleTestPool struct {
+	sent   []*types.Transaction
+	refuse uint64
+	lock   sync.Mutex
+}
+
+func (p *scheduleTestPool) send(tx *types.Transaction) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if tx.Nonce() == p.refuse {
+		return errors.New("refused")
+	}
+	p.sent = append(p.sent, tx)
+	return nil
+}
+
+func (p *scheduleTestPool) nonces() []uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	nonces := make([]uint64, len(p.sent))
+	for i, tx := range p.sent {
+		nonces[i] = tx.Nonce()
+	}
+	return nonces
+}
+
+// Tests that scheduled transactions are broadcast in nonce order once both their
+// block and time are reached, survive restarts, and are kept with the error if
+// refused until cancelled.
+func TestScheduler(t *testing.T) {
+	var (
+		db    = ethdb.NewMemDatabase()
+		chain = new(scheduleTestChain)
+		pool  = &scheduleTestPool{refuse: 3}
+		start = time.Now()
+		later = uint64(start.Add(time.Hour).Unix())
+	)
+	txs := make([]*types.Transaction, 4)
+	for i := range txs {
+		tx := types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil)
+		txs[i], _ = types.SignTx(tx, types.HomesteadSigner{}, testBankKey)
+	}
+	s := newScheduler(chain, db, pool.send)
+	for _, sched := range []struct {
+		tx          *types.Transaction
+		block, time uint64
+	}{{txs[1], 10, 0}, {txs[0], 0, later}, {txs[2], 20, later}, {txs[3], 10, 0}} {
+		if err := s.add(sched.tx, testBank, sched.block, sched.time); err != nil {
+			t.Fatalf("failed to schedule transaction %d: %v", sched.tx.Nonce(), err)
+		}
+	}
+	if err := s.add(txs[0], testBank, 0, later); err != errScheduleKnown {
+		t.Errorf("duplicate error mismatch: have %v, want %v", err, errScheduleKnown)
+	}
+	if err := s.add(types.NewTransaction(5, common.Address{}, nil, 0, nil, nil), testBank, 0, 0); err != errScheduleMissing {
+		t.Errorf("unconditional error mismatch: have %v, want %v", err, errScheduleMissing)
+	}
+	// Nothing is due before the block and time are both reached
+	s.broadcast(9, start)
+	s.broadcast(9, start.Add(30*time.Minute))
+	if sent := pool.nonces(); len(sent) != 0 {
+		t.Fatalf("broadcast early: %v", sent)
+	}
+	// Transactions survive restarts
+	s.stop()
+	s = newScheduler(chain, db, pool.send)
+	defer s.stop()
+
+	if list := s.list(); len(list) != 4 || list[0].Hash != txs[0].Hash() || list[0].Time == nil || list[0].Block != nil {
+		t.Fatalf("reloaded schedule mismatch: %+v", list)
+	}
+	s.broadcast(10, start.Add(2*time.Hour))
+	if sent := pool.nonces(); len(sent) != 2 || sent[0] != 0 || sent[1] != 1 {
+		t.Fatalf("broadcast mismatch: have %v, want [0 1]", sent)
+	}
+	list := s.list()
+	if len(list) != 2 || list[0].Hash != txs[2].Hash() || list[1].Hash != txs[3].Hash() {
+		t.Fatalf("remaining schedule mismatch: %+v", list)
+	}
+	if list[0].Error != "" || list[1].Error != "refused" {
+		t.Errorf("refusal mismatch: have %q, %q", list[0].Error, list[1].Error)
+	}
+	// Refused transactions are not retried, cancelled ones are dropped
+	if ok, err := s.cancel(txs[2].Hash()); !ok || err != nil {
+		t.Errorf("failed to cancel transaction: %v %v", ok, err)
+	}
+	if ok, _ := s.cancel(txs[2].Hash()); ok {
+		t.Error("cancelled transaction twice")
+	}
+	s.broadcast(20, start.Add(2*time.Hour))
+	if sent := pool.nonces(); len(sent) != 2 {
+		t.Errorf("broadcast cancelled or refused transactions: %v", sent)
+	}
+	if list := s.list(); len(list) != 1 || list[0].Hash != txs[3].Hash() {
+		t.Errorf("final schedule mismatch: %+v", list)
+	}
+}
@@ -0,0 +1,423 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	_ "github.com/lib/pq"           // Registers the postgres driver
+	_ "github.com/mattn/go-sqlite3" // Registers the sqlite3 driver
+)
+
+const (
+	// SQLMirrorSQLite is the driver of SQLite mirror databases.
+	SQLMirrorSQLite = "sqlite3"
+	// SQLMirrorPostgres is the driver of PostgreSQL mirror databases.
+	SQLMirrorPostgres = "postgres"
+
+	sqlMirrorBatch = 128 // Number of blocks mirrored per database transaction
+)
+
+var errSQLMirrorDisabled = errors.New("SQL mirror not enabled")
+
+// sqlMirrorSchema creates the mirrored tables. Hashes, addresses and big
+// integers are stored as hex and decimal strings, readable by both drivers.
+var sqlMirrorSchema = []string{
+	`CREATE TABLE IF NOT EXISTS blocks (
+		number       BIGINT PRIMARY KEY,
+		hash         TEXT NOT NULL,
+		parent_hash  TEXT NOT NULL,
+		time         BIGINT NOT NULL,
+		coinbase     TEXT NOT NULL,
+		difficulty   TEXT NOT NULL,
+		gas_limit    BIGINT NOT NULL,
+		gas_used     BIGINT NOT NULL,
+		tx_count     INTEGER NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS transactions (
+		block_number     BIGINT NOT NULL,
+		tx_index         INTEGER NOT NULL,
+		hash             TEXT NOT NULL,
+		sender           TEXT NOT NULL,
+		recipient        TEXT,
+		value            TEXT NOT NULL,
+		nonce            BIGINT NOT NULL,
+		gas              BIGINT NOT NULL,
+		gas_price        TEXT NOT NULL,
+		status           INTEGER,
+		gas_used         BIGINT,
+		contract_address TEXT,
+		log_count        INTEGER,
+		PRIMARY KEY (block_number, tx_index)
+	)`,
+	`CREATE INDEX IF NOT EXISTS transactions_hash ON transactions (hash)`,
+	`CREATE INDEX IF NOT EXISTS transactions_sender ON transactions (sender)`,
+	`CREATE INDEX IF NOT EXISTS transactions_recipient ON transactions (recipient)`,
+}
+
+// SQLMirrorConfig selects the relational database the canonical chain metadata
+// is mirrored into, and how many blocks have to be built on top of a block
+// before it is mirrored.
+type SQLMirrorConfig struct {
+	Driver        string `toml:",omitempty"` // sqlite3 or postgres
+	DSN           string `toml:",omitempty"` // Data source name, empty to disable
+	Confirmations uint64 `toml:",omitempty"`
+}
+
+// SQLMirrorStatus is the progress of the SQL mirror.
+type SQLMirrorStatus struct {
+	Driver string      `json:"driver"`
+	Number uint64      `json:"number"` // Last block mirrored
+	Hash   common.Hash `json:"hash"`
+	Error  string      `json:"error,omitempty"` // Last failure to mirror, if any
+}
+
+// sqlMirror follows the canonical chain a number of confirmations behind its
+// head, writing the headers, transactions and receipt summaries of its blocks
+// into a relational database for reporting. Blocks reorged out of the chain are
+// deleted from the mirror before the new ones are written, so the mirror always
+// holds a prefix of the canonical chain. The mirror resumes from the last block
+// it holds, starting at the genesis if empty.
+type sqlMirror struct {
+	config  SQLMirrorConfig
+	chain   *core.BlockChain
+	chainDb ethdb.Database // Database to read blocks and receipts from
+	db      *sql.DB
+
+	cursor watchCursor
+	err    error
+	lock   sync.RWMutex // Protects the cursor and the error
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newSQLMirror connects to the configured database, creating the mirrored
+// tables if missing, and starts mirroring the chain.
+func newSQLMirror(config SQLMirrorConfig, chain *core.BlockChain, chainDb ethdb.Database) (*sqlMirror, error) {
+	if config.Driver == "" {
+		config.Driver = SQLMirrorSQLite
+	}
+	if config.Driver != SQLMirrorSQLite && config.Driver != SQLMirrorPostgres {
+		return nil, fmt.Errorf("unsupported SQL mirror driver %q", config.Driver)
+	}
+	db, err := sql.Open(config.Driver, config.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if config.Driver == SQLMirrorSQLite {
+		// Writes are serialized anyway, and in-memory databases are per connection
+		db.SetMaxOpenConns(1)
+	}
+	m := &sqlMirror{
+		config:  config,
+		chain:   chain,
+		chainDb: chainDb,
+		db:      db,
+		quit:    make(chan struct{}),
+	}
+	if err := m.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQL mirror: %v", err)
+	}
+	m.wg.Add(1)
+	go m.loop()
+	return m, nil
+}
+
+// init creates the mirrored tables and loads the last mirrored block.
+func (m *sqlMirror) init() error {
+	for _, stmt := range sqlMirrorSchema {
+		if _, err := m.db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	var (
+		number int64
+		hash   string
+	)
+	err := m.db.QueryRow(`SELECT number, hash FROM blocks ORDER BY number DESC LIMIT 1`).Scan(&number, &hash)
+	switch {
+	case err == sql.ErrNoRows:
+		// Empty mirror, starting at the genesis
+	case err != nil:
+		return err
+	default:
+		m.cursor = watchCursor{Number: uint64(number), Hash: common.HexToHash(hash)}
+	}
+	return nil
+}
+
+// loop mirrors the blocks confirmed by every new chain head.
+func (m *sqlMirror) loop() {
+	defer m.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := m.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	ctx := context.Background()
+	m.update(ctx)
+	for {
+		select {
+		case <-heads:
+			m.update(ctx)
+		case <-sub.Err():
+			return
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// update deletes the mirrored blocks reorged out of the canonical chain, then
+// mirrors the blocks up to the confirmed head.
+func (m *sqlMirror) update(ctx context.Context) {
+	m.lock.RLock()
+	cursor := m.cursor
+	m.lock.RUnlock()
+
+	// Walk back to the common ancestor of the mirrored and canonical chains. An
+	// empty mirror has no ancestor, the genesis is mirrored first.
+	empty := cursor.Hash == (common.Hash{})
+	if !empty {
+		ancestor := cursor
+		for ancestor.Number > 0 && core.GetCanonicalHash(m.chainDb, ancestor.Number) != ancestor.Hash {
+			header := m.chain.GetHeader(ancestor.Hash, ancestor.Number)
+			if header == nil {
+				ancestor = watchCursor{Number: ancestor.Number - 1, Hash: core.GetCanonicalHash(m.chainDb, ancestor.Number-1)}
+				continue
+			}
+			ancestor = watchCursor{Number: ancestor.Number - 1, Hash: header.ParentHash}
+		}
+		if ancestor != cursor {
+			if err := m.rollback(ancestor); err != nil {
+				m.failed(fmt.Errorf("failed to roll back to #%d: %v", ancestor.Number, err))
+				return
+			}
+			log.Warn("SQL mirror reorged", "number", ancestor.Number, "hash", ancestor.Hash, "dropped", cursor.Number-ancestor.Number)
+			cursor = ancestor
+		}
+	}
+	var (
+		head   = m.chain.CurrentBlock().NumberU64()
+		blocks []*types.Block
+	)
+	for empty || cursor.Number+m.config.Confirmations < head {
+		select {
+		case <-m.quit:
+			return
+		default:
+		}
+		number := cursor.Number + 1
+		if empty {
+			number = 0
+		}
+		block := m.chain.GetBlockByNumber(number)
+		if block == nil || (!empty && block.ParentHash() != cursor.Hash) {
+			// The chain moved under us, retry with the next head
+			break
+		}
+		blocks = append(blocks, block)
+		cursor, empty = watchCursor{Number: block.NumberU64(), Hash: block.Hash()}, false
+
+		if len(blocks) == sqlMirrorBatch {
+			if err := m.commit(ctx, blocks); err != nil {
+				m.failed(err)
+				return
+			}
+			blocks = blocks[:0]
+		}
+	}
+	if len(blocks) > 0 {
+		if err := m.commit(ctx, blocks); err != nil {
+			m.failed(err)
+		}
+	}
+}
+
+// commit writes the blocks, along with their transactions and receipts, in a
+// single database transaction, and moves the cursor onto the last one.
+func (m *sqlMirror) commit(ctx context.Context, blocks []*types.Block) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	insertBlock, err := tx.Prepare(m.rebind(`INSERT INTO blocks (number, hash, parent_hash, time, coinbase, difficulty, gas_limit, gas_used, tx_count) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	insertTx, err := tx.Prepare(m.rebind(`INSERT INTO transactions (block_number, tx_index, hash, sender, recipient, value, nonce, gas, gas_price, status, gas_used, contract_address, log_count) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, block := range blocks {
+		if err := m.insert(ctx, insertBlock, insertTx, block); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to mirror block #%d: %v", block.NumberU64(), err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	last := blocks[len(blocks)-1]
+
+	m.lock.Lock()
+	m.cursor = watchCursor{Number: last.NumberU64(), Hash: last.Hash()}
+	m.err = nil
+	m.lock.Unlock()
+
+	log.Debug("Mirrored blocks into SQL", "count", len(blocks), "number", last.NumberU64(), "hash", last.Hash())
+	return nil
+}
+
+// insert writes a block and its transactions with the given statements. Blocks
+// without receipts, e.g. fast synced ancient ones, are mirrored without the
+// receipt summaries of their transactions.
+func (m *sqlMirror) insert(ctx context.Context, insertBlock, insertTx *sql.Stmt, block *types.Block) error {
+	header := block.Header()
+	if _, err := insertBlock.ExecContext(ctx, int64(header.Number.Uint64()), hexString(header.Hash()), hexString(header.ParentHash),
+		header.Time.Int64(), hexString(header.Coinbase), header.Difficulty.String(), int64(header.GasLimit), int64(header.GasUsed), len(block.Transactions())); err != nil {
+		return err
+	}
+	receipts := core.GetBlockReceipts(m.chainDb, block.Hash(), block.NumberU64())
+	if len(receipts) != len(block.Transactions()) {
+		receipts = nil
+	}
+	signer := types.MakeSigner(m.chain.Config(), block.Number())
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(ctx, signer, tx)
+		if err != nil {
+			return err
+		}
+		var (
+			to                        interface{}
+			status, gasUsed, logCount interface{}
+			contract                  interface{}
+		)
+		if tx.To() != nil {
+			to = hexString(*tx.To())
+		}
+		if receipts != nil {
+			receipt := receipts[i]
+			if len(receipt.PostState) == 0 {
+				status = int64(receipt.Status)
+			}
+			gasUsed, logCount = int64(receipt.GasUsed), len(receipt.Logs)
+			if tx.To() == nil {
+				contract = hexString(receipt.ContractAddress)
+			}
+		}
+		if _, err := insertTx.ExecContext(ctx, int64(block.NumberU64()), i, hexString(tx.Hash()), hexString(from), to, tx.Value().String(),
+			int64(tx.Nonce()), int64(tx.Gas()), tx.GasPrice().String(), status, gasUsed, contract, logCount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback deletes the mirrored blocks above the ancestor, along with their
+// transactions, and moves the cursor onto it.
+func (m *sqlMirror) rollback(ancestor watchCursor) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(m.rebind(`DELETE FROM transactions WHERE block_number > ?`), int64(ancestor.Number)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(m.rebind(`DELETE FROM blocks WHERE number > ?`), int64(ancestor.Number)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	m.cursor = ancestor
+	m.lock.Unlock()
+	return nil
+}
+
+// rebind replaces the ? placeholders of a query with the numbered ones of
+// PostgreSQL if needed.
+func (m *sqlMirror) rebind(query string) string {
+	if m.config.Driver != SQLMirrorPostgres {
+		return query
+	}
+	var (
+		out bytes.Buffer
+		n   int
+	)
+	for _, c := range query {
+		if c == '?' {
+			n++
+			out.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		out.WriteRune(c)
+	}
+	return out.String()
+}
+
+// failed records and logs a failure to mirror, retried with the next head.
+func (m *sqlMirror) failed(err error) {
+	m.lock.Lock()
+	m.err = err
+	m.lock.Unlock()
+
+	log.Error("Failed to mirror chain into SQL", "err", err)
+}
+
+// status returns the last mirrored block and the last failure, if any.
+func (m *sqlMirror) status() *SQLMirrorStatus {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	status := &SQLMirrorStatus{Driver: m.config.Driver, Number: m.cursor.Number, Hash: m.cursor.Hash}
+	if m.err != nil {
+		status.Error = m.err.Error()
+	}
+	return status
+}
+
+// stop terminates the mirror and closes its database.
+func (m *sqlMirror) stop() {
+	close(m.quit)
+	m.wg.Wait()
+	m.db.Close()
+}
+
+// hexString returns the lowercase hex form of a hash or address, as stored in
+// the mirror.
+func hexString(b interface{ Bytes() []byte }) string {
+	return common.ToHex(b.Bytes())
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// Tests that the SQL mirror writes the blocks and transactions of the canonical
+// chain behind the confirmations, and replaces the blocks reorged out.
+func TestSQLMirror(t *testing.T) {
+	ctx := context.Background()
+	var (
+		db     = ethdb.NewMemDatabase()
+		engine = clique.NewFaker()
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000)}},
+		}
+		genesis       = gspec.MustCommit(db)
+		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	)
+	defer blockchain.Stop()
+
+	chain, _ := core.GenerateChain(ctx, gspec.Config, genesis, engine, db, 6, func(ctx context.Context, i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), common.Address{0xaa}, big.NewInt(int64(i+1)), 21000, nil, nil), types.HomesteadSigner{}, testBankKey)
+		block.AddTx(ctx, tx)
+	})
+	m, err := newSQLMirror(SQLMirrorConfig{Driver: SQLMirrorSQLite, DSN: ":memory:", Confirmations: 1}, blockchain, db)
+	if err != nil {
+		t.Fatalf("failed to create mirror: %v", err)
+	}
+	defer m.stop()
+
+	waitMirrored := func(number uint64, hash common.Hash) {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if status := m.status(); status.Number == number && status.Hash == hash {
+				return
+			}
+		}
+		t.Fatalf("timeout waiting for block #%d, mirror at %+v", number, m.status())
+	}
+	if _, err := blockchain.InsertChain(ctx, chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitMirrored(5, chain[4].Hash())
+
+	var blocks, txs int
+	var value, sender string
+	var status int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM blocks`).Scan(&blocks); err != nil || blocks != 6 {
+		t.Fatalf("block count mismatch: have %d, want 6 (err %v)", blocks, err)
+	}
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&txs); err != nil || txs != 5 {
+		t.Fatalf("transaction count mismatch: have %d, want 5 (err %v)", txs, err)
+	}
+	hash := chain[2].Transactions()[0].Hash()
+	if err := m.db.QueryRow(`SELECT value, sender, status FROM transactions WHERE hash = ?`, hexString(hash)).Scan(&value, &sender, &status); err != nil {
+		t.Fatalf("failed to query transaction: %v", err)
+	}
+	if value != "3" || sender != hexString(testBank) || status != int(types.ReceiptStatusSuccessful) {
+		t.Errorf("transaction mismatch: value %s, sender %s, status %d", value, sender, status)
+	}
+	// Reorg out the last four blocks with longer empty ones
+	fork, _ := core.GenerateChain(ctx, gspec.Config, chain[1], engine, db, 6, func(ctx context.Context, i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := blockchain.InsertChain(ctx, fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	waitMirrored(7, fork[4].Hash())
+
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM transactions`).Scan(&txs); err != nil || txs != 2 {
+		t.Fatalf("transaction count mismatch after reorg: have %d, want 2 (err %v)", txs, err)
+	}
+	var mirrored string
+	if err := m.db.QueryRow(`SELECT hash FROM blocks WHERE number = 3`).Scan(&mirrored); err != nil || mirrored != hexString(fork[0].Hash()) {
+		t.Errorf("block #3 mismatch: have %s, want %s (err %v)", mirrored, hexString(fork[0].Hash()), err)
+	}
+}
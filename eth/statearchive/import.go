@@ -0,0 +1,176 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package statearchive
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+// ImportResult reports the outcome of an import.
+type ImportResult struct {
+	Number  uint64        `json:"number"`
+	Hash    common.Hash   `json:"hash"`
+	Nodes   uint64        `json:"nodes"`
+	Codes   uint64        `json:"codes"`
+	Head    bool          `json:"head"` // Whether the block became the head of the chain
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// Import verifies and stores the state archived in dir. The block it belongs to
+// has to be known, e.g. imported without execution from chain segments, its
+// header vouching for the state root. Once every node is verified reachable
+// from the root, the block becomes the head of the chain if it is canonical
+// and ahead of the current head.
+func Import(ctx context.Context, chain ImportChain, db ethdb.Database, dir string) (*ImportResult, error) {
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Genesis != chain.Genesis().Hash() {
+		return nil, fmt.Errorf("genesis mismatch: archive %x, chain %x", manifest.Genesis, chain.Genesis().Hash())
+	}
+	header := chain.GetHeaderByHash(manifest.Hash)
+	if header == nil || header.Number.Uint64() != manifest.Number {
+		return nil, fmt.Errorf("block #%d [%x…] unknown, import its header first", manifest.Number, manifest.Hash[:4])
+	}
+	if header.Root != manifest.Root {
+		return nil, fmt.Errorf("state root mismatch: archive %x, header %x", manifest.Root, header.Root)
+	}
+	start := time.Now()
+	result := &ImportResult{Number: manifest.Number, Hash: manifest.Hash}
+	if err := importEntries(ctx, db, dir, manifest, result); err != nil {
+		return nil, err
+	}
+	if err := verify(ctx, db, manifest.Root); err != nil {
+		return nil, err
+	}
+	if core.GetCanonicalHash(db, manifest.Number) == manifest.Hash && chain.CurrentBlock().NumberU64() < manifest.Number {
+		if err := chain.FastSyncCommitHead(manifest.Hash); err != nil {
+			return nil, err
+		}
+		if err := core.WriteHeadBlockHash(db, manifest.Hash); err != nil {
+			return nil, err
+		}
+		result.Head = true
+	}
+	result.Elapsed = time.Since(start)
+	log.Info("Imported state", "dir", dir, "number", manifest.Number, "root", manifest.Root, "nodes", result.Nodes, "codes", result.Codes, "head", result.Head, "elapsed", common.PrettyDuration(result.Elapsed))
+	return result, nil
+}
+
+// importEntries stores the entries of the state file, keyed by their hash, and
+// checks them against the manifest.
+func importEntries(ctx context.Context, db ethdb.Database, dir string, manifest *Manifest, result *ImportResult) error {
+	f, err := os.Open(filepath.Join(dir, StateFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sum := sha256.New()
+	gz, err := gzip.NewReader(io.TeeReader(f, sum))
+	if err != nil {
+		return err
+	}
+	var (
+		stream = rlp.NewStream(gz, 0)
+		batch  = db.NewBatch()
+		logged = time.Now()
+	)
+	for {
+		var e entry
+		if err := stream.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("entry %d: failed to decode: %v", result.Nodes+result.Codes, err)
+		}
+		switch e.Kind {
+		case kindNode:
+			result.Nodes++
+		case kindCode:
+			result.Codes++
+		default:
+			return fmt.Errorf("entry %d: unknown kind %d", result.Nodes+result.Codes, e.Kind)
+		}
+		if err := batch.Put(crypto.Keccak256(e.Blob), e.Blob); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+
+			if time.Since(logged) > logInterval {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				log.Info("Importing state", "number", manifest.Number, "nodes", result.Nodes, "codes", result.Codes)
+				logged = time.Now()
+			}
+		}
+	}
+	// Drain the rest of the file into the checksum
+	if _, err := io.Copy(ioutil.Discard, f); err != nil {
+		return err
+	}
+	if have := hex.EncodeToString(sum.Sum(nil)); have != manifest.Checksum {
+		return fmt.Errorf("checksum mismatch: have %s, want %s", have, manifest.Checksum)
+	}
+	if result.Nodes != manifest.Nodes || result.Codes != manifest.Codes {
+		return fmt.Errorf("entry count mismatch: have %d nodes and %d codes, manifest lists %d and %d", result.Nodes, result.Codes, manifest.Nodes, manifest.Codes)
+	}
+	return batch.Write()
+}
+
+// verify walks the state from the root, checking that every node and code is
+// present, each being stored under its own hash.
+func verify(ctx context.Context, db ethdb.Database, root common.Hash) error {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return fmt.Errorf("incomplete state: %v", err)
+	}
+	it := state.NewNodeIterator(statedb)
+	for n := 0; it.Next(); n++ {
+		if n%100000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+	}
+	if it.Error != nil {
+		return fmt.Errorf("incomplete state: %v", it.Error)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package statearchive exports the state of a block into a verifiable archive,
+// and imports it back, to provision nodes from static storage without syncing
+// the state from the network.
+//
+// An archive directory holds a manifest.json naming the block, its state root
+// and the SHA-256 checksum of the state file, a gzip compressed stream of RLP
+// encoded entries: every node of the account trie and of the storage tries, and
+// every contract code. As nodes are referenced by their hash from the state
+// root of the header, the archive is a proof of every account, storage slot and
+// code it holds, and an import is complete once every node is reachable.
+package statearchive
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const (
+	// Version is the version of the archive format written by Export.
+	Version = 1
+
+	// ManifestFile is the name of the manifest within an archive directory.
+	ManifestFile = "manifest.json"
+
+	// StateFile is the name of the state entries within an archive directory.
+	StateFile = "state.rlp.gz"
+
+	logInterval = 8 * time.Second // Interval of the progress logs
+)
+
+// Kinds of archive entries.
+const (
+	kindNode = iota // Node of the account trie or of a storage trie
+	kindCode        // Contract code
+)
+
+var errNoState = errors.New("state not available")
+
+// Manifest describes the state archived in a directory.
+type Manifest struct {
+	Version  uint        `json:"version"`
+	Genesis  common.Hash `json:"genesis"`
+	Number   uint64      `json:"number"`
+	Hash     common.Hash `json:"hash"`
+	Root     common.Hash `json:"root"`
+	Nodes    uint64      `json:"nodes"`
+	Codes    uint64      `json:"codes"`
+	Size     int64       `json:"size"`
+	Checksum string      `json:"sha256"`
+}
+
+// entry is a trie node or a contract code, as stored in the state file.
+type entry struct {
+	Kind uint
+	Blob []byte
+}
+
+// ExportChain is the source of the exported state.
+type ExportChain interface {
+	Genesis() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// ImportChain is the chain the state is imported into.
+type ImportChain interface {
+	Genesis() *types.Block
+	CurrentBlock() *types.Block
+	GetHeaderByHash(hash common.Hash) *types.Header
+	FastSyncCommitHead(hash common.Hash) error
+}
+
+// ReadManifest reads the manifest of the archive in dir.
+func ReadManifest(dir string) (*Manifest, error) {
+	blob, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(Manifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("invalid state manifest: %v", err)
+	}
+	if manifest.Version != Version {
+		return nil, fmt.Errorf("unsupported state archive version %d", manifest.Version)
+	}
+	return manifest, nil
+}
+
+// writeManifest atomically replaces the manifest of the archive in dir.
+func writeManifest(dir string, manifest *Manifest) error {
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, ManifestFile)
+	if err := ioutil.WriteFile(path+".tmp", blob, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Export writes the state of the given block into dir. The state has to be
+// available, which for pruning nodes limits exports to recent blocks.
+func Export(ctx context.Context, chain ExportChain, number uint64, dir string) (*Manifest, error) {
+	block := chain.GetBlockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	statedb, err := chain.StateAt(block.Root())
+	if err != nil {
+		return nil, fmt.Errorf("%v for block #%d: %v", errNoState, number, err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, StateFile)
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(path + ".tmp")
+	defer f.Close()
+
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		manifest = &Manifest{Version: Version, Genesis: chain.Genesis().Hash(), Number: number, Hash: block.Hash(), Root: block.Root()}
+		sum      = sha256.New()
+		gz       = gzip.NewWriter(io.MultiWriter(f, sum))
+		triedb   = statedb.Database().TrieDB()
+		codes    = make(map[common.Hash]struct{})
+	)
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		// Embedded nodes are archived within their parent
+		if it.Hash == (common.Hash{}) {
+			continue
+		}
+		e := entry{Kind: kindNode}
+		if code := it.Code(); code != nil {
+			if _, ok := codes[it.Hash]; ok {
+				continue
+			}
+			codes[it.Hash] = struct{}{}
+			e = entry{Kind: kindCode, Blob: code}
+			manifest.Codes++
+		} else {
+			if e.Blob, err = triedb.Node(it.Hash); err != nil {
+				return nil, fmt.Errorf("node %x: %v", it.Hash, err)
+			}
+			manifest.Nodes++
+		}
+		if err := rlp.Encode(gz, &e); err != nil {
+			return nil, err
+		}
+		if time.Since(logged) > logInterval {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			log.Info("Exporting state", "number", number, "nodes", manifest.Nodes, "codes", manifest.Codes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Error != nil {
+		return nil, fmt.Errorf("failed to iterate state: %v", it.Error)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	if manifest.Size, err = f.Seek(0, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	manifest.Checksum = hex.EncodeToString(sum.Sum(nil))
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return nil, err
+	}
+	if err := writeManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	log.Info("Exported state", "dir", dir, "number", number, "root", manifest.Root, "nodes", manifest.Nodes, "codes", manifest.Codes, "size", common.StorageSize(manifest.Size), "elapsed", common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
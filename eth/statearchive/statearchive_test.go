@@ -0,0 +1,163 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package statearchive
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/eth/era"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
+	testGenesis = &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: core.GenesisAlloc{
+			testAddress:          {Balance: big.NewInt(1000000000)},
+			common.Address{0xaa}: {Balance: new(big.Int), Code: []byte{0x60, 0x01, 0x43, 0x55}}, // sstore(number, 1)
+			common.Address{0xbb}: {Balance: new(big.Int), Code: []byte{0x60, 0x01, 0x43, 0x55}}, // same code, stored once
+		},
+	}
+)
+
+// newTestChain creates a chain with the genesis of testGenesis, inserting n
+// blocks each calling a contract storing the block number.
+func newTestChain(t *testing.T, n int) (*core.BlockChain, ethdb.Database) {
+	ctx := context.Background()
+	db := ethdb.NewMemDatabase()
+	genesis := testGenesis.MustCommit(db)
+	engine := clique.NewFaker()
+
+	chain, err := core.NewBlockChain(db, nil, testGenesis.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if n == 0 {
+		return chain, db
+	}
+	blocks, _ := core.GenerateChain(ctx, testGenesis.Config, genesis, engine, db, n, func(ctx context.Context, i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), common.Address{0xaa}, big.NewInt(1), 100000, new(big.Int), nil), types.HomesteadSigner{}, testKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block.AddTx(ctx, tx)
+	})
+	if _, err := chain.InsertChain(ctx, blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain, db
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src, _ := newTestChain(t, 6)
+	defer src.Stop()
+
+	dir, err := ioutil.TempDir("", "statearchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	segments, states := filepath.Join(dir, "segments"), filepath.Join(dir, "state")
+	if _, err := era.Export(ctx, src, segments, 4); err != nil {
+		t.Fatalf("failed to export segments: %v", err)
+	}
+	manifest, err := Export(ctx, src, 6, states)
+	if err != nil {
+		t.Fatalf("failed to export state: %v", err)
+	}
+	head := src.CurrentBlock()
+	if manifest.Hash != head.Hash() || manifest.Root != head.Root() || manifest.Codes != 1 || manifest.Nodes == 0 {
+		t.Fatalf("manifest mismatch: %+v", manifest)
+	}
+	dst, db := newTestChain(t, 0)
+	defer dst.Stop()
+
+	// The state can't be imported before the block vouching for it
+	if _, err := Import(ctx, dst, db, states); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("unknown block error mismatch: %v", err)
+	}
+	if _, err := era.Import(ctx, dst, segments, true); err != nil {
+		t.Fatalf("failed to import segments: %v", err)
+	}
+	result, err := Import(ctx, dst, db, states)
+	if err != nil {
+		t.Fatalf("failed to import state: %v", err)
+	}
+	if !result.Head || result.Nodes != manifest.Nodes || result.Codes != manifest.Codes {
+		t.Errorf("import result mismatch: %+v", result)
+	}
+	if current := dst.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head mismatch: have #%d, want #%d", current.NumberU64(), head.NumberU64())
+	}
+	statedb, err := dst.State()
+	if err != nil {
+		t.Fatalf("failed to open imported state: %v", err)
+	}
+	if balance := statedb.GetBalance(common.Address{0xaa}); balance.Cmp(big.NewInt(6)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 6", balance)
+	}
+	for i := int64(1); i <= 6; i++ {
+		if value := statedb.GetState(common.Address{0xaa}, common.BigToHash(big.NewInt(i))); value != common.BigToHash(common.Big1) {
+			t.Errorf("slot %d mismatch: have %x, want 1", i, value)
+		}
+	}
+	if code := statedb.GetCode(common.Address{0xbb}); len(code) != 4 {
+		t.Errorf("code mismatch: have %x", code)
+	}
+}
+
+func TestImportCorrupt(t *testing.T) {
+	ctx := context.Background()
+	src, _ := newTestChain(t, 2)
+	defer src.Stop()
+
+	dir, err := ioutil.TempDir("", "statearchive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := Export(ctx, src, 2, dir); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	path := filepath.Join(dir, StateFile)
+	blob, _ := ioutil.ReadFile(path)
+	blob[len(blob)/2] ^= 0xff
+	ioutil.WriteFile(path, blob, 0644)
+
+	// The source chain knows the block, so only the archive can fail
+	if _, err := Import(ctx, src, ethdb.NewMemDatabase(), dir); err == nil {
+		t.Errorf("corrupt archive imported")
+	}
+}
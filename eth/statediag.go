@@ -0,0 +1,313 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const (
+	stateDiagDepth   = 128              // Maximum depth below the head of the blocks diagnostics are served for
+	stateDiagTimeout = 10 * time.Second // Time to wait for the diagnostics of a block from peers
+	stateDiagPeers   = 4                // Maximum number of peers asked for the diagnostics of a block
+	stateDiagReports = 16               // Number of diagnosed bad blocks remembered
+	stateDiagServe   = 10 * time.Second // Minimum time between two requests served for the same peer
+)
+
+var errStateDiagUnavailable = errors.New("no peer served the state diagnostics")
+
+// stateDiagResponse is the state diagnostics of a block received from a peer.
+type stateDiagResponse struct {
+	peer string
+	diag *core.StateDiag
+}
+
+// stateDiagRequests hands the state diagnostics received from peers to the
+// pending requests for the same block.
+type stateDiagRequests struct {
+	pending map[common.Hash][]chan *stateDiagResponse
+	lock    sync.Mutex
+}
+
+func newStateDiagRequests() *stateDiagRequests {
+	return &stateDiagRequests{pending: make(map[common.Hash][]chan *stateDiagResponse)}
+}
+
+// wait registers a request for the diagnostics of a block, returning the
+// channel they are delivered on.
+func (r *stateDiagRequests) wait(hash common.Hash) chan *stateDiagResponse {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	ch := make(chan *stateDiagResponse, stateDiagPeers)
+	r.pending[hash] = append(r.pending[hash], ch)
+	return ch
+}
+
+// done unregisters a request.
+func (r *stateDiagRequests) done(hash common.Hash, ch chan *stateDiagResponse) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	waiting := r.pending[hash]
+	for i, c := range waiting {
+		if c == ch {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(r.pending, hash)
+	} else {
+		r.pending[hash] = waiting
+	}
+}
+
+// deliver hands the diagnostics received from a peer to the pending requests,
+// reporting whether any was waiting for them.
+func (r *stateDiagRequests) deliver(peer string, data *stateDiagData) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	waiting := r.pending[data.Hash]
+	for _, ch := range waiting {
+		select {
+		case ch <- &stateDiagResponse{peer: peer, diag: data.Diag}:
+		default:
+		}
+	}
+	return len(waiting) > 0
+}
+
+// allowStateDiag reports whether a state diagnostics request of the peer may be
+// served, at most one every stateDiagServe.
+func (p *peer) allowStateDiag(now time.Time) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if now.Sub(p.stateDiagServed) < stateDiagServe {
+		return false
+	}
+	p.stateDiagServed = now
+	return true
+}
+
+// serveStateDiag executes a recent local block for a peer, returning the state
+// roots and writes of its transactions. It returns nil if serving is disabled,
+// the block unknown or too old, another block being executed, or if the
+// diagnostics don't fit in a protocol message.
+func (pm *ProtocolManager) serveStateDiag(ctx context.Context, hash common.Hash) *core.StateDiag {
+	if !pm.serveStateDiags {
+		return nil
+	}
+	block := pm.blockchain.GetBlockByHash(hash)
+	if block == nil || block.NumberU64()+stateDiagDepth < pm.blockchain.CurrentBlock().NumberU64() {
+		return nil
+	}
+	if !atomic.CompareAndSwapInt32(&pm.stateDiagBusy, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&pm.stateDiagBusy, 0)
+
+	diag, err := pm.blockchain.StateDiag(ctx, block)
+	if err != nil {
+		log.Debug("Failed to diagnose block state", "number", block.Number(), "hash", hash, "err", err)
+		return nil
+	}
+	if size, _, err := rlp.EncodeToReader(&stateDiagData{Hash: hash, Diag: diag}); err != nil || size > ProtocolMaxMsgSize {
+		log.Debug("Block state diagnostics too large to serve", "number", block.Number(), "hash", hash, "size", size, "err", err)
+		return nil
+	}
+	return diag
+}
+
+// requestStateDiag asks eth/65 peers for their execution of a block, those that
+// propagated it first, returning the first diagnostics received along with the
+// peer that sent them.
+func (pm *ProtocolManager) requestStateDiag(ctx context.Context, hash common.Hash) (string, *core.StateDiag, error) {
+	var peers []*peer
+	for _, p := range pm.peers.All() {
+		if p.version >= eth65 {
+			peers = append(peers, p)
+		}
+	}
+	sort.SliceStable(peers, func(i, j int) bool {
+		return peers[i].knownBlocks.Has(hash) && !peers[j].knownBlocks.Has(hash)
+	})
+	if len(peers) > stateDiagPeers {
+		peers = peers[:stateDiagPeers]
+	}
+	ch := pm.stateDiags.wait(hash)
+	defer pm.stateDiags.done(hash, ch)
+
+	asked := 0
+	for _, p := range peers {
+		if err := p.RequestStateDiag(ctx, hash); err != nil {
+			p.Log().Debug("Failed to request state diagnostics", "err", err)
+			continue
+		}
+		asked++
+	}
+	timeout := time.NewTimer(stateDiagTimeout)
+	defer timeout.Stop()
+
+	for ; asked > 0; asked-- {
+		select {
+		case res := <-ch:
+			if res.diag != nil && res.diag.Hash == hash {
+				return res.peer, res.diag, nil
+			}
+		case <-timeout.C:
+			return "", nil, errStateDiagUnavailable
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		}
+	}
+	return "", nil, errStateDiagUnavailable
+}
+
+// StateDiagReport is the outcome of comparing the local execution of a block
+// rejected for its state root with the execution of a peer.
+type StateDiagReport struct {
+	Hash       common.Hash           `json:"hash"`
+	Number     hexutil.Uint64        `json:"number"`
+	Peer       string                `json:"peer,omitempty"`
+	Divergence *core.StateDivergence `json:"divergence"` // Nil if the executions agree
+	Error      string                `json:"error,omitempty"`
+	Time       time.Time             `json:"time"`
+}
+
+// stateDiagnostics asks peers for their execution of the blocks rejected for
+// their state root, pinpointing the first transaction and the keys at which the
+// local execution diverges. The last reports are kept for inspection.
+type stateDiagnostics struct {
+	chain   *core.BlockChain
+	request func(ctx context.Context, hash common.Hash) (string, *core.StateDiag, error)
+
+	busy    int32              // Whether a block is being diagnosed
+	reports []*StateDiagReport // Last reports, most recent first
+	lock    sync.RWMutex       // Protects reports
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newStateDiagnostics starts diagnosing the blocks the chain rejects for their
+// state root.
+func newStateDiagnostics(chain *core.BlockChain, request func(ctx context.Context, hash common.Hash) (string, *core.StateDiag, error)) *stateDiagnostics {
+	d := &stateDiagnostics{
+		chain:   chain,
+		request: request,
+		quit:    make(chan struct{}),
+	}
+	d.wg.Add(1)
+	go d.loop()
+	return d
+}
+
+// loop diagnoses the bad blocks one at a time, skipping those rejected while
+// another is diagnosed.
+func (d *stateDiagnostics) loop() {
+	defer d.wg.Done()
+
+	bad := make(chan core.BadBlockEvent, 16)
+	sub := d.chain.SubscribeBadBlockEvent(bad)
+	defer sub.Unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for {
+		select {
+		case ev := <-bad:
+			if _, ok := ev.Error.(*core.StateRootError); !ok {
+				continue
+			}
+			if !atomic.CompareAndSwapInt32(&d.busy, 0, 1) {
+				log.Debug("Skipping state diagnostics, busy", "number", ev.Block.Number(), "hash", ev.Block.Hash())
+				continue
+			}
+			d.wg.Add(1)
+			go func(block *types.Block) {
+				defer d.wg.Done()
+				defer atomic.StoreInt32(&d.busy, 0)
+				d.diagnose(ctx, block)
+			}(ev.Block)
+		case <-sub.Err():
+			return
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// diagnose compares the local execution of a block with the one of a peer,
+// logging and remembering the outcome.
+func (d *stateDiagnostics) diagnose(ctx context.Context, block *types.Block) *StateDiagReport {
+	report := &StateDiagReport{Hash: block.Hash(), Number: hexutil.Uint64(block.NumberU64()), Time: time.Now()}
+	local, err := d.chain.StateDiag(ctx, block)
+	if err == nil {
+		var remote *core.StateDiag
+		if report.Peer, remote, err = d.request(ctx, block.Hash()); err == nil {
+			report.Divergence = core.CompareStateDiags(local, remote)
+		}
+	}
+	switch {
+	case err != nil:
+		report.Error = err.Error()
+		log.Warn("Failed to diagnose block state", "number", block.Number(), "hash", block.Hash(), "err", err)
+	case report.Divergence == nil:
+		log.Warn("Block state agrees with peer", "number", block.Number(), "hash", block.Hash(), "peer", report.Peer)
+	default:
+		div := report.Divergence
+		log.Error("Block state diverges from peer", "number", block.Number(), "hash", block.Hash(), "peer", report.Peer,
+			"step", div.Step, "tx", div.TxHash, "local", div.LocalRoot, "remote", div.RemoteRoot, "keys", len(div.Keys))
+	}
+	d.lock.Lock()
+	d.reports = append([]*StateDiagReport{report}, d.reports...)
+	if len(d.reports) > stateDiagReports {
+		d.reports = d.reports[:stateDiagReports]
+	}
+	d.lock.Unlock()
+	return report
+}
+
+// Reports returns the last diagnosed blocks, most recent first.
+func (d *stateDiagnostics) Reports() []*StateDiagReport {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return append([]*StateDiagReport{}, d.reports...)
+}
+
+// stop terminates the diagnostics, waiting for the one in progress.
+func (d *stateDiagnostics) stop() {
+	close(d.quit)
+	d.wg.Wait()
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/eth/downloader"
+	"github.com/fulcrumchain/indigo/p2p"
+)
+
+// Tests that the state diagnostics of recent blocks are served to eth/65 peers
+// only when enabled, and at most once every stateDiagServe.
+func TestGetStateDiag65(t *testing.T) {
+	ctx := context.Background()
+	pm, _ := newTestProtocolManagerMust(ctx, t, downloader.FullSync, 4, nil, nil)
+	peer, _ := newTestPeer(ctx, "peer", eth65, pm, true)
+	defer peer.close()
+
+	head := pm.blockchain.CurrentBlock()
+	diag, err := pm.blockchain.StateDiag(ctx, head)
+	if err != nil {
+		t.Fatalf("failed to diagnose head: %v", err)
+	}
+	tests := []struct {
+		serve   bool
+		limited bool
+		hash    common.Hash
+		diag    *core.StateDiag
+	}{
+		{false, false, head.Hash(), nil},
+		{true, false, common.Hash{0x01}, nil},
+		{true, false, head.Hash(), diag},
+		{true, true, head.Hash(), nil},
+	}
+	for i, test := range tests {
+		pm.serveStateDiags = test.serve
+		if !test.limited {
+			peer.lock.Lock()
+			peer.stateDiagServed = time.Time{}
+			peer.lock.Unlock()
+		}
+		if err := p2p.Send(peer.app, GetStateDiagMsg, test.hash); err != nil {
+			t.Fatalf("test %d: failed to send request: %v", i, err)
+		}
+		if err := p2p.ExpectMsg(peer.app, StateDiagMsg, &stateDiagData{Hash: test.hash, Diag: test.diag}); err != nil {
+			t.Errorf("test %d: diagnostics mismatch: %v", i, err)
+		}
+	}
+}
+
+// Tests that a diagnosed block is reported with the divergence from the peer.
+func TestStateDiagnose(t *testing.T) {
+	ctx := context.Background()
+	pm, _ := newTestProtocolManagerMust(ctx, t, downloader.FullSync, 4, nil, nil)
+	defer pm.Stop()
+
+	head := pm.blockchain.CurrentBlock()
+	remote, _ := pm.blockchain.StateDiag(ctx, head)
+	remote.Steps[len(remote.Steps)-1].Root = common.Hash{0x01}
+
+	d := &stateDiagnostics{
+		chain: pm.blockchain,
+		request: func(ctx context.Context, hash common.Hash) (string, *core.StateDiag, error) {
+			return "remote", remote, nil
+		},
+	}
+	report := d.diagnose(ctx, head)
+	if report.Error != "" || report.Peer != "remote" || report.Divergence == nil {
+		t.Fatalf("report mismatch: %+v", report)
+	}
+	if report.Divergence.RemoteRoot != (common.Hash{0x01}) || report.Divergence.LocalRoot != head.Root() {
+		t.Errorf("divergence mismatch: %+v", report.Divergence)
+	}
+	if reports := d.Reports(); len(reports) != 1 || reports[0] != report {
+		t.Errorf("reports mismatch: %v", reports)
+	}
+}
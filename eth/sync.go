@@ -50,7 +50,7 @@ type txsync struct {
 func (pm *ProtocolManager) syncTransactions(ctx context.Context, p *peer) {
 	ctx, span := trace.StartSpan(context.Background(), "ProtocolManager.syncTransactions")
 	defer span.End()
-	txs := pm.txpool.PendingList(ctx)
+	txs := pm.publicTxs(pm.txpool.PendingList(ctx))
 	if len(txs) == 0 {
 		return
 	}
@@ -63,7 +63,7 @@ func (pm *ProtocolManager) syncTransactions(ctx context.Context, p *peer) {
 // syncTransactionsAllPeers syncs pending txs to all peers.
 func (pm *ProtocolManager) syncTransactionsAllPeers() {
 	ctx := context.TODO()
-	txs := pm.txpool.PendingList(ctx)
+	txs := pm.publicTxs(pm.txpool.PendingList(ctx))
 	if len(txs) == 0 {
 		return
 	}
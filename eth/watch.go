@@ -0,0 +1,573 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/internal/ethapi"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+const (
+	// WatchCredit is the kind of the events moving funds into a watched address.
+	WatchCredit = "credit"
+	// WatchDebit is the kind of the events moving funds out of a watched address.
+	WatchDebit = "debit"
+
+	defaultWatchEventLimit = 100
+	maxWatchEventLimit     = 1000
+)
+
+var (
+	watchPrefix       = "watch-"         // Database table of the watched address events
+	watchCursorKey    = []byte("cursor") // Last block processed for the watched addresses
+	watchEventPrefix  = byte('e')        // watchEventPrefix + address + num (uint64 big endian) + index (uint32 big endian) -> event
+	erc20TransferSig  = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	errWatchDisabled  = errors.New("address watching not enabled")
+	errWatchNotTarget = errors.New("address not watched")
+)
+
+// WatchConfig selects the addresses whose deposits and withdrawals are tracked,
+// and how many blocks have to be built on top of a block before its events are
+// reported.
+type WatchConfig struct {
+	Addresses     []common.Address `toml:",omitempty"`
+	Confirmations uint64           `toml:",omitempty"`
+}
+
+// WatchEvent is a credit or debit of a watched address, either in the native
+// currency or in an ERC-20 token.
+type WatchEvent struct {
+	Address      common.Address  `json:"address"`
+	Kind         string          `json:"kind"`
+	Token        *common.Address `json:"token"` // Token contract, nil for the native currency
+	Counterparty common.Address  `json:"counterparty"`
+	Value        *hexutil.Big    `json:"value"`
+	Internal     bool            `json:"internal"` // Whether the native transfer was made by a contract
+	BlockNumber  hexutil.Uint64  `json:"blockNumber"`
+	BlockHash    common.Hash     `json:"blockHash"`
+	TxHash       common.Hash     `json:"transactionHash"`
+	TxIndex      hexutil.Uint    `json:"transactionIndex"`
+	Index        hexutil.Uint    `json:"index"`   // Position of the event among the ones of its block
+	Removed      bool            `json:"removed"` // Whether the event was reverted by a reorg
+}
+
+// storedWatchEvent is the database representation of a WatchEvent, whose
+// address, block number and index are part of the key.
+type storedWatchEvent struct {
+	Debit        bool
+	Native       bool
+	Token        common.Address
+	Counterparty common.Address
+	Value        *big.Int
+	Internal     bool
+	BlockHash    common.Hash
+	TxHash       common.Hash
+	TxIndex      uint32
+}
+
+// watchCursor is the last block processed for the watched addresses.
+type watchCursor struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// watchEventKey = watchEventPrefix + address + num (uint64 big endian) + index (uint32 big endian)
+func watchEventKey(addr common.Address, number uint64, index uint32) []byte {
+	key := make([]byte, 1+common.AddressLength+8+4)
+	key[0] = watchEventPrefix
+	copy(key[1:], addr[:])
+	binary.BigEndian.PutUint64(key[1+common.AddressLength:], number)
+	binary.BigEndian.PutUint32(key[1+common.AddressLength+8:], index)
+	return key
+}
+
+// watcher follows the canonical chain a number of confirmations behind its
+// head, storing and announcing the credits and debits of the watched addresses.
+// Native transfers are collected by re-executing the blocks, so the state of
+// their parents has to be available; otherwise the watcher stops at the block
+// and retries it with the next head, rather than reporting it without its
+// internal transfers. Events of blocks reorged out of the chain are deleted and
+// announced again as removed.
+type watcher struct {
+	config  WatchConfig
+	watched map[common.Address]bool
+	chain   *core.BlockChain
+	chainDb ethdb.Database // Database to read blocks and receipts from
+	db      ethdb.Database // Table to store the events in
+
+	cursor watchCursor
+	lock   sync.RWMutex // Protects the cursor
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newWatcher creates a watcher of the configured addresses, resuming from the
+// last processed block or starting at the current head if none.
+func newWatcher(config WatchConfig, chain *core.BlockChain, chainDb ethdb.Database) *watcher {
+	w := &watcher{
+		config:  config,
+		watched: make(map[common.Address]bool),
+		chain:   chain,
+		chainDb: chainDb,
+		db:      ethdb.NewTable(chainDb, watchPrefix),
+		quit:    make(chan struct{}),
+	}
+	for _, addr := range config.Addresses {
+		w.watched[addr] = true
+	}
+	if data, _ := w.db.Get(watchCursorKey); len(data) > 0 {
+		if err := rlp.DecodeBytes(data, &w.cursor); err != nil {
+			log.Error("Invalid watch cursor RLP", "err", err)
+		}
+	}
+	if w.cursor.Hash == (common.Hash{}) {
+		number := uint64(0)
+		if head := chain.CurrentBlock().NumberU64(); head > config.Confirmations {
+			number = head - config.Confirmations
+		}
+		w.cursor = watchCursor{Number: number, Hash: core.GetCanonicalHash(chainDb, number)}
+	}
+	w.wg.Add(1)
+	go w.loop()
+	return w
+}
+
+// loop processes the blocks confirmed by every new chain head.
+func (w *watcher) loop() {
+	defer w.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := w.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	w.update()
+	for {
+		select {
+		case <-heads:
+			w.update()
+		case <-sub.Err():
+			return
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// update rolls the cursor back to the canonical chain if it was reorged out,
+// then processes the blocks up to the confirmed head.
+func (w *watcher) update() {
+	cursor := w.Cursor()
+
+	// Walk back to the common ancestor of the processed and canonical chains
+	ancestor := cursor
+	for ancestor.Number > 0 && core.GetCanonicalHash(w.chainDb, ancestor.Number) != ancestor.Hash {
+		header := w.chain.GetHeader(ancestor.Hash, ancestor.Number)
+		if header == nil {
+			ancestor = watchCursor{Number: ancestor.Number - 1, Hash: core.GetCanonicalHash(w.chainDb, ancestor.Number-1)}
+			continue
+		}
+		ancestor = watchCursor{Number: ancestor.Number - 1, Hash: header.ParentHash}
+	}
+	if ancestor != cursor {
+		removed, err := w.rollback(ancestor)
+		if err != nil {
+			log.Error("Failed to roll back watched address events", "number", ancestor.Number, "err", err)
+			return
+		}
+		log.Warn("Watched address events reorged", "number", ancestor.Number, "hash", ancestor.Hash, "removed", len(removed))
+		if len(removed) > 0 {
+			w.feed.Send(removed)
+		}
+		cursor = ancestor
+	}
+	head := w.chain.CurrentBlock().NumberU64()
+	for cursor.Number+w.config.Confirmations < head {
+		select {
+		case <-w.quit:
+			return
+		default:
+		}
+		block := w.chain.GetBlockByNumber(cursor.Number + 1)
+		if block == nil || block.ParentHash() != cursor.Hash {
+			// The chain moved under us, retry with the next head
+			return
+		}
+		events, err := w.process(block)
+		if err != nil {
+			log.Error("Failed to process watched addresses, retrying with the next head", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+			return
+		}
+		cursor = watchCursor{Number: block.NumberU64(), Hash: block.Hash()}
+		if err := w.commit(events, cursor); err != nil {
+			log.Error("Failed to store watched address events", "number", cursor.Number, "err", err)
+			return
+		}
+		if len(events) > 0 {
+			w.feed.Send(events)
+		}
+	}
+}
+
+// process collects the credits and debits of the watched addresses in a block.
+func (w *watcher) process(block *types.Block) ([]*WatchEvent, error) {
+	receipts := core.GetBlockReceipts(w.chainDb, block.Hash(), block.NumberU64())
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("receipts missing for block #%d", block.NumberU64())
+	}
+	traced, err := blockTransfers(w.chain, block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to trace block #%d: %v", block.NumberU64(), err)
+	}
+	var events []*WatchEvent
+	add := func(addr common.Address, kind string, token *common.Address, counterparty common.Address, value *big.Int, internal bool, tx *types.Transaction, index int) {
+		if !w.watched[addr] {
+			return
+		}
+		events = append(events, &WatchEvent{
+			Address:      addr,
+			Kind:         kind,
+			Token:        token,
+			Counterparty: counterparty,
+			Value:        (*hexutil.Big)(value),
+			Internal:     internal,
+			BlockNumber:  hexutil.Uint64(block.NumberU64()),
+			BlockHash:    block.Hash(),
+			TxHash:       tx.Hash(),
+			TxIndex:      hexutil.Uint(index),
+			Index:        hexutil.Uint(len(events)),
+		})
+	}
+	for i, tx := range block.Transactions() {
+		receipt := receipts[i]
+
+		// Collect the native transfers, including those made by contracts
+		for _, t := range traced[i] {
+			add(t.from, WatchDebit, nil, t.to, t.value, t.internal, tx, i)
+			add(t.to, WatchCredit, nil, t.from, t.value, t.internal, tx, i)
+		}
+		// Collect the token transfers from the logs, skipping the ERC-721 ones
+		// indexing the token identifier too
+		for _, l := range receipt.Logs {
+			if len(l.Topics) != 3 || l.Topics[0] != erc20TransferSig || len(l.Data) != 32 {
+				continue
+			}
+			value := new(big.Int).SetBytes(l.Data)
+			if value.Sign() == 0 {
+				continue
+			}
+			token := l.Address
+			from, to := common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes())
+			add(from, WatchDebit, &token, to, value, false, tx, i)
+			add(to, WatchCredit, &token, from, value, false, tx, i)
+		}
+	}
+	return events, nil
+}
+
+// commit stores the events of a block along with the cursor moved onto it.
+func (w *watcher) commit(events []*WatchEvent, cursor watchCursor) error {
+	batch := w.db.NewBatch()
+	for _, ev := range events {
+		stored := storedWatchEvent{
+			Debit:        ev.Kind == WatchDebit,
+			Native:       ev.Token == nil,
+			Counterparty: ev.Counterparty,
+			Value:        (*big.Int)(ev.Value),
+			Internal:     ev.Internal,
+			BlockHash:    ev.BlockHash,
+			TxHash:       ev.TxHash,
+			TxIndex:      uint32(ev.TxIndex),
+		}
+		if ev.Token != nil {
+			stored.Token = *ev.Token
+		}
+		data, err := rlp.EncodeToBytes(stored)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(watchEventKey(ev.Address, uint64(ev.BlockNumber), uint32(ev.Index)), data); err != nil {
+			return err
+		}
+	}
+	data, err := rlp.EncodeToBytes(cursor)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(watchCursorKey, data); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	w.lock.Lock()
+	w.cursor = cursor
+	w.lock.Unlock()
+	return nil
+}
+
+// rollback deletes the events above the ancestor and moves the cursor onto it,
+// returning the deleted events newest first, flagged as removed.
+func (w *watcher) rollback(ancestor watchCursor) ([]*WatchEvent, error) {
+	var removed []*WatchEvent
+	for addr := range w.watched {
+		events, keys, err := w.events(addr, ancestor.Number+1, math.MaxUint64, false)
+		if err != nil {
+			return nil, err
+		}
+		for i, ev := range events {
+			if err := w.db.Delete(keys[i]); err != nil {
+				return nil, err
+			}
+			ev.Removed = true
+			removed = append(removed, ev)
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		if removed[i].BlockNumber != removed[j].BlockNumber {
+			return removed[i].BlockNumber > removed[j].BlockNumber
+		}
+		return removed[i].Index > removed[j].Index
+	})
+	return removed, w.commit(nil, ancestor)
+}
+
+// events retrieves the stored events of an address within the given block range
+// (both inclusive) in chain order, along with their keys. If canonical is set,
+// the events of blocks no longer in the canonical chain are skipped.
+func (w *watcher) events(addr common.Address, from, to uint64, canonical bool) ([]*WatchEvent, [][]byte, error) {
+	var (
+		events []*WatchEvent
+		keys   [][]byte
+		hashes = make(map[uint64]common.Hash)
+	)
+	it := w.db.NewRangeIterator(watchEventKey(addr, from, 0), append(watchEventKey(addr, to, math.MaxUint32), 0))
+	defer it.Release()
+
+	for it.Next() {
+		var stored storedWatchEvent
+		if err := rlp.DecodeBytes(it.Value(), &stored); err != nil {
+			return nil, nil, err
+		}
+		number := binary.BigEndian.Uint64(it.Key()[1+common.AddressLength:])
+		if canonical {
+			hash, ok := hashes[number]
+			if !ok {
+				hash = core.GetCanonicalHash(w.chainDb, number)
+				hashes[number] = hash
+			}
+			if hash != stored.BlockHash {
+				continue
+			}
+		}
+		ev := &WatchEvent{
+			Address:      addr,
+			Kind:         WatchCredit,
+			Counterparty: stored.Counterparty,
+			Value:        (*hexutil.Big)(stored.Value),
+			Internal:     stored.Internal,
+			BlockNumber:  hexutil.Uint64(number),
+			BlockHash:    stored.BlockHash,
+			TxHash:       stored.TxHash,
+			TxIndex:      hexutil.Uint(stored.TxIndex),
+			Index:        hexutil.Uint(binary.BigEndian.Uint32(it.Key()[1+common.AddressLength+8:])),
+		}
+		if stored.Debit {
+			ev.Kind = WatchDebit
+		}
+		if !stored.Native {
+			token := stored.Token
+			ev.Token = &token
+		}
+		events = append(events, ev)
+		keys = append(keys, common.CopyBytes(it.Key()))
+	}
+	return events, keys, it.Error()
+}
+
+// Cursor returns the last block processed for the watched addresses.
+func (w *watcher) Cursor() watchCursor {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	return w.cursor
+}
+
+// SubscribeEvents subscribes to the batches of events of every processed or
+// reorged block.
+func (w *watcher) SubscribeEvents(ch chan<- []*WatchEvent) event.Subscription {
+	return w.scope.Track(w.feed.Subscribe(ch))
+}
+
+// stop terminates the watcher.
+func (w *watcher) stop() {
+	close(w.quit)
+	w.wg.Wait()
+	w.scope.Close()
+}
+
+// PrivateWatchAPI provides the credits and debits of the watched addresses.
+type PrivateWatchAPI struct {
+	eth *Indigo
+}
+
+// NewPrivateWatchAPI creates a new API for the watched addresses.
+func NewPrivateWatchAPI(eth *Indigo) *PrivateWatchAPI {
+	return &PrivateWatchAPI{eth: eth}
+}
+
+// WatchStatus is the progress of the watched address tracking.
+type WatchStatus struct {
+	Addresses     []common.Address `json:"addresses"`
+	Confirmations hexutil.Uint64   `json:"confirmations"`
+	Number        hexutil.Uint64   `json:"number"`
+	Hash          common.Hash      `json:"hash"`
+}
+
+// Status returns the watched addresses and the last block processed for them.
+func (api *PrivateWatchAPI) Status() (*WatchStatus, error) {
+	w := api.eth.watcher
+	if w == nil {
+		return nil, errWatchDisabled
+	}
+	cursor := w.Cursor()
+	return &WatchStatus{
+		Addresses:     w.config.Addresses,
+		Confirmations: hexutil.Uint64(w.config.Confirmations),
+		Number:        hexutil.Uint64(cursor.Number),
+		Hash:          cursor.Hash,
+	}, nil
+}
+
+// GetEvents returns the credits and debits of a watched address within the
+// given block range in chain order. The first Offset events of the range are
+// skipped and at most Limit are returned. The latest and pending tags select the
+// last processed block.
+func (api *PrivateWatchAPI) GetEvents(address common.Address, fromBlock, toBlock rpc.BlockNumber, page *ethapi.AddressTxPagination) ([]*WatchEvent, error) {
+	w := api.eth.watcher
+	if w == nil {
+		return nil, errWatchDisabled
+	}
+	if !w.watched[address] {
+		return nil, errWatchNotTarget
+	}
+	cursor := w.Cursor()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		number = api.eth.resolveBlockNumber(number)
+		if number < 0 || uint64(number) > cursor.Number {
+			return cursor.Number
+		}
+		return uint64(number)
+	}
+	from, to := resolve(fromBlock), resolve(toBlock)
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d > %d", from, to)
+	}
+	offset, limit := 0, defaultWatchEventLimit
+	if page != nil {
+		if page.Offset < 0 || page.Limit < 0 {
+			return nil, errors.New("negative pagination")
+		}
+		if page.Limit > maxWatchEventLimit {
+			return nil, fmt.Errorf("page limit %d above maximum %d", page.Limit, maxWatchEventLimit)
+		}
+		offset = page.Offset
+		if page.Limit > 0 {
+			limit = page.Limit
+		}
+	}
+	events, _, err := w.events(address, from, to, true)
+	if err != nil {
+		return nil, err
+	}
+	if offset >= len(events) {
+		return []*WatchEvent{}, nil
+	}
+	events = events[offset:]
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// Events creates a subscription notifying the credits and debits of the given
+// watched addresses, or of all of them if none given, in chain order as their
+// blocks are confirmed. Events reverted by a reorg are notified again with the
+// removed flag set.
+func (api *PrivateWatchAPI) Events(ctx context.Context, addresses []common.Address) (*rpc.Subscription, error) {
+	w := api.eth.watcher
+	if w == nil {
+		return nil, errWatchDisabled
+	}
+	filter := make(map[common.Address]bool)
+	for _, addr := range addresses {
+		if !w.watched[addr] {
+			return nil, errWatchNotTarget
+		}
+		filter[addr] = true
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan []*WatchEvent, 64)
+		sub := w.SubscribeEvents(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case batch := <-events:
+				for _, ev := range batch {
+					if len(filter) == 0 || filter[ev.Address] {
+						notifier.Notify(rpcSub.ID, ev)
+					}
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
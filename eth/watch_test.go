@@ -0,0 +1,175 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// Tests that the watcher reports the native transfers of the watched addresses,
+// including internal ones, along with their token transfers, skips reverted
+// transfers and retracts the events of reorged blocks.
+func TestWatcher(t *testing.T) {
+	ctx := context.Background()
+	var (
+		watched   = common.Address{0xee}
+		forwarder = common.Address{0xf0} // Forwards its value to the watched address
+		reverter  = common.Address{0xf1} // Forwards its value to the watched address, then reverts
+		caller    = common.Address{0xf2} // Calls the reverter with its value, ignoring the failure
+		token     = common.Address{0xf3} // Logs a transfer of 5 tokens from the caller to the watched address
+		destroyer = common.Address{0xf4} // Self-destructs to the watched address
+
+		forward = append(append([]byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x34, 0x73}, watched[:]...), 0x5a, 0xf1, 0x50)
+	)
+	callReverter := append(append([]byte{0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x60, 0x00, 0x34, 0x73}, reverter[:]...), 0x5a, 0xf1, 0x50, 0x00)
+	logTransfer := append(append(append(append([]byte{0x60, 0x05, 0x60, 0x00, 0x52, 0x73}, watched[:]...), 0x33, 0x7f), erc20TransferSig[:]...), 0x60, 0x20, 0x60, 0x00, 0xa3, 0x00)
+
+	var (
+		db     = ethdb.NewMemDatabase()
+		engine = clique.NewFaker()
+		signer = types.HomesteadSigner{}
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				testBank:  {Balance: big.NewInt(1000000)},
+				forwarder: {Code: append(forward, 0x00), Balance: new(big.Int)},
+				reverter:  {Code: append(forward, 0x60, 0x00, 0x60, 0x00, 0xfd), Balance: new(big.Int)},
+				caller:    {Code: callReverter, Balance: new(big.Int)},
+				token:     {Code: logTransfer, Balance: new(big.Int)},
+				destroyer: {Code: append(append([]byte{0x73}, watched[:]...), 0xff), Balance: big.NewInt(7)},
+			},
+		}
+		genesis       = gspec.MustCommit(db)
+		blockchain, _ = core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	)
+	defer blockchain.Stop()
+
+	calls := []struct {
+		to    common.Address
+		value int64
+	}{{forwarder, 100}, {caller, 50}, {token, 0}, {destroyer, 0}, {watched, 10}}
+	chain, _ := core.GenerateChain(ctx, gspec.Config, genesis, engine, db, len(calls)+1, func(ctx context.Context, i int, block *core.BlockGen) {
+		if i < len(calls) {
+			tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), calls[i].to, big.NewInt(calls[i].value), 100000, nil, nil), signer, testBankKey)
+			block.AddTx(ctx, tx)
+		}
+	})
+	w := newWatcher(WatchConfig{Addresses: []common.Address{watched, testBank}, Confirmations: 1}, blockchain, db)
+	defer w.stop()
+
+	events := make(chan []*WatchEvent, 16)
+	sub := w.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	if _, err := blockchain.InsertChain(ctx, chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	type want struct {
+		addr         common.Address
+		kind         string
+		token        bool
+		counterparty common.Address
+		value        int64
+		internal     bool
+		block        uint64
+	}
+	wants := []want{
+		{testBank, WatchDebit, false, forwarder, 100, false, 1},
+		{watched, WatchCredit, false, forwarder, 100, true, 1},
+		{testBank, WatchDebit, false, caller, 50, false, 2},
+		{testBank, WatchDebit, true, watched, 5, false, 3},
+		{watched, WatchCredit, true, testBank, 5, false, 3},
+		{watched, WatchCredit, false, destroyer, 7, true, 4},
+		{testBank, WatchDebit, false, watched, 10, false, 5},
+		{watched, WatchCredit, false, testBank, 10, false, 5},
+	}
+	check := func(i int, ev *WatchEvent, want want, removed bool) {
+		if ev.Address != want.addr || ev.Kind != want.kind || (ev.Token != nil) != want.token || ev.Counterparty != want.counterparty ||
+			ev.Value.ToInt().Int64() != want.value || ev.Internal != want.internal || uint64(ev.BlockNumber) != want.block || ev.Removed != removed {
+			t.Errorf("event %d: mismatch: have %+v, want %+v", i, ev, want)
+		}
+		if ev.Token != nil && *ev.Token != token {
+			t.Errorf("event %d: token mismatch: have %x, want %x", i, *ev.Token, token)
+		}
+	}
+	var have []*WatchEvent
+	for len(have) < len(wants) {
+		select {
+		case batch := <-events:
+			have = append(have, batch...)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for events, have %d of %d", len(have), len(wants))
+		}
+	}
+	for i, ev := range have {
+		check(i, ev, wants[i], false)
+	}
+	if cursor := w.Cursor(); cursor.Number != uint64(len(calls)) {
+		t.Fatalf("cursor mismatch: have %d, want %d", cursor.Number, len(calls))
+	}
+	// Check that the stored events are paginated per address in chain order
+	api := &PrivateWatchAPI{eth: &Indigo{config: &DefaultConfig, blockchain: blockchain, watcher: w}}
+	stored, err := api.GetEvents(watched, 0, -1, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve events: %v", err)
+	}
+	if len(stored) != 4 {
+		t.Fatalf("stored event count mismatch: have %d, want 4", len(stored))
+	}
+	for i, j := range []int{1, 4, 5, 7} {
+		check(j, stored[i], wants[j], false)
+	}
+	// Reorg out the last three blocks and check their events get retracted
+	fork, _ := core.GenerateChain(ctx, gspec.Config, chain[1], engine, db, len(calls), func(ctx context.Context, i int, block *core.BlockGen) {
+		block.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := blockchain.InsertChain(ctx, fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	have = have[:0]
+	for len(have) < 5 {
+		select {
+		case batch := <-events:
+			have = append(have, batch...)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for removed events, have %d of 5", len(have))
+		}
+	}
+	for i, ev := range have {
+		check(i, ev, wants[len(wants)-1-i], true)
+	}
+	if stored, _ := api.GetEvents(watched, 0, -1, nil); len(stored) != 1 {
+		t.Fatalf("stored event count mismatch after reorg: have %d, want 1", len(stored))
+	}
+	// Blocks that can't be re-executed are retried instead of being processed
+	// without their internal transfers
+	orphan := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(100), ParentHash: common.Hash{0x01}})
+	if _, err := w.process(orphan); err == nil {
+		t.Errorf("block without parent state processed")
+	}
+}
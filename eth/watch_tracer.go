@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+)
+
+// transfer is a movement of native currency between two accounts.
+type transfer struct {
+	from, to common.Address
+	value    *big.Int
+	internal bool // Whether the transfer was made by a contract rather than the transaction
+}
+
+// transferFrame collects the transfers of a call frame until it is known
+// whether the frame succeeded and its transfers stand.
+type transferFrame struct {
+	depth     int       // Depth of the operation that opened the frame
+	create    bool      // Whether the frame creates a contract, receiving its value at return
+	call      *transfer // Value transferred into the frame, nil if none
+	transfers []transfer
+}
+
+// transferTracer is a vm.Tracer collecting the native currency transfers of a
+// transaction, including the ones made by internal calls, contract creations
+// and self-destructs. Transfers of frames that revert are discarded.
+type transferTracer struct {
+	frames []*transferFrame
+	failed bool
+}
+
+// newTransferTracer creates a tracer for a single transaction.
+func newTransferTracer() *transferTracer {
+	return &transferTracer{
+		frames: []*transferFrame{{}},
+	}
+}
+
+// CaptureStart implements vm.Tracer, recording the value transferred by the
+// transaction itself.
+func (t *transferTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	if value != nil && value.Sign() > 0 {
+		t.frames[0].transfers = append(t.frames[0].transfers, transfer{from: from, to: to, value: new(big.Int).Set(value)})
+	}
+	return nil
+}
+
+// CaptureState implements vm.Tracer, opening a frame for every call or creation
+// and settling it once execution returns to the calling depth.
+func (t *transferTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil {
+		// The operation failed before executing, its frame reverts as a whole
+		return nil
+	}
+	// Frames opened deeper that never returned to their depth ended with their
+	// last operation, keep their transfers.
+	for len(t.frames) > 1 && t.frames[len(t.frames)-1].depth > depth {
+		t.settle(true)
+	}
+	// The first operation after a call at the same depth finds its status on
+	// top of the stack.
+	if len(t.frames) > 1 && t.frames[len(t.frames)-1].depth == depth {
+		frame := t.frames[len(t.frames)-1]
+		result := stack.Back(0)
+		if frame.create && frame.call != nil {
+			frame.call.to = common.BigToAddress(result)
+		}
+		t.settle(result.Sign() != 0)
+	}
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		frame := &transferFrame{depth: depth}
+		if value := stack.Back(2); value.Sign() > 0 && op == vm.CALL {
+			frame.call = &transfer{from: contract.Address(), to: common.BigToAddress(stack.Back(1)), value: new(big.Int).Set(value), internal: true}
+		}
+		t.frames = append(t.frames, frame)
+
+	case vm.DELEGATECALL, vm.STATICCALL:
+		t.frames = append(t.frames, &transferFrame{depth: depth})
+
+	case vm.CREATE:
+		frame := &transferFrame{depth: depth, create: true}
+		if value := stack.Back(0); value.Sign() > 0 {
+			frame.call = &transfer{from: contract.Address(), value: new(big.Int).Set(value), internal: true}
+		}
+		t.frames = append(t.frames, frame)
+
+	case vm.SELFDESTRUCT:
+		if balance := env.StateDB.GetBalance(contract.Address()); balance.Sign() > 0 {
+			frame := t.frames[len(t.frames)-1]
+			frame.transfers = append(frame.transfers, transfer{from: contract.Address(), to: common.BigToAddress(stack.Back(0)), value: new(big.Int).Set(balance), internal: true})
+		}
+	}
+	return nil
+}
+
+// settle closes the innermost frame, merging its transfers into its parent if
+// the frame succeeded.
+func (t *transferTracer) settle(success bool) {
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	if !success {
+		return
+	}
+	parent := t.frames[len(t.frames)-1]
+	if frame.call != nil {
+		parent.transfers = append(parent.transfers, *frame.call)
+	}
+	parent.transfers = append(parent.transfers, frame.transfers...)
+}
+
+// CaptureFault implements vm.Tracer.
+func (t *transferTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer, discarding every transfer if the transaction
+// failed.
+func (t *transferTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	if err != nil {
+		t.failed = true
+	}
+	return nil
+}
+
+// Transfers returns the transfers of the transaction in execution order.
+func (t *transferTracer) Transfers() []transfer {
+	if t.failed {
+		return nil
+	}
+	for len(t.frames) > 1 {
+		t.settle(true)
+	}
+	return t.frames[0].transfers
+}
+
+// blockTransfers re-executes a block on the state of its parent, returning the
+// native transfers of each of its transactions.
+func blockTransfers(chain *core.BlockChain, block *types.Block) ([][]transfer, error) {
+	parent := chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent block #%d not found", block.NumberU64()-1)
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	var (
+		config    = chain.Config()
+		signer    = types.MakeSigner(config, block.Number())
+		transfers = make([][]transfer, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(context.Background(), signer)
+		if err != nil {
+			return nil, err
+		}
+		tracer := newTransferTracer()
+		vmctx := core.NewEVMContext(msg, block.Header(), chain, nil)
+		vmenv := vm.NewEVM(vmctx, statedb, config, vm.Config{Debug: true, Tracer: tracer})
+		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		statedb.Finalise(true)
+		transfers[i] = tracer.Transfers()
+	}
+	return transfers, nil
+}
@@ -0,0 +1,263 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+const (
+	watchdogInterval   = 30 * time.Second // Interval of the divergence checks
+	watchdogLifetime   = 10 * time.Minute // Time a peer refused for its genesis counts as diverging
+	watchdogMinPeers   = 3                // Minimum number of peers observed before judging divergence
+	watchdogMaxRefused = 64               // Maximum number of refused peers tracked, the oldest forgotten first
+)
+
+var (
+	errWatchdogDisabled = errors.New("chain watchdog not enabled")
+
+	watchdogDivergingGauge = metrics.NewGauge("eth/watchdog/diverging")
+	watchdogAlarmGauge     = metrics.NewGauge("eth/watchdog/alarm")
+)
+
+// WatchdogConfig sets the fraction of peers with a different genesis or an
+// incompatible fork at which the chain watchdog raises its alarm, and whether
+// the node stops sealing while it is raised.
+type WatchdogConfig struct {
+	Threshold float64 `toml:",omitempty"` // Fraction of diverging peers raising the alarm, 0 to disable
+	Halt      bool    `toml:",omitempty"` // Whether sealing is refused while the alarm is raised
+}
+
+// WatchdogPeer is a peer whose genesis differs from the local one, or whose
+// fork identifier is incompatible with the local chain.
+type WatchdogPeer struct {
+	ID        string        `json:"id"`
+	Genesis   common.Hash   `json:"genesis"`
+	ForkHash  hexutil.Bytes `json:"forkHash,omitempty"` // Empty if not advertised, before eth/64
+	ForkNext  uint64        `json:"forkNext,omitempty"`
+	Connected bool          `json:"connected"`
+	Seen      time.Time     `json:"seen"`
+}
+
+// WatchdogStatus is the last verdict of the chain watchdog.
+type WatchdogStatus struct {
+	Genesis   common.Hash     `json:"genesis"`
+	ForkHash  hexutil.Bytes   `json:"forkHash"`
+	ForkNext  uint64          `json:"forkNext"`
+	Peers     int             `json:"peers"`
+	Diverging []*WatchdogPeer `json:"diverging"`
+	Alarm     bool            `json:"alarm"`
+	Halted    bool            `json:"halted"`
+}
+
+// watchdogPeer is the genesis and fork identifier observed for a peer.
+type watchdogPeer struct {
+	genesis    common.Hash
+	fork       *forkID // Nil if not advertised, before eth/64
+	compatible bool    // Whether the fork identifier is compatible with the local chain
+	connected  bool    // Whether the peer was accepted, otherwise refused for its genesis
+	seen       time.Time
+}
+
+// chainWatchdog compares the genesis and the fork identifier advertised by
+// peers in their handshakes against the local ones, raising an alarm when a
+// significant fraction diverges. Only peers of the local network passing the
+// handshake are counted, and peers refused for their genesis only if they are
+// static or trusted ones, for a while after their last attempt. Nodes that
+// upgraded to a fork the rest of the network did not schedule, or failed to,
+// are spotted before the fork block splits the chain, or right after it
+// otherwise.
+type chainWatchdog struct {
+	config  WatchdogConfig
+	genesis common.Hash
+	fork    func() forkID // Local fork identifier at the current head
+	halt    func(bool)    // Stops or resumes sealing, nil to only alert
+
+	peers map[string]*watchdogPeer
+	alarm bool
+	lock  sync.Mutex // Protects peers and alarm
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newChainWatchdog starts a watchdog checking peers against the given genesis
+// and local fork identifier.
+func newChainWatchdog(config WatchdogConfig, genesis common.Hash, fork func() forkID, halt func(bool)) *chainWatchdog {
+	w := &chainWatchdog{
+		config:  config,
+		genesis: genesis,
+		fork:    fork,
+		halt:    halt,
+		peers:   make(map[string]*watchdogPeer),
+		quit:    make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.loop()
+	return w
+}
+
+// loop checks the observed peers at a fixed interval.
+func (w *chainWatchdog) loop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(watchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			w.check(now)
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// connected records the genesis and fork identifier of an accepted peer, and
+// whether the latter is compatible with the local chain.
+func (w *chainWatchdog) connected(id string, genesis common.Hash, fork *forkID, compatible bool, now time.Time) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.peers[id] = &watchdogPeer{genesis: genesis, fork: fork, compatible: compatible, connected: true, seen: now}
+}
+
+// refused records a peer refused for advertising another genesis. Past
+// watchdogMaxRefused refused peers, the one seen the longest ago is forgotten.
+func (w *chainWatchdog) refused(id string, genesis common.Hash, now time.Time) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if _, ok := w.peers[id]; !ok {
+		var (
+			refused int
+			oldest  string
+		)
+		for pid, peer := range w.peers {
+			if peer.connected {
+				continue
+			}
+			if refused++; oldest == "" || peer.seen.Before(w.peers[oldest].seen) {
+				oldest = pid
+			}
+		}
+		if refused >= watchdogMaxRefused {
+			delete(w.peers, oldest)
+		}
+	}
+	w.peers[id] = &watchdogPeer{genesis: genesis, seen: now}
+}
+
+// disconnected forgets an accepted peer.
+func (w *chainWatchdog) disconnected(id string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if peer, ok := w.peers[id]; ok && peer.connected {
+		delete(w.peers, id)
+	}
+}
+
+// diverges reports whether a peer is on another genesis or an incompatible
+// fork. Caller must hold the lock.
+func (w *chainWatchdog) diverges(peer *watchdogPeer) bool {
+	return peer.genesis != w.genesis || (peer.connected && !peer.compatible)
+}
+
+// check expires the refused peers last seen too long ago, and raises or clears
+// the alarm depending on the fraction of diverging peers.
+func (w *chainWatchdog) check(now time.Time) {
+	w.lock.Lock()
+	diverging := 0
+	for id, peer := range w.peers {
+		if !peer.connected && now.Sub(peer.seen) > watchdogLifetime {
+			delete(w.peers, id)
+			continue
+		}
+		if w.diverges(peer) {
+			diverging++
+		}
+	}
+	total := len(w.peers)
+	alarm := total >= watchdogMinPeers && float64(diverging) >= w.config.Threshold*float64(total)
+	changed := alarm != w.alarm
+	w.alarm = alarm
+	w.lock.Unlock()
+
+	watchdogDivergingGauge.Update(int64(diverging))
+	switch {
+	case changed && alarm:
+		watchdogAlarmGauge.Update(1)
+		log.Error("Peers diverge from the local chain configuration", "diverging", diverging, "peers", total, "halt", w.config.Halt)
+	case changed:
+		watchdogAlarmGauge.Update(0)
+		log.Info("Peers agree with the local chain configuration again", "diverging", diverging, "peers", total)
+	}
+	if changed && w.config.Halt && w.halt != nil {
+		w.halt(alarm)
+	}
+}
+
+// Status returns the diverging peers and the last verdict of the watchdog.
+func (w *chainWatchdog) Status() *WatchdogStatus {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	fork := w.fork()
+	status := &WatchdogStatus{
+		Genesis:   w.genesis,
+		ForkHash:  fork.Hash[:],
+		ForkNext:  fork.Next,
+		Peers:     len(w.peers),
+		Diverging: []*WatchdogPeer{},
+		Alarm:     w.alarm,
+		Halted:    w.alarm && w.config.Halt,
+	}
+	for id, peer := range w.peers {
+		if w.diverges(peer) {
+			diverging := &WatchdogPeer{
+				ID:        id,
+				Genesis:   peer.genesis,
+				Connected: peer.connected,
+				Seen:      peer.seen,
+			}
+			if peer.fork != nil {
+				diverging.ForkHash, diverging.ForkNext = peer.fork.Hash[:], peer.fork.Next
+			}
+			status.Diverging = append(status.Diverging, diverging)
+		}
+	}
+	sort.Slice(status.Diverging, func(i, j int) bool {
+		return status.Diverging[i].ID < status.Diverging[j].ID
+	})
+	return status
+}
+
+// stop terminates the watchdog.
+func (w *chainWatchdog) stop() {
+	close(w.quit)
+	w.wg.Wait()
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/params"
+)
+
+// Tests that fork identifiers tell apart configurations scheduling other forks,
+// and that only peers diverging from the local chain are found incompatible.
+func TestForkID(t *testing.T) {
+	config := *params.TestChainConfig
+	config.HomesteadBlock, config.EIP150Block = big.NewInt(0), big.NewInt(10)
+	config.EIP155Block, config.EIP158Block = big.NewInt(20), big.NewInt(20)
+	config.ByzantiumBlock, config.TypedTxBlock = big.NewInt(30), nil
+
+	genesis := common.Hash{0x01}
+	filter := newForkFilter(&config, genesis)
+	if want := []uint64{10, 20, 30}; fmt.Sprint(filter.forks) != fmt.Sprint(want) {
+		t.Fatalf("forks mismatch: have %v, want %v", filter.forks, want)
+	}
+	if id := filter.id(0); id.Hash != filter.sums[0] || id.Next != 10 {
+		t.Errorf("genesis fork id mismatch: %v", id)
+	}
+	if id := filter.id(25); id.Hash != filter.sums[2] || id.Next != 30 {
+		t.Errorf("fork id mismatch: %v", id)
+	}
+	if id := filter.id(100); id.Hash != filter.sums[3] || id.Next != 0 {
+		t.Errorf("last fork id mismatch: %v", id)
+	}
+	// Rescheduling a future fork changes the identifier, not the checksum
+	other := config
+	other.ByzantiumBlock = big.NewInt(40)
+	if a, b := filter.id(25), newForkFilter(&other, genesis).id(25); a.Hash != b.Hash || a.Next == b.Next {
+		t.Errorf("rescheduled fork id mismatch: %v, %v", a, b)
+	}
+	tests := []struct {
+		head       uint64
+		remote     forkID
+		compatible bool
+	}{
+		{25, forkID{filter.sums[2], 30}, true},   // Same forks
+		{25, forkID{filter.sums[2], 0}, true},    // Remote not scheduling the next fork yet
+		{25, forkID{filter.sums[2], 40}, true},   // Remote scheduling it later, not passed yet
+		{35, forkID{filter.sums[3], 32}, false},  // Remote announcing a fork passed without it
+		{35, forkID{filter.sums[2], 30}, true},   // Remote behind, announcing the fork passed
+		{35, forkID{filter.sums[2], 40}, false},  // Remote behind, scheduling another fork
+		{25, forkID{filter.sums[1], 20}, true},   // Remote behind, syncing
+		{25, forkID{filter.sums[1], 22}, false},  // Remote behind, scheduling another fork
+		{15, forkID{filter.sums[3], 0}, true},    // Local behind, syncing
+		{25, forkID{[4]byte{0xff}, 0}, false},    // Remote on another chain
+		{100, forkID{filter.sums[3], 0}, true},   // Both past all forks
+		{100, forkID{filter.sums[3], 200}, true}, // Remote scheduling a new fork
+		{100, forkID{filter.sums[0], 10}, true},  // Remote far behind
+	}
+	for i, tt := range tests {
+		if compatible := filter.compatible(tt.head, tt.remote); compatible != tt.compatible {
+			t.Errorf("test %d: compatibility mismatch: have %v, want %v", i, compatible, tt.compatible)
+		}
+	}
+}
+
+// Tests that the watchdog raises its alarm and halts sealing once the fraction of
+// peers on another genesis or fork configuration reaches the threshold, and that
+// it lifts them once the peers are gone.
+func TestChainWatchdog(t *testing.T) {
+	var (
+		genesis = common.Hash{0x01}
+		fork    = &forkID{Hash: [4]byte{0x02}}
+		halts   []bool
+		now     = time.Now()
+	)
+	w := newChainWatchdog(WatchdogConfig{Threshold: 0.3, Halt: true}, genesis, func() forkID { return *fork }, func(halt bool) {
+		halts = append(halts, halt)
+	})
+	defer w.stop()
+
+	// Too few peers are not judged
+	w.connected("a", genesis, fork, true, now)
+	w.connected("b", genesis, &forkID{Hash: [4]byte{0xff}}, false, now)
+	w.check(now)
+	if status := w.Status(); status.Alarm || len(halts) != 0 {
+		t.Fatalf("alarm raised with too few peers: %+v", status)
+	}
+	// Peers not advertising their configuration only count for their genesis
+	w.connected("c", genesis, nil, true, now)
+	w.connected("d", genesis, fork, true, now)
+	w.check(now)
+	if status := w.Status(); status.Alarm || status.Peers != 4 || len(status.Diverging) != 1 {
+		t.Fatalf("alarm mismatch below threshold: %+v", status)
+	}
+	// Refused peers tip the balance
+	w.refused("e", common.Hash{0x03}, now)
+	w.check(now)
+	status := w.Status()
+	if !status.Alarm || !status.Halted || len(status.Diverging) != 2 {
+		t.Fatalf("alarm mismatch above threshold: %+v", status)
+	}
+	if status.Diverging[0].ID != "b" || !status.Diverging[0].Connected || status.Diverging[1].ID != "e" || status.Diverging[1].Connected {
+		t.Errorf("diverging peers mismatch: %+v %+v", status.Diverging[0], status.Diverging[1])
+	}
+	if len(halts) != 1 || !halts[0] {
+		t.Fatalf("halt mismatch: have %v, want [true]", halts)
+	}
+	// Refused peers expire and disconnected ones are forgotten
+	w.disconnected("b")
+	w.check(now.Add(watchdogLifetime + time.Second))
+	if status := w.Status(); status.Alarm || status.Peers != 3 {
+		t.Fatalf("alarm not lifted: %+v", status)
+	}
+	if len(halts) != 2 || halts[1] {
+		t.Errorf("halt mismatch: have %v, want [true false]", halts)
+	}
+	// Refused peers are capped, the oldest forgotten first
+	for i := 0; i <= watchdogMaxRefused; i++ {
+		w.refused(fmt.Sprintf("refused %d", i), common.Hash{0x03}, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if status := w.Status(); status.Peers != 3+watchdogMaxRefused || status.Diverging[0].ID == "refused 0" {
+		t.Errorf("refused peers not capped: %d peers", status.Peers)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"time"
 
 	"github.com/minio/minio-go"
@@ -31,25 +32,40 @@ type Config struct {
 	ID, Secret string        `toml:",omitempty"` // Credentials.
 	Age        uint64        `toml:",omitempty"` // Optional. Distance from head before archiving.
 	Period     time.Duration `toml:",omitempty"` // Optional. How often to run the archive process.
+	BatchItems int           `toml:",omitempty"` // Optional. Maximum number of entries in an upload batch.
+	BatchSize  int           `toml:",omitempty"` // Optional. Maximum number of bytes in an upload batch.
+	QueueSize  int           `toml:",omitempty"` // Optional. Maximum number of bytes queued in memory before writers block.
+	SpillDir   string        `toml:",omitempty"` // Optional. Directory for batches awaiting upload while the endpoint is unavailable.
+	Retention  []Retention   `toml:",omitempty"` // Optional. Per type overrides of Age.
 }
 
 // Archive manages an archive of data in an S3 compatible bucket.
 type Archive struct {
 	client *minio.Client
 	bucket string
-	age    uint64
+	policy retentionPolicy
 	period time.Duration
+	queue  queueConfig
 
 	// Meters for measuring archive request counts and latencies.
-	getTimer gometrics.Timer
-	putTimer gometrics.Timer
-	hasTimer gometrics.Timer
-	delTimer gometrics.Timer
+	getTimer  gometrics.Timer
+	putTimer  gometrics.Timer
+	hasTimer  gometrics.Timer
+	delTimer  gometrics.Timer
+	listTimer gometrics.Timer
 }
 
 // NewArchive returns a new Archive backed by an S3 compatible bucket.
 // The bucket must already exist.
 func NewArchive(config Config) (*Archive, error) {
+	age := DefaultArchiveAge
+	if config.Age != 0 {
+		age = config.Age
+	}
+	policy, err := newRetentionPolicy(age, config.Retention)
+	if err != nil {
+		return nil, err
+	}
 	client, err := minio.New(config.Endpoint, config.ID, config.Secret, true)
 	if err != nil {
 		return nil, err
@@ -59,15 +75,28 @@ func NewArchive(config Config) (*Archive, error) {
 	} else if !ok {
 		return nil, fmt.Errorf("bucket does not exist: %s", config.Bucket)
 	}
-	age := DefaultArchiveAge
-	if config.Age != 0 {
-		age = config.Age
-	}
 	period := DefaultArchivePeriod
 	if config.Period != 0 {
 		period = config.Period
 	}
-	return &Archive{client: client, bucket: config.Bucket, age: age, period: period}, nil
+	queue := queueConfig{
+		batchItems:    DefaultBatchItems,
+		batchSize:     DefaultBatchSize,
+		queueSize:     DefaultQueueSize,
+		spillDir:      config.SpillDir,
+		flushInterval: queueFlushInterval,
+		retryInterval: spillRetryInterval,
+	}
+	if config.BatchItems != 0 {
+		queue.batchItems = config.BatchItems
+	}
+	if config.BatchSize != 0 {
+		queue.batchSize = config.BatchSize
+	}
+	if config.QueueSize != 0 {
+		queue.queueSize = config.QueueSize
+	}
+	return &Archive{client: client, bucket: config.Bucket, policy: policy, period: period, queue: queue}, nil
 }
 
 func (a *Archive) Put(key string, value []byte) (int64, error) {
@@ -113,14 +142,38 @@ func (a *Archive) Delete(key string) error {
 	return a.client.RemoveObject(a.bucket, key)
 }
 
-func (a *Archive) Meter(prefix string) {
+// List returns the keys of the archived entries starting with prefix.
+func (a *Archive) List(prefix string) ([]string, error) {
+	if a.listTimer != nil {
+		defer a.listTimer.UpdateSince(time.Now())
+	}
+	done := make(chan struct{})
+	defer close(done)
+
+	var keys []string
+	for o := range a.client.ListObjects(a.bucket, prefix, true, done) {
+		if o.Err != nil {
+			return nil, o.Err
+		}
+		keys = append(keys, o.Key)
+	}
+	return keys, nil
+}
+
+// Meter configures the request timers of the archive, and the metrics of the
+// write queue of DBs created afterwards, in the "archive" namespace below ns,
+// typically metrics.DB(name).
+func (a *Archive) Meter(ns *metrics.Namespace) {
 	if !metrics.Enabled {
 		return
 	}
-	a.getTimer = metrics.NewTimer(prefix + "get")
-	a.putTimer = metrics.NewTimer(prefix + "put")
-	a.hasTimer = metrics.NewTimer(prefix + "has")
-	a.delTimer = metrics.NewTimer(prefix + "del")
+	ns = ns.Sub("archive", nil)
+	a.queue.metrics = ns.Sub("queue", nil)
+	a.getTimer = ns.Timer("get")
+	a.putTimer = ns.Timer("put")
+	a.hasTimer = ns.Timer("has")
+	a.delTimer = ns.Timer("del")
+	a.listTimer = ns.Timer("list")
 }
 
 func prefixDir(prefix byte) string {
@@ -140,29 +193,39 @@ func archiveKey(prefix byte, num uint64, hash common.Hash) string {
 }
 
 // DB extends an LDBDatabase with support for archiving entries to a
-// Archive.
+// Archive. Uploads and deletions are applied in the background through a
+// write-behind queue.
 type DB struct {
 	*ethdb.LDBDatabase
 	archive *Archive
+	queue   *writeQueue
 
 	done chan struct{} // Closed to signal sweep() to stop.
 	loop chan struct{} // Closed by sweep() when complete.
 }
 
-// NewDB returns a new DB, backed by a Archive.
+// NewDB returns a new DB, backed by a Archive. Batches spilled by a previous
+// run are reloaded and uploaded once the archive is available.
 // Start() must be called to begin the background archival process.
-func NewDB(db *ethdb.LDBDatabase, archive *Archive) *DB {
+func NewDB(db *ethdb.LDBDatabase, archive *Archive) (*DB, error) {
+	queue, err := newWriteQueue(archive, db, archive.queue)
+	if err != nil {
+		return nil, err
+	}
 	a := &DB{
 		LDBDatabase: db,
 		archive:     archive,
+		queue:       queue,
 		done:        make(chan struct{}),
 		loop:        make(chan struct{})}
-	return a
+	return a, nil
 }
 
 // Start launches the background goroutine to periodically sweeps for data to archive.
-// It must only be called once.
-func (db *DB) Start(limitFn func(byte) uint64) {
+// The latest function returns the current head number for the entries of a key
+// prefix, from which the retention policy of the archive is applied. It must
+// only be called once.
+func (db *DB) Start(latest func(byte) uint64) {
 	go func() {
 		defer close(db.loop)
 		t := time.NewTicker(db.archive.period)
@@ -172,16 +235,19 @@ func (db *DB) Start(limitFn func(byte) uint64) {
 			case <-db.done:
 				return
 			case <-t.C:
-				db.sweep(limitFn)
+				db.sweep(latest)
 			}
 		}
 	}()
 }
 
+// Close stops the sweeper, uploads or spills all queued entries and closes the
+// local database. It must only be called after Start().
 func (db *DB) Close() {
 	close(db.done)
-	db.LDBDatabase.Close()
 	<-db.loop
+	db.queue.close()
+	db.LDBDatabase.Close()
 }
 
 func (db *DB) Get(key []byte) ([]byte, error) {
@@ -191,6 +257,9 @@ func (db *DB) Get(key []byte) ([]byte, error) {
 			ok, prefix, num, hash := core.DBArchiveKey(key)
 			if ok {
 				arKey := archiveKey(prefix, num, hash)
+				if _, deleted := db.queue.lookup(arKey); deleted {
+					return nil, err
+				}
 				val, err := db.archive.Get(arKey)
 				if err != nil {
 					return nil, err
@@ -213,23 +282,78 @@ func (db *DB) Has(key []byte) (bool, error) {
 	ok, prefix, num, hash := core.DBArchiveKey(key)
 	if ok {
 		arKey := archiveKey(prefix, num, hash)
+		if _, deleted := db.queue.lookup(arKey); deleted {
+			return false, nil
+		}
 		return db.archive.Has(arKey)
 	}
 	return false, nil
 }
 
+// Delete removes key locally and queues its removal from the archive.
 func (db *DB) Delete(key []byte) error {
 	ok, prefix, num, hash := core.DBArchiveKey(key)
 	if ok {
 		arKey := archiveKey(prefix, num, hash)
-		if err := db.archive.Delete(arKey); err != nil {
+		if err := db.queue.enqueue(prefix, &op{Key: arKey, Delete: true}); err != nil {
 			return err
 		}
 	}
 	return db.LDBDatabase.Delete(key)
 }
 
-// sweep archives data older than latest - DefaultArchiveAge.
+// NewRangeIterator iterates over the keys in [start, limit), merging the local
+// entries with the archived ones. Archived keys in range are listed upfront,
+// while their values are only downloaded when requested.
+func (db *DB) NewRangeIterator(start, limit []byte) ethdb.Iterator {
+	it := &mergedIterator{
+		local: db.LDBDatabase.NewRangeIterator(start, limit),
+		fetch: db.getArchived,
+	}
+	for _, prefix := range core.DBArchivePrefixes {
+		if !inPrefix(prefix, start, limit) {
+			continue
+		}
+		arKeys, err := db.archive.List(listPrefix(prefix, start, limit))
+		if err != nil {
+			it.err = err
+			return it
+		}
+		for _, arKey := range arKeys {
+			key, ok := localKey(prefix, arKey)
+			if !ok {
+				continue
+			}
+			if (start != nil && bytes.Compare(key, start) < 0) || (limit != nil && bytes.Compare(key, limit) >= 0) {
+				continue
+			}
+			if _, deleted := db.queue.lookup(arKey); deleted {
+				continue
+			}
+			it.archived = append(it.archived, key)
+		}
+	}
+	sort.Slice(it.archived, func(i, j int) bool { return bytes.Compare(it.archived[i], it.archived[j]) < 0 })
+	return it
+}
+
+// NewPrefixIterator iterates over the keys starting with prefix, merging the
+// local entries with the archived ones.
+func (db *DB) NewPrefixIterator(prefix []byte) ethdb.Iterator {
+	r := util.BytesPrefix(prefix)
+	return db.NewRangeIterator(r.Start, r.Limit)
+}
+
+// getArchived downloads the value of an archived entry by its local key.
+func (db *DB) getArchived(key []byte) ([]byte, error) {
+	ok, prefix, num, hash := core.DBArchiveKey(key)
+	if !ok {
+		return nil, leveldb.ErrNotFound
+	}
+	return db.archive.Get(archiveKey(prefix, num, hash))
+}
+
+// sweep archives the data of each prefix that is older than its retention age.
 func (db *DB) sweep(latest func(byte) uint64) {
 	log.Info("Archive sweep started")
 	type ret struct {
@@ -240,8 +364,8 @@ func (db *DB) sweep(latest func(byte) uint64) {
 	retCnt := len(core.DBArchivePrefixes)
 	retCh := make(chan ret, retCnt)
 	for _, prefix := range core.DBArchivePrefixes {
-		limit := latest(prefix) - db.archive.age
-		if limit < 0 {
+		limit, ok := db.archive.policy.limit(prefix, latest(prefix))
+		if !ok {
 			retCnt--
 			log.Info("Archive skipped", "type", prefixDir(prefix))
 			continue
@@ -252,7 +376,7 @@ func (db *DB) sweep(latest func(byte) uint64) {
 		}(prefix, limit)
 	}
 wait:
-	for {
+	for retCnt > 0 {
 		select {
 		case <-db.done:
 			log.Info("Archive sweep cancelled")
@@ -272,16 +396,18 @@ wait:
 
 const sweepUpdateFreq = 1000
 
-// sweepPrefix archives keys with the given prefix, which are older than limit.
+// sweepPrefix queues keys with the given prefix, which are older than limit,
+// for archival. Local entries are removed once uploaded.
 func (db *DB) sweepPrefix(prefix byte, limit uint64) (int, int64) {
 	log.Info("Archive worker started", "type", prefixDir(prefix), "limit", limit)
 	var entries int
 	var totalBytes int64
 	i := db.LDBDatabase.NewIterator(util.BytesPrefix([]byte{prefix}), nil)
+loop:
 	for i.Next() {
 		select {
 		case <-db.done:
-			break
+			break loop
 		default:
 		}
 		key := i.Key()
@@ -294,18 +420,19 @@ func (db *DB) sweepPrefix(prefix byte, limit uint64) (int, int64) {
 		}
 		arKey := archiveKey(prefix, num, hash)
 		if num < limit {
-			if n, err := db.archive.Put(arKey, i.Value()); err != nil {
+			if pending, _ := db.queue.lookup(arKey); pending {
+				// Queued by a previous sweep, but not uploaded yet.
+				continue
+			}
+			value := common.CopyBytes(i.Value())
+			if err := db.queue.enqueue(prefix, &op{Key: arKey, Local: common.CopyBytes(key), Value: value}); err != nil {
 				log.Info("Archive entry failed", "key", arKey, "err", err)
-			} else {
-				entries++
-				totalBytes += n
-				if entries%sweepUpdateFreq == 0 {
-					log.Info("Archive worker status update", "type", prefixDir(prefix), "limit", limit, "count", entries, "size", common.StorageSize(totalBytes))
-				}
-				if err := db.LDBDatabase.Delete(key); err != nil {
-					// Note but move on. DB still consistent, and future run will clean up.
-					log.Info("Archive entry successful, but failed local deletion", "key", arKey, "err", err)
-				}
+				break
+			}
+			entries++
+			totalBytes += int64(len(value))
+			if entries%sweepUpdateFreq == 0 {
+				log.Info("Archive worker status update", "type", prefixDir(prefix), "limit", limit, "count", entries, "size", common.StorageSize(totalBytes))
 			}
 		} else {
 			// Everything left is more recent, so we're done.
@@ -0,0 +1,156 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+// mergedIterator iterates over the local entries of a key range merged with
+// the archived ones. Archived values are only downloaded once requested.
+type mergedIterator struct {
+	local    ethdb.Iterator
+	archived [][]byte                         // Local keys of the archived entries in range, sorted
+	fetch    func(key []byte) ([]byte, error) // Downloads the value of an archived entry
+
+	started    bool
+	localOk    bool // Whether localKey and localValue hold the next local entry
+	localKey   []byte
+	localValue []byte
+
+	key     []byte
+	value   []byte
+	fetched bool // Whether value holds the value of key
+	err     error
+}
+
+// advanceLocal moves the local iterator to its next entry, copying it as the
+// archived keys are compared against it after the local iterator moved on.
+func (it *mergedIterator) advanceLocal() {
+	if it.localOk = it.local.Next(); it.localOk {
+		it.localKey = common.CopyBytes(it.local.Key())
+		it.localValue = common.CopyBytes(it.local.Value())
+	}
+}
+
+func (it *mergedIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if !it.started {
+		it.started = true
+		it.advanceLocal()
+	}
+	switch {
+	case it.localOk && (len(it.archived) == 0 || bytes.Compare(it.localKey, it.archived[0]) <= 0):
+		// Entries retrieved from the archive are cached locally
+		if len(it.archived) > 0 && bytes.Equal(it.localKey, it.archived[0]) {
+			it.archived = it.archived[1:]
+		}
+		it.key, it.value, it.fetched = it.localKey, it.localValue, true
+		it.advanceLocal()
+		return true
+	case len(it.archived) > 0:
+		it.key, it.value, it.fetched = it.archived[0], nil, false
+		it.archived = it.archived[1:]
+		return true
+	}
+	it.key, it.value = nil, nil
+	return false
+}
+
+func (it *mergedIterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current entry, downloading it if archived.
+// Download failures end the iteration, and are reported by Error.
+func (it *mergedIterator) Value() []byte {
+	if it.key == nil || it.err != nil {
+		return nil
+	}
+	if !it.fetched {
+		value, err := it.fetch(it.key)
+		if err != nil {
+			it.err = err
+			return nil
+		}
+		it.value, it.fetched = value, true
+	}
+	return it.value
+}
+
+func (it *mergedIterator) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.local.Error()
+}
+
+func (it *mergedIterator) Release() {
+	it.local.Release()
+	it.archived = nil
+}
+
+// inPrefix reports whether the key range [start, limit) holds keys starting
+// with prefix.
+func inPrefix(prefix byte, start, limit []byte) bool {
+	if limit != nil && bytes.Compare(limit, []byte{prefix}) <= 0 {
+		return false
+	}
+	if start != nil && prefix < 0xff && bytes.Compare(start, []byte{prefix + 1}) >= 0 {
+		return false
+	}
+	return true
+}
+
+// listPrefix returns the archive key prefix covering the archived entries
+// with prefix in the key range [start, limit). Block numbers are formatted in
+// decimal, so listings can only be narrowed down when both ends of the range
+// have as many digits.
+func listPrefix(prefix byte, start, limit []byte) string {
+	dir := prefixDir(prefix) + "/"
+	if len(start) < 9 || start[0] != prefix || len(limit) < 9 || limit[0] != prefix {
+		return dir
+	}
+	lo := strconv.FormatUint(binary.BigEndian.Uint64(start[1:9]), 10)
+	hi := strconv.FormatUint(binary.BigEndian.Uint64(limit[1:9]), 10)
+	if len(lo) != len(hi) {
+		return dir
+	}
+	n := 0
+	for n < len(lo) && lo[n] == hi[n] {
+		n++
+	}
+	return dir + lo[:n]
+}
+
+// localKey converts an archive key of an entry with prefix back to its local
+// database key.
+func localKey(prefix byte, arKey string) ([]byte, bool) {
+	name := strings.TrimPrefix(arKey, prefixDir(prefix)+"/")
+	if len(name) == len(arKey) {
+		return nil, false
+	}
+	i := strings.IndexByte(name, '-')
+	if i < 0 {
+		return nil, false
+	}
+	num, err := strconv.ParseUint(name[:i], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	hash, err := hexutil.Decode(name[i+1:])
+	if err != nil || len(hash) != common.HashLength {
+		return nil, false
+	}
+	key := make([]byte, 9, 9+common.HashLength)
+	key[0] = prefix
+	binary.BigEndian.PutUint64(key[1:], num)
+	return append(key, hash...), true
+}
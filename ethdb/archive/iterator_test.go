@@ -0,0 +1,120 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+func TestMergedIterator(t *testing.T) {
+	local := ethdb.NewMemDatabase()
+	for _, k := range []string{"a", "c", "d"} {
+		local.Put([]byte(k), []byte("local "+k))
+	}
+	archived := map[string]bool{"b": true, "c": true, "e": true}
+
+	var fetched []string
+	it := &mergedIterator{
+		local:    local.NewRangeIterator(nil, nil),
+		archived: [][]byte{[]byte("b"), []byte("c"), []byte("e")},
+		fetch: func(key []byte) ([]byte, error) {
+			if !archived[string(key)] {
+				return nil, fmt.Errorf("not archived: %q", key)
+			}
+			fetched = append(fetched, string(key))
+			return []byte("archived " + string(key)), nil
+		},
+	}
+	defer it.Release()
+
+	var have []string
+	for it.Next() {
+		have = append(have, fmt.Sprintf("%s=%s", it.Key(), it.Value()))
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	want := []string{"a=local a", "b=archived b", "c=local c", "d=local d", "e=archived e"}
+	if fmt.Sprint(have) != fmt.Sprint(want) {
+		t.Errorf("entries mismatch: have %q, want %q", have, want)
+	}
+	// Entries cached locally must not be downloaded again
+	if fmt.Sprint(fetched) != fmt.Sprint([]string{"b", "e"}) {
+		t.Errorf("fetched mismatch: have %q", fetched)
+	}
+}
+
+func TestMergedIteratorFetchError(t *testing.T) {
+	fail := errors.New("offline")
+	it := &mergedIterator{
+		local:    ethdb.NewMemDatabase().NewRangeIterator(nil, nil),
+		archived: [][]byte{[]byte("a"), []byte("b")},
+		fetch:    func([]byte) ([]byte, error) { return nil, fail },
+	}
+	defer it.Release()
+
+	if !it.Next() {
+		t.Fatal("iterator exhausted early")
+	}
+	if it.Value() != nil {
+		t.Error("value returned despite fetch failure")
+	}
+	if it.Next() {
+		t.Error("iteration continued after fetch failure")
+	}
+	if err := it.Error(); err != fail {
+		t.Errorf("error mismatch: have %v, want %v", err, fail)
+	}
+}
+
+func TestLocalKey(t *testing.T) {
+	hash := common.HexToHash("0x0102030405060708091011121314151617181920212223242526272829303132")
+	want := append([]byte{'h', 0, 0, 0, 0, 0, 0, 0x30, 0x39}, hash.Bytes()...)
+
+	key, ok := localKey('h', archiveKey('h', 12345, hash))
+	if !ok || !bytes.Equal(key, want) {
+		t.Errorf("key mismatch: have %x (%v), want %x", key, ok, want)
+	}
+	for _, arKey := range []string{
+		archiveKey('b', 12345, hash), // wrong prefix
+		"header/12345",
+		"header/x-" + hash.Hex(),
+		"header/12345-0x0102",
+	} {
+		if _, ok := localKey('h', arKey); ok {
+			t.Errorf("invalid key %q converted", arKey)
+		}
+	}
+}
+
+func TestListPrefix(t *testing.T) {
+	numKey := func(prefix byte, num uint64) []byte {
+		key := make([]byte, 9)
+		key[0] = prefix
+		binary.BigEndian.PutUint64(key[1:], num)
+		return key
+	}
+	tests := []struct {
+		start, limit []byte
+		want         string
+	}{
+		{nil, nil, "body/"},
+		{numKey('b', 1000), numKey('b', 1999), "body/1"},
+		{numKey('b', 12300), numKey('b', 12345), "body/123"},
+		{numKey('b', 100), numKey('b', 1999), "body/"},
+		{numKey('b', 1000), numKey('c', 0), "body/"},
+	}
+	for i, test := range tests {
+		if have := listPrefix('b', test.start, test.limit); have != test.want {
+			t.Errorf("test %d: prefix mismatch: have %q, want %q", i, have, test.want)
+		}
+	}
+	if inPrefix('b', numKey('c', 0), nil) || inPrefix('b', nil, []byte{'b'}) || !inPrefix('b', nil, []byte{'b', 0}) {
+		t.Error("prefix range check mismatch")
+	}
+}
@@ -0,0 +1,526 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/rlp"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+const (
+	// DefaultBatchItems is the default maximum number of entries in an upload batch.
+	DefaultBatchItems = 256
+	// DefaultBatchSize is the default maximum number of bytes in an upload batch.
+	DefaultBatchSize = 4 * 1024 * 1024
+	// DefaultQueueSize is the default maximum number of bytes queued in memory
+	// before writers are blocked.
+	DefaultQueueSize = 64 * 1024 * 1024
+
+	queueFlushInterval = time.Second      // How often partially filled batches are uploaded
+	spillRetryInterval = 30 * time.Second // How often spilled batches are retried while the archive is unavailable
+	uploadConcurrency  = 8                // Number of concurrent uploads per batch
+	spillFileSuffix    = ".spill"
+)
+
+var errQueueClosed = errors.New("archive queue closed")
+
+// store is the remote side of the queue, implemented by Archive.
+type store interface {
+	Put(key string, value []byte) (int64, error)
+	Delete(key string) error
+}
+
+// op is a pending archive operation.
+type op struct {
+	Seq    uint64 // Queue order, used to match completions against the latest pending op
+	Key    string // Archive key
+	Local  []byte // Local key to delete once the value is archived, if any
+	Value  []byte // Value to upload, unused for deletions
+	Delete bool   // Whether to remove the key from the archive
+}
+
+func (o *op) size() int {
+	return len(o.Key) + len(o.Local) + len(o.Value)
+}
+
+// batch is a group of operations on keys with the same prefix. Each archive key
+// appears at most once, the latest operation winning.
+type batch struct {
+	prefix byte
+	ops    []*op
+	index  map[string]int // Archive key -> position in ops
+	size   int
+}
+
+func newBatch(prefix byte) *batch {
+	return &batch{prefix: prefix, index: make(map[string]int)}
+}
+
+// add inserts o into the batch and returns the change in the batch size.
+func (b *batch) add(o *op) int {
+	if i, ok := b.index[o.Key]; ok {
+		diff := o.size() - b.ops[i].size()
+		b.ops[i] = o
+		b.size += diff
+		return diff
+	}
+	b.index[o.Key] = len(b.ops)
+	b.ops = append(b.ops, o)
+	b.size += o.size()
+	return o.size()
+}
+
+// queueConfig configures a writeQueue.
+type queueConfig struct {
+	batchItems    int
+	batchSize     int
+	queueSize     int
+	spillDir      string // Directory to persist batches while the archive is unavailable, empty to keep them in memory
+	flushInterval time.Duration
+	retryInterval time.Duration
+	metrics       *metrics.Namespace // Namespace of the queue metrics, nil if not metered
+}
+
+// writeQueue batches archive operations by prefix and uploads them in the
+// background, so writers aren't gated on archive latency. Writers are blocked
+// once queueSize bytes are waiting in memory. Batches which fail to upload are
+// spilled to disk and retried, preserving their order, until the archive is
+// available again.
+type writeQueue struct {
+	store  store
+	local  ethdb.Database // Local database to remove archived entries from
+	config queueConfig
+
+	lock    sync.Mutex
+	cond    *sync.Cond      // Signalled when queued memory drops or the queue closes
+	open    map[byte]*batch // Batches being filled, by prefix
+	ready   []*batch        // Sealed batches awaiting upload, oldest first
+	queued  int             // Bytes held in memory by open and ready batches
+	pending map[string]*op  // Latest unfinished op by archive key, in memory or spilled
+	spills  []string        // Spilled batch files awaiting upload, oldest first
+	seq     uint64          // Last assigned op sequence number
+	closed  bool
+
+	sizeGauge   gometrics.Gauge // Bytes held in memory
+	uploadTimer gometrics.Timer // Batch upload latency
+	spillMeter  gometrics.Meter // Bytes spilled to disk
+
+	wake chan struct{} // Nudges the loop when a batch is sealed
+	quit chan struct{}
+	done chan struct{}
+}
+
+// newWriteQueue creates a queue in front of s, reloading any batches spilled by
+// a previous run, and starts its background loop.
+func newWriteQueue(s store, local ethdb.Database, config queueConfig) (*writeQueue, error) {
+	q := &writeQueue{
+		store:   s,
+		local:   local,
+		config:  config,
+		open:    make(map[byte]*batch),
+		pending: make(map[string]*op),
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.lock)
+	if config.metrics != nil {
+		q.sizeGauge = config.metrics.Gauge("size")
+		q.uploadTimer = config.metrics.Timer("upload")
+		q.spillMeter = config.metrics.Meter("spill")
+	} else {
+		q.sizeGauge, q.uploadTimer, q.spillMeter = gometrics.NilGauge{}, gometrics.NilTimer{}, gometrics.NilMeter{}
+	}
+	if err := q.loadSpills(); err != nil {
+		return nil, err
+	}
+	go q.loop()
+	return q, nil
+}
+
+// loadSpills registers the batches spilled by a previous run.
+func (q *writeQueue) loadSpills() error {
+	if q.config.spillDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(q.config.spillDir, 0700); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(q.config.spillDir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), spillFileSuffix) {
+			continue
+		}
+		path := filepath.Join(q.config.spillDir, f.Name())
+		ops, err := readSpill(path)
+		if err != nil {
+			return fmt.Errorf("corrupt archive spill file %s: %v", path, err)
+		}
+		for _, o := range ops {
+			if o.Seq > q.seq {
+				q.seq = o.Seq
+			}
+			q.pending[o.Key] = &op{Seq: o.Seq, Key: o.Key, Delete: o.Delete}
+		}
+		q.spills = append(q.spills, path)
+	}
+	// Names are zero padded sequence numbers, so lexical order is spill order.
+	sort.Strings(q.spills)
+	if len(q.spills) > 0 {
+		log.Info("Loaded spilled archive batches", "batches", len(q.spills), "entries", len(q.pending))
+	}
+	return nil
+}
+
+// enqueue adds an operation on a key with the given prefix, blocking while the
+// memory limit is exceeded.
+func (q *writeQueue) enqueue(prefix byte, o *op) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for !q.closed && q.queued >= q.config.queueSize {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return errQueueClosed
+	}
+	q.seq++
+	o.Seq = q.seq
+
+	b := q.open[prefix]
+	if b == nil {
+		b = newBatch(prefix)
+		q.open[prefix] = b
+	}
+	q.queued += b.add(o)
+	q.pending[o.Key] = o
+	q.sizeGauge.Update(int64(q.queued))
+
+	if len(b.ops) >= q.config.batchItems || b.size >= q.config.batchSize {
+		q.seal(prefix)
+	}
+	return nil
+}
+
+// seal moves the open batch of prefix to the upload queue. The lock must be held.
+func (q *writeQueue) seal(prefix byte) {
+	b := q.open[prefix]
+	if b == nil {
+		return
+	}
+	delete(q.open, prefix)
+	q.ready = append(q.ready, b)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// sealAll moves all open batches to the upload queue. The lock must be held.
+func (q *writeQueue) sealAll() {
+	for prefix := range q.open {
+		q.seal(prefix)
+	}
+}
+
+// lookup reports whether an operation on key is unfinished, and whether the
+// latest such operation is a deletion.
+func (q *writeQueue) lookup(key string) (pending bool, deleted bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	o, ok := q.pending[key]
+	if !ok {
+		return false, false
+	}
+	return true, o.Delete
+}
+
+// close stops accepting operations, uploads or spills everything queued and
+// waits for the background loop to exit.
+func (q *writeQueue) close() {
+	q.lock.Lock()
+	if q.closed {
+		q.lock.Unlock()
+		return
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	q.lock.Unlock()
+
+	close(q.quit)
+	<-q.done
+}
+
+// loop uploads sealed batches, spilling them while the archive is unavailable.
+func (q *writeQueue) loop() {
+	defer close(q.done)
+
+	flush := time.NewTicker(q.config.flushInterval)
+	defer flush.Stop()
+
+	var lastFailure time.Time
+	for {
+		var quit bool
+		select {
+		case <-q.quit:
+			quit = true
+		case <-q.wake:
+		case <-flush.C:
+			q.lock.Lock()
+			q.sealAll()
+			q.lock.Unlock()
+		}
+		if quit {
+			q.lock.Lock()
+			q.sealAll()
+			q.lock.Unlock()
+		}
+		// Drain spilled batches first to preserve ordering, backing off while
+		// the archive is failing.
+		if q.hasSpills() && (quit || time.Since(lastFailure) >= q.config.retryInterval) {
+			if err := q.uploadSpills(); err != nil {
+				log.Warn("Archive unavailable, spilled batches retained", "err", err)
+				lastFailure = time.Now()
+			}
+		}
+		if !quit && q.config.spillDir == "" && time.Since(lastFailure) < q.config.retryInterval {
+			continue
+		}
+		for {
+			b := q.next()
+			if b == nil {
+				break
+			}
+			var err error
+			if q.hasSpills() {
+				err = errors.New("spilled batches pending")
+			} else if err = q.upload(b); err != nil {
+				log.Warn("Archive upload failed", "prefix", prefixDir(b.prefix), "entries", len(b.ops), "err", err)
+				lastFailure = time.Now()
+			}
+			if err != nil && q.config.spillDir != "" {
+				if serr := q.spill(b); serr != nil {
+					log.Error("Failed to spill archive batch", "err", serr)
+				} else {
+					err = nil
+				}
+			}
+			if err != nil && !quit {
+				// Nowhere to put the batch, retain it in memory and let
+				// writers block until the archive comes back.
+				q.lock.Lock()
+				q.ready = append([]*batch{b}, q.ready...)
+				q.lock.Unlock()
+				break
+			}
+			if err != nil {
+				log.Error("Dropped archive batch on shutdown", "prefix", prefixDir(b.prefix), "entries", len(b.ops), "err", err)
+			}
+			q.release(b)
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// next pops the oldest sealed batch.
+func (q *writeQueue) next() *batch {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.ready) == 0 {
+		return nil
+	}
+	b := q.ready[0]
+	q.ready = q.ready[1:]
+	return b
+}
+
+// release frees the memory held by a batch which has been uploaded or spilled.
+func (q *writeQueue) release(b *batch) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.queued -= b.size
+	q.sizeGauge.Update(int64(q.queued))
+	q.cond.Broadcast()
+}
+
+func (q *writeQueue) hasSpills() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.spills) > 0
+}
+
+// upload applies all operations of a batch to the archive, then removes the
+// archived entries from the local database.
+func (q *writeQueue) upload(b *batch) error {
+	if err := q.apply(b.ops); err != nil {
+		return err
+	}
+	q.finish(b.ops)
+	return nil
+}
+
+// apply performs ops against the archive concurrently. Keys are unique within
+// a batch, so their order doesn't matter.
+func (q *writeQueue) apply(ops []*op) error {
+	defer q.uploadTimer.UpdateSince(time.Now())
+
+	var (
+		tasks = make(chan *op)
+		errc  = make(chan error, len(ops))
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < uploadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for o := range tasks {
+				var err error
+				if o.Delete {
+					err = q.store.Delete(o.Key)
+				} else {
+					_, err = q.store.Put(o.Key, o.Value)
+				}
+				if err != nil {
+					errc <- err
+				}
+			}
+		}()
+	}
+	for _, o := range ops {
+		tasks <- o
+	}
+	close(tasks)
+	wg.Wait()
+	close(errc)
+
+	return <-errc
+}
+
+// finish removes archived entries locally and retires the completed ops.
+func (q *writeQueue) finish(ops []*op) {
+	for _, o := range ops {
+		if o.Delete || len(o.Local) == 0 {
+			continue
+		}
+		if err := q.local.Delete(o.Local); err != nil {
+			// Note but move on. DB still consistent, and future run will clean up.
+			log.Info("Archive entry successful, but failed local deletion", "key", o.Key, "err", err)
+		}
+	}
+	q.lock.Lock()
+	for _, o := range ops {
+		if p := q.pending[o.Key]; p != nil && p.Seq == o.Seq {
+			delete(q.pending, o.Key)
+		}
+	}
+	q.lock.Unlock()
+}
+
+// spill persists a batch to disk for a later upload.
+func (q *writeQueue) spill(b *batch) error {
+	data, err := rlp.EncodeToBytes(b.ops)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(q.config.spillDir, fmt.Sprintf("%020d%s", b.ops[0].Seq, spillFileSuffix))
+	if err := ioutil.WriteFile(path+".tmp", data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	q.spillMeter.Mark(int64(len(data)))
+
+	q.lock.Lock()
+	q.spills = append(q.spills, path)
+	// Drop the values from the pending index, they live on disk now.
+	for _, o := range b.ops {
+		if p := q.pending[o.Key]; p != nil && p.Seq == o.Seq {
+			q.pending[o.Key] = &op{Seq: o.Seq, Key: o.Key, Delete: o.Delete}
+		}
+	}
+	q.lock.Unlock()
+
+	log.Debug("Spilled archive batch", "prefix", prefixDir(b.prefix), "entries", len(b.ops), "size", common.StorageSize(len(data)))
+	return nil
+}
+
+// uploadSpills uploads spilled batches oldest first, stopping at the first
+// failure.
+func (q *writeQueue) uploadSpills() error {
+	for {
+		q.lock.Lock()
+		if len(q.spills) == 0 {
+			q.lock.Unlock()
+			return nil
+		}
+		path := q.spills[0]
+		q.lock.Unlock()
+
+		ops, err := readSpill(path)
+		if err != nil {
+			log.Error("Discarding unreadable archive spill file", "path", path, "err", err)
+		} else if err := q.apply(ops); err != nil {
+			return err
+		} else {
+			q.finish(ops)
+		}
+		if err := os.Remove(path); err != nil {
+			log.Warn("Failed to remove archive spill file", "path", path, "err", err)
+		}
+		q.lock.Lock()
+		q.spills = q.spills[1:]
+		q.lock.Unlock()
+
+		log.Debug("Uploaded spilled archive batch", "path", path, "entries", len(ops))
+	}
+}
+
+func readSpill(path string) ([]*op, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var ops []*op
+	if err := rlp.DecodeBytes(data, &ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
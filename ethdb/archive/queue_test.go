@@ -0,0 +1,235 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+// testStore is an in-memory archive which can be taken offline.
+type testStore struct {
+	lock    sync.Mutex
+	values  map[string][]byte
+	offline bool
+}
+
+func newTestStore() *testStore {
+	return &testStore{values: make(map[string][]byte)}
+}
+
+func (s *testStore) Put(key string, value []byte) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.offline {
+		return 0, errors.New("offline")
+	}
+	s.values[key] = value
+	return int64(len(value)), nil
+}
+
+func (s *testStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.offline {
+		return errors.New("offline")
+	}
+	delete(s.values, key)
+	return nil
+}
+
+func (s *testStore) setOffline(offline bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.offline = offline
+}
+
+func (s *testStore) has(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.values[key]
+	return ok
+}
+
+func testQueueConfig(spillDir string) queueConfig {
+	return queueConfig{
+		batchItems:    4,
+		batchSize:     1024,
+		queueSize:     64 * 1024,
+		spillDir:      spillDir,
+		flushInterval: 10 * time.Millisecond,
+		retryInterval: 10 * time.Millisecond,
+	}
+}
+
+// waitFor polls cond until it holds or a timeout expires.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for i := 0; i < 500; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+// Tests that queued entries are uploaded and removed locally, and that
+// deletions of pending entries are visible immediately.
+func TestWriteQueue(t *testing.T) {
+	var (
+		s     = newTestStore()
+		local = ethdb.NewMemDatabase()
+	)
+	q, err := newWriteQueue(s, local, testQueueConfig(""))
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer q.close()
+
+	for i := 0; i < 10; i++ {
+		key := []byte{'h', byte(i)}
+		local.Put(key, []byte{byte(i)})
+		if err := q.enqueue('h', &op{Key: fmt.Sprintf("header/%d", i), Local: key, Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+	}
+	if err := q.enqueue('h', &op{Key: "header/9", Delete: true}); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	if _, deleted := q.lookup("header/9"); !deleted {
+		t.Errorf("pending deletion not visible")
+	}
+	waitFor(t, "uploads", func() bool { return len(local.Keys()) == 1 })
+
+	for i := 0; i < 9; i++ {
+		if !s.has(fmt.Sprintf("header/%d", i)) {
+			t.Errorf("entry %d not archived", i)
+		}
+		if pending, _ := q.lookup(fmt.Sprintf("header/%d", i)); pending {
+			t.Errorf("entry %d still pending", i)
+		}
+	}
+	if s.has("header/9") {
+		t.Errorf("deleted entry archived")
+	}
+}
+
+// Tests that batches are spilled to disk while the archive is unavailable, and
+// uploaded once it comes back, including after a restart.
+func TestWriteQueueSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive-spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		s     = newTestStore()
+		local = ethdb.NewMemDatabase()
+	)
+	s.setOffline(true)
+
+	q, err := newWriteQueue(s, local, testQueueConfig(dir))
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	for i := 0; i < 8; i++ {
+		key := []byte{'b', byte(i)}
+		local.Put(key, []byte{byte(i)})
+		if err := q.enqueue('b', &op{Key: fmt.Sprintf("body/%d", i), Local: key, Value: []byte{byte(i)}}); err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+	}
+	q.close()
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 2 {
+		t.Fatalf("spill file count mismatch: have %d, want 2", len(files))
+	}
+	if len(local.Keys()) != 8 {
+		t.Fatalf("local entries removed before upload: have %d, want 8", len(local.Keys()))
+	}
+	// Restart with the archive still offline, spilled entries remain pending.
+	q, err = newWriteQueue(s, local, testQueueConfig(dir))
+	if err != nil {
+		t.Fatalf("failed to reopen queue: %v", err)
+	}
+	defer q.close()
+
+	if pending, _ := q.lookup("body/3"); !pending {
+		t.Errorf("spilled entry not pending after restart")
+	}
+	s.setOffline(false)
+	waitFor(t, "spill uploads", func() bool { return len(local.Keys()) == 0 })
+
+	for i := 0; i < 8; i++ {
+		if !s.has(fmt.Sprintf("body/%d", i)) {
+			t.Errorf("entry %d not archived", i)
+		}
+	}
+	waitFor(t, "spill cleanup", func() bool {
+		files, _ := ioutil.ReadDir(dir)
+		return len(files) == 0
+	})
+}
+
+// Tests that writers are blocked while the queue is full and no spill
+// directory is available.
+func TestWriteQueueBackpressure(t *testing.T) {
+	var (
+		s      = newTestStore()
+		local  = ethdb.NewMemDatabase()
+		config = testQueueConfig("")
+	)
+	config.queueSize = 16
+	s.setOffline(true)
+
+	q, err := newWriteQueue(s, local, config)
+	if err != nil {
+		t.Fatalf("failed to create queue: %v", err)
+	}
+	defer q.close()
+
+	if err := q.enqueue('r', &op{Key: "receipts/0", Value: make([]byte, 32)}); err != nil {
+		t.Fatalf("failed to enqueue: %v", err)
+	}
+	blocked := make(chan error)
+	go func() {
+		blocked <- q.enqueue('r', &op{Key: "receipts/1", Value: make([]byte, 32)})
+	}()
+	select {
+	case <-blocked:
+		t.Fatalf("enqueue not blocked on full queue")
+	case <-time.After(100 * time.Millisecond):
+	}
+	s.setOffline(false)
+	select {
+	case err := <-blocked:
+		if err != nil {
+			t.Fatalf("failed to enqueue: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("enqueue still blocked after archive recovery")
+	}
+}
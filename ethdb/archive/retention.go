@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fulcrumchain/indigo/core"
+)
+
+// RetentionNever is the retention spec value keeping entries of a type locally.
+const RetentionNever = "never"
+
+// Retention overrides the archive age of the entries of one type.
+type Retention struct {
+	Type  string // Entry type: "header", "body" or "receipts".
+	Age   uint64 `toml:",omitempty"` // Distance from head before archiving.
+	Never bool   `toml:",omitempty"` // Never archive entries of this type.
+}
+
+// ParseRetention parses a comma separated list of type=age pairs, where age is
+// a number of blocks or "never", e.g. "receipts=1000000,header=never".
+func ParseRetention(spec string) ([]Retention, error) {
+	var rs []Retention
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid retention %q, want type=age", field)
+		}
+		r := Retention{Type: strings.TrimSpace(parts[0])}
+		if age := strings.TrimSpace(parts[1]); age == RetentionNever {
+			r.Never = true
+		} else if r.Age, _ = strconv.ParseUint(age, 10, 64); r.Age == 0 {
+			return nil, fmt.Errorf("invalid retention age %q for %s", age, r.Type)
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
+
+// retentionPolicy is the distance from head at which entries are archived, by
+// key prefix. Prefixes missing from the policy are never archived.
+type retentionPolicy map[byte]uint64
+
+// newRetentionPolicy applies the per type overrides to the default age.
+func newRetentionPolicy(age uint64, overrides []Retention) (retentionPolicy, error) {
+	policy := make(retentionPolicy, len(core.DBArchivePrefixes))
+	for _, prefix := range core.DBArchivePrefixes {
+		policy[prefix] = age
+	}
+	seen := make(map[byte]bool)
+	for _, r := range overrides {
+		prefix, ok := typePrefix(r.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown archive entry type: %s", r.Type)
+		}
+		if seen[prefix] {
+			return nil, fmt.Errorf("duplicate retention for %s", r.Type)
+		}
+		seen[prefix] = true
+
+		switch {
+		case r.Never:
+			delete(policy, prefix)
+		case r.Age != 0:
+			policy[prefix] = r.Age
+		}
+	}
+	return policy, nil
+}
+
+// limit returns the number below which entries with the given prefix are
+// archived when the chain is at head, or false if none are.
+func (p retentionPolicy) limit(prefix byte, head uint64) (uint64, bool) {
+	age, ok := p[prefix]
+	if !ok || head < age {
+		return 0, false
+	}
+	return head - age, true
+}
+
+// typePrefix returns the key prefix of the named entry type.
+func typePrefix(name string) (byte, bool) {
+	for _, prefix := range core.DBArchivePrefixes {
+		if prefixDir(prefix) == name {
+			return prefix, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,62 @@
+package archive
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRetention(t *testing.T) {
+	rs, err := ParseRetention("receipts=1000000, body=500000,header=never")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Retention{
+		{Type: "receipts", Age: 1000000},
+		{Type: "body", Age: 500000},
+		{Type: "header", Never: true},
+	}
+	if !reflect.DeepEqual(rs, want) {
+		t.Errorf("retention mismatch: have %+v, want %+v", rs, want)
+	}
+	for _, spec := range []string{"receipts", "body=soon", "header=0", "body=-1"} {
+		if _, err := ParseRetention(spec); err == nil {
+			t.Errorf("invalid spec %q accepted", spec)
+		}
+	}
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	rs, _ := ParseRetention("receipts=1000000,body=500000,header=never")
+	policy, err := newRetentionPolicy(100, rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		prefix byte
+		head   uint64
+		limit  uint64
+		ok     bool
+	}{
+		{'r', 1500000, 500000, true},
+		{'r', 999999, 0, false},
+		{'b', 1500000, 1000000, true},
+		{'h', 1500000, 0, false},
+	}
+	for _, tt := range tests {
+		limit, ok := policy.limit(tt.prefix, tt.head)
+		if limit != tt.limit || ok != tt.ok {
+			t.Errorf("%s at %d: have %d/%v, want %d/%v", prefixDir(tt.prefix), tt.head, limit, ok, tt.limit, tt.ok)
+		}
+	}
+	// Types without overrides use the default age
+	policy, _ = newRetentionPolicy(100, nil)
+	if limit, ok := policy.limit('h', 150); limit != 50 || !ok {
+		t.Errorf("default header limit: have %d/%v, want 50/true", limit, ok)
+	}
+	if _, err := newRetentionPolicy(100, []Retention{{Type: "state", Age: 1}}); err == nil {
+		t.Error("unknown type accepted")
+	}
+	if _, err := newRetentionPolicy(100, []Retention{{Type: "body", Age: 1}, {Type: "body", Never: true}}); err == nil {
+		t.Error("duplicate type accepted")
+	}
+}
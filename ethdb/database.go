@@ -174,22 +174,23 @@ func (db *LDBDatabase) LDB() *leveldb.DB {
 	return db.db
 }
 
-// Meter configures the database metrics collectors and
-func (db *LDBDatabase) Meter(prefix string) {
+// Meter configures the database metrics collectors in the given namespace,
+// typically metrics.DB(name), and starts the periodic compaction collector.
+func (db *LDBDatabase) Meter(ns *metrics.Namespace) {
 	// Short circuit metering if the metrics system is disabled
 	if !metrics.Enabled {
 		return
 	}
-	// Initialize all the metrics collector at the requested prefix
-	db.getTimer = metrics.NewTimer(prefix + "user/gets")
-	db.putTimer = metrics.NewTimer(prefix + "user/puts")
-	db.delTimer = metrics.NewTimer(prefix + "user/dels")
-	db.missMeter = metrics.NewMeter(prefix + "user/misses")
-	db.readMeter = metrics.NewMeter(prefix + "user/reads")
-	db.writeMeter = metrics.NewMeter(prefix + "user/writes")
-	db.compTimeMeter = metrics.NewMeter(prefix + "compact/time")
-	db.compReadMeter = metrics.NewMeter(prefix + "compact/input")
-	db.compWriteMeter = metrics.NewMeter(prefix + "compact/output")
+	// Initialize all the metrics collector in the requested namespace
+	db.getTimer = ns.Timer("user/gets")
+	db.putTimer = ns.Timer("user/puts")
+	db.delTimer = ns.Timer("user/dels")
+	db.missMeter = ns.Meter("user/misses")
+	db.readMeter = ns.Meter("user/reads")
+	db.writeMeter = ns.Meter("user/writes")
+	db.compTimeMeter = ns.Meter("compact/time")
+	db.compReadMeter = ns.Meter("compact/input")
+	db.compWriteMeter = ns.Meter("compact/output")
 
 	// Create a quit channel for the periodic collector and run it
 	db.quitLock.Lock()
@@ -293,6 +294,16 @@ func (db *LDBDatabase) meter(refresh time.Duration) {
 	errc <- merr
 }
 
+// NewRangeIterator iterates over the keys in [start, limit).
+func (db *LDBDatabase) NewRangeIterator(start, limit []byte) Iterator {
+	return db.db.NewIterator(&util.Range{Start: start, Limit: limit}, nil)
+}
+
+// NewPrefixIterator iterates over the keys starting with prefix.
+func (db *LDBDatabase) NewPrefixIterator(prefix []byte) Iterator {
+	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
+}
+
 func (db *LDBDatabase) NewBatch() Batch {
 	return &ldbBatch{db: db.db, b: new(leveldb.Batch)}
 }
@@ -356,6 +367,35 @@ func (dt *table) Close() {
 	// Do nothing; don't close the underlying DB.
 }
 
+func (dt *table) NewRangeIterator(start, limit []byte) Iterator {
+	r := util.BytesPrefix([]byte(dt.prefix))
+	if start != nil {
+		r.Start = append([]byte(dt.prefix), start...)
+	}
+	if limit != nil {
+		r.Limit = append([]byte(dt.prefix), limit...)
+	}
+	return &tableIterator{dt.db.NewRangeIterator(r.Start, r.Limit), len(dt.prefix)}
+}
+
+func (dt *table) NewPrefixIterator(prefix []byte) Iterator {
+	return &tableIterator{dt.db.NewPrefixIterator(append([]byte(dt.prefix), prefix...)), len(dt.prefix)}
+}
+
+// tableIterator strips the table prefix from the keys of the underlying
+// database iterator.
+type tableIterator struct {
+	Iterator
+	prefix int
+}
+
+func (it *tableIterator) Key() []byte {
+	if key := it.Iterator.Key(); len(key) >= it.prefix {
+		return key[it.prefix:]
+	}
+	return nil
+}
+
 type tableBatch struct {
 	batch  Batch
 	prefix string
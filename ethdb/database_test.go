@@ -190,3 +190,64 @@ func testParallelPutGet(db ethdb.Database, t *testing.T) {
 	}
 	pending.Wait()
 }
+
+func TestLDB_Iterator(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIterator(db, t)
+}
+
+func TestMemoryDB_Iterator(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	testIterator(db, t)
+}
+
+func TestTable_Iterator(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	// Entries outside the table must not be iterated over
+	db.Put([]byte("s"), []byte("outside"))
+	db.Put([]byte("u"), []byte("outside"))
+	testIterator(ethdb.NewTable(db, "t"), t)
+}
+
+func testIterator(db ethdb.Database, t *testing.T) {
+	t.Parallel()
+
+	keys := []string{"a", "aa", "ab", "b", "ba", "c", "\xff", "\xff\xff"}
+	for _, k := range keys {
+		if err := db.Put([]byte(k), []byte("v"+k)); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+	collect := func(it ethdb.Iterator) []string {
+		defer it.Release()
+		var have []string
+		for it.Next() {
+			if !bytes.Equal(it.Value(), []byte("v"+string(it.Key()))) {
+				t.Errorf("value mismatch for key %q: have %q", it.Key(), it.Value())
+			}
+			have = append(have, string(it.Key()))
+		}
+		if err := it.Error(); err != nil {
+			t.Fatalf("iteration failed: %v", err)
+		}
+		return have
+	}
+	tests := []struct {
+		it   ethdb.Iterator
+		want []string
+	}{
+		{db.NewRangeIterator(nil, nil), keys},
+		{db.NewRangeIterator([]byte("aa"), []byte("ba")), []string{"aa", "ab", "b"}},
+		{db.NewRangeIterator([]byte("b"), nil), []string{"b", "ba", "c", "\xff", "\xff\xff"}},
+		{db.NewRangeIterator(nil, []byte("ab")), []string{"a", "aa"}},
+		{db.NewPrefixIterator([]byte("a")), []string{"a", "aa", "ab"}},
+		{db.NewPrefixIterator([]byte("\xff")), []string{"\xff", "\xff\xff"}},
+		{db.NewPrefixIterator([]byte("d")), nil},
+	}
+	for i, test := range tests {
+		if have := collect(test.it); fmt.Sprint(have) != fmt.Sprint(test.want) {
+			t.Errorf("test %d: keys mismatch: have %q, want %q", i, have, test.want)
+		}
+	}
+}
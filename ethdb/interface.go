@@ -33,6 +33,30 @@ type Database interface {
 	Delete(key []byte) error
 	Close()
 	NewBatch() Batch
+	Iteratee
+}
+
+// Iterator iterates over a snapshot of key/value pairs of a database in
+// ascending key order. The key and value slices returned are only valid until
+// the next call to Next. An iterator must be released once done with.
+type Iterator interface {
+	// Next moves to the next key/value pair, returning false once exhausted or
+	// if an error occurred.
+	Next() bool
+	Key() []byte
+	Value() []byte
+	// Error returns the error encountered during the iteration, if any.
+	Error() error
+	Release()
+}
+
+// Iteratee wraps the ordered iteration over key ranges of a database.
+type Iteratee interface {
+	// NewRangeIterator iterates over the keys in [start, limit). A nil start
+	// begins at the first key, a nil limit runs until the last one.
+	NewRangeIterator(start, limit []byte) Iterator
+	// NewPrefixIterator iterates over the keys starting with prefix.
+	NewPrefixIterator(prefix []byte) Iterator
 }
 
 // NewDatabaseFunc represents a function for generating a new database.
@@ -17,7 +17,9 @@
 package ethdb
 
 import (
+	"bytes"
 	"errors"
+	"sort"
 	"sync"
 
 	"github.com/fulcrumchain/indigo/common"
@@ -96,8 +98,71 @@ func (db *MemDatabase) NewBatch() Batch {
 
 func (db *MemDatabase) Len() int { return len(db.db) }
 
+// NewRangeIterator iterates over a snapshot of the keys in [start, limit).
+func (db *MemDatabase) NewRangeIterator(start, limit []byte) Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	it := &memIterator{index: -1}
+	for key, value := range db.db {
+		k := []byte(key)
+		if start != nil && bytes.Compare(k, start) < 0 {
+			continue
+		}
+		if limit != nil && bytes.Compare(k, limit) >= 0 {
+			continue
+		}
+		it.entries = append(it.entries, kv{k, common.CopyBytes(value)})
+	}
+	sort.Slice(it.entries, func(i, j int) bool { return bytes.Compare(it.entries[i].k, it.entries[j].k) < 0 })
+	return it
+}
+
+// NewPrefixIterator iterates over a snapshot of the keys starting with prefix.
+func (db *MemDatabase) NewPrefixIterator(prefix []byte) Iterator {
+	var limit []byte
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			limit = append(common.CopyBytes(prefix[:i]), prefix[i]+1)
+			break
+		}
+	}
+	return db.NewRangeIterator(prefix, limit)
+}
+
 type kv struct{ k, v []byte }
 
+// memIterator iterates over the entries of a MemDatabase snapshot.
+type memIterator struct {
+	entries []kv
+	index   int
+}
+
+func (it *memIterator) Next() bool {
+	if it.index < len(it.entries) {
+		it.index++
+	}
+	return it.index < len(it.entries)
+}
+
+func (it *memIterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.entries) {
+		return nil
+	}
+	return it.entries[it.index].k
+}
+
+func (it *memIterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.entries) {
+		return nil
+	}
+	return it.entries[it.index].v
+}
+
+func (it *memIterator) Error() error { return nil }
+
+func (it *memIterator) Release() { it.entries = nil }
+
 type memBatch struct {
 	db     *MemDatabase
 	writes []kv
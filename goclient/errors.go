@@ -0,0 +1,191 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goclient
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/fulcrumchain/indigo"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+var (
+	// ErrNotFound is returned if the requested item does not exist. It is the
+	// same value as indigo.NotFound.
+	ErrNotFound = indigo.NotFound
+
+	// ErrReorged is returned if an item is no longer at the position in the
+	// chain it was requested for, because the chain was reorganized.
+	ErrReorged = errors.New("reorged")
+
+	// ErrRateLimited is returned if the node or a proxy in front of it refused
+	// the request because too many requests were made.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrNonceTooLow is returned if a transaction was rejected because its
+	// nonce is lower than the account nonce.
+	ErrNonceTooLow = errors.New("nonce too low")
+
+	// ErrResponseTooLarge is returned if the node refused a query because its
+	// result or the queried block range exceeds the limits of the node.
+	ErrResponseTooLarge = errors.New("response too large")
+
+	// ErrExecutionReverted is matched by RevertError, returned if a call or
+	// gas estimation was reverted by the EVM.
+	ErrExecutionReverted = errors.New("execution reverted")
+)
+
+const (
+	revertCode       = 3      // JSON-RPC error code for reverted executions
+	rateLimitedCode  = -32005 // JSON-RPC error code for exceeded request limits
+	nonCanonicalCode = 4445   // JSON-RPC error code for blocks reorged out of the chain
+)
+
+// tooLargeMessages are fragments of the errors nodes and providers return for
+// queries exceeding their result or block range limits.
+var tooLargeMessages = []string{
+	"query returned more than",
+	"response size exceeded",
+	"response too large",
+	"block range too large",
+	"exceed maximum block range",
+	"exceeds max block range",
+	"too many results",
+}
+
+// revertSelector is the selector of the Error(string) revert reason.
+var revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// Error is a JSON-RPC error returned by the node. It matches the sentinel error
+// it was classified as with errors.Is, if any.
+type Error struct {
+	Code    int
+	Message string
+	kind    error
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+// Unwrap returns the sentinel error err was classified as, or nil.
+func (err *Error) Unwrap() error {
+	return err.kind
+}
+
+// RevertError is returned if a call was reverted by the EVM. Reason holds the
+// decoded revert reason, if the contract supplied one.
+type RevertError struct {
+	Reason string
+	Data   []byte // raw revert data returned by the contract
+}
+
+func (err *RevertError) Error() string {
+	if err.Reason == "" {
+		return ErrExecutionReverted.Error()
+	}
+	return ErrExecutionReverted.Error() + ": " + err.Reason
+}
+
+// Is reports whether target is ErrExecutionReverted.
+func (err *RevertError) Is(target error) bool {
+	return target == ErrExecutionReverted
+}
+
+// toError maps errors returned by the RPC client into the typed errors of
+// this package. Errors which can't be classified are returned unchanged.
+func toError(err error) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *rpc.HTTPError:
+		if e.StatusCode == http.StatusTooManyRequests {
+			return &Error{Code: e.StatusCode, Message: e.Error(), kind: ErrRateLimited}
+		}
+		return err
+	case rpc.Error:
+		code, msg := e.ErrorCode(), e.Error()
+		switch {
+		case code == revertCode || strings.HasPrefix(msg, ErrExecutionReverted.Error()):
+			return newRevertError(e)
+		case isTooLarge(msg):
+			// Checked first, as some providers use the rate limit code for these.
+			return &Error{Code: code, Message: msg, kind: ErrResponseTooLarge}
+		case code == rateLimitedCode || strings.Contains(msg, "rate limit"):
+			return &Error{Code: code, Message: msg, kind: ErrRateLimited}
+		case strings.Contains(msg, ErrNonceTooLow.Error()):
+			return &Error{Code: code, Message: msg, kind: ErrNonceTooLow}
+		case code == nonCanonicalCode || strings.Contains(msg, "not currently canonical"):
+			return &Error{Code: code, Message: msg, kind: ErrReorged}
+		case code != -32601 && strings.HasSuffix(msg, "not found"):
+			// -32601 is a missing RPC method rather than a missing item.
+			return &Error{Code: code, Message: msg, kind: ErrNotFound}
+		}
+		return &Error{Code: code, Message: msg}
+	}
+	return err
+}
+
+// isTooLarge reports whether an error message refuses an oversized query.
+func isTooLarge(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, fragment := range tooLargeMessages {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRevertError extracts the revert data and reason from a reverted call.
+func newRevertError(err rpc.Error) *RevertError {
+	revert := new(RevertError)
+	if dataErr, ok := err.(rpc.DataError); ok {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			revert.Data, _ = hexutil.Decode(data)
+		}
+	}
+	if reason, ok := unpackRevert(revert.Data); ok {
+		revert.Reason = reason
+	} else if msg := err.Error(); strings.HasPrefix(msg, ErrExecutionReverted.Error()+": ") {
+		revert.Reason = strings.TrimPrefix(msg, ErrExecutionReverted.Error()+": ")
+	}
+	return revert
+}
+
+// unpackRevert decodes the ABI encoded Error(string) revert reason.
+func unpackRevert(data []byte) (string, bool) {
+	if len(data) < 4+64 || !bytes.Equal(data[:4], revertSelector) {
+		return "", false
+	}
+	data = data[4:]
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return "", false
+	}
+	start := offset.Uint64() + 32
+	size := new(big.Int).SetBytes(data[start-32 : start])
+	if !size.IsUint64() || size.Uint64() > uint64(len(data))-start {
+		return "", false
+	}
+	return string(data[start : start+size.Uint64()]), true
+}
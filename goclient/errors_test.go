@@ -0,0 +1,146 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fulcrumchain/indigo"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+// codeError is a JSON-RPC error with a custom code and optional data.
+type codeError struct {
+	code int
+	msg  string
+	data interface{}
+}
+
+func (e *codeError) Error() string          { return e.msg }
+func (e *codeError) ErrorCode() int         { return e.code }
+func (e *codeError) ErrorData() interface{} { return e.data }
+
+// ErrorService is an eth service which fails every call in a canned way.
+type ErrorService struct{}
+
+func (ErrorService) GetTransactionReceipt(hash common.Hash) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func (ErrorService) GetHeaderByHash(hash common.Hash) (map[string]interface{}, error) {
+	return nil, errors.New("header not found")
+}
+
+func (ErrorService) SendRawTransaction(data hexutil.Bytes) (common.Hash, error) {
+	return common.Hash{}, errors.New("nonce too low")
+}
+
+func (ErrorService) GetBalance(account common.Address, number string) (*hexutil.Big, error) {
+	return nil, &codeError{code: -32005, msg: "request limit exceeded"}
+}
+
+func (ErrorService) GetCode(account common.Address, block rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	if _, ok := block.Hash(); !ok || !block.RequireCanonical {
+		return nil, errors.New("block not pinned")
+	}
+	return nil, errors.New("hash is not currently canonical")
+}
+
+func (ErrorService) Call(args map[string]interface{}, number string) (hexutil.Bytes, error) {
+	// ABI encoding of Error("insufficient funds")
+	data := "0x08c379a0" +
+		"0000000000000000000000000000000000000000000000000000000000000020" +
+		"0000000000000000000000000000000000000000000000000000000000000012" +
+		"696e73756666696369656e742066756e64730000000000000000000000000000"
+	return nil, &codeError{code: 3, msg: "execution reverted", data: data}
+}
+
+func (ErrorService) EstimateGas(args map[string]interface{}) (hexutil.Uint64, error) {
+	return 0, errors.New("execution reverted: out of stock")
+}
+
+func TestErrors(t *testing.T) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", ErrorService{}); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	client := NewClient(rpc.DialInProc(server))
+	defer client.c.Close()
+
+	ctx := context.Background()
+	if _, err := client.TransactionReceipt(ctx, common.Hash{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing receipt error mismatch: have %v, want %v", err, ErrNotFound)
+	}
+	if _, err := client.HeaderByHash(ctx, common.Hash{}); !errors.Is(err, ErrNotFound) || !errors.Is(err, indigo.NotFound) {
+		t.Errorf("missing header error mismatch: have %v, want %v", err, ErrNotFound)
+	}
+	if _, err := client.CodeAtHash(ctx, common.Address{}, common.Hash{1}); !errors.Is(err, ErrReorged) {
+		t.Errorf("reorged code error mismatch: have %v, want %v", err, ErrReorged)
+	}
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	if err := client.SendTransaction(ctx, tx); !errors.Is(err, ErrNonceTooLow) {
+		t.Errorf("send error mismatch: have %v, want %v", err, ErrNonceTooLow)
+	}
+	if _, err := client.BalanceAt(ctx, common.Address{}, nil); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("balance error mismatch: have %v, want %v", err, ErrRateLimited)
+	}
+	_, err := client.CallContract(ctx, indigo.CallMsg{}, nil)
+	if !errors.Is(err, ErrExecutionReverted) {
+		t.Fatalf("call error mismatch: have %v, want %v", err, ErrExecutionReverted)
+	}
+	var revert *RevertError
+	if !errors.As(err, &revert) || revert.Reason != "insufficient funds" || len(revert.Data) != 100 {
+		t.Errorf("call revert mismatch: have %+v", revert)
+	}
+	_, err = client.EstimateGas(ctx, indigo.CallMsg{})
+	if !errors.As(err, &revert) || revert.Reason != "out of stock" {
+		t.Errorf("estimate revert mismatch: have %v", err)
+	}
+	// Unclassified errors keep their code, but match no sentinel.
+	_, err = client.NetworkID(ctx)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 || errors.Is(err, ErrNotFound) {
+		t.Errorf("missing method error mismatch: have %v", err)
+	}
+}
+
+func TestErrorsHTTPRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	client, err := Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.c.Close()
+
+	if _, err := client.LatestBlockNumber(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrRateLimited)
+	}
+}
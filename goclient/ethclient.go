@@ -20,7 +20,6 @@ package goclient
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"math/big"
 
@@ -34,21 +33,76 @@ import (
 
 // Client defines typed wrappers for the Ethereum RPC API.
 type Client struct {
-	c *rpc.Client
+	c       *rpc.Client
+	limiter *limiter // Request limits, nil if unlimited
 }
 
 // Dial connects a client to the given URL.
 func Dial(rawurl string) (*Client, error) {
+	return DialWithOptions(rawurl, Options{})
+}
+
+// DialWithOptions connects a client to the given URL, limiting its requests
+// according to opts.
+func DialWithOptions(rawurl string, opts Options) (*Client, error) {
 	c, err := rpc.Dial(rawurl)
 	if err != nil {
 		return nil, err
 	}
-	return NewClient(c), nil
+	return NewClientWithOptions(c, opts), nil
 }
 
 // NewClient creates a client that uses the given RPC client.
 func NewClient(c *rpc.Client) *Client {
-	return &Client{c}
+	return NewClientWithOptions(c, Options{})
+}
+
+// NewClientWithOptions creates a client that uses the given RPC client, limiting
+// its requests according to opts. The priority class of a request is set with
+// WithPriority on its context.
+func NewClientWithOptions(c *rpc.Client, opts Options) *Client {
+	return &Client{c: c, limiter: newLimiter(opts)}
+}
+
+// call performs a JSON-RPC call, mapping failures into the typed errors of this
+// package.
+func (ec *Client) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if err := ec.limiter.acquire(ctx); err != nil {
+		return err
+	}
+	defer ec.limiter.release()
+
+	return toError(ec.c.CallContext(ctx, result, method, args...))
+}
+
+// batchCall performs a batch of JSON-RPC calls as a single request.
+func (ec *Client) batchCall(ctx context.Context, reqs []rpc.BatchElem) error {
+	if err := ec.limiter.acquire(ctx); err != nil {
+		return err
+	}
+	defer ec.limiter.release()
+
+	if err := ec.c.BatchCallContext(ctx, reqs); err != nil {
+		return toError(err)
+	}
+	for i := range reqs {
+		reqs[i].Error = toError(reqs[i].Error)
+	}
+	return nil
+}
+
+// subscribe establishes a subscription in the "eth" namespace.
+func (ec *Client) subscribe(ctx context.Context, ch interface{}, args ...interface{}) (indigo.Subscription, error) {
+	if err := ec.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer ec.limiter.release()
+
+	sub, err := ec.c.EthSubscribe(ctx, ch, args...)
+	if err != nil {
+		return nil, toError(err)
+	}
+	return sub, nil
 }
 
 // Blockchain Access
@@ -73,7 +127,7 @@ func (ec *Client) BlockByNumber(ctx context.Context, number *big.Int) (*types.Bl
 // LatestBlockNumber gets latest block number
 func (ec *Client) LatestBlockNumber(ctx context.Context) (*big.Int, error) {
 	var result hexutil.Big
-	err := ec.c.CallContext(ctx, &result, "eth_blockNumber", nil)
+	err := ec.call(ctx, &result, "eth_blockNumber", nil)
 	return (*big.Int)(&result), err
 }
 
@@ -85,11 +139,11 @@ type rpcBlock struct {
 
 func (ec *Client) getBlock(ctx context.Context, method string, args ...interface{}) (*types.Block, error) {
 	var raw json.RawMessage
-	err := ec.c.CallContext(ctx, &raw, method, args...)
+	err := ec.call(ctx, &raw, method, args...)
 	if err != nil {
 		return nil, err
 	} else if len(raw) == 0 {
-		return nil, indigo.NotFound
+		return nil, ErrNotFound
 	}
 	// Decode header and transactions.
 	var head *types.Header
@@ -125,7 +179,7 @@ func (ec *Client) getBlock(ctx context.Context, method string, args ...interface
 				Result: &uncles[i],
 			}
 		}
-		if err := ec.c.BatchCallContext(ctx, reqs); err != nil {
+		if err := ec.batchCall(ctx, reqs); err != nil {
 			return nil, err
 		}
 		for i := range reqs {
@@ -149,9 +203,9 @@ func (ec *Client) getBlock(ctx context.Context, method string, args ...interface
 // HeaderByHash returns the block header with the given hash.
 func (ec *Client) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
 	var head *types.Header
-	err := ec.c.CallContext(ctx, &head, "eth_getBlockByHash", hash, false)
+	err := ec.call(ctx, &head, "eth_getHeaderByHash", hash)
 	if err == nil && head == nil {
-		err = indigo.NotFound
+		err = ErrNotFound
 	}
 	return head, err
 }
@@ -160,9 +214,9 @@ func (ec *Client) HeaderByHash(ctx context.Context, hash common.Hash) (*types.He
 // nil, the latest known header is returned.
 func (ec *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
 	var head *types.Header
-	err := ec.c.CallContext(ctx, &head, "eth_getBlockByNumber", toBlockNumArg(number), false)
+	err := ec.call(ctx, &head, "eth_getHeaderByNumber", toBlockNumArg(number))
 	if err == nil && head == nil {
-		err = indigo.NotFound
+		err = ErrNotFound
 	}
 	return head, err
 }
@@ -188,11 +242,11 @@ func (tx *rpcTransaction) UnmarshalJSON(msg []byte) error {
 // TransactionByHash returns the transaction with the given hash.
 func (ec *Client) TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error) {
 	var json *rpcTransaction
-	err = ec.c.CallContext(ctx, &json, "eth_getTransactionByHash", hash)
+	err = ec.call(ctx, &json, "eth_getTransactionByHash", hash)
 	if err != nil {
 		return nil, false, err
 	} else if json == nil {
-		return nil, false, indigo.NotFound
+		return nil, false, ErrNotFound
 	} else if _, r, _ := json.tx.RawSignatureValues(); r == nil {
 		return nil, false, fmt.Errorf("server returned transaction without signature")
 	}
@@ -206,6 +260,9 @@ func (ec *Client) TransactionByHash(ctx context.Context, hash common.Hash) (tx *
 //
 // There is a fast-path for transactions retrieved by TransactionByHash and
 // TransactionInBlock. Getting their sender address can be done without an RPC interaction.
+//
+// ErrReorged is returned if the transaction is not at the given position, e.g. because
+// it was included in a different block after a reorg.
 func (ec *Client) TransactionSender(ctx context.Context, tx *types.Transaction, block common.Hash, index uint) (common.Address, error) {
 	// Try to load the address from the cache.
 	sender, err := types.Sender(ctx, &senderFromServer{blockhash: block}, tx)
@@ -216,11 +273,11 @@ func (ec *Client) TransactionSender(ctx context.Context, tx *types.Transaction,
 		Hash common.Hash
 		From common.Address
 	}
-	if err = ec.c.CallContext(ctx, &meta, "eth_getTransactionByBlockHashAndIndex", block, hexutil.Uint64(index)); err != nil {
+	if err = ec.call(ctx, &meta, "eth_getTransactionByBlockHashAndIndex", block, hexutil.Uint64(index)); err != nil {
 		return common.Address{}, err
 	}
 	if meta.Hash == (common.Hash{}) || meta.Hash != tx.Hash() {
-		return common.Address{}, errors.New("wrong inclusion block/index")
+		return common.Address{}, ErrReorged
 	}
 	return meta.From, nil
 }
@@ -228,33 +285,33 @@ func (ec *Client) TransactionSender(ctx context.Context, tx *types.Transaction,
 // TransactionCount returns the total number of transactions in the given block.
 func (ec *Client) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
 	var num hexutil.Uint
-	err := ec.c.CallContext(ctx, &num, "eth_getBlockTransactionCountByHash", blockHash)
+	err := ec.call(ctx, &num, "eth_getBlockTransactionCountByHash", blockHash)
 	return uint(num), err
 }
 
 // TransactionInBlock returns a single transaction at index in the given block.
 func (ec *Client) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
 	var json *rpcTransaction
-	err := ec.c.CallContext(ctx, &json, "eth_getTransactionByBlockHashAndIndex", blockHash, hexutil.Uint64(index))
-	if err == nil {
-		if json == nil {
-			return nil, indigo.NotFound
-		} else if _, r, _ := json.tx.RawSignatureValues(); r == nil {
-			return nil, fmt.Errorf("server returned transaction without signature")
-		}
+	if err := ec.call(ctx, &json, "eth_getTransactionByBlockHashAndIndex", blockHash, hexutil.Uint64(index)); err != nil {
+		return nil, err
+	}
+	if json == nil {
+		return nil, ErrNotFound
+	} else if _, r, _ := json.tx.RawSignatureValues(); r == nil {
+		return nil, fmt.Errorf("server returned transaction without signature")
 	}
 	setSenderFromServer(ctx, json.tx, json.From, json.BlockHash)
-	return json.tx, err
+	return json.tx, nil
 }
 
 // TransactionReceipt returns the receipt of a transaction by transaction hash.
 // Note that the receipt is not available for pending transactions.
 func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
 	var r *types.Receipt
-	err := ec.c.CallContext(ctx, &r, "eth_getTransactionReceipt", txHash)
+	err := ec.call(ctx, &r, "eth_getTransactionReceipt", txHash)
 	if err == nil {
 		if r == nil {
-			return nil, indigo.NotFound
+			return nil, ErrNotFound
 		}
 	}
 	return r, err
@@ -267,6 +324,15 @@ func toBlockNumArg(number *big.Int) string {
 	return hexutil.EncodeBig(number)
 }
 
+// toBlockHashArg selects the block with the given hash, failing with ErrReorged
+// if it is no longer part of the canonical chain.
+func toBlockHashArg(hash common.Hash) interface{} {
+	return map[string]interface{}{
+		"blockHash":        hash,
+		"requireCanonical": true,
+	}
+}
+
 type rpcProgress struct {
 	StartingBlock hexutil.Uint64
 	CurrentBlock  hexutil.Uint64
@@ -279,7 +345,7 @@ type rpcProgress struct {
 // no sync currently running, it returns nil.
 func (ec *Client) SyncProgress(ctx context.Context) (*indigo.SyncProgress, error) {
 	var raw json.RawMessage
-	if err := ec.c.CallContext(ctx, &raw, "eth_syncing"); err != nil {
+	if err := ec.call(ctx, &raw, "eth_syncing"); err != nil {
 		return nil, err
 	}
 	// Handle the possible response types
@@ -303,7 +369,7 @@ func (ec *Client) SyncProgress(ctx context.Context) (*indigo.SyncProgress, error
 // SubscribeNewHead subscribes to notifications about the current blockchain head
 // on the given channel.
 func (ec *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (indigo.Subscription, error) {
-	return ec.c.EthSubscribe(ctx, ch, "newHeads", map[string]struct{}{})
+	return ec.subscribe(ctx, ch, "newHeads", map[string]struct{}{})
 }
 
 // State Access
@@ -312,7 +378,7 @@ func (ec *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 func (ec *Client) NetworkID(ctx context.Context) (*big.Int, error) {
 	version := new(big.Int)
 	var ver string
-	if err := ec.c.CallContext(ctx, &ver, "net_version"); err != nil {
+	if err := ec.call(ctx, &ver, "net_version"); err != nil {
 		return nil, err
 	}
 	if _, ok := version.SetString(ver, 10); !ok {
@@ -325,7 +391,7 @@ func (ec *Client) NetworkID(ctx context.Context) (*big.Int, error) {
 // The block number can be nil, in which case the balance is taken from the latest known block.
 func (ec *Client) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
 	var result hexutil.Big
-	err := ec.c.CallContext(ctx, &result, "eth_getBalance", account, toBlockNumArg(blockNumber))
+	err := ec.call(ctx, &result, "eth_getBalance", account, toBlockNumArg(blockNumber))
 	return (*big.Int)(&result), err
 }
 
@@ -333,7 +399,7 @@ func (ec *Client) BalanceAt(ctx context.Context, account common.Address, blockNu
 // The block number can be nil, in which case the value is taken from the latest known block.
 func (ec *Client) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getStorageAt", account, key, toBlockNumArg(blockNumber))
+	err := ec.call(ctx, &result, "eth_getStorageAt", account, key, toBlockNumArg(blockNumber))
 	return result, err
 }
 
@@ -341,7 +407,7 @@ func (ec *Client) StorageAt(ctx context.Context, account common.Address, key com
 // The block number can be nil, in which case the code is taken from the latest known block.
 func (ec *Client) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getCode", account, toBlockNumArg(blockNumber))
+	err := ec.call(ctx, &result, "eth_getCode", account, toBlockNumArg(blockNumber))
 	return result, err
 }
 
@@ -349,7 +415,43 @@ func (ec *Client) CodeAt(ctx context.Context, account common.Address, blockNumbe
 // The block number can be nil, in which case the nonce is taken from the latest known block.
 func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
 	var result hexutil.Uint64
-	err := ec.c.CallContext(ctx, &result, "eth_getTransactionCount", account, toBlockNumArg(blockNumber))
+	err := ec.call(ctx, &result, "eth_getTransactionCount", account, toBlockNumArg(blockNumber))
+	return uint64(result), err
+}
+
+// BalanceAtHash returns the wei balance of the given account in the state of the
+// block with the given hash. It fails with ErrReorged if the block is no longer
+// canonical.
+func (ec *Client) BalanceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (*big.Int, error) {
+	var result hexutil.Big
+	err := ec.call(ctx, &result, "eth_getBalance", account, toBlockHashArg(blockHash))
+	return (*big.Int)(&result), err
+}
+
+// StorageAtHash returns the value of key in the contract storage of the given
+// account in the state of the block with the given hash. It fails with
+// ErrReorged if the block is no longer canonical.
+func (ec *Client) StorageAtHash(ctx context.Context, account common.Address, key common.Hash, blockHash common.Hash) ([]byte, error) {
+	var result hexutil.Bytes
+	err := ec.call(ctx, &result, "eth_getStorageAt", account, key, toBlockHashArg(blockHash))
+	return result, err
+}
+
+// CodeAtHash returns the contract code of the given account in the state of the
+// block with the given hash. It fails with ErrReorged if the block is no longer
+// canonical.
+func (ec *Client) CodeAtHash(ctx context.Context, account common.Address, blockHash common.Hash) ([]byte, error) {
+	var result hexutil.Bytes
+	err := ec.call(ctx, &result, "eth_getCode", account, toBlockHashArg(blockHash))
+	return result, err
+}
+
+// NonceAtHash returns the account nonce of the given account in the state of the
+// block with the given hash. It fails with ErrReorged if the block is no longer
+// canonical.
+func (ec *Client) NonceAtHash(ctx context.Context, account common.Address, blockHash common.Hash) (uint64, error) {
+	var result hexutil.Uint64
+	err := ec.call(ctx, &result, "eth_getTransactionCount", account, toBlockHashArg(blockHash))
 	return uint64(result), err
 }
 
@@ -358,13 +460,13 @@ func (ec *Client) NonceAt(ctx context.Context, account common.Address, blockNumb
 // FilterLogs executes a filter query.
 func (ec *Client) FilterLogs(ctx context.Context, q indigo.FilterQuery) ([]types.Log, error) {
 	var result []types.Log
-	err := ec.c.CallContext(ctx, &result, "eth_getLogs", toFilterArg(q))
+	err := ec.call(ctx, &result, "eth_getLogs", toFilterArg(q))
 	return result, err
 }
 
 // SubscribeFilterLogs subscribes to the results of a streaming filter query.
 func (ec *Client) SubscribeFilterLogs(ctx context.Context, q indigo.FilterQuery, ch chan<- types.Log) (indigo.Subscription, error) {
-	return ec.c.EthSubscribe(ctx, ch, "logs", toFilterArg(q))
+	return ec.subscribe(ctx, ch, "logs", toFilterArg(q))
 }
 
 func toFilterArg(q indigo.FilterQuery) interface{} {
@@ -385,21 +487,21 @@ func toFilterArg(q indigo.FilterQuery) interface{} {
 // PendingBalanceAt returns the wei balance of the given account in the pending state.
 func (ec *Client) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
 	var result hexutil.Big
-	err := ec.c.CallContext(ctx, &result, "eth_getBalance", account, "pending")
+	err := ec.call(ctx, &result, "eth_getBalance", account, "pending")
 	return (*big.Int)(&result), err
 }
 
 // PendingStorageAt returns the value of key in the contract storage of the given account in the pending state.
 func (ec *Client) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getStorageAt", account, key, "pending")
+	err := ec.call(ctx, &result, "eth_getStorageAt", account, key, "pending")
 	return result, err
 }
 
 // PendingCodeAt returns the contract code of the given account in the pending state.
 func (ec *Client) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
 	var result hexutil.Bytes
-	err := ec.c.CallContext(ctx, &result, "eth_getCode", account, "pending")
+	err := ec.call(ctx, &result, "eth_getCode", account, "pending")
 	return result, err
 }
 
@@ -407,14 +509,14 @@ func (ec *Client) PendingCodeAt(ctx context.Context, account common.Address) ([]
 // This is the nonce that should be used for the next transaction.
 func (ec *Client) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
 	var result hexutil.Uint64
-	err := ec.c.CallContext(ctx, &result, "eth_getTransactionCount", account, "pending")
+	err := ec.call(ctx, &result, "eth_getTransactionCount", account, "pending")
 	return uint64(result), err
 }
 
 // PendingTransactionCount returns the total number of transactions in the pending state.
 func (ec *Client) PendingTransactionCount(ctx context.Context) (uint, error) {
 	var num hexutil.Uint
-	err := ec.c.CallContext(ctx, &num, "eth_getBlockTransactionCountByNumber", "pending")
+	err := ec.call(ctx, &num, "eth_getBlockTransactionCountByNumber", "pending")
 	return uint(num), err
 }
 
@@ -430,7 +532,19 @@ func (ec *Client) PendingTransactionCount(ctx context.Context) (uint, error) {
 // blocks might not be available.
 func (ec *Client) CallContract(ctx context.Context, msg indigo.CallMsg, blockNumber *big.Int) ([]byte, error) {
 	var hex hexutil.Bytes
-	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
+	err := ec.call(ctx, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
+	if err != nil {
+		return nil, err
+	}
+	return hex, nil
+}
+
+// CallContractAtHash executes a message call transaction using the EVM, on top
+// of the state of the block with the given hash. It fails with ErrReorged if the
+// block is no longer canonical.
+func (ec *Client) CallContractAtHash(ctx context.Context, msg indigo.CallMsg, blockHash common.Hash) ([]byte, error) {
+	var hex hexutil.Bytes
+	err := ec.call(ctx, &hex, "eth_call", toCallArg(msg), toBlockHashArg(blockHash))
 	if err != nil {
 		return nil, err
 	}
@@ -441,7 +555,7 @@ func (ec *Client) CallContract(ctx context.Context, msg indigo.CallMsg, blockNum
 // The state seen by the contract call is the pending state.
 func (ec *Client) PendingCallContract(ctx context.Context, msg indigo.CallMsg) ([]byte, error) {
 	var hex hexutil.Bytes
-	err := ec.c.CallContext(ctx, &hex, "eth_call", toCallArg(msg), "pending")
+	err := ec.call(ctx, &hex, "eth_call", toCallArg(msg), "pending")
 	if err != nil {
 		return nil, err
 	}
@@ -452,7 +566,7 @@ func (ec *Client) PendingCallContract(ctx context.Context, msg indigo.CallMsg) (
 // execution of a transaction.
 func (ec *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 	var hex hexutil.Big
-	if err := ec.c.CallContext(ctx, &hex, "eth_gasPrice"); err != nil {
+	if err := ec.call(ctx, &hex, "eth_gasPrice"); err != nil {
 		return nil, err
 	}
 	return (*big.Int)(&hex), nil
@@ -464,7 +578,7 @@ func (ec *Client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
 // but it should provide a basis for setting a reasonable default.
 func (ec *Client) EstimateGas(ctx context.Context, msg indigo.CallMsg) (uint64, error) {
 	var hex hexutil.Uint64
-	err := ec.c.CallContext(ctx, &hex, "eth_estimateGas", toCallArg(msg))
+	err := ec.call(ctx, &hex, "eth_estimateGas", toCallArg(msg))
 	if err != nil {
 		return 0, err
 	}
@@ -480,7 +594,33 @@ func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) er
 	if err != nil {
 		return err
 	}
-	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
+	return ec.call(ctx, nil, "eth_sendRawTransaction", common.ToHex(data))
+}
+
+// SignTransaction has the node sign a transaction with one of its unlocked accounts,
+// without submitting it. The gas, gas price and nonce must be set, as the node signing
+// may not be the one the transaction is later sent to with SendTransaction.
+func (ec *Client) SignTransaction(ctx context.Context, msg indigo.CallMsg, nonce uint64) (*types.Transaction, error) {
+	if msg.Gas == 0 {
+		return nil, fmt.Errorf("gas not specified")
+	}
+	if msg.GasPrice == nil {
+		return nil, fmt.Errorf("gasPrice not specified")
+	}
+	arg := toCallArg(msg).(map[string]interface{})
+	arg["nonce"] = hexutil.Uint64(nonce)
+
+	var result struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := ec.call(ctx, &result, "eth_signTransaction", arg); err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(result.Raw); err != nil {
+		return nil, err
+	}
+	return tx, nil
 }
 
 func toCallArg(msg indigo.CallMsg) interface{} {
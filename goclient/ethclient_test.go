@@ -16,7 +16,20 @@
 
 package goclient
 
-import "github.com/fulcrumchain/indigo"
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/rpc"
+)
 
 // Verify that Client implements the ethereum interfaces.
 var (
@@ -32,3 +45,60 @@ var (
 	// _ = indigo.PendingStateEventer(&Client{})
 	_ = indigo.PendingContractCaller(&Client{})
 )
+
+// SignService is an eth service signing transactions with a single key.
+type SignService struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *SignService) SignTransaction(args struct {
+	From     common.Address  `json:"from"`
+	To       *common.Address `json:"to"`
+	Gas      hexutil.Uint64  `json:"gas"`
+	GasPrice *hexutil.Big    `json:"gasPrice"`
+	Value    *hexutil.Big    `json:"value"`
+	Nonce    hexutil.Uint64  `json:"nonce"`
+}) (map[string]interface{}, error) {
+	if args.From != crypto.PubkeyToAddress(s.key.PublicKey) {
+		return nil, fmt.Errorf("unknown account %x", args.From)
+	}
+	tx := types.NewTransaction(uint64(args.Nonce), *args.To, (*big.Int)(args.Value), uint64(args.Gas), (*big.Int)(args.GasPrice), nil)
+	signed, err := types.SignTx(tx, types.HomesteadSigner{}, s.key)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}, nil
+}
+
+func TestSignTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &SignService{key: key}); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(rpc.DialInProc(server))
+	defer server.Stop()
+	defer client.c.Close()
+
+	from, to := crypto.PubkeyToAddress(key.PublicKey), common.Address{0x01}
+	msg := indigo.CallMsg{From: from, To: &to, Gas: 21000, GasPrice: big.NewInt(2), Value: big.NewInt(3)}
+	tx, err := client.SignTransaction(context.Background(), msg, 7)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if tx.Nonce() != 7 || tx.Gas() != 21000 || tx.GasPrice().Uint64() != 2 || tx.Value().Uint64() != 3 || *tx.To() != to {
+		t.Errorf("signed transaction mismatch: have %v", tx)
+	}
+	if sender, err := types.Sender(context.Background(), types.HomesteadSigner{}, tx); err != nil || sender != from {
+		t.Errorf("sender mismatch: have %x (%v), want %x", sender, err, from)
+	}
+	// Transactions without gas are refused before reaching the node
+	msg.Gas = 0
+	if _, err := client.SignTransaction(context.Background(), msg, 7); err == nil {
+		t.Error("signed transaction without gas")
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goclient
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Priority is the scheduling class of a request. When requests have to wait for
+// a concurrency slot or a rate limit token, higher priority requests are served
+// first.
+type Priority int
+
+const (
+	PriorityLow    Priority = iota // Background work, e.g. backfilling
+	PriorityNormal                 // Default class of requests
+	PriorityHigh                   // Latency sensitive requests
+
+	priorityCount = int(PriorityHigh) + 1
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a context which schedules the requests made with it in
+// the given priority class.
+func WithPriority(ctx context.Context, prio Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, prio)
+}
+
+// priorityOf returns the priority class of requests made with ctx.
+func priorityOf(ctx context.Context) Priority {
+	if prio, ok := ctx.Value(priorityKey{}).(Priority); ok && prio >= PriorityLow && prio <= PriorityHigh {
+		return prio
+	}
+	return PriorityNormal
+}
+
+// Options configures the request limits of a Client. The zero value imposes no
+// limits.
+type Options struct {
+	MaxConcurrent int     // Maximum number of requests in flight, 0 for unlimited
+	HighReserve   int     // Concurrency slots only available to high priority requests
+	RateLimit     float64 // Maximum sustained requests per second, 0 for unlimited
+	RateBurst     int     // Maximum requests allowed at once above the sustained rate
+}
+
+// limiter enforces the request limits of a Client.
+type limiter struct {
+	maxConcurrent int
+	highReserve   int
+	rate          float64
+	burst         float64
+
+	inflight int                      // Number of requests currently admitted
+	tokens   float64                  // Rate limit tokens currently available
+	last     time.Time                // Time the tokens were last refilled
+	waiting  [priorityCount][]*waiter // Requests waiting for admission, per priority
+	timer    *time.Timer              // Timer dispatching waiters once tokens refill
+
+	lock sync.Mutex
+}
+
+// waiter is a request waiting for admission.
+type waiter struct {
+	prio     Priority
+	ready    chan struct{} // Closed when the request is admitted
+	admitted bool
+}
+
+// newLimiter creates a limiter for the options, or nil if they impose no limits.
+func newLimiter(opts Options) *limiter {
+	if opts.MaxConcurrent <= 0 && opts.RateLimit <= 0 {
+		return nil
+	}
+	l := &limiter{
+		maxConcurrent: opts.MaxConcurrent,
+		rate:          opts.RateLimit,
+		burst:         float64(opts.RateBurst),
+		last:          time.Now(),
+	}
+	if l.maxConcurrent > 0 && opts.HighReserve > 0 && opts.HighReserve < l.maxConcurrent {
+		l.highReserve = opts.HighReserve
+	}
+	if l.burst < 1 {
+		l.burst = 1
+	}
+	l.tokens = l.burst
+	return l
+}
+
+// acquire blocks until a request with the priority of ctx may be sent, or ctx
+// is cancelled. Every successful acquire must be paired with a release.
+func (l *limiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	w := &waiter{prio: priorityOf(ctx), ready: make(chan struct{})}
+
+	l.lock.Lock()
+	l.waiting[w.prio] = append(l.waiting[w.prio], w)
+	l.dispatch()
+	l.lock.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		if w.admitted {
+			// Admitted concurrently with the cancellation, hand the slot back
+			l.inflight--
+			l.dispatch()
+		} else {
+			l.remove(w)
+		}
+		return ctx.Err()
+	}
+}
+
+// release returns the concurrency slot of a finished request.
+func (l *limiter) release() {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.inflight--
+	l.dispatch()
+}
+
+// dispatch admits waiting requests in priority order for as long as the limits
+// allow. It must be called with the lock held.
+func (l *limiter) dispatch() {
+	for prio := PriorityHigh; prio >= PriorityLow; prio-- {
+		for len(l.waiting[prio]) > 0 {
+			if l.maxConcurrent > 0 {
+				limit := l.maxConcurrent
+				if prio != PriorityHigh {
+					limit -= l.highReserve
+				}
+				if l.inflight >= limit {
+					break // no slots left for this class, nor any lower one
+				}
+			}
+			if l.rate > 0 {
+				l.refill()
+				if l.tokens < 1 {
+					l.schedule()
+					return
+				}
+				l.tokens--
+			}
+			w := l.waiting[prio][0]
+			l.waiting[prio] = l.waiting[prio][1:]
+
+			l.inflight++
+			w.admitted = true
+			close(w.ready)
+		}
+		if l.maxConcurrent > 0 && len(l.waiting[prio]) > 0 {
+			// Don't let lower priorities overtake requests blocked on slots
+			return
+		}
+	}
+}
+
+// refill adds the tokens accrued since the last refill.
+func (l *limiter) refill() {
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
+
+// schedule arranges for dispatch to run once the next token is available.
+func (l *limiter) schedule() {
+	if l.timer != nil {
+		return
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	l.timer = time.AfterFunc(wait, func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+
+		l.timer = nil
+		l.dispatch()
+	})
+}
+
+// remove drops a waiter which gave up before being admitted.
+func (l *limiter) remove(w *waiter) {
+	queue := l.waiting[w.prio]
+	for i, other := range queue {
+		if other == w {
+			l.waiting[w.prio] = append(queue[:i:i], queue[i+1:]...)
+			break
+		}
+	}
+	// A cancelled head of queue may have been blocking others
+	l.dispatch()
+}
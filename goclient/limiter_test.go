@@ -0,0 +1,128 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitingCount returns the number of requests of a class waiting for admission.
+func (l *limiter) waitingCount(prio Priority) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return len(l.waiting[prio])
+}
+
+// acquireAsync starts acquiring in the background, returning the channel the
+// result is delivered on once the request is admitted.
+func acquireAsync(l *limiter, prio Priority) chan error {
+	done := make(chan error, 1)
+	before := l.waitingCount(prio)
+	go func() { done <- l.acquire(WithPriority(context.Background(), prio)) }()
+
+	for i := 0; i < 100 && l.waitingCount(prio) == before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+func expectAdmitted(t *testing.T, done chan error, want bool, what string) {
+	select {
+	case err := <-done:
+		if !want {
+			t.Fatalf("%s admitted", what)
+		}
+		if err != nil {
+			t.Fatalf("%s failed: %v", what, err)
+		}
+	case <-time.After(50 * time.Millisecond):
+		if want {
+			t.Fatalf("%s not admitted", what)
+		}
+	}
+}
+
+// Tests that waiting requests are admitted in priority order.
+func TestLimiterPriority(t *testing.T) {
+	l := newLimiter(Options{MaxConcurrent: 1})
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	low := acquireAsync(l, PriorityLow)
+	high := acquireAsync(l, PriorityHigh)
+	expectAdmitted(t, low, false, "low priority request")
+
+	l.release()
+	expectAdmitted(t, high, true, "high priority request")
+	expectAdmitted(t, low, false, "low priority request")
+
+	l.release()
+	expectAdmitted(t, low, true, "low priority request")
+}
+
+// Tests that reserved slots are only used by high priority requests.
+func TestLimiterHighReserve(t *testing.T) {
+	l := newLimiter(Options{MaxConcurrent: 2, HighReserve: 1})
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	normal := acquireAsync(l, PriorityNormal)
+	expectAdmitted(t, normal, false, "normal priority request")
+
+	high := acquireAsync(l, PriorityHigh)
+	expectAdmitted(t, high, true, "high priority request")
+}
+
+// Tests that the request rate is limited.
+func TestLimiterRate(t *testing.T) {
+	l := newLimiter(Options{RateLimit: 100, RateBurst: 2})
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.acquire(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		l.release()
+	}
+	// Two requests are covered by the burst, the rest need a token each.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("requests not rate limited: took %v", elapsed)
+	}
+}
+
+// Tests that a request waiting for admission can be cancelled.
+func TestLimiterCancel(t *testing.T) {
+	l := newLimiter(Options{MaxConcurrent: 1})
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := l.acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := l.waitingCount(PriorityNormal); n != 0 {
+		t.Errorf("cancelled request still waiting: %d", n)
+	}
+	l.release()
+	if err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("failed to acquire after cancellation: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/fulcrumchain/indigo"
+	"github.com/fulcrumchain/indigo/core/types"
+)
+
+// DefaultLogPageSize is the number of blocks queried per page by
+// FilterLogsPaged if no page size is given.
+const DefaultLogPageSize = 10000
+
+// LogPageFunc is called by FilterLogsPaged with the logs of the blocks from
+// to to, in ascending block order. Returning an error stops the iteration.
+type LogPageFunc func(from, to uint64, logs []types.Log) error
+
+// FilterLogsPaged executes a filter query over a block range of any size, by
+// splitting it into pages of pageSize blocks and calling fn with the logs of
+// each page. Pages the node refuses as too large are bisected until they are
+// accepted, or until a single block is still refused.
+//
+// A nil FromBlock starts at the genesis block and a nil ToBlock ends at the
+// head block at the time of the call.
+func (ec *Client) FilterLogsPaged(ctx context.Context, q indigo.FilterQuery, pageSize uint64, fn LogPageFunc) error {
+	if pageSize == 0 {
+		pageSize = DefaultLogPageSize
+	}
+	var from, to uint64
+	if q.FromBlock != nil {
+		if !q.FromBlock.IsUint64() {
+			return fmt.Errorf("invalid from block %v", q.FromBlock)
+		}
+		from = q.FromBlock.Uint64()
+	}
+	if q.ToBlock != nil {
+		if !q.ToBlock.IsUint64() {
+			return fmt.Errorf("invalid to block %v", q.ToBlock)
+		}
+		to = q.ToBlock.Uint64()
+	} else {
+		head, err := ec.LatestBlockNumber(ctx)
+		if err != nil {
+			return err
+		}
+		to = head.Uint64()
+	}
+	for from <= to {
+		last := to
+		if to-from >= pageSize {
+			last = from + pageSize - 1
+		}
+		if err := ec.filterLogsRange(ctx, q, from, last, fn); err != nil {
+			return err
+		}
+		if last == to {
+			break
+		}
+		from = last + 1
+	}
+	return nil
+}
+
+// filterLogsRange queries the logs of the blocks from to to, bisecting the
+// range if the node refuses it as too large.
+func (ec *Client) filterLogsRange(ctx context.Context, q indigo.FilterQuery, from, to uint64, fn LogPageFunc) error {
+	q.FromBlock, q.ToBlock = new(big.Int).SetUint64(from), new(big.Int).SetUint64(to)
+
+	logs, err := ec.FilterLogs(ctx, q)
+	switch {
+	case err == nil:
+		return fn(from, to, logs)
+	case errors.Is(err, ErrResponseTooLarge) && from < to:
+		mid := from + (to-from)/2
+		if err := ec.filterLogsRange(ctx, q, from, mid, fn); err != nil {
+			return err
+		}
+		return ec.filterLogsRange(ctx, q, mid+1, to, fn)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+// LogService is an eth service with a log in every block up to head, which
+// refuses queries returning more than limit logs.
+type LogService struct {
+	head, limit uint64
+}
+
+func (s *LogService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(s.head)
+}
+
+func (s *LogService) GetLogs(args struct {
+	FromBlock hexutil.Uint64 `json:"fromBlock"`
+	ToBlock   hexutil.Uint64 `json:"toBlock"`
+}) ([]types.Log, error) {
+	from, to := uint64(args.FromBlock), uint64(args.ToBlock)
+	if to > s.head {
+		to = s.head
+	}
+	if to-from+1 > s.limit {
+		return nil, &codeError{code: -32005, msg: fmt.Sprintf("query returned more than %d results", s.limit)}
+	}
+	var logs []types.Log
+	for number := from; number <= to; number++ {
+		logs = append(logs, types.Log{BlockNumber: number, Topics: []common.Hash{}, Data: []byte{}})
+	}
+	return logs, nil
+}
+
+func newLogClient(t *testing.T, limit uint64) (*Client, func()) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &LogService{head: 99, limit: limit}); err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(rpc.DialInProc(server))
+	return client, func() {
+		client.c.Close()
+		server.Stop()
+	}
+}
+
+func TestFilterLogsPaged(t *testing.T) {
+	client, closer := newLogClient(t, 10)
+	defer closer()
+
+	var (
+		next  uint64
+		pages int
+	)
+	err := client.FilterLogsPaged(context.Background(), indigo.FilterQuery{}, 40, func(from, to uint64, logs []types.Log) error {
+		if from != next || to < from || to-from+1 > 10 {
+			return fmt.Errorf("page %d-%d out of order, want from %d", from, to, next)
+		}
+		if len(logs) != int(to-from+1) {
+			return fmt.Errorf("page %d-%d has %d logs", from, to, len(logs))
+		}
+		for i, log := range logs {
+			if log.BlockNumber != from+uint64(i) {
+				return fmt.Errorf("log %d of page %d-%d from block %d", i, from, to, log.BlockNumber)
+			}
+		}
+		next, pages = to+1, pages+1
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to filter logs: %v", err)
+	}
+	if next != 100 {
+		t.Errorf("logs filtered up to block %d, want 99", next-1)
+	}
+	// Pages of 40 blocks are bisected twice and the last one of 20 blocks once
+	if pages != 10 {
+		t.Errorf("page count mismatch: have %d, want 10", pages)
+	}
+	// Errors of the callback abort the iteration
+	stop := errors.New("stop")
+	q := indigo.FilterQuery{FromBlock: big.NewInt(20), ToBlock: big.NewInt(29)}
+	if err := client.FilterLogsPaged(context.Background(), q, 5, func(from, to uint64, logs []types.Log) error {
+		return stop
+	}); err != stop {
+		t.Errorf("callback error mismatch: have %v, want %v", err, stop)
+	}
+	// Single blocks refused as too large fail the query
+	client, closer = newLogClient(t, 0)
+	defer closer()
+
+	if err := client.FilterLogsPaged(context.Background(), q, 5, func(from, to uint64, logs []types.Log) error {
+		return nil
+	}); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("oversized block error mismatch: have %v, want %v", err, ErrResponseTooLarge)
+	}
+}
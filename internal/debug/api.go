@@ -62,6 +62,18 @@ func (*HandlerT) Vmodule(pattern string) error {
 	return glogger.Vmodule(pattern)
 }
 
+// ModuleVerbosity sets the log verbosity of the files matching a single vmodule
+// pattern, keeping the other patterns intact. See package log for details.
+func ModuleVerbosity(module string, level log.Lvl) error {
+	return glogger.ModuleVerbosity(module, level)
+}
+
+// GetVerbosity returns the log verbosity ceiling, and the verbosity of the
+// vmodule patterns raising it.
+func GetVerbosity() (log.Lvl, map[string]log.Lvl) {
+	return glogger.GetVerbosity(), glogger.Modules()
+}
+
 // BacktraceAt sets the log backtrace location. See package log for details on
 // the pattern syntax.
 func (*HandlerT) BacktraceAt(location string) error {
@@ -22,7 +22,9 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"runtime"
+	"sync"
 
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/log/term"
@@ -46,6 +48,11 @@ var (
 		Usage: "Request a stack trace at a specific logging statement (e.g. \"block.go:271\")",
 		Value: "",
 	}
+	logFormatFlag = cli.StringFlag{
+		Name:  "logformat",
+		Usage: "Log output format: terminal, logfmt or json (structured, with caller, module and chain head)",
+		Value: "terminal",
+	}
 	debugFlag = cli.BoolFlag{
 		Name:  "debug",
 		Usage: "Prepends log messages with call-site location (file and line number)",
@@ -81,30 +88,88 @@ var (
 		Name:  "trace",
 		Usage: "Write execution trace to the given file",
 	}
+	watchdogFlag = cli.BoolFlag{
+		Name:  "watchdog",
+		Usage: "Watch the goroutines and heap of the rpc, downloader, txpool and filters subsystems",
+	}
+	watchdogIntervalFlag = cli.DurationFlag{
+		Name:  "watchdog.interval",
+		Usage: "Interval between watchdog checks",
+		Value: defaultWatchdogInterval,
+	}
+	watchdogGoroutinesFlag = cli.StringFlag{
+		Name:  "watchdog.goroutines",
+		Usage: "Goroutine limits of the watched subsystems: comma-separated list of <subsystem>=<count>",
+		Value: "rpc=10000,downloader=2000,txpool=500,filters=2000",
+	}
+	watchdogHeapFlag = cli.StringFlag{
+		Name:  "watchdog.heap",
+		Usage: "Heap limits of the watched subsystems: comma-separated list of <subsystem>=<megabytes>",
+		Value: "rpc=2048,downloader=2048,txpool=1024,filters=1024",
+	}
+	watchdogDumpDirFlag = cli.StringFlag{
+		Name:  "watchdog.dumpdir",
+		Usage: "Directory the goroutine and heap dumps of subsystems over their limits are written to",
+		Value: filepath.Join(os.TempDir(), "indigo-watchdog"),
+	}
 )
 
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
-	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
+	verbosityFlag, vmoduleFlag, backtraceAtFlag, logFormatFlag, debugFlag,
 	pprofFlag, pprofAddrFlag, pprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
+	watchdogFlag, watchdogIntervalFlag, watchdogGoroutinesFlag, watchdogHeapFlag, watchdogDumpDirFlag,
 }
 
 var glogger *log.GlogHandler
 
+// logOutput is the destination of the logs, which may be redirected after the
+// log handler has been set up.
+var logOutput = new(redirectWriter)
+
 func init() {
 	usecolor := term.IsTty(os.Stderr.Fd()) && os.Getenv("TERM") != "dumb"
-	output := io.Writer(os.Stderr)
+	logOutput.w = os.Stderr
 	if usecolor {
-		output = colorable.NewColorableStderr()
+		logOutput.w = colorable.NewColorableStderr()
 	}
-	glogger = log.NewGlogHandler(log.StreamHandler(output, log.TerminalFormat(usecolor)))
+	glogger = log.NewGlogHandler(log.StreamHandler(logOutput, log.TerminalFormat(usecolor)))
+}
+
+// redirectWriter forwards writes to a replaceable writer.
+type redirectWriter struct {
+	w    io.Writer
+	lock sync.Mutex
+}
+
+func (r *redirectWriter) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.w.Write(p)
+}
+
+// RedirectLogs routes the log output through the writer returned by wrap, which
+// is handed the current destination of the logs.
+func RedirectLogs(wrap func(io.Writer) io.Writer) {
+	logOutput.lock.Lock()
+	defer logOutput.lock.Unlock()
+	logOutput.w = wrap(logOutput.w)
 }
 
 // Setup initializes profiling and logging based on the CLI flags.
 // It should be called as early as possible in the program.
 func Setup(ctx *cli.Context) error {
 	// logging
+	switch format := ctx.GlobalString(logFormatFlag.Name); format {
+	case "", "terminal":
+	case "logfmt":
+		glogger = log.NewGlogHandler(log.StreamHandler(logOutput, log.LogfmtFormat()))
+	case "json":
+		glogger = log.NewGlogHandler(log.StreamHandler(logOutput, log.JsonStructuredFormat()))
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
 	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
@@ -125,6 +190,31 @@ func Setup(ctx *cli.Context) error {
 		}
 	}
 
+	// subsystem watchdog
+	if ctx.GlobalBool(watchdogFlag.Name) {
+		goroutines, err := parseLimits(ctx.GlobalString(watchdogGoroutinesFlag.Name))
+		if err != nil {
+			return err
+		}
+		heap, err := parseLimits(ctx.GlobalString(watchdogHeapFlag.Name))
+		if err != nil {
+			return err
+		}
+		config := WatchdogConfig{
+			Interval:        ctx.GlobalDuration(watchdogIntervalFlag.Name),
+			GoroutineLimits: make(map[string]int),
+			HeapLimits:      make(map[string]uint64),
+			DumpDir:         ctx.GlobalString(watchdogDumpDirFlag.Name),
+		}
+		for name, limit := range goroutines {
+			config.GoroutineLimits[name] = int(limit)
+		}
+		for name, limit := range heap {
+			config.HeapLimits[name] = limit * 1024 * 1024
+		}
+		startWatchdog(config)
+	}
+
 	// pprof server
 	if ctx.GlobalBool(pprofFlag.Name) {
 		address := fmt.Sprintf("%s:%d", ctx.GlobalString(pprofAddrFlag.Name), ctx.GlobalInt(pprofPortFlag.Name))
@@ -141,6 +231,7 @@ func Setup(ctx *cli.Context) error {
 // Exit stops all running profiles, flushing their output to the
 // respective file.
 func Exit() {
+	stopWatchdog()
 	Handler.StopCPUProfile()
 	Handler.StopGoTrace()
 }
@@ -0,0 +1,442 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+/*
+Watchdogs
+
+Goroutines and heap are attributed to the major subsystems of the node by the
+functions on their stacks: goroutines by the function which created them, or
+failing that by the outermost function of their stack belonging to a subsystem,
+and sampled heap allocations by the innermost such function. The watchdog
+samples the attribution periodically, and raises an alert with a dump of the
+goroutine stacks and of the heap profile when a subsystem crosses its limits,
+to catch slow leaks long before the node runs out of memory.
+*/
+
+const (
+	defaultWatchdogInterval = time.Minute
+	watchdogDumpCooldown    = time.Hour // Minimum time between dumps of a subsystem
+)
+
+// watchedPackage is the import path prefix of the watched code.
+const watchedPackage = "github.com/fulcrumchain/indigo/"
+
+// Subsystem is a part of the node whose goroutines and heap are watched,
+// identified by the prefixes of its function names.
+type Subsystem struct {
+	Name     string
+	Prefixes []string
+}
+
+// WatchedSubsystems are the subsystems watched by default.
+var WatchedSubsystems = []Subsystem{
+	{Name: "rpc", Prefixes: []string{watchedPackage + "rpc."}},
+	{Name: "downloader", Prefixes: []string{watchedPackage + "eth/downloader."}},
+	{Name: "txpool", Prefixes: []string{
+		watchedPackage + "core.(*TxPool)", watchedPackage + "core.(*txList)",
+		watchedPackage + "core.(*txPricedList)", watchedPackage + "core.(*txJournal)",
+	}},
+	{Name: "filters", Prefixes: []string{watchedPackage + "eth/filters."}},
+}
+
+// match reports whether a function belongs to the subsystem.
+func (s *Subsystem) match(function string) bool {
+	for _, prefix := range s.Prefixes {
+		if strings.HasPrefix(function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SubsystemUsage is the usage of a subsystem at a point in time.
+type SubsystemUsage struct {
+	Subsystem      string `json:"subsystem"`
+	Goroutines     int    `json:"goroutines"`
+	Heap           uint64 `json:"heap"` // Estimated bytes in use, as of the last garbage collection
+	GoroutineLimit int    `json:"goroutineLimit,omitempty"`
+	HeapLimit      uint64 `json:"heapLimit,omitempty"`
+	Exceeded       bool   `json:"exceeded"`
+}
+
+// WatchdogReport is the usage of the watched subsystems at a point in time.
+type WatchdogReport struct {
+	Time       time.Time         `json:"time"`
+	Goroutines int               `json:"goroutines"` // Total number of goroutines
+	Heap       uint64            `json:"heap"`       // Total estimated bytes in use
+	Subsystems []*SubsystemUsage `json:"subsystems"`
+	Dumps      []string          `json:"dumps,omitempty"` // Diagnostics written for the exceeded limits
+}
+
+// WatchdogConfig are the settings of a watchdog.
+type WatchdogConfig struct {
+	Interval        time.Duration
+	GoroutineLimits map[string]int    // Goroutine limits by subsystem name
+	HeapLimits      map[string]uint64 // Heap limits in bytes by subsystem name
+	DumpDir         string            // Directory the diagnostics are written to, none if empty
+}
+
+// Watchdog periodically checks the usage of the subsystems against limits.
+type Watchdog struct {
+	config     WatchdogConfig
+	subsystems []Subsystem
+	dumped     map[string]time.Time // Time of the last dump by subsystem
+
+	goroutineGauges map[string]gometrics.Gauge
+	heapGauges      map[string]gometrics.Gauge
+	alertMeters     map[string]gometrics.Meter
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+	lock sync.Mutex
+}
+
+// NewWatchdog creates a watchdog of the given subsystems.
+func NewWatchdog(config WatchdogConfig, subsystems []Subsystem) *Watchdog {
+	if config.Interval <= 0 {
+		config.Interval = defaultWatchdogInterval
+	}
+	w := &Watchdog{
+		config:          config,
+		subsystems:      subsystems,
+		dumped:          make(map[string]time.Time),
+		goroutineGauges: make(map[string]gometrics.Gauge),
+		heapGauges:      make(map[string]gometrics.Gauge),
+		alertMeters:     make(map[string]gometrics.Meter),
+		quit:            make(chan struct{}),
+	}
+	for _, s := range subsystems {
+		w.goroutineGauges[s.Name] = metrics.NewGauge("watchdog/" + s.Name + "/goroutines")
+		w.heapGauges[s.Name] = metrics.NewGauge("watchdog/" + s.Name + "/heap")
+		w.alertMeters[s.Name] = metrics.NewMeter("watchdog/" + s.Name + "/alerts")
+	}
+	return w
+}
+
+// Start starts checking the usage periodically.
+func (w *Watchdog) Start() {
+	w.wg.Add(1)
+	go w.loop()
+}
+
+// Stop stops the periodic checks.
+func (w *Watchdog) Stop() {
+	close(w.quit)
+	w.wg.Wait()
+}
+
+func (w *Watchdog) loop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.Check()
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// Check samples the usage of the subsystems, raising alerts for the ones over
+// their limits.
+func (w *Watchdog) Check() *WatchdogReport {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	report := sampleUsage(w.subsystems)
+	for _, usage := range report.Subsystems {
+		usage.GoroutineLimit = w.config.GoroutineLimits[usage.Subsystem]
+		usage.HeapLimit = w.config.HeapLimits[usage.Subsystem]
+		usage.Exceeded = (usage.GoroutineLimit > 0 && usage.Goroutines > usage.GoroutineLimit) ||
+			(usage.HeapLimit > 0 && usage.Heap > usage.HeapLimit)
+
+		w.goroutineGauges[usage.Subsystem].Update(int64(usage.Goroutines))
+		w.heapGauges[usage.Subsystem].Update(int64(usage.Heap))
+		if !usage.Exceeded {
+			continue
+		}
+		w.alertMeters[usage.Subsystem].Mark(1)
+		log.Error("Subsystem over watchdog limits", "subsystem", usage.Subsystem,
+			"goroutines", usage.Goroutines, "goroutinelimit", usage.GoroutineLimit,
+			"heap", usage.Heap, "heaplimit", usage.HeapLimit)
+
+		if w.config.DumpDir == "" || time.Since(w.dumped[usage.Subsystem]) < watchdogDumpCooldown {
+			continue
+		}
+		dumps, err := writeDiagnostics(w.config.DumpDir, usage.Subsystem, report.Time)
+		if err != nil {
+			log.Error("Failed to write watchdog diagnostics", "subsystem", usage.Subsystem, "err", err)
+			continue
+		}
+		w.dumped[usage.Subsystem] = report.Time
+		report.Dumps = append(report.Dumps, dumps...)
+		log.Warn("Wrote watchdog diagnostics", "subsystem", usage.Subsystem, "files", strings.Join(dumps, ", "))
+	}
+	return report
+}
+
+// sampleUsage attributes the current goroutines and heap to the subsystems.
+func sampleUsage(subsystems []Subsystem) *WatchdogReport {
+	report := &WatchdogReport{Time: time.Now()}
+	usages := make(map[string]*SubsystemUsage)
+	for _, s := range subsystems {
+		usage := &SubsystemUsage{Subsystem: s.Name}
+		usages[s.Name] = usage
+		report.Subsystems = append(report.Subsystems, usage)
+	}
+	for _, stack := range goroutineStacks() {
+		report.Goroutines++
+		if s := attributeGoroutine(subsystems, stack); s != nil {
+			usages[s.Name].Goroutines++
+		}
+	}
+	records := memProfile()
+	for _, record := range records {
+		heap := scaledInUse(&record)
+		report.Heap += heap
+		if s := attributeAllocation(subsystems, record.Stack()); s != nil {
+			usages[s.Name].Heap += heap
+		}
+	}
+	return report
+}
+
+// goroutineStack is the function names of a goroutine stack, innermost first,
+// and of the function which created the goroutine.
+type goroutineStack struct {
+	functions []string
+	creator   string
+}
+
+// goroutineStacks returns the stacks of all the goroutines.
+func goroutineStacks() []goroutineStack {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return parseStacks(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parseStacks parses the goroutine stacks formatted by runtime.Stack.
+func parseStacks(dump []byte) []goroutineStack {
+	var stacks []goroutineStack
+	for _, block := range bytes.Split(dump, []byte("\n\n")) {
+		lines := strings.Split(strings.TrimSpace(string(block)), "\n")
+		if len(lines) == 0 || !strings.HasPrefix(lines[0], "goroutine ") {
+			continue
+		}
+		var stack goroutineStack
+		for _, line := range lines[1:] {
+			if strings.HasPrefix(line, "\t") {
+				continue // Source location of the previous function
+			}
+			if strings.HasPrefix(line, "created by ") {
+				stack.creator = functionName(strings.TrimPrefix(line, "created by "))
+				continue
+			}
+			stack.functions = append(stack.functions, functionName(line))
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks
+}
+
+// functionName strips the arguments and goroutine ids from a stack line.
+func functionName(line string) string {
+	if i := strings.Index(line, " in goroutine "); i >= 0 {
+		line = line[:i]
+	}
+	if strings.HasSuffix(line, ")") {
+		if i := strings.LastIndex(line, "("); i > 0 {
+			line = line[:i]
+		}
+	}
+	return line
+}
+
+// attributeGoroutine returns the subsystem a goroutine belongs to, if any.
+func attributeGoroutine(subsystems []Subsystem, stack goroutineStack) *Subsystem {
+	for i := range subsystems {
+		if subsystems[i].match(stack.creator) {
+			return &subsystems[i]
+		}
+	}
+	for j := len(stack.functions) - 1; j >= 0; j-- {
+		for i := range subsystems {
+			if subsystems[i].match(stack.functions[j]) {
+				return &subsystems[i]
+			}
+		}
+	}
+	return nil
+}
+
+// memProfile returns the heap profile records as of the last garbage collection.
+func memProfile() []runtime.MemProfileRecord {
+	n, _ := runtime.MemProfile(nil, false)
+	for {
+		records := make([]runtime.MemProfileRecord, n+50)
+		m, ok := runtime.MemProfile(records, false)
+		if ok {
+			return records[:m]
+		}
+		n = m
+	}
+}
+
+// scaledInUse estimates the bytes in use by the allocations of a heap profile
+// record, compensating for the sampling of the profile the way pprof does.
+func scaledInUse(record *runtime.MemProfileRecord) uint64 {
+	count, size := record.InUseObjects(), record.InUseBytes()
+	if count == 0 || size == 0 {
+		return 0
+	}
+	rate := runtime.MemProfileRate
+	if rate <= 1 {
+		return uint64(size)
+	}
+	avg := float64(size) / float64(count)
+	return uint64(float64(size) / (1 - math.Exp(-avg/float64(rate))))
+}
+
+// attributeAllocation returns the subsystem which allocated on the given call
+// stack, if any.
+func attributeAllocation(subsystems []Subsystem, stack []uintptr) *Subsystem {
+	frames := runtime.CallersFrames(stack)
+	for {
+		frame, more := frames.Next()
+		for i := range subsystems {
+			if subsystems[i].match(frame.Function) {
+				return &subsystems[i]
+			}
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// writeDiagnostics writes the goroutine stacks and the heap profile into dir,
+// returning the paths of the files written.
+func writeDiagnostics(dir, subsystem string, now time.Time) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	prefix := filepath.Join(dir, fmt.Sprintf("%s-%s", subsystem, now.Format("20060102-150405")))
+
+	var stacks bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&stacks, 2); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(prefix+".goroutines", stacks.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(prefix + ".heap")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := pprof.Lookup("heap").WriteTo(f, 0); err != nil {
+		return nil, err
+	}
+	return []string{prefix + ".goroutines", prefix + ".heap"}, f.Close()
+}
+
+// parseLimits parses a comma separated list of <subsystem>=<limit> pairs.
+func parseLimits(spec string) (map[string]uint64, error) {
+	limits := make(map[string]uint64)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid limit %q, want <subsystem>=<limit>", pair)
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid limit %q: %v", pair, err)
+		}
+		limits[strings.TrimSpace(kv[0])] = limit
+	}
+	return limits, nil
+}
+
+var (
+	watchdog     *Watchdog // Running watchdog, if enabled
+	watchdogLock sync.Mutex
+)
+
+// startWatchdog starts the process wide watchdog.
+func startWatchdog(config WatchdogConfig) {
+	watchdogLock.Lock()
+	defer watchdogLock.Unlock()
+
+	watchdog = NewWatchdog(config, WatchedSubsystems)
+	watchdog.Start()
+	log.Info("Started subsystem watchdog", "interval", config.Interval, "dumpdir", config.DumpDir)
+}
+
+// stopWatchdog stops the process wide watchdog, if running.
+func stopWatchdog() {
+	watchdogLock.Lock()
+	defer watchdogLock.Unlock()
+
+	if watchdog != nil {
+		watchdog.Stop()
+		watchdog = nil
+	}
+}
+
+// Watchdog reports the goroutines and heap attributed to the watched
+// subsystems, checking them against the watchdog limits if enabled.
+func (*HandlerT) Watchdog() *WatchdogReport {
+	watchdogLock.Lock()
+	defer watchdogLock.Unlock()
+
+	if watchdog != nil {
+		return watchdog.Check()
+	}
+	return sampleUsage(WatchedSubsystems)
+}
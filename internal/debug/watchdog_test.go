@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// leakGoroutines starts n goroutines blocking until release is closed.
+func leakGoroutines(n int, release chan struct{}) {
+	for i := 0; i < n; i++ {
+		go func() { <-release }()
+	}
+}
+
+func TestWatchdogGoroutines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchdog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	release := make(chan struct{})
+	defer close(release)
+	leakGoroutines(20, release)
+
+	subsystems := []Subsystem{
+		{Name: "leaky", Prefixes: []string{watchedPackage + "internal/debug.leakGoroutines"}},
+		{Name: "quiet", Prefixes: []string{watchedPackage + "nonexistent."}},
+	}
+	w := NewWatchdog(WatchdogConfig{
+		GoroutineLimits: map[string]int{"leaky": 10, "quiet": 10},
+		DumpDir:         dir,
+	}, subsystems)
+
+	report := w.Check()
+	leaky, quiet := report.Subsystems[0], report.Subsystems[1]
+	if leaky.Goroutines != 20 || !leaky.Exceeded {
+		t.Errorf("leaky subsystem mismatch: %+v", leaky)
+	}
+	if quiet.Goroutines != 0 || quiet.Exceeded {
+		t.Errorf("quiet subsystem mismatch: %+v", quiet)
+	}
+	if report.Goroutines < 20 {
+		t.Errorf("total goroutines mismatch: have %d, want at least 20", report.Goroutines)
+	}
+	if len(report.Dumps) != 2 {
+		t.Fatalf("dump count mismatch: have %d, want 2", len(report.Dumps))
+	}
+	for _, path := range report.Dumps {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("dump %s missing: %v", path, err)
+		}
+	}
+	// Dumps are rate limited
+	if report := w.Check(); len(report.Dumps) != 0 {
+		t.Errorf("repeated dump written: %v", report.Dumps)
+	}
+}
+
+func TestParseStacks(t *testing.T) {
+	dump := `goroutine 1 [running]:
+main.work(0x1)
+	/src/main.go:10 +0x1
+main.main()
+	/src/main.go:5 +0x2
+
+goroutine 7 [chan receive]:
+github.com/fulcrumchain/indigo/rpc.(*Server).serveRequest(0xc000)
+	/src/rpc/server.go:20 +0x3
+created by github.com/fulcrumchain/indigo/rpc.(*Server).ServeCodec in goroutine 1
+	/src/rpc/server.go:10 +0x4
+`
+	want := []goroutineStack{
+		{functions: []string{"main.work", "main.main"}},
+		{functions: []string{"github.com/fulcrumchain/indigo/rpc.(*Server).serveRequest"}, creator: "github.com/fulcrumchain/indigo/rpc.(*Server).ServeCodec"},
+	}
+	if stacks := parseStacks([]byte(dump)); !reflect.DeepEqual(stacks, want) {
+		t.Errorf("stacks mismatch:\nhave %+v\nwant %+v", stacks, want)
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	limits, err := parseLimits("rpc=100, txpool = 5,")
+	if err != nil {
+		t.Fatalf("failed to parse limits: %v", err)
+	}
+	if want := map[string]uint64{"rpc": 100, "txpool": 5}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("limits mismatch: have %v, want %v", limits, want)
+	}
+	for _, spec := range []string{"rpc", "rpc=x", "rpc=-1"} {
+		if _, err := parseLimits(spec); err == nil {
+			t.Errorf("invalid spec %q accepted", spec)
+		}
+	}
+}
@@ -94,6 +94,52 @@ func (s *PublicEthereumAPI) Syncing() (interface{}, error) {
 	}, nil
 }
 
+// SyncProgress is the detailed synchronisation progress returned by
+// eth_syncProgress.
+type SyncProgress struct {
+	StartingBlock    hexutil.Uint64 `json:"startingBlock"`
+	CurrentBlock     hexutil.Uint64 `json:"currentBlock"`
+	HighestBlock     hexutil.Uint64 `json:"highestBlock"`
+	PulledStates     hexutil.Uint64 `json:"pulledStates"`
+	KnownStates      hexutil.Uint64 `json:"knownStates"`
+	PendingStates    hexutil.Uint64 `json:"pendingStates"`
+	HealedStates     hexutil.Uint64 `json:"healedStates"`
+	DuplicateStates  hexutil.Uint64 `json:"duplicateStates"`
+	UnexpectedStates hexutil.Uint64 `json:"unexpectedStates"`
+	StateBytes       hexutil.Uint64 `json:"stateBytes"`
+	StatesPerSecond  float64        `json:"statesPerSecond"`
+	BytesPerSecond   float64        `json:"bytesPerSecond"`
+	StateETA         hexutil.Uint64 `json:"stateEta"` // Seconds, zero if unknown
+}
+
+// SyncProgress returns false in case the node is not syncing, or the block
+// sync boundaries along with the progress, throughput and estimated time to
+// completion of the state download otherwise.
+func (s *PublicEthereumAPI) SyncProgress() (interface{}, error) {
+	progress := s.b.Downloader().Progress()
+
+	// Return not syncing if the synchronisation already completed
+	if progress.CurrentBlock >= progress.HighestBlock {
+		return false, nil
+	}
+	state := s.b.Downloader().StateProgress()
+	return &SyncProgress{
+		StartingBlock:    hexutil.Uint64(progress.StartingBlock),
+		CurrentBlock:     hexutil.Uint64(progress.CurrentBlock),
+		HighestBlock:     hexutil.Uint64(progress.HighestBlock),
+		PulledStates:     hexutil.Uint64(progress.PulledStates),
+		KnownStates:      hexutil.Uint64(progress.KnownStates),
+		PendingStates:    hexutil.Uint64(state.Pending),
+		HealedStates:     hexutil.Uint64(state.Healed),
+		DuplicateStates:  hexutil.Uint64(state.Duplicate),
+		UnexpectedStates: hexutil.Uint64(state.Unexpected),
+		StateBytes:       hexutil.Uint64(state.Bytes),
+		StatesPerSecond:  state.NodeRate,
+		BytesPerSecond:   state.ByteRate,
+		StateETA:         hexutil.Uint64(state.ETA / time.Second),
+	}, nil
+}
+
 // PublicTxPoolAPI offers and API for the transaction pool. It only operates on data that is non confidential.
 type PublicTxPoolAPI struct {
 	b Backend
@@ -175,6 +221,74 @@ func (s *PublicTxPoolAPI) Inspect(ctx context.Context) map[string]map[string]map
 	return content
 }
 
+// InspectAccount reports the pooled transactions of an account, the nonce gaps
+// and underpriced transactions keeping them from being mined, and when its
+// queued transactions expire.
+func (s *PublicTxPoolAPI) InspectAccount(address common.Address) (*core.TxPoolAccount, error) {
+	return s.b.TxPoolAccount(address)
+}
+
+// RPCTxLifecycleEvent is a transaction pool lifecycle event as sent to the
+// subscribers of txpool_subscribe("events").
+type RPCTxLifecycleEvent struct {
+	Hash        common.Hash          `json:"hash"`
+	Kind        core.TxLifecycleKind `json:"kind"`
+	Reason      string               `json:"reason,omitempty"`
+	ReplacedBy  *common.Hash         `json:"replacedBy,omitempty"`
+	BlockHash   *common.Hash         `json:"blockHash,omitempty"`
+	BlockNumber *hexutil.Uint64      `json:"blockNumber,omitempty"`
+}
+
+func newRPCTxLifecycleEvent(ev core.TxLifecycleEvent) *RPCTxLifecycleEvent {
+	result := &RPCTxLifecycleEvent{
+		Hash:   ev.Hash,
+		Kind:   ev.Kind,
+		Reason: ev.Reason,
+	}
+	switch ev.Kind {
+	case core.TxReplaced:
+		result.ReplacedBy = &ev.ReplacedBy
+	case core.TxIncluded:
+		number := hexutil.Uint64(ev.BlockNumber)
+		result.BlockHash, result.BlockNumber = &ev.BlockHash, &number
+	}
+	return result
+}
+
+// Events creates a subscription that is triggered each time a pooled
+// transaction is queued, promoted, replaced, dropped or included in a block.
+// Events are best effort: they are skipped if the subscriber falls too far
+// behind.
+func (s *PublicTxPoolAPI) Events(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan core.TxLifecycleEvent, 128)
+		eventsSub := s.b.SubscribeTxLifecycleEvent(events)
+		defer eventsSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, newRPCTxLifecycleEvent(ev))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-eventsSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // PublicAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type PublicAccountAPI struct {
@@ -373,7 +487,7 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return submitTransaction(withValidUntil(ctx, args.ValidUntil), s.b, signed)
 }
 
 // SignTransaction will create a transaction from the given arguments and
@@ -492,13 +606,34 @@ func (s *PublicBlockChainAPI) BlockNumber() *big.Int {
 	return header.Number
 }
 
+// resolveFinalityTag resolves the "safe" and "finalized" tags to the number of
+// the block they currently select. Other block numbers are returned unchanged.
+func resolveFinalityTag(ctx context.Context, b Backend, blockNr rpc.BlockNumber) (rpc.BlockNumber, error) {
+	if blockNr != rpc.SafeBlockNumber && blockNr != rpc.FinalizedBlockNumber {
+		return blockNr, nil
+	}
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block %d not found", blockNr)
+	}
+	return rpc.BlockNumber(header.Number.Int64()), nil
+}
+
 // TotalSupply returns the total supply in wei as of the given block number. The
-// rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block numbers are also allowed.
+// rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block numbers, and the
+// finality tags are also allowed.
 func (s *PublicBlockChainAPI) TotalSupply(ctx context.Context, blockNr rpc.BlockNumber) (*big.Int, error) {
 	initial := s.b.InitialSupply()
 	if initial == nil {
 		return nil, fmt.Errorf("unknown initial allocation")
 	}
+	blockNr, err := resolveFinalityTag(ctx, s.b, blockNr)
+	if err != nil {
+		return nil, err
+	}
 	var n *big.Int
 	switch blockNr {
 	default:
@@ -532,19 +667,49 @@ func (s *PublicBlockChainAPI) GenesisAlloc(ctx context.Context) (core.GenesisAll
 }
 
 // GetBalance returns the amount of wei for the given address in the state of the
-// given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
-// block numbers are also allowed.
-func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*big.Int, error) {
+// given block number or hash. The rpc.LatestBlockNumber and rpc.PendingBlockNumber
+// meta block numbers are also allowed.
+func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*big.Int, error) {
 	ctx, span := trace.StartSpan(ctx, "PublicBlockChainAPI.GetBalance")
 	defer span.End()
 	var bal *big.Int
-	err := s.b.StateQuery(ctx, blockNr, func(state *state.StateDB) (err error) {
+	err := s.b.StateQuery(ctx, blockNrOrHash, func(state *state.StateDB) (err error) {
 		bal, err = state.GetBalanceErr(address)
 		return
 	})
 	return bal, err
 }
 
+// GetHeaderByNumber returns the requested canonical block header. When blockNr is -1 the chain head is returned.
+// Unlike GetBlockByNumber it doesn't load the block body.
+func (s *PublicBlockChainAPI) GetHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
+	ctx, span := trace.StartSpan(ctx, "PublicBlockChainAPI.GetHeaderByNumber")
+	defer span.End()
+	header, err := s.b.HeaderByNumber(ctx, blockNr)
+	if header != nil && err == nil {
+		response := s.rpcOutputHeader(header)
+		if blockNr == rpc.PendingBlockNumber {
+			// Pending headers need to nil out a few fields
+			for _, field := range []string{"hash", "nonce", "miner"} {
+				response[field] = nil
+			}
+		}
+		return response, nil
+	}
+	return nil, err
+}
+
+// GetHeaderByHash returns the requested block header. Unlike GetBlockByHash it doesn't load the block body.
+func (s *PublicBlockChainAPI) GetHeaderByHash(ctx context.Context, blockHash common.Hash) (map[string]interface{}, error) {
+	ctx, span := trace.StartSpan(ctx, "PublicBlockChainAPI.GetHeaderByHash")
+	defer span.End()
+	header, err := s.b.HeaderByHash(ctx, blockHash)
+	if header != nil && err == nil {
+		return s.rpcOutputHeader(header), nil
+	}
+	return nil, err
+}
+
 // GetBlockByNumber returns the requested block. When blockNr is -1 the chain head is returned. When fullTx is true all
 // transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
@@ -626,10 +791,10 @@ func (s *PublicBlockChainAPI) GetUncleCountByBlockHash(ctx context.Context, bloc
 	return nil
 }
 
-// GetCode returns the code stored at the given address in the state for the given block number.
-func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+// GetCode returns the code stored at the given address in the state for the given block number or hash.
+func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
 	var code hexutil.Bytes
-	err := s.b.StateQuery(ctx, blockNr, func(state *state.StateDB) (err error) {
+	err := s.b.StateQuery(ctx, blockNrOrHash, func(state *state.StateDB) (err error) {
 		code, err = state.GetCodeErr(address)
 		return
 	})
@@ -637,11 +802,11 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 }
 
 // GetStorageAt returns the storage from the state at the given address, key and
-// block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
-// numbers are also allowed.
-func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+// block number or hash. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
+// block numbers are also allowed.
+func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
 	var st common.Hash
-	err := s.b.StateQuery(ctx, blockNr, func(state *state.StateDB) (err error) {
+	err := s.b.StateQuery(ctx, blockNrOrHash, func(state *state.StateDB) (err error) {
 		st, err = state.GetStateErr(address, common.HexToHash(key))
 		return
 	})
@@ -658,13 +823,57 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// OverrideAccount holds the fields of an account to override during a call.
+// Unset fields keep their value in the state the call is executed on.
+type OverrideAccount struct {
+	Nonce   *hexutil.Uint64             `json:"nonce"`
+	Code    *hexutil.Bytes              `json:"code"`
+	Balance *hexutil.Big                `json:"balance"`
+	Storage map[common.Hash]common.Hash `json:"storage"` // Slots to override, others are kept
+}
+
+// StateOverride is the set of accounts to override during a call.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the accounts in the given state database.
+func (diff *StateOverride) Apply(statedb *state.StateDB) {
+	if diff == nil {
+		return
+	}
+	for addr, account := range *diff {
+		if account.Nonce != nil {
+			statedb.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			statedb.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			statedb.SetBalance(addr, account.Balance.ToInt())
+		}
+		for key, value := range account.Storage {
+			statedb.SetState(addr, key, value)
+		}
+	}
+}
 
-	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, vmCfg vm.Config) ([]byte, uint64, bool, error) {
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
 	if state == nil || err != nil {
 		return nil, 0, false, err
 	}
+	overrides.Apply(state)
+
+	return s.execute(ctx, args, state, header, vmCfg)
+}
+
+// execute applies the call on top of the given state, leaving its changes in it.
+func (s *PublicBlockChainAPI) execute(ctx context.Context, args CallArgs, state *state.StateDB, header *types.Header, vmCfg vm.Config) ([]byte, uint64, bool, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	// Refuse to execute contracts blocked by the node policy, unless allowed
+	if blocklist := s.b.Blocklist(); args.To != nil && !blocklist.CallsAllowed() && blocklist.Blocked(*args.To, state.GetCodeHash(*args.To)) {
+		return nil, 0, false, core.ErrBlockedContract
+	}
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
@@ -716,16 +925,28 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	return core.ApplyMessage(evm, msg, gp)
 }
 
-// Call executes the given transaction on the state for the given block number.
+// Call executes the given transaction on the state for the given block number or hash.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{DisableGasMetering: true})
+//
+// The optional overrides replace the balance, nonce, code or storage slots of
+// accounts for the duration of the call, to simulate hypothetical states.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, _, _, err := s.doCall(ctx, args, blockNrOrHash, overrides, vm.Config{DisableGasMetering: true})
 	return (hexutil.Bytes)(result), err
 }
 
+// estimateGasErrorRatio is the relative precision at which gas estimation stops
+// searching, trading a slightly higher limit for fewer executions.
+const estimateGasErrorRatio = 0.02
+
 // EstimateGas returns an estimate of the amount of gas needed to execute the
-// given transaction against the current pending block.
-func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (hexutil.Uint64, error) {
+// given transaction against the given block, or the current pending block if
+// none is given.
+func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Uint64, error) {
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
 		lo  uint64 = params.TxGas - 1
@@ -734,44 +955,106 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	)
 	if uint64(args.Gas) >= params.TxGas {
 		hi = uint64(args.Gas)
-	} else {
+	} else if blockNr, ok := bNrOrHash.Number(); ok && blockNr == rpc.PendingBlockNumber {
 		// Retrieve the current pending block to act as the gas ceiling
 		block, err := s.b.BlockByNumber(ctx, rpc.PendingBlockNumber)
 		if err != nil {
 			return 0, err
 		}
 		hi = block.GasLimit()
+	} else {
+		// Retrieve the requested block to act as the gas ceiling
+		header, err := s.b.HeaderByNumberOrHash(ctx, bNrOrHash)
+		if err != nil {
+			return 0, err
+		}
+		if header == nil {
+			return 0, fmt.Errorf("block not found")
+		}
+		hi = header.GasLimit
 	}
 	cap = hi
 
-	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	// Execute all attempts on the same state, reverting their changes in between
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if state == nil || err != nil {
+		return 0, err
+	}
+	// Create a helper to check if a gas allowance results in an executable
+	// transaction, returning the gas it consumed before refunds if it does
+	executable := func(gas uint64) (uint64, bool) {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{})
+		snapshot, refund := state.Snapshot(), state.GetRefund()
+		defer state.RevertToSnapshot(snapshot)
+
+		_, used, failed, err := s.execute(ctx, args, state, header, vm.Config{})
 		if err != nil || failed {
-			return false
+			return 0, false
+		}
+		return consumedGas(used, state.GetRefund()-refund), true
+	}
+	// Execute at the highest allowance first. If the transaction succeeds, the
+	// gas it consumed is a lower bound of its requirement and usually an exact
+	// one, so only transactions forwarding gas to calls need a few more attempts.
+	if consumed, ok := executable(hi); ok {
+		if consumed > lo+1 {
+			lo = consumed - 1
+		}
+		if lo+1 < hi {
+			if _, ok := executable(lo + 1); ok {
+				return hexutil.Uint64(lo + 1), nil
+			}
+			lo++
+		}
+		// Calls are only given 63/64 of the remaining gas, so try a margin
+		// covering the gas withheld at a single call depth
+		if guess := lo * 64 / 63; guess < hi {
+			if _, ok := executable(guess); ok {
+				hi = guess
+			} else {
+				lo = guess
+			}
 		}
-		return true
+		// Hone in on the requirement, settling for a slight overestimate
+		for lo+1 < hi && float64(hi-lo)/float64(hi) > estimateGasErrorRatio {
+			mid := (hi + lo) / 2
+			if _, ok := executable(mid); !ok {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		return hexutil.Uint64(hi), nil
 	}
-	// Execute the binary search and hone in on an executable gas limit
+	// The transaction may still succeed with a lower allowance, e.g. if the
+	// sender can't pay for the highest one, so binary search the whole range
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		if _, ok := executable(mid); !ok {
 			lo = mid
 		} else {
 			hi = mid
 		}
 	}
-	// Reject the transaction as invalid if it still fails at the highest allowance
+	// Reject the transaction as invalid if it fails at the highest allowance
 	if hi == cap {
-		if !executable(hi) {
-			return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
-		}
+		return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
 	}
 	return hexutil.Uint64(hi), nil
 }
 
+// consumedGas returns the gas a transaction consumed before the refund was
+// deducted, given the gas it used in the end and its refund counter. As the
+// refund is capped to half of the consumed gas, larger counters only give an
+// upper bound of the deducted refund.
+func consumedGas(used, refund uint64) uint64 {
+	if refund <= used {
+		return used + refund
+	}
+	return 2*used - 1
+}
+
 // ExecutionResult groups all structured logs emitted by the EVM
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
@@ -833,14 +1116,12 @@ func FormatLogs(logs []vm.StructLog) []StructLogRes {
 	return formatted
 }
 
-// rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
-// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
-// transaction hashes.
-func (s *PublicBlockChainAPI) rpcOutputBlock(ctx context.Context, b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
-	head := b.Header() // copies the header once
-	fields := map[string]interface{}{
+// rpcOutputHeader converts the given header to the RPC output.
+func (s *PublicBlockChainAPI) rpcOutputHeader(head *types.Header) map[string]interface{} {
+	hash := head.Hash()
+	return map[string]interface{}{
 		"number":           (*hexutil.Big)(head.Number),
-		"hash":             b.Hash(),
+		"hash":             hash,
 		"parentHash":       head.ParentHash,
 		"nonce":            head.Nonce,
 		"mixHash":          head.MixDigest,
@@ -849,18 +1130,25 @@ func (s *PublicBlockChainAPI) rpcOutputBlock(ctx context.Context, b *types.Block
 		"stateRoot":        head.Root,
 		"miner":            head.Coinbase,
 		"difficulty":       (*hexutil.Big)(head.Difficulty),
-		"totalDifficulty":  (*hexutil.Big)(s.b.GetTd(b.Hash())),
+		"totalDifficulty":  (*hexutil.Big)(s.b.GetTd(hash)),
 		"extraData":        hexutil.Bytes(head.Extra),
 		"signers":          head.Signers,
 		"voters":           head.Voters,
 		"signer":           hexutil.Bytes(head.Signer),
-		"size":             hexutil.Uint64(b.Size()),
 		"gasLimit":         hexutil.Uint64(head.GasLimit),
 		"gasUsed":          hexutil.Uint64(head.GasUsed),
 		"timestamp":        (*hexutil.Big)(head.Time),
 		"transactionsRoot": head.TxHash,
 		"receiptsRoot":     head.ReceiptHash,
 	}
+}
+
+// rpcOutputBlock converts the given block to the RPC output which depends on fullTx. If inclTx is true transactions are
+// returned. When fullTx is true the returned block contains full transaction details, otherwise it will only contain
+// transaction hashes.
+func (s *PublicBlockChainAPI) rpcOutputBlock(ctx context.Context, b *types.Block, inclTx bool, fullTx bool) (map[string]interface{}, error) {
+	fields := s.rpcOutputHeader(b.Header()) // copies the header once
+	fields["size"] = hexutil.Uint64(b.Size())
 
 	if inclTx {
 		formatTx := func(tx *types.Transaction) (interface{}, error) {
@@ -910,6 +1198,7 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+	Type             *hexutil.Uint64 `json:"type,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -935,6 +1224,10 @@ func newRPCTransaction(ctx context.Context, tx *types.Transaction, blockHash com
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
 	}
+	if tx.Type() != types.LegacyTxType {
+		typ := hexutil.Uint64(tx.Type())
+		result.Type = &typ
+	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = blockHash
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
@@ -963,7 +1256,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1038,12 +1331,12 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByBlockHashAndIndex(ctx cont
 	return nil
 }
 
-// GetTransactionCount returns the number of transactions the given address has sent for the given block number
-func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Uint64, error) {
+// GetTransactionCount returns the number of transactions the given address has sent for the given block number or hash
+func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
 	ctx, span := trace.StartSpan(ctx, "PublicTransactionPoolAPI.GetTransactionCount")
 	defer span.End()
 	var nonce uint64
-	err := s.b.StateQuery(ctx, blockNr, func(state *state.StateDB) (err error) {
+	err := s.b.StateQuery(ctx, blockNrOrHash, func(state *state.StateDB) (err error) {
 		nonce, err = state.GetNonceErr(address)
 		return
 	})
@@ -1064,6 +1357,125 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	return nil
 }
 
+// Page sizes of eth_getTransactionsByAddress.
+const (
+	defaultAddressTxLimit = 100
+	maxAddressTxLimit     = 1000
+)
+
+// AddressTxPagination selects a page of the transactions of an address: the
+// first Offset matches are skipped and at most Limit are returned.
+type AddressTxPagination struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// addressRange resolves the block range and the page of an address query.
+func addressRange(ctx context.Context, b Backend, fromBlock, toBlock rpc.BlockNumber, page *AddressTxPagination) (from, to uint64, offset, limit int, err error) {
+	resolve := func(number rpc.BlockNumber) (uint64, error) {
+		number, err := resolveFinalityTag(ctx, b, number)
+		if err != nil {
+			return 0, err
+		}
+		if number < 0 {
+			return b.CurrentBlock().NumberU64(), nil
+		}
+		return uint64(number), nil
+	}
+	if from, err = resolve(fromBlock); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if to, err = resolve(toBlock); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if from > to {
+		return 0, 0, 0, 0, fmt.Errorf("invalid block range %d > %d", from, to)
+	}
+	offset, limit = 0, defaultAddressTxLimit
+	if page != nil {
+		if page.Offset < 0 || page.Limit < 0 {
+			return 0, 0, 0, 0, errors.New("negative pagination")
+		}
+		if page.Limit > maxAddressTxLimit {
+			return 0, 0, 0, 0, fmt.Errorf("page limit %d above maximum %d", page.Limit, maxAddressTxLimit)
+		}
+		offset = page.Offset
+		if page.Limit > 0 {
+			limit = page.Limit
+		}
+	}
+	return from, to, offset, limit, nil
+}
+
+// GetTransactionsByAddress returns the transactions sent from or to an address,
+// including contract creations, within the given block range in chain order.
+// It requires the node to maintain the address index.
+func (s *PublicTransactionPoolAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, page *AddressTxPagination) ([]*RPCTransaction, error) {
+	from, to, offset, limit, err := addressRange(ctx, s.b, fromBlock, toBlock, page)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.b.GetAddressTransactions(ctx, address, from, to, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*RPCTransaction, 0, len(entries))
+	for _, entry := range entries {
+		block, err := s.b.GetBlock(ctx, entry.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block #%d [%x…] not found", entry.BlockNumber, entry.BlockHash[:4])
+		}
+		tx := newRPCTransactionFromBlockIndex(ctx, block, uint64(entry.Index))
+		if tx == nil {
+			return nil, fmt.Errorf("transaction %d of block #%d not found", entry.Index, entry.BlockNumber)
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
+
+// RPCInternalTransaction is a value transfer made by a contract while executing
+// a transaction.
+type RPCInternalTransaction struct {
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      *hexutil.Big   `json:"blockNumber"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint   `json:"transactionIndex"`
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	Value            *hexutil.Big   `json:"value"`
+}
+
+// GetInternalTransactions returns the value transfers made by contracts from or
+// to an address within the given block range in chain order. It requires the
+// node to maintain the internal transaction index.
+func (s *PublicTransactionPoolAPI) GetInternalTransactions(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, page *AddressTxPagination) ([]*RPCInternalTransaction, error) {
+	from, to, offset, limit, err := addressRange(ctx, s.b, fromBlock, toBlock, page)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.b.GetInternalTransactions(ctx, address, from, to, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	txs := make([]*RPCInternalTransaction, 0, len(entries))
+	for _, entry := range entries {
+		txs = append(txs, &RPCInternalTransaction{
+			BlockHash:        entry.BlockHash,
+			BlockNumber:      (*hexutil.Big)(new(big.Int).SetUint64(entry.BlockNumber)),
+			TransactionHash:  entry.TxHash,
+			TransactionIndex: hexutil.Uint(entry.TxIndex),
+			From:             entry.From,
+			To:               entry.To,
+			Value:            (*hexutil.Big)(entry.Value),
+		})
+	}
+	return txs, nil
+}
+
 // GetRawTransactionByHash returns the bytes of the transaction for the given hash.
 func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	var tx *types.Transaction
@@ -1075,8 +1487,8 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	// Serialize to the canonical encoding and return
+	return tx.MarshalBinary()
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1087,9 +1499,185 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	}
 	receipt, _, _, _ := core.GetReceipt(s.b.ChainDb(), hash) // Old receipts don't have the lookup data available
 	if receipt == nil {
+		// Report receipts removed by the retention policy as such
+		if _, err := s.b.GetReceipts(ctx, blockHash); err != nil {
+			return nil, err
+		}
 		return nil, nil
 	}
+	return marshalReceipt(ctx, receipt, tx, blockHash, blockNumber, index), nil
+}
 
+// GetBlockReceipts returns the receipts of all transactions in the given block,
+// in the format of GetTransactionReceipt. The receipts are read from the
+// database at once, rather than looked up transaction by transaction.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	block, err := s.b.GetBlock(ctx, header.Hash())
+	if block == nil || err != nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, nil // Receipts not stored, e.g. for the pending block
+	}
+	fields := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		fields[i] = marshalReceipt(ctx, receipt, txs[i], block.Hash(), block.NumberU64(), uint64(i))
+	}
+	return fields, nil
+}
+
+// TxOrderCandidate is a candidate transaction a sealer committed to, stamped
+// with the time it arrived at the sealer.
+type TxOrderCandidate struct {
+	Hash    common.Hash    `json:"hash"`
+	Arrival hexutil.Uint64 `json:"arrival"` // Unix milliseconds, 0 if unknown
+}
+
+// TxOrderAudit is the result of checking the transaction order of a block
+// against the candidates its sealer committed to and the arrival times seen by
+// the local node.
+type TxOrderAudit struct {
+	Commitment common.Hash        `json:"commitment"` // Commitment in the extra-data vanity
+	Committed  bool               `json:"committed"`  // Whether the candidates match the commitment and list all transactions of the block
+	Candidates hexutil.Uint       `json:"candidates"` // Number of candidates committed to, 0 if unknown
+	Deviations []TxOrderInversion `json:"deviations"` // Transactions sealed after others stamped later by the sealer itself
+	Observed   hexutil.Uint       `json:"observed"`   // Number of transactions whose arrival was seen locally
+	Inversions []TxOrderInversion `json:"inversions"` // Transactions sealed after others which arrived later locally
+}
+
+// TxOrderInversion is a transaction sealed after one of another account, even
+// though it arrived first.
+type TxOrderInversion struct {
+	Tx    common.Hash    `json:"tx"`
+	After common.Hash    `json:"after"` // Latest arrived transaction sealed before Tx
+	Lead  hexutil.Uint64 `json:"lead"`  // Milliseconds by which Tx arrived before After
+}
+
+// GetTxOrderCandidates returns the arrival stamped candidates the local sealer
+// committed to when sealing the given block, for others to audit its order
+// with, or nil if the block wasn't sealed recently by the local node.
+func (s *PublicTransactionPoolAPI) GetTxOrderCandidates(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]TxOrderCandidate, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil || len(header.Extra) < common.HashLength {
+		return nil, err
+	}
+	arrivals, ok := s.b.TxOrderCandidates(common.BytesToHash(clique.ExtraVanity(header.Extra)))
+	if !ok {
+		return nil, nil
+	}
+	candidates := make([]TxOrderCandidate, len(arrivals))
+	for i, arrival := range arrivals {
+		candidates[i] = TxOrderCandidate{Hash: arrival.Hash, Arrival: hexutil.Uint64(arrival.Time)}
+	}
+	return candidates, nil
+}
+
+// VerifyTxOrder audits the first come first served order of the transactions
+// in the given block. The sealer commits to the arrival stamped candidates it
+// ordered the block from, which are either given, as published by the sealer
+// through GetTxOrderCandidates, or known locally if this node sealed the block.
+// Once they match the commitment, the sealed order is checked against the
+// stamps, and independently against the arrival times seen locally. As arrival
+// times differ across the network, small local inversions are expected.
+func (s *PublicTransactionPoolAPI) VerifyTxOrder(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, candidates *[]TxOrderCandidate) (*TxOrderAudit, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, err
+	}
+	block, err := s.b.GetBlock(ctx, header.Hash())
+	if block == nil || err != nil {
+		return nil, err
+	}
+	var (
+		txs    = block.Transactions()
+		signer = types.MakeSigner(s.b.ChainConfig(), block.Number())
+		audit  = &TxOrderAudit{
+			Commitment: common.BytesToHash(clique.ExtraVanity(header.Extra)),
+			Deviations: []TxOrderInversion{},
+			Inversions: []TxOrderInversion{},
+		}
+	)
+	// Check the sealed order against the stamps the sealer committed to
+	arrivals, ok := s.b.TxOrderCandidates(audit.Commitment)
+	if candidates != nil {
+		arrivals, ok = make([]types.TxArrival, len(*candidates)), true
+		for i, candidate := range *candidates {
+			arrivals[i] = types.TxArrival{Hash: candidate.Hash, Time: uint64(candidate.Arrival)}
+		}
+	}
+	if ok && len(header.Extra) >= common.HashLength && audit.Commitment == types.TxOrderCommitment(arrivals) {
+		stamps := make(map[common.Hash]uint64, len(arrivals))
+		for _, arrival := range arrivals {
+			stamps[arrival.Hash] = arrival.Time
+		}
+		audit.Committed = true
+		for _, tx := range txs {
+			if _, ok := stamps[tx.Hash()]; !ok {
+				audit.Committed = false
+			}
+		}
+		audit.Candidates = hexutil.Uint(len(arrivals))
+		_, audit.Deviations = orderInversions(ctx, signer, txs, func(hash common.Hash) (time.Time, bool) {
+			stamp := stamps[hash]
+			return time.Unix(0, int64(stamp)*int64(time.Millisecond)), stamp != 0
+		})
+	}
+	// Check the sealed order against the local arrival times
+	audit.Observed, audit.Inversions = orderInversions(ctx, signer, txs, s.b.TxArrivalTime)
+	return audit, nil
+}
+
+// orderInversions returns the number of transactions of known arrival, and those
+// sealed after a transaction of another account which arrived later. A
+// transaction only counts as arrived once its predecessors of the same account
+// did, as the nonce order takes precedence, and those following one of unknown
+// arrival are skipped.
+func orderInversions(ctx context.Context, signer types.Signer, txs types.Transactions, arrival func(common.Hash) (time.Time, bool)) (hexutil.Uint, []TxOrderInversion) {
+	var (
+		observed   hexutil.Uint
+		inversions = []TxOrderInversion{}
+		eligible   = make(map[common.Address]time.Time) // Arrival of the last transaction of each account
+		unseen     = make(map[common.Address]bool)      // Accounts with transactions of unknown arrival
+		latest     time.Time
+		after      common.Hash
+	)
+	for _, tx := range txs {
+		from, _ := types.Sender(ctx, signer, tx)
+		arrived, ok := arrival(tx.Hash())
+		if !ok || unseen[from] {
+			unseen[from] = true
+			continue
+		}
+		observed++
+		if prev := eligible[from]; prev.After(arrived) {
+			arrived = prev
+		}
+		eligible[from] = arrived
+
+		if arrived.Before(latest) {
+			inversions = append(inversions, TxOrderInversion{
+				Tx:    tx.Hash(),
+				After: after,
+				Lead:  hexutil.Uint64(latest.Sub(arrived) / time.Millisecond),
+			})
+			continue
+		}
+		latest, after = arrived, tx.Hash()
+	}
+	return observed, inversions
+}
+
+// marshalReceipt converts the receipt of a transaction into its RPC representation.
+func marshalReceipt(ctx context.Context, receipt *types.Receipt, tx *types.Transaction, blockHash common.Hash, blockNumber uint64, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
 		signer = types.NewEIP155Signer(tx.ChainId())
@@ -1099,7 +1687,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1123,7 +1711,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1155,6 +1743,11 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// Unix time until which the transaction may be included, after which the
+	// local pool drops it. It is not part of the signed transaction, and is
+	// refused if the local miner is not running.
+	ValidUntil *hexutil.Uint64 `json:"validUntil"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1199,6 +1792,15 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	return types.NewTransaction(uint64(*args.Nonce), *args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
 }
 
+// withValidUntil returns ctx submitting transactions valid until the given Unix
+// time, if any.
+func withValidUntil(ctx context.Context, validUntil *hexutil.Uint64) context.Context {
+	if validUntil == nil {
+		return ctx
+	}
+	return core.WithValidUntil(ctx, time.Unix(int64(*validUntil), 0))
+}
+
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
 func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
 	ctx, span := trace.StartSpan(ctx, "submitTransaction")
@@ -1260,19 +1862,39 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, signed)
+	return submitTransaction(withValidUntil(ctx, args.ValidUntil), s.b, signed)
 }
 
 // SendRawTransaction will add the signed transaction to the transaction pool.
 // The sender is responsible for signing the transaction and using the correct nonce.
-func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
+// If validUntil is given, the transaction is kept for the local miner only and
+// dropped from the pool once that Unix time passes without it being included;
+// it is refused if the local miner is not running.
+func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes, validUntil *hexutil.Uint64) (common.Hash, error) {
 	ctx, span := trace.StartSpan(ctx, "PublicTransactionPoolAPI.SendRawTransaction")
 	defer span.End()
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
-	return submitTransaction(ctx, s.b, tx)
+	return submitTransaction(withValidUntil(ctx, validUntil), s.b, tx)
+}
+
+// SendRawTransactionPrivate adds the signed transaction to the local pool
+// without propagating it, so only the local miner can include it. If validUntil
+// is given, the miner only includes it until that Unix time.
+func (s *PublicTransactionPoolAPI) SendRawTransactionPrivate(ctx context.Context, encodedTx hexutil.Bytes, validUntil *hexutil.Uint64) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.b.SendPrivateTx(withValidUntil(ctx, validUntil), tx); err != nil {
+		return common.Hash{}, err
+	}
+	if log.Tracing() {
+		log.Trace("Submitted private transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To())
+	}
+	return tx.Hash(), nil
 }
 
 // Sign calculates an ECDSA signature for:
@@ -1326,7 +1948,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
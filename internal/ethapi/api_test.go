@@ -0,0 +1,690 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/accounts"
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/common/math"
+	"github.com/fulcrumchain/indigo/consensus/clique"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/state"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/eth/downloader"
+	"github.com/fulcrumchain/indigo/ethdb"
+	"github.com/fulcrumchain/indigo/event"
+	"github.com/fulcrumchain/indigo/params"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+var updateSnapshots = flag.Bool("update", false, "rewrite the recorded RPC responses in testdata/rpc")
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testRecv    = common.HexToAddress("0x0000000000000000000000000000000000001234")
+	testBalance = big.NewInt(1000000000000000000)
+)
+
+// testBackend is a Backend serving a fixed, locally generated chain. It has
+// no transaction pool, miner or accounts.
+type testBackend struct {
+	db      *ethdb.MemDatabase
+	chain   *core.BlockChain
+	gspec   *core.Genesis
+	am      *accounts.Manager
+	mux     *event.TypeMux
+	txFeed  event.Feed
+	pending types.Transactions
+
+	txEventFeed event.Feed
+	blocklist   *core.Blocklist
+	arrivals    map[common.Hash]time.Time
+	candidates  map[common.Hash][]types.TxArrival
+}
+
+// newTestBackend creates a backend seeded with the fixture chain: n blocks
+// on top of a fixed genesis, each carrying a single value transfer.
+func newTestBackend(t *testing.T, n int) *testBackend {
+	ctx := context.Background()
+	var (
+		db     = ethdb.NewMemDatabase()
+		engine = clique.NewFaker()
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testAddr: {Balance: testBalance}},
+			Signer: hexutil.MustDecode("0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"),
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blocks, _ := core.GenerateChain(ctx, gspec.Config, genesis, engine, db, n, func(ctx context.Context, i int, gen *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(testAddr), testRecv, big.NewInt(int64(1000*(i+1))), params.TxGas, big.NewInt(1), nil), signer, testKey)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		gen.AddTx(ctx, tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := chain.InsertChain(ctx, blocks); err != nil {
+		t.Fatalf("failed to insert fixture chain: %v", err)
+	}
+	// Index the fixture transfers as the address indexer of a full node would
+	for _, block := range blocks {
+		for i := range block.Transactions() {
+			for _, addr := range []common.Address{testAddr, testRecv} {
+				if err := core.WriteAddressTx(db, addr, block.NumberU64(), uint32(i), block.Hash()); err != nil {
+					t.Fatalf("failed to index transaction: %v", err)
+				}
+			}
+		}
+	}
+	return &testBackend{db: db, chain: chain, gspec: gspec, am: accounts.NewManager(), mux: new(event.TypeMux)}
+}
+
+func (b *testBackend) Downloader() *downloader.Downloader                 { return nil }
+func (b *testBackend) ProtocolVersion() int                               { return 63 }
+func (b *testBackend) SuggestPrice(ctx context.Context) (*big.Int, error) { return big.NewInt(1), nil }
+func (b *testBackend) ChainDb() ethdb.Database                            { return b.db }
+func (b *testBackend) EventMux() *event.TypeMux                           { return b.mux }
+func (b *testBackend) AccountManager() *accounts.Manager                  { return b.am }
+func (b *testBackend) ChainConfig() *params.ChainConfig                   { return b.gspec.Config }
+func (b *testBackend) CurrentBlock() *types.Block                         { return b.chain.CurrentBlock() }
+func (b *testBackend) InitialSupply() *big.Int                            { return b.gspec.Alloc.Total() }
+func (b *testBackend) Blocklist() *core.Blocklist                         { return b.blocklist }
+func (b *testBackend) GenesisAlloc() core.GenesisAlloc                    { return b.gspec.Alloc }
+func (b *testBackend) GetTd(blockHash common.Hash) *big.Int               { return b.chain.GetTdByHash(blockHash) }
+
+func (b *testBackend) SetHead(number uint64) {
+	if err := b.chain.SetHead(number); err != nil {
+		panic(err)
+	}
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(blockNr)), nil
+}
+
+func (b *testBackend) HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(blockHash), nil
+}
+
+func (b *testBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.HeaderByNumber(ctx, blockNr)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	header := b.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, &core.BlockNotFoundError{Hash: hash}
+	}
+	if blockNrOrHash.RequireCanonical && core.GetCanonicalHash(b.db, header.Number.Uint64()) != hash {
+		return nil, &core.NonCanonicalBlockError{Hash: hash, Number: header.Number.Uint64()}
+	}
+	return header, nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(blockNr)), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumber(ctx, blockNr)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	stateDb, err := b.chain.StateAt(header.Root)
+	return stateDb, header, err
+}
+
+func (b *testBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	stateDb, err := b.chain.StateAt(header.Root)
+	return stateDb, header, err
+}
+
+func (b *testBackend) StateQuery(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, fn func(*state.StateDB) error) error {
+	stateDb, _, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if stateDb == nil || err != nil {
+		return err
+	}
+	return fn(stateDb)
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(blockHash), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	return core.GetBlockReceipts(b.db, blockHash, core.GetBlockNumber(b.db, blockHash)), nil
+}
+
+func (b *testBackend) GetAddressTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.AddressTx, error) {
+	return core.GetAddressTxs(b.db, addr, from, to, skip, limit)
+}
+
+func (b *testBackend) GetInternalTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.InternalTx, error) {
+	return core.GetInternalTxs(b.db, addr, from, to, skip, limit)
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(context, state, b.gspec.Config, vmCfg), nil
+}
+
+func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
+	return b.chain.SubscribeChainEvent(ch)
+}
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.chain.SubscribeChainHeadEvent(ch)
+}
+
+func (b *testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
+	return b.chain.SubscribeChainSideEvent(ch)
+}
+
+func (b *testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	b.pending = append(b.pending, signedTx)
+	return nil
+}
+
+func (b *testBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return b.SendTx(ctx, signedTx)
+}
+
+func (b *testBackend) GetPoolTransactions() types.Transactions { return b.pending }
+
+func (b *testBackend) TxArrivalTime(txHash common.Hash) (time.Time, bool) {
+	t, ok := b.arrivals[txHash]
+	return t, ok
+}
+
+func (b *testBackend) TxOrderCandidates(commitment common.Hash) ([]types.TxArrival, bool) {
+	candidates, ok := b.candidates[commitment]
+	return candidates, ok
+}
+
+func (b *testBackend) GetPoolTransaction(txHash common.Hash) *types.Transaction {
+	for _, tx := range b.pending {
+		if tx.Hash() == txHash {
+			return tx
+		}
+	}
+	return nil
+}
+
+func (b *testBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	var nonce uint64
+	err := b.StateQuery(ctx, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), func(db *state.StateDB) error {
+		nonce = db.GetNonce(addr)
+		return nil
+	})
+	return nonce, err
+}
+
+func (b *testBackend) Stats() (pending int, queued int) { return len(b.pending), 0 }
+
+func (b *testBackend) TxPoolContent(context.Context) (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return make(map[common.Address]types.Transactions), make(map[common.Address]types.Transactions)
+}
+
+func (b *testBackend) TxPoolAccount(addr common.Address) (*core.TxPoolAccount, error) {
+	return &core.TxPoolAccount{Address: addr}, nil
+}
+
+func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+func (b *testBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return b.txEventFeed.Subscribe(ch)
+}
+
+// newTestServer registers the public APIs of backend with a fresh RPC server.
+func newTestServer(t *testing.T, backend Backend) *rpc.Server {
+	server := rpc.NewServer()
+	for _, api := range GetAPIs(backend) {
+		if !api.Public {
+			continue
+		}
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			t.Fatalf("failed to register %s API: %v", api.Namespace, err)
+		}
+	}
+	return server
+}
+
+// rpcExchange is a single recorded request and the response expected for it.
+type rpcExchange struct {
+	request  string
+	response string
+}
+
+// readSnapshot parses a recorded session. Requests are prefixed with ">> " and
+// are followed by their response, prefixed with "<< ". Blank lines and lines
+// starting with "#" are ignored.
+func readSnapshot(path string) ([]rpcExchange, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		exchanges []rpcExchange
+		scanner   = bufio.NewScanner(bytes.NewReader(data))
+	)
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case text == "" || strings.HasPrefix(text, "#"):
+		case strings.HasPrefix(text, ">> "):
+			exchanges = append(exchanges, rpcExchange{request: text[3:]})
+		case strings.HasPrefix(text, "<< "):
+			if len(exchanges) == 0 || exchanges[len(exchanges)-1].response != "" {
+				return nil, fmt.Errorf("%s:%d: response without request", path, line)
+			}
+			exchanges[len(exchanges)-1].response = text[3:]
+		default:
+			return nil, fmt.Errorf("%s:%d: invalid line %q", path, line, text)
+		}
+	}
+	return exchanges, scanner.Err()
+}
+
+// writeSnapshot stores a recorded session in the format read by readSnapshot.
+func writeSnapshot(path string, exchanges []rpcExchange) error {
+	var buf bytes.Buffer
+	for _, ex := range exchanges {
+		fmt.Fprintf(&buf, ">> %s\n<< %s\n", ex.request, ex.response)
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// serveRequest sends a raw JSON-RPC request to server over HTTP and returns
+// the raw response body.
+func serveRequest(server *rpc.Server, request string) string {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(request))
+	req.Header.Set("content-type", "application/json")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return strings.TrimRight(rec.Body.String(), "\n")
+}
+
+// TestRPCSnapshots replays the recorded JSON-RPC sessions in testdata/rpc
+// against a node seeded with the fixture chain, and checks that responses are
+// byte-for-byte identical to the recorded ones. Any change to the encoding of
+// RPC results (hex formats, field names or ordering) shows up here.
+//
+// Run with -update to re-record the responses after an intended change.
+func TestRPCSnapshots(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "rpc", "*.io"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no recorded RPC sessions found")
+	}
+	for _, file := range files {
+		file := file
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".io"), func(t *testing.T) {
+			exchanges, err := readSnapshot(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Every session starts from a pristine node, so that sessions
+			// which modify state can't influence each other.
+			backend := newTestBackend(t, 4)
+			defer backend.chain.Stop()
+			server := newTestServer(t, backend)
+			defer server.Stop()
+
+			for i, ex := range exchanges {
+				have := serveRequest(server, ex.request)
+				if *updateSnapshots {
+					exchanges[i].response = have
+					continue
+				}
+				if have != ex.response {
+					t.Errorf("request %d: response mismatch\nrequest: %s\nhave: %s\nwant: %s", i, ex.request, have, ex.response)
+				}
+			}
+			if *updateSnapshots {
+				if err := writeSnapshot(file, exchanges); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Tests that state requests pinned to a block hash fail with a distinct error
+// once the block has been reorged out of the canonical chain, unless they
+// tolerate non-canonical blocks.
+func TestNonCanonicalBlockHash(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestBackend(t, 2)
+	defer backend.chain.Stop()
+
+	reorged := backend.chain.CurrentBlock().Hash()
+
+	// Replace the head block with a longer fork without any transactions
+	fork, _ := core.GenerateChain(ctx, backend.gspec.Config, backend.chain.GetBlockByNumber(1), clique.NewFaker(), backend.db, 2, func(ctx context.Context, i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{1})
+	})
+	if _, err := backend.chain.InsertChain(ctx, fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if backend.chain.CurrentBlock().Hash() != fork[1].Hash() {
+		t.Fatalf("fork not canonical")
+	}
+	api := NewPublicBlockChainAPI(backend)
+
+	// Pinned requests may read the stale state, unless canonical blocks are required
+	balance, err := api.GetBalance(ctx, testRecv, rpc.BlockNumberOrHashWithHash(reorged, false))
+	if err != nil {
+		t.Fatalf("failed to read reorged state: %v", err)
+	}
+	if balance.Cmp(big.NewInt(3000)) != 0 {
+		t.Errorf("reorged balance mismatch: have %v, want %v", balance, 3000)
+	}
+	_, err = api.GetBalance(ctx, testRecv, rpc.BlockNumberOrHashWithHash(reorged, true))
+	if _, ok := err.(*core.NonCanonicalBlockError); !ok {
+		t.Errorf("canonical balance error mismatch: have %v, want %T", err, &core.NonCanonicalBlockError{})
+	}
+	if _, err := api.Call(ctx, CallArgs{To: &testRecv}, rpc.BlockNumberOrHashWithHash(reorged, true), nil); err == nil {
+		t.Errorf("call on reorged block succeeded")
+	}
+	// Requests pinned to canonical blocks succeed
+	balance, err = api.GetBalance(ctx, testRecv, rpc.BlockNumberOrHashWithHash(fork[1].Hash(), true))
+	if err != nil {
+		t.Fatalf("failed to read canonical state: %v", err)
+	}
+	if balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("canonical balance mismatch: have %v, want %v", balance, 1000)
+	}
+}
+
+func TestCallBlocklist(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestBackend(t, 1)
+	defer backend.chain.Stop()
+
+	api := NewPublicBlockChainAPI(backend)
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	backend.blocklist = core.NewBlocklist(core.BlocklistConfig{Addresses: []common.Address{testRecv}})
+	if _, err := api.Call(ctx, CallArgs{To: &testRecv}, latest, nil); err != core.ErrBlockedContract {
+		t.Errorf("blocked call error mismatch: have %v, want %v", err, core.ErrBlockedContract)
+	}
+	if _, err := api.Call(ctx, CallArgs{To: &testAddr}, latest, nil); err != nil {
+		t.Errorf("unrelated call failed: %v", err)
+	}
+	// The override lets calls execute blocked contracts again
+	backend.blocklist.Set(core.BlocklistConfig{Addresses: []common.Address{testRecv}, AllowCalls: true})
+	if _, err := api.Call(ctx, CallArgs{To: &testRecv}, latest, nil); err != nil {
+		t.Errorf("allowed call failed: %v", err)
+	}
+}
+
+func TestEstimateGas(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestBackend(t, 1)
+	defer backend.chain.Stop()
+
+	api := NewPublicBlockChainAPI(backend)
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	// Value transfers are estimated exactly
+	gas, err := api.EstimateGas(ctx, CallArgs{From: testAddr, To: &testRecv, Value: hexutil.Big(*big.NewInt(1))}, &latest)
+	if err != nil {
+		t.Fatalf("failed to estimate transfer: %v", err)
+	}
+	if uint64(gas) != params.TxGas {
+		t.Errorf("transfer estimate mismatch: have %d, want %d", gas, params.TxGas)
+	}
+	// Deploy code hashing 8KB with the sha256 precompile, forwarding all gas to
+	// it and failing if the call does, so that it needs more than it consumes
+	code := hexutil.MustDecode("0x600060006120006000600060025af115601457005bfe")
+	args := CallArgs{From: testAddr, Data: code}
+
+	gas, err = api.EstimateGas(ctx, args, &latest)
+	if err != nil {
+		t.Fatalf("failed to estimate creation: %v", err)
+	}
+	executable := func(gas uint64) bool {
+		args.Gas = hexutil.Uint64(gas)
+		_, _, failed, err := api.doCall(ctx, args, latest, nil, vm.Config{})
+		return err == nil && !failed
+	}
+	if !executable(uint64(gas)) {
+		t.Fatalf("estimate %d not executable", gas)
+	}
+	lo, hi := params.TxGas, uint64(gas)
+	for lo+1 < hi {
+		if mid := (lo + hi) / 2; executable(mid) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	if float64(uint64(gas)-hi)/float64(hi) > estimateGasErrorRatio {
+		t.Errorf("estimate %d too far above requirement %d", gas, hi)
+	}
+}
+
+func TestVerifyTxOrder(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestBackend(t, 1)
+	defer backend.chain.Stop()
+
+	// Fund a second account, then seal transactions of both accounts
+	var (
+		signer   = types.NewEIP155Signer(backend.gspec.Config.ChainId)
+		key, _   = crypto.GenerateKey()
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		transfer = func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+			tx, err := types.SignTx(types.NewTransaction(nonce, testRecv, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			return tx
+		}
+	)
+	fund, _ := types.SignTx(types.NewTransaction(1, addr, big.NewInt(1000000), params.TxGas, big.NewInt(1), nil), signer, testKey)
+	sealed := types.Transactions{transfer(testKey, 2), transfer(key, 0), transfer(testKey, 3)}
+
+	// The sealer stamped the last transaction, executable after the first one,
+	// before the second one it sealed ahead, and left another candidate out
+	candidates := []types.TxArrival{
+		{Hash: common.Hash{0x01}, Time: 500},
+		{Hash: sealed[0].Hash(), Time: 1000},
+		{Hash: sealed[2].Hash(), Time: 2000},
+		{Hash: sealed[1].Hash(), Time: 3000},
+	}
+	commitment := types.TxOrderCommitment(candidates)
+	backend.candidates = map[common.Hash][]types.TxArrival{commitment: candidates}
+
+	blocks, _ := core.GenerateChain(ctx, backend.gspec.Config, backend.chain.CurrentBlock(), clique.NewFaker(), backend.db, 2, func(ctx context.Context, i int, gen *core.BlockGen) {
+		if i == 0 {
+			gen.AddTx(ctx, fund)
+			return
+		}
+		gen.SetExtra(commitment[:])
+		for _, tx := range sealed {
+			gen.AddTx(ctx, tx)
+		}
+	})
+	if _, err := backend.chain.InsertChain(ctx, blocks); err != nil {
+		t.Fatalf("failed to insert blocks: %v", err)
+	}
+	// The second account's transaction arrived before the first one sealed,
+	// while the last one only became executable after the latter arrived
+	start := time.Now()
+	backend.arrivals = map[common.Hash]time.Time{
+		sealed[0].Hash(): start.Add(3 * time.Second),
+		sealed[1].Hash(): start.Add(time.Second),
+		sealed[2].Hash(): start,
+	}
+	api := NewPublicTransactionPoolAPI(backend, new(AddrLocker))
+
+	published, err := api.GetTxOrderCandidates(ctx, rpc.BlockNumberOrHashWithNumber(3))
+	if err != nil || len(published) != len(candidates) || published[2] != (TxOrderCandidate{Hash: sealed[2].Hash(), Arrival: 2000}) {
+		t.Fatalf("published candidates mismatch: %+v, %v", published, err)
+	}
+	audit, err := api.VerifyTxOrder(ctx, rpc.BlockNumberOrHashWithNumber(3), nil)
+	if err != nil {
+		t.Fatalf("failed to verify order: %v", err)
+	}
+	if !audit.Committed || audit.Candidates != 4 || audit.Observed != 3 {
+		t.Errorf("audit mismatch: committed %v, candidates %d, observed %d", audit.Committed, audit.Candidates, audit.Observed)
+	}
+	deviations := []TxOrderInversion{{Tx: sealed[2].Hash(), After: sealed[1].Hash(), Lead: 1000}}
+	if !reflect.DeepEqual(audit.Deviations, deviations) {
+		t.Errorf("deviations mismatch: have %+v, want %+v", audit.Deviations, deviations)
+	}
+	want := []TxOrderInversion{{Tx: sealed[1].Hash(), After: sealed[0].Hash(), Lead: 2000}}
+	if !reflect.DeepEqual(audit.Inversions, want) {
+		t.Errorf("inversions mismatch: have %+v, want %+v", audit.Inversions, want)
+	}
+	// Candidates restamped after sealing don't match the commitment
+	published[2].Arrival = 3500
+	audit, err = api.VerifyTxOrder(ctx, rpc.BlockNumberOrHashWithNumber(3), &published)
+	if err != nil {
+		t.Fatalf("failed to verify order: %v", err)
+	}
+	if audit.Committed || audit.Candidates != 0 || len(audit.Deviations) != 0 {
+		t.Errorf("restamped audit mismatch: %+v", audit)
+	}
+	// Blocks without commitment and unseen transactions
+	audit, err = api.VerifyTxOrder(ctx, rpc.BlockNumberOrHashWithNumber(2), nil)
+	if err != nil {
+		t.Fatalf("failed to verify order: %v", err)
+	}
+	if audit.Committed || audit.Observed != 0 || len(audit.Inversions) != 0 {
+		t.Errorf("uncommitted audit mismatch: %+v", audit)
+	}
+}
+
+func TestSimulateBundle(t *testing.T) {
+	ctx := context.Background()
+	backend := newTestBackend(t, 1)
+	defer backend.chain.Stop()
+
+	api := NewPublicBlockChainAPI(backend)
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+
+	signer := types.NewEIP155Signer(backend.gspec.Config.ChainId)
+	encode := func(tx *types.Transaction) hexutil.Bytes {
+		signed, err := types.SignTx(tx, signer, testKey)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		blob, _ := rlp.EncodeToBytes(signed)
+		return blob
+	}
+	// A transfer, a creation logging and a reverting creation, on top of the
+	// fixture transfer of the first block
+	bundle := []hexutil.Bytes{
+		encode(types.NewTransaction(1, testRecv, big.NewInt(1), params.TxGas, big.NewInt(1), nil)),
+		encode(types.NewContractCreation(2, new(big.Int), 100000, big.NewInt(1), hexutil.MustDecode("0x60006000a0"))),
+		encode(types.NewContractCreation(3, new(big.Int), 100000, big.NewInt(1), hexutil.MustDecode("0x60006000fd"))),
+	}
+	result, err := api.SimulateBundle(ctx, bundle, latest)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if result.BlockNumber != 2 || len(result.Results) != 3 {
+		t.Fatalf("result mismatch: block %d, %d results", result.BlockNumber, len(result.Results))
+	}
+	var total uint64
+	for i, want := range []uint{1, 1, 0} {
+		res := result.Results[i]
+		if uint(res.Status) != want {
+			t.Errorf("tx %d: status mismatch: have %d, want %d", i, res.Status, want)
+		}
+		if res.From != testAddr {
+			t.Errorf("tx %d: sender mismatch: have %x, want %x", i, res.From, testAddr)
+		}
+		total += uint64(res.GasUsed)
+	}
+	if uint64(result.Results[0].GasUsed) != params.TxGas {
+		t.Errorf("transfer gas mismatch: have %d, want %d", result.Results[0].GasUsed, params.TxGas)
+	}
+	if uint64(result.GasUsed) != total {
+		t.Errorf("total gas mismatch: have %d, want %d", result.GasUsed, total)
+	}
+	created := crypto.CreateAddress(testAddr, 2)
+	if res := result.Results[1]; res.ContractAddress == nil || *res.ContractAddress != created || len(res.Logs) != 1 || res.Logs[0].Address != created {
+		t.Errorf("creation result mismatch: %+v", res)
+	}
+	// Nothing is stored, and transactions pay for themselves
+	statedb, _, _ := backend.StateAndHeaderByNumberOrHash(ctx, latest)
+	if nonce := statedb.GetNonce(testAddr); nonce != 1 {
+		t.Errorf("nonce changed by simulation: have %d, want 1", nonce)
+	}
+	invalid := [][]hexutil.Bytes{
+		{bundle[0], bundle[2]},
+		{encode(types.NewTransaction(1, testRecv, testBalance, params.TxGas, big.NewInt(1), nil))},
+		{encode(types.NewTransaction(1, testRecv, big.NewInt(1), backend.chain.CurrentBlock().GasLimit()+1, big.NewInt(1), nil))},
+		{},
+	}
+	for i, bundle := range invalid {
+		if _, err := api.SimulateBundle(ctx, bundle, latest); err == nil {
+			t.Errorf("invalid bundle %d simulated", i)
+		}
+	}
+	// Cancelled requests abort the simulation
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := api.SimulateBundle(cancelled, bundle, latest); err == nil {
+		t.Errorf("cancelled bundle simulated")
+	}
+}
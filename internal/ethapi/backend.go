@@ -20,6 +20,7 @@ package ethapi
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/fulcrumchain/indigo/accounts"
 	"github.com/fulcrumchain/indigo/common"
@@ -48,12 +49,21 @@ type Backend interface {
 	// BlockChain API
 	SetHead(number uint64)
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
+	HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error)
+	HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
+	StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error)
 	// StateQuery calls fn with a read-only state.StateDB.
-	StateQuery(ctx context.Context, blockNr rpc.BlockNumber, fn func(db *state.StateDB) error) error
+	StateQuery(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, fn func(db *state.StateDB) error) error
 	GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
+	// GetAddressTransactions retrieves the positions of the canonical transactions
+	// sent from or to an address within a block range, skipping the first skip.
+	GetAddressTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.AddressTx, error)
+	// GetInternalTransactions retrieves the canonical value transfers made by
+	// contracts from or to an address within a block range, skipping the first skip.
+	GetInternalTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.InternalTx, error)
 	GetTd(blockHash common.Hash) *big.Int
 	GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, error)
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
@@ -62,15 +72,24 @@ type Backend interface {
 
 	// TxPool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error
 	GetPoolTransactions() types.Transactions
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
+	TxArrivalTime(txHash common.Hash) (time.Time, bool)
+	TxOrderCandidates(commitment common.Hash) ([]types.TxArrival, bool)
 	Stats() (pending int, queued int)
 	TxPoolContent(context.Context) (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
+	// TxPoolAccount reports the pooled transactions of an account and why they
+	// are stuck, if they are.
+	TxPoolAccount(addr common.Address) (*core.TxPoolAccount, error)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeTxLifecycleEvent(chan<- core.TxLifecycleEvent) event.Subscription
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+	// Blocklist returns the contracts calls are refused to, or nil if none are.
+	Blocklist() *core.Blocklist
 	// InitialSupply returns the initial total supply from the genesis allocation,
 	// or nil if a custom genesis is not available.
 	InitialSupply() *big.Int
@@ -0,0 +1,179 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/rlp"
+	"github.com/fulcrumchain/indigo/rpc"
+)
+
+const (
+	maxBundleTxs  = 256             // Maximum number of transactions simulated in a bundle
+	bundleTimeout = 5 * time.Second // Time after which the simulation of a bundle is aborted
+)
+
+// BundleResult is the outcome of the simulation of a bundle of transactions.
+type BundleResult struct {
+	ParentHash  common.Hash       `json:"parentHash"`  // Block the bundle was executed on top of
+	BlockNumber hexutil.Uint64    `json:"blockNumber"` // Number of the block simulated
+	GasUsed     hexutil.Uint64    `json:"gasUsed"`
+	Results     []*BundleTxResult `json:"results"`
+}
+
+// BundleTxResult is the outcome of a transaction of a simulated bundle.
+type BundleTxResult struct {
+	TxHash          common.Hash     `json:"txHash"`
+	From            common.Address  `json:"from"`
+	To              *common.Address `json:"to"`
+	ContractAddress *common.Address `json:"contractAddress"` // Address of the contract created, if any
+	GasUsed         hexutil.Uint64  `json:"gasUsed"`
+	Status          hexutil.Uint    `json:"status"`     // 1 if executed, 0 if reverted or failed
+	ReturnData      hexutil.Bytes   `json:"returnData"` // Output of the execution, or the revert data
+	Logs            []*types.Log    `json:"logs"`
+}
+
+// SimulateBundle executes the given signed transactions in order, as if they
+// were the content of the block following the given one, and returns the
+// outcome of each. Nothing is broadcast nor stored. The bundle is executed
+// atomically: transactions that revert are reported as failed, while an
+// invalid transaction, e.g. with a wrong nonce, insufficient funds or above
+// the gas left in the block, fails the whole bundle. The bundle is bound by the
+// gas limit of the block, and aborted if it runs longer than bundleTimeout.
+func (s *PublicBlockChainAPI) SimulateBundle(ctx context.Context, encodedTxs []hexutil.Bytes, blockNrOrHash rpc.BlockNumberOrHash) (*BundleResult, error) {
+	if len(encodedTxs) == 0 {
+		return nil, fmt.Errorf("empty bundle")
+	}
+	if len(encodedTxs) > maxBundleTxs {
+		return nil, fmt.Errorf("bundle of %d transactions exceeds the limit of %d", len(encodedTxs), maxBundleTxs)
+	}
+	txs := make([]*types.Transaction, len(encodedTxs))
+	for i, encoded := range encodedTxs {
+		txs[i] = new(types.Transaction)
+		if err := rlp.DecodeBytes(encoded, txs[i]); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+	}
+	// Abort the simulation once the deadline passes or the request is cancelled
+	ctx, cancel := context.WithTimeout(ctx, bundleTimeout)
+	defer cancel()
+
+	statedb, parent, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	var (
+		config = s.b.ChainConfig()
+		header = bundleHeader(parent)
+		signer = types.MakeSigner(config, header.Number)
+		gp     = new(core.GasPool).AddGas(header.GasLimit)
+		result = &BundleResult{ParentHash: parent.Hash(), BlockNumber: hexutil.Uint64(header.Number.Uint64()), Results: []*BundleTxResult{}}
+	)
+	for i, tx := range txs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, err := tx.AsMessage(ctx, signer)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x): %v", i, tx.Hash(), err)
+		}
+		if to := tx.To(); to != nil && s.b.Blocklist().Blocked(*to, statedb.GetCodeHash(*to)) {
+			return nil, fmt.Errorf("transaction %d (%x): %v", i, tx.Hash(), core.ErrBlockedContract)
+		}
+		statedb.Prepare(tx.Hash(), common.Hash{}, i)
+
+		// The backend credits the sender to execute calls, while transactions
+		// have to pay for themselves
+		balance := new(big.Int).Set(statedb.GetBalance(msg.From()))
+		evm, err := s.b.GetEVM(ctx, msg, statedb, header, vm.Config{})
+		if err != nil {
+			return nil, err
+		}
+		statedb.SetBalance(msg.From(), balance)
+
+		// Cancel the EVM if the context is done before the transaction completes
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				evm.Cancel()
+			case <-done:
+			}
+		}()
+		ret, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+		close(done)
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("transaction %d (%x): execution aborted: %v", i, tx.Hash(), ctx.Err())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d (%x): %v", i, tx.Hash(), err)
+		}
+		statedb.Finalise(config.IsEIP158(header.Number))
+
+		res := &BundleTxResult{
+			TxHash:     tx.Hash(),
+			From:       msg.From(),
+			To:         tx.To(),
+			GasUsed:    hexutil.Uint64(gas),
+			ReturnData: ret,
+			Logs:       statedb.GetLogs(tx.Hash()),
+		}
+		if !failed {
+			res.Status = hexutil.Uint(types.ReceiptStatusSuccessful)
+		}
+		if tx.To() == nil {
+			addr := crypto.CreateAddress(msg.From(), tx.Nonce())
+			res.ContractAddress = &addr
+		}
+		if res.Logs == nil {
+			res.Logs = []*types.Log{}
+		}
+		for _, l := range res.Logs {
+			l.BlockNumber = header.Number.Uint64()
+		}
+		result.GasUsed += res.GasUsed
+		result.Results = append(result.Results, res)
+	}
+	return result, nil
+}
+
+// bundleHeader returns the header of the block a bundle is simulated in, the
+// successor of the given one.
+func bundleHeader(parent *types.Header) *types.Header {
+	timestamp := new(big.Int).Add(parent.Time, common.Big1)
+	if now := big.NewInt(time.Now().Unix()); now.Cmp(timestamp) > 0 {
+		timestamp = now
+	}
+	return &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       timestamp,
+	}
+}
@@ -0,0 +1,12 @@
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getTransactionsByAddress","params":["0x71562b71999873db5b286df957af199ec94617f7","0x0","latest",null]}
+<< {"jsonrpc":"2.0","id":1,"result":[{"blockHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","blockNumber":"0x1","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521","input":"0x","nonce":"0x0","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x3e8","v":"0x25","r":"0x297dfe29ce82386d94000857c5c3ef32e257fad272e1d21429fe06366047dccc","s":"0x217c73e7fcf5f45afe4455470530596ce83129349bfc5b3314d34c350f98fe39"},{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","blockNumber":"0x2","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xf27c27b45269cda7126812bc2855c94c72ff1bc67e4ead2916621ee9703eff75","input":"0x","nonce":"0x1","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x7d0","v":"0x26","r":"0xc4d5c9376e4a2af665eaaefe7c64c997cfed552211fb12cb5bddd9ae780028b","s":"0x7bd2886348da2aefadb72ba81859f5da293e7929c960ab948bdc431bd4987a9b"},{"blockHash":"0x9b5a8f7ac50988706de48d605b1c8e3becd684d8d00edc1801c9cedb3cd3520f","blockNumber":"0x3","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0x8a0267662325c865e6266137dd92bca1efc3a5e990066c0b8c00a0b9276d48be","input":"0x","nonce":"0x2","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0xbb8","v":"0x25","r":"0xa9cb6ff18326b983a2e2705e37334b708bc55c875826226f79e59c4ef62a4cd2","s":"0x79f205f4556b06758defe2ce77e7ccd34cc1760a8a8379c721073ce183ff4f0"},{"blockHash":"0x398ab163c096204978929103a681e5dbb327ba0e870892a533b0397e5948a069","blockNumber":"0x4","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0x715db2f509af3029d35faf147e6d7b87e26c87b47f517404f1db6d8a61c039f1","input":"0x","nonce":"0x3","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0xfa0","v":"0x26","r":"0xe78cf527e42ef2d4ba001055d53f6529d0425af97e2412f23641b92bcf91da71","s":"0x60f4049d351970cd42cf787db3c762ccea8b0b7c480e681e040ad363544fe8fa"}]}
+>> {"jsonrpc":"2.0","id":2,"method":"eth_getTransactionsByAddress","params":["0x0000000000000000000000000000000000001234","0x2","0x3",null]}
+<< {"jsonrpc":"2.0","id":2,"result":[{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","blockNumber":"0x2","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xf27c27b45269cda7126812bc2855c94c72ff1bc67e4ead2916621ee9703eff75","input":"0x","nonce":"0x1","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x7d0","v":"0x26","r":"0xc4d5c9376e4a2af665eaaefe7c64c997cfed552211fb12cb5bddd9ae780028b","s":"0x7bd2886348da2aefadb72ba81859f5da293e7929c960ab948bdc431bd4987a9b"},{"blockHash":"0x9b5a8f7ac50988706de48d605b1c8e3becd684d8d00edc1801c9cedb3cd3520f","blockNumber":"0x3","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0x8a0267662325c865e6266137dd92bca1efc3a5e990066c0b8c00a0b9276d48be","input":"0x","nonce":"0x2","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0xbb8","v":"0x25","r":"0xa9cb6ff18326b983a2e2705e37334b708bc55c875826226f79e59c4ef62a4cd2","s":"0x79f205f4556b06758defe2ce77e7ccd34cc1760a8a8379c721073ce183ff4f0"}]}
+>> {"jsonrpc":"2.0","id":3,"method":"eth_getTransactionsByAddress","params":["0x0000000000000000000000000000000000001234","0x0","latest",{"offset":1,"limit":2}]}
+<< {"jsonrpc":"2.0","id":3,"result":[{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","blockNumber":"0x2","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xf27c27b45269cda7126812bc2855c94c72ff1bc67e4ead2916621ee9703eff75","input":"0x","nonce":"0x1","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x7d0","v":"0x26","r":"0xc4d5c9376e4a2af665eaaefe7c64c997cfed552211fb12cb5bddd9ae780028b","s":"0x7bd2886348da2aefadb72ba81859f5da293e7929c960ab948bdc431bd4987a9b"},{"blockHash":"0x9b5a8f7ac50988706de48d605b1c8e3becd684d8d00edc1801c9cedb3cd3520f","blockNumber":"0x3","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0x8a0267662325c865e6266137dd92bca1efc3a5e990066c0b8c00a0b9276d48be","input":"0x","nonce":"0x2","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0xbb8","v":"0x25","r":"0xa9cb6ff18326b983a2e2705e37334b708bc55c875826226f79e59c4ef62a4cd2","s":"0x79f205f4556b06758defe2ce77e7ccd34cc1760a8a8379c721073ce183ff4f0"}]}
+>> {"jsonrpc":"2.0","id":4,"method":"eth_getTransactionsByAddress","params":["0x0000000000000000000000000000000000005678","0x0","latest",null]}
+<< {"jsonrpc":"2.0","id":4,"result":[]}
+>> {"jsonrpc":"2.0","id":5,"method":"eth_getTransactionsByAddress","params":["0x0000000000000000000000000000000000001234","0x3","0x1",null]}
+<< {"jsonrpc":"2.0","id":5,"error":{"code":-32000,"message":"invalid block range 3 \u003e 1"}}
+>> {"jsonrpc":"2.0","id":6,"method":"eth_getTransactionsByAddress","params":["0x0000000000000000000000000000000000001234","0x0","latest",{"offset":0,"limit":5000}]}
+<< {"jsonrpc":"2.0","id":6,"error":{"code":-32000,"message":"page limit 5000 above maximum 1000"}}
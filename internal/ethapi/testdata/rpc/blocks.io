@@ -0,0 +1,30 @@
+>> {"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}
+<< {"jsonrpc":"2.0","id":1,"result":"0x4"}
+>> {"jsonrpc":"2.0","id":2,"method":"eth_getBlockByNumber","params":["0x0",false]}
+<< {"jsonrpc":"2.0","id":2,"result":{"difficulty":"0x1","extraData":"0x","gasLimit":"0xc88afa0","gasUsed":"0x0","hash":"0x33e64d79dc7825dc5001dc7d922689a9c48f396fc5e611cd56c5c26d85be74f6","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x0","parentHash":"0x0000000000000000000000000000000000000000000000000000000000000000","receiptsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":[],"size":"0x23e","stateRoot":"0x9f88be00eee1114edfd9372f52560aab3980a142efe8b5b39a09644075084275","timestamp":"0x0","totalDifficulty":"0x0","transactions":[],"transactionsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","uncles":[],"voters":[]}}
+>> {"jsonrpc":"2.0","id":3,"method":"eth_getBlockByNumber","params":["0x1",false]}
+<< {"jsonrpc":"2.0","id":3,"result":{"difficulty":"0x1","extraData":"0x0000000000000000000000000000000000000000000000000000000000000000","gasLimit":"0xc858d76","gasUsed":"0x5208","hash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x1","parentHash":"0x33e64d79dc7825dc5001dc7d922689a9c48f396fc5e611cd56c5c26d85be74f6","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":[],"size":"0x2c4","stateRoot":"0xeaa4d6e80a4340ea9e49eece2480966cafdee894ddf74f48f01b485a99c62ade","timestamp":"0x5","totalDifficulty":"0x1","transactions":["0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521"],"transactionsRoot":"0xb91890e1c73bd23c132eadfe0925b377c670cfb186c3d769b7bf08cdf902cdb3","uncles":[],"voters":[]}}
+>> {"jsonrpc":"2.0","id":4,"method":"eth_getBlockByNumber","params":["0x2",true]}
+<< {"jsonrpc":"2.0","id":4,"result":{"difficulty":"0x1","extraData":"0x0000000000000000000000000000000000000000000000000000000000000000","gasLimit":"0xc826c32","gasUsed":"0x5208","hash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x2","parentHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":[],"size":"0x2c4","stateRoot":"0x1907dc7aad8e6822a9ae8ac13d957931f802c44dabbaa7415cbfc6cd2c9cbb2c","timestamp":"0xa","totalDifficulty":"0x2","transactions":[{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","blockNumber":"0x2","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xf27c27b45269cda7126812bc2855c94c72ff1bc67e4ead2916621ee9703eff75","input":"0x","nonce":"0x1","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x7d0","v":"0x26","r":"0xc4d5c9376e4a2af665eaaefe7c64c997cfed552211fb12cb5bddd9ae780028b","s":"0x7bd2886348da2aefadb72ba81859f5da293e7929c960ab948bdc431bd4987a9b"}],"transactionsRoot":"0xc1eaab8679802d2dcc093144b28bbe17e7f4b5668089f20bf0fd84d83d05b629","uncles":[],"voters":[]}}
+>> {"jsonrpc":"2.0","id":5,"method":"eth_getBlockByNumber","params":["latest",false]}
+<< {"jsonrpc":"2.0","id":5,"result":{"difficulty":"0x1","extraData":"0x0000000000000000000000000000000000000000000000000000000000000000","gasLimit":"0xc7c2c03","gasUsed":"0x5208","hash":"0x398ab163c096204978929103a681e5dbb327ba0e870892a533b0397e5948a069","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x4","parentHash":"0x9b5a8f7ac50988706de48d605b1c8e3becd684d8d00edc1801c9cedb3cd3520f","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":null,"size":"0x2c4","stateRoot":"0xb82d9a93174b59bc581c097322584a29cc46a3abe933fdfbab47566953e54b58","timestamp":"0x14","totalDifficulty":"0x4","transactions":["0x715db2f509af3029d35faf147e6d7b87e26c87b47f517404f1db6d8a61c039f1"],"transactionsRoot":"0x9323f00da9346fcb1e92a2322d86ef1f80023d316a1496e6b9aa4cef4a463031","uncles":[],"voters":null}}
+>> {"jsonrpc":"2.0","id":6,"method":"eth_getBlockByNumber","params":["0x64",false]}
+<< {"jsonrpc":"2.0","id":6,"result":null}
+>> {"jsonrpc":"2.0","id":7,"method":"eth_getBlockTransactionCountByNumber","params":["0x3"]}
+<< {"jsonrpc":"2.0","id":7,"result":"0x1"}
+>> {"jsonrpc":"2.0","id":8,"method":"eth_getUncleCountByBlockNumber","params":["0x3"]}
+<< {"jsonrpc":"2.0","id":8,"result":"0x0"}
+>> {"jsonrpc":"2.0","id":9,"method":"eth_getBlockByHash","params":["0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a",true]}
+<< {"jsonrpc":"2.0","id":9,"result":{"difficulty":"0x1","extraData":"0x0000000000000000000000000000000000000000000000000000000000000000","gasLimit":"0xc858d76","gasUsed":"0x5208","hash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x1","parentHash":"0x33e64d79dc7825dc5001dc7d922689a9c48f396fc5e611cd56c5c26d85be74f6","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":[],"size":"0x2c4","stateRoot":"0xeaa4d6e80a4340ea9e49eece2480966cafdee894ddf74f48f01b485a99c62ade","timestamp":"0x5","totalDifficulty":"0x1","transactions":[{"blockHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","blockNumber":"0x1","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521","input":"0x","nonce":"0x0","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x3e8","v":"0x25","r":"0x297dfe29ce82386d94000857c5c3ef32e257fad272e1d21429fe06366047dccc","s":"0x217c73e7fcf5f45afe4455470530596ce83129349bfc5b3314d34c350f98fe39"}],"transactionsRoot":"0xb91890e1c73bd23c132eadfe0925b377c670cfb186c3d769b7bf08cdf902cdb3","uncles":[],"voters":[]}}
+>> {"jsonrpc":"2.0","id":10,"method":"eth_getBlockTransactionCountByHash","params":["0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a"]}
+<< {"jsonrpc":"2.0","id":10,"result":"0x1"}
+>> {"jsonrpc":"2.0","id":11,"method":"eth_getHeaderByNumber","params":["0x1"]}
+<< {"jsonrpc":"2.0","id":11,"result":{"difficulty":"0x1","extraData":"0x0000000000000000000000000000000000000000000000000000000000000000","gasLimit":"0xc858d76","gasUsed":"0x5208","hash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x1","parentHash":"0x33e64d79dc7825dc5001dc7d922689a9c48f396fc5e611cd56c5c26d85be74f6","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":[],"stateRoot":"0xeaa4d6e80a4340ea9e49eece2480966cafdee894ddf74f48f01b485a99c62ade","timestamp":"0x5","totalDifficulty":"0x1","transactionsRoot":"0xb91890e1c73bd23c132eadfe0925b377c670cfb186c3d769b7bf08cdf902cdb3","voters":[]}}
+>> {"jsonrpc":"2.0","id":12,"method":"eth_getHeaderByNumber","params":["latest"]}
+<< {"jsonrpc":"2.0","id":12,"result":{"difficulty":"0x1","extraData":"0x0000000000000000000000000000000000000000000000000000000000000000","gasLimit":"0xc7c2c03","gasUsed":"0x5208","hash":"0x398ab163c096204978929103a681e5dbb327ba0e870892a533b0397e5948a069","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x4","parentHash":"0x9b5a8f7ac50988706de48d605b1c8e3becd684d8d00edc1801c9cedb3cd3520f","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":null,"stateRoot":"0xb82d9a93174b59bc581c097322584a29cc46a3abe933fdfbab47566953e54b58","timestamp":"0x14","totalDifficulty":"0x4","transactionsRoot":"0x9323f00da9346fcb1e92a2322d86ef1f80023d316a1496e6b9aa4cef4a463031","voters":null}}
+>> {"jsonrpc":"2.0","id":13,"method":"eth_getHeaderByNumber","params":["0x64"]}
+<< {"jsonrpc":"2.0","id":13,"result":null}
+>> {"jsonrpc":"2.0","id":14,"method":"eth_getHeaderByHash","params":["0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a"]}
+<< {"jsonrpc":"2.0","id":14,"result":{"difficulty":"0x1","extraData":"0x0000000000000000000000000000000000000000000000000000000000000000","gasLimit":"0xc858d76","gasUsed":"0x5208","hash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","miner":"0x0000000000000000000000000000000000000000","mixHash":"0x0000000000000000000000000000000000000000000000000000000000000000","nonce":"0x0000000000000000","number":"0x1","parentHash":"0x33e64d79dc7825dc5001dc7d922689a9c48f396fc5e611cd56c5c26d85be74f6","receiptsRoot":"0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2","sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","signer":"0x0000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","signers":[],"stateRoot":"0xeaa4d6e80a4340ea9e49eece2480966cafdee894ddf74f48f01b485a99c62ade","timestamp":"0x5","totalDifficulty":"0x1","transactionsRoot":"0xb91890e1c73bd23c132eadfe0925b377c670cfb186c3d769b7bf08cdf902cdb3","voters":[]}}
+>> {"jsonrpc":"2.0","id":15,"method":"eth_getHeaderByHash","params":["0x0000000000000000000000000000000000000000000000000000000000000001"]}
+<< {"jsonrpc":"2.0","id":15,"result":null}
@@ -0,0 +1,10 @@
+>> {"jsonrpc":"2.0","id":1,"method":"eth_noSuchMethod","params":[]}
+<< {"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"The method eth_noSuchMethod does not exist/is not available"}}
+>> {"jsonrpc":"2.0","id":2,"method":"eth_getBalance","params":["0xzz","latest"]}
+<< {"jsonrpc":"2.0","id":2,"error":{"code":-32602,"message":"invalid argument 0: hex string has length 2, want 40 for common.Address"}}
+>> {"jsonrpc":"2.0","id":3,"method":"eth_getBlockByNumber","params":[]}
+<< {"jsonrpc":"2.0","id":3,"error":{"code":-32602,"message":"missing value for required argument 0"}}
+>> {"jsonrpc":"2.0","id":4,"method"
+<< {"jsonrpc":"2.0","error":{"code":-32600,"message":"unexpected EOF"}}
+>> [{"jsonrpc":"2.0","id":5,"method":"eth_blockNumber","params":[]},{"jsonrpc":"2.0","id":6,"method":"net_version","params":[]}]
+<< [{"jsonrpc":"2.0","id":5,"result":"0x4"},{"jsonrpc":"2.0","id":6,"error":{"code":-32601,"message":"The method net_version does not exist/is not available"}}]
@@ -0,0 +1,44 @@
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getBalance","params":["0x71562b71999873db5b286df957af199ec94617f7","0x0"]}
+<< {"jsonrpc":"2.0","id":1,"result":"0xde0b6b3a7640000"}
+>> {"jsonrpc":"2.0","id":2,"method":"eth_getBalance","params":["0x71562b71999873db5b286df957af199ec94617f7","latest"]}
+<< {"jsonrpc":"2.0","id":2,"result":"0xde0b6b3a76290d0"}
+>> {"jsonrpc":"2.0","id":3,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000001234","0x2"]}
+<< {"jsonrpc":"2.0","id":3,"result":"0xbb8"}
+>> {"jsonrpc":"2.0","id":4,"method":"eth_getTransactionCount","params":["0x71562b71999873db5b286df957af199ec94617f7","latest"]}
+<< {"jsonrpc":"2.0","id":4,"result":"0x4"}
+>> {"jsonrpc":"2.0","id":5,"method":"eth_getCode","params":["0x71562b71999873db5b286df957af199ec94617f7","latest"]}
+<< {"jsonrpc":"2.0","id":5,"result":"0x"}
+>> {"jsonrpc":"2.0","id":6,"method":"eth_getStorageAt","params":["0x71562b71999873db5b286df957af199ec94617f7","0x0","latest"]}
+<< {"jsonrpc":"2.0","id":6,"result":"0x0000000000000000000000000000000000000000000000000000000000000000"}
+>> {"jsonrpc":"2.0","id":7,"method":"eth_call","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000001234","value":"0x1"},"latest"]}
+<< {"jsonrpc":"2.0","id":7,"result":"0x"}
+>> {"jsonrpc":"2.0","id":8,"method":"eth_totalSupply","params":["latest"]}
+<< {"jsonrpc":"2.0","id":8,"result":"0x19274b259f6540000"}
+>> {"jsonrpc":"2.0","id":9,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000001234","0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7"]}
+<< {"jsonrpc":"2.0","id":9,"result":"0xbb8"}
+>> {"jsonrpc":"2.0","id":10,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000001234",{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","requireCanonical":true}]}
+<< {"jsonrpc":"2.0","id":10,"result":"0xbb8"}
+>> {"jsonrpc":"2.0","id":11,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000001234",{"blockNumber":"0x2"}]}
+<< {"jsonrpc":"2.0","id":11,"result":"0xbb8"}
+>> {"jsonrpc":"2.0","id":12,"method":"eth_getTransactionCount","params":["0x71562b71999873db5b286df957af199ec94617f7",{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7"}]}
+<< {"jsonrpc":"2.0","id":12,"result":"0x2"}
+>> {"jsonrpc":"2.0","id":13,"method":"eth_call","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000001234","value":"0x1"},{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7"}]}
+<< {"jsonrpc":"2.0","id":13,"result":"0x"}
+>> {"jsonrpc":"2.0","id":14,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000001234","0x0000000000000000000000000000000000000000000000000000000000000001"]}
+<< {"jsonrpc":"2.0","id":14,"error":{"code":-32001,"message":"header for hash 0000000000000000000000000000000000000000000000000000000000000001 not found"}}
+>> {"jsonrpc":"2.0","id":15,"method":"eth_getBalance","params":["0x0000000000000000000000000000000000001234",{"blockNumber":"0x2","blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7"}]}
+<< {"jsonrpc":"2.0","id":15,"error":{"code":-32602,"message":"invalid argument 1: cannot specify both blockNumber and blockHash"}}
+>> {"jsonrpc":"2.0","id":16,"method":"eth_estimateGas","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000001234","value":"0x1"},{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","requireCanonical":true}]}
+<< {"jsonrpc":"2.0","id":16,"result":"0x5208"}
+>> {"jsonrpc":"2.0","id":17,"method":"eth_getCode","params":["0x0000000000000000000000000000000000001234",{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7"}]}
+<< {"jsonrpc":"2.0","id":17,"result":"0x"}
+>> {"jsonrpc":"2.0","id":18,"method":"eth_getStorageAt","params":["0x0000000000000000000000000000000000001234","0x0",{"blockHash":"0x33215a9c818e7ad0735f77800841b69b1e4bde565ee0b14f8fca7e0e98a43bf7","requireCanonical":true}]}
+<< {"jsonrpc":"2.0","id":18,"result":"0x0000000000000000000000000000000000000000000000000000000000000000"}
+>> {"jsonrpc":"2.0","id":19,"method":"eth_call","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000001234"},"latest",{"0x0000000000000000000000000000000000001234":{"code":"0x60005460005260206000f3","storage":{"0x0000000000000000000000000000000000000000000000000000000000000000":"0x000000000000000000000000000000000000000000000000000000000000002a"}}}]}
+<< {"jsonrpc":"2.0","id":19,"result":"0x000000000000000000000000000000000000000000000000000000000000002a"}
+>> {"jsonrpc":"2.0","id":20,"method":"eth_call","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000001234"},"latest",{"0x0000000000000000000000000000000000001234":{"code":"0x7300000000000000000000000000000000000056783160005260206000f3"},"0x0000000000000000000000000000000000005678":{"balance":"0x1234","nonce":"0x7"}}]}
+<< {"jsonrpc":"2.0","id":20,"result":"0x0000000000000000000000000000000000000000000000000000000000001234"}
+>> {"jsonrpc":"2.0","id":21,"method":"eth_call","params":[{"from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000001234"},"latest",{"0x0000000000000000000000000000000000001234":{"balance":"0xzz"}}]}
+<< {"jsonrpc":"2.0","id":21,"error":{"code":-32602,"message":"invalid argument 2: json: cannot unmarshal invalid hex string into Go value of type *hexutil.Big"}}
+>> {"jsonrpc":"2.0","id":22,"method":"eth_getCode","params":["0x0000000000000000000000000000000000001234","latest"]}
+<< {"jsonrpc":"2.0","id":22,"result":"0x"}
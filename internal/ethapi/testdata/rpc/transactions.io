@@ -0,0 +1,22 @@
+>> {"jsonrpc":"2.0","id":1,"method":"eth_getTransactionByBlockNumberAndIndex","params":["0x1","0x0"]}
+<< {"jsonrpc":"2.0","id":1,"result":{"blockHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","blockNumber":"0x1","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521","input":"0x","nonce":"0x0","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x3e8","v":"0x25","r":"0x297dfe29ce82386d94000857c5c3ef32e257fad272e1d21429fe06366047dccc","s":"0x217c73e7fcf5f45afe4455470530596ce83129349bfc5b3314d34c350f98fe39"}}
+>> {"jsonrpc":"2.0","id":2,"method":"eth_getRawTransactionByBlockNumberAndIndex","params":["0x1","0x0"]}
+<< {"jsonrpc":"2.0","id":2,"result":"0xf86180018252089400000000000000000000000000000000000012348203e88025a0297dfe29ce82386d94000857c5c3ef32e257fad272e1d21429fe06366047dccca0217c73e7fcf5f45afe4455470530596ce83129349bfc5b3314d34c350f98fe39"}
+>> {"jsonrpc":"2.0","id":3,"method":"eth_getTransactionByBlockNumberAndIndex","params":["0x1","0x1"]}
+<< {"jsonrpc":"2.0","id":3,"result":null}
+>> {"jsonrpc":"2.0","id":4,"method":"eth_getTransactionByHash","params":["0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521"]}
+<< {"jsonrpc":"2.0","id":4,"result":{"blockHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","blockNumber":"0x1","from":"0x71562b71999873db5b286df957af199ec94617f7","gas":"0x5208","gasPrice":"0x1","hash":"0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521","input":"0x","nonce":"0x0","to":"0x0000000000000000000000000000000000001234","transactionIndex":"0x0","value":"0x3e8","v":"0x25","r":"0x297dfe29ce82386d94000857c5c3ef32e257fad272e1d21429fe06366047dccc","s":"0x217c73e7fcf5f45afe4455470530596ce83129349bfc5b3314d34c350f98fe39"}}
+>> {"jsonrpc":"2.0","id":5,"method":"eth_getRawTransactionByHash","params":["0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521"]}
+<< {"jsonrpc":"2.0","id":5,"result":"0xf86180018252089400000000000000000000000000000000000012348203e88025a0297dfe29ce82386d94000857c5c3ef32e257fad272e1d21429fe06366047dccca0217c73e7fcf5f45afe4455470530596ce83129349bfc5b3314d34c350f98fe39"}
+>> {"jsonrpc":"2.0","id":6,"method":"eth_getTransactionReceipt","params":["0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521"]}
+<< {"jsonrpc":"2.0","id":6,"result":{"blockHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","blockNumber":"0x1","contractAddress":null,"cumulativeGasUsed":"0x5208","from":"0x71562b71999873db5b286df957af199ec94617f7","gasUsed":"0x5208","logs":[],"logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","status":"0x1","to":"0x0000000000000000000000000000000000001234","transactionHash":"0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521","transactionIndex":"0x0"}}
+>> {"jsonrpc":"2.0","id":7,"method":"eth_getTransactionReceipt","params":["0x0000000000000000000000000000000000000000000000000000000000000000"]}
+<< {"jsonrpc":"2.0","id":7,"result":null}
+>> {"jsonrpc":"2.0","id":8,"method":"eth_getBlockReceipts","params":["0x1"]}
+<< {"jsonrpc":"2.0","id":8,"result":[{"blockHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","blockNumber":"0x1","contractAddress":null,"cumulativeGasUsed":"0x5208","from":"0x71562b71999873db5b286df957af199ec94617f7","gasUsed":"0x5208","logs":[],"logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","status":"0x1","to":"0x0000000000000000000000000000000000001234","transactionHash":"0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521","transactionIndex":"0x0"}]}
+>> {"jsonrpc":"2.0","id":9,"method":"eth_getBlockReceipts","params":["0x0"]}
+<< {"jsonrpc":"2.0","id":9,"result":[]}
+>> {"jsonrpc":"2.0","id":10,"method":"eth_getBlockReceipts","params":["0x64"]}
+<< {"jsonrpc":"2.0","id":10,"result":null}
+>> {"jsonrpc":"2.0","id":11,"method":"eth_getBlockReceipts","params":["0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a"]}
+<< {"jsonrpc":"2.0","id":11,"result":[{"blockHash":"0xe5424481f4b0eff3061c63e532241195a90a8c99f0ad634c98bb1b4efcd77d5a","blockNumber":"0x1","contractAddress":null,"cumulativeGasUsed":"0x5208","from":"0x71562b71999873db5b286df957af199ec94617f7","gasUsed":"0x5208","logs":[],"logsBloom":"0x00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000","status":"0x1","to":"0x0000000000000000000000000000000000001234","transactionHash":"0xa79b9c26384079b4794220cd803193c0f4a5144b99a341f5f7e52cb027a27521","transactionIndex":"0x0"}]}
@@ -0,0 +1,272 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package jobs runs expensive operations in the background, for their progress
+// and results to be polled instead of holding API connections open.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fulcrumchain/indigo/log"
+)
+
+var (
+	ErrUnknownJob  = errors.New("unknown job")
+	ErrJobRunning  = errors.New("job still running")
+	ErrTooManyJobs = errors.New("too many running jobs")
+	ErrStopped     = errors.New("job manager stopped")
+)
+
+// Status is the state of a job.
+type Status string
+
+const (
+	Running   Status = "running"
+	Done      Status = "done"
+	Failed    Status = "failed"
+	Cancelled Status = "cancelled"
+)
+
+// Func is an operation run as a job. It should return early once ctx is
+// cancelled, and may report its progress with ReportProgress.
+type Func func(ctx context.Context) (interface{}, error)
+
+// JobStatus reports the state of a job.
+type JobStatus struct {
+	ID       string     `json:"id"`
+	Method   string     `json:"method"`
+	Status   Status     `json:"status"`
+	Done     uint64     `json:"done"`  // Units of work done, as reported by the job
+	Total    uint64     `json:"total"` // Units of work to do, 0 if unknown
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// job is a background operation tracked by a Manager.
+type job struct {
+	id       string
+	method   string
+	started  time.Time
+	finished time.Time
+	status   Status
+	done     uint64 // Accessed atomically
+	total    uint64 // Accessed atomically
+	result   interface{}
+	err      error
+	cancel   context.CancelFunc
+}
+
+func (j *job) report() *JobStatus {
+	status := &JobStatus{
+		ID:      j.id,
+		Method:  j.method,
+		Status:  j.status,
+		Done:    atomic.LoadUint64(&j.done),
+		Total:   atomic.LoadUint64(&j.total),
+		Started: j.started,
+	}
+	if j.status != Running {
+		finished := j.finished
+		status.Finished = &finished
+	}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+	return status
+}
+
+// Manager runs jobs and keeps their results until retrieved or expired.
+type Manager struct {
+	maxRunning int           // Maximum number of concurrently running jobs
+	retention  time.Duration // Time finished jobs are kept for
+
+	jobs    map[string]*job
+	running int
+	stopped bool
+	lock    sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// NewManager creates a job manager running at most maxRunning jobs at once,
+// and keeping the results of finished jobs for the retention period.
+func NewManager(maxRunning int, retention time.Duration) *Manager {
+	return &Manager{
+		maxRunning: maxRunning,
+		retention:  retention,
+		jobs:       make(map[string]*job),
+	}
+}
+
+// Submit starts running fn in the background, returning the id of the job.
+func (m *Manager) Submit(method string, fn Func) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.stopped {
+		return "", ErrStopped
+	}
+	m.expire()
+	if m.running >= m.maxRunning {
+		return "", ErrTooManyJobs
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:      "0x" + hex.EncodeToString(id[:]),
+		method:  method,
+		started: time.Now(),
+		status:  Running,
+		cancel:  cancel,
+	}
+	m.jobs[j.id] = j
+	m.running++
+
+	m.wg.Add(1)
+	go m.run(context.WithValue(ctx, progressKey{}, j), j, fn)
+	return j.id, nil
+}
+
+func (m *Manager) run(ctx context.Context, j *job, fn Func) {
+	defer m.wg.Done()
+
+	log.Debug("Started job", "id", j.id, "method", j.method)
+	result, err := fn(ctx)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	j.finished = time.Now()
+	j.result, j.err = result, err
+	switch {
+	case ctx.Err() != nil:
+		j.status = Cancelled
+		if err == nil {
+			j.err = ctx.Err()
+		}
+	case err != nil:
+		j.status = Failed
+	default:
+		j.status = Done
+	}
+	j.cancel()
+	m.running--
+	log.Debug("Finished job", "id", j.id, "method", j.method, "status", j.status, "elapsed", j.finished.Sub(j.started), "err", j.err)
+}
+
+// expire forgets the jobs finished longer than the retention period ago.
+func (m *Manager) expire() {
+	for id, j := range m.jobs {
+		if j.status != Running && time.Since(j.finished) > m.retention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// Status reports the state of a job.
+func (m *Manager) Status(id string) (*JobStatus, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.expire()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	return j.report(), nil
+}
+
+// Result returns the result of a finished job, or the error it failed with.
+func (m *Manager) Result(id string) (interface{}, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.expire()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	if j.status == Running {
+		return nil, ErrJobRunning
+	}
+	return j.result, j.err
+}
+
+// Cancel aborts a running job. Finished jobs are forgotten.
+func (m *Manager) Cancel(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return ErrUnknownJob
+	}
+	if j.status == Running {
+		j.cancel()
+	} else {
+		delete(m.jobs, id)
+	}
+	return nil
+}
+
+// Jobs reports the state of all the tracked jobs, the most recent first.
+func (m *Manager) Jobs() []*JobStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.expire()
+	jobs := make([]*JobStatus, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.report())
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Started.After(jobs[j].Started) })
+	return jobs
+}
+
+// Stop cancels the running jobs and waits for them to return.
+func (m *Manager) Stop() {
+	m.lock.Lock()
+	m.stopped = true
+	for _, j := range m.jobs {
+		if j.status == Running {
+			j.cancel()
+		}
+	}
+	m.lock.Unlock()
+
+	m.wg.Wait()
+}
+
+type progressKey struct{}
+
+// ReportProgress records the progress of the job running with ctx, if any.
+func ReportProgress(ctx context.Context, done, total uint64) {
+	if j, ok := ctx.Value(progressKey{}).(*job); ok {
+		atomic.StoreUint64(&j.done, done)
+		atomic.StoreUint64(&j.total, total)
+	}
+}
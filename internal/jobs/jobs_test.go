@@ -0,0 +1,159 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitJob polls a job until it is no longer running.
+func waitJob(t *testing.T, m *Manager, id string) *JobStatus {
+	for i := 0; i < 500; i++ {
+		status, err := m.Status(id)
+		if err != nil {
+			t.Fatalf("failed to retrieve job status: %v", err)
+		}
+		if status.Status != Running {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s still running", id)
+	return nil
+}
+
+func TestJobLifecycle(t *testing.T) {
+	m := NewManager(2, time.Hour)
+	defer m.Stop()
+
+	release := make(chan struct{})
+	id, err := m.Submit("test_work", func(ctx context.Context) (interface{}, error) {
+		ReportProgress(ctx, 1, 2)
+		<-release
+		return "result", nil
+	})
+	if err != nil {
+		t.Fatalf("failed to submit job: %v", err)
+	}
+	for i := 0; ; i++ {
+		status, _ := m.Status(id)
+		if status.Done == 1 && status.Total == 2 {
+			break
+		}
+		if i == 500 {
+			t.Fatalf("progress not reported: %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := m.Result(id); err != ErrJobRunning {
+		t.Errorf("result of running job: have %v, want %v", err, ErrJobRunning)
+	}
+	close(release)
+
+	if status := waitJob(t, m, id); status.Status != Done || status.Finished == nil {
+		t.Errorf("status mismatch: %+v", status)
+	}
+	if result, err := m.Result(id); err != nil || result != "result" {
+		t.Errorf("result mismatch: have %v/%v, want result", result, err)
+	}
+	if jobs := m.Jobs(); len(jobs) != 1 || jobs[0].ID != id {
+		t.Errorf("job list mismatch: %v", jobs)
+	}
+	if err := m.Cancel(id); err != nil {
+		t.Fatalf("failed to forget job: %v", err)
+	}
+	if _, err := m.Status(id); err != ErrUnknownJob {
+		t.Errorf("forgotten job status: have %v, want %v", err, ErrUnknownJob)
+	}
+}
+
+func TestJobFailure(t *testing.T) {
+	m := NewManager(1, time.Hour)
+	defer m.Stop()
+
+	failure := errors.New("failure")
+	id, _ := m.Submit("test_fail", func(ctx context.Context) (interface{}, error) {
+		return nil, failure
+	})
+	if status := waitJob(t, m, id); status.Status != Failed || status.Error != failure.Error() {
+		t.Errorf("status mismatch: %+v", status)
+	}
+	if _, err := m.Result(id); err != failure {
+		t.Errorf("result error mismatch: have %v, want %v", err, failure)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	m := NewManager(1, time.Hour)
+	defer m.Stop()
+
+	work := func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	id, err := m.Submit("test_work", work)
+	if err != nil {
+		t.Fatalf("failed to submit job: %v", err)
+	}
+	if _, err := m.Submit("test_work", work); err != ErrTooManyJobs {
+		t.Errorf("job over limit: have %v, want %v", err, ErrTooManyJobs)
+	}
+	if err := m.Cancel(id); err != nil {
+		t.Fatalf("failed to cancel job: %v", err)
+	}
+	if status := waitJob(t, m, id); status.Status != Cancelled {
+		t.Errorf("status mismatch: %+v", status)
+	}
+	if _, err := m.Submit("test_work", work); err != nil {
+		t.Errorf("failed to submit job after cancellation: %v", err)
+	}
+}
+
+func TestJobExpiry(t *testing.T) {
+	m := NewManager(1, 50*time.Millisecond)
+	defer m.Stop()
+
+	id, _ := m.Submit("test_work", func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+	waitJob(t, m, id)
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := m.Result(id); err != ErrUnknownJob {
+		t.Errorf("expired job result: have %v, want %v", err, ErrUnknownJob)
+	}
+}
+
+func TestJobStop(t *testing.T) {
+	m := NewManager(1, time.Hour)
+
+	id, _ := m.Submit("test_work", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, nil
+	})
+	m.Stop()
+
+	if status, _ := m.Status(id); status.Status != Cancelled {
+		t.Errorf("status mismatch: %+v", status)
+	}
+	if _, err := m.Submit("test_work", nil); err != ErrStopped {
+		t.Errorf("job submitted after stop: have %v, want %v", err, ErrStopped)
+	}
+}
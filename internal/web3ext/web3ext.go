@@ -27,9 +27,11 @@ var Modules = map[string]string{
 	"net":        Net_JS,
 	"personal":   Personal_JS,
 	"rpc":        RPC_JS,
+	"schedule":   Schedule_JS,
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
+	"watch":      Watch_JS,
 }
 
 const Chequebook_JS = `
@@ -95,6 +97,17 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'exportCheckpoint',
+			call: 'clique_exportCheckpoint',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'importCheckpoint',
+			call: 'clique_importCheckpoint',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'propose',
 			call: 'clique_propose',
@@ -140,11 +153,40 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'exportAnalytics',
+			call: 'admin_exportAnalytics',
+			params: 4,
+			inputFormatter: [null, null, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'importChain',
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'exportChainSegments',
+			call: 'admin_exportChainSegments',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'importChainSegments',
+			call: 'admin_importChainSegments',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'exportStateSnapshot',
+			call: 'admin_exportStateSnapshot',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'importStateSnapshot',
+			call: 'admin_importStateSnapshot',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -170,12 +212,135 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'setVerbosity',
+			call: 'admin_setVerbosity',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getVerbosity',
+			call: 'admin_getVerbosity'
+		}),
+		new web3._extend.Method({
+			name: 'setSyncBandwidth',
+			call: 'admin_setSyncBandwidth',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'banPeer',
+			call: 'admin_banPeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'unbanPeer',
+			call: 'admin_unbanPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'registerStorageLayout',
+			call: 'admin_registerStorageLayout',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setPeerScaling',
+			call: 'admin_setPeerScaling',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setBlocklist',
+			call: 'admin_setBlocklist',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'addRPCKey',
+			call: 'admin_addRPCKey',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'removeRPCKey',
+			call: 'admin_removeRPCKey',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'addFirewallRule',
+			call: 'admin_addFirewallRule',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'removeFirewallRule',
+			call: 'admin_removeFirewallRule',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'banClient',
+			call: 'admin_banClient',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'unbanClient',
+			call: 'admin_unbanClient',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
 			name: 'nodeInfo',
 			getter: 'admin_nodeInfo'
 		}),
+		new web3._extend.Property({
+			name: 'rpcKeys',
+			getter: 'admin_rpcKeys'
+		}),
+		new web3._extend.Property({
+			name: 'wsConnections',
+			getter: 'admin_wsConnections'
+		}),
+		new web3._extend.Property({
+			name: 'firewall',
+			getter: 'admin_firewall'
+		}),
+		new web3._extend.Property({
+			name: 'clientBans',
+			getter: 'admin_clientBans'
+		}),
+		new web3._extend.Property({
+			name: 'syncBandwidth',
+			getter: 'admin_syncBandwidth'
+		}),
+		new web3._extend.Property({
+			name: 'peerScores',
+			getter: 'admin_peerScores'
+		}),
+		new web3._extend.Property({
+			name: 'peerScaling',
+			getter: 'admin_peerScaling'
+		}),
+		new web3._extend.Property({
+			name: 'headBeacons',
+			getter: 'admin_headBeacons'
+		}),
+		new web3._extend.Property({
+			name: 'chainWatchdog',
+			getter: 'admin_chainWatchdog'
+		}),
+		new web3._extend.Property({
+			name: 'dbSupervisor',
+			getter: 'admin_dbSupervisor'
+		}),
+		new web3._extend.Property({
+			name: 'sqlMirror',
+			getter: 'admin_sqlMirror'
+		}),
+		new web3._extend.Property({
+			name: 'blocklist',
+			getter: 'admin_blocklist'
+		}),
+		new web3._extend.Property({
+			name: 'tenants',
+			getter: 'admin_tenants'
+		}),
 		new web3._extend.Property({
 			name: 'peers',
 			getter: 'admin_peers'
@@ -197,6 +362,28 @@ web3._extend({
 			call: 'debug_printBlock',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'dbStats',
+			call: 'debug_dbStats',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'bloomIndexerStatus',
+			call: 'debug_bloomIndexerStatus',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'vmStats',
+			call: 'debug_vmStats',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'resetVmStats',
+			call: 'debug_resetVmStats',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'getBlockRlp',
 			call: 'debug_getBlockRlp',
@@ -230,7 +417,19 @@ web3._extend({
 		new web3._extend.Method({
 			name: 'metrics',
 			call: 'debug_metrics',
-			params: 1
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'metricsSnapshot',
+			call: 'debug_metricsSnapshot',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'p2pStats',
+			call: 'debug_p2pStats',
+			params: 0
 		}),
 		new web3._extend.Method({
 			name: 'verbosity',
@@ -253,6 +452,11 @@ web3._extend({
 			params: 0,
 			outputFormatter: console.log
 		}),
+		new web3._extend.Method({
+			name: 'watchdog',
+			call: 'debug_watchdog',
+			params: 0
+		}),
 		new web3._extend.Method({
 			name: 'freeOSMemory',
 			call: 'debug_freeOSMemory',
@@ -379,11 +583,67 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'submitJob',
+			call: 'debug_submitJob',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'jobStatus',
+			call: 'debug_jobStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'jobResult',
+			call: 'debug_jobResult',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'cancelJob',
+			call: 'debug_cancelJob',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'replayBadBlock',
+			call: 'debug_replayBadBlock',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'stateDiag',
+			call: 'debug_stateDiag',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'diagnoseStateRoot',
+			call: 'debug_diagnoseStateRoot',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getDecodedStorage',
+			call: 'debug_getDecodedStorage',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockWitness',
+			call: 'debug_getBlockWitness',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'verifyBlockWitness',
+			call: 'debug_verifyBlockWitness',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
 			params: 5,
 		}),
+		new web3._extend.Method({
+			name: 'snapshotProgress',
+			call: 'debug_snapshotProgress',
+		}),
 		new web3._extend.Method({
 			name: 'getModifiedAccountsByNumber',
 			call: 'debug_getModifiedAccountsByNumber',
@@ -397,7 +657,16 @@ web3._extend({
 			inputFormatter:[null, null],
 		}),
 	],
-	properties: []
+	properties: [
+		new web3._extend.Property({
+			name: 'jobs',
+			getter: 'debug_jobs'
+		}),
+		new web3._extend.Property({
+			name: 'stateDiagReports',
+			getter: 'debug_stateDiagReports'
+		}),
+	]
 });
 `
 
@@ -429,11 +698,24 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'sendRawTransactionPrivate',
+			call: 'eth_sendRawTransactionPrivate',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransaction',
 			call: 'eth_getRawTransactionByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getHeader',
+			call: function(args) {
+				return (web3._extend.utils.isString(args[0]) && args[0].indexOf('0x') === 0) ? 'eth_getHeaderByHash' : 'eth_getHeaderByNumber';
+			},
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -442,8 +724,61 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getBlockReceipts',
+			call: 'eth_getBlockReceipts',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getTxOrderCandidates',
+			call: 'eth_getTxOrderCandidates',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'verifyTxOrder',
+			call: 'eth_verifyTxOrder',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'getBalanceChanges',
+			call: 'eth_getBalanceChanges',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getTransactionsByAddress',
+			call: 'eth_getTransactionsByAddress',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null],
+			outputFormatter: function(txs) {
+				var formatted = [];
+				for (var i = 0; i < txs.length; i++) {
+					formatted.push(web3._extend.formatters.outputTransactionFormatter(txs[i]));
+				}
+				return formatted;
+			}
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactions',
+			call: 'eth_getInternalTransactions',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'simulateBundle',
+			call: 'eth_simulateBundle',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'syncProgress',
+			getter: 'eth_syncProgress'
+		}),
 		new web3._extend.Property({
 			name: 'pendingTransactions',
 			getter: 'eth_pendingTransactions',
@@ -491,6 +826,17 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'setGasLimitTarget',
+			call: 'miner_setGasLimitTarget',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'setTxOrderCommit',
+			call: 'miner_setTxOrderCommit',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
@@ -619,7 +965,14 @@ web3._extend({
 const TxPool_JS = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'inspectAccount',
+			call: 'txpool_inspectAccount',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -642,3 +995,48 @@ web3._extend({
 	]
 });
 `
+
+const Watch_JS = `
+web3._extend({
+	property: 'watch',
+	methods: [
+		new web3._extend.Method({
+			name: 'getEvents',
+			call: 'watch_getEvents',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'status',
+			getter: 'watch_status'
+		}),
+	]
+});
+`
+
+const Schedule_JS = `
+web3._extend({
+	property: 'schedule',
+	methods: [
+		new web3._extend.Method({
+			name: 'submit',
+			call: 'schedule_submit',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'cancel',
+			call: 'schedule_cancel',
+			params: 1
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'list',
+			getter: 'schedule_list'
+		}),
+	]
+});
+`
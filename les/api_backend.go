@@ -18,7 +18,9 @@ package les
 
 import (
 	"context"
+	"errors"
 	"math/big"
+	"time"
 
 	"github.com/fulcrumchain/indigo/accounts"
 	"github.com/fulcrumchain/indigo/common"
@@ -47,6 +49,10 @@ func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
 	return b.eth.chainConfig
 }
 
+func (b *LesApiBackend) Blocklist() *core.Blocklist {
+	return b.eth.blocklist
+}
+
 func (b *LesApiBackend) InitialSupply() *big.Int {
 	return b.initialSupply
 }
@@ -68,13 +74,36 @@ func (b *LesApiBackend) SetHead(number uint64) {
 }
 
 func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	head := b.eth.blockchain.CurrentHeader()
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
-		return b.eth.blockchain.CurrentHeader(), nil
+		return head, nil
 	}
+	blockNr = b.eth.config.Finality.Resolve(blockNr, head.Number.Uint64())
 
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
 
+func (b *LesApiBackend) HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error) {
+	return b.eth.blockchain.GetHeaderByHash(blockHash), nil
+}
+
+func (b *LesApiBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if blockNr, ok := blockNrOrHash.Number(); ok {
+		return b.HeaderByNumber(ctx, blockNr)
+	}
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		header := b.eth.blockchain.GetHeaderByHash(hash)
+		if header == nil {
+			return nil, &core.BlockNotFoundError{Hash: hash}
+		}
+		if blockNrOrHash.RequireCanonical && core.GetCanonicalHash(b.eth.chainDb, header.Number.Uint64()) != hash {
+			return nil, &core.NonCanonicalBlockError{Hash: hash, Number: header.Number.Uint64()}
+		}
+		return header, nil
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
 func (b *LesApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
 	header, err := b.HeaderByNumber(ctx, blockNr)
 	if header == nil || err != nil {
@@ -83,8 +112,8 @@ func (b *LesApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	return b.GetBlock(ctx, header.Hash())
 }
 
-func (b *LesApiBackend) StateQuery(ctx context.Context, blockNr rpc.BlockNumber, fn func(*state.StateDB) error) error {
-	header, err := b.HeaderByNumber(ctx, blockNr)
+func (b *LesApiBackend) StateQuery(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, fn func(*state.StateDB) error) error {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
 	if header == nil || err != nil {
 		return err
 	}
@@ -99,6 +128,14 @@ func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	return light.NewState(ctx, header, b.eth.odr), header, nil
 }
 
+func (b *LesApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if header == nil || err != nil {
+		return nil, nil, err
+	}
+	return light.NewState(ctx, header, b.eth.odr), header, nil
+}
+
 func (b *LesApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
 	return b.eth.blockchain.GetBlockByHash(ctx, blockHash)
 }
@@ -117,10 +154,21 @@ func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *sta
 	return vm.NewEVM(context, state, b.eth.chainConfig, vmCfg), nil
 }
 
+// SendTx relays a transaction to the servers. Deadlines are not supported, as
+// the servers can't be told about them.
 func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if _, ok := core.ValidUntilFromContext(ctx); ok {
+		return errors.New("transaction deadlines not supported by light clients")
+	}
 	return b.eth.txPool.Add(ctx, signedTx)
 }
 
+// SendPrivateTx is not supported, as light clients relay all transactions to
+// their servers.
+func (b *LesApiBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	return errors.New("private transactions not supported by light clients")
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.eth.txPool.RemoveTx(txHash)
 }
@@ -133,6 +181,17 @@ func (b *LesApiBackend) GetPoolTransaction(txHash common.Hash) *types.Transactio
 	return b.eth.txPool.GetTransaction(txHash)
 }
 
+// TxArrivalTime is not supported by light clients, which don't see transactions
+// of others arrive.
+func (b *LesApiBackend) TxArrivalTime(txHash common.Hash) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// TxOrderCandidates is not supported by light clients, which don't seal blocks.
+func (b *LesApiBackend) TxOrderCandidates(commitment common.Hash) ([]types.TxArrival, bool) {
+	return nil, false
+}
+
 func (b *LesApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
 	return b.eth.txPool.GetNonce(ctx, addr)
 }
@@ -145,10 +204,32 @@ func (b *LesApiBackend) TxPoolContent(ctx context.Context) (map[common.Address]t
 	return b.eth.txPool.Content(ctx)
 }
 
+func (b *LesApiBackend) TxPoolAccount(addr common.Address) (*core.TxPoolAccount, error) {
+	return nil, errors.New("account inspection not supported by light clients")
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
 
+// GetAddressTransactions is not supported, as light clients do not keep the
+// transactions needed for the address index.
+func (b *LesApiBackend) GetAddressTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.AddressTx, error) {
+	return nil, errors.New("address index not supported by light clients")
+}
+
+// GetInternalTransactions is not supported, as light clients can't re-execute
+// blocks to index their internal transactions.
+func (b *LesApiBackend) GetInternalTransactions(ctx context.Context, addr common.Address, from, to uint64, skip, limit int) ([]core.InternalTx, error) {
+	return nil, errors.New("internal transaction index not supported by light clients")
+}
+
+// SubscribeTxLifecycleEvent returns an empty subscription, as the light
+// transaction pool does not track the lifecycle of its transactions.
+func (b *LesApiBackend) SubscribeTxLifecycleEvent(ch chan<- core.TxLifecycleEvent) event.Subscription {
+	return new(event.Feed).Subscribe(ch)
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.blockchain.SubscribeChainEvent(ch)
 }
@@ -169,6 +250,10 @@ func (b *LesApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEven
 	return b.eth.blockchain.SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.eth.blockchain.SubscribeChainReorgEvent(ch)
+}
+
 func (b *LesApiBackend) Downloader() *downloader.Downloader {
 	return b.eth.Downloader()
 }
@@ -201,6 +286,12 @@ func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
 	return light.BloomTrieFrequency, sections
 }
 
+// BloomParams returns the size of the header blooms, as light clients only
+// retrieve those.
+func (b *LesApiBackend) BloomParams() (uint, uint) {
+	return params.HeaderBloomBits, params.HeaderBloomHashes
+}
+
 func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
@@ -66,6 +66,8 @@ type LightIndigo struct {
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
 
+	blocklist *core.Blocklist // Contracts calls to are refused
+
 	bloomRequests                              chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer, chtIndexer, bloomTrieIndexer *core.ChainIndexer
 
@@ -141,6 +143,7 @@ func New(ctx context.Context, sctx *node.ServiceContext, config *eth.Config) (*L
 	}
 
 	leth.txPool = light.NewTxPool(leth.chainConfig, leth.blockchain, leth.relay)
+	leth.blocklist = core.NewBlocklist(config.Blocklist)
 	if leth.protocolManager, err = NewProtocolManager(ctx, leth.chainConfig, true, ClientProtocolVersions, config.NetworkId, leth.eventMux, leth.engine, leth.peers, leth.blockchain, nil, chainDb, leth.odr, leth.relay, quitSync, &leth.wg); err != nil {
 		return nil, err
 	}
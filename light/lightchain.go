@@ -29,6 +29,7 @@ import (
 	"github.com/fulcrumchain/indigo/core"
 	"github.com/fulcrumchain/indigo/core/state"
 	"github.com/fulcrumchain/indigo/core/types"
+	"github.com/fulcrumchain/indigo/core/vm"
 	"github.com/fulcrumchain/indigo/ethdb"
 	"github.com/fulcrumchain/indigo/event"
 	"github.com/fulcrumchain/indigo/log"
@@ -75,6 +76,9 @@ type LightChain struct {
 // available in the database. It initialises the default Ethereum header
 // validator.
 func NewLightChain(odr OdrBackend, config *params.ChainConfig, engine consensus.Engine) (*LightChain, error) {
+	if err := vm.CheckPrecompiles(config); err != nil {
+		return nil, err
+	}
 	bodyCache, _ := lru.New(bodyCacheLimit)
 	bodyRLPCache, _ := lru.New(bodyCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
@@ -514,6 +518,12 @@ func (self *LightChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 	return self.scope.Track(new(event.Feed).Subscribe(ch))
 }
 
+// SubscribeChainReorgEvent implements the interface of filters.Backend
+// LightChain does not send core.ChainReorgEvent, so return an empty subscription.
+func (self *LightChain) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return self.scope.Track(new(event.Feed).Subscribe(ch))
+}
+
 // SubscribeRemovedLogsEvent implements the interface of filters.Backend
 // LightChain does not send core.RemovedLogsEvent, so return an empty subscription.
 func (self *LightChain) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
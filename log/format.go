@@ -107,10 +107,7 @@ func TerminalFormat(usecolor bool) Format {
 		lvl := r.Lvl.AlignedString()
 		if atomic.LoadUint32(&locationEnabled) != 0 {
 			// Log origin printing was requested, format the location path and line number
-			location := fmt.Sprintf("%+v", r.Call)
-			for _, prefix := range locationTrims {
-				location = strings.TrimPrefix(location, prefix)
-			}
+			location := trimLocation(fmt.Sprintf("%+v", r.Call))
 			// Maintain the maximum location length for fancyer alignment
 			align := int(atomic.LoadUint32(&locationLength))
 			if align < len(location) {
@@ -244,6 +241,63 @@ func JsonFormatEx(pretty, lineSeparated bool) Format {
 	})
 }
 
+// HeadFunc reports the current chain head for structured log records.
+type HeadFunc func() (number uint64, hash [32]byte)
+
+// chainHead holds the HeadFunc registered via SetChainHead.
+var chainHead atomic.Value
+
+// SetChainHead registers fn to supply the chain head attached to the records
+// of JsonStructuredFormat. A nil fn omits the head fields.
+func SetChainHead(fn HeadFunc) {
+	chainHead.Store(fn)
+}
+
+// JsonStructuredFormat formats log records as newline separated JSON objects
+// meant for log shippers. Besides the message and context, every record carries
+// its timestamp, calling location, the emitting module (unless the context sets
+// one) and the chain head registered via SetChainHead.
+func JsonStructuredFormat() Format {
+	return FormatFunc(func(r *Record) []byte {
+		props := make(map[string]interface{}, len(r.Ctx)/2+7)
+
+		for i := 0; i < len(r.Ctx); i += 2 {
+			k, ok := r.Ctx[i].(string)
+			if !ok {
+				props[errorKey] = fmt.Sprintf("%+v is not a string key", r.Ctx[i])
+			}
+			props[k] = formatJsonValue(r.Ctx[i+1])
+		}
+		props[r.KeyNames.Time] = r.Time
+		props[r.KeyNames.Lvl] = r.Lvl.String()
+		props[r.KeyNames.Msg] = r.Msg
+		props[callerKey] = trimLocation(fmt.Sprintf("%+v", r.Call))
+		if _, ok := props[moduleKey]; !ok {
+			props[moduleKey] = trimLocation(fmt.Sprintf("%+k", r.Call))
+		}
+		if fn, _ := chainHead.Load().(HeadFunc); fn != nil {
+			number, hash := fn()
+			props[headNumberKey] = number
+			props[headHashKey] = fmt.Sprintf("%#x", hash)
+		}
+		b, err := json.Marshal(props)
+		if err != nil {
+			b, _ = json.Marshal(map[string]string{
+				errorKey: err.Error(),
+			})
+		}
+		return append(b, '\n')
+	})
+}
+
+// trimLocation strips the well known prefixes from a source location.
+func trimLocation(location string) string {
+	for _, prefix := range locationTrims {
+		location = strings.TrimPrefix(location, prefix)
+	}
+	return location
+}
+
 func formatShared(value interface{}) (result interface{}) {
 	defer func() {
 		if err := recover(); err != nil {
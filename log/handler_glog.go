@@ -60,6 +60,7 @@ func NewGlogHandler(h Handler) *GlogHandler {
 // pattern contains a filter for the Vmodule option, holding a verbosity level
 // and a file pattern to match.
 type pattern struct {
+	source  string // File pattern as specified by the user
 	pattern *regexp.Regexp
 	level   Lvl
 }
@@ -90,6 +91,27 @@ func (h *GlogHandler) IsLogging(level Lvl) bool {
 //  pattern="foo/*=3"
 //   sets V to 3 in all files of any packages whose import path contains "foo"
 func (h *GlogHandler) Vmodule(ruleset string) error {
+	filter, err := parseVmodule(ruleset)
+	if err != nil {
+		return err
+	}
+	// Swap out the vmodule pattern for the new filter system
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.setPatterns(filter)
+	return nil
+}
+
+// setPatterns replaces the vmodule patterns. The caller must hold the lock.
+func (h *GlogHandler) setPatterns(filter []pattern) {
+	h.patterns = filter
+	h.siteCache = make(map[uintptr]Lvl)
+	atomic.StoreUint32(&h.override, uint32(len(filter)))
+}
+
+// parseVmodule compiles a vmodule ruleset into filter patterns.
+func parseVmodule(ruleset string) ([]pattern, error) {
 	var filter []pattern
 	for _, rule := range strings.Split(ruleset, ",") {
 		// Empty strings such as from a trailing comma can be ignored
@@ -99,17 +121,17 @@ func (h *GlogHandler) Vmodule(ruleset string) error {
 		// Ensure we have a pattern = level filter rule
 		parts := strings.Split(rule, "=")
 		if len(parts) != 2 {
-			return errVmoduleSyntax
+			return nil, errVmoduleSyntax
 		}
 		parts[0] = strings.TrimSpace(parts[0])
 		parts[1] = strings.TrimSpace(parts[1])
 		if len(parts[0]) == 0 || len(parts[1]) == 0 {
-			return errVmoduleSyntax
+			return nil, errVmoduleSyntax
 		}
 		// Parse the level and if correct, assemble the filter rule
 		level, err := strconv.Atoi(parts[1])
 		if err != nil {
-			return errVmoduleSyntax
+			return nil, errVmoduleSyntax
 		}
 		if level <= 0 {
 			continue // Ignore. It's harmless but no point in paying the overhead.
@@ -129,19 +151,62 @@ func (h *GlogHandler) Vmodule(ruleset string) error {
 		matcher = matcher + "$"
 
 		re, _ := regexp.Compile(matcher)
-		filter = append(filter, pattern{re, Lvl(level)})
+		filter = append(filter, pattern{parts[0], re, Lvl(level)})
+	}
+	return filter, nil
+}
+
+// GetVerbosity returns the glog verbosity ceiling.
+func (h *GlogHandler) GetVerbosity() Lvl {
+	return Lvl(atomic.LoadUint32(&h.level))
+}
+
+// ModuleVerbosity sets the verbosity of the files matching a single Vmodule
+// pattern, keeping the other patterns intact. A level of 0 removes the pattern.
+// As with Vmodule, patterns can only raise the verbosity above the ceiling.
+func (h *GlogHandler) ModuleVerbosity(module string, level Lvl) error {
+	module = strings.TrimSpace(module)
+	if len(module) == 0 || strings.ContainsAny(module, ",=") {
+		return errVmoduleSyntax
 	}
-	// Swap out the vmodule pattern for the new filter system
 	h.lock.Lock()
 	defer h.lock.Unlock()
 
-	h.patterns = filter
-	h.siteCache = make(map[uintptr]Lvl)
-	atomic.StoreUint32(&h.override, uint32(len(filter)))
-
+	var (
+		rules []string
+		found bool
+	)
+	for _, rule := range h.patterns {
+		if rule.source == module {
+			rule.level, found = level, true
+		}
+		if rule.level > 0 {
+			rules = append(rules, fmt.Sprintf("%s=%d", rule.source, rule.level))
+		}
+	}
+	if !found && level > 0 {
+		rules = append(rules, fmt.Sprintf("%s=%d", module, level))
+	}
+	filter, err := parseVmodule(strings.Join(rules, ","))
+	if err != nil {
+		return err
+	}
+	h.setPatterns(filter)
 	return nil
 }
 
+// Modules returns the verbosity of each Vmodule pattern.
+func (h *GlogHandler) Modules() map[string]Lvl {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	modules := make(map[string]Lvl, len(h.patterns))
+	for _, rule := range h.patterns {
+		modules[rule.source] = rule.level
+	}
+	return modules
+}
+
 // BacktraceAt sets the glog backtrace location. When set to a file and line
 // number holding a logging statement, a stack trace will be written to the Info
 // log whenever execution hits that statement.
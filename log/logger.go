@@ -12,6 +12,10 @@ const timeKey = "t"
 const lvlKey = "lvl"
 const msgKey = "msg"
 const errorKey = "LOG15_ERROR"
+const callerKey = "caller"
+const moduleKey = "module"
+const headNumberKey = "headNumber"
+const headHashKey = "headHash"
 
 type Lvl int
 
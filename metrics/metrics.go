@@ -84,6 +84,16 @@ func NewTimer(name string) metrics.Timer {
 	return metrics.GetOrRegisterTimer(name, metrics.DefaultRegistry)
 }
 
+// NewHistogram create a new metrics Histogram, either a real one of a NOP stub
+// depending on the metrics flag. It samples with an exponentially decaying
+// reservoir, biased towards the last five minutes.
+func NewHistogram(name string) metrics.Histogram {
+	if !Enabled {
+		return new(metrics.NilHistogram)
+	}
+	return metrics.GetOrRegisterHistogram(name, metrics.DefaultRegistry, metrics.NewExpDecaySample(1028, 0.015))
+}
+
 // CollectProcessMetrics periodically collects various metrics about the running
 // process.
 func CollectProcessMetrics(refresh time.Duration) {
@@ -0,0 +1,139 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// Labels are key-value pairs qualifying metrics, such as the database measured
+// or the network the node is on, so that dashboards can be templated across
+// nodes and subsystems.
+type Labels map[string]string
+
+// Common label keys.
+const (
+	LabelDB      = "db"      // Database measured, e.g. "chaindata"
+	LabelRole    = "role"    // Role of the node, e.g. "sealer"
+	LabelNetwork = "network" // Network identifier of the node
+
+	LabelProtocol = "protocol" // Devp2p protocol measured, e.g. "eth"
+)
+
+var (
+	labelLock    sync.RWMutex
+	nodeLabels   = make(Labels)            // Labels applying to all metrics of the node
+	metricLabels = make(map[string]Labels) // Labels of individual metrics, by full name
+)
+
+// SetNodeLabel sets a label applying to all metrics of the node.
+func SetNodeLabel(key, value string) {
+	labelLock.Lock()
+	defer labelLock.Unlock()
+
+	nodeLabels[key] = value
+}
+
+// LabelsOf returns the labels of a metric, including the node labels.
+func LabelsOf(name string) Labels {
+	labelLock.RLock()
+	defer labelLock.RUnlock()
+
+	labels := make(Labels, len(nodeLabels)+len(metricLabels[name]))
+	for key, value := range nodeLabels {
+		labels[key] = value
+	}
+	for key, value := range metricLabels[name] {
+		labels[key] = value
+	}
+	return labels
+}
+
+// Namespace is a group of metrics sharing a name prefix and labels. Metric names
+// are lowercase and slash separated, going from the subsystem down to the value
+// measured, e.g. "db/chaindata/user/gets".
+type Namespace struct {
+	prefix string
+	labels Labels
+}
+
+// NewNamespace creates the namespace of a subsystem's metrics.
+func NewNamespace(name string, labels Labels) *Namespace {
+	return &Namespace{prefix: strings.Trim(name, "/"), labels: labels}
+}
+
+// DB returns the namespace of the metrics of the named database.
+func DB(name string) *Namespace {
+	return NewNamespace("db/"+name, Labels{LabelDB: name})
+}
+
+// Sub creates a nested namespace, inheriting the labels of ns.
+func (ns *Namespace) Sub(name string, labels Labels) *Namespace {
+	merged := make(Labels, len(ns.labels)+len(labels))
+	for key, value := range ns.labels {
+		merged[key] = value
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return NewNamespace(ns.Name(name), merged)
+}
+
+// Name returns the full name of a metric in the namespace.
+func (ns *Namespace) Name(metric string) string {
+	return ns.prefix + "/" + strings.Trim(metric, "/")
+}
+
+// register records the labels of a metric in the namespace and returns its
+// full name.
+func (ns *Namespace) register(metric string) string {
+	name := ns.Name(metric)
+	if Enabled && len(ns.labels) > 0 {
+		labelLock.Lock()
+		metricLabels[name] = ns.labels
+		labelLock.Unlock()
+	}
+	return name
+}
+
+// Counter creates a new Counter in the namespace.
+func (ns *Namespace) Counter(name string) metrics.Counter {
+	return NewCounter(ns.register(name))
+}
+
+// Meter creates a new Meter in the namespace.
+func (ns *Namespace) Meter(name string) metrics.Meter {
+	return NewMeter(ns.register(name))
+}
+
+// Gauge creates a new Gauge in the namespace.
+func (ns *Namespace) Gauge(name string) metrics.Gauge {
+	return NewGauge(ns.register(name))
+}
+
+// Histogram creates a new Histogram in the namespace.
+func (ns *Namespace) Histogram(name string) metrics.Histogram {
+	return NewHistogram(ns.register(name))
+}
+
+// Timer creates a new Timer in the namespace.
+func (ns *Namespace) Timer(name string) metrics.Timer {
+	return NewTimer(ns.register(name))
+}
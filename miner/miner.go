@@ -19,6 +19,7 @@ package miner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync/atomic"
 
@@ -58,6 +59,7 @@ type Miner struct {
 
 	canStart    int32 // can start indicates whether we can start the mining operation
 	shouldStart int32 // should start indicates whether we should start after sync
+	halted      int32 // halted indicates whether sealing is refused until lifted
 }
 
 func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine) *Miner {
@@ -115,6 +117,10 @@ func (self *Miner) Start(coinbase common.Address) {
 		log.Info("Network syncing, will start miner afterwards")
 		return
 	}
+	if atomic.LoadInt32(&self.halted) == 1 {
+		log.Warn("Mining halted, will start miner once lifted")
+		return
+	}
 	atomic.StoreInt32(&self.mining, 1)
 
 	log.Info("Starting mining operation")
@@ -128,6 +134,29 @@ func (self *Miner) Stop() {
 	atomic.StoreInt32(&self.shouldStart, 0)
 }
 
+// SetHalted stops sealing and refuses to start until lifted, resuming once lifted
+// if mining was requested in the meantime or running before.
+func (self *Miner) SetHalted(halted bool) {
+	if halted {
+		if !atomic.CompareAndSwapInt32(&self.halted, 0, 1) {
+			return
+		}
+		if self.Mining() {
+			self.Stop()
+			atomic.StoreInt32(&self.shouldStart, 1)
+			log.Warn("Mining halted")
+		}
+		return
+	}
+	if !atomic.CompareAndSwapInt32(&self.halted, 1, 0) {
+		return
+	}
+	if atomic.LoadInt32(&self.shouldStart) == 1 {
+		log.Info("Mining halt lifted")
+		self.Start(self.coinbase)
+	}
+}
+
 func (self *Miner) Register(agent Agent) {
 	if self.Mining() {
 		agent.Start()
@@ -151,6 +180,34 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetGasLimitTarget sets the gas limit that sealed blocks move toward, bounded
+// by the maximum change allowed per block. A zero target restores the default
+// usage based strategy. Chains whose configuration defines a gas limit
+// trajectory refuse any target, as sealers must follow the trajectory.
+func (self *Miner) SetGasLimitTarget(target uint64) error {
+	if target != 0 && self.worker.config.GasLimit != nil {
+		return errors.New("gas limit target conflicts with the chain configured gas limit")
+	}
+	if target != 0 && target < params.MinGasLimit {
+		return fmt.Errorf("gas limit target below minimum: %d < %d", target, params.MinGasLimit)
+	}
+	self.worker.setGasLimitTarget(target)
+	return nil
+}
+
+// SetTxOrderCommit toggles first come first served transaction ordering, with
+// sealed blocks committing to their arrival stamped candidates in the vanity of
+// their extra-data so that the order can be audited.
+func (self *Miner) SetTxOrderCommit(commit bool) {
+	self.worker.setTxOrderCommit(commit)
+}
+
+// TxOrderCandidates returns the arrival stamped candidate transactions recently
+// sealed work committed to, for the given transaction order commitment.
+func (self *Miner) TxOrderCandidates(commitment common.Hash) ([]types.TxArrival, bool) {
+	return self.worker.txOrderCandidates(commitment)
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending(ctx context.Context) (*types.Block, *state.StateDB) {
 	ctx, span := trace.StartSpan(ctx, "Miner.Pending")
@@ -174,6 +231,13 @@ func (self *Miner) PendingBlock(ctx context.Context) *types.Block {
 	return self.worker.pendingBlock()
 }
 
+// SubscribePendingBlockEvent registers a subscription of PendingBlockEvent, sent
+// each time the miner updates the block it works on. Events are dropped for
+// subscribers falling behind.
+func (self *Miner) SubscribePendingBlockEvent(ch chan<- core.PendingBlockEvent) event.Subscription {
+	return self.worker.subscribePending(ch)
+}
+
 func (self *Miner) SetEtherbase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setEtherbase(addr)
@@ -27,6 +27,7 @@ import (
 
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/consensus"
+	"github.com/fulcrumchain/indigo/consensus/clique"
 	"github.com/fulcrumchain/indigo/core"
 	"github.com/fulcrumchain/indigo/core/state"
 	"github.com/fulcrumchain/indigo/core/types"
@@ -34,7 +35,9 @@ import (
 	"github.com/fulcrumchain/indigo/ethdb"
 	"github.com/fulcrumchain/indigo/event"
 	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
 	"github.com/fulcrumchain/indigo/params"
+	"github.com/hashicorp/golang-lru"
 )
 
 const (
@@ -48,8 +51,13 @@ const (
 	chainHeadChanSize = 100
 	// chainSideChanSize is the size of channel listening to ChainSideEvent.
 	chainSideChanSize = 10
+	// txOrdersCacheSize is the number of candidate lists of recent work kept for
+	// the transaction order commitments to be audited.
+	txOrdersCacheSize = 256
 )
 
+var pendingDropMeter = metrics.NewMeter("miner/pending/dropped") // Pending block events lost to slow subscribers
+
 // Agent can register themself with the worker
 type Agent interface {
 	Work() chan<- *Work
@@ -63,6 +71,7 @@ type Agent interface {
 type Work struct {
 	config *params.ChainConfig
 	signer types.Signer
+	pool   *core.TxPool // Pool the transactions are taken from, tracking their deadlines
 
 	stateMu sync.RWMutex
 	state   *state.StateDB // apply state changes here
@@ -78,6 +87,13 @@ type Work struct {
 	createdAt time.Time
 }
 
+// txSet is an ordered set of pending transactions blocks are filled from.
+type txSet interface {
+	Peek() *types.Transaction
+	Shift(ctx context.Context)
+	Pop()
+}
+
 type Result struct {
 	Work  *Work
 	Block *types.Block
@@ -106,8 +122,11 @@ type worker struct {
 	proc    core.Validator
 	chainDb ethdb.Database
 
-	coinbase common.Address
-	extra    []byte
+	coinbase       common.Address
+	extra          []byte
+	gasLimitTarget uint64     // Gas limit sealed blocks move toward, 0 for the usage based strategy, ignored if the chain configuration defines a gas limit
+	txOrderCommit  bool       // Order transactions by arrival and commit to the stamped candidates in the extra-data
+	txOrders       *lru.Cache // Candidate lists committed to by recent work, keyed by commitment
 
 	currentMu sync.RWMutex
 	current   *Work
@@ -116,6 +135,9 @@ type worker struct {
 	snapshotBlock *types.Block
 	snapshotState *state.StateDB
 
+	pendingSubs map[chan<- core.PendingBlockEvent]struct{} // Subscribers to the work in progress, sent on each snapshot update
+	pendingLock sync.Mutex                                 // Protects pendingSubs
+
 	unconfirmed *unconfirmedBlocks // set of locally mined blocks pending canonicalness confirmations
 
 	// atomic status counters
@@ -137,8 +159,11 @@ func newWorker(config *params.ChainConfig, engine consensus.Engine, coinbase com
 		proc:        eth.BlockChain().Validator(),
 		coinbase:    coinbase,
 		agents:      make(map[Agent]struct{}),
+		pendingSubs: make(map[chan<- core.PendingBlockEvent]struct{}),
 		unconfirmed: newUnconfirmedBlocks(eth.BlockChain(), miningLogAtDepth),
 	}
+	worker.txOrders, _ = lru.New(txOrdersCacheSize)
+
 	// Subscribe NewTxsEvent for tx pool
 	worker.txsSub = eth.TxPool().SubscribeNewTxsEvent(worker.txsCh)
 	// Subscribe events for blockchain
@@ -163,6 +188,28 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+func (w *worker) setGasLimitTarget(target uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.gasLimitTarget = target
+}
+
+func (w *worker) setTxOrderCommit(commit bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.txOrderCommit = commit
+}
+
+// txOrderCandidates returns the arrival stamped candidates recent work committed
+// to, if known.
+func (w *worker) txOrderCandidates(commitment common.Hash) ([]types.TxArrival, bool) {
+	candidates, ok := w.txOrders.Get(commitment)
+	if !ok {
+		return nil, false
+	}
+	return candidates.([]types.TxArrival), true
+}
+
 func (w *worker) pending(ctx context.Context) (*types.Block, *state.StateDB) {
 	if atomic.LoadInt32(&w.mining) == 0 {
 		// return a snapshot to avoid contention on currentMu mutex
@@ -288,7 +335,7 @@ func (w *worker) update() {
 				w.currentMu.Unlock()
 			} else {
 				// If we're mining, but nothing is being processed, wake on new transactions
-				if w.config.Clique != nil && w.config.Clique.Period == 0 {
+				if w.config.Clique != nil && w.config.Clique.PeriodAt(w.chain.CurrentBlock().NumberU64()+1) == 0 {
 					w.commitNewWork(ctx)
 				}
 			}
@@ -394,6 +441,7 @@ func (w *worker) makeCurrent(parent *types.Block, header *types.Header) error {
 	work := &Work{
 		config:    w.config,
 		signer:    types.NewEIP155Signer(w.config.ChainId),
+		pool:      w.eth.TxPool(),
 		state:     state,
 		header:    header,
 		createdAt: time.Now(),
@@ -428,11 +476,15 @@ func (w *worker) commitNewWork(ctx context.Context) {
 		time.Sleep(wait)
 	}
 
+	gasLimit := core.CalcChainGasLimit(w.config, parent)
+	if w.gasLimitTarget != 0 && w.config.GasLimit == nil {
+		gasLimit = core.CalcGasLimitTarget(parent, w.gasLimitTarget)
+	}
 	num := parent.Number()
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     num.Add(num, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent),
+		GasLimit:   gasLimit,
 		Extra:      w.extra,
 		Time:       big.NewInt(tstamp),
 	}
@@ -457,10 +509,31 @@ func (w *worker) commitNewWork(ctx context.Context) {
 
 	// Create the current work task and check any fork transitions needed
 	work := w.current
-	pending := w.eth.TxPool().Pending(ctx)
-	txs := types.NewTransactionsByPriceAndNonce(ctx, w.current.signer, pending)
+	pool := w.eth.TxPool()
+	pending := pool.Pending(ctx)
+	var (
+		txs        txSet
+		candidates []types.TxArrival
+	)
+	switch {
+	case w.txOrderCommit:
+		ordered := types.NewTransactionsByArrivalAndNonce(ctx, w.current.signer, pending, pool.ArrivalTime)
+		txs, candidates = ordered, ordered.Candidates()
+	case pool.TenantWeights():
+		txs = types.NewTransactionsByWeightedPriceAndNonce(ctx, w.current.signer, pending, pool.TxWeight)
+	default:
+		txs = types.NewTransactionsByPriceAndNonce(ctx, w.current.signer, pending)
+	}
 	work.commitTransactions(ctx, w.mux, txs, w.chain, w.coinbase)
 
+	// Commit to the arrival stamped candidates in place of the vanity, keeping
+	// them for the sealed order to be audited against
+	if w.txOrderCommit {
+		commitment := types.TxOrderCommitment(candidates)
+		header.Extra = clique.ExtraReplaceVanity(header.Extra, commitment[:])
+		w.txOrders.Add(commitment, candidates)
+	}
+
 	// Create the new block to seal with the consensus engine
 	work.Block = w.engine.Finalize(ctx, w.chain, header, work.state, work.txs, work.receipts, true)
 	// We only care about logging if we're actually mining.
@@ -472,13 +545,12 @@ func (w *worker) commitNewWork(ctx context.Context) {
 	w.updateSnapshot(ctx)
 }
 
-// updateSnapshot updates snapshotState. Caller must hold currentMu.
+// updateSnapshot updates snapshotState and notifies the pending block
+// subscribers. Caller must hold currentMu.
 func (w *worker) updateSnapshot(ctx context.Context) {
 	ctx, span := trace.StartSpan(ctx, "worker.updateSnapshot")
 	defer span.End()
 	w.snapshotMu.Lock()
-	defer w.snapshotMu.Unlock()
-
 	w.snapshotBlock = types.NewBlock(
 		w.current.header,
 		w.current.txs,
@@ -486,9 +558,44 @@ func (w *worker) updateSnapshot(ctx context.Context) {
 		w.current.receipts,
 	)
 	w.snapshotState = w.current.state.Copy(ctx)
+	block := w.snapshotBlock
+	w.snapshotMu.Unlock()
+
+	w.sendPending(core.PendingBlockEvent{Block: block, Receipts: w.current.receipts})
+}
+
+// subscribePending registers a subscriber to the work in progress.
+func (w *worker) subscribePending(ch chan<- core.PendingBlockEvent) event.Subscription {
+	w.pendingLock.Lock()
+	w.pendingSubs[ch] = struct{}{}
+	w.pendingLock.Unlock()
+
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		w.pendingLock.Lock()
+		delete(w.pendingSubs, ch)
+		w.pendingLock.Unlock()
+		return nil
+	})
+}
+
+// sendPending delivers a pending block event to the subscribers, dropping it
+// for those whose buffer is full rather than stalling the worker, as the next
+// update supersedes it anyway.
+func (w *worker) sendPending(ev core.PendingBlockEvent) {
+	w.pendingLock.Lock()
+	defer w.pendingLock.Unlock()
+
+	for ch := range w.pendingSubs {
+		select {
+		case ch <- ev:
+		default:
+			pendingDropMeter.Mark(1)
+		}
+	}
 }
 
-func (env *Work) commitTransactions(ctx context.Context, mux *event.TypeMux, txs *types.TransactionsByPriceAndNonce, bc *core.BlockChain, coinbase common.Address) {
+func (env *Work) commitTransactions(ctx context.Context, mux *event.TypeMux, txs txSet, bc *core.BlockChain, coinbase common.Address) {
 	ctx, span := trace.StartSpan(ctx, "Work.commitTransactions")
 	defer span.End()
 
@@ -527,6 +634,24 @@ func (env *Work) commitTransactions(ctx context.Context, mux *event.TypeMux, txs
 			txs.Pop()
 			continue
 		}
+		// Skip typed transactions until they are activated, or if they are of a
+		// type not supported by this node.
+		if tx.Type() != types.LegacyTxType && (!env.config.IsTypedTx(env.header.Number) || types.ValidateTxType(tx) != nil) {
+			if tracing {
+				log.Trace("Ignoring unsupported typed transaction", "hash", tx.Hash(), "type", tx.Type(), "fork", env.config.TypedTxBlock)
+			}
+			txs.Pop()
+			continue
+		}
+		// Skip transactions valid until a deadline the block is timestamped after,
+		// along with the later ones of the sender
+		if env.pool != nil && env.pool.Expired(tx.Hash(), time.Unix(env.header.Time.Int64(), 0)) {
+			if tracing {
+				log.Trace("Ignoring transaction past its deadline", "hash", tx.Hash(), "sender", from)
+			}
+			txs.Pop()
+			continue
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), common.Hash{}, env.tcount)
 
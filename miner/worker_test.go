@@ -0,0 +1,56 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/core"
+	"github.com/fulcrumchain/indigo/core/types"
+)
+
+// Tests that pending block events are dropped for subscribers falling behind
+// instead of blocking the worker, and that others still receive them.
+func TestPendingSubscriptionDrops(t *testing.T) {
+	w := &worker{pendingSubs: make(map[chan<- core.PendingBlockEvent]struct{})}
+
+	var (
+		slow = make(chan core.PendingBlockEvent)
+		fast = make(chan core.PendingBlockEvent, 2)
+	)
+	slowSub := w.subscribePending(slow)
+	defer slowSub.Unsubscribe()
+	fastSub := w.subscribePending(fast)
+
+	for i := 0; i < 3; i++ {
+		w.sendPending(core.PendingBlockEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(int64(i))})})
+	}
+	if len(fast) != 2 {
+		t.Fatalf("buffered event count mismatch: have %d, want 2", len(fast))
+	}
+	if ev := <-fast; ev.Block.NumberU64() != 0 {
+		t.Errorf("first event mismatch: have block %d, want 0", ev.Block.NumberU64())
+	}
+	fastSub.Unsubscribe()
+	<-fast
+
+	w.sendPending(core.PendingBlockEvent{Block: types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3)})})
+	if len(fast) != 0 {
+		t.Errorf("event delivered after unsubscribing")
+	}
+}
@@ -20,13 +20,16 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fulcrumchain/indigo/common/hexutil"
 	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/internal/debug"
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/p2p"
 	"github.com/fulcrumchain/indigo/p2p/discover"
+	"github.com/fulcrumchain/indigo/p2p/netutil"
 	"github.com/fulcrumchain/indigo/rpc"
 	"github.com/rcrowley/go-metrics"
 )
@@ -35,6 +38,8 @@ import (
 // over a secure RPC channel.
 type PrivateAdminAPI struct {
 	node *Node // Node interfaced by this API
+
+	firewallLock sync.Mutex // Serializes firewall rule updates
 }
 
 // NewPrivateAdminAPI creates a new API definition for the private admin methods
@@ -232,6 +237,195 @@ func (api *PrivateAdminAPI) StopWS() (bool, error) {
 	return true, nil
 }
 
+// WsConnections returns the traffic counters of the open websocket RPC
+// connections.
+func (api *PrivateAdminAPI) WsConnections() ([]rpc.WebsocketStats, error) {
+	api.node.lock.RLock()
+	defer api.node.lock.RUnlock()
+
+	if api.node.wsHandler == nil {
+		return nil, fmt.Errorf("WebSocket RPC not running")
+	}
+	stats := api.node.wsHandler.WebsocketStats()
+	if api.node.wsScoped != nil {
+		stats = append(stats, api.node.wsScoped.WebsocketStats()...)
+	}
+	return stats, nil
+}
+
+// AddRPCKey generates an API key granting access to the given RPC namespaces,
+// or to the modules exposed by the endpoints if none, and returns its secret.
+// Keys added at runtime are not persisted across restarts.
+func (api *PrivateAdminAPI) AddRPCKey(name string, namespaces []string) (string, error) {
+	if api.node.rpcAuth == nil {
+		return "", ErrRPCAuthOff
+	}
+	secret, err := rpc.GenerateAPIKey()
+	if err != nil {
+		return "", err
+	}
+	if err := api.node.rpcAuth.AddKey(rpc.APIKey{Name: name, Key: secret, Namespaces: namespaces}); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// RemoveRPCKey revokes the API key with the given name.
+func (api *PrivateAdminAPI) RemoveRPCKey(name string) (bool, error) {
+	if api.node.rpcAuth == nil {
+		return false, ErrRPCAuthOff
+	}
+	return api.node.rpcAuth.RemoveKey(name), nil
+}
+
+// RpcKeys lists the accepted API keys with the namespaces they grant access to,
+// without their secrets.
+func (api *PrivateAdminAPI) RpcKeys() ([]rpc.APIKey, error) {
+	if api.node.rpcAuth == nil {
+		return nil, ErrRPCAuthOff
+	}
+	return api.node.rpcAuth.Keys(), nil
+}
+
+// Firewall returns the connection rules enforced by the p2p server.
+func (api *PrivateAdminAPI) Firewall() (*netutil.ConnPolicy, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.FirewallRules(), nil
+}
+
+// AddFirewallRule adds a CIDR mask to the allow or deny rules of the inbound
+// or outbound direction, or both, and persists the updated rules. Connected
+// peers denied by the new rules are dropped.
+func (api *PrivateAdminAPI) AddFirewallRule(direction, action, cidr string) (bool, error) {
+	return api.updateFirewall(direction, action, func(rules *netutil.ConnPolicy, inbound, allow bool) (bool, error) {
+		return true, rules.AddRule(inbound, allow, cidr)
+	})
+}
+
+// RemoveFirewallRule removes a CIDR mask from the allow or deny rules of the
+// inbound or outbound direction, or both, and persists the updated rules. It
+// reports whether any rule was removed.
+func (api *PrivateAdminAPI) RemoveFirewallRule(direction, action, cidr string) (bool, error) {
+	return api.updateFirewall(direction, action, func(rules *netutil.ConnPolicy, inbound, allow bool) (bool, error) {
+		return rules.RemoveRule(inbound, allow, cidr)
+	})
+}
+
+// ClientBans returns the client name patterns rejected during the protocol
+// handshake, along with the number of peers each one rejected.
+func (api *PrivateAdminAPI) ClientBans() ([]p2p.ClientBan, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.ClientBans(), nil
+}
+
+// BanClient rejects peers whose advertised client name matches the regular
+// expression, disconnecting the matching peers already connected. Bans added
+// at runtime are not persisted across restarts.
+func (api *PrivateAdminAPI) BanClient(pattern string) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	if err := server.AddClientBan(pattern); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UnbanClient lifts a client name ban, reporting whether it existed.
+func (api *PrivateAdminAPI) UnbanClient(pattern string) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	return server.RemoveClientBan(pattern), nil
+}
+
+// updateFirewall applies a rule change to the directions selected by
+// direction ("inbound", "outbound" or "both") and action ("allow" or "deny").
+func (api *PrivateAdminAPI) updateFirewall(direction, action string, update func(*netutil.ConnPolicy, bool, bool) (bool, error)) (bool, error) {
+	var inbound []bool
+	switch direction {
+	case "inbound":
+		inbound = []bool{true}
+	case "outbound":
+		inbound = []bool{false}
+	case "both":
+		inbound = []bool{true, false}
+	default:
+		return false, fmt.Errorf("invalid direction %q, want inbound, outbound or both", direction)
+	}
+	if action != "allow" && action != "deny" {
+		return false, fmt.Errorf("invalid action %q, want allow or deny", action)
+	}
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	api.firewallLock.Lock()
+	defer api.firewallLock.Unlock()
+
+	rules, changed := server.FirewallRules(), false
+	for _, in := range inbound {
+		ok, err := update(rules, in, action == "allow")
+		if err != nil {
+			return false, err
+		}
+		changed = changed || ok
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := api.node.config.saveFirewallRules(rules); err != nil {
+		return false, fmt.Errorf("failed to persist firewall rules: %v", err)
+	}
+	server.SetFirewallRules(rules)
+	return true, nil
+}
+
+// VerbosityInfo describes the log verbosity settings of the node.
+type VerbosityInfo struct {
+	Level   int            `json:"level"`   // Verbosity ceiling of all modules
+	Modules map[string]int `json:"modules"` // Modules logging above the ceiling
+}
+
+// SetVerbosity changes the log verbosity of a module without affecting other
+// modules. Modules are specified as with --vmodule: an import path suffix such
+// as "p2p", a path pattern such as "eth/*" or a file name. Modules may only log
+// above the verbosity ceiling, which is changed if module is empty. A level of
+// 0 returns the module to the ceiling.
+func (api *PrivateAdminAPI) SetVerbosity(module string, level int) (bool, error) {
+	if level < int(log.LvlCrit) || level > int(log.LvlTrace) {
+		return false, fmt.Errorf("invalid log level %d, want %d-%d", level, log.LvlCrit, log.LvlTrace)
+	}
+	if module == "" {
+		debug.Handler.Verbosity(level)
+		return true, nil
+	}
+	if err := debug.ModuleVerbosity(module, log.Lvl(level)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetVerbosity retrieves the log verbosity ceiling and the modules logging above
+// it.
+func (api *PrivateAdminAPI) GetVerbosity() *VerbosityInfo {
+	level, modules := debug.GetVerbosity()
+
+	info := &VerbosityInfo{Level: int(level), Modules: make(map[string]int, len(modules))}
+	for module, level := range modules {
+		info.Modules[module] = int(level)
+	}
+	return info
+}
+
 // PublicAdminAPI is the collection of administrative API methods exposed over
 // both secure and unsecure RPC channels.
 type PublicAdminAPI struct {
@@ -281,8 +475,9 @@ func NewPublicDebugAPI(node *Node) *PublicDebugAPI {
 	return &PublicDebugAPI{node: node}
 }
 
-// Metrics retrieves all the known system metric collected by the node.
-func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
+// Metrics retrieves the system metrics collected by the node, optionally only
+// the ones matching a filter.
+func (api *PublicDebugAPI) Metrics(raw bool, filter *MetricsFilter) (map[string]interface{}, error) {
 	// Create a rate formatter
 	units := []string{"", "K", "M", "G", "T", "E", "P"}
 	round := func(value float64, prec int) string {
@@ -298,6 +493,9 @@ func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
 	// Iterate over all the metrics, and just dump for now
 	counters := make(map[string]interface{})
 	metrics.DefaultRegistry.Each(func(name string, metric interface{}) {
+		if !filter.match(name) {
+			return
+		}
 		// Create or retrieve the counter hierarchy for this metric
 		root, parts := counters, strings.Split(name, "/")
 		for _, part := range parts[:len(parts)-1] {
@@ -374,6 +572,12 @@ func (api *PublicDebugAPI) Metrics(raw bool) (map[string]interface{}, error) {
 	return counters, nil
 }
 
+// P2PStats retrieves the traffic of each message type of each protocol since
+// the node started, to tell where the bandwidth goes.
+func (api *PublicDebugAPI) P2PStats() []p2p.MsgStats {
+	return p2p.TrafficStats()
+}
+
 // PublicWeb3API offers helper utils
 type PublicWeb3API struct {
 	stack *Node
@@ -0,0 +1,234 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	indigometrics "github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/rpc"
+	"github.com/rcrowley/go-metrics"
+)
+
+const (
+	defaultMetricsInterval = time.Second            // Default interval between metric notifications
+	minMetricsInterval     = 100 * time.Millisecond // Shortest interval a subscriber may request
+)
+
+var errMetricsDisabled = errors.New("metrics collection disabled, run with --" + indigometrics.MetricsEnabledFlag)
+
+// MetricsUpdate is a snapshot of the metrics matching a subscription, keyed by
+// their full name.
+type MetricsUpdate struct {
+	Time    time.Time              `json:"time"`
+	Metrics map[string]interface{} `json:"metrics"`
+}
+
+// PublicDebugMetricsAPI streams the metrics of the node over subscriptions, so
+// dashboards can chart them live without polling.
+type PublicDebugMetricsAPI struct {
+	registry metrics.Registry
+}
+
+// NewPublicDebugMetricsAPI creates a new API definition for streaming the
+// metrics of the default registry.
+func NewPublicDebugMetricsAPI() *PublicDebugMetricsAPI {
+	return &PublicDebugMetricsAPI{registry: metrics.DefaultRegistry}
+}
+
+// Metrics creates a subscription notifying a snapshot of the metrics matching
+// the filters every interval milliseconds, one second by default. A filter
+// matches the metrics its name is a prefix of, such as "eth/downloader/", or
+// the ones it matches as a glob pattern, such as "txpool/*/discard". Without
+// filters all metrics are streamed.
+func (api *PublicDebugMetricsAPI) Metrics(ctx context.Context, filters []string, interval *uint64) (*rpc.Subscription, error) {
+	if !indigometrics.Enabled {
+		return nil, errMetricsDisabled
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	period := defaultMetricsInterval
+	if interval != nil {
+		period = time.Duration(*interval) * time.Millisecond
+	}
+	if period < minMetricsInterval {
+		period = minMetricsInterval
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				update := &MetricsUpdate{Time: now, Metrics: snapshotMetrics(api.registry, filters)}
+				if err := notifier.Notify(rpcSub.ID, update); err != nil {
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// MetricsFilter selects metrics by name and labels. A metric matches if its name
+// is matched by one of the names, as a prefix or glob pattern as for the
+// metrics subscription, and it carries all the labels. Empty fields match all
+// metrics.
+type MetricsFilter struct {
+	Names  []string          `json:"names"`
+	Labels map[string]string `json:"labels"`
+}
+
+// match reports whether a metric is selected by the filter. A nil filter
+// selects all metrics.
+func (f *MetricsFilter) match(name string) bool {
+	if f == nil {
+		return true
+	}
+	if !matchMetric(name, f.Names) {
+		return false
+	}
+	if len(f.Labels) > 0 {
+		labels := indigometrics.LabelsOf(name)
+		for key, value := range f.Labels {
+			if labels[key] != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// MetricSample is the value of a metric at the time of a snapshot.
+type MetricSample struct {
+	Name   string               `json:"name"`
+	Labels indigometrics.Labels `json:"labels"`
+	Value  interface{}          `json:"value"`
+}
+
+// MetricsSnapshot returns the raw values of the metrics matching the filter,
+// along with their labels, sorted by name.
+func (api *PublicDebugAPI) MetricsSnapshot(filter *MetricsFilter) ([]*MetricSample, error) {
+	if !indigometrics.Enabled {
+		return nil, errMetricsDisabled
+	}
+	return sampleMetrics(metrics.DefaultRegistry, filter), nil
+}
+
+// sampleMetrics returns the labelled values of the metrics in registry matching
+// the filter, sorted by name.
+func sampleMetrics(registry metrics.Registry, filter *MetricsFilter) []*MetricSample {
+	samples := []*MetricSample{}
+	registry.Each(func(name string, metric interface{}) {
+		if !filter.match(name) {
+			return
+		}
+		if value, ok := metricValue(metric); ok {
+			samples = append(samples, &MetricSample{Name: name, Labels: indigometrics.LabelsOf(name), Value: value})
+		}
+	})
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Name < samples[j].Name })
+	return samples
+}
+
+// matchMetric reports whether a metric name is selected by the filters.
+func matchMetric(name string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if strings.HasPrefix(name, filter) {
+			return true
+		}
+		if ok, _ := path.Match(filter, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotMetrics returns the raw values of the metrics in registry matching
+// the filters.
+func snapshotMetrics(registry metrics.Registry, filters []string) map[string]interface{} {
+	values := make(map[string]interface{})
+	registry.Each(func(name string, metric interface{}) {
+		if !matchMetric(name, filters) {
+			return
+		}
+		if value, ok := metricValue(metric); ok {
+			values[name] = value
+		}
+	})
+	return values
+}
+
+// metricValue returns the raw value of a metric, or false for unknown types.
+func metricValue(metric interface{}) (interface{}, bool) {
+	switch metric := metric.(type) {
+	case metrics.Counter:
+		return metric.Count(), true
+	case metrics.Gauge:
+		return metric.Value(), true
+	case metrics.GaugeFloat64:
+		return metric.Value(), true
+	case metrics.Meter:
+		snap := metric.Snapshot()
+		return map[string]interface{}{
+			"count": snap.Count(),
+			"rate1": snap.Rate1(),
+			"mean":  snap.RateMean(),
+		}, true
+	case metrics.Timer:
+		snap := metric.Snapshot()
+		ps := snap.Percentiles([]float64{0.5, 0.95, 0.99})
+		return map[string]interface{}{
+			"count": snap.Count(),
+			"rate1": snap.Rate1(),
+			"mean":  snap.Mean(),
+			"max":   snap.Max(),
+			"p50":   ps[0],
+			"p95":   ps[1],
+			"p99":   ps[2],
+		}, true
+	case metrics.Histogram:
+		snap := metric.Snapshot()
+		ps := snap.Percentiles([]float64{0.5, 0.95, 0.99})
+		return map[string]interface{}{
+			"count": snap.Count(),
+			"mean":  snap.Mean(),
+			"max":   snap.Max(),
+			"p50":   ps[0],
+			"p95":   ps[1],
+			"p99":   ps[2],
+		}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/internal/debug"
+	indigometrics "github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/rpc"
+	"github.com/rcrowley/go-metrics"
+)
+
+// Tests that the log verbosity of individual modules can be changed without
+// affecting the other modules.
+func TestAdminVerbosity(t *testing.T) {
+	api := NewPrivateAdminAPI(nil)
+
+	level, _ := debug.GetVerbosity()
+	defer debug.Handler.Verbosity(int(level))
+	defer debug.Handler.Vmodule("")
+
+	if _, err := api.SetVerbosity("", 3); err != nil {
+		t.Fatalf("failed to set verbosity ceiling: %v", err)
+	}
+	for module, level := range map[string]int{"p2p": 5, "eth/*": 4, "core": 4} {
+		if _, err := api.SetVerbosity(module, level); err != nil {
+			t.Fatalf("failed to set %s verbosity: %v", module, err)
+		}
+	}
+	// Changing a module keeps the others, and level 0 drops the module
+	if _, err := api.SetVerbosity("eth/*", 5); err != nil {
+		t.Fatalf("failed to change verbosity: %v", err)
+	}
+	if _, err := api.SetVerbosity("core", 0); err != nil {
+		t.Fatalf("failed to reset verbosity: %v", err)
+	}
+	want := &VerbosityInfo{Level: 3, Modules: map[string]int{"p2p": 5, "eth/*": 5}}
+	if have := api.GetVerbosity(); !reflect.DeepEqual(have, want) {
+		t.Errorf("verbosity mismatch: have %+v, want %+v", have, want)
+	}
+	// Invalid levels and modules are rejected
+	if _, err := api.SetVerbosity("p2p", 6); err == nil {
+		t.Errorf("invalid level accepted")
+	}
+	if _, err := api.SetVerbosity("p2p=1", 3); err == nil {
+		t.Errorf("invalid module accepted")
+	}
+}
+
+// Tests that RPC API keys can be managed through the admin API.
+func TestAdminRPCKeys(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	if _, err := NewPrivateAdminAPI(stack).AddRPCKey("a", nil); err != ErrRPCAuthOff {
+		t.Errorf("key added without authentication: %v", err)
+	}
+	config := testNodeConfig()
+	config.RPCKeys = []rpc.APIKey{{Name: "configured", Key: "0123456789abcdef"}}
+	if stack, err = New(config); err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	api := NewPrivateAdminAPI(stack)
+
+	secret, err := api.AddRPCKey("added", []string{"eth", "net"})
+	if err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+	if len(secret) != 64 {
+		t.Errorf("secret length mismatch: have %d, want 64", len(secret))
+	}
+	if _, err := api.AddRPCKey("added", nil); err == nil {
+		t.Error("duplicate key added")
+	}
+	want := []rpc.APIKey{{Name: "added", Namespaces: []string{"eth", "net"}}, {Name: "configured"}}
+	keys, _ := api.RpcKeys()
+	if len(keys) != 2 || !reflect.DeepEqual(keys[0], want[0]) || keys[1].Name != want[1].Name {
+		t.Errorf("keys mismatch: have %+v, want %+v", keys, want)
+	}
+	if ok, _ := api.RemoveRPCKey("configured"); !ok {
+		t.Error("failed to remove key")
+	}
+	if keys, _ := api.RpcKeys(); len(keys) != 1 {
+		t.Errorf("removed key still listed: %+v", keys)
+	}
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("eth/downloader/throttle", registry).Inc(3)
+	metrics.NewRegisteredGauge("txpool/pending", registry).Update(7)
+	metrics.NewRegisteredMeter("txpool/underpriced/discard", registry).Mark(2)
+	metrics.NewRegisteredMeter("p2p/InboundTraffic", registry).Mark(100)
+
+	values := snapshotMetrics(registry, []string{"eth/downloader/", "txpool/*/discard"})
+	if len(values) != 2 {
+		t.Fatalf("filtered metrics mismatch: have %v", values)
+	}
+	if values["eth/downloader/throttle"] != int64(3) {
+		t.Errorf("counter mismatch: have %v, want 3", values["eth/downloader/throttle"])
+	}
+	if meter, ok := values["txpool/underpriced/discard"].(map[string]interface{}); !ok || meter["count"] != int64(2) {
+		t.Errorf("meter mismatch: have %v", values["txpool/underpriced/discard"])
+	}
+	if values := snapshotMetrics(registry, nil); len(values) != 4 {
+		t.Errorf("unfiltered metric count mismatch: have %d, want 4", len(values))
+	}
+}
+
+func TestMetricsSnapshotLabels(t *testing.T) {
+	enabled := indigometrics.Enabled
+	indigometrics.Enabled = true
+	defer func() { indigometrics.Enabled = enabled }()
+
+	indigometrics.DB("snapshota").Counter("user/writes").Inc(5)
+	indigometrics.DB("snapshotb").Counter("user/writes").Inc(9)
+	indigometrics.DB("snapshotb").Sub("archive", nil).Gauge("queue/size").Update(2)
+
+	samples := sampleMetrics(metrics.DefaultRegistry, &MetricsFilter{
+		Names:  []string{"db/"},
+		Labels: map[string]string{indigometrics.LabelDB: "snapshotb"},
+	})
+	if len(samples) != 2 {
+		t.Fatalf("filtered sample count mismatch: have %d, want 2", len(samples))
+	}
+	if samples[0].Name != "db/snapshotb/archive/queue/size" || samples[0].Value != int64(2) {
+		t.Errorf("gauge sample mismatch: have %s = %v", samples[0].Name, samples[0].Value)
+	}
+	if samples[1].Name != "db/snapshotb/user/writes" || samples[1].Value != int64(9) {
+		t.Errorf("counter sample mismatch: have %s = %v", samples[1].Name, samples[1].Value)
+	}
+	for _, sample := range samples {
+		if sample.Labels[indigometrics.LabelDB] != "snapshotb" {
+			t.Errorf("%s: label mismatch: have %v", sample.Name, sample.Labels)
+		}
+	}
+}
+
+func TestMetricsSubscription(t *testing.T) {
+	enabled := indigometrics.Enabled
+	indigometrics.Enabled = true
+	defer func() { indigometrics.Enabled = enabled }()
+
+	registry := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("txpool/pending", registry).Update(7)
+	metrics.NewRegisteredGauge("txpool/queued", registry).Update(1)
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("debug", &PublicDebugMetricsAPI{registry: registry}); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	updates := make(chan MetricsUpdate)
+	sub, err := client.Subscribe(context.Background(), "debug", updates, "metrics", []string{"txpool/pending"}, 100)
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	select {
+	case update := <-updates:
+		if len(update.Metrics) != 1 || update.Metrics["txpool/pending"] != float64(7) {
+			t.Errorf("update mismatch: have %v", update.Metrics)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no metrics update received")
+	}
+}
@@ -18,6 +18,7 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -26,13 +27,17 @@ import (
 	"strings"
 
 	"github.com/fulcrumchain/indigo/accounts"
+	"github.com/fulcrumchain/indigo/accounts/external"
 	"github.com/fulcrumchain/indigo/accounts/keystore"
 	"github.com/fulcrumchain/indigo/accounts/usbwallet"
 	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/common/hexutil"
 	"github.com/fulcrumchain/indigo/crypto"
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/p2p"
 	"github.com/fulcrumchain/indigo/p2p/discover"
+	"github.com/fulcrumchain/indigo/p2p/netutil"
+	"github.com/fulcrumchain/indigo/rpc"
 )
 
 const (
@@ -41,6 +46,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirFirewall        = "firewall.json"      // Path within the datadir to the persisted firewall rules
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -82,9 +88,18 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreKDF selects the key derivation function and parameters used to
+	// encrypt new key files. Unset parameters take the standard values, or the
+	// light ones if UseLightweightKDF is set.
+	KeyStoreKDF keystore.KDFConfig
+
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// ExternalSigner is the endpoint (URL or IPC path) of an external signer
+	// daemon to delegate signing to, so keys don't have to live in the keystore.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -148,6 +163,25 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSCompression enables permessage-deflate compression of the websocket
+	// messages for the clients offering it.
+	WSCompression bool `toml:",omitempty"`
+
+	// WSMaxFrameSize and WSMaxMessageSize limit the size of the frames and the
+	// (decompressed) messages received over websocket. Zero means the defaults.
+	WSMaxFrameSize   int64 `toml:",omitempty"`
+	WSMaxMessageSize int64 `toml:",omitempty"`
+
+	// RPCKeys are the API keys accepted by the HTTP and websocket RPC servers. Once
+	// any key or a JWT secret is configured, requests without valid credentials
+	// are rejected. Keys listing namespaces may access all the APIs of exactly
+	// those namespaces, other keys the modules exposed by the endpoint.
+	RPCKeys []rpc.APIKey `toml:",omitempty"`
+
+	// RPCJWTSecret is the hex encoded secret verifying the HMAC SHA-256 signed
+	// JSON Web Tokens accepted as credentials by the HTTP and websocket RPC servers.
+	RPCJWTSecret string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -177,6 +211,22 @@ func (c *Config) IPCEndpoint() string {
 	return c.IPCPath
 }
 
+// RPCAuth creates the authenticator of the HTTP and websocket RPC requests, or
+// nil if no credentials are configured.
+func (c *Config) RPCAuth() (*rpc.Auth, error) {
+	if len(c.RPCKeys) == 0 && c.RPCJWTSecret == "" {
+		return nil, nil
+	}
+	var secret []byte
+	if c.RPCJWTSecret != "" {
+		var err error
+		if secret, err = hexutil.Decode(c.RPCJWTSecret); err != nil {
+			return nil, fmt.Errorf("invalid RPC JWT secret: %v", err)
+		}
+	}
+	return rpc.NewAuth(c.RPCKeys, secret)
+}
+
 // NodeDB returns the path to the discovery node database.
 func (c *Config) NodeDB() string {
 	if c.DataDir == "" {
@@ -375,14 +425,74 @@ func (c *Config) parsePersistentNodes(path string) []*discover.Node {
 	return nodes
 }
 
-// AccountConfig determines the settings for scrypt and keydirectory
-func (c *Config) AccountConfig() (int, int, string, error) {
-	scryptN := keystore.StandardScryptN
-	scryptP := keystore.StandardScryptP
+// FirewallRules returns the connection rules persisted through the admin API,
+// or nil if none have been saved.
+func (c *Config) FirewallRules() *netutil.ConnPolicy {
+	if c.DataDir == "" {
+		return nil
+	}
+	path := c.resolvePath(datadirFirewall)
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	rules := new(netutil.ConnPolicy)
+	if err := common.LoadJSON(path, rules); err != nil {
+		log.Error(fmt.Sprintf("Can't load firewall file %s: %v", path, err))
+		return nil
+	}
+	return rules
+}
+
+// saveFirewallRules persists the connection rules so they survive restarts.
+// Without a data directory the rules are only kept in memory.
+func (c *Config) saveFirewallRules(rules *netutil.ConnPolicy) error {
+	if c.DataDir == "" {
+		return nil
+	}
+	blob, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := c.resolvePath(datadirFirewall)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, blob, 0600)
+}
+
+// KDFConfig determines the key derivation settings of new key files.
+func (c *Config) KDFConfig() keystore.KDFConfig {
+	kdf := c.KeyStoreKDF
+	if kdf.KDF == "" {
+		kdf.KDF = keystore.KDFScrypt
+	}
 	if c.UseLightweightKDF {
-		scryptN = keystore.LightScryptN
-		scryptP = keystore.LightScryptP
+		if kdf.ScryptN == 0 {
+			kdf.ScryptN = keystore.LightScryptN
+		}
+		if kdf.ScryptP == 0 {
+			kdf.ScryptP = keystore.LightScryptP
+		}
+		if kdf.Argon2Time == 0 {
+			kdf.Argon2Time = keystore.LightArgon2Time
+		}
+		if kdf.Argon2Memory == 0 {
+			kdf.Argon2Memory = keystore.LightArgon2Memory
+		}
 	}
+	if kdf.ScryptN == 0 {
+		kdf.ScryptN = keystore.StandardScryptN
+	}
+	if kdf.ScryptP == 0 {
+		kdf.ScryptP = keystore.StandardScryptP
+	}
+	return kdf
+}
+
+// AccountConfig determines the settings for scrypt and keydirectory
+func (c *Config) AccountConfig() (int, int, string, error) {
+	kdf := c.KDFConfig()
+	scryptN, scryptP := kdf.ScryptN, kdf.ScryptP
 
 	var (
 		keydir string
@@ -404,7 +514,7 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 }
 
 func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
-	scryptN, scryptP, keydir, err := conf.AccountConfig()
+	_, _, keydir, err := conf.AccountConfig()
 	var ephemeral string
 	if keydir == "" {
 		// There is no datadir.
@@ -418,9 +528,20 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	if err := os.MkdirAll(keydir, 0700); err != nil {
 		return nil, "", err
 	}
+	kdf := conf.KDFConfig()
+	if err := kdf.Validate(); err != nil {
+		return nil, "", err
+	}
 	// Assemble the account manager and supported backends
 	backends := []accounts.Backend{
-		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		keystore.NewKeyStoreWithKDF(keydir, kdf),
+	}
+	if conf.ExternalSigner != "" {
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to connect to external signer: %v", err)
+		}
+		backends = append(backends, extapi)
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
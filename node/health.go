@@ -0,0 +1,106 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+const (
+	healthPath = "/health" // Liveness: whether the node works at all
+	readyPath  = "/ready"  // Readiness: whether the node is fit to serve requests
+)
+
+// HealthCheck is the verdict of a service on an aspect of the node's health.
+// A node failing a liveness check should be restarted, while one failing a
+// readiness check only should be kept out of rotation until it recovers.
+type HealthCheck struct {
+	Name   string      `json:"name"`
+	Live   bool        `json:"live"`
+	Ready  bool        `json:"ready"`
+	Reason string      `json:"reason,omitempty"` // Why the check failed, if it did
+	Value  interface{} `json:"value,omitempty"`  // Measure the verdict is based on
+}
+
+// HealthChecker is implemented by the services reporting on the health of the
+// node, served over the /health and /ready HTTP endpoints.
+type HealthChecker interface {
+	HealthChecks() []HealthCheck
+}
+
+// HealthReport is the response of the health endpoints.
+type HealthReport struct {
+	Status string        `json:"status"` // "ok" or "fail"
+	Checks []HealthCheck `json:"checks"`
+}
+
+// healthHandler serves the liveness and readiness of the node on their paths,
+// passing the other requests on to the RPC handler. Failing reports are served
+// with status 503, as expected by probes.
+type healthHandler struct {
+	node *Node
+	next http.Handler
+}
+
+func (h *healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || (r.URL.Path != healthPath && r.URL.Path != readyPath) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	report := h.node.health(r.URL.Path == readyPath)
+
+	w.Header().Set("content-type", "application/json")
+	w.Header().Set("cache-control", "no-cache")
+	if report.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// health collects the checks of the running services. The report fails if a
+// liveness check does, or, for readiness, if any check does.
+func (n *Node) health(readiness bool) *HealthReport {
+	n.lock.RLock()
+	running := n.server != nil
+	var checkers []HealthChecker
+	for _, service := range n.services {
+		if checker, ok := service.(HealthChecker); ok {
+			checkers = append(checkers, checker)
+		}
+	}
+	n.lock.RUnlock()
+
+	report := &HealthReport{Status: "ok", Checks: []HealthCheck{}}
+	if !running {
+		report.Status = "fail"
+		report.Checks = append(report.Checks, HealthCheck{Name: "node", Reason: "not running"})
+		return report
+	}
+	for _, checker := range checkers {
+		report.Checks = append(report.Checks, checker.HealthChecks()...)
+	}
+	sort.SliceStable(report.Checks, func(i, j int) bool { return report.Checks[i].Name < report.Checks[j].Name })
+
+	for _, check := range report.Checks {
+		if !check.Live || (readiness && !check.Ready) {
+			report.Status = "fail"
+		}
+	}
+	return report
+}
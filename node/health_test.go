@@ -0,0 +1,83 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// healthService reports a fixed set of health checks.
+type healthService struct {
+	NoopService
+	checks []HealthCheck
+}
+
+func (s *healthService) HealthChecks() []HealthCheck { return s.checks }
+
+// Tests that the health endpoints report the checks of the services, failing
+// readiness on any failed check but liveness only on failed liveness checks.
+func TestHealthEndpoints(t *testing.T) {
+	stack, err := New(testNodeConfig())
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	service := &healthService{checks: []HealthCheck{
+		{Name: "peers", Live: true, Ready: false, Reason: "no peers"},
+		{Name: "database", Live: true, Ready: true},
+	}}
+	if err := stack.Register(func(*ServiceContext) (Service, error) { return service, nil }); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	handler := &healthHandler{node: stack, next: http.NotFoundHandler()}
+
+	query := func(path string) (int, *HealthReport) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		report := new(HealthReport)
+		if err := json.Unmarshal(rec.Body.Bytes(), report); err != nil {
+			t.Fatalf("%s: invalid report %q: %v", path, rec.Body.String(), err)
+		}
+		return rec.Code, report
+	}
+	if code, report := query("/health"); code != http.StatusServiceUnavailable || report.Status != "fail" {
+		t.Errorf("stopped node reported live: %d %+v", code, report)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start protocol stack: %v", err)
+	}
+	defer stack.Stop()
+
+	code, report := query("/health")
+	if code != http.StatusOK || report.Status != "ok" || len(report.Checks) != 2 || report.Checks[0].Name != "database" {
+		t.Errorf("liveness mismatch: %d %+v", code, report)
+	}
+	if code, report := query("/ready"); code != http.StatusServiceUnavailable || report.Status != "fail" {
+		t.Errorf("readiness mismatch: %d %+v", code, report)
+	}
+	service.checks[1].Live = false
+	if code, _ := query("/health"); code != http.StatusServiceUnavailable {
+		t.Errorf("failed liveness check not reported: %d", code)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("other path not passed on: %d", rec.Code)
+	}
+}
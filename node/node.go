@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -58,14 +59,18 @@ type Node struct {
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
 	ipcHandler  *rpc.Server  // IPC RPC request handler to process the API requests
 
+	rpcAuth *rpc.Auth // Authenticator of the HTTP and websocket RPC requests (nil = open access)
+
 	httpEndpoint  string       // HTTP endpoint (interface + port) to listen at (empty = HTTP disabled)
 	httpWhitelist []string     // HTTP RPC modules to allow through this endpoint
 	httpListener  net.Listener // HTTP RPC listener socket to server API requests
 	httpHandler   *rpc.Server  // HTTP RPC request handler to process the API requests
+	httpScoped    *rpc.Server  // HTTP RPC request handler to process the API requests restricted to namespaces
 
 	wsEndpoint string       // Websocket endpoint (interface + port) to listen at (empty = websocket disabled)
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
+	wsScoped   *rpc.Server  // Websocket RPC request handler to process the API requests restricted to namespaces
 
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
@@ -108,6 +113,10 @@ func New(conf *Config) (*Node, error) {
 	if conf.Logger == nil {
 		conf.Logger = log.New()
 	}
+	auth, err := conf.RPCAuth()
+	if err != nil {
+		return nil, err
+	}
 	// Note: any interaction with Config that would create/touch files
 	// in the data directory or instance directory is delayed until Start.
 	return &Node{
@@ -118,6 +127,7 @@ func New(conf *Config) (*Node, error) {
 		ipcEndpoint:       conf.IPCEndpoint(),
 		httpEndpoint:      conf.HTTPEndpoint(),
 		wsEndpoint:        conf.WSEndpoint(),
+		rpcAuth:           auth,
 		eventmux:          new(event.TypeMux),
 		log:               conf.Logger,
 		tracing:           conf.HTTPTracing,
@@ -162,6 +172,14 @@ func (n *Node) Start() error {
 	if n.serverConfig.TrustedNodes == nil {
 		n.serverConfig.TrustedNodes = n.config.TrustedNodes()
 	}
+	if rules := n.config.FirewallRules(); rules != nil {
+		// Rules changed through the admin API persist on top of the configured
+		// ones, which must still be enforced if they were added since
+		if rules.Merge(n.serverConfig.Firewall) {
+			n.log.Warn("Configured firewall rules missing from the persisted ones, enforcing both", "file", n.config.resolvePath(datadirFirewall))
+		}
+		n.serverConfig.Firewall = rules
+	}
 	if n.serverConfig.NodeDatabase == "" {
 		n.serverConfig.NodeDatabase = n.config.NodeDB()
 	}
@@ -394,6 +412,21 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 			n.log.Debug("HTTP registered", "service", api.Service, "namespace", api.Namespace)
 		}
 	}
+	// Requests restricted to namespaces may access any of their APIs
+	var (
+		httpHandler http.Handler = handler
+		scoped      *rpc.Server
+	)
+	if n.rpcAuth != nil {
+		var err error
+		if scoped, err = newScopedRPCServer(apis); err != nil {
+			return err
+		}
+		httpHandler = rpc.NewAuthHandler(n.rpcAuth, handler, scoped)
+	}
+	// Health probes are served without authentication
+	httpHandler = &healthHandler{node: n, next: httpHandler}
+
 	// All APIs registered, start the HTTP listener
 	var (
 		listener net.Listener
@@ -403,12 +436,13 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 		return err
 	}
 
-	go rpc.NewHTTPServer(cors, vhosts, handler, tracing).Serve(listener)
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	go rpc.NewHTTPServer(cors, vhosts, httpHandler, tracing).Serve(listener)
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "auth", n.rpcAuth != nil)
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
 	n.httpHandler = handler
+	n.httpScoped = scoped
 
 	return nil
 }
@@ -427,6 +461,10 @@ func (n *Node) stopHTTP() {
 		n.httpHandler.Stop()
 		n.httpHandler = nil
 	}
+	if n.httpScoped != nil {
+		n.httpScoped.Stop()
+		n.httpScoped = nil
+	}
 }
 
 // startWS initializes and starts the websocket RPC endpoint.
@@ -450,6 +488,23 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 			n.log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
 		}
 	}
+	// Requests restricted to namespaces may access any of their APIs
+	opts := rpc.WebsocketOptions{
+		Compression:    n.config.WSCompression,
+		MaxFrameSize:   n.config.WSMaxFrameSize,
+		MaxMessageSize: n.config.WSMaxMessageSize,
+	}
+	var (
+		wsHandler = handler.WebsocketHandlerWithOptions(wsOrigins, opts)
+		scoped    *rpc.Server
+	)
+	if n.rpcAuth != nil {
+		var err error
+		if scoped, err = newScopedRPCServer(apis); err != nil {
+			return err
+		}
+		wsHandler = rpc.NewAuthHandler(n.rpcAuth, wsHandler, scoped.WebsocketHandlerWithOptions(wsOrigins, opts))
+	}
 	// All APIs registered, start the HTTP listener
 	var (
 		listener net.Listener
@@ -458,13 +513,14 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	go (&http.Server{Handler: wsHandler}).Serve(listener)
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()), "auth", n.rpcAuth != nil)
 
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
 	n.wsListener = listener
 	n.wsHandler = handler
+	n.wsScoped = scoped
 
 	return nil
 }
@@ -483,6 +539,22 @@ func (n *Node) stopWS() {
 		n.wsHandler.Stop()
 		n.wsHandler = nil
 	}
+	if n.wsScoped != nil {
+		n.wsScoped.Stop()
+		n.wsScoped = nil
+	}
+}
+
+// newScopedRPCServer creates an RPC request handler registering all the APIs,
+// to serve the requests whose credentials restrict them to namespaces.
+func newScopedRPCServer(apis []rpc.API) (*rpc.Server, error) {
+	handler := rpc.NewServer()
+	for _, api := range apis {
+		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+			return nil, err
+		}
+	}
+	return handler, nil
 }
 
 // Stop terminates a running node along with all it's services. In the node was
@@ -688,6 +760,11 @@ func (n *Node) apis() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicDebugAPI(n),
 			Public:    true,
+		}, {
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPublicDebugMetricsAPI(),
+			Public:    true,
 		}, {
 			Namespace: "web3",
 			Version:   "1.0",
@@ -0,0 +1,120 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// ClientBan is a pattern of client names, as advertised by peers in the
+// protocol handshake, which the server refuses to connect to.
+type ClientBan struct {
+	Pattern  string `json:"pattern"`  // Regular expression matched against the client name
+	Rejected uint64 `json:"rejected"` // Number of handshakes rejected by the pattern
+}
+
+// clientBan is a compiled client name pattern with its rejection counter.
+type clientBan struct {
+	pattern  string
+	re       *regexp.Regexp
+	rejected uint64 // accessed atomically
+}
+
+// clientFilter screens peers by the client name they advertise, in order to
+// quarantine client versions with known consensus bugs.
+type clientFilter struct {
+	lock sync.RWMutex
+	bans []*clientBan
+}
+
+// add compiles a pattern and adds it to the filter. Adding a pattern twice is
+// a no-op.
+func (f *clientFilter) add(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid client pattern %q: %v", pattern, err)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, ban := range f.bans {
+		if ban.pattern == pattern {
+			return nil
+		}
+	}
+	f.bans = append(f.bans, &clientBan{pattern: pattern, re: re})
+	return nil
+}
+
+// remove drops a pattern from the filter, reporting whether it was present.
+func (f *clientFilter) remove(pattern string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for i, ban := range f.bans {
+		if ban.pattern == pattern {
+			f.bans = append(f.bans[:i], f.bans[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether a client name is banned, without counting it as a
+// rejection.
+func (f *clientFilter) match(name string) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	for _, ban := range f.bans {
+		if ban.re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// reject reports whether a client name is banned, counting the rejection
+// against the first matching pattern.
+func (f *clientFilter) reject(name string) bool {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	for _, ban := range f.bans {
+		if ban.re.MatchString(name) {
+			atomic.AddUint64(&ban.rejected, 1)
+			clientRejectMeter.Mark(1)
+			return true
+		}
+	}
+	return false
+}
+
+// list returns the patterns of the filter with their rejection counts.
+func (f *clientFilter) list() []ClientBan {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	bans := make([]ClientBan, len(f.bans))
+	for i, ban := range f.bans {
+		bans[i] = ClientBan{Pattern: ban.pattern, Rejected: atomic.LoadUint64(&ban.rejected)}
+	}
+	return bans
+}
@@ -73,6 +73,7 @@ type dialstate struct {
 	maxDynDials int
 	ntab        discoverTable
 	netrestrict *netutil.Netlist
+	firewall    func(net.IP) bool // reports whether dialing an IP is allowed
 
 	lookupRunning bool
 	dialing       map[discover.NodeID]connFlag
@@ -267,6 +268,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNotWhitelisted   = errors.New("not contained in netrestrict whitelist")
+	errFirewalled       = errors.New("denied by firewall rules")
 )
 
 func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer) error {
@@ -280,6 +282,8 @@ func (s *dialstate) checkDial(n *discover.Node, peers map[discover.NodeID]*Peer)
 		return errSelf
 	case s.netrestrict != nil && !s.netrestrict.Contains(n.IP):
 		return errNotWhitelisted
+	case s.firewall != nil && !s.firewall(n.IP):
+		return errFirewalled
 	case s.hist.contains(n.ID):
 		return errRecentlyDialed
 	}
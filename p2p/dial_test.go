@@ -432,6 +432,33 @@ func TestDialStateNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that candidates denied by the firewall rules are not dialed.
+func TestDialStateFirewall(t *testing.T) {
+	table := fakeTable{
+		{ID: uintID(1), IP: net.ParseIP("127.0.0.1")},
+		{ID: uintID(2), IP: net.ParseIP("127.0.0.2")},
+		{ID: uintID(3), IP: net.ParseIP("127.0.2.3")},
+		{ID: uintID(4), IP: net.ParseIP("127.0.2.4")},
+	}
+	rules := new(netutil.ConnPolicy)
+	rules.AddRule(false, true, "127.0.2.0/24")
+	rules.AddRule(false, false, "127.0.2.3/32")
+
+	dialer := newDialState(nil, nil, table, 10, nil)
+	dialer.firewall = rules.AllowOutbound
+	runDialTest(t, dialtest{
+		init: dialer,
+		rounds: []round{
+			{
+				new: []task{
+					&dialTask{flags: dynDialedConn, dest: table[3]},
+					&discoverTask{},
+				},
+			},
+		},
+	})
+}
+
 // This test checks that static dials are launched.
 func TestDialStateStaticDial(t *testing.T) {
 	wantStatic := []*discover.Node{
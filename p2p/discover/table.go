@@ -101,6 +101,7 @@ type transport interface {
 	ping(NodeID, *net.UDPAddr) error
 	waitping(NodeID) error
 	findnode(toid NodeID, addr *net.UDPAddr, target NodeID) ([]*Node, error)
+	beacon(addr *net.UDPAddr, payload []byte) error
 	close()
 }
 
@@ -208,6 +209,29 @@ func (tab *Table) ReadRandomNodes(buf []*Node) (n int) {
 	return i + 1
 }
 
+// SendBeacon announces the given payload to every node of the table, returning
+// the number of nodes it was sent to.
+func (tab *Table) SendBeacon(payload []byte) (int, error) {
+	tab.mutex.Lock()
+	var nodes []*Node
+	for _, b := range &tab.buckets {
+		nodes = append(nodes, b.entries...)
+	}
+	tab.mutex.Unlock()
+
+	sent := 0
+	for _, n := range nodes {
+		if err := tab.net.beacon(n.addr(), payload); err != nil {
+			if err == errBeaconTooLarge {
+				return sent, err
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
 // Close terminates the network listener and flushes the node database.
 func (tab *Table) Close() {
 	select {
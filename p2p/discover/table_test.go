@@ -206,6 +206,9 @@ func newPingRecorder() *pingRecorder {
 func (t *pingRecorder) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node, error) {
 	return nil, nil
 }
+func (t *pingRecorder) beacon(toaddr *net.UDPAddr, payload []byte) error {
+	return nil
+}
 func (t *pingRecorder) close() {}
 func (t *pingRecorder) waitping(from NodeID) error {
 	return nil // remote always pings
@@ -577,6 +580,7 @@ func (tn *preminedTestnet) findnode(toid NodeID, toaddr *net.UDPAddr, target Nod
 }
 
 func (*preminedTestnet) close()                                      {}
+func (*preminedTestnet) beacon(*net.UDPAddr, []byte) error           { return nil }
 func (*preminedTestnet) waitping(from NodeID) error                  { return nil }
 func (*preminedTestnet) ping(toid NodeID, toaddr *net.UDPAddr) error { return nil }
 
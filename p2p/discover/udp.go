@@ -44,6 +44,7 @@ var (
 	errTimeout          = errors.New("RPC timeout")
 	errClockWarp        = errors.New("reply deadline too far in the future")
 	errClosed           = errors.New("socket closed")
+	errBeaconTooLarge   = errors.New("beacon payload too large")
 )
 
 // Timeouts
@@ -63,6 +64,7 @@ const (
 	pongPacket
 	findnodePacket
 	neighborsPacket
+	beaconPacket
 )
 
 // RPC request structures
@@ -104,6 +106,14 @@ type (
 		Rest []rlp.RawValue `rlp:"tail"`
 	}
 
+	// beacon is an unsolicited status announcement to bonded nodes.
+	beacon struct {
+		Payload    []byte
+		Expiration uint64
+		// Ignore additional fields (for forward compatibility).
+		Rest []rlp.RawValue `rlp:"tail"`
+	}
+
 	rpcNode struct {
 		IP  net.IP // len 4 for IPv4 or 16 for IPv6
 		UDP uint16 // for discovery protocol
@@ -170,6 +180,8 @@ type udp struct {
 	closing chan struct{}
 	nat     nat.Interface
 
+	beacons chan<- Beacon // Received beacons, nil to ignore them
+
 	*Table
 }
 
@@ -210,6 +222,14 @@ type reply struct {
 	matched chan<- bool
 }
 
+// Beacon is a status announcement received from a bonded node. Its payload is
+// opaque to the discovery protocol.
+type Beacon struct {
+	From    NodeID
+	Addr    *net.UDPAddr
+	Payload []byte
+}
+
 // ReadPacket is sent to the unhandled channel when it could not be processed
 type ReadPacket struct {
 	Data []byte
@@ -227,6 +247,7 @@ type Config struct {
 	NetRestrict  *netutil.Netlist  // network whitelist
 	Bootnodes    []*Node           // list of bootstrap nodes
 	Unhandled    chan<- ReadPacket // unhandled packets are sent on this channel
+	Beacons      chan<- Beacon     // beacons of bonded nodes are sent on this channel, dropped if full
 }
 
 // ListenUDP returns a new table that listens for UDP packets on laddr.
@@ -247,6 +268,7 @@ func newUDP(c conn, cfg Config) (*Table, *udp, error) {
 		closing:     make(chan struct{}),
 		gotreply:    make(chan reply),
 		addpending:  make(chan *pending),
+		beacons:     cfg.Beacons,
 	}
 	realaddr := c.LocalAddr().(*net.UDPAddr)
 	if cfg.AnnounceAddr != nil {
@@ -323,6 +345,18 @@ func (t *udp) findnode(toid NodeID, toaddr *net.UDPAddr, target NodeID) ([]*Node
 	return nodes, err
 }
 
+// beacon sends a status announcement to the given node.
+func (t *udp) beacon(toaddr *net.UDPAddr, payload []byte) error {
+	if len(payload) > maxBeaconPayload {
+		return errBeaconTooLarge
+	}
+	_, err := t.send(toaddr, beaconPacket, &beacon{
+		Payload:    payload,
+		Expiration: uint64(time.Now().Add(expiration).Unix()),
+	})
+	return err
+}
+
 // pending adds a reply callback to the pending reply queue.
 // see the documentation of type pending for a detailed explanation.
 func (t *udp) pending(id NodeID, ptype byte, callback func(interface{}) bool) <-chan error {
@@ -454,6 +488,9 @@ var (
 	// stay below the 1280 byte limit. We compute the maximum number
 	// of entries by stuffing a packet until it grows too large.
 	maxNeighbors int
+
+	// Beacon payloads must fit a single packet along with the envelope.
+	maxBeaconPayload int
 )
 
 func init() {
@@ -471,6 +508,10 @@ func init() {
 			break
 		}
 	}
+	b := beacon{Expiration: ^uint64(0)}
+	size, _, _ := rlp.EncodeToReader(b)
+	// The length prefixes of the payload and the list grow by 2 bytes each
+	maxBeaconPayload = 1280 - headSize - 1 - size - 4
 }
 
 func (t *udp) send(toaddr *net.UDPAddr, ptype byte, req packet) ([]byte, error) {
@@ -578,6 +619,8 @@ func decodePacket(buf []byte) (packet, NodeID, []byte, error) {
 		req = new(findnode)
 	case neighborsPacket:
 		req = new(neighbors)
+	case beaconPacket:
+		req = new(beacon)
 	default:
 		return nil, fromID, hash, fmt.Errorf("unknown type: %d", ptype)
 	}
@@ -667,6 +710,26 @@ func (req *neighbors) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byt
 
 func (req *neighbors) name() string { return "NEIGHBORS/v4" }
 
+func (req *beacon) handle(t *udp, from *net.UDPAddr, fromID NodeID, mac []byte) error {
+	if expired(req.Expiration) {
+		return errExpired
+	}
+	if !t.db.hasBond(fromID) {
+		// Only bonded nodes are listened to, their endpoint having been
+		// verified by a ping-pong round trip.
+		return errUnknownNode
+	}
+	if t.beacons != nil {
+		select {
+		case t.beacons <- Beacon{From: fromID, Addr: from, Payload: req.Payload}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (req *beacon) name() string { return "BEACON/v4" }
+
 func expired(ts uint64) bool {
 	return time.Unix(int64(ts), 0).Before(time.Now())
 }
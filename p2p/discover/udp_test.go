@@ -269,6 +269,41 @@ func TestUDP_findnode(t *testing.T) {
 	waitNeighbors(expected.entries[maxNeighbors:])
 }
 
+func TestUDP_beacon(t *testing.T) {
+	test := newUDPTest(t)
+	defer test.table.Close()
+
+	beacons := make(chan Beacon, 1)
+	test.udp.beacons = beacons
+
+	// beacons of unbonded nodes are ignored.
+	payload := []byte("head")
+	test.packetIn(errUnknownNode, beaconPacket, &beacon{Payload: payload, Expiration: futureExp})
+	test.packetIn(errExpired, beaconPacket, &beacon{Payload: payload})
+
+	test.table.db.updateBondTime(PubkeyID(&test.remotekey.PublicKey), time.Now())
+	test.packetIn(nil, beaconPacket, &beacon{Payload: payload, Expiration: futureExp})
+	select {
+	case b := <-beacons:
+		if b.From != PubkeyID(&test.remotekey.PublicKey) || !bytes.Equal(b.Payload, payload) {
+			t.Errorf("beacon mismatch: got %v", b)
+		}
+	default:
+		t.Fatal("beacon not delivered")
+	}
+	// the largest payload fits a packet, larger ones are refused.
+	packet, _, err := encodePacket(test.localkey, beaconPacket, &beacon{Payload: make([]byte, maxBeaconPayload), Expiration: ^uint64(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packet) > 1280 {
+		t.Errorf("largest beacon packet too large: %d bytes", len(packet))
+	}
+	if err := test.udp.beacon(test.remoteaddr, make([]byte, maxBeaconPayload+1)); err != errBeaconTooLarge {
+		t.Errorf("oversized beacon error mismatch: got %v, want %v", err, errBeaconTooLarge)
+	}
+}
+
 func TestUDP_findnodeMultiReply(t *testing.T) {
 	test := newUDPTest(t)
 	defer test.table.Close()
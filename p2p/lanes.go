@@ -0,0 +1,145 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// Lane is the priority class of an outbound message. When several protocols of
+// a peer wait to write, messages of the lower numbered lanes go first.
+type Lane int
+
+const (
+	LaneConsensus Lane = iota // Block propagation and consensus messages
+	LaneDefault               // Messages of protocols not declaring lanes
+	LaneBulk                  // Sync data and transaction gossip
+
+	numLanes = int(LaneBulk) + 1
+)
+
+func (l Lane) String() string {
+	switch l {
+	case LaneConsensus:
+		return "consensus"
+	case LaneDefault:
+		return "default"
+	case LaneBulk:
+		return "bulk"
+	default:
+		return fmt.Sprintf("lane(%d)", int(l))
+	}
+}
+
+// laneMeter accounts for the writers waiting in a lane.
+type laneMeter struct {
+	queued gometrics.Counter // Writers currently waiting, across all peers
+	wait   gometrics.Timer   // Time spent waiting for the connection
+}
+
+var laneMeters [numLanes]laneMeter
+
+func init() {
+	for i := range laneMeters {
+		name := Lane(i).String()
+		laneMeters[i] = laneMeter{
+			queued: metrics.NewCounter("p2p/lanes/" + name + "/queued"),
+			wait:   metrics.NewTimer("p2p/lanes/" + name + "/wait"),
+		}
+	}
+}
+
+// writeScheduler hands out the right to write to a peer connection, one message
+// at a time. Once a write completes, the right passes to the longest waiting
+// writer of the most important lane, so that block propagation isn't delayed
+// behind the bulk traffic of syncing peers. Lower lanes only progress when the
+// higher ones are idle, which consensus traffic is most of the time.
+type writeScheduler struct {
+	mu      sync.Mutex
+	busy    bool                      // Whether a writer holds the right to write
+	waiting [numLanes][]chan struct{} // Waiting writers of each lane, in arrival order
+}
+
+// acquire waits for the right to write a message in the lane, returning false
+// if closed is closed first.
+func (s *writeScheduler) acquire(lane Lane, closed <-chan struct{}) bool {
+	if lane < 0 || int(lane) >= numLanes {
+		lane = LaneDefault
+	}
+	meter := laneMeters[lane]
+
+	s.mu.Lock()
+	if !s.busy {
+		s.busy = true
+		s.mu.Unlock()
+		meter.wait.Update(0)
+		return true
+	}
+	grant := make(chan struct{}, 1)
+	s.waiting[lane] = append(s.waiting[lane], grant)
+	s.mu.Unlock()
+
+	meter.queued.Inc(1)
+	defer meter.queued.Dec(1)
+
+	start := time.Now()
+	select {
+	case <-grant:
+		meter.wait.UpdateSince(start)
+		return true
+	case <-closed:
+		s.cancel(lane, grant)
+		return false
+	}
+}
+
+// release passes the right to write on to the next waiting writer, if any.
+func (s *writeScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for lane, queue := range s.waiting {
+		if len(queue) > 0 {
+			queue[0] <- struct{}{}
+			queue[0] = nil
+			s.waiting[lane] = queue[1:]
+			return
+		}
+	}
+	s.busy = false
+}
+
+// cancel withdraws a waiting writer, passing the right to write on if it was
+// granted in the meantime.
+func (s *writeScheduler) cancel(lane Lane, grant chan struct{}) {
+	s.mu.Lock()
+	queue := s.waiting[lane]
+	for i := range queue {
+		if queue[i] == grant {
+			s.waiting[lane] = append(queue[:i], queue[i+1:]...)
+			s.mu.Unlock()
+			return
+		}
+	}
+	s.mu.Unlock()
+	s.release()
+}
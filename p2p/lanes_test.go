@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the right to write passes to waiting writers by lane, and in
+// arrival order within a lane.
+func TestWriteSchedulerLanes(t *testing.T) {
+	var (
+		sched  = new(writeScheduler)
+		closed = make(chan struct{})
+		order  = make(chan string, 4)
+	)
+	if !sched.acquire(LaneBulk, closed) {
+		t.Fatal("failed to acquire idle scheduler")
+	}
+	queue := func(name string, lane Lane) {
+		sched.mu.Lock()
+		want := len(sched.waiting[lane]) + 1
+		sched.mu.Unlock()
+
+		go func() {
+			if sched.acquire(lane, closed) {
+				order <- name
+			}
+		}()
+		// Wait for the writer to queue up, keeping the arrival order
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			sched.mu.Lock()
+			n := len(sched.waiting[lane])
+			sched.mu.Unlock()
+			if n == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("writer %s didn't queue up", name)
+			}
+		}
+	}
+	queue("bulk1", LaneBulk)
+	queue("default", LaneDefault)
+	queue("bulk2", LaneBulk)
+	queue("consensus", LaneConsensus)
+
+	for _, want := range []string{"consensus", "default", "bulk1", "bulk2"} {
+		sched.release()
+		select {
+		case name := <-order:
+			if name != want {
+				t.Fatalf("writer mismatch: have %s, want %s", name, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("writer %s not granted", want)
+		}
+	}
+	sched.release()
+	if sched.busy {
+		t.Fatal("scheduler busy without writers")
+	}
+}
+
+// Tests that writers giving up on a closed peer don't hold on to the right to
+// write.
+func TestWriteSchedulerCancel(t *testing.T) {
+	var (
+		sched  = new(writeScheduler)
+		closed = make(chan struct{})
+		done   = make(chan bool)
+	)
+	sched.acquire(LaneDefault, nil)
+	go func() { done <- sched.acquire(LaneBulk, closed) }()
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		sched.mu.Lock()
+		n := len(sched.waiting[LaneBulk])
+		sched.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("writer didn't queue up")
+		}
+	}
+	close(closed)
+	if <-done {
+		t.Fatal("writer granted after close")
+	}
+	if len(sched.waiting[LaneBulk]) != 0 {
+		t.Fatal("cancelled writer still queued")
+	}
+	sched.release()
+	if sched.busy {
+		t.Fatal("scheduler busy without writers")
+	}
+}
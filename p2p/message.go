@@ -50,6 +50,10 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to eth/64
+	GetStateDiagMsg = 0x11
+	StateDiagMsg    = 0x12
 )
 
 func MsgCodeString(code uint64) string {
@@ -80,6 +84,11 @@ func MsgCodeString(code uint64) string {
 	case ReceiptsMsg:
 		return "Receipts"
 
+	case GetStateDiagMsg:
+		return "GetStateDiag"
+	case StateDiagMsg:
+		return "StateDiag"
+
 	default:
 		return fmt.Sprintf("Unrecognized: %x", code)
 	}
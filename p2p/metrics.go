@@ -19,9 +19,14 @@
 package p2p
 
 import (
+	"fmt"
 	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
 
 	"github.com/fulcrumchain/indigo/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
 )
 
 var (
@@ -29,6 +34,7 @@ var (
 	ingressTrafficMeter = metrics.NewMeter("p2p/InboundTraffic")
 	egressConnectMeter  = metrics.NewMeter("p2p/OutboundConnects")
 	egressTrafficMeter  = metrics.NewMeter("p2p/OutboundTraffic")
+	clientRejectMeter   = metrics.NewMeter("p2p/ClientRejects")
 )
 
 // meteredConn is a wrapper around a network TCP connection that meters both the
@@ -69,3 +75,112 @@ func (c *meteredConn) Write(b []byte) (n int, err error) {
 	egressTrafficMeter.Mark(int64(n))
 	return
 }
+
+// labelMsgCode is the label key of the message code of per message metrics.
+const labelMsgCode = "code"
+
+var (
+	msgMeterLock sync.RWMutex
+	msgMeters    = make(map[msgKey]*msgMeter)
+)
+
+// msgKey identifies the messages of a given code of a protocol.
+type msgKey struct {
+	proto string
+	code  uint64
+}
+
+// msgMeter accounts for the traffic of a message type of a protocol. The totals
+// are always kept for debug_p2pStats, the meters and size histograms only if
+// the metrics system is enabled.
+type msgMeter struct {
+	inPackets, inBytes   uint64 // Totals of inbound messages, accessed atomically
+	outPackets, outBytes uint64 // Totals of outbound messages, accessed atomically
+
+	inPacketMeter, inByteMeter   gometrics.Meter
+	outPacketMeter, outByteMeter gometrics.Meter
+	inSizeHist, outSizeHist      gometrics.Histogram
+}
+
+// MsgStats is the traffic of a message type of a protocol since startup.
+type MsgStats struct {
+	Protocol   string `json:"protocol"`
+	Code       uint64 `json:"code"`
+	InPackets  uint64 `json:"inPackets"`
+	InBytes    uint64 `json:"inBytes"`
+	OutPackets uint64 `json:"outPackets"`
+	OutBytes   uint64 `json:"outBytes"`
+}
+
+// getMsgMeter retrieves the meter of a message type, creating it on first use.
+func getMsgMeter(proto string, code uint64) *msgMeter {
+	key := msgKey{proto, code}
+
+	msgMeterLock.RLock()
+	m := msgMeters[key]
+	msgMeterLock.RUnlock()
+	if m != nil {
+		return m
+	}
+	msgMeterLock.Lock()
+	defer msgMeterLock.Unlock()
+
+	if m = msgMeters[key]; m == nil {
+		ns := metrics.NewNamespace("p2p/msg", nil).Sub(proto, metrics.Labels{metrics.LabelProtocol: proto})
+		ns = ns.Sub(fmt.Sprint(code), metrics.Labels{labelMsgCode: fmt.Sprint(code)})
+		m = &msgMeter{
+			inPacketMeter:  ns.Meter("in/packets"),
+			inByteMeter:    ns.Meter("in/bytes"),
+			inSizeHist:     ns.Histogram("in/size"),
+			outPacketMeter: ns.Meter("out/packets"),
+			outByteMeter:   ns.Meter("out/bytes"),
+			outSizeHist:    ns.Histogram("out/size"),
+		}
+		msgMeters[key] = m
+	}
+	return m
+}
+
+// meterMsg accounts for a message of a protocol read from or written to a peer.
+func meterMsg(proto string, code uint64, size uint32, ingress bool) {
+	m := getMsgMeter(proto, code)
+	if ingress {
+		atomic.AddUint64(&m.inPackets, 1)
+		atomic.AddUint64(&m.inBytes, uint64(size))
+		m.inPacketMeter.Mark(1)
+		m.inByteMeter.Mark(int64(size))
+		m.inSizeHist.Update(int64(size))
+	} else {
+		atomic.AddUint64(&m.outPackets, 1)
+		atomic.AddUint64(&m.outBytes, uint64(size))
+		m.outPacketMeter.Mark(1)
+		m.outByteMeter.Mark(int64(size))
+		m.outSizeHist.Update(int64(size))
+	}
+}
+
+// TrafficStats returns the traffic of all message types seen so far, ordered
+// by protocol and message code.
+func TrafficStats() []MsgStats {
+	msgMeterLock.RLock()
+	defer msgMeterLock.RUnlock()
+
+	stats := make([]MsgStats, 0, len(msgMeters))
+	for key, m := range msgMeters {
+		stats = append(stats, MsgStats{
+			Protocol:   key.proto,
+			Code:       key.code,
+			InPackets:  atomic.LoadUint64(&m.inPackets),
+			InBytes:    atomic.LoadUint64(&m.inBytes),
+			OutPackets: atomic.LoadUint64(&m.outPackets),
+			OutBytes:   atomic.LoadUint64(&m.outBytes),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Protocol != stats[j].Protocol {
+			return stats[i].Protocol < stats[j].Protocol
+		}
+		return stats[i].Code < stats[j].Code
+	})
+	return stats
+}
@@ -0,0 +1,170 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"encoding/json"
+	"net"
+)
+
+// ConnPolicy holds the IP rules applied to peer connections, separately for
+// inbound and outbound connections. An address is admitted in a direction if
+// it matches none of the deny rules and either there are no allow rules or it
+// matches one of them.
+type ConnPolicy struct {
+	InboundAllow  Netlist `json:"inboundAllow"`
+	InboundDeny   Netlist `json:"inboundDeny"`
+	OutboundAllow Netlist `json:"outboundAllow"`
+	OutboundDeny  Netlist `json:"outboundDeny"`
+}
+
+// AllowInbound reports whether connections from ip may be accepted.
+func (p *ConnPolicy) AllowInbound(ip net.IP) bool {
+	if p == nil {
+		return true
+	}
+	return admits(p.InboundAllow, p.InboundDeny, ip)
+}
+
+// AllowOutbound reports whether connections to ip may be dialed.
+func (p *ConnPolicy) AllowOutbound(ip net.IP) bool {
+	if p == nil {
+		return true
+	}
+	return admits(p.OutboundAllow, p.OutboundDeny, ip)
+}
+
+func admits(allow, deny Netlist, ip net.IP) bool {
+	if deny.Contains(ip) {
+		return false
+	}
+	return len(allow) == 0 || allow.Contains(ip)
+}
+
+// Copy returns a deep copy of the policy.
+func (p *ConnPolicy) Copy() *ConnPolicy {
+	if p == nil {
+		return new(ConnPolicy)
+	}
+	return &ConnPolicy{
+		InboundAllow:  append(Netlist{}, p.InboundAllow...),
+		InboundDeny:   append(Netlist{}, p.InboundDeny...),
+		OutboundAllow: append(Netlist{}, p.OutboundAllow...),
+		OutboundDeny:  append(Netlist{}, p.OutboundDeny...),
+	}
+}
+
+// AddRule parses a CIDR mask and adds it to the allow or deny list of the
+// given direction. Masks already present are not added twice.
+func (p *ConnPolicy) AddRule(inbound, allow bool, cidr string) error {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return err
+	}
+	list := p.rules(inbound, allow)
+	if list.index(n) < 0 {
+		*list = append(*list, *n)
+	}
+	return nil
+}
+
+// RemoveRule removes a CIDR mask from the allow or deny list of the given
+// direction. It reports whether the mask was present.
+func (p *ConnPolicy) RemoveRule(inbound, allow bool, cidr string) (bool, error) {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return false, err
+	}
+	list := p.rules(inbound, allow)
+	i := list.index(n)
+	if i < 0 {
+		return false, nil
+	}
+	*list = append((*list)[:i], (*list)[i+1:]...)
+	return true, nil
+}
+
+// Merge adds the masks of other missing from the policy, in the same lists. It
+// reports whether any were added.
+func (p *ConnPolicy) Merge(other *ConnPolicy) bool {
+	if other == nil {
+		return false
+	}
+	added := false
+	for _, inbound := range []bool{true, false} {
+		for _, allow := range []bool{true, false} {
+			list := p.rules(inbound, allow)
+			for _, n := range *other.rules(inbound, allow) {
+				if list.index(&n) < 0 {
+					*list = append(*list, n)
+					added = true
+				}
+			}
+		}
+	}
+	return added
+}
+
+func (p *ConnPolicy) rules(inbound, allow bool) *Netlist {
+	switch {
+	case inbound && allow:
+		return &p.InboundAllow
+	case inbound:
+		return &p.InboundDeny
+	case allow:
+		return &p.OutboundAllow
+	default:
+		return &p.OutboundDeny
+	}
+}
+
+// index returns the position of the network n in the list, or -1.
+func (l Netlist) index(n *net.IPNet) int {
+	for i := range l {
+		if l[i].String() == n.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+// MarshalJSON implements json.Marshaler, encoding the list as CIDR masks.
+func (l Netlist) MarshalJSON() ([]byte, error) {
+	list := make([]string, 0, len(l))
+	for _, n := range l {
+		list = append(list, n.String())
+	}
+	return json.Marshal(list)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *Netlist) UnmarshalJSON(input []byte) error {
+	var masks []string
+	if err := json.Unmarshal(input, &masks); err != nil {
+		return err
+	}
+	list := make(Netlist, 0, len(masks))
+	for _, mask := range masks {
+		_, n, err := net.ParseCIDR(mask)
+		if err != nil {
+			return err
+		}
+		list = append(list, *n)
+	}
+	*l = list
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package netutil
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestConnPolicy(t *testing.T) {
+	var p ConnPolicy
+	for _, rule := range []struct {
+		inbound, allow bool
+		cidr           string
+	}{
+		{true, true, "10.0.0.0/8"},
+		{true, false, "10.1.0.0/16"},
+		{false, false, "192.168.0.0/16"},
+	} {
+		if err := p.AddRule(rule.inbound, rule.allow, rule.cidr); err != nil {
+			t.Fatalf("AddRule(%s) failed: %v", rule.cidr, err)
+		}
+	}
+	tests := []struct {
+		ip                string
+		inbound, outbound bool
+	}{
+		{"10.0.0.1", true, true},
+		{"10.1.0.1", false, true},
+		{"172.16.0.1", false, true},
+		{"192.168.1.1", false, false},
+	}
+	for _, test := range tests {
+		ip := net.ParseIP(test.ip)
+		if ok := p.AllowInbound(ip); ok != test.inbound {
+			t.Errorf("AllowInbound(%s) = %t, want %t", test.ip, ok, test.inbound)
+		}
+		if ok := p.AllowOutbound(ip); ok != test.outbound {
+			t.Errorf("AllowOutbound(%s) = %t, want %t", test.ip, ok, test.outbound)
+		}
+	}
+	// Adding an existing mask must not duplicate it, removal must report it.
+	p.AddRule(true, true, "10.0.0.0/8")
+	if len(p.InboundAllow) != 1 {
+		t.Errorf("duplicate rule added: %v", p.InboundAllow)
+	}
+	if ok, _ := p.RemoveRule(true, false, "10.1.0.0/16"); !ok {
+		t.Errorf("existing rule not removed")
+	}
+	if ok, _ := p.RemoveRule(true, false, "10.1.0.0/16"); ok {
+		t.Errorf("missing rule reported as removed")
+	}
+	if !p.AllowInbound(net.ParseIP("10.1.0.1")) {
+		t.Errorf("removed deny rule still enforced")
+	}
+	if _, err := p.RemoveRule(true, true, "10.0.0.0"); err == nil {
+		t.Errorf("expected error for invalid mask")
+	}
+}
+
+func TestConnPolicyMerge(t *testing.T) {
+	var p, other ConnPolicy
+	p.AddRule(true, true, "10.0.0.0/8")
+	other.AddRule(true, true, "10.0.0.0/8")
+	other.AddRule(false, false, "192.168.0.0/16")
+
+	if !p.Merge(&other) {
+		t.Fatalf("missing rules not reported as added")
+	}
+	if len(p.InboundAllow) != 1 || len(p.OutboundDeny) != 1 {
+		t.Errorf("merged rules mismatch: %v", p)
+	}
+	if p.Merge(&other) || p.Merge(nil) {
+		t.Errorf("present rules reported as added")
+	}
+}
+
+func TestNilConnPolicy(t *testing.T) {
+	var p *ConnPolicy
+	if !p.AllowInbound(net.ParseIP("1.2.3.4")) || !p.AllowOutbound(net.ParseIP("1.2.3.4")) {
+		t.Errorf("nil policy denies connections")
+	}
+}
+
+func TestConnPolicyJSON(t *testing.T) {
+	var p ConnPolicy
+	p.AddRule(true, true, "10.0.0.0/8")
+	p.AddRule(false, false, "fe80::/10")
+
+	blob, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want := `{"inboundAllow":["10.0.0.0/8"],"inboundDeny":[],"outboundAllow":[],"outboundDeny":["fe80::/10"]}`
+	if string(blob) != want {
+		t.Errorf("encoding mismatch:\n  got:  %s\n  want: %s", blob, want)
+	}
+	var dec ConnPolicy
+	if err := json.Unmarshal(blob, &dec); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(dec.Copy(), p.Copy()) {
+		t.Errorf("decoded policy mismatch:\n  got:  %v\n  want: %v", dec, p)
+	}
+}
@@ -55,6 +55,9 @@ const (
 	pongMsg      = 0x03
 	getPeersMsg  = 0x04
 	peersMsg     = 0x05
+	rekeyMsg     = 0x06
+	rekeyAckMsg  = 0x07
+	rekeyDoneMsg = 0x08
 )
 
 var ErrShuttingDown = errors.New("shutting down")
@@ -115,6 +118,11 @@ type Peer struct {
 	closed   chan struct{}
 	disc     chan DiscReason
 
+	// rekey renegotiates the session keys if both ends support it,
+	// every rekeyInterval if the local side dialed the connection.
+	rekey         *sessionRekeyer
+	rekeyInterval time.Duration
+
 	// events receives message send / receive events if set
 	events *event.Feed
 }
@@ -180,7 +188,7 @@ func newPeer(conn *conn, protocols []Protocol) *Peer {
 		running:  protomap,
 		created:  mclock.Now(),
 		disc:     make(chan DiscReason),
-		protoErr: make(chan error, len(protomap)+1), // protocols + pingLoop
+		protoErr: make(chan error, len(protomap)+2), // protocols + pingLoop + rekeyLoop
 		closed:   make(chan struct{}),
 		log:      log.New("id", conn.id, "conn", conn.flags),
 	}
@@ -193,7 +201,7 @@ func (p *Peer) Log() log.Logger {
 
 func (p *Peer) run() (remoteRequested bool, err error) {
 	var (
-		writeStart = make(chan struct{}, 1)
+		writeSched = new(writeScheduler)
 		writeErr   = make(chan error, 1)
 		readErr    = make(chan error, 1)
 		reason     DiscReason // sent to the peer
@@ -201,10 +209,13 @@ func (p *Peer) run() (remoteRequested bool, err error) {
 	p.wg.Add(2)
 	go p.readLoop(readErr)
 	go p.pingLoop()
+	if p.rekey != nil && p.rekeyInterval > 0 {
+		p.wg.Add(1)
+		go p.rekeyLoop()
+	}
 
 	// Start all protocol handlers.
-	writeStart <- struct{}{}
-	p.startProtocols(writeStart, writeErr)
+	p.startProtocols(writeSched, writeErr)
 
 	// Wait for an error or disconnect.
 loop:
@@ -217,7 +228,7 @@ loop:
 				reason = DiscNetworkError
 				break loop
 			}
-			writeStart <- struct{}{}
+			writeSched.release()
 		case err = <-readErr:
 			if r, ok := err.(DiscReason); ok {
 				remoteRequested = true
@@ -259,6 +270,38 @@ func (p *Peer) pingLoop() {
 	}
 }
 
+// enableRekey turns on session rekeying if the transport supports it and the
+// remote node advertised the capability. Only the dialing side initiates
+// renegotiations, so the two ends never race each other.
+func (p *Peer) enableRekey(interval time.Duration) {
+	t, ok := p.rw.transport.(rekeyTransport)
+	if !ok || !hasRekeyCap(p.rw.caps) {
+		return
+	}
+	p.rekey = newSessionRekeyer(t, p.log)
+	if p.rw.flags&inboundConn == 0 {
+		p.rekeyInterval = interval
+	}
+}
+
+func (p *Peer) rekeyLoop() {
+	rekey := time.NewTimer(p.rekeyInterval)
+	defer p.wg.Done()
+	defer rekey.Stop()
+	for {
+		select {
+		case <-rekey.C:
+			if err := p.rekey.request(); err != nil {
+				p.protoErr <- err
+				return
+			}
+			rekey.Reset(p.rekeyInterval)
+		case <-p.closed:
+			return
+		}
+	}
+}
+
 func (p *Peer) readLoop(errc chan<- error) {
 	defer p.wg.Done()
 	for {
@@ -297,6 +340,8 @@ func (p *Peer) handle(msg Msg) error {
 			return fmt.Errorf("failed to decode disc msg: %s", err)
 		}
 		return reason[0]
+	case (msg.Code == rekeyMsg || msg.Code == rekeyAckMsg || msg.Code == rekeyDoneMsg) && p.rekey != nil:
+		return p.rekey.handle(msg)
 	case msg.Code < baseProtocolLength:
 		// ignore other base protocol messages
 		return msg.Discard()
@@ -353,12 +398,12 @@ outer:
 	return result
 }
 
-func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error) {
+func (p *Peer) startProtocols(writeSched *writeScheduler, writeErr chan<- error) {
 	p.wg.Add(len(p.running))
 	for _, proto := range p.running {
 		proto := proto
 		proto.closed = p.closed
-		proto.wstart = writeStart
+		proto.wsched = writeSched
 		proto.werr = writeErr
 		var rw MsgReadWriter = proto
 		if p.events != nil {
@@ -394,7 +439,7 @@ type protoRW struct {
 	Protocol
 	in     chan Msg        // receices read messages
 	closed <-chan struct{} // receives when peer is shutting down
-	wstart <-chan struct{} // receives when write may start
+	wsched *writeScheduler // grants the right to write by lane
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
@@ -407,18 +452,25 @@ func (rw *protoRW) WriteMsg(ctx context.Context, msg Msg) (err error) {
 	if msg.Code >= rw.Length {
 		return newPeerError(errInvalidMsgCode, "not handled")
 	}
+	code := msg.Code
 	msg.Code += rw.offset
-	select {
-	case <-rw.wstart:
-		err = rw.w.WriteMsg(ctx, msg)
-		// Report write status back to Peer.run. It will initiate
-		// shutdown if the error is non-nil and unblock the next write
-		// otherwise. The calling protocol code should exit for errors
-		// as well but we don't want to rely on that.
-		rw.werr <- err
-	case <-rw.closed:
-		err = ErrShuttingDown
+
+	lane := LaneDefault
+	if rw.Lane != nil {
+		lane = rw.Lane(code)
+	}
+	if !rw.wsched.acquire(lane, rw.closed) {
+		return ErrShuttingDown
+	}
+	err = rw.w.WriteMsg(ctx, msg)
+	if err == nil {
+		meterMsg(rw.Name, code, msg.Size, false)
 	}
+	// Report write status back to Peer.run. It will initiate
+	// shutdown if the error is non-nil and unblock the next write
+	// otherwise. The calling protocol code should exit for errors
+	// as well but we don't want to rely on that.
+	rw.werr <- err
 	return err
 }
 
@@ -426,6 +478,7 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	select {
 	case msg := <-rw.in:
 		msg.Code -= rw.offset
+		meterMsg(rw.Name, msg.Code, msg.Size, true)
 		return msg, nil
 	case <-rw.closed:
 		return Msg{}, io.EOF
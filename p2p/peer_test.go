@@ -98,6 +98,46 @@ func TestPeerProtoReadMsg(t *testing.T) {
 	}
 }
 
+func TestPeerTrafficStats(t *testing.T) {
+	proto := Protocol{
+		Name:   "stats",
+		Length: 2,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			for i := 0; i < 2; i++ {
+				if err := ExpectMsg(rw, 1, []uint{1}); err != nil {
+					t.Error(err)
+				}
+			}
+			return SendItems(rw, 0, "foo")
+		},
+	}
+	closer, rw, _, errc := testPeer([]Protocol{proto})
+	defer closer()
+
+	Send(rw, baseProtocolLength+1, []uint{1})
+	Send(rw, baseProtocolLength+1, []uint{1})
+	if err := ExpectMsg(rw, baseProtocolLength, []string{"foo"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-errc:
+	case <-time.After(2 * time.Second):
+		t.Fatal("protocol timeout")
+	}
+	stats := make(map[uint64]MsgStats)
+	for _, s := range TrafficStats() {
+		if s.Protocol == "stats" {
+			stats[s.Code] = s
+		}
+	}
+	if s := stats[1]; s.InPackets != 2 || s.InBytes != 4 || s.OutPackets != 0 {
+		t.Errorf("inbound stats mismatch: %+v", s)
+	}
+	if s := stats[0]; s.OutPackets != 1 || s.OutBytes != 5 || s.InPackets != 0 {
+		t.Errorf("outbound stats mismatch: %+v", s)
+	}
+}
+
 func TestPeerProtoEncodeMsg(t *testing.T) {
 	proto := Protocol{
 		Name:   "a",
@@ -52,6 +52,10 @@ type Protocol struct {
 	// about a certain peer in the network. If an info retrieval function is set,
 	// but returns nil, it is assumed that the protocol handshake is still running.
 	PeerInfo func(id discover.NodeID) interface{}
+
+	// Lane is an optional helper method to classify the outbound messages of the
+	// protocol by priority. Messages of protocols without one use LaneDefault.
+	Lane func(code uint64) Lane
 }
 
 func (p Protocol) cap() Cap {
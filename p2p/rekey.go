@@ -0,0 +1,255 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common/mclock"
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/crypto/ecies"
+	"github.com/fulcrumchain/indigo/crypto/sha3"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/rlp"
+)
+
+const (
+	// DefaultRekeyInterval is the default period after which dialed
+	// connections renegotiate their session keys.
+	DefaultRekeyInterval = time.Hour
+
+	// minRekeyInterval is the shortest period a remote node may leave
+	// between two of its rekey requests.
+	minRekeyInterval = time.Minute
+)
+
+// rekeyCap is advertised in the protocol handshake by nodes which are able
+// to renegotiate the session keys of a live RLPx connection. It does not
+// correspond to a subprotocol and is never matched against Protocols.
+var rekeyCap = Cap{Name: "rekey", Version: 1}
+
+var errRekeyPending = errors.New("session rekey not acknowledged")
+
+// rekeyPacket carries the ephemeral public key of one side of a session key
+// renegotiation.
+type rekeyPacket struct {
+	Pubkey [pubLen]byte
+
+	// Ignore additional fields (forward-compatibility)
+	Rest []rlp.RawValue `rlp:"tail"`
+}
+
+// rekeyTransport is implemented by transports whose session keys can be
+// renegotiated while the connection is live.
+type rekeyTransport interface {
+	MsgWriter
+
+	sessionKeys() secrets
+	setSessionKeys(s secrets)
+	writeRekey(ctx context.Context, msg Msg, next secrets) error
+	readRekey(next secrets)
+}
+
+// sessionRekeyer renegotiates the session keys of a single peer connection.
+// A renegotiation consists of three base protocol messages:
+//
+//	initiator -> rekeyMsg(pubA)
+//	responder -> rekeyAckMsg(pubB), last message under the old egress keys
+//	initiator -> rekeyDoneMsg, last message under the old egress keys
+//
+// Both sides derive the next key generation from the agreement between two
+// fresh ephemeral keys, which are discarded right after. Traffic sent under
+// earlier generations therefore stays confidential even if the node keys or
+// the keys of a later generation are compromised.
+type sessionRekeyer struct {
+	t   rekeyTransport
+	log log.Logger
+
+	lock    sync.Mutex
+	pending *ecies.PrivateKey // our ephemeral key while a request is in flight
+	next    *secrets          // ingress keys waiting for the initiator's done marker
+	lastReq mclock.AbsTime    // time of the last request received
+	rekeys  uint64            // number of completed renegotiations
+}
+
+func newSessionRekeyer(t rekeyTransport, log log.Logger) *sessionRekeyer {
+	return &sessionRekeyer{t: t, log: log}
+}
+
+// request starts a renegotiation. It fails if the previous request has not
+// been acknowledged yet.
+func (r *sessionRekeyer) request() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.pending != nil {
+		return errRekeyPending
+	}
+	key, err := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+	if err != nil {
+		return err
+	}
+	var req rekeyPacket
+	copy(req.Pubkey[:], exportPubkey(&key.PublicKey))
+	if err := Send(r.t, rekeyMsg, &req); err != nil {
+		return err
+	}
+	r.pending = key
+	return nil
+}
+
+// handle processes a rekey message. It must be called from the connection's
+// read loop so the ingress keys switch exactly at the announcing message.
+func (r *sessionRekeyer) handle(msg Msg) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch msg.Code {
+	case rekeyMsg:
+		if r.pending != nil || r.next != nil {
+			return newPeerError(errInvalidMsg, "overlapping rekey request")
+		}
+		now := mclock.Now()
+		if r.lastReq != 0 && time.Duration(now-r.lastReq) < minRekeyInterval {
+			return newPeerError(errInvalidMsg, "rekey requested too frequently")
+		}
+		var req rekeyPacket
+		if err := msg.Decode(&req); err != nil {
+			return err
+		}
+		remote, err := importPublicKey(req.Pubkey[:])
+		if err != nil {
+			return newPeerError(errInvalidMsg, "invalid rekey key: %v", err)
+		}
+		key, err := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+		if err != nil {
+			return err
+		}
+		next, err := rekeySecrets(r.t.sessionKeys(), key, remote, false)
+		if err != nil {
+			return err
+		}
+		var ack rekeyPacket
+		copy(ack.Pubkey[:], exportPubkey(&key.PublicKey))
+		if err := r.send(rekeyAckMsg, &ack, next); err != nil {
+			return err
+		}
+		r.t.setSessionKeys(next)
+		r.next, r.lastReq = &next, now
+		return nil
+
+	case rekeyAckMsg:
+		if r.pending == nil {
+			return newPeerError(errInvalidMsg, "unexpected rekey ack")
+		}
+		var ack rekeyPacket
+		if err := msg.Decode(&ack); err != nil {
+			return err
+		}
+		remote, err := importPublicKey(ack.Pubkey[:])
+		if err != nil {
+			return newPeerError(errInvalidMsg, "invalid rekey key: %v", err)
+		}
+		next, err := rekeySecrets(r.t.sessionKeys(), r.pending, remote, true)
+		if err != nil {
+			return err
+		}
+		// The responder has already switched its egress keys, everything
+		// after the ack arrives under the new generation.
+		r.t.readRekey(next)
+		if err := r.send(rekeyDoneMsg, []interface{}{}, next); err != nil {
+			return err
+		}
+		r.t.setSessionKeys(next)
+		r.pending = nil
+		r.rekeys++
+		r.log.Debug("Renegotiated session keys", "generation", r.rekeys)
+		return nil
+
+	case rekeyDoneMsg:
+		if r.next == nil {
+			return newPeerError(errInvalidMsg, "unexpected rekey done")
+		}
+		if err := msg.Discard(); err != nil {
+			return err
+		}
+		r.t.readRekey(*r.next)
+		r.next = nil
+		r.rekeys++
+		r.log.Debug("Renegotiated session keys", "generation", r.rekeys)
+		return nil
+	}
+	return msg.Discard()
+}
+
+// send writes a rekey message as the last one under the current egress keys
+// and switches the egress direction to next.
+func (r *sessionRekeyer) send(code uint64, data interface{}, next secrets) error {
+	size, payload, err := rlp.EncodeToReader(data)
+	if err != nil {
+		return err
+	}
+	return r.t.writeRekey(context.Background(), Msg{Code: code, Size: uint32(size), Payload: payload}, next)
+}
+
+// rekeySecrets derives the next key generation of a session from the current
+// base secrets and the agreement between the local and remote ephemeral keys.
+// The initiator flag tells whether the local side requested the renegotiation.
+func rekeySecrets(base secrets, prv *ecies.PrivateKey, remote *ecies.PublicKey, initiator bool) (secrets, error) {
+	ecdheSecret, err := prv.GenerateShared(remote, sskLen, sskLen)
+	if err != nil {
+		return secrets{}, err
+	}
+	reqPub, ackPub := exportPubkey(&prv.PublicKey), exportPubkey(remote)
+	if !initiator {
+		reqPub, ackPub = ackPub, reqPub
+	}
+	// chain the new generation to the old one, so that a renegotiation
+	// cannot be injected without knowledge of the current keys.
+	aesSecret := crypto.Keccak256(ecdheSecret, base.AES)
+	s := secrets{
+		RemoteID: base.RemoteID,
+		AES:      aesSecret,
+		MAC:      crypto.Keccak256(ecdheSecret, aesSecret, base.MAC),
+	}
+	mac1 := sha3.NewKeccak256()
+	mac1.Write(xor(s.MAC, crypto.Keccak256(ackPub)))
+	mac1.Write(reqPub)
+	mac2 := sha3.NewKeccak256()
+	mac2.Write(xor(s.MAC, crypto.Keccak256(reqPub)))
+	mac2.Write(ackPub)
+	if initiator {
+		s.EgressMAC, s.IngressMAC = mac1, mac2
+	} else {
+		s.EgressMAC, s.IngressMAC = mac2, mac1
+	}
+	return s, nil
+}
+
+// hasRekeyCap reports whether caps contains the rekey capability.
+func hasRekeyCap(caps []Cap) bool {
+	for _, cap := range caps {
+		if cap == rekeyCap {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/p2p/discover"
+)
+
+// rekeyPipe returns two rlpx transports connected through an in-memory pipe
+// which have completed the encryption handshake.
+func rekeyPipe(t *testing.T) (*rlpx, *rlpx) {
+	var (
+		prv0, _  = crypto.GenerateKey()
+		prv1, _  = crypto.GenerateKey()
+		fd0, fd1 = net.Pipe()
+		c0, c1   = newRLPX(fd0).(*rlpx), newRLPX(fd1).(*rlpx)
+		errc     = make(chan error, 2)
+	)
+	go func() {
+		dest := &discover.Node{ID: discover.PubkeyID(&prv1.PublicKey)}
+		_, err := c0.doEncHandshake(context.Background(), prv0, dest)
+		errc <- err
+	}()
+	go func() {
+		_, err := c1.doEncHandshake(context.Background(), prv1, nil)
+		errc <- err
+	}()
+	for i := 0; i < 2; i++ {
+		if err := <-errc; err != nil {
+			t.Fatalf("handshake failed: %v", err)
+		}
+	}
+	fd0.SetDeadline(time.Time{})
+	fd1.SetDeadline(time.Time{})
+	return c0, c1
+}
+
+// readAndHandle reads the next message from c and feeds it to the rekeyer,
+// checking that it carries the expected code.
+func readAndHandle(c *rlpx, r *sessionRekeyer, code uint64) error {
+	msg, err := c.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != code {
+		return fmt.Errorf("got message code %d, want %d", msg.Code, code)
+	}
+	return r.handle(msg)
+}
+
+// runRekey performs a full renegotiation initiated by r0.
+func runRekey(c0, c1 *rlpx, r0, r1 *sessionRekeyer) error {
+	errc := make(chan error, 3)
+	go func() { errc <- r0.request() }()
+	go func() {
+		if err := readAndHandle(c1, r1, rekeyMsg); err != nil {
+			errc <- err
+			return
+		}
+		errc <- readAndHandle(c1, r1, rekeyDoneMsg)
+	}()
+	go func() { errc <- readAndHandle(c0, r0, rekeyAckMsg) }()
+	for i := 0; i < 3; i++ {
+		if err := <-errc; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exchange sends a message in both directions and checks its delivery.
+func exchange(c0, c1 *rlpx, payload string) error {
+	for _, pair := range [][2]*rlpx{{c0, c1}, {c1, c0}} {
+		errc := make(chan error, 1)
+		go func(w *rlpx) { errc <- Send(w, baseProtocolLength, []string{payload}) }(pair[0])
+		if err := ExpectMsg(pair[1], baseProtocolLength, []string{payload}); err != nil {
+			return err
+		}
+		if err := <-errc; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSessionRekey(t *testing.T) {
+	c0, c1 := rekeyPipe(t)
+	defer c0.close(DiscQuitting)
+	defer c1.close(DiscQuitting)
+
+	r0 := newSessionRekeyer(c0, log.Root())
+	r1 := newSessionRekeyer(c1, log.Root())
+
+	initial := c0.sessionKeys()
+	if err := exchange(c0, c1, "before"); err != nil {
+		t.Fatalf("exchange before rekey failed: %v", err)
+	}
+	if err := runRekey(c0, c1, r0, r1); err != nil {
+		t.Fatalf("rekey failed: %v", err)
+	}
+	k0, k1 := c0.sessionKeys(), c1.sessionKeys()
+	if !bytes.Equal(k0.AES, k1.AES) || !bytes.Equal(k0.MAC, k1.MAC) {
+		t.Fatalf("session keys diverged:\n  initiator: %x %x\n  responder: %x %x", k0.AES, k0.MAC, k1.AES, k1.MAC)
+	}
+	if bytes.Equal(k0.AES, initial.AES) || bytes.Equal(k0.MAC, initial.MAC) {
+		t.Fatalf("session keys not renegotiated")
+	}
+	if err := exchange(c0, c1, "after"); err != nil {
+		t.Fatalf("exchange after rekey failed: %v", err)
+	}
+	if r0.rekeys != 1 || r1.rekeys != 1 {
+		t.Errorf("rekey count mismatch: initiator %d, responder %d", r0.rekeys, r1.rekeys)
+	}
+	// A second request right away must be rejected by the responder.
+	go r0.request()
+	if err := readAndHandle(c1, r1, rekeyMsg); err == nil {
+		t.Errorf("expected rejection of too frequent rekey request")
+	}
+}
+
+func TestSessionRekeyUnexpected(t *testing.T) {
+	c0, c1 := rekeyPipe(t)
+	defer c0.close(DiscQuitting)
+	defer c1.close(DiscQuitting)
+
+	r1 := newSessionRekeyer(c1, log.Root())
+	for _, code := range []uint64{rekeyAckMsg, rekeyDoneMsg} {
+		go Send(c0, code, []interface{}{})
+		if err := readAndHandle(c1, r1, code); err == nil {
+			t.Errorf("message %d: expected error for unsolicited message", code)
+		}
+	}
+}
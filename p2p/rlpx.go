@@ -86,6 +86,9 @@ type rlpx struct {
 
 	rmu, wmu sync.Mutex
 	rw       *rlpxFrameRW
+
+	keymu sync.Mutex
+	keys  secrets // base secrets of the latest key generation
 }
 
 func newRLPX(fd net.Conn) transport {
@@ -135,6 +138,44 @@ func (t *rlpx) close(err error) {
 	}
 }
 
+// sessionKeys returns the base secrets of the current key generation.
+func (t *rlpx) sessionKeys() secrets {
+	t.keymu.Lock()
+	defer t.keymu.Unlock()
+	return t.keys
+}
+
+// setSessionKeys records the base secrets of a newly negotiated key generation.
+func (t *rlpx) setSessionKeys(s secrets) {
+	t.keymu.Lock()
+	defer t.keymu.Unlock()
+	t.keys = secrets{RemoteID: s.RemoteID, AES: s.AES, MAC: s.MAC}
+}
+
+// writeRekey sends msg using the current egress keys and switches the
+// outgoing direction to next before any other message can be written.
+func (t *rlpx) writeRekey(ctx context.Context, msg Msg, next secrets) error {
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	if err := t.fd.SetWriteDeadline(time.Now().Add(frameWriteTimeout)); err != nil {
+		return err
+	}
+	if err := t.rw.WriteMsg(ctx, msg); err != nil {
+		return err
+	}
+	t.rw.setEgress(next)
+	return nil
+}
+
+// readRekey switches the incoming direction to next. It must be called by
+// the reader between two messages, right after the message announcing the
+// switch has been read.
+func (t *rlpx) readRekey(next secrets) {
+	t.rmu.Lock()
+	defer t.rmu.Unlock()
+	t.rw.setIngress(next)
+}
+
 // doEncHandshake runs the protocol handshake using authenticated
 // messages. the protocol handshake is the first authenticated message
 // and also verifies whether the encryption handshake 'worked' and the
@@ -215,6 +256,10 @@ func (t *rlpx) doEncHandshake(ctx context.Context, prv *ecdsa.PrivateKey, dial *
 	t.wmu.Lock()
 	t.rw = newRLPXFrameRW(t.fd, sec)
 	t.wmu.Unlock()
+
+	t.keymu.Lock()
+	t.keys = secrets{RemoteID: sec.RemoteID, AES: sec.AES, MAC: sec.MAC}
+	t.keymu.Unlock()
 	return sec.RemoteID, nil
 }
 
@@ -595,14 +640,38 @@ type rlpxFrameRW struct {
 	enc  cipher.Stream
 	dec  cipher.Stream
 
-	macCipher  cipher.Block
-	egressMAC  hash.Hash
-	ingressMAC hash.Hash
+	egressMACCipher  cipher.Block
+	ingressMACCipher cipher.Block
+	egressMAC        hash.Hash
+	ingressMAC       hash.Hash
 
 	snappy bool
 }
 
 func newRLPXFrameRW(conn io.ReadWriter, s secrets) *rlpxFrameRW {
+	rw := &rlpxFrameRW{conn: conn}
+	rw.setEgress(s)
+	rw.setIngress(s)
+	return rw
+}
+
+// setEgress switches the outgoing direction of the frame stream to the
+// given secrets. The next frame written is encrypted with the new keys.
+func (rw *rlpxFrameRW) setEgress(s secrets) {
+	rw.enc, rw.egressMACCipher = newFrameCiphers(s)
+	rw.egressMAC = s.EgressMAC
+}
+
+// setIngress switches the incoming direction of the frame stream to the
+// given secrets. The next frame read is expected to use the new keys.
+func (rw *rlpxFrameRW) setIngress(s secrets) {
+	rw.dec, rw.ingressMACCipher = newFrameCiphers(s)
+	rw.ingressMAC = s.IngressMAC
+}
+
+// newFrameCiphers creates the frame cipher stream and the MAC block cipher
+// for one direction of the connection.
+func newFrameCiphers(s secrets) (cipher.Stream, cipher.Block) {
 	macc, err := aes.NewCipher(s.MAC)
 	if err != nil {
 		panic("invalid MAC secret: " + err.Error())
@@ -614,14 +683,7 @@ func newRLPXFrameRW(conn io.ReadWriter, s secrets) *rlpxFrameRW {
 	// we use an all-zeroes IV for AES because the key used
 	// for encryption is ephemeral.
 	iv := make([]byte, encc.BlockSize())
-	return &rlpxFrameRW{
-		conn:       conn,
-		enc:        cipher.NewCTR(encc, iv),
-		dec:        cipher.NewCTR(encc, iv),
-		macCipher:  macc,
-		egressMAC:  s.EgressMAC,
-		ingressMAC: s.IngressMAC,
-	}
+	return cipher.NewCTR(encc, iv), macc
 }
 
 func (rw *rlpxFrameRW) WriteMsg(ctx context.Context, msg Msg) error {
@@ -652,7 +714,7 @@ func (rw *rlpxFrameRW) WriteMsg(ctx context.Context, msg Msg) error {
 	rw.enc.XORKeyStream(headbuf[:16], headbuf[:16]) // first half is now encrypted
 
 	// write header MAC
-	copy(headbuf[16:], updateMAC(rw.egressMAC, rw.macCipher, headbuf[:16]))
+	copy(headbuf[16:], updateMAC(rw.egressMAC, rw.egressMACCipher, headbuf[:16]))
 	if _, err := rw.conn.Write(headbuf); err != nil {
 		return err
 	}
@@ -675,7 +737,7 @@ func (rw *rlpxFrameRW) WriteMsg(ctx context.Context, msg Msg) error {
 	// write frame MAC. egress MAC hash is up to date because
 	// frame content was written to it as well.
 	fmacseed := rw.egressMAC.Sum(nil)
-	mac := updateMAC(rw.egressMAC, rw.macCipher, fmacseed)
+	mac := updateMAC(rw.egressMAC, rw.egressMACCipher, fmacseed)
 	_, err := rw.conn.Write(mac)
 	return err
 }
@@ -687,7 +749,7 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 		return msg, err
 	}
 	// verify header mac
-	shouldMAC := updateMAC(rw.ingressMAC, rw.macCipher, headbuf[:16])
+	shouldMAC := updateMAC(rw.ingressMAC, rw.ingressMACCipher, headbuf[:16])
 	if !hmac.Equal(shouldMAC, headbuf[16:]) {
 		return msg, errors.New("bad header MAC")
 	}
@@ -711,7 +773,7 @@ func (rw *rlpxFrameRW) ReadMsg() (msg Msg, err error) {
 	if _, err := io.ReadFull(rw.conn, headbuf[:16]); err != nil {
 		return msg, err
 	}
-	shouldMAC = updateMAC(rw.ingressMAC, rw.macCipher, fmacseed)
+	shouldMAC = updateMAC(rw.ingressMAC, rw.ingressMACCipher, fmacseed)
 	if !hmac.Equal(shouldMAC, headbuf[:16]) {
 		return msg, errors.New("bad frame MAC")
 	}
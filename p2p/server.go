@@ -109,6 +109,11 @@ type Config struct {
 	// IP networks contained in the list are considered.
 	NetRestrict *netutil.Netlist `toml:",omitempty"`
 
+	// Firewall restricts the IP networks inbound connections are accepted
+	// from and outbound connections are dialed to. The rules can be replaced
+	// while the server is running using SetFirewallRules.
+	Firewall *netutil.ConnPolicy `toml:",omitempty"`
+
 	// NodeDatabase is the path to the database containing the previously seen
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
@@ -138,6 +143,16 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// BannedClients are regular expressions matched against the client name
+	// advertised by peers in the protocol handshake. Matching peers are
+	// rejected, e.g. to quarantine versions with known consensus bugs.
+	BannedClients []string `toml:",omitempty"`
+
+	// RekeyInterval is the period after which dialed connections renegotiate
+	// their session keys with remote nodes supporting it. Zero disables
+	// renegotiations initiated by this node.
+	RekeyInterval time.Duration `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
@@ -159,6 +174,11 @@ type Server struct {
 	lock    sync.Mutex // protects running
 	running bool
 
+	firewallMu sync.RWMutex
+	firewall   *netutil.ConnPolicy // rules currently enforced
+
+	clients clientFilter // client name patterns rejected at handshake
+
 	ntab         discoverTable
 	listener     net.Listener
 	ourHandshake *protoHandshake
@@ -177,6 +197,7 @@ type Server struct {
 	delpeer       chan peerDrop
 	loopWG        sync.WaitGroup // loop, listenLoop
 	peerFeed      event.Feed
+	beaconFeed    event.Feed // Beacons of the bonded discovery nodes
 	log           log.Logger
 }
 
@@ -274,6 +295,83 @@ func (srv *Server) Peers() []*Peer {
 	return ps
 }
 
+// FirewallRules returns a copy of the connection rules currently enforced.
+func (srv *Server) FirewallRules() *netutil.ConnPolicy {
+	srv.firewallMu.RLock()
+	defer srv.firewallMu.RUnlock()
+	return srv.firewall.Copy()
+}
+
+// SetFirewallRules replaces the connection rules enforced by the server.
+// Connected peers which the new rules no longer admit are disconnected.
+func (srv *Server) SetFirewallRules(rules *netutil.ConnPolicy) {
+	srv.firewallMu.Lock()
+	srv.firewall = rules.Copy()
+	srv.firewallMu.Unlock()
+
+	srv.lock.Lock()
+	running := srv.running
+	srv.lock.Unlock()
+	if !running {
+		return
+	}
+	for _, p := range srv.Peers() {
+		tcp, ok := p.RemoteAddr().(*net.TCPAddr)
+		if !ok {
+			continue
+		}
+		if (p.Inbound() && !srv.allowInbound(tcp.IP)) || (!p.Inbound() && !srv.allowOutbound(tcp.IP)) {
+			srv.log.Debug("Dropping peer denied by firewall", "id", p.ID(), "addr", tcp)
+			p.Disconnect(DiscRequested)
+		}
+	}
+}
+
+// ClientBans returns the client name patterns rejected during the protocol
+// handshake, along with the number of peers each one rejected.
+func (srv *Server) ClientBans() []ClientBan {
+	return srv.clients.list()
+}
+
+// AddClientBan starts rejecting peers whose advertised client name matches the
+// given regular expression. Connected peers matching it are disconnected.
+func (srv *Server) AddClientBan(pattern string) error {
+	if err := srv.clients.add(pattern); err != nil {
+		return err
+	}
+	srv.lock.Lock()
+	running := srv.running
+	srv.lock.Unlock()
+	if !running {
+		return nil
+	}
+	for _, p := range srv.Peers() {
+		if srv.clients.match(p.Name()) {
+			srv.log.Debug("Dropping peer running banned client", "id", p.ID(), "name", p.Name())
+			p.Disconnect(DiscIncompatibleVersion)
+		}
+	}
+	return nil
+}
+
+// RemoveClientBan stops rejecting peers by the given client name pattern. It
+// reports whether the pattern was banned.
+func (srv *Server) RemoveClientBan(pattern string) bool {
+	return srv.clients.remove(pattern)
+}
+
+func (srv *Server) allowInbound(ip net.IP) bool {
+	srv.firewallMu.RLock()
+	defer srv.firewallMu.RUnlock()
+	return srv.firewall.AllowInbound(ip)
+}
+
+func (srv *Server) allowOutbound(ip net.IP) bool {
+	srv.firewallMu.RLock()
+	defer srv.firewallMu.RUnlock()
+	return srv.firewall.AllowOutbound(ip)
+}
+
 // PeerCount returns the number of connected peers.
 func (srv *Server) PeerCount() int {
 	var count int
@@ -410,6 +508,16 @@ func (srv *Server) Start() (err error) {
 	if srv.Dialer == nil {
 		srv.Dialer = TCPDialer{&net.Dialer{Timeout: defaultDialTimeout}}
 	}
+	srv.firewallMu.Lock()
+	srv.firewall = srv.Firewall.Copy()
+	srv.firewallMu.Unlock()
+
+	for _, pattern := range srv.BannedClients {
+		if err := srv.clients.add(pattern); err != nil {
+			return err
+		}
+	}
+
 	srv.quit = make(chan struct{})
 	srv.addpeer = make(chan *conn)
 	srv.delpeer = make(chan peerDrop)
@@ -454,6 +562,7 @@ func (srv *Server) Start() (err error) {
 
 	// node table
 	if !srv.NoDiscovery {
+		beacons := make(chan discover.Beacon, 64)
 		cfg := discover.Config{
 			PrivateKey:   srv.PrivateKey,
 			AnnounceAddr: realaddr,
@@ -461,12 +570,15 @@ func (srv *Server) Start() (err error) {
 			NetRestrict:  srv.NetRestrict,
 			Bootnodes:    srv.BootstrapNodes,
 			Unhandled:    unhandled,
+			Beacons:      beacons,
 		}
 		ntab, err := discover.ListenUDP(conn, cfg)
 		if err != nil {
 			return err
 		}
 		srv.ntab = ntab
+		srv.loopWG.Add(1)
+		go srv.beaconLoop(beacons)
 	}
 
 	if srv.DiscoveryV5 {
@@ -490,12 +602,14 @@ func (srv *Server) Start() (err error) {
 
 	dynPeers := srv.maxDialedConns()
 	dialer := newDialState(srv.StaticNodes, srv.BootstrapNodes, srv.ntab, dynPeers, srv.NetRestrict)
+	dialer.firewall = srv.allowOutbound
 
 	// handshake
 	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: discover.PubkeyID(&srv.PrivateKey.PublicKey)}
 	for _, p := range srv.Protocols {
 		srv.ourHandshake.Caps = append(srv.ourHandshake.Caps, p.cap())
 	}
+	srv.ourHandshake.Caps = append(srv.ourHandshake.Caps, rekeyCap)
 	// listen/dial
 	if srv.ListenAddr != "" {
 		if err := srv.startListening(); err != nil {
@@ -512,6 +626,37 @@ func (srv *Server) Start() (err error) {
 	return nil
 }
 
+// beaconLoop relays the beacons received by the discovery table to subscribers.
+func (srv *Server) beaconLoop(beacons <-chan discover.Beacon) {
+	defer srv.loopWG.Done()
+	for {
+		select {
+		case b := <-beacons:
+			srv.beaconFeed.Send(b)
+		case <-srv.quit:
+			return
+		}
+	}
+}
+
+// SendBeacon announces a payload to the nodes of the discovery table, returning
+// the number of nodes it was sent to. Payloads must fit in a discovery packet.
+func (srv *Server) SendBeacon(payload []byte) (int, error) {
+	tab, ok := srv.ntab.(interface {
+		SendBeacon([]byte) (int, error)
+	})
+	if !ok {
+		return 0, errors.New("discovery not running")
+	}
+	return tab.SendBeacon(payload)
+}
+
+// SubscribeBeacons subscribes to the beacons received from the bonded nodes of
+// the discovery table.
+func (srv *Server) SubscribeBeacons(ch chan<- discover.Beacon) event.Subscription {
+	return srv.beaconFeed.Subscribe(ch)
+}
+
 func (srv *Server) startListening() error {
 	// Launch the TCP listener.
 	listener, err := net.Listen("tcp", srv.ListenAddr)
@@ -647,6 +792,7 @@ running:
 				if srv.EnableMsgEvents {
 					p.events = &srv.peerFeed
 				}
+				p.enableRekey(srv.RekeyInterval)
 				name := truncateName(c.name)
 				srv.log.Debug("Adding p2p peer", "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 				go srv.runPeer(p)
@@ -702,6 +848,10 @@ func (srv *Server) protoHandshakeChecks(peers map[discover.NodeID]*Peer, inbound
 	if len(srv.Protocols) > 0 && countMatchingProtocols(srv.Protocols, c.caps) == 0 {
 		return DiscUselessPeer
 	}
+	// Drop clients running banned versions.
+	if srv.clients.reject(c.name) {
+		return DiscIncompatibleVersion
+	}
 	// Repeat the encryption handshake checks because the
 	// peer set might have changed between the handshakes.
 	return srv.encHandshakeChecks(peers, inboundCount, c)
@@ -787,6 +937,15 @@ func (srv *Server) listenLoop() {
 				continue
 			}
 		}
+		// Reject connections denied by the firewall rules.
+		if tcp, ok := fd.RemoteAddr().(*net.TCPAddr); ok && !srv.allowInbound(tcp.IP) {
+			srv.log.Debug("Rejected conn (denied by firewall)", "addr", fd.RemoteAddr())
+			if err := fd.Close(); err != nil {
+				log.Error("Cannot close p2p server connection", "err", err)
+			}
+			slots <- struct{}{}
+			continue
+		}
 
 		fd = newMeteredConn(fd, true)
 		srv.log.Trace("Accepted connection", "addr", fd.RemoteAddr())
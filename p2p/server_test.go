@@ -455,6 +455,53 @@ func TestServerSetupConn(t *testing.T) {
 	}
 }
 
+// This test checks that peers advertising banned client names are rejected
+// after the protocol handshake, and that bans can be lifted at runtime.
+func TestServerClientBans(t *testing.T) {
+	id := randomID()
+	srv := &Server{
+		Config: Config{
+			PrivateKey:    newkey(),
+			MaxPeers:      10,
+			NoDial:        true,
+			Protocols:     []Protocol{discard},
+			BannedClients: []string{`^Geth/v1\.8\.0-`},
+		},
+		newTransport: func(fd net.Conn) transport { return nil },
+		log:          log.New(),
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("couldn't start server: %v", err)
+	}
+	defer srv.Stop()
+
+	if err := srv.AddClientBan("(invalid"); err == nil {
+		t.Errorf("invalid pattern accepted")
+	}
+	if err := srv.AddClientBan(`^Parity/v1\.9\.`); err != nil {
+		t.Fatalf("failed to ban client: %v", err)
+	}
+	for _, name := range []string{"Geth/v1.8.0-stable/linux-amd64/go1.10", "Parity/v1.9.2-beta/x86_64-linux-gnu/rustc1.24.1"} {
+		tt := &setupTransport{id: id, phs: &protoHandshake{ID: id, Name: name, Caps: []Cap{discard.cap()}}}
+		srv.newTransport = func(fd net.Conn) transport { return tt }
+		p1, _ := net.Pipe()
+		srv.SetupConn(p1, inboundConn, nil)
+		if tt.closeErr != DiscIncompatibleVersion {
+			t.Errorf("%s: close error mismatch: got %q, want %q", name, tt.closeErr, DiscIncompatibleVersion)
+		}
+	}
+	want := []ClientBan{{Pattern: `^Geth/v1\.8\.0-`, Rejected: 1}, {Pattern: `^Parity/v1\.9\.`, Rejected: 1}}
+	if bans := srv.ClientBans(); !reflect.DeepEqual(bans, want) {
+		t.Errorf("client bans mismatch: got %v, want %v", bans, want)
+	}
+	if !srv.RemoveClientBan(`^Parity/v1\.9\.`) || srv.RemoveClientBan(`^Parity/v1\.9\.`) {
+		t.Errorf("ban removal mismatch")
+	}
+	if srv.clients.match("Parity/v1.9.2-beta/x86_64-linux-gnu/rustc1.24.1") {
+		t.Errorf("removed ban still matching")
+	}
+}
+
 type setupTransport struct {
 	id              discover.NodeID
 	encHandshakeErr error
@@ -19,6 +19,7 @@ package params
 import (
 	"fmt"
 	"math/big"
+	"reflect"
 
 	"github.com/fulcrumchain/indigo/common"
 )
@@ -65,11 +66,15 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, DefaultCliqueConfig()}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, DefaultCliqueConfig(), nil, nil, nil, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0),
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0),
 		nil,
 		DefaultCliqueConfig(),
+		nil,
+		nil,
+		nil,
+		nil,
 	}
 	TestRules = TestChainConfig.Rules(new(big.Int))
 )
@@ -93,9 +98,212 @@ type ChainConfig struct {
 
 	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 
+	TypedTxBlock *big.Int `json:"typedTxBlock,omitempty"` // Typed transaction envelope switch block (nil = no fork, 0 = already activated)
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+
+	// Gas limit trajectory followed by sealers (nil = usage based)
+	GasLimit *GasLimitConfig `json:"gasLimit,omitempty"`
+
+	// Extra precompiled contracts activated by the chain (nil = none)
+	Precompiles []*PrecompileConfig `json:"precompiles,omitempty"`
+
+	// Sponsorship of the gas of whitelisted transactions (nil = none)
+	GasSponsor *GasSponsorConfig `json:"gasSponsor,omitempty"`
+
+	// Bloom filters indexed for log searches (nil = header blooms)
+	Bloom *BloomConfig `json:"bloom,omitempty"`
+}
+
+// PrecompileConfig activates the precompiled contract registered with the EVM
+// under Name at Address, starting with Block.
+type PrecompileConfig struct {
+	Name    string         `json:"name"`
+	Address common.Address `json:"address"`
+	Block   *big.Int       `json:"block"`
+}
+
+// CheckPrecompiles verifies that the extra precompiles are well formed and do
+// not share addresses. Whether the names are known is up to the EVM to check.
+func (c *ChainConfig) CheckPrecompiles() error {
+	seen := make(map[common.Address]string)
+	for _, p := range c.Precompiles {
+		if p.Name == "" {
+			return fmt.Errorf("precompile at %x has no name", p.Address)
+		}
+		if p.Block == nil || p.Block.Sign() < 0 {
+			return fmt.Errorf("precompile %s has no valid activation block", p.Name)
+		}
+		if name, ok := seen[p.Address]; ok {
+			return fmt.Errorf("precompiles %s and %s share address %x", name, p.Name, p.Address)
+		}
+		seen[p.Address] = p.Name
+	}
+	return nil
+}
+
+// ActivePrecompiles returns the extra precompiles active at block num.
+func (c *ChainConfig) ActivePrecompiles(num *big.Int) []*PrecompileConfig {
+	var active []*PrecompileConfig
+	for _, p := range c.Precompiles {
+		if isForked(p.Block, num) {
+			active = append(active, p)
+		}
+	}
+	return active
+}
+
+// GasSponsorConfig makes a sponsor account pay for the gas of the transactions
+// sent by whitelisted accounts or calling whitelisted contracts, starting with
+// Block. The senders of sponsored transactions only need to afford the value
+// they transfer, while the sponsor is debited the gas up front and refunded the
+// unused part, as senders otherwise are.
+//
+// Only transactions priced up to MaxGasPrice are sponsored, so whitelisted
+// senders can't drain the sponsor by bidding up the price: they pay themselves
+// for the gas of the transactions they price higher.
+type GasSponsorConfig struct {
+	Sponsor     common.Address   `json:"sponsor"`
+	Block       *big.Int         `json:"block"`
+	MaxGasPrice *big.Int         `json:"maxGasPrice"`         // Highest gas price of sponsored transactions
+	Senders     []common.Address `json:"senders,omitempty"`   // Accounts whose transactions are sponsored
+	Contracts   []common.Address `json:"contracts,omitempty"` // Contracts calls to which are sponsored
+}
+
+// Check verifies that the sponsorship is well formed.
+func (c *GasSponsorConfig) Check() error {
+	if c.Sponsor == (common.Address{}) {
+		return fmt.Errorf("gas sponsor has no account")
+	}
+	if c.Block == nil || c.Block.Sign() < 0 {
+		return fmt.Errorf("gas sponsor has no valid activation block")
+	}
+	if c.MaxGasPrice == nil || c.MaxGasPrice.Sign() <= 0 {
+		return fmt.Errorf("gas sponsor %x has no valid gas price cap", c.Sponsor)
+	}
+	if len(c.Senders) == 0 && len(c.Contracts) == 0 {
+		return fmt.Errorf("gas sponsor %x has no whitelisted senders or contracts", c.Sponsor)
+	}
+	return nil
+}
+
+// sponsors reports whether a transaction from the sender to the recipient at the
+// gas price is sponsored, contract creations only being so for whitelisted
+// senders.
+func (c *GasSponsorConfig) sponsors(from common.Address, to *common.Address, gasPrice *big.Int) bool {
+	if c.MaxGasPrice == nil || gasPrice.Cmp(c.MaxGasPrice) > 0 {
+		return false
+	}
+	for _, addr := range c.Senders {
+		if addr == from {
+			return true
+		}
+	}
+	if to != nil {
+		for _, addr := range c.Contracts {
+			if addr == *to {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equal reports whether two sponsorships are the same, whitelists included.
+func (c *GasSponsorConfig) equal(other *GasSponsorConfig) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	return c.Sponsor == other.Sponsor && configNumEqual(c.Block, other.Block) &&
+		configNumEqual(c.MaxGasPrice, other.MaxGasPrice) && reflect.DeepEqual(c.Senders, other.Senders) && reflect.DeepEqual(c.Contracts, other.Contracts)
+}
+
+// GasPayer returns the account paying for the gas of a transaction from the
+// sender to the recipient at the gas price in block num, the sponsor if the
+// transaction is sponsored and the sender otherwise.
+func (c *ChainConfig) GasPayer(num *big.Int, from common.Address, to *common.Address, gasPrice *big.Int) common.Address {
+	if s := c.GasSponsor; s != nil && isForked(s.Block, num) && s.sponsors(from, to, gasPrice) {
+		return s.Sponsor
+	}
+	return from
+}
+
+// GasLimitConfig is the gas limit policy of a chain: starting from the gas
+// limit of the genesis block, sealers move the limit of every block toward the
+// target by at most the delta. It is sealer strategy, blocks deviating from it
+// are not invalid.
+type GasLimitConfig struct {
+	Initial uint64 `json:"initial,omitempty"` // Gas limit of the genesis block if the genesis doesn't set one
+	Target  uint64 `json:"target"`            // Gas limit sealed blocks move toward
+	Delta   uint64 `json:"delta,omitempty"`   // Maximum change of the gas limit per block (0 = protocol maximum)
+}
+
+// Check verifies that the gas limit trajectory is reachable.
+func (c *GasLimitConfig) Check() error {
+	if c.Target < MinGasLimit {
+		return fmt.Errorf("gas limit target %d below minimum %d", c.Target, MinGasLimit)
+	}
+	if c.Initial != 0 && c.Initial < MinGasLimit {
+		return fmt.Errorf("initial gas limit %d below minimum %d", c.Initial, MinGasLimit)
+	}
+	return nil
+}
+
+// Bounds of the bloom filters indexed for log searches.
+const (
+	HeaderBloomBits   = 2048  // Size of the bloom filters of block headers
+	HeaderBloomHashes = 3     // Bits set per address and topic in header blooms
+	MaxBloomBits      = 65536 // Largest bloom filter indexable, as bits are picked by two bytes of hash
+	MaxBloomHashes    = 16    // Most bits set per item, as a hash provides 16 pairs of bytes
+)
+
+// BloomConfig tunes the bloom filters indexed for log searches, trading index
+// size for fewer false positives on chains with many logs per block. Headers
+// keep their blooms, so larger filters are computed from the receipts. It is
+// node policy, changing it only rebuilds the index.
+type BloomConfig struct {
+	SectionSize uint64 `json:"sectionSize,omitempty"` // Blocks per index section (0 = BloomBitsBlocks)
+	Bits        uint   `json:"bits,omitempty"`        // Size of the filters, a power of two (0 = header size)
+	Hashes      uint   `json:"hashes,omitempty"`      // Bits set per address and topic (0 = as headers)
+}
+
+// Check verifies that the bloom parameters can be indexed.
+func (c *BloomConfig) Check() error {
+	if c.SectionSize%8 != 0 {
+		return fmt.Errorf("bloom section size %d not a multiple of 8", c.SectionSize)
+	}
+	if c.Bits != 0 && (c.Bits < HeaderBloomBits || c.Bits > MaxBloomBits || c.Bits&(c.Bits-1) != 0) {
+		return fmt.Errorf("bloom size %d not a power of two between %d and %d", c.Bits, HeaderBloomBits, MaxBloomBits)
+	}
+	if c.Hashes > MaxBloomHashes {
+		return fmt.Errorf("bloom hash count %d above maximum %d", c.Hashes, MaxBloomHashes)
+	}
+	return nil
+}
+
+// HeaderSized reports whether the filters are the blooms of the block headers.
+func (c BloomConfig) HeaderSized() bool {
+	return c.Bits == HeaderBloomBits && c.Hashes == HeaderBloomHashes
+}
+
+// BloomParams returns the parameters of the bloom filters indexed for log
+// searches, defaults filled in.
+func (c *ChainConfig) BloomParams() BloomConfig {
+	bloom := BloomConfig{SectionSize: BloomBitsBlocks, Bits: HeaderBloomBits, Hashes: HeaderBloomHashes}
+	if c.Bloom != nil {
+		if c.Bloom.SectionSize != 0 {
+			bloom.SectionSize = c.Bloom.SectionSize
+		}
+		if c.Bloom.Bits != 0 {
+			bloom.Bits = c.Bloom.Bits
+		}
+		if c.Bloom.Hashes != 0 {
+			bloom.Hashes = c.Bloom.Hashes
+		}
+	}
+	return bloom
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -110,6 +318,18 @@ func (c *EthashConfig) String() string {
 type CliqueConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+
+	Schedule []CliqueFork `json:"schedule,omitempty"` // Parameter changes at future blocks, in ascending block order
+}
+
+// CliqueFork schedules a change of the clique parameters from a block on.
+//
+// If the epoch length changes, the fork block is a checkpoint and the following
+// checkpoints are counted from it.
+type CliqueFork struct {
+	Block  uint64  `json:"block"`            // Block from which on the parameters apply
+	Epoch  uint64  `json:"epoch,omitempty"`  // New epoch length (0 = unchanged)
+	Period *uint64 `json:"period,omitempty"` // New number of seconds between blocks (nil = unchanged)
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -117,6 +337,71 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// CheckSchedule verifies that the scheduled parameter changes are ordered and
+// valid.
+func (c *CliqueConfig) CheckSchedule() error {
+	var last uint64
+	for i, fork := range c.Schedule {
+		if fork.Block == 0 {
+			return fmt.Errorf("clique schedule entry %d: fork at genesis, set the base parameters instead", i)
+		}
+		if fork.Block <= last {
+			return fmt.Errorf("clique schedule entry %d: block %d not after block %d", i, fork.Block, last)
+		}
+		if fork.Epoch == 0 && fork.Period == nil {
+			return fmt.Errorf("clique schedule entry %d: no parameter changed at block %d", i, fork.Block)
+		}
+		last = fork.Block
+	}
+	return nil
+}
+
+// epochAt returns the epoch length in force at block number, and the block
+// from which on its checkpoints are counted.
+func (c *CliqueConfig) epochAt(number uint64) (epoch, start uint64) {
+	epoch = c.Epoch
+	if epoch == 0 {
+		epoch = DefaultCliqueEpoch
+	}
+	for _, fork := range c.Schedule {
+		if fork.Block > number {
+			break
+		}
+		if fork.Epoch != 0 {
+			epoch, start = fork.Epoch, fork.Block
+		}
+	}
+	return epoch, start
+}
+
+// EpochAt returns the epoch length in force at block number.
+func (c *CliqueConfig) EpochAt(number uint64) uint64 {
+	epoch, _ := c.epochAt(number)
+	return epoch
+}
+
+// IsCheckpoint returns whether block number is an epoch checkpoint, which
+// resets the pending votes and carries the full list of signers and voters.
+func (c *CliqueConfig) IsCheckpoint(number uint64) bool {
+	epoch, start := c.epochAt(number)
+	return (number-start)%epoch == 0
+}
+
+// PeriodAt returns the number of seconds to enforce between block number and
+// its parent.
+func (c *CliqueConfig) PeriodAt(number uint64) uint64 {
+	period := c.Period
+	for _, fork := range c.Schedule {
+		if fork.Block > number {
+			break
+		}
+		if fork.Period != nil {
+			period = *fork.Period
+		}
+	}
+	return period
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -128,13 +413,14 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v TypedTx: %v Engine: %v}",
 		c.ChainId,
 		c.HomesteadBlock,
 		c.EIP150Block,
 		c.EIP155Block,
 		c.EIP158Block,
 		c.ByzantiumBlock,
+		c.TypedTxBlock,
 		engine,
 	)
 }
@@ -160,6 +446,12 @@ func (c *ChainConfig) IsByzantium(num *big.Int) bool {
 	return isForked(c.ByzantiumBlock, num)
 }
 
+// IsTypedTx returns whether num is either equal to the typed transaction
+// envelope fork block or greater.
+func (c *ChainConfig) IsTypedTx(num *big.Int) bool {
+	return isForked(c.TypedTxBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -214,9 +506,108 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
 		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	}
+	if isForkIncompatible(c.TypedTxBlock, newcfg.TypedTxBlock, head) {
+		return newCompatError("typed transaction fork block", c.TypedTxBlock, newcfg.TypedTxBlock)
+	}
+	if c.Clique != nil && newcfg.Clique != nil {
+		if block := cliqueScheduleConflict(c.Clique, newcfg.Clique, head); block != nil {
+			return newCompatError("clique schedule", block, block)
+		}
+	}
+	if block := precompileConflict(c, newcfg, head); block != nil {
+		return newCompatError("precompile activation", block, block)
+	}
+	if block := gasSponsorConflict(c.GasSponsor, newcfg.GasSponsor, head); block != nil {
+		return newCompatError("gas sponsorship", block, block)
+	}
 	return nil
 }
 
+// gasSponsorConflict returns the first block at or below head from which the
+// gas sponsorships of the two configurations differ, or nil if they only differ
+// past head.
+func gasSponsorConflict(s1, s2 *GasSponsorConfig, head *big.Int) *big.Int {
+	if head == nil || s1.equal(s2) {
+		return nil
+	}
+	var conflict *big.Int
+	for _, s := range []*GasSponsorConfig{s1, s2} {
+		if s == nil || s.Block == nil || s.Block.Cmp(head) > 0 {
+			continue
+		}
+		if conflict == nil || s.Block.Cmp(conflict) < 0 {
+			conflict = s.Block
+		}
+	}
+	return conflict
+}
+
+// precompileConflict returns the first block at or below head whose set of
+// active extra precompiles differs between the two configurations, or nil if
+// they only differ past head.
+func precompileConflict(c1, c2 *ChainConfig, head *big.Int) *big.Int {
+	if head == nil {
+		return nil
+	}
+	var blocks []*big.Int
+	for _, p := range append(append([]*PrecompileConfig{}, c1.Precompiles...), c2.Precompiles...) {
+		if p.Block != nil {
+			blocks = append(blocks, p.Block)
+		}
+	}
+	var conflict *big.Int
+	for _, num := range blocks {
+		if num.Cmp(head) > 0 || (conflict != nil && conflict.Cmp(num) <= 0) {
+			continue
+		}
+		a1, a2 := c1.ActivePrecompiles(num), c2.ActivePrecompiles(num)
+		if len(a1) != len(a2) {
+			conflict = num
+			continue
+		}
+		names := make(map[common.Address]string, len(a1))
+		for _, p := range a1 {
+			names[p.Address] = p.Name
+		}
+		for _, p := range a2 {
+			if name, ok := names[p.Address]; !ok || name != p.Name {
+				conflict = num
+				break
+			}
+		}
+	}
+	return conflict
+}
+
+// cliqueScheduleConflict returns the first block at or below head whose clique
+// parameters differ between the two configurations, or nil if the schedules
+// only differ past head.
+func cliqueScheduleConflict(c1, c2 *CliqueConfig, head *big.Int) *big.Int {
+	if head == nil {
+		return nil
+	}
+	blocks := []uint64{0}
+	for _, fork := range c1.Schedule {
+		blocks = append(blocks, fork.Block)
+	}
+	for _, fork := range c2.Schedule {
+		blocks = append(blocks, fork.Block)
+	}
+	var conflict *big.Int
+	for _, block := range blocks {
+		num := new(big.Int).SetUint64(block)
+		if num.Cmp(head) > 0 || (conflict != nil && conflict.Cmp(num) <= 0) {
+			continue
+		}
+		e1, s1 := c1.epochAt(block)
+		e2, s2 := c2.epochAt(block)
+		if e1 != e2 || s1 != s2 || c1.PeriodAt(block) != c2.PeriodAt(block) {
+			conflict = num
+		}
+	}
+	return conflict
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
@@ -280,7 +671,7 @@ func (err *ConfigCompatError) Error() string {
 type Rules struct {
 	ChainId                                   *big.Int
 	IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
-	IsByzantium                               bool
+	IsByzantium, IsTypedTx                    bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -288,5 +679,5 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	if chainId == nil {
 		chainId = new(big.Int)
 	}
-	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num)}
+	return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: c.IsHomestead(num), IsEIP150: c.IsEIP150(num), IsEIP155: c.IsEIP155(num), IsEIP158: c.IsEIP158(num), IsByzantium: c.IsByzantium(num), IsTypedTx: c.IsTypedTx(num)}
 }
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -70,6 +72,51 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     9,
 			},
 		},
+		{
+			stored:  &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "a", Address: common.Address{0x10}, Block: big.NewInt(10)}}},
+			new:     &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "a", Address: common.Address{0x10}, Block: big.NewInt(20)}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "a", Address: common.Address{0x10}, Block: big.NewInt(10)}}},
+			new:    &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "a", Address: common.Address{0x10}, Block: big.NewInt(20)}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "precompile activation",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "a", Address: common.Address{0x10}, Block: big.NewInt(10)}}},
+			new:    &ChainConfig{Precompiles: []*PrecompileConfig{{Name: "b", Address: common.Address{0x10}, Block: big.NewInt(10)}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "precompile activation",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored:  &ChainConfig{},
+			new:     &ChainConfig{GasSponsor: &GasSponsorConfig{Sponsor: common.Address{0x01}, Block: big.NewInt(20), MaxGasPrice: big.NewInt(1), Senders: []common.Address{{0x02}}}},
+			head:    15,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{GasSponsor: &GasSponsorConfig{Sponsor: common.Address{0x01}, Block: big.NewInt(10), MaxGasPrice: big.NewInt(1), Senders: []common.Address{{0x02}}}},
+			new:    &ChainConfig{GasSponsor: &GasSponsorConfig{Sponsor: common.Address{0x01}, Block: big.NewInt(10), MaxGasPrice: big.NewInt(1), Senders: []common.Address{{0x03}}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "gas sponsorship",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -79,3 +126,101 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestCliqueSchedule(t *testing.T) {
+	period := uint64(2)
+	config := &CliqueConfig{
+		Period: 5,
+		Epoch:  10,
+		Schedule: []CliqueFork{
+			{Block: 25, Epoch: 4},
+			{Block: 40, Period: &period},
+		},
+	}
+	if err := config.CheckSchedule(); err != nil {
+		t.Fatalf("valid schedule rejected: %v", err)
+	}
+	checkpoints := map[uint64]bool{0: true, 10: true, 20: true, 24: false, 25: true, 29: true, 30: false, 33: true, 40: false, 41: true}
+	for number, want := range checkpoints {
+		if have := config.IsCheckpoint(number); have != want {
+			t.Errorf("block %d: checkpoint mismatch: have %v, want %v", number, have, want)
+		}
+	}
+	epochs := map[uint64]uint64{0: 10, 24: 10, 25: 4, 100: 4}
+	for number, want := range epochs {
+		if have := config.EpochAt(number); have != want {
+			t.Errorf("block %d: epoch mismatch: have %d, want %d", number, have, want)
+		}
+	}
+	periods := map[uint64]uint64{0: 5, 39: 5, 40: 2, 100: 2}
+	for number, want := range periods {
+		if have := config.PeriodAt(number); have != want {
+			t.Errorf("block %d: period mismatch: have %d, want %d", number, have, want)
+		}
+	}
+	invalid := [][]CliqueFork{
+		{{Block: 0, Epoch: 4}},
+		{{Block: 10, Epoch: 4}, {Block: 10, Epoch: 8}},
+		{{Block: 10}},
+	}
+	for i, schedule := range invalid {
+		if err := (&CliqueConfig{Epoch: 10, Schedule: schedule}).CheckSchedule(); err == nil {
+			t.Errorf("invalid schedule %d accepted", i)
+		}
+	}
+}
+
+func TestCliqueScheduleCompatible(t *testing.T) {
+	stored := &ChainConfig{Clique: &CliqueConfig{Epoch: 10, Schedule: []CliqueFork{{Block: 30, Epoch: 5}}}}
+
+	// Forks past the head may be added, moved or dropped
+	future := &ChainConfig{Clique: &CliqueConfig{Epoch: 10, Schedule: []CliqueFork{{Block: 50, Epoch: 8}}}}
+	if err := stored.CheckCompatible(future, 29); err != nil {
+		t.Errorf("future schedule change rejected: %v", err)
+	}
+	// Forks at or below the head may not
+	want := &ConfigCompatError{What: "clique schedule", StoredConfig: big.NewInt(30), NewConfig: big.NewInt(30), RewindTo: 29}
+	if err := stored.CheckCompatible(future, 40); !reflect.DeepEqual(err, want) {
+		t.Errorf("error mismatch: have %v, want %v", err, want)
+	}
+}
+
+func TestGasPayer(t *testing.T) {
+	var (
+		sponsor  = common.Address{0x01}
+		sender   = common.Address{0x02}
+		contract = common.Address{0x03}
+		other    = common.Address{0x04}
+	)
+	config := &ChainConfig{GasSponsor: &GasSponsorConfig{
+		Sponsor:     sponsor,
+		Block:       big.NewInt(10),
+		MaxGasPrice: big.NewInt(100),
+		Senders:     []common.Address{sender},
+		Contracts:   []common.Address{contract},
+	}}
+	tests := []struct {
+		num   int64
+		from  common.Address
+		to    *common.Address
+		price int64
+		want  common.Address
+	}{
+		{10, sender, &other, 1, sponsor},
+		{10, sender, nil, 1, sponsor},
+		{10, other, &contract, 1, sponsor},
+		{10, other, &contract, 100, sponsor},
+		{10, other, &contract, 101, other},
+		{10, other, nil, 1, other},
+		{10, other, &sender, 1, other},
+		{9, sender, &contract, 1, sender},
+	}
+	for i, tt := range tests {
+		if payer := config.GasPayer(big.NewInt(tt.num), tt.from, tt.to, big.NewInt(tt.price)); payer != tt.want {
+			t.Errorf("test %d: payer mismatch: have %x, want %x", i, payer, tt.want)
+		}
+	}
+	if payer := (&ChainConfig{}).GasPayer(big.NewInt(10), sender, &contract, big.NewInt(1)); payer != sender {
+		t.Errorf("payer without sponsorship mismatch: have %x, want %x", payer, sender)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errMissingCredentials = errors.New("missing credentials")
+	errInvalidCredentials = errors.New("invalid credentials")
+	errTokenExpired       = errors.New("token expired")
+)
+
+// APIKey is a credential granting access to the RPC API. Keys listing namespaces
+// may access all the APIs of exactly those namespaces, other keys the APIs the
+// endpoint exposes by default.
+type APIKey struct {
+	Name       string   `json:"name"`          // Owner of the key, unique among the keys
+	Key        string   `json:"key,omitempty"` // Secret presented by the clients
+	Namespaces []string `json:"namespaces"`
+}
+
+// GenerateAPIKey creates a random API key secret.
+func GenerateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
+
+// Auth authenticates HTTP and websocket RPC requests by API key, or by JSON Web
+// Token signed with HMAC SHA-256. Tokens restrict the namespaces accessible by
+// listing them in their "namespaces" claim, and expire with their "exp" claim.
+//
+// Requests are made on behalf of a tenant, the name of their API key or the
+// "sub" claim of their token, which services may retrieve with TenantFromContext
+// to account for the resources used by each of them.
+//
+// Credentials are presented as bearer tokens in the Authorization header, or in
+// the token query parameter for websocket clients unable to set headers.
+type Auth struct {
+	keys      map[string]*APIKey // API keys by secret
+	jwtSecret []byte
+	lock      sync.RWMutex
+}
+
+// NewAuth creates an authenticator accepting the given API keys, and JWTs
+// signed with jwtSecret unless empty.
+func NewAuth(keys []APIKey, jwtSecret []byte) (*Auth, error) {
+	a := &Auth{keys: make(map[string]*APIKey), jwtSecret: jwtSecret}
+	for _, key := range keys {
+		if err := a.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// AddKey starts accepting an API key.
+func (a *Auth) AddKey(key APIKey) error {
+	if key.Name == "" {
+		return errors.New("API key without name")
+	}
+	if len(key.Key) < 16 {
+		return fmt.Errorf("API key %q too short", key.Name)
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, ok := a.keys[key.Key]; ok {
+		return fmt.Errorf("API key %q already exists", key.Name)
+	}
+	for _, existing := range a.keys {
+		if existing.Name == key.Name {
+			return fmt.Errorf("API key %q already exists", key.Name)
+		}
+	}
+	key.Namespaces = append([]string(nil), key.Namespaces...)
+	a.keys[key.Key] = &key
+	return nil
+}
+
+// RemoveKey stops accepting the API key with the given name, and reports
+// whether it existed.
+func (a *Auth) RemoveKey(name string) bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for secret, key := range a.keys {
+		if key.Name == name {
+			delete(a.keys, secret)
+			return true
+		}
+	}
+	return false
+}
+
+// Keys returns the accepted API keys by name, without their secrets.
+func (a *Auth) Keys() []APIKey {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	keys := make([]APIKey, 0, len(a.keys))
+	for _, key := range a.keys {
+		keys = append(keys, APIKey{Name: key.Name, Namespaces: append([]string(nil), key.Namespaces...)})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Name < keys[j].Name })
+	return keys
+}
+
+// authenticate checks the credentials of a request, and returns the tenant they
+// belong to and the namespaces they grant access to, nil for the endpoint
+// defaults.
+func (a *Auth) authenticate(r *http.Request) (string, []string, error) {
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); header != "" {
+		if !strings.HasPrefix(header, "Bearer ") {
+			return "", nil, errInvalidCredentials
+		}
+		token = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	if token == "" {
+		return "", nil, errMissingCredentials
+	}
+	if strings.Count(token, ".") == 2 {
+		return a.verifyToken(token)
+	}
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	key, ok := a.keys[token]
+	if !ok {
+		return "", nil, errInvalidCredentials
+	}
+	if len(key.Namespaces) == 0 {
+		return key.Name, nil, nil
+	}
+	return key.Name, key.Namespaces, nil
+}
+
+// verifyToken checks the signature and expiry of a JSON Web Token, and returns
+// the tenant it was issued to and the namespaces it grants access to.
+func (a *Auth) verifyToken(token string) (string, []string, error) {
+	if len(a.jwtSecret) == 0 {
+		return "", nil, errInvalidCredentials
+	}
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if data, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(data, &header) != nil || header.Alg != "HS256" {
+		return "", nil, errInvalidCredentials
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, errInvalidCredentials
+	}
+	mac := hmac.New(sha256.New, a.jwtSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", nil, errInvalidCredentials
+	}
+	var claims struct {
+		Sub        string   `json:"sub"`
+		Exp        int64    `json:"exp"`
+		Namespaces []string `json:"namespaces"`
+	}
+	if data, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil || json.Unmarshal(data, &claims) != nil {
+		return "", nil, errInvalidCredentials
+	}
+	if claims.Exp != 0 && time.Now().Unix() >= claims.Exp {
+		return "", nil, errTokenExpired
+	}
+	if len(claims.Namespaces) == 0 {
+		return claims.Sub, nil, nil
+	}
+	return claims.Sub, claims.Namespaces, nil
+}
+
+// authHandler authenticates requests, serving the ones granted access to the
+// endpoint defaults by def, and the ones restricted to namespaces by scoped.
+type authHandler struct {
+	auth   *Auth
+	def    http.Handler
+	scoped http.Handler
+}
+
+// NewAuthHandler returns a handler rejecting requests without valid credentials.
+// Requests whose credentials grant access to the endpoint defaults are passed to
+// def, the ones restricted to namespaces to scoped, which should serve all the
+// APIs, and limits the requests to their namespaces.
+func NewAuthHandler(auth *Auth, def, scoped http.Handler) http.Handler {
+	return &authHandler{auth: auth, def: def, scoped: scoped}
+}
+
+// ServeHTTP authenticates the request before passing it on, implements http.Handler
+func (h *authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tenant, namespaces, err := h.auth.authenticate(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	ctx := r.Context()
+	if tenant != "" {
+		ctx = WithTenant(ctx, tenant)
+	}
+	if namespaces == nil {
+		h.def.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+	h.scoped.ServeHTTP(w, r.WithContext(withNamespaces(ctx, namespaces)))
+}
+
+// namespacesKey is used to store the namespaces accessible by a connection
+// within its context.
+type namespacesKey struct{}
+
+// withNamespaces restricts the requests served within ctx to namespaces.
+func withNamespaces(ctx context.Context, namespaces []string) context.Context {
+	allowed := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		allowed[namespace] = true
+	}
+	return context.WithValue(ctx, namespacesKey{}, allowed)
+}
+
+// namespaceAllowed reports whether the requests served within ctx may access
+// the given namespace. The metadata API is always accessible.
+func namespaceAllowed(ctx context.Context, namespace string) bool {
+	allowed, ok := ctx.Value(namespacesKey{}).(map[string]bool)
+	return !ok || allowed[namespace] || namespace == MetadataApi
+}
+
+// tenantKey is used to store the tenant requests are made on behalf of within
+// their context.
+type tenantKey struct{}
+
+// WithTenant attributes the requests served within ctx to the named tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant the requests served within ctx are made
+// on behalf of, if they were authenticated as one.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signToken creates an HS256 JSON Web Token carrying claims.
+func signToken(secret []byte, claims interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, _ := json.Marshal(claims)
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthHandler(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	auth, err := NewAuth([]APIKey{
+		{Name: "default", Key: "default-key-0123456789"},
+		{Name: "scoped", Key: "scoped-key-0123456789", Namespaces: []string{"other"}},
+	}, secret)
+	if err != nil {
+		t.Fatalf("failed to create authenticator: %v", err)
+	}
+	// The default server exposes test, the scoped one test and other
+	def := newTestServer("test", new(Service))
+	scoped := newTestServer("test", new(Service))
+	if err := scoped.RegisterName("other", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	hs := httptest.NewServer(NewAuthHandler(auth, def, scoped))
+	defer hs.Close()
+
+	call := func(token, method string) (int, *jsonrpcMessage) {
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":[]}`
+		req, _ := http.NewRequest(http.MethodPost, hs.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		msg := new(jsonrpcMessage)
+		if err := json.NewDecoder(resp.Body).Decode(msg); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		return resp.StatusCode, msg
+	}
+	tests := []struct {
+		token, method string
+		status        int
+		ok            bool
+	}{
+		{"", "test_rets", http.StatusUnauthorized, false},
+		{"wrong-key-0123456789", "test_rets", http.StatusUnauthorized, false},
+		{"Basic default-key-0123456789", "test_rets", http.StatusUnauthorized, false},
+		// Keys without namespaces access the default server
+		{"default-key-0123456789", "test_rets", http.StatusOK, true},
+		{"default-key-0123456789", "other_rets", http.StatusOK, false},
+		// Keys with namespaces access exactly those
+		{"scoped-key-0123456789", "other_rets", http.StatusOK, true},
+		{"scoped-key-0123456789", "test_rets", http.StatusOK, false},
+		// Tokens likewise, until expired
+		{signToken(secret, map[string]interface{}{"namespaces": []string{"test"}}), "test_rets", http.StatusOK, true},
+		{signToken(secret, map[string]interface{}{"namespaces": []string{"test"}}), "other_rets", http.StatusOK, false},
+		{signToken(secret, map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()}), "test_rets", http.StatusOK, true},
+		{signToken(secret, map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}), "test_rets", http.StatusUnauthorized, false},
+		{signToken([]byte("wrong secret"), map[string]interface{}{}), "test_rets", http.StatusUnauthorized, false},
+	}
+	for i, test := range tests {
+		status, msg := call(test.token, test.method)
+		if status != test.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, status, test.status)
+			continue
+		}
+		if msg != nil && (msg.Error == nil) != test.ok {
+			t.Errorf("test %d: call to %s: have error %v, want success %v", i, test.method, msg.Error, test.ok)
+		}
+	}
+	// Revoked keys must be rejected
+	if !auth.RemoveKey("scoped") {
+		t.Fatal("failed to remove key")
+	}
+	if status, _ := call("scoped-key-0123456789", "other_rets"); status != http.StatusUnauthorized {
+		t.Errorf("revoked key status mismatch: have %d", status)
+	}
+}
+
+func TestAuthTenant(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	auth, _ := NewAuth([]APIKey{{Name: "alice", Key: "alice-key-0123456789"}}, secret)
+
+	tests := []struct {
+		token, tenant string
+	}{
+		{"alice-key-0123456789", "alice"},
+		{signToken(secret, map[string]interface{}{"sub": "bob", "namespaces": []string{"eth"}}), "bob"},
+		{signToken(secret, map[string]interface{}{}), ""},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+test.token)
+		tenant, _, err := auth.authenticate(req)
+		if err != nil {
+			t.Fatalf("test %d: failed to authenticate: %v", i, err)
+		}
+		if tenant != test.tenant {
+			t.Errorf("test %d: tenant mismatch: have %q, want %q", i, tenant, test.tenant)
+		}
+	}
+	if tenant, ok := TenantFromContext(WithTenant(context.Background(), "alice")); !ok || tenant != "alice" {
+		t.Errorf("context tenant mismatch: have %q/%v, want alice", tenant, ok)
+	}
+	if _, ok := TenantFromContext(context.Background()); ok {
+		t.Error("tenant found in plain context")
+	}
+}
+
+func TestAuthKeys(t *testing.T) {
+	auth, _ := NewAuth(nil, nil)
+	if err := auth.AddKey(APIKey{Name: "a", Key: "short"}); err == nil {
+		t.Error("short key accepted")
+	}
+	if err := auth.AddKey(APIKey{Name: "b", Key: "0123456789abcdef", Namespaces: []string{"eth"}}); err != nil {
+		t.Fatalf("failed to add key: %v", err)
+	}
+	if err := auth.AddKey(APIKey{Name: "b", Key: "fedcba9876543210"}); err == nil {
+		t.Error("duplicate key name accepted")
+	}
+	keys := auth.Keys()
+	if len(keys) != 1 || keys[0].Name != "b" || keys[0].Key != "" || len(keys[0].Namespaces) != 1 {
+		t.Errorf("keys mismatch: %+v", keys)
+	}
+}
+
+func TestModulesWithNamespaces(t *testing.T) {
+	server := newTestServer("test", new(Service))
+	if err := server.RegisterName("other", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	rpcService := &RPCService{server}
+	if modules := rpcService.Modules(context.Background()); len(modules) != 3 {
+		t.Errorf("unrestricted modules mismatch: %v", modules)
+	}
+	modules := rpcService.Modules(withNamespaces(context.Background(), []string{"other"}))
+	if len(modules) != 2 || modules["other"] == "" || modules[MetadataApi] == "" {
+		t.Errorf("restricted modules mismatch: %v", modules)
+	}
+}
@@ -64,3 +64,12 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when the credentials of a request don't grant access to the service.
+type namespaceNotAllowedError struct{ service string }
+
+func (e *namespaceNotAllowedError) ErrorCode() int { return -32601 }
+
+func (e *namespaceNotAllowedError) Error() string {
+	return fmt.Sprintf("access to the %s namespace is not allowed", e.service)
+}
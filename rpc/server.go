@@ -48,6 +48,7 @@ func NewServer() *Server {
 	server := &Server{
 		services: make(serviceRegistry),
 		codecs:   make(map[ServerCodec]struct{}),
+		wsConns:  make(map[*wsConn]struct{}),
 		run:      1,
 	}
 
@@ -67,11 +68,14 @@ type RPCService struct {
 	server *Server
 }
 
-// Modules returns the list of RPC services with their version number
-func (s *RPCService) Modules() map[string]string {
+// Modules returns the list of RPC services accessible to the caller with their
+// version number
+func (s *RPCService) Modules(ctx context.Context) map[string]string {
 	modules := make(map[string]string)
 	for name := range s.server.services {
-		modules[name] = "1.0"
+		if namespaceAllowed(ctx, name) {
+			modules[name] = "1.0"
+		}
 	}
 	return modules
 }
@@ -223,8 +227,13 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec, options)
+}
+
+// serveCodec is ServeCodec serving the requests within ctx.
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	if err := s.serveRequest(context.Background(), codec, false, options); err != nil {
+	if err := s.serveRequest(ctx, codec, false, options); err != nil {
 		log.Error("Cannot serve codec request", "err", err)
 	}
 }
@@ -288,6 +297,10 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
 	}
 
+	if !namespaceAllowed(ctx, req.svcname) {
+		return codec.CreateErrorResponse(&req.id, &namespaceNotAllowedError{req.svcname}), nil
+	}
+
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
@@ -328,6 +341,13 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
+			// Preserve the code of errors which carry their own
+			if rpcErr, ok := e.(Error); ok {
+				if dataErr, ok := e.(DataError); ok {
+					return codec.CreateErrorResponseWithInfo(&req.id, rpcErr, dataErr.ErrorData()), nil
+				}
+				return codec.CreateErrorResponse(&req.id, rpcErr), nil
+			}
 			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
 			return res, nil
 		}
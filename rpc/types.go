@@ -17,12 +17,14 @@
 package rpc
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 	"reflect"
 	"strings"
 	"sync"
 
+	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/hexutil"
 )
 
@@ -73,6 +75,7 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   map[ServerCodec]struct{}
+	wsConns  map[*wsConn]struct{}
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -91,6 +94,13 @@ type Error interface {
 	ErrorCode() int // returns the code
 }
 
+// DataError is an Error which carries additional data, sent in the data field
+// of the JSON-RPC error response.
+type DataError interface {
+	Error() string          // returns the message
+	ErrorData() interface{} // returns the error data
+}
+
 // ServerCodec implements reading, parsing and writing RPC messages for the server side of
 // a RPC session. Implementations must be go-routine safe since the codec can be called in
 // multiple go-routines concurrently.
@@ -118,13 +128,15 @@ type ServerCodec interface {
 type BlockNumber int64
 
 const (
-	PendingBlockNumber  = BlockNumber(-2)
-	LatestBlockNumber   = BlockNumber(-1)
-	EarliestBlockNumber = BlockNumber(0)
+	FinalizedBlockNumber = BlockNumber(-4)
+	SafeBlockNumber      = BlockNumber(-3)
+	PendingBlockNumber   = BlockNumber(-2)
+	LatestBlockNumber    = BlockNumber(-1)
+	EarliestBlockNumber  = BlockNumber(0)
 )
 
 // UnmarshalJSON parses the given JSON fragment into a BlockNumber. It supports:
-// - "latest", "earliest" or "pending" as string arguments
+// - "latest", "earliest", "pending", "safe" or "finalized" as string arguments
 // - the block number
 // Returned errors:
 // - an invalid block number error when the given argument isn't a known strings
@@ -145,6 +157,12 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 	case "pending":
 		*bn = PendingBlockNumber
 		return nil
+	case "safe":
+		*bn = SafeBlockNumber
+		return nil
+	case "finalized":
+		*bn = FinalizedBlockNumber
+		return nil
 	}
 
 	blckNum, err := hexutil.DecodeUint64(input)
@@ -162,3 +180,82 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 func (bn BlockNumber) Int64() int64 {
 	return (int64)(bn)
 }
+
+// BlockNumberOrHash selects a block either by number, like BlockNumber, or by
+// hash. Selecting by hash pins the request to that exact block, so that reads
+// stay consistent while the chain reorganizes. If RequireCanonical is set, the
+// request fails unless the block is still part of the canonical chain.
+type BlockNumberOrHash struct {
+	BlockNumber      *BlockNumber `json:"blockNumber,omitempty"`
+	BlockHash        *common.Hash `json:"blockHash,omitempty"`
+	RequireCanonical bool         `json:"requireCanonical,omitempty"`
+}
+
+// UnmarshalJSON parses the given JSON fragment into a BlockNumberOrHash. It
+// supports:
+//   - everything BlockNumber supports
+//   - a 32 byte block hash
+//   - an object with either a "blockNumber" or a "blockHash" field, the latter
+//     optionally accompanied by a "requireCanonical" flag
+func (bnh *BlockNumberOrHash) UnmarshalJSON(data []byte) error {
+	type erased BlockNumberOrHash
+	var obj erased
+	if err := json.Unmarshal(data, &obj); err == nil {
+		if obj.BlockNumber != nil && obj.BlockHash != nil {
+			return fmt.Errorf("cannot specify both blockNumber and blockHash")
+		}
+		if obj.BlockNumber == nil && obj.BlockHash == nil {
+			return fmt.Errorf("either blockNumber or blockHash must be specified")
+		}
+		if obj.BlockNumber != nil && obj.RequireCanonical {
+			return fmt.Errorf("requireCanonical is only allowed with blockHash")
+		}
+		*bnh = BlockNumberOrHash(obj)
+		return nil
+	}
+	var input string
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+	if len(input) == 2+2*common.HashLength {
+		var hash common.Hash
+		if err := hash.UnmarshalText([]byte(input)); err != nil {
+			return err
+		}
+		*bnh = BlockNumberOrHash{BlockHash: &hash}
+		return nil
+	}
+	var number BlockNumber
+	if err := number.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*bnh = BlockNumberOrHash{BlockNumber: &number}
+	return nil
+}
+
+// Number returns the selected block number, if the block is selected by number.
+func (bnh BlockNumberOrHash) Number() (BlockNumber, bool) {
+	if bnh.BlockNumber != nil {
+		return *bnh.BlockNumber, true
+	}
+	return BlockNumber(0), false
+}
+
+// Hash returns the selected block hash, if the block is selected by hash.
+func (bnh BlockNumberOrHash) Hash() (common.Hash, bool) {
+	if bnh.BlockHash != nil {
+		return *bnh.BlockHash, true
+	}
+	return common.Hash{}, false
+}
+
+// BlockNumberOrHashWithNumber selects the block with the given number.
+func BlockNumberOrHashWithNumber(blockNr BlockNumber) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockNumber: &blockNr}
+}
+
+// BlockNumberOrHashWithHash selects the block with the given hash, optionally
+// requiring it to be canonical.
+func BlockNumberOrHashWithHash(hash common.Hash, canonical bool) BlockNumberOrHash {
+	return BlockNumberOrHash{BlockHash: &hash, RequireCanonical: canonical}
+}
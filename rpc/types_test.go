@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/common/math"
 )
 
@@ -46,6 +47,8 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		14: {`someString`, true, BlockNumber(0)},
 		15: {`""`, true, BlockNumber(0)},
 		16: {``, true, BlockNumber(0)},
+		17: {`"safe"`, false, SafeBlockNumber},
+		18: {`"finalized"`, false, FinalizedBlockNumber},
 	}
 
 	for i, test := range tests {
@@ -64,3 +67,55 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberOrHashJSONUnmarshal(t *testing.T) {
+	hash := common.HexToHash("0x1d9e8f6a1b2c3d4e5f60718293a4b5c6d7e8f9001122334455667788990aabbc")
+	number := func(n BlockNumber) BlockNumberOrHash { return BlockNumberOrHashWithNumber(n) }
+
+	tests := []struct {
+		input    string
+		mustFail bool
+		expected BlockNumberOrHash
+	}{
+		0:  {`"0x1"`, false, number(1)},
+		1:  {`"latest"`, false, number(LatestBlockNumber)},
+		2:  {`"pending"`, false, number(PendingBlockNumber)},
+		3:  {`"0x01"`, true, BlockNumberOrHash{}},
+		4:  {`"` + hash.Hex() + `"`, false, BlockNumberOrHashWithHash(hash, false)},
+		5:  {`{"blockNumber":"0x2"}`, false, number(2)},
+		6:  {`{"blockNumber":"earliest"}`, false, number(EarliestBlockNumber)},
+		7:  {`{"blockHash":"` + hash.Hex() + `"}`, false, BlockNumberOrHashWithHash(hash, false)},
+		8:  {`{"blockHash":"` + hash.Hex() + `","requireCanonical":true}`, false, BlockNumberOrHashWithHash(hash, true)},
+		9:  {`{"blockNumber":"0x2","blockHash":"` + hash.Hex() + `"}`, true, BlockNumberOrHash{}},
+		10: {`{"blockNumber":"0x2","requireCanonical":true}`, true, BlockNumberOrHash{}},
+		11: {`{}`, true, BlockNumberOrHash{}},
+		12: {`{"blockHash":"0x1234"}`, true, BlockNumberOrHash{}},
+		13: {`0`, true, BlockNumberOrHash{}},
+		14: {`"safe"`, false, number(SafeBlockNumber)},
+		15: {`{"blockNumber":"finalized"}`, false, number(FinalizedBlockNumber)},
+	}
+	for i, test := range tests {
+		var bnh BlockNumberOrHash
+		err := json.Unmarshal([]byte(test.input), &bnh)
+		if test.mustFail && err == nil {
+			t.Errorf("Test %d should fail", i)
+			continue
+		}
+		if !test.mustFail && err != nil {
+			t.Errorf("Test %d should pass but got err: %v", i, err)
+			continue
+		}
+		if test.mustFail {
+			continue
+		}
+		if have, ok := bnh.Number(); ok != (test.expected.BlockNumber != nil) || (ok && have != *test.expected.BlockNumber) {
+			t.Errorf("Test %d got unexpected number: %v", i, bnh.BlockNumber)
+		}
+		if have, ok := bnh.Hash(); ok != (test.expected.BlockHash != nil) || (ok && have != *test.expected.BlockHash) {
+			t.Errorf("Test %d got unexpected hash: %v", i, bnh.BlockHash)
+		}
+		if bnh.RequireCanonical != test.expected.RequireCanonical {
+			t.Errorf("Test %d got unexpected requireCanonical: %v", i, bnh.RequireCanonical)
+		}
+	}
+}
@@ -36,12 +36,73 @@ import (
 // allowedOrigins should be a comma-separated list of allowed origin URLs.
 // To allow connections with any origin, pass "*".
 func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
-	return websocket.Server{
-		Handshake: wsHandshakeValidator(allowedOrigins),
-		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
-		},
+	return srv.WebsocketHandlerWithOptions(allowedOrigins, DefaultWebsocketOptions)
+}
+
+// WebsocketHandlerWithOptions returns a handler that serves JSON-RPC to WebSocket
+// connections, negotiating compression and limiting the received payloads as
+// configured by opts. Zero limits fall back to the defaults.
+func (srv *Server) WebsocketHandlerWithOptions(allowedOrigins []string, opts WebsocketOptions) http.Handler {
+	if opts.MaxFrameSize <= 0 {
+		opts.MaxFrameSize = DefaultWebsocketOptions.MaxFrameSize
+	}
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = DefaultWebsocketOptions.MaxMessageSize
+	}
+	validate := wsHandshakeValidator(allowedOrigins)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := validate(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		conn, err := upgradeWebsocket(w, r, opts)
+		if err != nil {
+			log.Debug("WebSocket handshake failed", "remote", r.RemoteAddr, "err", err)
+			return
+		}
+		srv.trackWebsocket(conn, true)
+		defer func() {
+			srv.trackWebsocket(conn, false)
+			stats := conn.stats()
+			log.Debug("WebSocket connection closed", "remote", stats.Remote, "compressed", stats.Compressed,
+				"sent", stats.Sent, "wire", stats.Wire, "received", stats.Received)
+		}()
+		// Carry over the tenant and namespaces the connection was authenticated for
+		ctx := context.Background()
+		if allowed := r.Context().Value(namespacesKey{}); allowed != nil {
+			ctx = context.WithValue(ctx, namespacesKey{}, allowed)
+		}
+		if tenant, ok := TenantFromContext(r.Context()); ok {
+			ctx = WithTenant(ctx, tenant)
+		}
+		srv.serveCodec(ctx, NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+	})
+}
+
+// trackWebsocket adds or removes a connection from the set reported by
+// WebsocketStats.
+func (srv *Server) trackWebsocket(conn *wsConn, add bool) {
+	srv.codecsMu.Lock()
+	defer srv.codecsMu.Unlock()
+
+	if add {
+		srv.wsConns[conn] = struct{}{}
+	} else {
+		delete(srv.wsConns, conn)
+	}
+}
+
+// WebsocketStats returns the traffic counters of the open websocket connections.
+func (srv *Server) WebsocketStats() []WebsocketStats {
+	srv.codecsMu.Lock()
+	defer srv.codecsMu.Unlock()
+
+	stats := make([]WebsocketStats, 0, len(srv.wsConns))
+	for conn := range srv.wsConns {
+		stats = append(stats, conn.stats())
 	}
+	return stats
 }
 
 // NewWSServer creates a new websocket RPC server around an API provider.
@@ -54,7 +115,7 @@ func NewWSServer(allowedOrigins []string, srv *Server) *http.Server {
 // wsHandshakeValidator returns a handler that verifies the origin during the
 // websocket upgrade process. When a '*' is specified as an allowed origins all
 // connections are accepted.
-func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http.Request) error {
+func wsHandshakeValidator(allowedOrigins []string) func(*http.Request) error {
 	origins := make(map[string]struct{})
 	allowAllOrigins := false
 
@@ -77,7 +138,7 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 
 	log.Debug(fmt.Sprintf("Allowed origin(s) for WS RPC interface %v\n", origins))
 
-	f := func(cfg *websocket.Config, req *http.Request) error {
+	f := func(req *http.Request) error {
 		origin := strings.ToLower(req.Header.Get("Origin"))
 		if allowAllOrigins {
 			return nil
@@ -0,0 +1,405 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+const (
+	// wsGUID is the magic string hashed with the client key to accept a
+	// websocket handshake (RFC 6455, section 1.3).
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	// wsCompressThreshold is the size below which messages are sent
+	// uncompressed, as the deflate framing would outweigh the savings.
+	wsCompressThreshold = 128
+
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+
+	wsCloseNormal          = 1000
+	wsCloseProtocolError   = 1002
+	wsCloseMessageTooLarge = 1009
+)
+
+var (
+	// wsDeflateTail terminates a compressed message with the empty stored block
+	// stripped by the sender, followed by a final empty block to end the stream.
+	wsDeflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+	wsSentMeter       = metrics.NewMeter("rpc/ws/sent")       // Bytes of the messages sent
+	wsCompressedMeter = metrics.NewMeter("rpc/ws/compressed") // Bytes of the sent messages on the wire
+	wsReceivedMeter   = metrics.NewMeter("rpc/ws/received")   // Bytes of the messages received
+
+	errWSProtocol        = errors.New("websocket protocol violation")
+	errWSMessageTooLarge = errors.New("websocket message too large")
+)
+
+// WebsocketOptions configures the websocket RPC server.
+type WebsocketOptions struct {
+	Compression    bool  // Negotiate permessage-deflate with the clients offering it
+	MaxFrameSize   int64 // Maximum payload size of a received frame
+	MaxMessageSize int64 // Maximum size of a received message, once decompressed
+}
+
+// DefaultWebsocketOptions are the websocket server options used by default.
+var DefaultWebsocketOptions = WebsocketOptions{
+	MaxFrameSize:   32 * 1024 * 1024,
+	MaxMessageSize: 32 * 1024 * 1024,
+}
+
+// WebsocketStats are the traffic counters of a websocket connection.
+type WebsocketStats struct {
+	Remote     string `json:"remote"`
+	Compressed bool   `json:"compressed"` // Whether permessage-deflate was negotiated
+	Sent       uint64 `json:"sent"`       // Bytes of the messages sent
+	Wire       uint64 `json:"wire"`       // Bytes of the sent messages on the wire, after compression
+	Received   uint64 `json:"received"`   // Bytes of the messages received, after decompression
+}
+
+// wsConn is the server side of a websocket connection, exchanging every JSON
+// message as a single text message, compressed with permessage-deflate if the
+// client negotiated it. Neither side keeps the compression context between
+// messages.
+type wsConn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	opts    WebsocketOptions
+	deflate bool // Whether permessage-deflate was negotiated
+
+	msg bytes.Reader // Unread part of the last received message
+
+	wlock sync.Mutex // Protects the writer fields and serializes frames
+	fw    *flate.Writer
+	fbuf  bytes.Buffer
+
+	sent, wire, received uint64 // Traffic counters, atomically accessed
+	closed               int32
+}
+
+// upgradeWebsocket completes the websocket handshake of the request, returning
+// the established connection. On failure an HTTP error is replied.
+func upgradeWebsocket(w http.ResponseWriter, r *http.Request, opts WebsocketOptions) (*wsConn, error) {
+	fail := func(status int, err error) (*wsConn, error) {
+		http.Error(w, err.Error(), status)
+		return nil, err
+	}
+	if r.Method != http.MethodGet {
+		return fail(http.StatusMethodNotAllowed, errors.New("websocket handshake requires GET"))
+	}
+	if !headerHasToken(r.Header, "Connection", "upgrade") || !headerHasToken(r.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, errors.New("not a websocket handshake"))
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return fail(http.StatusBadRequest, errors.New("unsupported websocket version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return fail(http.StatusBadRequest, errors.New("websocket key missing"))
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return fail(http.StatusInternalServerError, errors.New("connection can't be hijacked"))
+	}
+	deflate := opts.Compression && offersDeflate(r.Header)
+
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	accept := sha1.Sum([]byte(key + wsGUID))
+
+	var resp bytes.Buffer
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	fmt.Fprintf(&resp, "Sec-WebSocket-Accept: %s\r\n", base64.StdEncoding.EncodeToString(accept[:]))
+	if deflate {
+		resp.WriteString("Sec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover\r\n")
+	}
+	resp.WriteString("\r\n")
+	if _, err := conn.Write(resp.Bytes()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &wsConn{conn: conn, br: brw.Reader, opts: opts, deflate: deflate}
+	if deflate {
+		c.fw, _ = flate.NewWriter(nil, flate.DefaultCompression)
+	}
+	return c, nil
+}
+
+// headerHasToken reports whether the comma separated values of a header
+// contain the token, case insensitively.
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header[name] {
+		for _, t := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// offersDeflate reports whether the client offers a permessage-deflate
+// configuration the server can honor. Offers limiting the server window below
+// the full 32KB can't be, as the flate package always uses the full window.
+func offersDeflate(header http.Header) bool {
+	for _, value := range header["Sec-Websocket-Extensions"] {
+		for _, offer := range strings.Split(value, ",") {
+			params := strings.Split(offer, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+			ok := true
+			for _, param := range params[1:] {
+				param = strings.TrimSpace(param)
+				if strings.HasPrefix(param, "server_max_window_bits") && strings.TrimSpace(strings.TrimPrefix(param, "server_max_window_bits")) != "=15" {
+					ok = false
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Read implements io.Reader, reading from the payload of the received messages.
+func (c *wsConn) Read(p []byte) (int, error) {
+	for c.msg.Len() == 0 {
+		msg, err := c.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		c.msg.Reset(msg)
+	}
+	return c.msg.Read(p)
+}
+
+// readMessage reads the next data message, answering the control frames
+// interleaved with it.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var (
+		msg        []byte
+		started    bool
+		compressed bool
+	)
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.br, head[:]); err != nil {
+			return nil, err
+		}
+		var (
+			fin    = head[0]&0x80 != 0
+			rsv1   = head[0]&0x40 != 0
+			opcode = head[0] & 0x0f
+			masked = head[1]&0x80 != 0
+			length = int64(head[1] & 0x7f)
+		)
+		if head[0]&0x30 != 0 || (rsv1 && (!c.deflate || opcode == wsOpContinuation || opcode >= wsOpClose)) || !masked {
+			return nil, c.fail(wsCloseProtocolError, errWSProtocol)
+		}
+		switch length {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return nil, err
+			}
+			length = int64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+				return nil, err
+			}
+			length = int64(binary.BigEndian.Uint64(ext[:]) & (1<<63 - 1))
+		}
+		if opcode >= wsOpClose && (!fin || length > 125) {
+			return nil, c.fail(wsCloseProtocolError, errWSProtocol)
+		}
+		if length > c.opts.MaxFrameSize || int64(len(msg))+length > c.opts.MaxMessageSize {
+			return nil, c.fail(wsCloseMessageTooLarge, errWSMessageTooLarge)
+		}
+		var mask [4]byte
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return nil, err
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return nil, err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload, false); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			c.close(wsCloseNormal)
+			return nil, io.EOF
+		case wsOpText, wsOpBinary:
+			if started {
+				return nil, c.fail(wsCloseProtocolError, errWSProtocol)
+			}
+			started, compressed = true, rsv1
+		case wsOpContinuation:
+			if !started {
+				return nil, c.fail(wsCloseProtocolError, errWSProtocol)
+			}
+		default:
+			return nil, c.fail(wsCloseProtocolError, errWSProtocol)
+		}
+		msg = append(msg, payload...)
+		if fin {
+			break
+		}
+	}
+	if compressed {
+		fr := flate.NewReader(io.MultiReader(bytes.NewReader(msg), bytes.NewReader(wsDeflateTail)))
+		inflated, err := ioutil.ReadAll(io.LimitReader(fr, c.opts.MaxMessageSize+1))
+		fr.Close()
+		if err != nil {
+			return nil, c.fail(wsCloseProtocolError, err)
+		}
+		if int64(len(inflated)) > c.opts.MaxMessageSize {
+			return nil, c.fail(wsCloseMessageTooLarge, errWSMessageTooLarge)
+		}
+		msg = inflated
+	}
+	atomic.AddUint64(&c.received, uint64(len(msg)))
+	wsReceivedMeter.Mark(int64(len(msg)))
+	return msg, nil
+}
+
+// Write implements io.Writer, sending p as a single text message.
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.wlock.Lock()
+	defer c.wlock.Unlock()
+
+	payload, compressed := p, false
+	if c.deflate && len(p) >= wsCompressThreshold {
+		c.fbuf.Reset()
+		c.fw.Reset(&c.fbuf)
+		if _, err := c.fw.Write(p); err != nil {
+			return 0, err
+		}
+		if err := c.fw.Flush(); err != nil {
+			return 0, err
+		}
+		// Strip the empty stored block ending the flush, the receiver adds it back
+		payload, compressed = bytes.TrimSuffix(c.fbuf.Bytes(), wsDeflateTail[:4]), true
+	}
+	if err := c.writeFrameLocked(wsOpText, payload, compressed); err != nil {
+		return 0, err
+	}
+	atomic.AddUint64(&c.sent, uint64(len(p)))
+	atomic.AddUint64(&c.wire, uint64(len(payload)))
+	wsSentMeter.Mark(int64(len(p)))
+	wsCompressedMeter.Mark(int64(len(payload)))
+	return len(p), nil
+}
+
+// writeFrame sends a single unmasked frame.
+func (c *wsConn) writeFrame(opcode byte, payload []byte, compressed bool) error {
+	c.wlock.Lock()
+	defer c.wlock.Unlock()
+
+	return c.writeFrameLocked(opcode, payload, compressed)
+}
+
+func (c *wsConn) writeFrameLocked(opcode byte, payload []byte, compressed bool) error {
+	frame := make([]byte, 0, 10+len(payload))
+
+	head := 0x80 | opcode
+	if compressed {
+		head |= 0x40
+	}
+	frame = append(frame, head)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[len(frame)-8:], uint64(n))
+	}
+	_, err := c.conn.Write(append(frame, payload...))
+	return err
+}
+
+// fail closes the connection with the given status code, returning err.
+func (c *wsConn) fail(code uint16, err error) error {
+	c.close(code)
+	return err
+}
+
+// close sends a close frame with the given status code and closes the
+// connection, once.
+func (c *wsConn) close(code uint16) error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return nil
+	}
+	var status [2]byte
+	binary.BigEndian.PutUint16(status[:], code)
+	c.writeFrame(wsOpClose, status[:], false)
+
+	return c.conn.Close()
+}
+
+// Close implements io.Closer, closing the connection normally.
+func (c *wsConn) Close() error {
+	return c.close(wsCloseNormal)
+}
+
+// stats returns the traffic counters of the connection.
+func (c *wsConn) stats() WebsocketStats {
+	return WebsocketStats{
+		Remote:     c.conn.RemoteAddr().String(),
+		Compressed: c.deflate,
+		Sent:       atomic.LoadUint64(&c.sent),
+		Wire:       atomic.LoadUint64(&c.wire),
+		Received:   atomic.LoadUint64(&c.received),
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// wsTestDial performs a websocket handshake over a raw connection, offering
+// the given extensions.
+func wsTestDial(t *testing.T, hs *httptest.Server, extensions string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", hs.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	req := "GET / HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	if extensions != "" {
+		req += "Sec-WebSocket-Extensions: " + extensions + "\r\n"
+	}
+	if _, err := io.WriteString(conn, req+"\r\n"); err != nil {
+		t.Fatalf("failed to send handshake: %v", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("failed to read handshake: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status mismatch: have %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if have, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; have != want {
+		t.Fatalf("accept key mismatch: have %s, want %s", have, want)
+	}
+	return conn, br, resp
+}
+
+// wsTestWrite sends a single masked frame, deflating the payload if requested.
+func wsTestWrite(t *testing.T, conn net.Conn, opcode byte, payload []byte, compress bool) {
+	head := 0x80 | opcode
+	if compress {
+		var buf bytes.Buffer
+		fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+		fw.Write(payload)
+		fw.Flush()
+		payload = bytes.TrimSuffix(buf.Bytes(), []byte{0x00, 0x00, 0xff, 0xff})
+		head |= 0x40
+	}
+	frame := []byte{head}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	default:
+		frame = append(frame, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[len(frame)-8:], uint64(n))
+	}
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+}
+
+// wsTestRead reads a single unmasked frame, returning its first header byte
+// and the payload.
+func wsTestRead(t *testing.T, br *bufio.Reader) (byte, []byte) {
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	length := uint64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(br, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(br, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("failed to read payload: %v", err)
+	}
+	return head[0], payload
+}
+
+func TestWebsocketCompression(t *testing.T) {
+	srv := newTestServer("service", new(Service))
+	defer srv.Stop()
+	hs := httptest.NewServer(srv.WebsocketHandlerWithOptions([]string{"*"}, WebsocketOptions{Compression: true}))
+	defer hs.Close()
+
+	conn, br, resp := wsTestDial(t, hs, "permessage-deflate; client_max_window_bits")
+	defer conn.Close()
+
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); !strings.HasPrefix(ext, "permessage-deflate") {
+		t.Fatalf("compression not negotiated: %q", ext)
+	}
+	text := strings.Repeat("indigo", 100)
+	wsTestWrite(t, conn, wsOpText, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"service_echo","params":[%q,1,null]}`, text)), true)
+
+	head, payload := wsTestRead(t, br)
+	if head != 0x80|0x40|wsOpText {
+		t.Fatalf("frame header mismatch: have %#x, want %#x", head, 0x80|0x40|wsOpText)
+	}
+	inflated, err := ioutil.ReadAll(flate.NewReader(io.MultiReader(bytes.NewReader(payload), bytes.NewReader(wsDeflateTail))))
+	if err != nil {
+		t.Fatalf("failed to inflate response: %v", err)
+	}
+	var result struct {
+		Result Result `json:"result"`
+	}
+	if err := json.Unmarshal(inflated, &result); err != nil {
+		t.Fatalf("failed to decode response %q: %v", inflated, err)
+	}
+	if result.Result.String != text {
+		t.Fatalf("echo mismatch: have %q, want %q", result.Result.String, text)
+	}
+	stats := srv.WebsocketStats()
+	if len(stats) != 1 {
+		t.Fatalf("connection count mismatch: have %d, want 1", len(stats))
+	}
+	if !stats[0].Compressed || stats[0].Sent != uint64(len(inflated)) || stats[0].Wire != uint64(len(payload)) {
+		t.Fatalf("stats mismatch: have %+v, want sent %d, wire %d", stats[0], len(inflated), len(payload))
+	}
+	if stats[0].Wire >= stats[0].Sent {
+		t.Fatalf("response not compressed: %d wire bytes for %d sent", stats[0].Wire, stats[0].Sent)
+	}
+}
+
+func TestWebsocketCompressionDisabled(t *testing.T) {
+	srv := newTestServer("service", new(Service))
+	defer srv.Stop()
+	hs := httptest.NewServer(srv.WebsocketHandler([]string{"*"}))
+	defer hs.Close()
+
+	conn, br, resp := wsTestDial(t, hs, "permessage-deflate")
+	defer conn.Close()
+
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "" {
+		t.Fatalf("unexpected extension negotiated: %q", ext)
+	}
+	text := strings.Repeat("indigo", 100)
+	wsTestWrite(t, conn, wsOpText, []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"service_echo","params":[%q,1,null]}`, text)), false)
+
+	head, payload := wsTestRead(t, br)
+	if head != 0x80|wsOpText {
+		t.Fatalf("frame header mismatch: have %#x, want %#x", head, 0x80|wsOpText)
+	}
+	if !bytes.Contains(payload, []byte(text)) {
+		t.Fatalf("echo missing from response %q", payload)
+	}
+}
+
+func TestWebsocketLimits(t *testing.T) {
+	tests := []struct {
+		opts WebsocketOptions
+		send func(net.Conn)
+	}{
+		// Single frame over the frame limit
+		{
+			opts: WebsocketOptions{MaxFrameSize: 64, MaxMessageSize: 1024},
+			send: func(conn net.Conn) {
+				wsTestWrite(t, conn, wsOpText, bytes.Repeat([]byte{' '}, 100), false)
+			},
+		},
+		// Fragments within the frame limit, but over the message limit
+		{
+			opts: WebsocketOptions{MaxFrameSize: 64, MaxMessageSize: 100},
+			send: func(conn net.Conn) {
+				conn.Write(append([]byte{wsOpText, 0x80 | 60, 0, 0, 0, 0}, bytes.Repeat([]byte{' '}, 60)...))
+				wsTestWrite(t, conn, wsOpContinuation, bytes.Repeat([]byte{' '}, 60), false)
+			},
+		},
+		// Compressed message over the message limit once inflated
+		{
+			opts: WebsocketOptions{Compression: true, MaxMessageSize: 100},
+			send: func(conn net.Conn) {
+				wsTestWrite(t, conn, wsOpText, bytes.Repeat([]byte{' '}, 1000), true)
+			},
+		},
+	}
+	for i, tt := range tests {
+		srv := newTestServer("service", new(Service))
+		hs := httptest.NewServer(srv.WebsocketHandlerWithOptions([]string{"*"}, tt.opts))
+
+		conn, br, _ := wsTestDial(t, hs, "permessage-deflate")
+		tt.send(conn)
+
+		head, status := wsTestRead(t, br)
+		if head&0x0f != wsOpClose || len(status) < 2 || binary.BigEndian.Uint16(status) != wsCloseMessageTooLarge {
+			t.Errorf("test %d: expected close with status %d, have header %#x payload %x", i, wsCloseMessageTooLarge, head, status)
+		}
+		conn.Close()
+		hs.Close()
+		srv.Stop()
+	}
+}
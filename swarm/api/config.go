@@ -25,6 +25,7 @@ import (
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/contracts/ens"
 	"github.com/fulcrumchain/indigo/crypto"
+	"github.com/fulcrumchain/indigo/ethdb/archive"
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/node"
 	"github.com/fulcrumchain/indigo/swarm/network"
@@ -61,6 +62,7 @@ type Config struct {
 	Cors        string
 	BzzAccount  string
 	BootNodes   string
+	Archive     archive.Config // Cold store for chunks evicted from the local store
 }
 
 //create a default config with all parameters to set to defaults
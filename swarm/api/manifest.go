@@ -68,6 +68,138 @@ func (a *Api) NewManifest() (storage.Key, error) {
 	return a.Store(bytes.NewReader(data), int64(len(data)), &sync.WaitGroup{})
 }
 
+// TreeFile is a file to upload into a manifest, at its path relative to the
+// manifest root.
+type TreeFile struct {
+	Path        string
+	ContentType string // Detected from the content if empty
+	Content     []byte
+}
+
+// PutTree uploads a directory tree of files as a manifest, and returns the key
+// of the manifest. The file at index, if any, is also served at the root of the
+// manifest.
+func (a *Api) PutTree(files []TreeFile, index string) (storage.Key, error) {
+	key, err := a.NewManifest()
+	if err != nil {
+		return nil, err
+	}
+	mw, err := a.NewManifestWriter(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		path := RegularSlashes(strings.TrimPrefix(file.Path, "/"))
+		if path == "" || strings.HasSuffix(file.Path, "/") {
+			return nil, fmt.Errorf("invalid file path %q", file.Path)
+		}
+		if seen[path] {
+			return nil, fmt.Errorf("duplicate file path %q", path)
+		}
+		seen[path] = true
+
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = http.DetectContentType(file.Content)
+		}
+		entry := &ManifestEntry{
+			Path:        path,
+			ContentType: contentType,
+			Mode:        0644,
+			Size:        int64(len(file.Content)),
+			ModTime:     time.Now(),
+		}
+		if _, err := mw.AddEntry(bytes.NewReader(file.Content), entry); err != nil {
+			return nil, err
+		}
+		if path == index {
+			root := *entry
+			root.Path = ""
+			if _, err := mw.AddEntry(bytes.NewReader(file.Content), &root); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if index != "" && !seen[index] {
+		return nil, fmt.Errorf("index file %q not uploaded", index)
+	}
+	return mw.Store()
+}
+
+// Entry resolves a path within a manifest, following nested manifests, and
+// returns the entry at exactly that path. Unlike Get, it doesn't fall back to
+// the entries of prefixes of the path.
+func (a *Api) Entry(key storage.Key, path string) (*ManifestEntry, error) {
+	trie, err := loadManifest(a.dpa, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	entry, fullpath := trie.getEntry(path)
+	if entry == nil || fullpath != RegularSlashes(path) {
+		return nil, fmt.Errorf("manifest entry for '%s' not found", path)
+	}
+	result := entry.ManifestEntry
+	result.Path = fullpath
+	return &result, nil
+}
+
+// List returns the files of a manifest and its nested manifests under prefix,
+// grouped into common prefixes using "/" as a delimiter.
+func (a *Api) List(key storage.Key, prefix string) (list ManifestList, err error) {
+	walker, err := a.NewManifestWalker(key, nil)
+	if err != nil {
+		return
+	}
+	err = walker.Walk(func(entry *ManifestEntry) error {
+		// handle non-manifest files
+		if entry.ContentType != ManifestType {
+			// ignore the file if it doesn't have the specified prefix
+			if !strings.HasPrefix(entry.Path, prefix) {
+				return nil
+			}
+
+			// if the path after the prefix contains a slash, add a
+			// common prefix to the list, otherwise add the entry
+			suffix := strings.TrimPrefix(entry.Path, prefix)
+			if index := strings.Index(suffix, "/"); index > -1 {
+				list.CommonPrefixes = append(list.CommonPrefixes, prefix+suffix[:index+1])
+				return nil
+			}
+			if entry.Path == "" {
+				entry.Path = "/"
+			}
+			list.Entries = append(list.Entries, entry)
+			return nil
+		}
+
+		// if the manifest's path is a prefix of the specified prefix
+		// then just recurse into the manifest by returning nil and
+		// continuing the walk
+		if strings.HasPrefix(prefix, entry.Path) {
+			return nil
+		}
+
+		// if the manifest's path has the specified prefix, then if the
+		// path after the prefix contains a slash, add a common prefix
+		// to the list and skip the manifest, otherwise recurse into
+		// the manifest by returning nil and continuing the walk
+		if strings.HasPrefix(entry.Path, prefix) {
+			suffix := strings.TrimPrefix(entry.Path, prefix)
+			if index := strings.Index(suffix, "/"); index > -1 {
+				list.CommonPrefixes = append(list.CommonPrefixes, prefix+suffix[:index+1])
+				return SkipManifest
+			}
+			return nil
+		}
+
+		// the manifest neither has the prefix or needs recursing in to
+		// so just skip it
+		return SkipManifest
+	})
+	return list, err
+}
+
 // ManifestWriter is used to add and remove entries from an underlying manifest
 type ManifestWriter struct {
 	api   *Api
@@ -0,0 +1,89 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"strings"
+
+	"github.com/fulcrumchain/indigo/common/hexutil"
+	"github.com/fulcrumchain/indigo/swarm/storage"
+)
+
+// ManifestFile is a file uploaded into a manifest over RPC.
+type ManifestFile struct {
+	Path        string        `json:"path"`
+	ContentType string        `json:"contentType,omitempty"`
+	Content     hexutil.Bytes `json:"content"`
+}
+
+// Manifests is the RPC service building, resolving and listing manifests, so
+// that clients don't have to assemble manifest JSON themselves.
+type Manifests struct {
+	api *Api
+}
+
+func NewManifests(api *Api) *Manifests {
+	return &Manifests{api}
+}
+
+// UploadTree uploads a directory tree of files as a manifest and returns its
+// hash. The file at index, if any, is also served at the root of the manifest.
+func (m *Manifests) UploadTree(files []ManifestFile, index string) (string, error) {
+	tree := make([]TreeFile, len(files))
+	for i, file := range files {
+		tree[i] = TreeFile{Path: file.Path, ContentType: file.ContentType, Content: file.Content}
+	}
+	key, err := m.api.PutTree(tree, index)
+	if err != nil {
+		return "", err
+	}
+	return key.String(), nil
+}
+
+// Entry resolves bzzpath, a manifest hash or name followed by a path within it,
+// to the manifest entry serving it.
+func (m *Manifests) Entry(bzzpath string) (*ManifestEntry, error) {
+	key, path, err := m.resolve(bzzpath)
+	if err != nil {
+		return nil, err
+	}
+	return m.api.Entry(key, path)
+}
+
+// List returns the files of the manifest under the path of bzzpath, grouped
+// into common prefixes using "/" as a delimiter.
+func (m *Manifests) List(bzzpath string) (ManifestList, error) {
+	key, path, err := m.resolve(bzzpath)
+	if err != nil {
+		return ManifestList{}, err
+	}
+	return m.api.List(key, path)
+}
+
+// resolve splits bzzpath into the key of its manifest and the path within it,
+// keeping trailing slashes which delimit directories in listings.
+func (m *Manifests) resolve(bzzpath string) (storage.Key, string, error) {
+	uri, err := Parse("bzz:/" + strings.TrimPrefix(bzzpath, "/"))
+	if err != nil {
+		return nil, "", err
+	}
+	key, err := m.api.Resolve(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, uri.Path, nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestManifestsUploadTree(t *testing.T) {
+	testApi(t, func(api *Api) {
+		m := NewManifests(api)
+		hash, err := m.UploadTree([]ManifestFile{
+			{Path: "index.html", ContentType: "text/html", Content: []byte("<h1>index</h1>")},
+			{Path: "/img/logo.png", ContentType: "image/png", Content: []byte("logo")},
+			{Path: "img/icons/a.txt", Content: []byte("icon a")},
+			{Path: "img/icons/b.txt", Content: []byte("icon b")},
+		}, "index.html")
+		if err != nil {
+			t.Fatalf("failed to upload tree: %v", err)
+		}
+		// Nested paths resolve to their entries, the root to the index
+		for path, want := range map[string]string{
+			"":                "text/html",
+			"index.html":      "text/html",
+			"img/logo.png":    "image/png",
+			"img/icons/b.txt": "text/plain; charset=utf-8",
+		} {
+			entry, err := m.Entry(hash + "/" + path)
+			if err != nil {
+				t.Fatalf("failed to resolve %q: %v", path, err)
+			}
+			if entry.Path != path || entry.ContentType != want {
+				t.Errorf("entry %q mismatch: have %s (%s), want %s", path, entry.Path, entry.ContentType, want)
+			}
+			resp := testGet(t, api, hash, path)
+			if resp.MimeType != want {
+				t.Errorf("content type of %q mismatch: have %s, want %s", path, resp.MimeType, want)
+			}
+		}
+		if _, err := m.Entry(hash + "/missing"); err == nil {
+			t.Error("missing path resolved")
+		}
+		// Listings group the entries by directory
+		list, err := m.List(hash + "/img/")
+		if err != nil {
+			t.Fatalf("failed to list manifest: %v", err)
+		}
+		if !reflect.DeepEqual(list.CommonPrefixes, []string{"img/icons/"}) {
+			t.Errorf("common prefixes mismatch: have %v", list.CommonPrefixes)
+		}
+		if len(list.Entries) != 1 || list.Entries[0].Path != "img/logo.png" {
+			t.Errorf("entries mismatch: have %+v", list.Entries)
+		}
+	})
+}
+
+func TestManifestsUploadTreeInvalid(t *testing.T) {
+	testApi(t, func(api *Api) {
+		m := NewManifests(api)
+		for i, files := range [][]ManifestFile{
+			{{Path: "", Content: []byte("a")}},
+			{{Path: "dir/", Content: []byte("a")}},
+			{{Path: "a", Content: []byte("a")}, {Path: "/a", Content: []byte("b")}},
+		} {
+			if _, err := m.UploadTree(files, ""); err == nil {
+				t.Errorf("test %d: invalid tree accepted", i)
+			}
+		}
+		if _, err := m.UploadTree([]ManifestFile{{Path: "a", Content: []byte("a")}}, "index.html"); err == nil {
+			t.Error("missing index accepted")
+		}
+	})
+}
@@ -17,16 +17,19 @@
 package api
 
 import (
+	"time"
+
 	"github.com/fulcrumchain/indigo/swarm/network"
 )
 
 type Control struct {
-	api  *Api
-	hive *network.Hive
+	api      *Api
+	hive     *network.Hive
+	dbAccess *network.DbAccess
 }
 
-func NewControl(api *Api, hive *network.Hive) *Control {
-	return &Control{api, hive}
+func NewControl(api *Api, hive *network.Hive, dbAccess *network.DbAccess) *Control {
+	return &Control{api, hive, dbAccess}
 }
 
 func (c *Control) BlockNetworkRead(on bool) {
@@ -44,3 +47,32 @@ func (c *Control) SwapEnabled(on bool) {
 func (c *Control) Hive() string {
 	return c.hive.String()
 }
+
+// NodeInfo reports the hive's table, the local store's fill levels and the
+// sync cursors of the connected peers.
+func (c *Control) NodeInfo() *network.NodeInfo {
+	return c.hive.NodeInfo(c.dbAccess)
+}
+
+// RetrievalStats reports the retrieval counters of all peers ever retrieved
+// from or served to.
+func (c *Control) RetrievalStats() map[string]network.PeerRetrievalStats {
+	return c.hive.RetrievalStats()
+}
+
+// HiveHealth probes up to sample connected peers of every proximity bin with
+// retrieve requests, giving them timeout milliseconds to answer, and reports
+// their success and latency with the saturation of the table.
+func (c *Control) HiveHealth(sample *int, timeout *uint64) *network.HiveHealth {
+	var (
+		n int
+		d time.Duration
+	)
+	if sample != nil {
+		n = *sample
+	}
+	if timeout != nil {
+		d = time.Duration(*timeout) * time.Millisecond
+	}
+	return c.hive.Health(n, d)
+}
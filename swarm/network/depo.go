@@ -99,7 +99,7 @@ func (d *Depo) HandleDeliveryRequestMsg(req *deliveryRequestMsgData, p *peer) er
 // if key found locally, return. otherwise
 // remote is untrusted, so hash is verified and chunk passed on to NetStore
 func (d *Depo) HandleStoreRequestMsg(req *storeRequestMsgData, p *peer) {
-	var islocal bool
+	var islocal, requested bool
 	req.from = p
 	chunk, err := d.localStore.Get(req.Key)
 	switch {
@@ -112,6 +112,7 @@ func (d *Depo) HandleStoreRequestMsg(req *storeRequestMsgData, p *peer) {
 	case chunk.SData == nil:
 		// found chunk in memory store, needs the data, validate now
 		log.Trace(fmt.Sprintf("Depo.HandleStoreRequest: %v. request entry found", req))
+		requested = chunk.Req != nil
 
 	default:
 		// data is found, store request ignored
@@ -139,6 +140,12 @@ func (d *Depo) HandleStoreRequestMsg(req *storeRequestMsgData, p *peer) {
 	log.Trace(fmt.Sprintf("delivery of %v from %v", chunk, p))
 	chunk.Source = p
 	d.netStore.Put(chunk)
+
+	// confirm the delivery of a chunk we asked for to the peer that served it
+	if requested {
+		p.hive.stats.delivered(p.Addr())
+		p.receipt(&receiptMsgData{Key: req.Key, Id: req.Id})
+	}
 }
 
 // entrypoint for retrieve requests coming from the bzz wire protocol
@@ -173,6 +180,7 @@ func (d *Depo) HandleRetrieveRequestMsg(req *retrieveRequestMsgData, p *peer) {
 				requestTimeout: req.timeout, //
 			}
 			p.syncer.addRequest(sreq, DeliverReq)
+			p.hive.stats.served(p.Addr())
 		} else {
 			log.Trace(fmt.Sprintf("Depo.HandleRetrieveRequest: %v - content found, not wanted", req.Key.Log()))
 		}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/swarm/network/kademlia"
 	"github.com/fulcrumchain/indigo/swarm/storage"
 )
 
@@ -37,11 +38,12 @@ which uses an MSB logarithmic distance-based semi-permanent Kademlia table for
 */
 
 type forwarder struct {
-	hive *Hive
+	hive       *Hive
+	retrievals *retrievalCoordinator
 }
 
 func NewForwarder(hive *Hive) *forwarder {
-	return &forwarder{hive: hive}
+	return &forwarder{hive: hive, retrievals: newRetrievalCoordinator()}
 }
 
 // generate a unique id uint64
@@ -52,14 +54,69 @@ func generateId() uint64 {
 
 var searchTimeout = 3 * time.Second
 
+const (
+	// number of peers a retrieve request is fanned out to, closest first
+	retrieveAttempts = 3
+	// time a peer is given to deliver a chunk before the next closest is asked
+	retrieveAttemptTimeout = 1 * time.Second
+)
+
 // forwarding logic
 // logic propagating retrieve requests to peers given by the kademlia hive
+// concurrent requests for the same chunk share a single search, and chunks
+// recently not found fail right away without asking peers again
+// the closest peer is asked first; if it does not deliver in time, the
+// request fans out to the next closest ones, staggered so that earlier peers
+// can still deliver, until it runs out of attempts, at which point the search
+// is failed so that waiting requesters return and later requests start a new
+// search once the failure expires
 func (f *forwarder) Retrieve(chunk *storage.Chunk) {
+	search := f.retrievals.join(chunk)
+	if search == nil {
+		log.Trace(fmt.Sprintf("forwarder.Retrieve: %v - joined search in flight or recently failed", chunk.Key.Log()))
+		return
+	}
+	tried := make(map[kademlia.Address]bool)
+	timeout := time.NewTimer(retrieveAttemptTimeout)
+	defer timeout.Stop()
+
+	for attempt := 0; attempt < retrieveAttempts; attempt++ {
+		p := f.retrieveFrom(chunk, tried)
+		if p == nil {
+			log.Trace(fmt.Sprintf("forwarder.Retrieve: %v - no more peers to ask", chunk.Key.Log()))
+			break
+		}
+		if attempt > 0 {
+			timeout.Reset(retrieveAttemptTimeout)
+		}
+		select {
+		case <-search.done:
+			return
+		case <-timeout.C:
+			f.hive.stats.timedOut(p.Addr())
+			log.Trace(fmt.Sprintf("forwarder.Retrieve: %v - peer [%v] timed out (attempt %d)", chunk.Key.Log(), p, attempt+1))
+		}
+	}
+	// the chunk may have been delivered just as we gave up
+	select {
+	case <-search.done:
+	default:
+		log.Debug(fmt.Sprintf("forwarder.Retrieve: %v - not found after trying %d peers", chunk.Key.Log(), len(tried)))
+		f.retrievals.failed(search)
+	}
+}
+
+// retrieveFrom sends a retrieve request for the chunk to the closest peer that
+// has not been tried yet and did not request the chunk itself, marking it tried
+// it returns the peer asked, or nil if none could be
+func (f *forwarder) retrieveFrom(chunk *storage.Chunk, tried map[kademlia.Address]bool) *peer {
 	peers := f.hive.getPeers(chunk.Key, 0)
 	log.Trace(fmt.Sprintf("forwarder.Retrieve: %v - received %d peers from KΛÐΞMLIΛ...", chunk.Key.Log(), len(peers)))
 OUT:
 	for _, p := range peers {
-		log.Trace(fmt.Sprintf("forwarder.Retrieve: sending retrieveRequest %v to peer [%v]", chunk.Key.Log(), p))
+		if tried[p.Addr()] {
+			continue
+		}
 		for _, recipients := range chunk.Req.Requesters {
 			for _, recipient := range recipients {
 				req := recipient.(*retrieveRequestMsgData)
@@ -68,6 +125,8 @@ OUT:
 				}
 			}
 		}
+		tried[p.Addr()] = true
+		log.Trace(fmt.Sprintf("forwarder.Retrieve: sending retrieveRequest %v to peer [%v]", chunk.Key.Log(), p))
 		req := &retrieveRequestMsgData{
 			Key: chunk.Key,
 			Id:  generateId(),
@@ -78,10 +137,12 @@ OUT:
 		}
 		if err == nil {
 			p.retrieve(req)
-			break OUT
+			f.hive.stats.requested(p.Addr())
+			return p
 		}
 		log.Warn(fmt.Sprintf("forwarder.Retrieve: unable to send retrieveRequest to peer [%v]: %v", chunk.Key.Log(), err))
 	}
+	return nil
 }
 
 // requests to specific peers given by the kademlia hive
@@ -110,6 +171,8 @@ func (f *forwarder) Store(chunk *storage.Chunk) {
 
 // once a chunk is found deliver it to its requesters unless timed out
 func (f *forwarder) Deliver(chunk *storage.Chunk) {
+	f.retrievals.delivered(chunk)
+
 	// iterate over request entries
 	for id, requesters := range chunk.Req.Requesters {
 		counter := requesterCount
@@ -126,6 +189,7 @@ func (f *forwarder) Deliver(chunk *storage.Chunk) {
 				log.Trace(fmt.Sprintf("forwarder.Deliver: %v -> %v", req.Id, req.from))
 				msg.Id = uint64(id)
 				Deliver(req.from, msg, DeliverReq)
+				f.hive.stats.served(req.from.Addr())
 				n++
 				counter--
 				if counter <= 0 {
@@ -0,0 +1,220 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/swarm/network/kademlia"
+	"github.com/fulcrumchain/indigo/swarm/storage"
+)
+
+/*
+Hive health
+
+The hive probes a sample of the connected peers of every proximity bin with
+retrieve requests for random targets within the bin. The requests are lookups,
+so the peers answer them right away with a peers message without searching the
+network, measuring whether and how fast the peers respond over the bzz
+protocol itself. Together with the saturation of the table this tells a local
+connectivity problem apart from content being unavailable in the swarm.
+*/
+
+const (
+	defaultHealthSample  = 3               // peers probed per bin by default
+	defaultHealthTimeout = 5 * time.Second // time the probed peers are given to answer by default
+)
+
+// BinHealth reports the probes of the peers in a proximity bin.
+type BinHealth struct {
+	ProximityOrder int     `json:"po"`
+	Connected      int     `json:"connected"`  // Number of live peers in the bin
+	Known          int     `json:"known"`      // Number of node records in the bin
+	Saturated      bool    `json:"saturated"`  // Whether the bin holds as many peers as it may
+	Probed         int     `json:"probed"`     // Number of peers probed
+	Answered       int     `json:"answered"`   // Number of probed peers answering in time
+	AvgLatency     float64 `json:"avgLatency"` // Mean latency of the answers in milliseconds
+	MaxLatency     float64 `json:"maxLatency"` // Highest latency of the answers in milliseconds
+}
+
+// HiveHealth is the saturation and connectivity report of the hive.
+type HiveHealth struct {
+	Connected int          `json:"connected"` // Number of live peers
+	Known     int          `json:"known"`     // Number of node records
+	ProxLimit int          `json:"proxLimit"` // Proximity order of the most proximate bin
+	Saturated bool         `json:"saturated"` // Whether all bins are saturated, so that the hive calls for no more peers
+	Probed    int          `json:"probed"`    // Number of peers probed
+	Answered  int          `json:"answered"`  // Number of probed peers answering in time
+	Bins      []*BinHealth `json:"bins"`
+}
+
+// probeKey identifies a probe by the peer asked and the target looked up.
+type probeKey struct {
+	peer   kademlia.Address
+	target kademlia.Address
+}
+
+// healthProbes tracks the probes awaiting an answer.
+type healthProbes struct {
+	pending map[probeKey]chan struct{}
+	lock    sync.Mutex
+}
+
+func newHealthProbes() *healthProbes {
+	return &healthProbes{
+		pending: make(map[probeKey]chan struct{}),
+	}
+}
+
+// expect registers a probe, returning the channel closed once it is answered.
+func (p *healthProbes) expect(key probeKey) chan struct{} {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	answered := make(chan struct{})
+	p.pending[key] = answered
+	return answered
+}
+
+// answer reports a peers message answering a lookup, returning whether it was
+// a probe.
+func (p *healthProbes) answer(key probeKey) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	answered, ok := p.pending[key]
+	if ok {
+		close(answered)
+		delete(p.pending, key)
+	}
+	return ok
+}
+
+// forget drops a probe left unanswered.
+func (p *healthProbes) forget(key probeKey) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.pending, key)
+}
+
+// Health probes up to sample connected peers of every proximity bin, waiting
+// at most timeout for their answers, and reports them with the saturation of
+// the table. Non-positive arguments fall back to the defaults.
+func (h *Hive) Health(sample int, timeout time.Duration) *HiveHealth {
+	if sample <= 0 {
+		sample = defaultHealthSample
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+	table := h.kad.Info()
+	report := &HiveHealth{
+		Connected: table.Connected,
+		Known:     table.Known,
+		ProxLimit: table.ProxLimit,
+		Saturated: true,
+	}
+	// Sample the peers of every bin
+	peers := make(map[string]*peer)
+	for _, node := range h.kad.Nodes() {
+		if p, ok := node.(*peer); ok {
+			peers[p.Addr().String()] = p
+		}
+	}
+	var (
+		wg       sync.WaitGroup
+		lock     sync.Mutex
+		deadline = time.NewTimer(timeout)
+		expired  = make(chan struct{})
+	)
+	go func() {
+		<-deadline.C
+		close(expired)
+	}()
+	defer deadline.Stop()
+
+	for _, bin := range table.Bins {
+		health := &BinHealth{
+			ProximityOrder: bin.ProximityOrder,
+			Connected:      len(bin.Connected),
+			Known:          bin.Known,
+			Saturated:      len(bin.Connected) >= h.kad.BucketSize,
+		}
+		if !health.Saturated {
+			report.Saturated = false
+		}
+		report.Bins = append(report.Bins, health)
+
+		for _, i := range rand.Perm(len(bin.Connected)) {
+			if health.Probed == sample {
+				break
+			}
+			p := peers[bin.Connected[i]]
+			if p == nil {
+				continue
+			}
+			health.Probed++
+			report.Probed++
+
+			wg.Add(1)
+			go func(health *BinHealth) {
+				defer wg.Done()
+				if latency, ok := h.probe(p, health.ProximityOrder, expired); ok {
+					lock.Lock()
+					defer lock.Unlock()
+
+					ms := float64(latency) / float64(time.Millisecond)
+					health.AvgLatency += (ms - health.AvgLatency) / float64(health.Answered+1)
+					if ms > health.MaxLatency {
+						health.MaxLatency = ms
+					}
+					health.Answered++
+					report.Answered++
+				}
+			}(health)
+		}
+	}
+	wg.Wait()
+	log.Debug(fmt.Sprintf("hive health: %d/%d probed bees answered (saturated: %v)", report.Answered, report.Probed, report.Saturated))
+	return report
+}
+
+// probe sends a lookup for a random target in the given proximity bin to the
+// peer, returning the latency of its answer unless expired is closed first.
+func (h *Hive) probe(p *peer, po int, expired chan struct{}) (time.Duration, bool) {
+	key := probeKey{peer: p.Addr(), target: kademlia.RandomAddressAt(h.addr, po)}
+	answered := h.probes.expect(key)
+	defer h.probes.forget(key)
+
+	start := time.Now()
+	if err := p.retrieve(&retrieveRequestMsgData{Key: storage.Key(key.target[:]), MaxPeers: 1}); err != nil {
+		log.Debug(fmt.Sprintf("unable to probe bee %v: %v", p, err))
+		return 0, false
+	}
+	select {
+	case <-answered:
+		return time.Since(start), true
+	case <-expired:
+		log.Debug(fmt.Sprintf("bee %v did not answer probe in time", p))
+		return 0, false
+	}
+}
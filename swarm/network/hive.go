@@ -24,6 +24,7 @@ import (
 
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
 	"github.com/fulcrumchain/indigo/p2p/discover"
 	"github.com/fulcrumchain/indigo/p2p/netutil"
 	"github.com/fulcrumchain/indigo/swarm/network/kademlia"
@@ -40,15 +41,22 @@ import (
 // to keep the nodetable uptodate
 
 type Hive struct {
-	listenAddr   func() string
-	callInterval uint64
-	id           discover.NodeID
-	addr         kademlia.Address
-	kad          *kademlia.Kademlia
-	path         string
-	quit         chan bool
-	toggle       chan bool
-	more         chan bool
+	listenAddr      func() string
+	callInterval    uint64
+	minCallInterval uint64
+	maxCallInterval uint64
+	id              discover.NodeID
+	addr            kademlia.Address
+	kad             *kademlia.Kademlia
+	path            string
+	warmPath        string
+	warmCount       int
+	quit            chan bool
+	toggle          chan bool
+	more            chan bool
+	dropped         chan bool
+	stats           *retrievalStats
+	probes          *healthProbes
 
 	// for testing only
 	swapEnabled bool
@@ -58,15 +66,31 @@ type Hive struct {
 }
 
 const (
-	callInterval = 3000000000
+	callInterval    = 3000000000
+	minCallInterval = 500000000
+	maxCallInterval = 60000000000
 	// bucketSize   = 3
 	// maxProx      = 8
 	// proxBinSize  = 4
 )
 
+// callIntervalGauge reports the current interval between calls for new peers
+// in milliseconds.
+var callIntervalGauge = metrics.NewGauge("swarm/hive/callinterval")
+
+// HiveParams configures the hive. Calls for new peers are made every
+// CallInterval nanoseconds while they keep connecting new peers. The interval
+// backs off up to MaxCallInterval while the calls are fruitless or the table is
+// saturated, and shortens down to MinCallInterval on bursts of disconnections.
+// The best WarmPeers peers connected when stopping are recorded to be dialed
+// first on the next start.
 type HiveParams struct {
-	CallInterval uint64
-	KadDbPath    string
+	CallInterval    uint64
+	MinCallInterval uint64
+	MaxCallInterval uint64
+	WarmPeers       int
+	KadDbPath       string
+	WarmPeersPath   string
 	*kademlia.KadParams
 }
 
@@ -78,8 +102,11 @@ func NewDefaultHiveParams() *HiveParams {
 	// kad.ProxBinSize = proxBinSize
 
 	return &HiveParams{
-		CallInterval: callInterval,
-		KadParams:    kad,
+		CallInterval:    callInterval,
+		MinCallInterval: minCallInterval,
+		MaxCallInterval: maxCallInterval,
+		WarmPeers:       defaultWarmPeers,
+		KadParams:       kad,
 	}
 }
 
@@ -87,18 +114,39 @@ func NewDefaultHiveParams() *HiveParams {
 //have been evaluated
 func (h *HiveParams) Init(path string) {
 	h.KadDbPath = filepath.Join(path, "bzz-peers.json")
+	h.WarmPeersPath = filepath.Join(path, "bzz-warm.json")
 }
 
 func NewHive(addr common.Hash, params *HiveParams, swapEnabled, syncEnabled bool) *Hive {
 	kad := kademlia.New(kademlia.Address(addr), params.KadParams)
-	return &Hive{
-		callInterval: params.CallInterval,
-		kad:          kad,
-		addr:         kad.Addr(),
-		path:         params.KadDbPath,
-		swapEnabled:  swapEnabled,
-		syncEnabled:  syncEnabled,
+	h := &Hive{
+		callInterval:    params.CallInterval,
+		minCallInterval: params.MinCallInterval,
+		maxCallInterval: params.MaxCallInterval,
+		kad:             kad,
+		addr:            kad.Addr(),
+		path:            params.KadDbPath,
+		warmPath:        params.WarmPeersPath,
+		warmCount:       params.WarmPeers,
+		stats:           newRetrievalStats(),
+		probes:          newHealthProbes(),
+		swapEnabled:     swapEnabled,
+		syncEnabled:     syncEnabled,
+	}
+	// configs predating the adaptive interval leave the bounds unset
+	if h.minCallInterval == 0 || h.minCallInterval > h.callInterval {
+		h.minCallInterval = h.callInterval
+		if h.minCallInterval > minCallInterval {
+			h.minCallInterval = minCallInterval
+		}
+	}
+	if h.maxCallInterval < h.callInterval {
+		h.maxCallInterval = h.callInterval
+		if h.maxCallInterval < maxCallInterval {
+			h.maxCallInterval = maxCallInterval
+		}
 	}
+	return h
 }
 
 func (h *Hive) SyncEnabled(on bool) {
@@ -129,6 +177,7 @@ func (h *Hive) Addr() kademlia.Address {
 func (h *Hive) Start(id discover.NodeID, listenAddr func() string, connectPeer func(string) error) (err error) {
 	h.toggle = make(chan bool)
 	h.more = make(chan bool)
+	h.dropped = make(chan bool, 1)
 	h.quit = make(chan bool)
 	h.id = id
 	h.listenAddr = listenAddr
@@ -137,6 +186,8 @@ func (h *Hive) Start(id discover.NodeID, listenAddr func() string, connectPeer f
 		log.Warn(fmt.Sprintf("Warning: error reading kaddb '%s' (skipping): %v", h.path, err))
 		err = nil
 	}
+	// dial the peers recorded at the last stop before the table is consulted
+	h.warmUp(h.warmPath, h.warmCount, connectPeer)
 	// this loop is doing bootstrapping and maintains a healthy table
 	go h.keepAlive()
 	go func() {
@@ -184,16 +235,70 @@ func (h *Hive) Start(id discover.NodeID, listenAddr func() string, connectPeer f
 	return
 }
 
+// callEvent is an outcome of the hive's calls for new peers, deciding how
+// often the next ones are made
+type callEvent int
+
+const (
+	callSaturated callEvent = iota // the table needs no more peers
+	callFruitless                  // no new peers connected since the last call
+	callFruitful                   // new peers connected since the last call
+	callDropped                    // peers disconnected
+)
+
+// nextCallInterval returns the interval between calls following an event:
+// it doubles on saturated or fruitless calls, resets to the configured
+// interval once calls connect new peers again, and halves on disconnections
+// so that the table is refilled quickly after a burst of them.
+func (h *Hive) nextCallInterval(current time.Duration, event callEvent) time.Duration {
+	base := time.Duration(h.callInterval)
+	next := current
+	switch event {
+	case callSaturated, callFruitless:
+		next = current * 2
+	case callFruitful:
+		next = base
+	case callDropped:
+		if next > base {
+			next = base
+		}
+		next /= 2
+	}
+	if min := time.Duration(h.minCallInterval); next < min {
+		next = min
+	}
+	if max := time.Duration(h.maxCallInterval); next > max {
+		next = max
+	}
+	return next
+}
+
 // keepAlive is a forever loop
 // in its awake state it periodically triggers connection attempts
-// by writing to self.more until Kademlia Table is saturated
-// wake state is toggled by writing to self.toggle
-// it restarts if the table becomes non-full again due to disconnections
+// by writing to self.more
+// the interval between attempts adapts to how they fare, as reported
+// by writing to self.toggle, and to disconnections reported on self.dropped
 func (h *Hive) keepAlive() {
-	alarm := time.NewTicker(time.Duration(h.callInterval)).C
+	interval := time.Duration(h.callInterval)
+	alarm := time.NewTicker(interval)
+	defer func() { alarm.Stop() }()
+	callIntervalGauge.Update(int64(interval / time.Millisecond))
+
+	reschedule := func(event callEvent) {
+		next := h.nextCallInterval(interval, event)
+		if next == interval {
+			return
+		}
+		log.Debug(fmt.Sprintf("buzz interval %v -> %v", interval, next))
+		interval = next
+		alarm.Stop()
+		alarm = time.NewTicker(interval)
+		callIntervalGauge.Update(int64(interval / time.Millisecond))
+	}
+	population := h.kad.Count()
 	for {
 		select {
-		case <-alarm:
+		case <-alarm.C:
 			if h.kad.DBCount() > 0 {
 				select {
 				case h.more <- true:
@@ -202,13 +307,18 @@ func (h *Hive) keepAlive() {
 				}
 			}
 		case need := <-h.toggle:
-			if alarm == nil && need {
-				alarm = time.NewTicker(time.Duration(h.callInterval)).C
-			}
-			if alarm != nil && !need {
-				alarm = nil
-
+			count := h.kad.Count()
+			switch {
+			case !need:
+				reschedule(callSaturated)
+			case count > population:
+				reschedule(callFruitful)
+			case count == population:
+				reschedule(callFruitless)
 			}
+			population = count
+		case <-h.dropped:
+			reschedule(callDropped)
 		case <-h.quit:
 			return
 		}
@@ -218,6 +328,9 @@ func (h *Hive) keepAlive() {
 func (h *Hive) Stop() error {
 	// closing toggle channel quits the updateloop
 	close(h.quit)
+	if err := h.saveWarmPeers(h.warmPath, h.warmCount); err != nil {
+		log.Warn(fmt.Sprintf("unable to save warm-up peers to %v: %v", h.warmPath, err))
+	}
 	return h.kad.Save(h.path, saveSync)
 }
 
@@ -250,6 +363,10 @@ func (h *Hive) removePeer(p *peer) {
 	log.Debug(fmt.Sprintf("bee %v removed", p))
 	h.kad.Off(p, saveSync)
 	select {
+	case h.dropped <- true:
+	default:
+	}
+	select {
 	case h.more <- true:
 	default:
 	}
@@ -292,6 +409,12 @@ func newNodeRecord(addr *peerAddr) *kademlia.NodeRecord {
 // peersMsgData is converted to a slice of NodeRecords for Kademlia
 // this is to store all thats needed
 func (h *Hive) HandlePeersMsg(req *peersMsgData, from *peer) {
+	// answers to lookups for a target may be answering health probes
+	if req.Id == 0 && len(req.Key) == len(kademlia.Address{}) {
+		var target kademlia.Address
+		copy(target[:], req.Key)
+		h.probes.answer(probeKey{peer: from.Addr(), target: target})
+	}
 	var nrs []*kademlia.NodeRecord
 	for _, p := range req.Peers {
 		if err := netutil.CheckRelayIP(from.remoteAddr.IP, p.IP); err != nil {
@@ -391,3 +514,52 @@ func (h *Hive) peers(req *retrieveRequestMsgData) {
 func (h *Hive) String() string {
 	return h.kad.String()
 }
+
+// PeerSyncInfo reports the synchronisation cursors of a connected peer.
+type PeerSyncInfo struct {
+	Addr       string `json:"addr"`
+	Synced     bool   `json:"synced"`     // Whether sync was done up to the last disconnect
+	First      uint64 `json:"first"`      // Storage counter the sync resumes from
+	Last       uint64 `json:"last"`       // Storage counter of the peer at the last connection
+	SessionAt  uint64 `json:"sessionAt"`  // Storage counter at the time of connection
+	LastSeenAt uint64 `json:"lastSeenAt"` // Storage counter when last seen
+	Latest     string `json:"latest"`     // Key of the last chunk offered
+}
+
+// NodeInfo reports the state of the hive and the local chunk store.
+type NodeInfo struct {
+	Table *kademlia.TableInfo `json:"table"`
+	Store *storage.StoreStats `json:"store,omitempty"`
+	Peers []*PeerSyncInfo     `json:"peers"`
+}
+
+// NodeInfo reports the state of the hive, and of the local chunk store behind
+// dbaccess if not nil.
+func (h *Hive) NodeInfo(dbaccess *DbAccess) *NodeInfo {
+	info := &NodeInfo{
+		Table: h.kad.Info(),
+		Peers: make([]*PeerSyncInfo, 0),
+	}
+	if dbaccess != nil && dbaccess.loc != nil {
+		info.Store = dbaccess.loc.Stats()
+	}
+	for _, node := range h.kad.Nodes() {
+		p, ok := node.(*peer)
+		if !ok || p.syncState == nil {
+			continue
+		}
+		state := p.syncState
+		sync := &PeerSyncInfo{
+			Addr:       p.Addr().String(),
+			Synced:     state.Synced,
+			SessionAt:  state.SessionAt,
+			LastSeenAt: state.LastSeenAt,
+			Latest:     state.Latest.String(),
+		}
+		if state.DbSyncState != nil {
+			sync.First, sync.Last = state.First, state.Last
+		}
+		info.Peers = append(info.Peers, sync)
+	}
+	return info
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/p2p"
+	"github.com/fulcrumchain/indigo/swarm/network/kademlia"
+)
+
+func TestNextCallInterval(t *testing.T) {
+	params := NewDefaultHiveParams()
+	params.CallInterval = uint64(4 * time.Second)
+	params.MinCallInterval = uint64(time.Second)
+	params.MaxCallInterval = uint64(20 * time.Second)
+	h := NewHive(common.Hash{}, params, false, false)
+
+	steps := []struct {
+		event callEvent
+		want  time.Duration
+	}{
+		{callFruitless, 8 * time.Second},
+		{callSaturated, 16 * time.Second},
+		{callSaturated, 20 * time.Second},
+		{callFruitless, 20 * time.Second},
+		{callDropped, 2 * time.Second},
+		{callDropped, time.Second},
+		{callDropped, time.Second},
+		{callFruitless, 2 * time.Second},
+		{callFruitful, 4 * time.Second},
+		{callFruitful, 4 * time.Second},
+	}
+	interval := time.Duration(params.CallInterval)
+	for i, step := range steps {
+		interval = h.nextCallInterval(interval, step.event)
+		if interval != step.want {
+			t.Fatalf("step %d: interval mismatch: have %v, want %v", i, interval, step.want)
+		}
+	}
+}
+
+func TestCallIntervalBounds(t *testing.T) {
+	params := NewDefaultHiveParams()
+	params.CallInterval = uint64(100 * time.Millisecond)
+	params.MinCallInterval, params.MaxCallInterval = 0, 0
+	h := NewHive(common.Hash{}, params, false, false)
+
+	if h.minCallInterval != params.CallInterval {
+		t.Errorf("min interval mismatch: have %v, want %v", h.minCallInterval, params.CallInterval)
+	}
+	if h.maxCallInterval != maxCallInterval {
+		t.Errorf("max interval mismatch: have %v, want %v", h.maxCallInterval, uint64(maxCallInterval))
+	}
+}
+
+func TestRetrievalStats(t *testing.T) {
+	h := NewHive(common.Hash{}, NewDefaultHiveParams(), false, false)
+	a, b := kademlia.Address{1}, kademlia.Address{2}
+
+	for i := 0; i < 3; i++ {
+		h.stats.requested(a)
+	}
+	h.stats.delivered(a)
+	h.stats.timedOut(a)
+	h.stats.timedOut(a)
+	h.stats.served(b)
+	h.stats.confirmed(b)
+
+	stats := h.RetrievalStats()
+	if len(stats) != 2 {
+		t.Fatalf("peer count mismatch: have %d, want 2", len(stats))
+	}
+	sa := stats[a.String()]
+	if sa.Requested != 3 || sa.Delivered != 1 || sa.TimedOut != 2 || sa.LastDelivery.IsZero() {
+		t.Errorf("requester stats mismatch: %+v", sa)
+	}
+	if sa.Score != 0.4 {
+		t.Errorf("score mismatch: have %v, want 0.4", sa.Score)
+	}
+	sb := stats[b.String()]
+	if sb.Served != 1 || sb.Confirmed != 1 || sb.Score != 0.5 {
+		t.Errorf("server stats mismatch: %+v", sb)
+	}
+}
+
+func TestRetrievalStatsLimit(t *testing.T) {
+	r := newRetrievalStats()
+	for i := 0; i < retrievalStatsLimit; i++ {
+		r.requested(kademlia.Address{byte(i), byte(i >> 8)})
+	}
+	stale := kademlia.Address{5}
+	r.peers[stale].active = time.Time{}
+
+	r.requested(kademlia.Address{0xff, 0xff})
+	if len(r.peers) != retrievalStatsLimit {
+		t.Fatalf("peer count mismatch: have %d, want %d", len(r.peers), retrievalStatsLimit)
+	}
+	if _, ok := r.peers[stale]; ok {
+		t.Errorf("least recently active peer kept")
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bzz-warmup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bzz-warm.json")
+
+	self := common.Hash{0xff}
+	h := NewHive(self, NewDefaultHiveParams(), false, false)
+	dial := func(dialed *[]string) func(string) error {
+		return func(url string) error {
+			*dialed = append(*dialed, url)
+			return nil
+		}
+	}
+	// Nothing is dialed without a record, and stopping without peers records none
+	var dialed []string
+	if n := h.warmUp(path, 2, dial(&dialed)); n != 0 || len(dialed) != 0 {
+		t.Fatalf("dialed %d bees without a record", n)
+	}
+	if err := h.saveWarmPeers(path, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("warm-up peers recorded without peers: %v", err)
+	}
+	// Recorded peers are dialed in order, up to the limit, skipping ourselves
+	peers := []*warmPeer{
+		{Addr: kademlia.Address(self), Url: "enode://self"},
+		{Addr: kademlia.Address{1}, Url: "enode://one"},
+		{Addr: kademlia.Address{2}, Url: "enode://two"},
+		{Addr: kademlia.Address{3}, Url: "enode://three"},
+	}
+	data, _ := json.Marshal(peers)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if n := h.warmUp(path, 3, dial(&dialed)); n != 2 {
+		t.Fatalf("dial count mismatch: have %d, want 2", n)
+	}
+	if len(dialed) != 2 || dialed[0] != "enode://one" || dialed[1] != "enode://two" {
+		t.Errorf("dialed bees mismatch: have %v", dialed)
+	}
+}
+
+func TestHiveHealth(t *testing.T) {
+	self := common.Hash{}
+	h := NewHive(self, NewDefaultHiveParams(), false, false)
+
+	// Connect a responsive and a silent bee in different bins
+	newBee := func(addr kademlia.Address, answer bool) {
+		local, remote := p2p.MsgPipe()
+		p := &peer{bzz: &bzz{hive: h, rw: local, remoteAddr: &peerAddr{Addr: addr}}}
+		if err := h.kad.On(p, nil); err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			for {
+				msg, err := remote.ReadMsg()
+				if err != nil {
+					return
+				}
+				var req retrieveRequestMsgData
+				if err := msg.Decode(&req); err != nil || !answer {
+					continue
+				}
+				h.HandlePeersMsg(&peersMsgData{Key: req.Key}, p)
+			}
+		}()
+	}
+	newBee(kademlia.Address{0x80}, true)
+	newBee(kademlia.Address{0x40}, false)
+
+	report := h.Health(0, 200*time.Millisecond)
+	if report.Connected != 2 || report.Probed != 2 || report.Answered != 1 {
+		t.Fatalf("report mismatch: connected %d, probed %d, answered %d", report.Connected, report.Probed, report.Answered)
+	}
+	if report.Saturated {
+		t.Errorf("sparse table reported saturated")
+	}
+	for _, bin := range report.Bins {
+		switch bin.ProximityOrder {
+		case 0:
+			if bin.Probed != 1 || bin.Answered != 1 || bin.AvgLatency <= 0 || bin.MaxLatency < bin.AvgLatency {
+				t.Errorf("responsive bin mismatch: %+v", bin)
+			}
+		case 1:
+			if bin.Probed != 1 || bin.Answered != 0 {
+				t.Errorf("silent bin mismatch: %+v", bin)
+			}
+		default:
+			if bin.Probed != 0 {
+				t.Errorf("empty bin %d probed", bin.ProximityOrder)
+			}
+		}
+	}
+	if len(h.probes.pending) != 0 {
+		t.Errorf("%d probes left pending", len(h.probes.pending))
+	}
+}
@@ -270,39 +270,80 @@ func (self *KadDb) delete(row int, purge []bool) {
 	self.Nodes[row] = nodes
 }
 
+// kadDbVersion is the version of the persisted node record database format.
+// Version 0 is the unversioned format, which stored the records in proximity
+// order rows.
+const kadDbVersion = 1
+
+// kadDbFile is the persisted form of the node record database. Records are
+// stored in a flat list, and sorted into proximity order rows when loaded.
+type kadDbFile struct {
+	Version int
+	Address Address
+	Nodes   []*NodeRecord
+}
+
+// legacyKadDbFile is the unversioned persisted form of the node record database.
+type legacyKadDbFile struct {
+	Address Address
+	Nodes   [][]*NodeRecord
+}
+
+// stale tells whether a record has gone unseen for longer than the purge
+// interval, and should be dropped from the database.
+func (self *KadDb) stale(node *NodeRecord) bool {
+	return time.Since(node.Seen) > self.purgeInterval
+}
+
 // save persists kaddb on disk (written to file on path in json format.
+// Records unseen for longer than the purge interval are left out.
 func (self *KadDb) save(path string, cb func(*NodeRecord, Node)) error {
 	defer self.lock.Unlock()
 	self.lock.Lock()
 
-	var n int
-
+	var nodes []*NodeRecord
 	for _, b := range self.Nodes {
 		for _, node := range b {
-			n++
+			if node.node != nil {
+				node.setSeen()
+			}
+			if self.stale(node) {
+				continue
+			}
 			node.After = time.Now()
-			node.Seen = time.Now()
 			if cb != nil {
 				cb(node, node.node)
 			}
+			nodes = append(nodes, node)
 		}
 	}
+	err := self.write(path, nodes)
+	if err != nil {
+		log.Warn(fmt.Sprintf("unable to save kaddb with %v nodes to %v: %v", len(nodes), path, err))
+	} else {
+		log.Info(fmt.Sprintf("saved kaddb with %v nodes to %v", len(nodes), path))
+	}
+	return err
+}
 
-	data, err := json.MarshalIndent(self, "", " ")
+// write atomically replaces the file on path with the given records.
+func (self *KadDb) write(path string, nodes []*NodeRecord) error {
+	data, err := json.MarshalIndent(&kadDbFile{Version: kadDbVersion, Address: self.Address, Nodes: nodes}, "", " ")
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(path, data, os.ModePerm)
-	if err != nil {
-		log.Warn(fmt.Sprintf("unable to save kaddb with %v nodes to %v: %v", n, path, err))
-	} else {
-		log.Info(fmt.Sprintf("saved kaddb with %v nodes to %v", n, path))
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
 	}
-	return err
+	return os.Rename(tmp, path)
 }
 
 // Load(path) loads the node record database (kaddb) from file on path.
-func (self *KadDb) load(path string, cb func(*NodeRecord, Node) error) (err error) {
+// Records are sorted into rows by proximityBin. Databases persisted in older
+// formats are migrated, and databases holding stale or duplicate records are
+// compacted, by rewriting the file.
+func (self *KadDb) load(path string, cb func(*NodeRecord, Node) error, proximityBin func(Address) int) (err error) {
 	defer self.lock.Unlock()
 	self.lock.Lock()
 
@@ -311,34 +352,62 @@ func (self *KadDb) load(path string, cb func(*NodeRecord, Node) error) (err erro
 	if err != nil {
 		return
 	}
-
-	err = json.Unmarshal(data, self)
-	if err != nil {
+	var version struct{ Version int }
+	if err = json.Unmarshal(data, &version); err != nil {
 		return
 	}
-	var n int
-	var purge []bool
-	for po, b := range self.Nodes {
-		purge = make([]bool, len(b))
-	ROW:
-		for i, node := range b {
-			if cb != nil {
-				err = cb(node, node.node)
-				if err != nil {
-					purge[i] = true
-					continue ROW
-				}
-			}
-			n++
-			if node.After.IsZero() {
-				node.After = time.Now()
+	var file kadDbFile
+	switch version.Version {
+	case 0:
+		var legacy legacyKadDbFile
+		if err = json.Unmarshal(data, &legacy); err != nil {
+			return
+		}
+		for _, row := range legacy.Nodes {
+			file.Nodes = append(file.Nodes, row...)
+		}
+	case kadDbVersion:
+		if err = json.Unmarshal(data, &file); err != nil {
+			return
+		}
+	default:
+		return fmt.Errorf("unsupported kaddb version %d", version.Version)
+	}
+	migrate := version.Version < kadDbVersion
+	var n, pruned int
+	for _, node := range file.Nodes {
+		if _, found := self.index[node.Addr]; found || node.Addr == self.Address || self.stale(node) {
+			pruned++
+			continue
+		}
+		if cb != nil {
+			if err = cb(node, node.node); err != nil {
+				pruned++
+				continue
 			}
-			self.index[node.Addr] = node
 		}
-		self.delete(po, purge)
+		n++
+		if node.After.IsZero() {
+			node.After = time.Now()
+		}
+		self.index[node.Addr] = node
+		po := proximityBin(node.Addr)
+		self.Nodes[po] = append(self.Nodes[po], node)
 	}
-	log.Info(fmt.Sprintf("loaded kaddb with %v nodes from %v", n, path))
+	err = nil
+	log.Info(fmt.Sprintf("loaded kaddb with %v nodes from %v, pruned %v", n, path, pruned))
 
+	if migrate || pruned > 0 {
+		var nodes []*NodeRecord
+		for _, b := range self.Nodes {
+			nodes = append(nodes, b...)
+		}
+		if err := self.write(path, nodes); err != nil {
+			log.Warn(fmt.Sprintf("unable to compact kaddb at %v: %v", path, err))
+		} else if migrate {
+			log.Info(fmt.Sprintf("migrated kaddb at %v from version %v to %v", path, version.Version, kadDbVersion))
+		}
+	}
 	return
 }
 
@@ -0,0 +1,126 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package kademlia
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readKadDbFile decodes the persisted node record database on path.
+func readKadDbFile(t *testing.T, path string) *kadDbFile {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read kaddb: %v", err)
+	}
+	file := new(kadDbFile)
+	if err := json.Unmarshal(data, file); err != nil {
+		t.Fatalf("failed to decode kaddb: %v", err)
+	}
+	return file
+}
+
+// Tests that databases in the unversioned format are loaded, and rewritten in
+// the current format with stale and duplicate records compacted away.
+func TestKadDbMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kaddb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bzz-peers.json")
+
+	self := RandomAddress()
+	params := NewDefaultKadParams()
+
+	var (
+		fresh = &NodeRecord{Addr: RandomAddress(), Url: "enode://fresh", Seen: time.Now()}
+		stale = &NodeRecord{Addr: RandomAddress(), Url: "enode://stale", Seen: time.Now().Add(-2 * params.PurgeInterval)}
+	)
+	legacy := &legacyKadDbFile{
+		Address: self,
+		Nodes:   [][]*NodeRecord{{fresh, stale}, nil, {fresh}},
+	}
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	kad := New(self, params)
+	if err := kad.Load(path, nil); err != nil {
+		t.Fatalf("failed to load legacy kaddb: %v", err)
+	}
+	if n := kad.db.count(); n != 1 {
+		t.Fatalf("loaded record count mismatch: have %d, want 1", n)
+	}
+	if _, ok := kad.db.index[fresh.Addr]; !ok {
+		t.Fatalf("fresh record not loaded")
+	}
+	row := kad.db.Nodes[kad.proximityBin(fresh.Addr)]
+	if len(row) != 1 || row[0].Addr != fresh.Addr {
+		t.Fatalf("fresh record not in its proximity row: %v", row)
+	}
+	file := readKadDbFile(t, path)
+	if file.Version != kadDbVersion {
+		t.Errorf("migrated version mismatch: have %d, want %d", file.Version, kadDbVersion)
+	}
+	if len(file.Nodes) != 1 || file.Nodes[0].Addr != fresh.Addr {
+		t.Errorf("migrated records mismatch: have %v", file.Nodes)
+	}
+}
+
+// Tests that records unseen for longer than the purge interval are not saved,
+// and that databases in unknown versions are refused.
+func TestKadDbCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kaddb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bzz-peers.json")
+
+	self := RandomAddress()
+	params := NewDefaultKadParams()
+	kad := New(self, params)
+
+	fresh, stale := RandomAddress(), RandomAddress()
+	kad.db.findOrCreate(kad.proximityBin(fresh), fresh, "enode://fresh")
+	kad.db.findOrCreate(kad.proximityBin(stale), stale, "enode://stale").Seen = time.Now().Add(-2 * params.PurgeInterval)
+
+	if err := kad.Save(path, nil); err != nil {
+		t.Fatalf("failed to save kaddb: %v", err)
+	}
+	file := readKadDbFile(t, path)
+	if len(file.Nodes) != 1 || file.Nodes[0].Addr != fresh {
+		t.Errorf("saved records mismatch: have %v", file.Nodes)
+	}
+	// Databases written by newer versions are refused
+	file.Version = kadDbVersion + 1
+	data, _ := json.Marshal(file)
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(self, params).Load(path, nil); err == nil {
+		t.Errorf("loaded kaddb of unsupported version")
+	}
+}
@@ -377,7 +377,67 @@ func (self *Kademlia) Save(path string, cb func(*NodeRecord, Node)) error {
 
 // Load(path) loads the node record database (kaddb) from file on path.
 func (self *Kademlia) Load(path string, cb func(*NodeRecord, Node) error) (err error) {
-	return self.db.load(path, cb)
+	return self.db.load(path, cb, self.proximityBin)
+}
+
+// BinInfo summarises a proximity order bin of the table.
+type BinInfo struct {
+	ProximityOrder int      `json:"po"`
+	Connected      []string `json:"connected"` // Addresses of the live peers in the bin
+	Known          int      `json:"known"`     // Number of node records in the bin
+	Cursor         int      `json:"cursor"`    // Position of the next record to consider connecting to
+}
+
+// TableInfo summarises the state of the table, as an alternative to parsing
+// its String form.
+type TableInfo struct {
+	Address   string     `json:"address"`
+	Connected int        `json:"connected"` // Number of live peers
+	Known     int        `json:"known"`     // Number of node records
+	ProxLimit int        `json:"proxLimit"` // Proximity order of the most proximate bin
+	ProxSize  int        `json:"proxSize"`  // Number of peers in the most proximate bin
+	Bins      []*BinInfo `json:"bins"`
+}
+
+// Info summarises the state of the table.
+func (self *Kademlia) Info() *TableInfo {
+	defer self.lock.RUnlock()
+	self.lock.RLock()
+	defer self.db.lock.RUnlock()
+	self.db.lock.RLock()
+
+	info := &TableInfo{
+		Address:   self.addr.String(),
+		Connected: self.count,
+		Known:     len(self.db.index),
+		ProxLimit: self.proxLimit,
+		ProxSize:  self.proxSize,
+	}
+	for i, bucket := range self.buckets {
+		bin := &BinInfo{
+			ProximityOrder: i,
+			Connected:      make([]string, 0, len(bucket)),
+			Known:          len(self.db.Nodes[i]),
+			Cursor:         self.db.cursors[i],
+		}
+		for _, node := range bucket {
+			bin.Connected = append(bin.Connected, node.Addr().String())
+		}
+		info.Bins = append(info.Bins, bin)
+	}
+	return info
+}
+
+// Nodes returns the live peers of the table.
+func (self *Kademlia) Nodes() []Node {
+	defer self.lock.RUnlock()
+	self.lock.RLock()
+
+	var nodes []Node
+	for _, bucket := range self.buckets {
+		nodes = append(nodes, bucket...)
+	}
+	return nodes
 }
 
 // kademlia table + kaddb table displayed with ascii
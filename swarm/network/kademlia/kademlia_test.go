@@ -228,6 +228,36 @@ func TestProxAdjust(t *testing.T) {
 	}
 }
 
+func TestInfo(t *testing.T) {
+	self := RandomAddress()
+	kad := New(self, NewDefaultKadParams())
+
+	near, far := RandomAddressAt(self, 3), RandomAddressAt(self, 0)
+	for _, addr := range []Address{near, far} {
+		if err := kad.On(&testNode{addr: addr}, nil); err != nil {
+			t.Fatalf("backend not accepting node: %v", err)
+		}
+	}
+	kad.Add([]*NodeRecord{{Addr: RandomAddressAt(self, 3)}})
+
+	info := kad.Info()
+	if info.Address != self.String() || info.Connected != 2 || info.Known != 3 {
+		t.Fatalf("table summary mismatch: have %+v", info)
+	}
+	if len(info.Bins) != kad.MaxProx+1 {
+		t.Fatalf("bin count mismatch: have %d, want %d", len(info.Bins), kad.MaxProx+1)
+	}
+	if bin := info.Bins[3]; len(bin.Connected) != 1 || bin.Connected[0] != near.String() || bin.Known != 2 {
+		t.Errorf("bin 3 summary mismatch: have %+v", bin)
+	}
+	if bin := info.Bins[0]; len(bin.Connected) != 1 || bin.Connected[0] != far.String() || bin.Known != 1 {
+		t.Errorf("bin 0 summary mismatch: have %+v", bin)
+	}
+	if nodes := kad.Nodes(); len(nodes) != 2 {
+		t.Errorf("live node count mismatch: have %d, want 2", len(nodes))
+	}
+}
+
 func TestSaveLoad(t *testing.T) {
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	addresses := gen([]Address{}, r).([]Address)
@@ -42,6 +42,7 @@ const (
 	deliveryRequestMsg        // 0x06
 	unsyncedKeysMsg           // 0x07
 	paymentMsg                // 0x08
+	receiptMsg                // 0x09
 )
 
 /*
@@ -171,6 +172,22 @@ func (d *retrieveRequestMsgData) getTimeout() (t *time.Time) {
 	return
 }
 
+/*
+Receipt
+
+sent by the requester of a chunk to the peer that delivered it, confirming the
+delivery answering the retrieve request with the given ID.
+Receipts feed the retrieval accounting of the serving peer.
+*/
+type receiptMsgData struct {
+	Key storage.Key // key of the chunk delivered
+	Id  uint64      // ID of the retrieve request the delivery answered
+}
+
+func (d *receiptMsgData) String() string {
+	return fmt.Sprintf("Receipt: Key: %v; ID: %v", d.Key.Log(), d.Id)
+}
+
 // peerAddr is sent in StatusMsg as part of the handshake
 type peerAddr struct {
 	IP   net.IP
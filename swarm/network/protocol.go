@@ -46,12 +46,19 @@ import (
 )
 
 const (
-	Version            = 0
-	ProtocolLength     = uint64(8)
+	Version            = 1
+	ProtocolLength     = uint64(9)
 	ProtocolMaxMsgSize = 10 * 1024 * 1024
 	NetworkId          = 3
 )
 
+// supported protocol versions, the first one is the primary, and the number of
+// messages each of them implements. Version 0 peers don't know receipts.
+var (
+	ProtocolVersions = []uint{Version, 0}
+	ProtocolLengths  = []uint64{ProtocolLength, 8}
+)
+
 // bzz represents the swarm wire protocol
 // an instance is running on each peer
 type bzz struct {
@@ -65,6 +72,7 @@ type bzz struct {
 	backend    chequebook.Backend
 	lastActive time.Time
 	NetworkId  uint64
+	version    uint // protocol version negotiated with the peer
 
 	swap        *swap.Swap          // swap instance for the peer connection
 	swapParams  *bzzswap.SwapParams // swap settings both local and remote
@@ -96,25 +104,35 @@ on each peer connection
 The Run function of the Bzz protocol class creates a bzz instance
 which will represent the peer for the swarm hive and all peer-aware components
 */
-func Bzz(cloud StorageHandler, backend chequebook.Backend, hive *Hive, dbaccess *DbAccess, sp *bzzswap.SwapParams, sy *SyncParams, networkId uint64) (p2p.Protocol, error) {
+func Bzz(cloud StorageHandler, backend chequebook.Backend, hive *Hive, dbaccess *DbAccess, sp *bzzswap.SwapParams, sy *SyncParams, networkId uint64) ([]p2p.Protocol, error) {
 
 	// a single global request db is created for all peer connections
 	// this is to persist delivery backlog and aid syncronisation
 	requestDb, err := storage.NewLDBDatabase(sy.RequestDbPath)
 	if err != nil {
-		return p2p.Protocol{}, fmt.Errorf("error setting up request db: %v", err)
+		return nil, fmt.Errorf("error setting up request db: %v", err)
 	}
 	if networkId == 0 {
 		networkId = NetworkId
 	}
-	return p2p.Protocol{
-		Name:    "bzz",
-		Version: Version,
-		Length:  ProtocolLength,
-		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
-			return run(requestDb, cloud, backend, hive, dbaccess, sp, sy, networkId, p, rw)
-		},
-	}, nil
+	// one protocol per supported version, devp2p picks the highest one both
+	// peers speak
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version
+		protocols[i] = p2p.Protocol{
+			Name:    "bzz",
+			Version: version,
+			Length:  ProtocolLengths[i],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return run(requestDb, cloud, backend, hive, dbaccess, sp, sy, networkId, version, p, rw)
+			},
+			NodeInfo: func() interface{} {
+				return hive.NodeInfo(dbaccess)
+			},
+		}
+	}
+	return protocols, nil
 }
 
 /*
@@ -129,7 +147,7 @@ the main protocol loop that
  * whenever the loop terminates, the peer will disconnect with Subprotocol error
  * whenever handlers return an error the loop terminates
 */
-func run(requestDb *storage.LDBDatabase, depo StorageHandler, backend chequebook.Backend, hive *Hive, dbaccess *DbAccess, sp *bzzswap.SwapParams, sy *SyncParams, networkId uint64, p *p2p.Peer, rw p2p.MsgReadWriter) (err error) {
+func run(requestDb *storage.LDBDatabase, depo StorageHandler, backend chequebook.Backend, hive *Hive, dbaccess *DbAccess, sp *bzzswap.SwapParams, sy *SyncParams, networkId uint64, version uint, p *p2p.Peer, rw p2p.MsgReadWriter) (err error) {
 
 	self := &bzz{
 		storage:     depo,
@@ -144,6 +162,7 @@ func run(requestDb *storage.LDBDatabase, depo StorageHandler, backend chequebook
 		swapEnabled: hive.swapEnabled,
 		syncEnabled: true,
 		NetworkId:   networkId,
+		version:     version,
 	}
 
 	// handle handshake
@@ -296,6 +315,15 @@ func (b *bzz) handle() error {
 			b.swap.Receive(int(req.Units), req.Promise)
 		}
 
+	case receiptMsg:
+		// confirmation of a chunk we delivered to the peer
+		var req receiptMsgData
+		if err := msg.Decode(&req); err != nil {
+			return fmt.Errorf("<- %v: %v", msg, err)
+		}
+		log.Trace(fmt.Sprintf("<- receipt: %s", req.String()))
+		b.hive.stats.confirmed(b.remoteAddr.Addr)
+
 	default:
 		// no other message is allowed
 		return fmt.Errorf("invalid message code: %v", msg.Code)
@@ -306,7 +334,7 @@ func (b *bzz) handle() error {
 func (b *bzz) handleStatus() (err error) {
 
 	handshake := &statusMsgData{
-		Version:   uint64(Version),
+		Version:   uint64(b.version),
 		ID:        "honey",
 		Addr:      b.selfAddr(),
 		NetworkId: b.NetworkId,
@@ -345,8 +373,8 @@ func (b *bzz) handleStatus() (err error) {
 		return fmt.Errorf("network id mismatch: %d (!= %d)", status.NetworkId, b.NetworkId)
 	}
 
-	if Version != status.Version {
-		return fmt.Errorf("protocol version mismatch: %d (!= %d)", status.Version, Version)
+	if uint64(b.version) != status.Version {
+		return fmt.Errorf("protocol version mismatch: %d (!= %d)", status.Version, b.version)
 	}
 
 	b.remoteAddr = b.peerAddr(status.Addr)
@@ -391,6 +419,7 @@ func (b *bzz) sync(state *syncState) error {
 	} else {
 		state.synced = make(chan bool)
 		state.SessionAt = cnt
+		resumeSync(state, cnt)
 		if storage.IsZeroKey(state.Stop) && state.Synced {
 			state.Start = storage.Key(start[:])
 			state.Stop = storage.Key(stop[:])
@@ -447,6 +476,14 @@ func (b *bzz) retrieve(req *retrieveRequestMsgData) error {
 	return b.send(retrieveRequestMsg, req)
 }
 
+// send receiptMsg, unless the peer speaks version 0 predating receipts
+func (b *bzz) receipt(req *receiptMsgData) error {
+	if b.version < 1 {
+		return nil
+	}
+	return b.send(receiptMsg, req)
+}
+
 // send storeRequestMsg
 func (b *bzz) store(req *storeRequestMsgData) error {
 	return b.send(storeRequestMsg, req)
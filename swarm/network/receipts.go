@@ -0,0 +1,139 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/swarm/network/kademlia"
+)
+
+/*
+Retrieval accounting
+
+Each node keeps per peer counters of the chunk retrievals it asked the peer for
+and the deliveries it got in return, and of the chunks it served to the peer.
+Requesters confirm every delivery answering one of their retrieve requests with
+a receipt message sent back to the serving peer, so that both ends of a
+retrieval know which peer served the chunk. The counters outlive the peer
+connections, so that reconnecting peers keep their record, but only for the
+retrievalStatsLimit most recently active peers.
+*/
+
+// retrievalStatsLimit is the number of peers whose retrieval counters are kept.
+const retrievalStatsLimit = 1024
+
+// PeerRetrievalStats are the retrieval counters kept about a peer.
+type PeerRetrievalStats struct {
+	Requested uint64 `json:"requested"` // Retrieve requests sent to the peer
+	Delivered uint64 `json:"delivered"` // Chunks delivered by the peer for our requests
+	TimedOut  uint64 `json:"timedOut"`  // Retrieve requests the peer did not deliver in time
+	Served    uint64 `json:"served"`    // Chunks delivered to the peer for its requests
+	Confirmed uint64 `json:"confirmed"` // Chunks served to the peer it confirmed with a receipt
+
+	LastDelivery time.Time `json:"lastDelivery"` // Time of the last chunk delivered by the peer
+	Score        float64   `json:"score"`        // Quality of the peer as a retrieval source, see score
+
+	active time.Time // Time the counters were last updated
+}
+
+// score rates the peer as a retrieval source between 0 and 1, as its smoothed
+// ratio of delivered to requested chunks. Unknown peers score 0.5.
+func (s *PeerRetrievalStats) score() float64 {
+	return float64(s.Delivered+1) / float64(s.Requested+2)
+}
+
+// retrievalStats keeps the retrieval counters of all peers ever retrieved from
+// or served to.
+type retrievalStats struct {
+	peers map[kademlia.Address]*PeerRetrievalStats
+	lock  sync.Mutex
+}
+
+func newRetrievalStats() *retrievalStats {
+	return &retrievalStats{
+		peers: make(map[kademlia.Address]*PeerRetrievalStats),
+	}
+}
+
+// update applies fn to the counters of a peer under the lock, forgetting the
+// least recently active peer if the counters of too many are kept.
+func (r *retrievalStats) update(addr kademlia.Address, fn func(*PeerRetrievalStats)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	stats, ok := r.peers[addr]
+	if !ok {
+		if len(r.peers) >= retrievalStatsLimit {
+			var oldest kademlia.Address
+			for peer, s := range r.peers {
+				if stats == nil || s.active.Before(stats.active) {
+					oldest, stats = peer, s
+				}
+			}
+			delete(r.peers, oldest)
+		}
+		stats = new(PeerRetrievalStats)
+		r.peers[addr] = stats
+	}
+	stats.active = time.Now()
+	fn(stats)
+}
+
+func (r *retrievalStats) requested(addr kademlia.Address) {
+	r.update(addr, func(s *PeerRetrievalStats) { s.Requested++ })
+}
+
+func (r *retrievalStats) delivered(addr kademlia.Address) {
+	r.update(addr, func(s *PeerRetrievalStats) {
+		s.Delivered++
+		s.LastDelivery = time.Now()
+	})
+}
+
+func (r *retrievalStats) timedOut(addr kademlia.Address) {
+	r.update(addr, func(s *PeerRetrievalStats) { s.TimedOut++ })
+}
+
+func (r *retrievalStats) served(addr kademlia.Address) {
+	r.update(addr, func(s *PeerRetrievalStats) { s.Served++ })
+}
+
+func (r *retrievalStats) confirmed(addr kademlia.Address) {
+	r.update(addr, func(s *PeerRetrievalStats) { s.Confirmed++ })
+}
+
+// snapshot returns a copy of the counters of all peers keyed by their address.
+func (r *retrievalStats) snapshot() map[string]PeerRetrievalStats {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	stats := make(map[string]PeerRetrievalStats, len(r.peers))
+	for addr, s := range r.peers {
+		peer := *s
+		peer.Score = s.score()
+		stats[addr.String()] = peer
+	}
+	return stats
+}
+
+// RetrievalStats returns the retrieval counters of all peers ever retrieved
+// from or served to, keyed by their overlay address.
+func (h *Hive) RetrievalStats() map[string]PeerRetrievalStats {
+	return h.stats.snapshot()
+}
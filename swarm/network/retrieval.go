@@ -0,0 +1,163 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"sync"
+	"time"
+
+	"github.com/fulcrumchain/indigo/metrics"
+	"github.com/fulcrumchain/indigo/swarm/storage"
+)
+
+const (
+	// time a chunk that could not be found is reported missing without asking
+	// peers again
+	retrieveFailureTTL = 10 * time.Second
+	// number of failed chunks remembered, beyond which the expired ones are
+	// pruned and, if still full, the oldest dropped
+	maxRetrieveFailures = 4096
+)
+
+var (
+	retrieveJoinedCounter = metrics.NewCounter("swarm/retrieve/joined")
+	retrieveMemoedCounter = metrics.NewCounter("swarm/retrieve/memoed")
+)
+
+// retrieval is a network search for a chunk in flight, shared by the requests
+// of every chunk entry with the same key. Entries of the same key coexist when
+// a request was evicted from the memory store while searching.
+type retrieval struct {
+	chunks []*storage.Chunk // Entries waiting for the chunk, the first one searching
+	done   chan struct{}    // Closed once the chunk is delivered
+}
+
+// retrievalCoordinator dedupes concurrent searches for the same chunk key so
+// only one of them asks peers, and memoizes the chunks recently not found so
+// repeated requests for them do not flood peers.
+type retrievalCoordinator struct {
+	inflight map[string]*retrieval
+	failures map[string]time.Time // Time a chunk was last not found
+	lock     sync.Mutex
+
+	now func() time.Time // Clock, replaced in tests
+}
+
+func newRetrievalCoordinator() *retrievalCoordinator {
+	return &retrievalCoordinator{
+		inflight: make(map[string]*retrieval),
+		failures: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// join registers a chunk entry searched for. It returns the search to run if
+// none is in flight for its key, nil if it joined one, in which case it is
+// resolved along with it. Chunks recently not found fail right away.
+func (c *retrievalCoordinator) join(chunk *storage.Chunk) *retrieval {
+	key := string(chunk.Key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if failed, ok := c.failures[key]; ok {
+		if c.now().Sub(failed) < retrieveFailureTTL {
+			retrieveMemoedCounter.Inc(1)
+			chunk.Req.FailSearch()
+			return nil
+		}
+		delete(c.failures, key)
+	}
+	if r, ok := c.inflight[key]; ok {
+		for _, joined := range r.chunks {
+			if joined.Req == chunk.Req {
+				return nil
+			}
+		}
+		retrieveJoinedCounter.Inc(1)
+		r.chunks = append(r.chunks, chunk)
+		return nil
+	}
+	r := &retrieval{chunks: []*storage.Chunk{chunk}, done: make(chan struct{})}
+	c.inflight[key] = r
+	return r
+}
+
+// delivered resolves the search for a delivered chunk, handing its data over to
+// the other entries waiting for it.
+func (c *retrievalCoordinator) delivered(chunk *storage.Chunk) {
+	key := string(chunk.Key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.failures, key)
+	r, ok := c.inflight[key]
+	if !ok {
+		return
+	}
+	delete(c.inflight, key)
+	close(r.done)
+
+	for _, waiting := range r.chunks {
+		if waiting.Req == chunk.Req {
+			continue
+		}
+		select {
+		case <-waiting.Req.C:
+		default:
+			waiting.SData, waiting.Size = chunk.SData, chunk.Size
+			close(waiting.Req.C)
+		}
+	}
+}
+
+// failed resolves a search that gave up, failing every entry waiting for the
+// chunk and remembering it as not found.
+func (c *retrievalCoordinator) failed(r *retrieval) {
+	key := string(r.chunks[0].Key)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.inflight[key] != r {
+		// Delivered just as the search gave up
+		return
+	}
+	delete(c.inflight, key)
+	for _, waiting := range r.chunks {
+		waiting.Req.FailSearch()
+	}
+	now := c.now()
+	if len(c.failures) >= maxRetrieveFailures {
+		var (
+			oldest     string
+			oldestTime time.Time
+		)
+		for k, t := range c.failures {
+			if now.Sub(t) >= retrieveFailureTTL {
+				delete(c.failures, k)
+			} else if oldest == "" || t.Before(oldestTime) {
+				oldest, oldestTime = k, t
+			}
+		}
+		if len(c.failures) >= maxRetrieveFailures {
+			delete(c.failures, oldest)
+		}
+	}
+	c.failures[key] = now
+}
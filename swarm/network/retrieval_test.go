@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fulcrumchain/indigo/swarm/storage"
+)
+
+// newSearchedChunk returns a chunk entry whose network search was started.
+func newSearchedChunk(key storage.Key) *storage.Chunk {
+	rs := &storage.RequestStatus{Key: key, C: make(chan bool), Requesters: make(map[uint64][]interface{})}
+	rs.StartSearch()
+	return storage.NewChunk(key, rs)
+}
+
+func isClosed(c <-chan bool) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// Tests that concurrent searches for a chunk are deduped, the delivery of the
+// chunk resolving every entry waiting for it.
+func TestRetrievalDedup(t *testing.T) {
+	c := newRetrievalCoordinator()
+	key := storage.Key{0x01}
+
+	first, second := newSearchedChunk(key), newSearchedChunk(key)
+	search := c.join(first)
+	if search == nil {
+		t.Fatalf("first search not started")
+	}
+	if c.join(second) != nil {
+		t.Fatalf("concurrent search not deduped")
+	}
+	if c.join(newSearchedChunk(storage.Key{0x02})) == nil {
+		t.Fatalf("search of another chunk deduped")
+	}
+	first.SData, first.Size = []byte{1, 2, 3}, 3
+	c.delivered(first)
+
+	select {
+	case <-search.done:
+	default:
+		t.Errorf("search not resolved on delivery")
+	}
+	if !isClosed(second.Req.C) || string(second.SData) != string(first.SData) || second.Size != 3 {
+		t.Errorf("joined entry not delivered: %v", second.SData)
+	}
+	if isClosed(first.Req.C) {
+		t.Errorf("delivered entry closed by the coordinator")
+	}
+	if c.join(newSearchedChunk(key)) == nil {
+		t.Errorf("new search not started after delivery")
+	}
+}
+
+// Tests that chunks not found fail right away until the failure expires.
+func TestRetrievalFailureMemo(t *testing.T) {
+	now := time.Unix(1000, 0)
+	c := newRetrievalCoordinator()
+	c.now = func() time.Time { return now }
+	key := storage.Key{0x01}
+
+	first, second := newSearchedChunk(key), newSearchedChunk(key)
+	search := c.join(first)
+	c.join(second)
+	c.failed(search)
+
+	if !isClosed(first.Req.Failed()) || !isClosed(second.Req.Failed()) {
+		t.Fatalf("waiting entries not failed")
+	}
+	memoed := newSearchedChunk(key)
+	if c.join(memoed) != nil {
+		t.Fatalf("search started for a chunk recently not found")
+	}
+	if !isClosed(memoed.Req.Failed()) {
+		t.Errorf("memoed failure not reported")
+	}
+	now = now.Add(retrieveFailureTTL)
+	if c.join(newSearchedChunk(key)) == nil {
+		t.Errorf("search not started after the failure expired")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 
 	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
 	"github.com/fulcrumchain/indigo/swarm/storage"
 )
 
@@ -35,6 +36,9 @@ const (
 	syncCacheSize      = 1024 // cache capacity to store request queue in memory
 )
 
+// counter of the sync states of rejoining peers reset as our store was reset
+var syncResetCounter = metrics.NewCounter("swarm/sync/reset")
+
 // priorities
 const (
 	Low        = iota // 0
@@ -118,6 +122,37 @@ func (s syncState) String() string {
 	}
 }
 
+// cursor returns the storage counter from which the history is synced: the
+// counter the peer was last seen at, or for unfinished syncs, the beginning of
+// the unfinished segment.
+func (s *syncState) cursor() uint64 {
+	if !s.Synced && s.First < s.LastSeenAt {
+		return s.First
+	}
+	return s.LastSeenAt
+}
+
+// resumeSync validates the sync state persisted by a rejoining peer against
+// the storage counter of the local store before syncHistory resumes it from
+// LastSeenAt. It returns false if the cursor lies beyond counter, i.e. if the
+// local store was reset since, in which case the state is reset to sync the
+// full history.
+func resumeSync(state *syncState, counter uint64) bool {
+	if state.DbSyncState == nil {
+		state.DbSyncState = &storage.DbSyncState{}
+	}
+	if state.LastSeenAt <= counter && state.First <= counter && state.Last <= counter {
+		return true
+	}
+	log.Debug(fmt.Sprintf("sync cursor %v beyond storage counter %v, syncing full history", state.cursor(), counter))
+	state.DbSyncState = &storage.DbSyncState{}
+	state.LastSeenAt = 0
+	state.Latest = storage.ZeroKey
+	state.Synced = true
+	syncResetCounter.Inc(1)
+	return false
+}
+
 // syncer parameters (global, not peer specific)
 type SyncParams struct {
 	RequestDbPath      string // path for request db (leveldb)
@@ -0,0 +1,54 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"testing"
+
+	"github.com/fulcrumchain/indigo/swarm/storage"
+)
+
+func TestResumeSync(t *testing.T) {
+	tests := []struct {
+		state    syncState
+		counter  uint64
+		accepted bool
+		cursor   uint64
+	}{
+		// new peers sync the full history
+		{syncState{DbSyncState: &storage.DbSyncState{}}, 100, true, 0},
+		// synced peers resume from where they were last seen
+		{syncState{DbSyncState: &storage.DbSyncState{First: 20, Last: 40}, LastSeenAt: 40, Synced: true}, 100, true, 40},
+		// unfinished syncs resume from the unfinished segment
+		{syncState{DbSyncState: &storage.DbSyncState{First: 20, Last: 40}, LastSeenAt: 60}, 100, true, 20},
+		// cursors beyond a reset store are rejected
+		{syncState{DbSyncState: &storage.DbSyncState{First: 20, Last: 40}, LastSeenAt: 60, Synced: true}, 50, false, 0},
+		{syncState{DbSyncState: &storage.DbSyncState{First: 20, Last: 40}, LastSeenAt: 30}, 10, false, 0},
+	}
+	for i, tt := range tests {
+		state := tt.state
+		if accepted := resumeSync(&state, tt.counter); accepted != tt.accepted {
+			t.Errorf("test %d: acceptance mismatch: have %v, want %v", i, accepted, tt.accepted)
+		}
+		if cursor := state.cursor(); cursor != tt.cursor {
+			t.Errorf("test %d: cursor mismatch: have %v, want %v", i, cursor, tt.cursor)
+		}
+		if !tt.accepted && (!state.Synced || state.First != 0 || state.Last != 0) {
+			t.Errorf("test %d: rejected state not reset: %v", i, state)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package network
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/swarm/network/kademlia"
+)
+
+/*
+Connection warm-up
+
+Filling the kademlia table from the node records alone takes minutes, as the
+suggestion loop dials a single peer per call interval. To cut the cold start
+short, the hive records the best of its connected peers when stopped, and dials
+all of them at once when started again, before the suggestion loop kicks in.
+*/
+
+// defaultWarmPeers is the default number of peers recorded for the warm-up.
+const defaultWarmPeers = 16
+
+// warmPeer is a peer recorded for the connection warm-up.
+type warmPeer struct {
+	Addr       kademlia.Address `json:"addr"`
+	Url        string           `json:"url"`
+	LastActive time.Time        `json:"lastActive"`
+	Score      float64          `json:"score"`
+}
+
+// warmPeers selects the connected peers to dial first on the next start: the
+// highest scoring retrieval sources, the most recently active first among
+// peers scoring the same, at most max of them.
+func (h *Hive) warmPeers(max int) []*warmPeer {
+	scores := h.stats.snapshot()
+
+	var peers []*warmPeer
+	for _, node := range h.kad.Nodes() {
+		p, ok := node.(*peer)
+		if !ok {
+			continue
+		}
+		wp := &warmPeer{
+			Addr:       p.Addr(),
+			Url:        p.Url(),
+			LastActive: p.LastActive(),
+			Score:      new(PeerRetrievalStats).score(),
+		}
+		if stats, ok := scores[wp.Addr.String()]; ok {
+			wp.Score = stats.Score
+		}
+		peers = append(peers, wp)
+	}
+	sort.Slice(peers, func(i, j int) bool {
+		if peers[i].Score != peers[j].Score {
+			return peers[i].Score > peers[j].Score
+		}
+		return peers[i].LastActive.After(peers[j].LastActive)
+	})
+	if len(peers) > max {
+		peers = peers[:max]
+	}
+	return peers
+}
+
+// saveWarmPeers records the peers to dial first on the next start into the
+// file on path. Nodes stopped without peers keep the previous record.
+func (h *Hive) saveWarmPeers(path string, max int) error {
+	if len(path) == 0 || max <= 0 {
+		return nil
+	}
+	peers := h.warmPeers(max)
+	if len(peers) == 0 {
+		return nil
+	}
+	data, err := json.MarshalIndent(peers, "", " ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("saved %d warm-up peers to %v", len(peers), path))
+	return nil
+}
+
+// loadWarmPeers reads the peers recorded for the warm-up from the file on path.
+func loadWarmPeers(path string) ([]*warmPeer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var peers []*warmPeer
+	if err := json.Unmarshal(data, &peers); err != nil {
+		return nil, err
+	}
+	return peers, nil
+}
+
+// warmUp dials the peers recorded at the last stop, at most max of them, and
+// returns the number of connection attempts made.
+func (h *Hive) warmUp(path string, max int, connectPeer func(string) error) int {
+	if len(path) == 0 || max <= 0 {
+		return 0
+	}
+	peers, err := loadWarmPeers(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn(fmt.Sprintf("error reading warm-up peers '%s' (skipping): %v", path, err))
+		}
+		return 0
+	}
+	if len(peers) > max {
+		peers = peers[:max]
+	}
+	dialed := 0
+	for _, p := range peers {
+		if len(p.Url) == 0 || p.Addr == h.addr {
+			continue
+		}
+		log.Trace(fmt.Sprintf("warm-up call to bee %v", p.Url))
+		if err := connectPeer(p.Url); err != nil {
+			log.Debug(fmt.Sprintf("warm-up call to bee %v failed: %v", p.Url, err))
+			continue
+		}
+		dialed++
+	}
+	log.Info(fmt.Sprintf("warm-up dialed %d of %d recorded bees", dialed, len(peers)))
+	return dialed
+}
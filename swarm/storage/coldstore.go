@@ -0,0 +1,176 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+/*
+Cold tier
+
+Chunks evicted from the store by garbage collection can be offloaded to a cold
+store, typically the S3 compatible bucket of an ethdb/archive.Archive, instead
+of being dropped. Offloaded chunks are uploaded in the background and marked in
+the database once archived, so that lookups of chunks never offloaded don't hit
+the cold store. Marked chunks missing locally are fetched on demand and stored
+locally again.
+*/
+
+const (
+	coldKeyPrefix = "swarm/chunks/" // prefix of chunk keys in the cold store
+	coldQueueSize = 1024            // number of evicted chunks awaiting upload before they are dropped
+)
+
+var (
+	coldPutMeter  = metrics.NewMeter("swarm/storage/cold/put")
+	coldGetMeter  = metrics.NewMeter("swarm/storage/cold/get")
+	coldDropMeter = metrics.NewMeter("swarm/storage/cold/drop")
+)
+
+// ColdStore is a remote store for rarely accessed chunks. It is implemented by
+// ethdb/archive.Archive.
+type ColdStore interface {
+	Put(key string, value []byte) (int64, error)
+	Get(key string) ([]byte, error)
+}
+
+// coldItem is an evicted chunk awaiting upload.
+type coldItem struct {
+	key  Key
+	data []byte
+}
+
+// coldTier offloads chunks to a ColdStore and fetches them back.
+type coldTier struct {
+	store ColdStore
+	db    *LDBDatabase // database of the store, holding the archived markers
+
+	lock    sync.Mutex
+	pending map[string][]byte // data of the chunks queued for upload, by key
+	queue   chan coldItem
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newColdTier(store ColdStore, db *LDBDatabase) *coldTier {
+	c := &coldTier{
+		store:   store,
+		db:      db,
+		pending: make(map[string][]byte),
+		queue:   make(chan coldItem, coldQueueSize),
+		quit:    make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.loop()
+	return c
+}
+
+// coldKey returns the key of a chunk in the cold store.
+func coldKey(key Key) string {
+	return coldKeyPrefix + hex.EncodeToString(key)
+}
+
+// getColdMarkerKey returns the database key marking a chunk as archived.
+func getColdMarkerKey(key Key) []byte {
+	return append([]byte{kpCold}, key...)
+}
+
+// archived reports whether a chunk is stored in the cold store.
+func (c *coldTier) archived(key Key) bool {
+	_, err := c.db.Get(getColdMarkerKey(key))
+	return err == nil
+}
+
+// offload queues an evicted chunk for upload, unless it is already archived.
+// Chunks are dropped if the queue is full.
+func (c *coldTier) offload(key Key, data []byte) {
+	if c.archived(key) {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.pending[string(key)]; ok {
+		return
+	}
+	select {
+	case c.queue <- coldItem{key: key, data: data}:
+		c.pending[string(key)] = data
+	default:
+		coldDropMeter.Mark(1)
+		log.Trace(fmt.Sprintf("DbStore: cold store queue full, dropping chunk %v", key.Log()))
+	}
+}
+
+// fetch retrieves the data of an offloaded chunk, either from the upload queue
+// or from the cold store.
+func (c *coldTier) fetch(key Key) ([]byte, error) {
+	c.lock.Lock()
+	data, ok := c.pending[string(key)]
+	c.lock.Unlock()
+	if ok {
+		return data, nil
+	}
+	if !c.archived(key) {
+		return nil, notFound
+	}
+	coldGetMeter.Mark(1)
+	return c.store.Get(coldKey(key))
+}
+
+// loop uploads the queued chunks until the tier is closed.
+func (c *coldTier) loop() {
+	defer c.wg.Done()
+	for {
+		select {
+		case item := <-c.queue:
+			c.upload(item)
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func (c *coldTier) upload(item coldItem) {
+	defer func() {
+		c.lock.Lock()
+		delete(c.pending, string(item.key))
+		c.lock.Unlock()
+	}()
+	if _, err := c.store.Put(coldKey(item.key), item.data); err != nil {
+		coldDropMeter.Mark(1)
+		log.Warn(fmt.Sprintf("DbStore: chunk %v could not be offloaded: %v", item.key.Log(), err))
+		return
+	}
+	c.db.Put(getColdMarkerKey(item.key), []byte{1})
+	coldPutMeter.Mark(1)
+}
+
+// close stops the uploads, dropping the chunks still queued.
+func (c *coldTier) close() {
+	close(c.quit)
+	c.wg.Wait()
+	if n := len(c.queue); n > 0 {
+		log.Warn(fmt.Sprintf("DbStore: dropped %d chunks awaiting offload on shutdown", n))
+	}
+}
@@ -30,8 +30,12 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/log"
 	"github.com/fulcrumchain/indigo/rlp"
 	"github.com/syndtr/goleveldb/leveldb"
@@ -47,7 +51,14 @@ const (
 
 	// key prefixes for leveldb storage
 	kpIndex = 0
-	kpData  = 1
+	kpData  = 1 // chunk data of the legacy layout
+	kpCold  = 8 // markers of the chunks archived in the cold store
+
+	// layouts of the chunk data
+	layoutLegacy  = 0 // data stored in leveldb, keyed by storage index
+	layoutSharded = 1 // data stored in shard files, see shardstore.go
+
+	migrateBatchSize = 1000 // number of chunks migrated per database write
 )
 
 var (
@@ -55,6 +66,8 @@ var (
 	keyEntryCnt  = []byte{3}
 	keyDataIdx   = []byte{4}
 	keyGCPos     = []byte{5}
+	keyLayout    = []byte{6}
+	keyMigration = []byte{7} // index key of the next chunk to migrate to the sharded layout
 )
 
 type gcItem struct {
@@ -74,6 +87,14 @@ type DbStore struct {
 
 	hashfunc SwarmHasher
 
+	shards   *shardStore
+	sharded  bool   // whether the data of all chunks is in the shard files
+	migrated uint64 // number of chunks migrated to the shard files since start
+
+	cold *coldTier // cold store evicted chunks are offloaded to, if any
+
+	quit chan struct{}
+	wg   sync.WaitGroup
 	lock sync.Mutex
 }
 
@@ -86,6 +107,13 @@ func NewDbStore(path string, hash SwarmHasher, capacity uint64, radius int) (s *
 	if err != nil {
 		return
 	}
+	s.shards, err = newShardStore(filepath.Join(path, "shards"))
+	if err != nil {
+		s.db.Close()
+		return nil, err
+	}
+	data, _ := s.db.Get(keyLayout)
+	s.sharded = len(data) > 0 && data[0] == layoutSharded
 
 	s.setCapacity(capacity)
 
@@ -93,7 +121,7 @@ func NewDbStore(path string, hash SwarmHasher, capacity uint64, radius int) (s *
 	s.gcStartPos[0] = kpIndex
 	s.gcArray = make([]*gcItem, gcArraySize)
 
-	data, _ := s.db.Get(keyEntryCnt)
+	data, _ = s.db.Get(keyEntryCnt)
 	s.entryCnt = BytesToU64(data)
 	data, _ = s.db.Get(keyAccessCnt)
 	s.accessCnt = BytesToU64(data)
@@ -103,6 +131,11 @@ func NewDbStore(path string, hash SwarmHasher, capacity uint64, radius int) (s *
 	if s.gcPos == nil {
 		s.gcPos = s.gcStartPos
 	}
+	s.quit = make(chan struct{})
+	if !s.sharded {
+		s.wg.Add(1)
+		go s.migrate()
+	}
 	return
 }
 
@@ -229,7 +262,7 @@ func (s *DbStore) collectGarbage(ratio float32) {
 		}
 
 		gci := new(gcItem)
-		gci.idxKey = s.gcPos
+		gci.idxKey = common.CopyBytes(s.gcPos) // the iterator reuses its key buffer
 		var index dpaDBIndex
 		decodeIndex(it.Value(), &index)
 		gci.idx = index.Idx
@@ -254,6 +287,9 @@ func (s *DbStore) collectGarbage(ratio float32) {
 	// actual gc
 	for i := 0; i < gcnt; i++ {
 		if s.gcArray[i].value <= cutval {
+			if s.cold != nil {
+				s.offload(s.gcArray[i].idx, s.gcArray[i].idxKey)
+			}
 			s.delete(s.gcArray[i].idx, s.gcArray[i].idxKey)
 		}
 	}
@@ -281,7 +317,9 @@ func (s *DbStore) Export(out io.Writer) (int64, error) {
 		var index dpaDBIndex
 		decodeIndex(it.Value(), &index)
 
-		data, err := s.db.Get(getDataKey(index.Idx))
+		s.lock.Lock()
+		data, err := s.getData(Key(key[1:]), index.Idx)
+		s.lock.Unlock()
 		if err != nil {
 			log.Warn(fmt.Sprintf("Chunk %x found but could not be accessed: %v", key[:], err))
 			continue
@@ -357,7 +395,9 @@ func (s *DbStore) Cleanup() {
 		var index dpaDBIndex
 		decodeIndex(it.Value(), &index)
 
-		data, err := s.db.Get(getDataKey(index.Idx))
+		s.lock.Lock()
+		data, err := s.getData(Key(key[1:]), index.Idx)
+		s.lock.Unlock()
 		if err != nil {
 			log.Warn(fmt.Sprintf("Chunk %x found but could not be accessed: %v", key[:], err))
 			s.delete(index.Idx, getIndexKey(key[1:]))
@@ -378,7 +418,21 @@ func (s *DbStore) Cleanup() {
 	log.Warn(fmt.Sprintf("Found %v errors out of %v entries", errorsFound, total))
 }
 
+// offload queues the data of a chunk about to be garbage collected for upload
+// to the cold store.
+func (s *DbStore) offload(idx uint64, idxKey []byte) {
+	key := Key(common.CopyBytes(idxKey[1:]))
+	data, err := s.getData(key, idx)
+	if err != nil {
+		return
+	}
+	s.cold.offload(key, data)
+}
+
 func (s *DbStore) delete(idx uint64, idxKey []byte) {
+	if err := s.shards.delete(Key(idxKey[1:])); err != nil {
+		log.Warn(fmt.Sprintf("Chunk %x could not be deleted: %v", idxKey[1:], err))
+	}
 	batch := new(leveldb.Batch)
 	batch.Delete(idxKey)
 	batch.Delete(getDataKey(idx))
@@ -393,10 +447,22 @@ func (s *DbStore) Counter() uint64 {
 	return s.dataIdx
 }
 
+// stats returns the number of chunks stored and the capacity of the store.
+func (s *DbStore) stats() (entries, capacity uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.entryCnt, s.capacity
+}
+
 func (s *DbStore) Put(chunk *Chunk) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.put(chunk)
+}
+
+// put stores a chunk. The lock must be held.
+func (s *DbStore) put(chunk *Chunk) {
 	ikey := getIndexKey(chunk.Key)
 	var index dpaDBIndex
 
@@ -415,10 +481,15 @@ func (s *DbStore) Put(chunk *Chunk) {
 		s.collectGarbage(gcArrayFreeRatio)
 	}
 
+	if err := s.shards.put(chunk.Key, data); err != nil {
+		log.Error(fmt.Sprintf("DbStore.Put: chunk %v could not be stored: %v", chunk.Key.Log(), err))
+		if chunk.dbStored != nil {
+			close(chunk.dbStored)
+		}
+		return
+	}
 	batch := new(leveldb.Batch)
 
-	batch.Put(getDataKey(s.dataIdx), data)
-
 	index.Idx = s.dataIdx
 	s.updateIndexAccess(&index)
 
@@ -461,6 +532,41 @@ func (s *DbStore) tryAccessIdx(ikey []byte, index *dpaDBIndex) bool {
 }
 
 func (s *DbStore) Get(key Key) (chunk *Chunk, err error) {
+	chunk, err = s.get(key)
+	if err == notFound && s.cold != nil {
+		return s.getCold(key)
+	}
+	return chunk, err
+}
+
+// getCold fetches a chunk offloaded to the cold store and stores it locally
+// again. The lock is not held while fetching.
+func (s *DbStore) getCold(key Key) (*Chunk, error) {
+	data, err := s.cold.fetch(key)
+	if err != nil {
+		if err != notFound {
+			log.Debug(fmt.Sprintf("DbStore: chunk %v could not be fetched from cold store: %v", key.Log(), err))
+			err = notFound
+		}
+		return nil, err
+	}
+	hasher := s.hashfunc()
+	hasher.Write(data)
+	if !bytes.Equal(hasher.Sum(nil), key) {
+		log.Warn(fmt.Sprintf("DbStore: invalid chunk %v in cold store", key.Log()))
+		return nil, notFound
+	}
+	chunk := &Chunk{Key: key}
+	decodeData(data, chunk)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.put(chunk)
+	return chunk, nil
+}
+
+// get looks up a chunk in the local database.
+func (s *DbStore) get(key Key) (chunk *Chunk, err error) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
@@ -468,7 +574,7 @@ func (s *DbStore) Get(key Key) (chunk *Chunk, err error) {
 
 	if s.tryAccessIdx(getIndexKey(key), &index) {
 		var data []byte
-		data, err = s.db.Get(getDataKey(index.Idx))
+		data, err = s.getData(key, index.Idx)
 		if err != nil {
 			log.Trace(fmt.Sprintf("DBStore: Chunk %v found but could not be accessed: %v", key.Log(), err))
 			s.delete(index.Idx, getIndexKey(key))
@@ -526,10 +632,126 @@ func (s *DbStore) setCapacity(c uint64) {
 	}
 }
 
+// SetColdStore makes the store offload the chunks it garbage collects to cs,
+// fetching them back on demand. It must be called before the store is used.
+func (s *DbStore) SetColdStore(cs ColdStore) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cold = newColdTier(cs, s.db)
+}
+
 func (s *DbStore) Close() {
+	close(s.quit)
+	s.wg.Wait()
+	if s.cold != nil {
+		s.cold.close()
+	}
 	s.db.Close()
 }
 
+// getData retrieves the data of a chunk from its shard file or, for chunks not
+// yet migrated to the sharded layout, from the database.
+func (s *DbStore) getData(key Key, idx uint64) ([]byte, error) {
+	data, err := s.shards.get(key)
+	if err == nil || s.sharded || !os.IsNotExist(err) {
+		return data, err
+	}
+	return s.db.Get(getDataKey(idx))
+}
+
+// layout reports whether the data of all chunks is in the shard files, and the
+// number of chunks migrated to them since the store was opened.
+func (s *DbStore) layout() (sharded bool, migrated uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.sharded, s.migrated
+}
+
+// migrate moves the data of the chunks stored in the database into the shard
+// files. Chunks are migrated in batches, leaving the store usable in between,
+// and the migration resumes where it stopped when the store is reopened.
+func (s *DbStore) migrate() {
+	defer s.wg.Done()
+
+	start := time.Now()
+	logged := start
+	for {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		done, err := s.migrateBatch(migrateBatchSize)
+		if err != nil {
+			log.Error(fmt.Sprintf("DbStore: migration to sharded layout failed: %v", err))
+			return
+		}
+		if done {
+			break
+		}
+		if time.Since(logged) > 8*time.Second {
+			_, migrated := s.layout()
+			log.Info(fmt.Sprintf("DbStore: migrating chunks to sharded layout, %d done", migrated))
+			logged = time.Now()
+		}
+	}
+	_, migrated := s.layout()
+	if migrated > 0 {
+		log.Info(fmt.Sprintf("DbStore: migrated %d chunks to sharded layout in %v", migrated, time.Since(start)))
+	}
+}
+
+// migrateBatch moves the data of at most n chunks to the shard files, returning
+// whether the migration is complete.
+func (s *DbStore) migrateBatch(n int) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pos, _ := s.db.Get(keyMigration)
+	if pos == nil {
+		pos = []byte{kpIndex}
+	}
+	it := s.db.NewIterator()
+	defer it.Release()
+
+	batch := new(leveldb.Batch)
+	count := 0
+	for ok := it.Seek(pos); ok; ok = it.Next() {
+		key := it.Key()
+		if key[0] != kpIndex {
+			break
+		}
+		if count == n {
+			batch.Put(keyMigration, key)
+			return false, s.db.Write(batch)
+		}
+		var index dpaDBIndex
+		decodeIndex(it.Value(), &index)
+
+		dataKey := getDataKey(index.Idx)
+		if data, err := s.db.Get(dataKey); err == nil {
+			if err := s.shards.put(Key(key[1:]), data); err != nil {
+				return false, err
+			}
+			batch.Delete(dataKey)
+			s.migrated++
+		}
+		count++
+	}
+	// All indexed chunks migrated, drop the data left without index
+	for ok := it.Seek([]byte{kpData}); ok && it.Key()[0] == kpData; ok = it.Next() {
+		batch.Delete(it.Key())
+	}
+	batch.Delete(keyMigration)
+	batch.Put(keyLayout, []byte{layoutSharded})
+	if err := s.db.Write(batch); err != nil {
+		return false, err
+	}
+	s.sharded = true
+	return true, nil
+}
+
 //  describes a section of the DbStore representing the unsynced
 // domain relevant to a peer
 // Start - Stop designate a continuous area Keys in an address space
@@ -18,8 +18,13 @@ package storage
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/fulcrumchain/indigo/common"
 )
@@ -189,3 +194,162 @@ func TestDbStoreSyncIterator(t *testing.T) {
 		t.Fatalf("Expected %v chunk, got %v", keys[3], res[0])
 	}
 }
+
+// Tests that chunks stored in the legacy layout are migrated to the shard files
+// in the background, and stay retrievable during and after the migration.
+func TestDbStoreMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bzz-storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Write chunks the way the legacy layout did, data keyed by storage index
+	db, err := NewLDBDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 2*migrateBatchSize + 10
+	keys := make([]Key, count)
+	for i := range keys {
+		data := make([]byte, 16)
+		binary.LittleEndian.PutUint64(data, 8)
+		binary.BigEndian.PutUint64(data[8:], uint64(i))
+
+		hasher := MakeHashFunc(SHA3Hash)()
+		hasher.Write(data)
+		keys[i] = hasher.Sum(nil)
+
+		db.Put(getIndexKey(keys[i]), encodeIndex(&dpaDBIndex{Idx: uint64(i)}))
+		db.Put(getDataKey(uint64(i)), data)
+	}
+	db.Put(getDataKey(uint64(count)), []byte("orphaned"))
+	db.Put(keyEntryCnt, U64ToBytes(uint64(count)))
+	db.Put(keyDataIdx, U64ToBytes(uint64(count+1)))
+	db.Close()
+
+	m, err := NewDbStore(dir, MakeHashFunc(SHA3Hash), defaultDbCapacity, defaultRadius)
+	if err != nil {
+		t.Fatal("can't create store:", err)
+	}
+	defer m.Close()
+
+	if _, err := m.Get(keys[count-1]); err != nil {
+		t.Errorf("chunk not retrievable during migration: %v", err)
+	}
+	for i := 0; ; i++ {
+		if sharded, _ := m.layout(); sharded {
+			break
+		}
+		if i == 500 {
+			t.Fatalf("migration not complete")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, migrated := m.layout(); migrated != uint64(count) {
+		t.Errorf("migrated chunk count mismatch: have %d, want %d", migrated, count)
+	}
+	for i, key := range keys {
+		chunk, err := m.Get(key)
+		if err != nil {
+			t.Fatalf("chunk %d not retrievable after migration: %v", i, err)
+		}
+		if n := binary.BigEndian.Uint64(chunk.SData[8:]); n != uint64(i) {
+			t.Errorf("chunk %d data mismatch: have %d", i, n)
+		}
+		if _, err := os.Stat(m.shards.path(key)); err != nil {
+			t.Errorf("chunk %d shard file missing: %v", i, err)
+		}
+	}
+	for i := 0; i <= count; i++ {
+		if _, err := m.db.Get(getDataKey(uint64(i))); err == nil {
+			t.Fatalf("legacy data of chunk %d left in database", i)
+		}
+	}
+}
+
+// memColdStore is an in-memory ColdStore.
+type memColdStore struct {
+	lock sync.Mutex
+	data map[string][]byte
+	gets int
+}
+
+func (s *memColdStore) Put(key string, value []byte) (int64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data[key] = common.CopyBytes(value)
+	return int64(len(value)), nil
+}
+
+func (s *memColdStore) Get(key string) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gets++
+	value, ok := s.data[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return common.CopyBytes(value), nil
+}
+
+func (s *memColdStore) len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.data)
+}
+
+// Tests that chunks evicted by garbage collection are offloaded to the cold
+// store and fetched back on demand.
+func TestDbStoreColdStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bzz-storage-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m, err := NewDbStore(dir, MakeHashFunc(SHA3Hash), 100, defaultRadius)
+	if err != nil {
+		t.Fatal("can't create store:", err)
+	}
+	defer m.Close()
+	cold := &memColdStore{data: make(map[string][]byte)}
+	m.SetColdStore(cold)
+
+	count := 150
+	keys := make([]Key, count)
+	for i := range keys {
+		data := make([]byte, 16)
+		binary.LittleEndian.PutUint64(data, 8)
+		binary.BigEndian.PutUint64(data[8:], uint64(i))
+
+		hasher := MakeHashFunc(SHA3Hash)()
+		hasher.Write(data)
+		keys[i] = hasher.Sum(nil)
+		m.Put(&Chunk{Key: keys[i], SData: data})
+	}
+	if entries, _ := m.stats(); entries > 100 {
+		t.Fatalf("store over capacity: %d entries", entries)
+	}
+	for i := 0; cold.len() == 0 || len(m.cold.queue) > 0; i++ {
+		if i == 500 {
+			t.Fatalf("evicted chunks not offloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i, key := range keys {
+		chunk, err := m.Get(key)
+		if err != nil {
+			t.Fatalf("chunk %d not retrievable: %v", i, err)
+		}
+		if n := binary.BigEndian.Uint64(chunk.SData[8:]); n != uint64(i) {
+			t.Errorf("chunk %d data mismatch: have %d", i, n)
+		}
+	}
+	if cold.gets == 0 {
+		t.Errorf("no chunk fetched from the cold store")
+	}
+	if _, err := m.Get(ZeroKey); err != notFound {
+		t.Errorf("expected notFound for unknown chunk, got %v", err)
+	}
+}
@@ -209,6 +209,9 @@ func (d *dpaChunkStore) Get(key Key) (chunk *Chunk, err error) {
 	case <-timer:
 		log.Trace(fmt.Sprintf("DPA.Get: %v request time out ", key.Log()))
 		err = notFound
+	case <-chunk.Req.Failed():
+		log.Trace(fmt.Sprintf("DPA.Get: %v request failed", key.Log()))
+		err = notFound
 	case <-chunk.Req.C:
 		log.Trace(fmt.Sprintf("DPA.Get: %v retrieved, %d bytes (%p)", key.Log(), len(chunk.SData), chunk))
 	}
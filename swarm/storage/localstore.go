@@ -71,5 +71,38 @@ func (self *LocalStore) Get(key Key) (chunk *Chunk, err error) {
 	return
 }
 
+// SetColdStore makes the disk store offload the chunks it garbage collects to
+// cs, fetching them back on demand.
+func (self *LocalStore) SetColdStore(cs ColdStore) {
+	if db, ok := self.DbStore.(*DbStore); ok {
+		db.SetColdStore(cs)
+	}
+}
+
 // Close local store
 func (self *LocalStore) Close() {}
+
+// StoreStats reports the fill levels of the local store.
+type StoreStats struct {
+	CacheEntries  uint   `json:"cacheEntries"`  // Number of chunks cached in memory
+	CacheCapacity uint   `json:"cacheCapacity"` // Maximum number of chunks cached in memory
+	DbEntries     uint64 `json:"dbEntries"`     // Number of chunks stored on disk
+	DbCapacity    uint64 `json:"dbCapacity"`    // Number of chunks stored on disk before garbage collection
+	DbCounter     uint64 `json:"dbCounter"`     // Storage index of the next chunk, which sync cursors refer to
+	DbSharded     bool   `json:"dbSharded"`     // Whether the data of all chunks is in the sharded layout
+	DbMigrated    uint64 `json:"dbMigrated"`    // Number of chunks migrated to the sharded layout since start
+}
+
+// Stats reports the fill levels of the local store.
+func (self *LocalStore) Stats() *StoreStats {
+	stats := new(StoreStats)
+	if mem, ok := self.memStore.(*MemStore); ok {
+		stats.CacheEntries, stats.CacheCapacity = mem.stats()
+	}
+	if db, ok := self.DbStore.(*DbStore); ok {
+		stats.DbEntries, stats.DbCapacity = db.stats()
+		stats.DbCounter = db.Counter()
+		stats.DbSharded, stats.DbMigrated = db.layout()
+	}
+	return stats
+}
@@ -120,6 +120,13 @@ func (node *memTree) updateAccess(a uint64) {
 	}
 }
 
+// stats returns the number of chunks cached and the capacity of the cache.
+func (s *MemStore) stats() (entries, capacity uint) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.entryCnt, s.capacity
+}
+
 func (s *MemStore) setCapacity(c uint) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
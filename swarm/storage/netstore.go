@@ -120,9 +120,14 @@ func (self *NetStore) Get(key Key) (*Chunk, error) {
 	var err error
 	chunk, err := self.localStore.Get(key)
 	if err == nil {
-		if chunk.Req == nil {
+		switch {
+		case chunk.Req == nil:
 			log.Trace(fmt.Sprintf("NetStore.Get: %v found locally", key))
-		} else {
+		case chunk.SData == nil && chunk.Req.StartSearch():
+			// the previous search gave up, launch a new one
+			log.Trace(fmt.Sprintf("NetStore.Get: %v hit on a failed request. search again", key))
+			go self.cloud.Retrieve(chunk)
+		default:
 			log.Trace(fmt.Sprintf("NetStore.Get: %v hit on an existing request", key))
 			// no need to launch again
 		}
@@ -131,6 +136,7 @@ func (self *NetStore) Get(key Key) (*Chunk, error) {
 	// no data and no request status
 	log.Trace(fmt.Sprintf("NetStore.Get: %v not found locally. open new request", key))
 	chunk = NewChunk(key, newRequestStatus(key))
+	chunk.Req.StartSearch()
 	self.localStore.memStore.Put(chunk)
 	go self.cloud.Retrieve(chunk)
 	return chunk, nil
@@ -0,0 +1,85 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// failingCloud is a cloud store failing every retrieval it is asked for.
+type failingCloud struct {
+	retrievals chan *Chunk
+}
+
+func (c *failingCloud) Store(*Chunk)   {}
+func (c *failingCloud) Deliver(*Chunk) {}
+func (c *failingCloud) Retrieve(chunk *Chunk) {
+	c.retrievals <- chunk
+}
+
+// Tests that concurrent requests for a chunk share a single network search,
+// and that a search which gave up is started anew by the next request.
+func TestNetStoreRetrieveDedup(t *testing.T) {
+	cloud := &failingCloud{retrievals: make(chan *Chunk, 10)}
+	lstore := &LocalStore{NewMemStore(nil, defaultCacheCapacity), initDbStore(t)}
+	nstore := NewNetStore(MakeHashFunc(SHA3Hash), lstore, cloud, NewDefaultStoreParams())
+	dpa := NewDpaChunkStore(lstore, nstore)
+
+	key := Key(make([]byte, 32))
+	key[0] = 1
+
+	first, _ := nstore.Get(key)
+	second, _ := nstore.Get(key)
+	if first.Req != second.Req {
+		t.Fatalf("requests not shared")
+	}
+	var chunk *Chunk
+	select {
+	case chunk = <-cloud.retrievals:
+	case <-time.After(time.Second):
+		t.Fatalf("no network search started")
+	}
+	select {
+	case <-cloud.retrievals:
+		t.Fatalf("duplicate network search started")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Fail the search and check that waiting requesters return right away
+	errc := make(chan error)
+	go func() {
+		_, err := dpa.Get(key)
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	chunk.Req.FailSearch()
+	select {
+	case err := <-errc:
+		if err != notFound {
+			t.Fatalf("failed search error mismatch: have %v, want %v", err, notFound)
+		}
+	case <-time.After(searchTimeout / 2):
+		t.Fatalf("requester not woken up by failed search")
+	}
+	// Request the chunk again and check that a new search is started
+	nstore.Get(key)
+	select {
+	case <-cloud.retrievals:
+	case <-time.After(time.Second):
+		t.Fatalf("no network search restarted")
+	}
+}
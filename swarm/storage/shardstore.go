@@ -0,0 +1,92 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package storage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+/*
+Sharded chunk layout
+
+Storing the chunk data in leveldb alongside the index makes compactions rewrite
+the data over and over, which brings stores of tens of millions of chunks to a
+crawl. The data of the chunks is instead stored in a file per chunk, named after
+the chunk key and sharded into shardLevels levels of directories by the leading
+bytes of the key, so that no directory grows beyond a few hundred entries. The
+leveldb database only keeps the (small) index entries and counters.
+*/
+
+// shardLevels is the number of directory levels chunk files are sharded into,
+// by one byte of the key per level.
+const shardLevels = 2
+
+// shardStore stores the data of chunks in content addressed files.
+type shardStore struct {
+	dir string
+}
+
+func newShardStore(dir string) (*shardStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &shardStore{dir: dir}, nil
+}
+
+// path returns the path of the file storing the data of a chunk.
+func (s *shardStore) path(key Key) string {
+	parts := make([]string, 0, shardLevels+2)
+	parts = append(parts, s.dir)
+	for i := 0; i < shardLevels && i < len(key); i++ {
+		parts = append(parts, hex.EncodeToString(key[i:i+1]))
+	}
+	return filepath.Join(append(parts, hex.EncodeToString(key))...)
+}
+
+// put stores the data of a chunk, atomically replacing any previous data.
+func (s *shardStore) put(key Key, data []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("empty chunk key")
+	}
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// get retrieves the data of a chunk, or an error satisfying os.IsNotExist if
+// the chunk is not stored.
+func (s *shardStore) get(key Key) ([]byte, error) {
+	return ioutil.ReadFile(s.path(key))
+}
+
+// delete removes the data of a chunk, if stored.
+func (s *shardStore) delete(key Key) error {
+	if err := os.Remove(s.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -128,11 +128,17 @@ func (key *Key) UnmarshalJSON(value []byte) error {
 // peers and has a channel that is closed when the chunk is retrieved. Multiple
 // local callers can wait on this channel (or combined with a timeout, block with a
 // select).
+// At most one network search is in flight for a request at any time, a search
+// that gave up is signalled on the Failed channel and can be started anew.
 type RequestStatus struct {
 	Key        Key
 	Source     Peer
 	C          chan bool
 	Requesters map[uint64][]interface{}
+
+	searching bool      // whether a network search is in flight
+	failed    chan bool // closed when the current search gives up
+	lock      sync.Mutex
 }
 
 func newRequestStatus(key Key) *RequestStatus {
@@ -140,9 +146,44 @@ func newRequestStatus(key Key) *RequestStatus {
 		Key:        key,
 		Requesters: make(map[uint64][]interface{}),
 		C:          make(chan bool),
+		failed:     make(chan bool),
+	}
+}
+
+// StartSearch marks a network search for the chunk as in flight. It returns
+// false if one already is, in which case the caller should wait for it instead.
+func (rs *RequestStatus) StartSearch() bool {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if rs.searching {
+		return false
+	}
+	rs.searching = true
+	rs.failed = make(chan bool)
+	return true
+}
+
+// FailSearch marks the network search in flight as given up, waking up the
+// callers waiting on it.
+func (rs *RequestStatus) FailSearch() {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	if rs.searching {
+		rs.searching = false
+		close(rs.failed)
 	}
 }
 
+// Failed returns a channel closed when the current network search gives up.
+func (rs *RequestStatus) Failed() <-chan bool {
+	rs.lock.Lock()
+	defer rs.lock.Unlock()
+
+	return rs.failed
+}
+
 // Chunk also serves as a request object passed to ChunkStores
 // in case it is a retrieval request, Data is nil and Size is 0
 // Note that Size is not the size of the data chunk, which is Data.Size()
@@ -27,8 +27,10 @@ import (
 	"github.com/fulcrumchain/indigo/common"
 	"github.com/fulcrumchain/indigo/contracts/chequebook"
 	"github.com/fulcrumchain/indigo/contracts/ens"
+	"github.com/fulcrumchain/indigo/ethdb/archive"
 	"github.com/fulcrumchain/indigo/goclient"
 	"github.com/fulcrumchain/indigo/log"
+	"github.com/fulcrumchain/indigo/metrics"
 	"github.com/fulcrumchain/indigo/p2p"
 	"github.com/fulcrumchain/indigo/p2p/discover"
 	"github.com/fulcrumchain/indigo/rpc"
@@ -96,6 +98,15 @@ func NewSwarm(backend chequebook.Backend, ensClient *goclient.Client, config *ap
 	if err != nil {
 		return
 	}
+	if config.Archive.Endpoint != "" {
+		ar, err := archive.NewArchive(config.Archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to archive: %s", err)
+		}
+		ar.Meter(metrics.DB("chunks"))
+		self.lstore.SetColdStore(ar)
+		log.Debug(fmt.Sprintf("-> archiving evicted chunks to bucket %s", config.Archive.Bucket))
+	}
 
 	// setup local store
 	log.Debug(fmt.Sprintf("Set up local storage"))
@@ -233,11 +244,11 @@ func (s *Swarm) Stop() error {
 
 // implements the node.Service interface
 func (s *Swarm) Protocols() []p2p.Protocol {
-	proto, err := network.Bzz(s.depo, s.backend, s.hive, s.dbAccess, s.config.Swap, s.config.SyncParams, s.config.NetworkId)
+	protos, err := network.Bzz(s.depo, s.backend, s.hive, s.dbAccess, s.config.Swap, s.config.SyncParams, s.config.NetworkId)
 	if err != nil {
 		return nil
 	}
-	return []p2p.Protocol{proto}
+	return protos
 }
 
 // implements node.Service
@@ -255,7 +266,7 @@ func (s *Swarm) APIs() []rpc.API {
 		{
 			Namespace: "bzz",
 			Version:   "0.1",
-			Service:   api.NewControl(s.api, s.hive),
+			Service:   api.NewControl(s.api, s.hive, s.dbAccess),
 			Public:    false,
 		},
 		{
@@ -284,6 +295,13 @@ func (s *Swarm) APIs() []rpc.API {
 			Service:   api.NewFileSystem(s.api),
 			Public:    false,
 		},
+		// manifest APIs
+		{
+			Namespace: "bzz",
+			Version:   "0.1",
+			Service:   api.NewManifests(s.api),
+			Public:    true,
+		},
 		// {Namespace, Version, api.NewAdmin(s), false},
 	}
 }
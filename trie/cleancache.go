@@ -0,0 +1,179 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/metrics"
+)
+
+var (
+	cleancacheHitMeter   = metrics.NewMeter("trie/cleancache/hit")
+	cleancacheMissMeter  = metrics.NewMeter("trie/cleancache/miss")
+	cleancacheWriteMeter = metrics.NewMeter("trie/cleancache/write")
+	cleancacheEvictMeter = metrics.NewMeter("trie/cleancache/evict")
+	cleancacheSizeGauge  = metrics.NewGauge("trie/cleancache/size")
+)
+
+const (
+	// cleanCacheShards is the number of independently locked shards of a clean
+	// cache, so that concurrent readers rarely contend.
+	cleanCacheShards = 16
+
+	// cleanCacheOverhead approximates the memory held by a cached node besides
+	// its hash and blob.
+	cleanCacheOverhead = 64
+
+	// cleanDoorkeeperSize is the number of missed node hashes a shard remembers
+	// before forgetting them all.
+	cleanDoorkeeperSize = 64 * 1024
+)
+
+// CleanCache is a size bounded cache of trie nodes already persisted to disk,
+// meant to be shared by all the trie databases of a process reading the same
+// disk database, so that the hot parts of the state are cached only once.
+//
+// Nodes read from disk are only admitted once they miss for the second time
+// within a while, so that one-off traversals such as state dumps or traces of
+// old blocks don't evict the nodes block processing keeps coming back to. Nodes
+// flushed from a trie database's dirty cache are admitted right away, as they
+// belong to the most recent states.
+type CleanCache struct {
+	shards [cleanCacheShards]*cleanShard
+	size   int64 // Memory held by all shards in bytes (atomic)
+}
+
+// cleanShard is an LRU cache of the nodes whose hashes map to it.
+type cleanShard struct {
+	cache *CleanCache
+	limit int                           // Memory allowance of the shard in bytes
+	size  int                           // Memory held by the shard in bytes
+	nodes map[common.Hash]*list.Element // Cached nodes by hash
+	lru   *list.List                    // Cached nodes, most recently used first
+	seen  map[common.Hash]struct{}      // Doorkeeper of nodes missed once
+	lock  sync.Mutex
+}
+
+// cleanNode is a cached trie node.
+type cleanNode struct {
+	hash common.Hash
+	blob []byte
+}
+
+// NewCleanCache creates a clean trie node cache holding at most size bytes.
+func NewCleanCache(size int) *CleanCache {
+	c := new(CleanCache)
+	for i := range c.shards {
+		c.shards[i] = &cleanShard{
+			cache: c,
+			limit: size / cleanCacheShards,
+			nodes: make(map[common.Hash]*list.Element),
+			lru:   list.New(),
+			seen:  make(map[common.Hash]struct{}),
+		}
+	}
+	return c
+}
+
+// shard returns the shard holding a node.
+func (c *CleanCache) shard(hash common.Hash) *cleanShard {
+	return c.shards[hash[0]%cleanCacheShards]
+}
+
+// Get retrieves a cached node.
+func (c *CleanCache) Get(hash common.Hash) ([]byte, bool) {
+	s := c.shard(hash)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	elem, ok := s.nodes[hash]
+	if !ok {
+		cleancacheMissMeter.Mark(1)
+		return nil, false
+	}
+	cleancacheHitMeter.Mark(1)
+	s.lru.MoveToFront(elem)
+	return elem.Value.(*cleanNode).blob, true
+}
+
+// Set caches a node unconditionally. The blob must not be modified afterwards.
+func (c *CleanCache) Set(hash common.Hash, blob []byte) {
+	s := c.shard(hash)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.set(hash, blob)
+}
+
+// Admit caches a node read from disk if it was already missed recently,
+// remembering it otherwise. The blob must not be modified afterwards.
+func (c *CleanCache) Admit(hash common.Hash, blob []byte) {
+	s := c.shard(hash)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.seen[hash]; !ok {
+		if len(s.seen) >= cleanDoorkeeperSize/cleanCacheShards {
+			s.seen = make(map[common.Hash]struct{})
+		}
+		s.seen[hash] = struct{}{}
+		return
+	}
+	delete(s.seen, hash)
+	s.set(hash, blob)
+}
+
+// Size returns the memory held by the cache in bytes.
+func (c *CleanCache) Size() int {
+	return int(atomic.LoadInt64(&c.size))
+}
+
+// set caches a node, evicting the least recently used ones until the shard
+// fits its allowance. The caller holds the lock.
+func (s *cleanShard) set(hash common.Hash, blob []byte) {
+	if elem, ok := s.nodes[hash]; ok {
+		s.lru.MoveToFront(elem)
+		return
+	}
+	size := common.HashLength + len(blob) + cleanCacheOverhead
+	if size > s.limit {
+		return
+	}
+	freed := 0
+	for s.size+size > s.limit {
+		elem := s.lru.Back()
+		node := elem.Value.(*cleanNode)
+
+		s.lru.Remove(elem)
+		delete(s.nodes, node.hash)
+		s.size -= common.HashLength + len(node.blob) + cleanCacheOverhead
+		freed += common.HashLength + len(node.blob) + cleanCacheOverhead
+		cleancacheEvictMeter.Mark(1)
+	}
+	s.nodes[hash] = s.lru.PushFront(&cleanNode{hash: hash, blob: blob})
+	s.size += size
+
+	cleancacheSizeGauge.Update(atomic.AddInt64(&s.cache.size, int64(size-freed)))
+	cleancacheWriteMeter.Mark(1)
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/fulcrumchain/indigo/common"
+	"github.com/fulcrumchain/indigo/ethdb"
+)
+
+func TestCleanCacheAdmission(t *testing.T) {
+	cache := NewCleanCache(1024 * 1024)
+	hash, blob := common.Hash{1}, []byte{0x01, 0x02}
+
+	// Nodes read from disk are admitted on their second miss only
+	cache.Admit(hash, blob)
+	if _, ok := cache.Get(hash); ok {
+		t.Fatalf("node admitted on its first miss")
+	}
+	cache.Admit(hash, blob)
+	if cached, ok := cache.Get(hash); !ok || !bytes.Equal(cached, blob) {
+		t.Fatalf("node not admitted on its second miss: %x", cached)
+	}
+	// Flushed nodes are admitted right away
+	cache.Set(common.Hash{2}, blob)
+	if _, ok := cache.Get(common.Hash{2}); !ok {
+		t.Fatalf("flushed node not admitted")
+	}
+}
+
+func TestCleanCacheEviction(t *testing.T) {
+	// Fill a single shard beyond its allowance
+	size := common.HashLength + 32 + cleanCacheOverhead
+	cache := NewCleanCache(4 * size * cleanCacheShards)
+	for i := 0; i < 6; i++ {
+		if i == 4 {
+			cache.Get(common.Hash{0, 0}) // keep the first node recently used
+		}
+		cache.Set(common.Hash{0, byte(i)}, make([]byte, 32))
+	}
+	if cache.Size() != 4*size {
+		t.Fatalf("cache size mismatch: have %d, want %d", cache.Size(), 4*size)
+	}
+	for i, want := range []bool{true, false, false, true, true, true} {
+		if _, ok := cache.Get(common.Hash{0, byte(i)}); ok != want {
+			t.Errorf("node %d: cached %v, want %v", i, ok, want)
+		}
+	}
+}
+
+func TestCleanCacheShared(t *testing.T) {
+	diskdb := ethdb.NewMemDatabase()
+	cache := NewCleanCache(1024 * 1024)
+
+	// Committing a trie moves its nodes into the clean cache
+	trie, _ := New(common.Hash{}, NewDatabaseWithCache(diskdb, cache))
+	for i := 0; i < 100; i++ {
+		trie.Update([]byte(fmt.Sprintf("key-%d", i)), bytes.Repeat([]byte{byte(i)}, 40))
+	}
+	root, err := trie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	if err := trie.db.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush trie: %v", err)
+	}
+	if cache.Size() == 0 {
+		t.Fatalf("committed nodes not cached")
+	}
+	// Another database sharing the cache reads the trie without the disk
+	for _, key := range diskdb.Keys() {
+		diskdb.Delete(key)
+	}
+	other, err := New(root, NewDatabaseWithCache(diskdb, cache))
+	if err != nil {
+		t.Fatalf("failed to open trie from the cache: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if value, err := other.TryGet([]byte(fmt.Sprintf("key-%d", i))); err != nil || !bytes.Equal(value, bytes.Repeat([]byte{byte(i)}, 40)) {
+			t.Fatalf("value %d mismatch: %x, %v", i, value, err)
+		}
+	}
+}
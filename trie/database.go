@@ -60,6 +60,7 @@ type DatabaseReader interface {
 // periodically flush a couple tries to disk, garbage collecting the remainder.
 type Database struct {
 	diskdb ethdb.Database // Persistent storage for matured trie nodes
+	cleans *CleanCache    // Cache of nodes already persisted to disk, nil if disabled
 
 	nodes  map[common.Hash]*cachedNode // Data and references relationships of a node
 	oldest common.Hash                 // Oldest tracked node, flush-list head
@@ -79,6 +80,9 @@ type Database struct {
 	nodesSize     common.StorageSize // Storage size of the nodes cache (exc. flushlist)
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	parent *Database                           // Database read through, nil unless recording
+	record func(hash common.Hash, blob []byte) // Callback receiving the nodes read from the parent
+
 	lock sync.RWMutex
 }
 
@@ -96,8 +100,16 @@ type cachedNode struct {
 // NewDatabase creates a new trie database to store ephemeral trie content before
 // its written out to disk or garbage collected.
 func NewDatabase(diskdb ethdb.Database) *Database {
+	return NewDatabaseWithCache(diskdb, nil)
+}
+
+// NewDatabaseWithCache creates a new trie database reading the nodes persisted
+// to disk through the given clean cache, which may be shared with the other
+// trie databases of the same disk database.
+func NewDatabaseWithCache(diskdb ethdb.Database, cleans *CleanCache) *Database {
 	return &Database{
 		diskdb: diskdb,
+		cleans: cleans,
 		nodes: map[common.Hash]*cachedNode{
 			{}: {children: make(map[common.Hash]int)},
 		},
@@ -105,11 +117,25 @@ func NewDatabase(diskdb ethdb.Database) *Database {
 	}
 }
 
+// NewRecordingDatabase creates a trie database reading the nodes it lacks from
+// a parent database, passing each of them to the given callback. It is meant
+// for reading and should not be committed.
+func NewRecordingDatabase(parent *Database, record func(hash common.Hash, blob []byte)) *Database {
+	db := NewDatabase(parent.diskdb)
+	db.parent, db.record = parent, record
+	return db
+}
+
 // DiskDB retrieves the persistent storage backing the trie database.
 func (db *Database) DiskDB() DatabaseReader {
 	return db.diskdb
 }
 
+// CleanCache retrieves the cache of nodes persisted to disk, nil if disabled.
+func (db *Database) CleanCache() *CleanCache {
+	return db.cleans
+}
+
 // Insert writes a new trie node to the memory database if it's yet unknown. The
 // method will make a copy of the slice.
 func (db *Database) Insert(hash common.Hash, blob []byte) {
@@ -162,8 +188,24 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	if node != nil {
 		return node.blob, nil
 	}
+	if db.parent != nil {
+		blob, err := db.parent.Node(hash)
+		if err == nil {
+			db.record(hash, blob)
+		}
+		return blob, err
+	}
+	if db.cleans != nil {
+		if blob, ok := db.cleans.Get(hash); ok {
+			return blob, nil
+		}
+	}
 	// Content unavailable in memory, attempt to retrieve from disk
-	return db.diskdb.Get(hash[:])
+	blob, err := db.diskdb.Get(hash[:])
+	if err == nil && db.cleans != nil {
+		db.cleans.Admit(hash, blob)
+	}
+	return blob, err
 }
 
 // preimage retrieves a cached trie node pre-image from memory. If it cannot be
@@ -361,6 +403,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	}
 	for db.oldest != oldest {
 		node := db.nodes[db.oldest]
+		if db.cleans != nil {
+			db.cleans.Set(db.oldest, node.blob)
+		}
 		delete(db.nodes, db.oldest)
 		db.oldest = node.flushNext
 
@@ -499,6 +544,9 @@ func (db *Database) uncache(hash common.Hash) {
 	for child := range node.children {
 		db.uncache(child)
 	}
+	if db.cleans != nil {
+		db.cleans.Set(hash, node.blob)
+	}
 	delete(db.nodes, hash)
 	db.nodesSize -= common.StorageSize(common.HashLength + len(node.blob))
 }